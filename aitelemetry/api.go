@@ -7,6 +7,12 @@ import (
 	"github.com/microsoft/ApplicationInsights-Go/appinsights"
 )
 
+// Telemetry backends selectable in the CNS and CNI telemetry configuration. AppInsights is used when none is set.
+const (
+	BackendAppInsights = "appinsights"
+	BackendOTLP        = "otlp"
+)
+
 // Application trace/log structure
 type Report struct {
 	Message          string
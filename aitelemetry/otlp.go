@@ -0,0 +1,348 @@
+package aitelemetry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpLogsPath    = "/v1/logs"
+	otlpMetricsPath = "/v1/metrics"
+	otlpContentType = "application/x-protobuf"
+	otlpScopeName   = "github.com/Azure/azure-container-networking/aitelemetry"
+	// resource_logs and resource_metrics are the first field of the OTLP export requests.
+	otlpResourceField protowire.Number = 1
+
+	// attribute keys of the OpenTelemetry semantic conventions
+	serviceNameAttr    = "service.name"
+	serviceVersionAttr = "service.version"
+	hostNameAttr       = "host.name"
+	hostIDAttr         = "host.id"
+	hostTypeAttr       = "host.type"
+	osTypeAttr         = "os.type"
+	cloudProviderAttr  = "cloud.provider"
+	cloudRegionAttr    = "cloud.region"
+	cloudAccountAttr   = "cloud.account.id"
+	eventNameAttr      = "event.name"
+	cloudProviderAzure = "azure"
+
+	contextStr    = "Context"
+	resourceIDStr = "ResourceID"
+)
+
+var errOTLPEndpointInvalid = errors.New("invalid OTLP endpoint")
+
+// otlpTelemetryHandle sends the telemetry as OTLP logs and metrics to an OpenTelemetry collector over http.
+type otlpTelemetryHandle struct {
+	// info holds the app information and the host metadata, its appinsights client is not used.
+	info       *telemetryHandle
+	endpoint   string
+	client     *http.Client
+	batchSize  int
+	mutex      sync.Mutex
+	logs       []*logspb.LogRecord
+	metrics    []*metricspb.Metric
+	queuedSize int
+	flushCh    chan struct{}
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewOTLPTelemetry creates telemetry handle which exports to the OTLP/HTTP endpoint of an OpenTelemetry collector,
+// e.g. http://localhost:4318. Logs and metrics are batched with the batch size and interval of the AIConfig.
+func NewOTLPTelemetry(endpoint string, aiConfig AIConfig) (TelemetryHandle, error) {
+	debugMode = aiConfig.DebugMode
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		debugLog("Invalid OTLP endpoint %s", endpoint)
+		return nil, errors.Wrapf(errOTLPEndpointInvalid, "%q", endpoint)
+	}
+
+	setAIConfigDefaults(&aiConfig)
+
+	th := &otlpTelemetryHandle{
+		info: &telemetryHandle{
+			appName:                      aiConfig.AppName,
+			appVersion:                   aiConfig.AppVersion,
+			disableMetadataRefreshThread: aiConfig.DisableMetadataRefreshThread,
+			refreshTimeout:               aiConfig.RefreshTimeout,
+		},
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		client:    &http.Client{Timeout: defaultTimeout * time.Second},
+		batchSize: aiConfig.BatchSize,
+		flushCh:   make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+
+	if th.info.disableMetadataRefreshThread {
+		getMetadata(th.info)
+	} else {
+		go getMetadata(th.info)
+	}
+
+	go th.run(time.Duration(aiConfig.BatchInterval) * time.Second)
+
+	return th, nil
+}
+
+// TrackLog function sends report (trace) as a log record with warning severity, the custom dimensions are sent as attributes.
+func (th *otlpTelemetryHandle) TrackLog(report Report) {
+	record := &logspb.LogRecord{
+		TimeUnixNano:   uint64(time.Now().UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		SeverityText:   "Warning",
+		Body:           stringValue(report.Message),
+		Attributes:     attributes(report.CustomDimensions),
+	}
+
+	if report.Context != "" {
+		record.Attributes = append(record.Attributes, attribute(contextStr, report.Context))
+	}
+
+	// will be empty if cns used as telemetry service for cni
+	if th.info.appVersion == "" && report.AppVersion != "" {
+		record.Attributes = append(record.Attributes, attribute(serviceVersionAttr, report.AppVersion))
+	}
+
+	th.trackLogRecord(record)
+}
+
+// TrackEvent function sends event as a log record named by the event.name attribute, the properties are sent as attributes.
+func (th *otlpTelemetryHandle) TrackEvent(event Event) {
+	record := &logspb.LogRecord{
+		TimeUnixNano:   uint64(time.Now().UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:   "Information",
+		Body:           stringValue(event.EventName),
+		Attributes:     attributes(event.Properties),
+	}
+
+	record.Attributes = append(record.Attributes, attribute(eventNameAttr, event.EventName))
+	if event.ResourceID != "" {
+		record.Attributes = append(record.Attributes, attribute(resourceIDStr, event.ResourceID))
+	}
+
+	th.trackLogRecord(record)
+}
+
+// TrackMetric function sends metric as a gauge with a single data point, the custom dimensions are sent as attributes.
+func (th *otlpTelemetryHandle) TrackMetric(metric Metric) {
+	dataPoint := &metricspb.NumberDataPoint{
+		TimeUnixNano: uint64(time.Now().UnixNano()),
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: metric.Value},
+		Attributes:   attributes(metric.CustomDimensions),
+	}
+
+	if th.info.appVersion == "" && metric.AppVersion != "" {
+		dataPoint.Attributes = append(dataPoint.Attributes, attribute(serviceVersionAttr, metric.AppVersion))
+	}
+
+	m := &metricspb.Metric{
+		Name: metric.Name,
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{dataPoint}}},
+	}
+
+	th.mutex.Lock()
+	th.metrics = append(th.metrics, m)
+	th.queued(proto.Size(m))
+	th.mutex.Unlock()
+}
+
+// Close - should be called for each NewOTLPTelemetry call. Sends the queued telemetry and stops the batching.
+func (th *otlpTelemetryHandle) Close(timeout int) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	th.closeOnce.Do(func() { close(th.stopCh) })
+
+	// wait for items to be sent otherwise timeout
+	select {
+	case <-th.doneCh:
+	case <-time.After(time.Duration(timeout) * time.Second):
+		debugLog("[OTLP] Timed out sending queued telemetry")
+	}
+}
+
+// Flush - sends the current queue
+func (th *otlpTelemetryHandle) Flush() {
+	th.export()
+}
+
+func (th *otlpTelemetryHandle) trackLogRecord(record *logspb.LogRecord) {
+	th.mutex.Lock()
+	th.logs = append(th.logs, record)
+	th.queued(proto.Size(record))
+	th.mutex.Unlock()
+}
+
+// queued accounts for the size of a queued item and triggers a flush once the batch size is reached.
+// It must be called with the mutex held.
+func (th *otlpTelemetryHandle) queued(size int) {
+	th.queuedSize += size
+	if th.queuedSize < th.batchSize {
+		return
+	}
+
+	select {
+	case th.flushCh <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued telemetry at every batch interval, or as soon as the batch size is reached, until the handle is closed.
+func (th *otlpTelemetryHandle) run(batchInterval time.Duration) {
+	defer close(th.doneCh)
+
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-th.flushCh:
+		case <-th.stopCh:
+			th.export()
+			return
+		}
+		th.export()
+	}
+}
+
+// export sends the queued logs and metrics to the collector. Telemetry that fails to send is dropped.
+func (th *otlpTelemetryHandle) export() {
+	th.mutex.Lock()
+	logs, metrics := th.logs, th.metrics
+	th.logs, th.metrics, th.queuedSize = nil, nil, 0
+	th.mutex.Unlock()
+
+	if len(logs) == 0 && len(metrics) == 0 {
+		return
+	}
+
+	resource := th.resource()
+	scope := &commonpb.InstrumentationScope{Name: otlpScopeName, Version: th.info.appVersion}
+
+	if len(logs) > 0 {
+		resourceLogs := &logspb.ResourceLogs{
+			Resource:  resource,
+			ScopeLogs: []*logspb.ScopeLogs{{Scope: scope, LogRecords: logs}},
+		}
+		if err := th.post(otlpLogsPath, resourceLogs); err != nil {
+			debugLog("[OTLP] Failed to export %d log records: %v", len(logs), err)
+		}
+	}
+
+	if len(metrics) > 0 {
+		resourceMetrics := &metricspb.ResourceMetrics{
+			Resource:     resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: scope, Metrics: metrics}},
+		}
+		if err := th.post(otlpMetricsPath, resourceMetrics); err != nil {
+			debugLog("[OTLP] Failed to export %d metrics: %v", len(metrics), err)
+		}
+	}
+}
+
+// post sends an OTLP export request holding the given resource logs or metrics.
+func (th *otlpTelemetryHandle) post(path string, resource proto.Message) error {
+	b, err := proto.Marshal(resource)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal resource")
+	}
+
+	// The export request only wraps the repeated resource field, so it is encoded here rather than
+	// depending on the collector service packages.
+	body := protowire.AppendTag(nil, otlpResourceField, protowire.BytesType)
+	body = protowire.AppendBytes(body, b)
+
+	req, err := http.NewRequest(http.MethodPost, th.endpoint+path, bytes.NewReader(body)) //nolint:noctx // bounded by the client timeout
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", otlpContentType)
+
+	resp, err := th.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+	//nolint:errcheck // drain the body so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector replied with http status code %d", resp.StatusCode) //nolint:goerr113 // status code is dynamic
+	}
+
+	return nil
+}
+
+// resource returns the app information and host metadata as resource attributes.
+func (th *otlpTelemetryHandle) resource() *resourcepb.Resource {
+	hostName, _ := os.Hostname()
+	attrs := []*commonpb.KeyValue{
+		attribute(serviceNameAttr, th.info.appName),
+		attribute(hostNameAttr, hostName),
+		attribute(osTypeAttr, runtime.GOOS),
+	}
+
+	if th.info.appVersion != "" {
+		attrs = append(attrs, attribute(serviceVersionAttr, th.info.appVersion))
+	}
+
+	// Acquire read lock to read metadata
+	th.info.rwmutex.RLock()
+	metadata := th.info.metadata
+	th.info.rwmutex.RUnlock()
+
+	// Check if metadata is populated
+	if metadata.SubscriptionID != "" {
+		attrs = append(attrs,
+			attribute(cloudProviderAttr, cloudProviderAzure),
+			attribute(cloudRegionAttr, metadata.Location),
+			attribute(cloudAccountAttr, metadata.SubscriptionID),
+			attribute(hostIDAttr, metadata.VMID),
+			attribute(hostTypeAttr, metadata.VMSize),
+			attribute(resourceGroupStr, metadata.ResourceGroupName),
+			attribute(vmNameStr, metadata.VMName),
+			attribute(osVersionStr, metadata.OSVersion),
+		)
+	}
+
+	return &resourcepb.Resource{Attributes: attrs}
+}
+
+func stringValue(value string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+}
+
+func attribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: stringValue(value)}
+}
+
+func attributes(dimensions map[string]string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(dimensions))
+	for key, value := range dimensions {
+		attrs = append(attrs, attribute(key, value))
+	}
+	return attrs
+}
@@ -0,0 +1,169 @@
+package aitelemetry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver is an in-process OTLP/HTTP collector which keeps the logs and metrics it receives.
+type otlpReceiver struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	logs    []*logspb.ResourceLogs
+	metrics []*metricspb.ResourceMetrics
+}
+
+func newOTLPReceiver(t *testing.T) *otlpReceiver {
+	r := &otlpReceiver{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpLogsPath, func(w http.ResponseWriter, req *http.Request) {
+		resourceLogs := &logspb.ResourceLogs{}
+		decodeExportRequest(t, req, resourceLogs)
+		r.mutex.Lock()
+		r.logs = append(r.logs, resourceLogs)
+		r.mutex.Unlock()
+	})
+	mux.HandleFunc(otlpMetricsPath, func(w http.ResponseWriter, req *http.Request) {
+		resourceMetrics := &metricspb.ResourceMetrics{}
+		decodeExportRequest(t, req, resourceMetrics)
+		r.mutex.Lock()
+		r.metrics = append(r.metrics, resourceMetrics)
+		r.mutex.Unlock()
+	})
+	r.Server = httptest.NewServer(mux)
+	t.Cleanup(r.Close)
+	return r
+}
+
+// decodeExportRequest decodes the single resource of an export request.
+func decodeExportRequest(t *testing.T, req *http.Request, resource proto.Message) {
+	require.Equal(t, otlpContentType, req.Header.Get("Content-Type"))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+
+	num, typ, n := protowire.ConsumeTag(body)
+	require.Equal(t, otlpResourceField, num)
+	require.Equal(t, protowire.BytesType, typ)
+	b, m := protowire.ConsumeBytes(body[n:])
+	require.Equal(t, len(body), n+m)
+	require.NoError(t, proto.Unmarshal(b, resource))
+}
+
+func (r *otlpReceiver) logRecords() []*logspb.LogRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var records []*logspb.LogRecord
+	for _, resourceLogs := range r.logs {
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			records = append(records, scopeLogs.GetLogRecords()...)
+		}
+	}
+	return records
+}
+
+func attributeMap(attrs []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[attr.GetKey()] = attr.GetValue().GetStringValue()
+	}
+	return m
+}
+
+func newTestOTLPTelemetry(t *testing.T, endpoint string) TelemetryHandle {
+	aiConfig := AIConfig{
+		AppName:                      "testapp",
+		AppVersion:                   "v1.0.26",
+		BatchSize:                    4096,
+		BatchInterval:                60,
+		DisableMetadataRefreshThread: true,
+	}
+	th, err := NewOTLPTelemetry(endpoint, aiConfig)
+	require.NoError(t, err)
+	return th
+}
+
+func TestNewOTLPTelemetryInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:4318", "grpc://localhost:4317"} {
+		_, err := NewOTLPTelemetry(endpoint, AIConfig{})
+		require.ErrorIs(t, err, errOTLPEndpointInvalid, endpoint)
+	}
+}
+
+func TestOTLPTelemetry(t *testing.T) {
+	receiver := newOTLPReceiver(t)
+	th := newTestOTLPTelemetry(t, receiver.URL)
+
+	th.TrackLog(Report{
+		Message:          "test log",
+		Context:          "container",
+		CustomDimensions: map[string]string{"OperationType": "ADD"},
+	})
+	th.TrackEvent(Event{EventName: "TestEvent", ResourceID: "nc", Properties: map[string]string{"key": "value"}})
+	th.TrackMetric(Metric{Name: "TestMetric", Value: 1.5, CustomDimensions: map[string]string{"status": "ok"}})
+	th.Close(10)
+
+	records := receiver.logRecords()
+	require.Len(t, records, 2)
+
+	require.Equal(t, "test log", records[0].GetBody().GetStringValue())
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].GetSeverityNumber())
+	require.Equal(t, map[string]string{"OperationType": "ADD", contextStr: "container"}, attributeMap(records[0].GetAttributes()))
+
+	require.Equal(t, "TestEvent", records[1].GetBody().GetStringValue())
+	require.Equal(t, map[string]string{"key": "value", eventNameAttr: "TestEvent", resourceIDStr: "nc"}, attributeMap(records[1].GetAttributes()))
+
+	resource := attributeMap(receiver.logs[0].GetResource().GetAttributes())
+	require.Equal(t, "testapp", resource[serviceNameAttr])
+	require.Equal(t, "v1.0.26", resource[serviceVersionAttr])
+
+	require.Len(t, receiver.metrics, 1)
+	metrics := receiver.metrics[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "TestMetric", metrics[0].GetName())
+	dataPoints := metrics[0].GetGauge().GetDataPoints()
+	require.Len(t, dataPoints, 1)
+	require.InDelta(t, 1.5, dataPoints[0].GetAsDouble(), 0)
+	require.Equal(t, map[string]string{"status": "ok"}, attributeMap(dataPoints[0].GetAttributes()))
+}
+
+// Tests that the queued telemetry is sent as soon as the batch size is reached.
+func TestOTLPTelemetryBatchSize(t *testing.T) {
+	receiver := newOTLPReceiver(t)
+	th, err := NewOTLPTelemetry(receiver.URL, AIConfig{BatchSize: 1, BatchInterval: 60, DisableMetadataRefreshThread: true})
+	require.NoError(t, err)
+	defer th.Close(10)
+
+	th.TrackLog(Report{Message: "test log"})
+	require.Eventually(t, func() bool { return len(receiver.logRecords()) == 1 }, defaultTimeout*time.Second, 10*time.Millisecond)
+}
+
+func TestOTLPCore(t *testing.T) {
+	receiver := newOTLPReceiver(t)
+	th := newTestOTLPTelemetry(t, receiver.URL)
+	defer th.Close(10)
+
+	logger := zap.New(NewOTLPCore(zapcore.InfoLevel, th)).With(zap.String("component", "test"))
+	logger.Debug("dropped")
+	logger.Error("failed", zap.Int("count", 3), zap.Strings("ips", []string{"10.0.0.1"}))
+	require.NoError(t, logger.Sync())
+
+	records := receiver.logRecords()
+	require.Len(t, records, 1)
+	require.Equal(t, "failed", records[0].GetBody().GetStringValue())
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, records[0].GetSeverityNumber())
+	require.Equal(t, map[string]string{"component": "test", "count": "3", "ips": `["10.0.0.1"]`}, attributeMap(records[0].GetAttributes()))
+}
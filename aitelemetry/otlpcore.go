@@ -0,0 +1,122 @@
+package aitelemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap/zapcore"
+)
+
+const callerStr = "caller"
+
+var levelToSeverity = map[zapcore.Level]logspb.SeverityNumber{
+	zapcore.DebugLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	zapcore.InfoLevel:   logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	zapcore.WarnLevel:   logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	zapcore.ErrorLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	zapcore.DPanicLevel: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	zapcore.PanicLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	zapcore.FatalLevel:  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+var _ zapcore.Core = (*OTLPCore)(nil)
+
+// OTLPCore implements zapcore.Core for the OTLP telemetry handle, in the same way as the zapai Core does for appinsights.
+//
+// Entries are sent as log records with the severity of their level, and the fields of the core and the entry as attributes.
+// Used with a handle other than the one returned by NewOTLPTelemetry, entries are sent with TrackLog instead.
+type OTLPCore struct {
+	zapcore.LevelEnabler
+	th     TelemetryHandle
+	fields []zapcore.Field
+}
+
+// NewOTLPCore creates a new zap core which writes to the telemetry handle.
+func NewOTLPCore(le zapcore.LevelEnabler, th TelemetryHandle) *OTLPCore {
+	return &OTLPCore{
+		LevelEnabler: le,
+		th:           th,
+	}
+}
+
+func (c *OTLPCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &OTLPCore{
+		LevelEnabler: c.LevelEnabler,
+		th:           c.th,
+		fields:       make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *OTLPCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+//
+//nolint:gocritic // ignore hugeparam in interface impl
+func (c *OTLPCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range c.fields {
+		c.fields[i].AddTo(enc)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	dimensions := make(map[string]string, len(enc.Fields)+1)
+	for key, value := range enc.Fields {
+		dimensions[key] = fieldString(value)
+	}
+
+	if entry.Caller.Defined {
+		dimensions[callerStr] = entry.Caller.String()
+	}
+
+	th, ok := c.th.(*otlpTelemetryHandle)
+	if !ok {
+		c.th.TrackLog(Report{Message: entry.Message, CustomDimensions: dimensions})
+		return nil
+	}
+
+	th.trackLogRecord(&logspb.LogRecord{
+		TimeUnixNano:         uint64(entry.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       levelToSeverity[entry.Level],
+		SeverityText:         entry.Level.CapitalString(),
+		Body:                 stringValue(entry.Message),
+		Attributes:           attributes(dimensions),
+	})
+	return nil
+}
+
+// Sync sends the queued telemetry.
+func (c *OTLPCore) Sync() error {
+	c.th.Flush()
+	return nil
+}
+
+// fieldString renders a field value encoded by the map encoder as a string attribute.
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -16,6 +16,12 @@ const (
 	CmdGet = "GET"
 	// CmdDel - CNI DEL command.
 	CmdDel = "DEL"
+	// CmdCheck - CNI CHECK command.
+	CmdCheck = "CHECK"
+	// CmdStatus - CNI STATUS command.
+	CmdStatus = "STATUS"
+	// CmdGC - CNI GC command.
+	CmdGC = "GC"
 	// CmdUpdate - CNI UPDATE command.
 	CmdUpdate = "UPDATE"
 	// CmdVersion - CNI VERSION command.
@@ -26,13 +32,17 @@ const (
 
 	// CNI errors.
 	ErrRuntime = 100
+	// ErrPluginNotAvailable is the CNI spec error code returned by STATUS when the plugin cannot service ADD requests.
+	ErrPluginNotAvailable = 50
 
 	// DefaultVersion is the CNI version used when no version is specified in a network config file.
 	defaultVersion = "0.2.0"
+	// networkCommandsVersion is the first CNI version that allows the STATUS and GC commands.
+	networkCommandsVersion = "1.1.0"
 )
 
 // Supported CNI versions.
-var supportedVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0"}
+var supportedVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0", "1.0.0", "1.1.0"}
 
 // CNI contract.
 type PluginApi interface {
@@ -40,4 +50,7 @@ type PluginApi interface {
 	Get(args *cniSkel.CmdArgs) error
 	Delete(args *cniSkel.CmdArgs) error
 	Update(args *cniSkel.CmdArgs) error
+	Check(args *cniSkel.CmdArgs) error
+	Status(args *cniSkel.CmdArgs) error
+	GC(args *cniSkel.CmdArgs) error
 }
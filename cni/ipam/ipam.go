@@ -255,7 +255,7 @@ func (plugin *ipamPlugin) Add(args *cniSkel.CmdArgs) error {
 	}
 
 	// Convert result to the requested CNI version.
-	res, err := result.GetAsVersion(nwCfg.CNIVersion)
+	res, err := cni.GetResultAsVersion(result, nwCfg.CNIVersion)
 	if err != nil {
 		err = plugin.Errorf("Failed to convert result: %v", err)
 		return err
@@ -278,6 +278,21 @@ func (plugin *ipamPlugin) Get(args *cniSkel.CmdArgs) error {
 	return nil
 }
 
+// Check handles CNI Check commands.
+func (plugin *ipamPlugin) Check(args *cniSkel.CmdArgs) error {
+	return nil
+}
+
+// Status handles CNI Status commands.
+func (plugin *ipamPlugin) Status(args *cniSkel.CmdArgs) error {
+	return nil
+}
+
+// GC handles CNI GC commands.
+func (plugin *ipamPlugin) GC(args *cniSkel.CmdArgs) error {
+	return nil
+}
+
 // Delete handles CNI delete commands.
 func (plugin *ipamPlugin) Delete(args *cniSkel.CmdArgs) error {
 	var err error
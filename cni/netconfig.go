@@ -72,13 +72,23 @@ type NetworkConfig struct {
 	EnableExactMatchForPodName    bool            `json:"enableExactMatchForPodName,omitempty"`
 	DisableHairpinOnHostInterface bool            `json:"disableHairpinOnHostInterface,omitempty"`
 	DisableIPTableLock            bool            `json:"disableIPTableLock,omitempty"`
+	IPTablesBackend               string          `json:"iptablesBackend,omitempty"`
+	StoreBackend                  string          `json:"storeBackend,omitempty"`
 	CNSUrl                        string          `json:"cnsurl,omitempty"`
+	CNSGRPCAddress                string          `json:"cnsGrpcAddress,omitempty"`
 	ExecutionMode                 string          `json:"executionMode,omitempty"`
 	IPAM                          IPAM            `json:"ipam,omitempty"`
 	DNS                           cniTypes.DNS    `json:"dns,omitempty"`
 	RuntimeConfig                 RuntimeConfig   `json:"runtimeConfig,omitempty"`
 	WindowsSettings               WindowsSettings `json:"windowsSettings,omitempty"`
 	AdditionalArgs                []KVPair        `json:"AdditionalArgs,omitempty"`
+	ValidAttachments              []GCAttachment  `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+// GCAttachment identifies a container attachment that the runtime still considers valid during CNI GC.
+type GCAttachment struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifname"`
 }
 
 type WindowsSettings struct {
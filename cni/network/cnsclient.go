@@ -2,8 +2,12 @@ package network
 
 import (
 	"context"
+	"time"
 
+	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"go.uber.org/zap"
 )
 
 type cnsclient interface {
@@ -14,3 +18,36 @@ type cnsclient interface {
 	GetNetworkContainer(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error)
 	GetAllNetworkContainers(ctx context.Context, orchestratorContext []byte) ([]cns.GetNetworkContainerResponse, error)
 }
+
+// grpcReadyTimeout bounds how long the CNS gRPC connection may take to be ready before the REST client is used.
+const grpcReadyTimeout = 2 * time.Second
+
+// ipamCNSClient returns the client for the CNS IPAM invoker: the CNS gRPC client if the network config opts into it
+// with cnsGrpcAddress, otherwise or if CNS cannot be reached over gRPC the REST client, and a func to close it once
+// the invocation is done with it.
+func ipamCNSClient(nwCfg *cni.NetworkConfig, restClient cnsclient) (client cnsclient, closeClient func()) {
+	if nwCfg.CNSGRPCAddress == "" {
+		return restClient, func() {}
+	}
+	grpcClient, err := cnscli.NewGRPC(nwCfg.CNSGRPCAddress, defaultRequestTimeout)
+	if err != nil {
+		logger.Error("Failed to create cns grpc client, falling back to the rest client",
+			zap.String("address", nwCfg.CNSGRPCAddress), zap.Error(err))
+		return restClient, func() {}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grpcReadyTimeout)
+	defer cancel()
+	if err := grpcClient.WaitForReady(ctx); err != nil {
+		logger.Error("Failed to connect to cns over grpc, falling back to the rest client",
+			zap.String("address", nwCfg.CNSGRPCAddress), zap.Error(err))
+		if err := grpcClient.Close(); err != nil {
+			logger.Error("Failed to close cns grpc client", zap.Error(err))
+		}
+		return restClient, func() {}
+	}
+	return grpcClient, func() {
+		if err := grpcClient.Close(); err != nil {
+			logger.Error("Failed to close cns grpc client", zap.Error(err))
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 func ReportPluginError(reportManager *telemetry.ReportManager, tb *telemetry.TelemetryBuffer, err error) {
 	logger.Error("Report plugin error")
 	reflect.ValueOf(reportManager.Report).Elem().FieldByName("ErrorMessage").SetString(err.Error())
+	reflect.ValueOf(reportManager.Report).Elem().FieldByName("ErrorClass").SetString(errorClass(err))
 
 	if err := reportManager.SendReport(tb); err != nil {
 		logger.Error("SendReport failed", zap.Error(err))
@@ -10,10 +10,12 @@ import (
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cni/util"
 	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
 	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/network"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 var testPodInfo cns.KubernetesPodInfo
@@ -1241,6 +1243,60 @@ func TestReleaseIPAPIsFail(t *testing.T) {
 	}
 }
 
+func TestIPAMCNSClient(t *testing.T) {
+	restClient := &MockCNSClient{}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	go s.Serve(l) //nolint:errcheck // stopped by the test
+	t.Cleanup(s.Stop)
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	tests := []struct {
+		name     string
+		address  string
+		wantREST bool
+	}{
+		{
+			name:     "REST client without a gRPC address",
+			wantREST: true,
+		},
+		{
+			name:    "gRPC client with a gRPC address",
+			address: l.Addr().String(),
+		},
+		{
+			name:     "REST client when the gRPC client cannot be dialed",
+			address:  "unix://%zz",
+			wantREST: true,
+		},
+		{
+			name:     "REST client when CNS is not listening for gRPC",
+			address:  closedAddress,
+			wantREST: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, closeClient := ipamCNSClient(&cni.NetworkConfig{CNSGRPCAddress: tt.address}, restClient)
+			if tt.wantREST {
+				require.Same(t, restClient, client)
+				closeClient()
+				return
+			}
+			require.IsType(t, &cnscli.GRPCClient{}, client)
+			closeClient()
+			require.Error(t, client.(*cnscli.GRPCClient).Close(), "the gRPC client is closed")
+		})
+	}
+}
+
 func Test_setHostOptions(t *testing.T) {
 	require := require.New(t) //nolint further usage of require without passing t
 	type args struct {
@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
@@ -61,6 +62,18 @@ const (
 	nmAgentSnatAndDnsSupportAPI = "NetworkManagementDNSSupport"
 )
 
+// Error classes of the CNI operation metrics.
+const (
+	errorClassNone          = "None"
+	errorClassIPAM          = "IPAM"
+	errorClassStore         = "Store"
+	errorClassTryAgainLater = "TryAgainLater"
+	errorClassPlugin        = "Plugin"
+	errorClassOther         = "Other"
+)
+
+var errIPAMInvokerAdd = errors.New("IPAM Invoker Add failed with error")
+
 // temporary consts related func determineSnat() which is to be deleted after
 // a baking period with newest NMAgent changes
 const (
@@ -151,6 +164,11 @@ func (plugin *NetPlugin) Start(config *common.PluginConfig) error {
 		zap.String("name", plugin.Name),
 		zap.String("version", plugin.Version))
 
+	// Report state files that had to be recovered from a backup generation.
+	store.RecoveryHandler = func(fileName, backupFileName string, err error) {
+		sendEvent(plugin, fmt.Sprintf("Recovered %s from backup %s after error: %v", fileName, backupFileName, err))
+	}
+
 	// Initialize network manager. rehyrdration not required on reboot for cni plugin
 	err = plugin.nm.Initialize(config, false)
 	if err != nil {
@@ -275,8 +293,10 @@ func SetCustomDimensions(cniMetric *telemetry.AIMetric, nwCfg *cni.NetworkConfig
 	} else {
 		cniMetric.Metric.CustomDimensions[telemetry.StatusStr] = telemetry.SucceededStr
 	}
+	cniMetric.Metric.CustomDimensions[telemetry.ErrorClassStr] = errorClass(err)
 
 	if nwCfg != nil {
+		cniMetric.Metric.CustomDimensions[telemetry.IPAMModeStr] = nwCfg.IPAM.Type
 		if nwCfg.MultiTenancy {
 			cniMetric.Metric.CustomDimensions[telemetry.CNIModeStr] = telemetry.MultiTenancyStr
 		} else {
@@ -287,6 +307,45 @@ func SetCustomDimensions(cniMetric *telemetry.AIMetric, nwCfg *cni.NetworkConfig
 	}
 }
 
+// errorClass returns the class of a CNI operation error used to label its metrics.
+func errorClass(err error) string {
+	if err == nil {
+		return errorClassNone
+	}
+
+	if errors.Is(err, errIPAMInvokerAdd) {
+		return errorClassIPAM
+	}
+
+	if errors.Is(err, store.ErrTimeoutLockingStore) {
+		return errorClassStore
+	}
+
+	var cniErr *cniTypes.Error
+	if errors.As(err, &cniErr) {
+		if cniErr.Code == cniTypes.ErrTryAgainLater {
+			return errorClassTryAgainLater
+		}
+		return errorClassPlugin
+	}
+
+	return errorClassOther
+}
+
+// sendDataplaneCallMetric reports the duration of a call that programs networks or endpoints through netlink or HNS.
+func (plugin *NetPlugin) sendDataplaneCallMetric(call string, startTime time.Time, err error) {
+	cniMetric := telemetry.AIMetric{
+		Metric: aitelemetry.Metric{
+			Name:             telemetry.CNIDataplaneCallTimeMetricStr,
+			Value:            float64(time.Since(startTime).Milliseconds()),
+			AppVersion:       plugin.Version,
+			CustomDimensions: map[string]string{telemetry.CallStr: call},
+		},
+	}
+	SetCustomDimensions(&cniMetric, nil, err)
+	telemetry.SendCNIMetric(&cniMetric, plugin.tb)
+}
+
 func (plugin *NetPlugin) setCNIReportDetails(nwCfg *cni.NetworkConfig, opType, msg string) {
 	plugin.report.OperationType = opType
 	plugin.report.SubContext = fmt.Sprintf("%+v", nwCfg)
@@ -348,6 +407,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 	}
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	iptables.Backend = nwCfg.IPTablesBackend
 	plugin.setCNIReportDetails(nwCfg, CNI_ADD, "")
 
 	defer func() {
@@ -367,7 +427,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		addSnatInterface(nwCfg, defaultCniResult)
 
 		// Convert result to the requested CNI version.
-		res, vererr := defaultCniResult.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetResultAsVersion(defaultCniResult, nwCfg.CNIVersion)
 		if vererr != nil {
 			logger.Error("GetAsVersion failed", zap.Error(vererr))
 			plugin.Error(vererr)
@@ -509,7 +569,9 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		if plugin.ipamInvoker == nil {
 			switch nwCfg.IPAM.Type {
 			case network.AzureCNS:
-				plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
+				ipamClient, closeIPAMClient := ipamCNSClient(nwCfg, cnsClient)
+				defer closeIPAMClient() //nolint:gocritic // the client is created once, for the first result
+				plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, ipamClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
 
 			default:
 				plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -520,7 +582,7 @@ func (plugin *NetPlugin) Add(args *cniSkel.CmdArgs) error {
 		if !nwCfg.MultiTenancy {
 			ipamAddResult, err = plugin.ipamInvoker.Add(ipamAddConfig)
 			if err != nil {
-				return fmt.Errorf("IPAM Invoker Add failed with error: %w", err)
+				return fmt.Errorf("%w: %w", errIPAMInvokerAdd, err)
 			}
 			sendEvent(plugin, fmt.Sprintf("Allocated IPAddress from ipam DefaultInterface: %+v, SecondaryInterfaces: %+v", ipamAddResult.defaultInterfaceInfo, ipamAddResult.secondaryInterfacesInfo))
 		}
@@ -656,7 +718,9 @@ func (plugin *NetPlugin) createNetworkInternal(
 	}
 	setNetworkOptions(ipamAddResult.ncResponse, &nwInfo)
 
+	callStartTime := time.Now()
 	err = plugin.nm.CreateNetwork(&nwInfo)
+	plugin.sendDataplaneCallMetric("CreateNetwork", callStartTime, err)
 	if err != nil {
 		err = plugin.Errorf("createNetworkInternal: Failed to create network: %v", err)
 	}
@@ -814,7 +878,9 @@ func (plugin *NetPlugin) createEndpointInternal(opt *createEndpointInternalOpt)
 	// Create the endpoint.
 	logger.Info("Creating endpoint", zap.String("endpointInfo", epInfo.PrettyString()))
 	sendEvent(plugin, fmt.Sprintf("[cni-net] Creating endpoint %s.", epInfo.PrettyString()))
+	callStartTime := time.Now()
 	err = plugin.nm.CreateEndpoint(cnsclient, opt.nwInfo.Id, epInfos)
+	plugin.sendDataplaneCallMetric("CreateEndpoint", callStartTime, err)
 	if err != nil {
 		err = plugin.Errorf("Failed to create endpoint: %v", err)
 	}
@@ -848,7 +914,7 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 		result.Interfaces = append(result.Interfaces, iface)
 
 		// Convert result to the requested CNI version.
-		res, vererr := result.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetResultAsVersion(&result, nwCfg.CNIVersion)
 		if vererr != nil {
 			logger.Error("GetAsVersion failed", zap.Error(vererr))
 			plugin.Error(vererr)
@@ -872,6 +938,7 @@ func (plugin *NetPlugin) Get(args *cniSkel.CmdArgs) error {
 	logger.Info("Read network configuration", zap.Any("config", nwCfg))
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	iptables.Backend = nwCfg.IPTablesBackend
 
 	// Initialize values from network config.
 	if networkID, err = plugin.getNetworkName(args.Netns, nil, nwCfg); err != nil {
@@ -963,6 +1030,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	plugin.report.ContainerName = k8sPodName + ":" + k8sNamespace
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	iptables.Backend = nwCfg.IPTablesBackend
 
 	sendMetricFunc := func() {
 		operationTimeMs := time.Since(startTime).Milliseconds()
@@ -996,7 +1064,9 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 				logger.Error("failed to create cns client", zap.Error(cnsErr))
 				return errors.Wrap(cnsErr, "failed to create cns client")
 			}
-			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, cnsClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
+			ipamClient, closeIPAMClient := ipamCNSClient(nwCfg, cnsClient)
+			defer closeIPAMClient()
+			plugin.ipamInvoker = NewCNSInvoker(k8sPodName, k8sNamespace, ipamClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
 
 		default:
 			plugin.ipamInvoker = NewAzureIpamInvoker(plugin, &nwInfo)
@@ -1077,7 +1147,10 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 			zap.String("endpointID", endpointID))
 		sendEvent(plugin, fmt.Sprintf("Deleting endpoint:%v", endpointID))
 		// Delete the endpoint.
-		if err = plugin.nm.DeleteEndpoint(networkID, endpointID, epInfo); err != nil {
+		callStartTime := time.Now()
+		err = plugin.nm.DeleteEndpoint(networkID, endpointID, epInfo)
+		plugin.sendDataplaneCallMetric("DeleteEndpoint", callStartTime, err)
+		if err != nil {
 			// return a retriable error so the container runtime will retry this DEL later
 			// the implementation of this function returns nil if the endpoint doens't exist, so
 			// we don't have to check that here
@@ -1108,6 +1181,236 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	return err
 }
 
+// Check handles CNI check commands.
+// It verifies that the endpoint created by ADD is still programmed as recorded in the state store.
+func (plugin *NetPlugin) Check(args *cniSkel.CmdArgs) error {
+	var (
+		err       error
+		nwCfg     *cni.NetworkConfig
+		networkID string
+	)
+
+	logger.Info("Processing CHECK command",
+		zap.String("containerId", args.ContainerID),
+		zap.String("netNS", args.Netns),
+		zap.String("ifName", args.IfName),
+		zap.String("args", args.Args),
+		zap.String("path", args.Path))
+
+	defer func() {
+		logger.Info("CHECK command completed", zap.Error(err))
+	}()
+
+	// Parse network configuration from stdin.
+	if nwCfg, err = cni.ParseNetworkConfig(args.StdinData); err != nil {
+		err = plugin.Errorf("Failed to parse network configuration: %v.", err)
+		return err
+	}
+
+	if networkID, err = plugin.getNetworkName(args.Netns, nil, nwCfg); err != nil {
+		err = plugin.Errorf("Failed to extract network name from network config. error: %v", err)
+		return err
+	}
+
+	endpointID := plugin.nm.GetEndpointID(args.ContainerID, args.IfName)
+	if err = plugin.nm.CheckEndpoint(networkID, endpointID, args.IfName); err != nil {
+		err = plugin.Errorf("Endpoint %s failed check: %v", endpointID, err)
+		return err
+	}
+
+	return nil
+}
+
+// Status handles CNI status commands.
+// The plugin is ready when its state store is accessible and, in azure-cns IPAM mode, CNS is reachable.
+func (plugin *NetPlugin) Status(args *cniSkel.CmdArgs) error {
+	var (
+		err   error
+		nwCfg *cni.NetworkConfig
+	)
+
+	logger.Info("Processing STATUS command", zap.String("path", args.Path))
+
+	defer func() {
+		logger.Info("STATUS command completed", zap.Error(err))
+	}()
+
+	// Parse network configuration from stdin.
+	if nwCfg, err = cni.ParseNetworkConfig(args.StdinData); err != nil {
+		err = plugin.Errorf("Failed to parse network configuration: %v.", err)
+		return err
+	}
+
+	// State has already been restored by Start, so only verify that the store is still accessible.
+	if plugin.Store != nil && plugin.Store.Exists() {
+		if _, err = plugin.Store.GetModificationTime(); err != nil {
+			err = &cniTypes.Error{
+				Code: cni.ErrPluginNotAvailable,
+				Msg:  fmt.Sprintf("state store is not accessible: %v", err),
+			}
+			return err
+		}
+	}
+
+	if nwCfg.IPAM.Type == network.AzureCNS {
+		cnsClient, cnsErr := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
+		if cnsErr != nil {
+			err = errors.Wrap(cnsErr, "failed to create cns client")
+			return err
+		}
+
+		if cnsErr = cnsClient.GetHealthReport(context.TODO()); cnsErr != nil {
+			err = &cniTypes.Error{
+				Code: cni.ErrPluginNotAvailable,
+				Msg:  fmt.Sprintf("cns is not reachable: %v", cnsErr),
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GC handles CNI garbage collection commands.
+// Every endpoint in the network that is not one of the runtime's valid attachments is deleted and its IPs are released.
+func (plugin *NetPlugin) GC(args *cniSkel.CmdArgs) error {
+	var (
+		err       error
+		nwCfg     *cni.NetworkConfig
+		networkID string
+		nwInfo    network.NetworkInfo
+		eps       map[string]*network.EndpointInfo
+	)
+
+	logger.Info("Processing GC command", zap.String("path", args.Path))
+
+	defer func() {
+		logger.Info("GC command completed", zap.Error(err))
+	}()
+
+	// Parse network configuration from stdin.
+	if nwCfg, err = cni.ParseNetworkConfig(args.StdinData); err != nil {
+		err = plugin.Errorf("Failed to parse network configuration: %v.", err)
+		return err
+	}
+
+	// Multitenant networks are keyed by netns and stateless CNI keeps no local state, so there is nothing to collect.
+	if nwCfg.MultiTenancy || plugin.nm.IsStatelessCNIMode() {
+		logger.Info("Skipping GC for network", zap.String("network", nwCfg.Name))
+		return nil
+	}
+
+	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	iptables.Backend = nwCfg.IPTablesBackend
+
+	if networkID, err = plugin.getNetworkName("", nil, nwCfg); err != nil {
+		err = plugin.Errorf("Failed to extract network name from network config. error: %v", err)
+		return err
+	}
+
+	if nwInfo, err = plugin.nm.GetNetworkInfo(networkID); err != nil {
+		if network.IsNetworkNotFoundError(err) {
+			err = nil
+		}
+		return err
+	}
+
+	if eps, err = plugin.nm.GetAllEndpoints(networkID); err != nil {
+		if errors.Is(err, store.ErrStoreEmpty) {
+			err = nil
+		}
+		return err
+	}
+
+	validEndpoints := make(map[string]struct{}, len(nwCfg.ValidAttachments))
+	validContainers := make(map[string]struct{}, len(nwCfg.ValidAttachments))
+	for _, attachment := range nwCfg.ValidAttachments {
+		validEndpoints[plugin.nm.GetEndpointID(attachment.ContainerID, attachment.IfName)] = struct{}{}
+		validContainers[attachment.ContainerID] = struct{}{}
+	}
+
+	var numFailed int
+	for endpointID, epInfo := range eps {
+		if _, ok := validEndpoints[endpointID]; ok {
+			continue
+		}
+
+		if _, ok := validContainers[epInfo.ContainerID]; ok {
+			continue
+		}
+
+		sendEvent(plugin, fmt.Sprintf("Garbage collecting endpoint:%v containerID:%v", endpointID, epInfo.ContainerID))
+		if gcErr := plugin.collectEndpoint(networkID, &nwInfo, epInfo, nwCfg, args); gcErr != nil {
+			logger.Error("Failed to garbage collect endpoint",
+				zap.String("endpointID", endpointID),
+				zap.Error(gcErr))
+			numFailed++
+		}
+	}
+
+	if numFailed > 0 {
+		err = plugin.RetriableError(fmt.Errorf("failed to garbage collect %d endpoints", numFailed)) //nolint:goerr113
+		return err
+	}
+
+	return nil
+}
+
+// collectEndpoint deletes a leaked endpoint and releases its IPs through the IPAM invoker.
+func (plugin *NetPlugin) collectEndpoint(
+	networkID string,
+	nwInfo *network.NetworkInfo,
+	epInfo *network.EndpointInfo,
+	nwCfg *cni.NetworkConfig,
+	args *cniSkel.CmdArgs,
+) error {
+	// Endpoint IDs are of the form <containerID prefix>-<ifName>, rebuild the args ADD was called with.
+	ifName := network.InfraInterfaceName
+	if i := strings.Index(epInfo.Id, "-"); i >= 0 {
+		ifName = epInfo.Id[i+1:]
+	}
+
+	epArgs := &cniSkel.CmdArgs{
+		ContainerID: epInfo.ContainerID,
+		IfName:      ifName,
+		Path:        args.Path,
+	}
+
+	ipamInvoker := plugin.ipamInvoker
+	if ipamInvoker == nil {
+		switch nwCfg.IPAM.Type {
+		case network.AzureCNS:
+			cnsClient, err := cnscli.New("", defaultRequestTimeout)
+			if err != nil {
+				return errors.Wrap(err, "failed to create cns client")
+			}
+			ipamClient, closeIPAMClient := ipamCNSClient(nwCfg, cnsClient)
+			defer closeIPAMClient()
+			ipamInvoker = NewCNSInvoker(epInfo.PODName, epInfo.PODNameSpace, ipamClient, util.ExecutionMode(nwCfg.ExecutionMode), util.IpamMode(nwCfg.IPAM.Mode))
+
+		default:
+			ipamInvoker = NewAzureIpamInvoker(plugin, nwInfo)
+		}
+	}
+
+	logger.Info("Deleting endpoint", zap.String("endpointID", epInfo.Id))
+	callStartTime := time.Now()
+	err := plugin.nm.DeleteEndpoint(networkID, epInfo.Id, epInfo)
+	plugin.sendDataplaneCallMetric("DeleteEndpoint", callStartTime, err)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete endpoint")
+	}
+
+	for i := range epInfo.IPAddresses {
+		logger.Info("Release ip", zap.String("ip", epInfo.IPAddresses[i].IP.String()))
+		if err := ipamInvoker.Delete(&epInfo.IPAddresses[i], nwCfg, epArgs, nwInfo.Options); err != nil {
+			return errors.Wrap(err, "failed to release address")
+		}
+	}
+
+	return nil
+}
+
 // Update handles CNI update commands.
 // Update is only supported for multitenancy and to update routes.
 func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
@@ -1138,6 +1441,7 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 	logger.Info("Read network configuration", zap.Any("config", nwCfg))
 
 	iptables.DisableIPTableLock = nwCfg.DisableIPTableLock
+	iptables.Backend = nwCfg.IPTablesBackend
 	plugin.setCNIReportDetails(nwCfg, CNI_UPDATE, "")
 
 	defer func() {
@@ -1156,7 +1460,7 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 		}
 
 		// Convert result to the requested CNI version.
-		res, vererr := result.GetAsVersion(nwCfg.CNIVersion)
+		res, vererr := cni.GetResultAsVersion(result, nwCfg.CNIVersion)
 		if vererr != nil {
 			logger.Error("GetAsVersion failed", zap.Error(vererr))
 			plugin.Error(vererr)
@@ -1291,7 +1595,10 @@ func (plugin *NetPlugin) Update(args *cniSkel.CmdArgs) error {
 	logger.Info("Now updating existing endpoint with targetNetworkConfig",
 		zap.String("endpoint", existingEpInfo.Id),
 		zap.Any("config", targetNetworkConfig))
-	if err = plugin.nm.UpdateEndpoint(networkID, existingEpInfo, targetEpInfo); err != nil {
+	callStartTime := time.Now()
+	err = plugin.nm.UpdateEndpoint(networkID, existingEpInfo, targetEpInfo)
+	plugin.sendDataplaneCallMetric("UpdateEndpoint", callStartTime, err)
+	if err != nil {
 		err = plugin.Errorf("Failed to update endpoint: %v", err)
 		return err
 	}
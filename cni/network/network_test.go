@@ -16,8 +16,11 @@ import (
 	"github.com/Azure/azure-container-networking/network/networkutils"
 	"github.com/Azure/azure-container-networking/network/policy"
 	"github.com/Azure/azure-container-networking/nns"
+	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-container-networking/telemetry"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -606,6 +609,88 @@ func TestPluginGet(t *testing.T) {
 	}
 }
 
+/*
+CHECK and GC scenarios
+*/
+
+func TestPluginCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		methods    []string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:    "CNI Check happy path",
+			methods: []string{CNI_ADD, cni.CmdCheck},
+			wantErr: false,
+		},
+		{
+			name:       "CNI Check fail with endpoint not found",
+			methods:    []string{CNI_ADD, CNI_DEL, cni.CmdCheck},
+			wantErr:    true,
+			wantErrMsg: "Endpoint not found",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			plugin := GetTestResources()
+
+			for _, method := range tt.methods {
+				switch method {
+				case CNI_ADD:
+					err = plugin.Add(args)
+				case CNI_DEL:
+					err = plugin.Delete(args)
+				case cni.CmdCheck:
+					err = plugin.Check(args)
+				}
+			}
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErrMsg)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPluginGC(t *testing.T) {
+	plugin := GetTestResources()
+
+	podArgs := func(containerID, podName string) *cniSkel.CmdArgs {
+		return &cniSkel.CmdArgs{
+			StdinData:   nwCfg.Serialize(),
+			ContainerID: containerID,
+			Netns:       containerID,
+			Args:        fmt.Sprintf("K8S_POD_NAME=%v;K8S_POD_NAMESPACE=%v", podName, "test-pod-ns"),
+			IfName:      eth0IfName,
+		}
+	}
+
+	require.NoError(t, plugin.Add(podArgs("valid-container", "valid-pod")))
+	require.NoError(t, plugin.Add(podArgs("leaked-container", "leaked-pod")))
+
+	gcCfg := nwCfg
+	gcCfg.ValidAttachments = []cni.GCAttachment{
+		{ContainerID: "valid-container", IfName: eth0IfName},
+	}
+
+	err := plugin.GC(&cniSkel.CmdArgs{StdinData: gcCfg.Serialize()})
+	require.NoError(t, err)
+
+	endpoints, _ := plugin.nm.GetAllEndpoints(nwCfg.Name)
+	require.Len(t, endpoints, 1)
+	for _, ep := range endpoints {
+		assert.Equal(t, "valid-container", ep.ContainerID)
+	}
+}
+
 /*
 Multitenancy scenarios
 */
@@ -1232,3 +1317,24 @@ func TestPluginSwiftV2Add(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "no error", err: nil, want: errorClassNone},
+		{name: "ipam invoker", err: fmt.Errorf("%w: %w", errIPAMInvokerAdd, errors.New("no ips")), want: errorClassIPAM},
+		{name: "store lock", err: errors.Wrap(store.ErrTimeoutLockingStore, "lock acquire error"), want: errorClassStore},
+		{name: "retriable", err: cniTypes.NewError(cniTypes.ErrTryAgainLater, "failed to delete endpoint", ""), want: errorClassTryAgainLater},
+		{name: "plugin", err: &cniTypes.Error{Code: 100, Msg: "Failed to create endpoint"}, want: errorClassPlugin},
+		{name: "other", err: errors.New("unexpected"), want: errorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, errorClass(tt.err))
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -45,6 +46,20 @@ var args = common.ArgumentList{
 		Type:         "bool",
 		DefaultValue: false,
 	},
+	{
+		Name:         common.OptDumpState,
+		Shorthand:    common.OptDumpStateAlias,
+		Description:  "Print the persisted state in the layout of the state file",
+		Type:         "bool",
+		DefaultValue: false,
+	},
+	{
+		Name:         common.OptTargetVersion,
+		Shorthand:    common.OptTargetVersionAlias,
+		Description:  "Schema version to convert the dumped state to, the current version when negative",
+		Type:         "int",
+		DefaultValue: "-1",
+	},
 }
 
 // Prints version information.
@@ -52,6 +67,42 @@ func printVersion() {
 	fmt.Printf("Azure CNI Version %v\n", version)
 }
 
+// dumpState prints the persisted state converted to the target schema version, so that the output can replace
+// the state file before rolling back to a release that only knows that version.
+func dumpState(targetVersion int) error {
+	var config common.PluginConfig
+
+	// There is no network configuration outside of CNI commands, so the bolt backend is used if its database exists.
+	config.StoreBackend = cni.PersistedStoreBackend(platform.CNIRuntimePath + name + ".json")
+
+	plugin, err := cni.NewPlugin(name, version)
+	if err != nil {
+		return errors.Wrap(err, "Create plugin error")
+	}
+
+	if err = plugin.InitializeKeyValueStore(&config); err != nil {
+		return errors.Wrap(err, "lock acquire error")
+	}
+	defer func() {
+		if errUninit := plugin.UninitializeKeyValueStore(); errUninit != nil {
+			logger.Error("Failed to uninitialize key-value store", zap.Error(errUninit))
+		}
+	}()
+
+	state, err := store.DumpVersioned(config.Store, targetVersion)
+	if err != nil {
+		return errors.Wrap(err, "Dump state error")
+	}
+
+	b, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "Marshal state error")
+	}
+
+	fmt.Println(string(b))
+	return nil
+}
+
 func rootExecute() error {
 	var (
 		config common.PluginConfig
@@ -101,6 +152,11 @@ func rootExecute() error {
 			cniReport.VMUptime = upTime.Format("2006-01-02 15:04:05")
 		}
 
+		// The store backend is part of the network configuration, which is only handed to the command handlers.
+		if config.StoreBackend, err = cni.StoreBackend(cniCmd, platform.CNIRuntimePath+name+".json"); err != nil {
+			logger.Error("Failed to read store backend from network configuration", zap.Error(err))
+		}
+
 		// CNI Acquires lock
 		if err = netPlugin.Plugin.InitializeKeyValueStore(&config); err != nil {
 			network.PrintCNIError(fmt.Sprintf("Failed to initialize key-value store of network plugin: %v", err))
@@ -207,6 +263,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if common.GetArg(common.OptDumpState).(bool) {
+		if err := dumpState(common.GetArg(common.OptTargetVersion).(int)); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to dump state: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if rootExecute() != nil {
 		os.Exit(1)
 	}
@@ -6,8 +6,10 @@ package cni
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cni/log"
@@ -90,7 +92,17 @@ func (plugin *Plugin) Execute(api PluginApi) (err error) {
 	pluginInfo := cniVers.PluginSupports(supportedVersions...)
 
 	// Parse args and call the appropriate cmd handler.
-	cniErr := cniSkel.PluginMainWithError(api.Add, api.Get, api.Delete, pluginInfo, plugin.version)
+	// The skel package predates the STATUS and GC verbs, so those are dispatched here.
+	var cniErr *cniTypes.Error
+	switch os.Getenv(Cmd) {
+	case CmdStatus:
+		cniErr = executeNetworkCommand(api.Status, pluginInfo)
+	case CmdGC:
+		cniErr = executeNetworkCommand(api.GC, pluginInfo)
+	default:
+		cniErr = cniSkel.PluginMainWithError(api.Add, api.Check, api.Delete, pluginInfo, plugin.version)
+	}
+
 	if cniErr != nil {
 		cniErr.Print()
 		return cniErr
@@ -99,6 +111,60 @@ func (plugin *Plugin) Execute(api PluginApi) (err error) {
 	return nil
 }
 
+// executeNetworkCommand runs a CNI command that is scoped to the network rather than to a container attachment.
+// Such commands only receive CNI_PATH and the network configuration on stdin.
+// The configuration version is negotiated the same way skel does for the other commands.
+func executeNetworkCommand(cmd func(*cniSkel.CmdArgs) error, pluginInfo cniVers.PluginInfo) *cniTypes.Error {
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return cniTypes.NewError(cniTypes.ErrIOFailure, fmt.Sprintf("error reading from stdin: %v", err), "")
+	}
+
+	configVersion, err := (&cniVers.ConfigDecoder{}).Decode(stdinData)
+	if err != nil {
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "")
+	}
+
+	if gtet, err := cniVers.GreaterThanOrEqualTo(configVersion, networkCommandsVersion); err != nil {
+		return cniTypes.NewError(cniTypes.ErrDecodingFailure, err.Error(), "")
+	} else if !gtet {
+		return cniTypes.NewError(cniTypes.ErrIncompatibleCNIVersion,
+			fmt.Sprintf("config version %s does not allow %s", configVersion, os.Getenv(Cmd)), "")
+	}
+
+	if verErr := (&cniVers.Reconciler{}).Check(configVersion, pluginInfo); verErr != nil {
+		return cniTypes.NewError(cniTypes.ErrIncompatibleCNIVersion, "incompatible CNI versions", verErr.Details())
+	}
+
+	args := &cniSkel.CmdArgs{
+		Path:      os.Getenv("CNI_PATH"),
+		StdinData: stdinData,
+	}
+
+	if err = cmd(args); err != nil {
+		var cniErr *cniTypes.Error
+		if errors.As(err, &cniErr) {
+			return cniErr
+		}
+
+		return cniTypes.NewError(cniTypes.ErrInternal, err.Error(), "")
+	}
+
+	return nil
+}
+
+// GetResultAsVersion converts the result to the requested CNI version.
+// The CNI library doesn't convert to 1.1.0 yet, whose results only differ from 1.0.0 in their version.
+func GetResultAsVersion(result *cniTypesCurr.Result, version string) (cniTypes.Result, error) {
+	if version != networkCommandsVersion {
+		return result.GetAsVersion(version)
+	}
+
+	converted := *result
+	converted.CNIVersion = version
+	return &converted, nil
+}
+
 // DelegateAdd calls the given plugin's ADD command and returns the result.
 func (plugin *Plugin) DelegateAdd(pluginName string, nwCfg *NetworkConfig) (*cniTypesCurr.Result, error) {
 	var result *cniTypesCurr.Result
@@ -191,7 +257,7 @@ func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error
 			return errors.Wrap(err, "error creating new filelock")
 		}
 
-		plugin.Store, err = store.NewJsonFileStore(platform.CNIRuntimePath+plugin.Name+".json", lockclient, storeLogger)
+		plugin.Store, err = store.NewStore(config.StoreBackend, platform.CNIRuntimePath+plugin.Name+".json", lockclient, storeLogger)
 		if err != nil {
 			logger.Error("Failed to create store", zap.Error(err))
 			return err
@@ -214,6 +280,56 @@ func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error
 	return nil
 }
 
+// StoreBackend returns the state store backend for a CNI command.
+// Commands with a network configuration use the backend it sets. The other commands, like GET_ENDPOINT_STATE,
+// have no configuration and use the bolt backend once its database replaced the json store file.
+func StoreBackend(cmd, jsonFileName string) (string, error) {
+	switch cmd {
+	case CmdAdd, CmdDel, CmdCheck, CmdUpdate, CmdStatus, CmdGC:
+		return StoreBackendFromStdin()
+	}
+
+	return PersistedStoreBackend(jsonFileName), nil
+}
+
+// PersistedStoreBackend returns the bolt backend if its database exists next to the json store file,
+// and the default backend otherwise.
+func PersistedStoreBackend(jsonFileName string) string {
+	if _, err := os.Stat(strings.TrimSuffix(jsonFileName, ".json") + store.BoltExtension); err == nil {
+		return store.BackendBolt
+	}
+	return ""
+}
+
+// StoreBackendFromStdin returns the state store backend set in the network configuration on stdin.
+// The configuration is read ahead of the command handlers, so stdin is replaced with a copy of it.
+func StoreBackendFromStdin() (string, error) {
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading from stdin")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", errors.Wrap(err, "error creating stdin pipe")
+	}
+
+	go func() {
+		_, _ = w.Write(stdinData)
+		w.Close()
+	}()
+
+	os.Stdin = r
+
+	// An invalid configuration is reported by the command handler.
+	nwCfg, err := ParseNetworkConfig(stdinData)
+	if err != nil {
+		return "", nil //nolint:nilerr // the default backend is used
+	}
+
+	return nwCfg.StoreBackend, nil
+}
+
 // Uninitialize key-value store
 func (plugin *Plugin) UninitializeKeyValueStore() error {
 	if plugin.Store != nil {
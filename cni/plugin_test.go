@@ -0,0 +1,180 @@
+package cni
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/Azure/azure-container-networking/store"
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	cniTypesCurr "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPlugin struct {
+	called []string
+}
+
+func (p *recordingPlugin) record(cmd string) error {
+	p.called = append(p.called, cmd)
+	return nil
+}
+
+func (p *recordingPlugin) Add(*cniSkel.CmdArgs) error    { return p.record(CmdAdd) }
+func (p *recordingPlugin) Get(*cniSkel.CmdArgs) error    { return p.record(CmdGet) }
+func (p *recordingPlugin) Delete(*cniSkel.CmdArgs) error { return p.record(CmdDel) }
+func (p *recordingPlugin) Update(*cniSkel.CmdArgs) error { return p.record(CmdUpdate) }
+func (p *recordingPlugin) Check(*cniSkel.CmdArgs) error  { return p.record(CmdCheck) }
+func (p *recordingPlugin) Status(*cniSkel.CmdArgs) error { return p.record(CmdStatus) }
+func (p *recordingPlugin) GC(*cniSkel.CmdArgs) error     { return p.record(CmdGC) }
+
+// setStdin replaces stdin with the given data for the duration of the test.
+func setStdin(t *testing.T, data string) {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+
+	stdin := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		f.Close()
+	})
+}
+
+func TestExecuteVersionNegotiation(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		cniVersion string
+		wantCalled []string
+		wantCode   uint
+	}{
+		{
+			name:       "STATUS with 1.1.0",
+			cmd:        CmdStatus,
+			cniVersion: "1.1.0",
+			wantCalled: []string{CmdStatus},
+		},
+		{
+			name:       "GC with 1.1.0",
+			cmd:        CmdGC,
+			cniVersion: "1.1.0",
+			wantCalled: []string{CmdGC},
+		},
+		{
+			name:       "ADD with 1.1.0",
+			cmd:        CmdAdd,
+			cniVersion: "1.1.0",
+			wantCalled: []string{CmdAdd},
+		},
+		{
+			name:       "ADD with 1.0.0",
+			cmd:        CmdAdd,
+			cniVersion: "1.0.0",
+			wantCalled: []string{CmdAdd},
+		},
+		{
+			name:       "STATUS with 0.4.0",
+			cmd:        CmdStatus,
+			cniVersion: "0.4.0",
+			wantCode:   cniTypes.ErrIncompatibleCNIVersion,
+		},
+		{
+			name:       "GC with 1.0.0",
+			cmd:        CmdGC,
+			cniVersion: "1.0.0",
+			wantCode:   cniTypes.ErrIncompatibleCNIVersion,
+		},
+		{
+			name:       "STATUS with unsupported version",
+			cmd:        CmdStatus,
+			cniVersion: "1.2.0",
+			wantCode:   cniTypes.ErrIncompatibleCNIVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(Cmd, tt.cmd)
+			t.Setenv("CNI_CONTAINERID", "container")
+			t.Setenv("CNI_NETNS", "/var/run/netns/container")
+			t.Setenv("CNI_IFNAME", "eth0")
+			t.Setenv("CNI_PATH", "/opt/cni/bin")
+			setStdin(t, `{"cniVersion": "`+tt.cniVersion+`", "name": "azure", "type": "azure-vnet"}`)
+
+			plugin, err := NewPlugin("test", "v1.0.0")
+			require.NoError(t, err)
+			api := &recordingPlugin{}
+
+			err = plugin.Execute(api)
+			if tt.wantCode != 0 {
+				var cniErr *cniTypes.Error
+				require.ErrorAs(t, err, &cniErr)
+				assert.Equal(t, tt.wantCode, cniErr.Code)
+				assert.Empty(t, api.called)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCalled, api.called)
+		})
+	}
+}
+
+func TestGetResultAsVersion(t *testing.T) {
+	result := &cniTypesCurr.Result{
+		CNIVersion: cniTypesCurr.ImplementedSpecVersion,
+		Interfaces: []*cniTypesCurr.Interface{{Name: "eth0"}},
+	}
+
+	for _, version := range []string{"0.4.0", "1.0.0", "1.1.0"} {
+		res, err := GetResultAsVersion(result, version)
+		require.NoError(t, err)
+		assert.Equal(t, version, res.Version())
+	}
+	assert.Equal(t, cniTypesCurr.ImplementedSpecVersion, result.CNIVersion, "the converted result must be a copy")
+}
+
+func TestStoreBackendAfterMigration(t *testing.T) {
+	jsonFileName := filepath.Join(t.TempDir(), "azure-vnet.json")
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"Network":{"ExternalInterfaces":{"eth0":{"Name":"eth0"}}}}`), 0o600))
+
+	backend, err := StoreBackend(CmdGetEndpointsState, jsonFileName)
+	require.NoError(t, err)
+	assert.Empty(t, backend, "the json store is used until it is migrated")
+
+	// An ADD with the bolt backend migrates the json store file.
+	kvs, err := store.NewStore(store.BackendBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	var state map[string]interface{}
+	require.NoError(t, kvs.Read("Network", &state))
+	require.NoError(t, kvs.Unlock())
+	_, err = os.Stat(jsonFileName)
+	require.True(t, os.IsNotExist(err))
+
+	// GET_ENDPOINT_STATE has no network configuration, so it has to find the migrated state on its own.
+	backend, err = StoreBackend(CmdGetEndpointsState, jsonFileName)
+	require.NoError(t, err)
+	assert.Equal(t, store.BackendBolt, backend)
+
+	kvs, err = store.NewStore(backend, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	state = nil
+	require.NoError(t, kvs.Read("Network", &state))
+	assert.Contains(t, state, "ExternalInterfaces")
+
+	// Commands with a network configuration use the backend it sets.
+	setStdin(t, `{"cniVersion": "1.0.0", "name": "azure", "type": "azure-vnet"}`)
+	backend, err = StoreBackend(CmdAdd, jsonFileName)
+	require.NoError(t, err)
+	assert.Empty(t, backend)
+}
@@ -167,7 +167,15 @@ func main() {
 		GetEnvRetryWaitTimeInSecs:    config.GetEnvRetryWaitTimeInSecs,
 	}
 
-	if tb.CreateAITelemetryHandle(aiConfig, config.DisableAll, config.DisableTrace, config.DisableMetric) != nil {
+	if config.MetricsAddress != "" {
+		telemetry.StartMetricsServer(config.MetricsAddress, logger)
+	}
+
+	if config.TelemetryBackend == aitelemetry.BackendOTLP {
+		if err = tb.CreateOTLPTelemetryHandle(aiConfig, config.OTLPEndpoint, config.DisableAll, config.DisableMetric, config.DisableTrace); err != nil {
+			logger.Error("OTLP Handle creation error", zap.Error(err))
+		}
+	} else if tb.CreateAITelemetryHandle(aiConfig, config.DisableAll, config.DisableTrace, config.DisableMetric) != nil {
 		logger.Error("AI Handle creation error", zap.Error(err))
 	}
 	logger.Info("Report to host interval", zap.Duration("seconds", config.ReportToHostIntervalInSeconds))
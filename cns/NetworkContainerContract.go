@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
@@ -35,6 +36,8 @@ const (
 	PathDebugIPAddresses                     = "/debug/ipaddresses"
 	PathDebugPodContext                      = "/debug/podcontext"
 	PathDebugRestData                        = "/debug/restdata"
+	PathDebugIPLeases                        = "/debug/ipleases"
+	PathIPStateEvents                        = "/network/ipstateevents"
 	NumberOfCPUCores                         = NumberOfCPUCoresPath
 	NMAgentSupportedAPIs                     = NmAgentSupportedApisPath
 	EndpointAPI                              = EndpointPath
@@ -536,6 +539,61 @@ type GetPodContextResponse struct {
 	Response   Response
 }
 
+// IPLease records the IPs last held by a Pod which opted in to sticky IPs, so that it can be given them again
+// if it is recreated on the node before the lease expires. ExpiresAt is unset until the Pod releases the IPs.
+type IPLease struct {
+	PodName      string
+	PodNamespace string
+	IPConfigIDs  []string // Can have multiple Pod IP UUIDs in the case of dualstack
+	IPAddresses  []string
+	ExpiresAt    time.Time
+}
+
+// GetIPLeasesResponse is used in CNS Client debug mode to get the active sticky IP leases
+type GetIPLeasesResponse struct {
+	IPLeases []IPLease
+	Response Response
+}
+
+// IPStateEvent is a transition of a secondary IP from one state to another, streamed by CNS as server-sent
+// events at PathIPStateEvents. The Sequence of each event is one more than that of the event before it, and
+// restarts from 1 when CNS does. PodInfo is the Pod the IP is assigned to, or was assigned to when it is released,
+// and OldState is empty when the IP is added to CNS.
+type IPStateEvent struct {
+	Sequence  uint64
+	ID        string // uuid
+	IPAddress string
+	NCID      string
+	PodInfo   PodInfo
+	OldState  types.IPState
+	NewState  types.IPState
+	Timestamp time.Time
+}
+
+// UnmarshalJSON is a custom unmarshaller for IPStateEvent that unmarshals the PodInfo interface with
+// UnmarshalPodInfo, as IPConfigurationStatus does.
+func (e *IPStateEvent) UnmarshalJSON(b []byte) error {
+	type alias IPStateEvent
+	raw := struct {
+		PodInfo json.RawMessage
+		*alias
+	}{
+		alias: (*alias)(e),
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return errors.Wrap(err, "failed to unmarshal IPStateEvent")
+	}
+	e.PodInfo = nil
+	if len(raw.PodInfo) > 0 && string(raw.PodInfo) != "null" {
+		pi, err := UnmarshalPodInfo(raw.PodInfo)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal key PodInfo to PodInfo")
+		}
+		e.PodInfo = pi
+	}
+	return nil
+}
+
 // IPAddressState Only used in the GetIPConfig API to return IPs that match a filter
 type IPAddressState struct {
 	IPAddress string
@@ -0,0 +1,166 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer authorizes the REST requests and gRPC calls against a policy, and audit logs the denied ones.
+type Authorizer struct {
+	policy *Policy
+}
+
+// New returns an Authorizer for the policy.
+func New(policy *Policy) *Authorizer {
+	return &Authorizer{policy: policy}
+}
+
+// authorize returns whether the identity may call the route, and audit logs it if not.
+func (a *Authorizer) authorize(id Identity, route string, group RouteGroup) bool {
+	caller, allowed := a.policy.Authorize(id, group)
+	authorizedRequests.WithLabelValues(caller, string(group), boolLabel(allowed)).Inc()
+	if !allowed {
+		logger.Printf("[Audit] Denied %s to caller %s (%s), route group %q", route, caller, id, group)
+	}
+	return allowed
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// Middleware wraps the REST API handler, so that the denied requests fail with 403 Forbidden and a StatusUnauthorized
+// response.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		id, err := requestIdentity(r)
+		if err != nil {
+			logger.Printf("[Audit] Denied %s, failed to identify the caller (%s): %v", route, id, err)
+			authorizedRequests.WithLabelValues(AnonymousCaller, string(PathRouteGroup(r.URL.Path)), "false").Inc()
+			deny(w, "failed to identify the caller")
+			return
+		}
+		if !a.authorize(id, route, PathRouteGroup(r.URL.Path)) {
+			deny(w, "the caller is not authorized to call "+r.URL.Path)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func deny(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(cns.Response{ReturnCode: types.StatusUnauthorized, Message: message})
+}
+
+// callIdentity returns the identity of a gRPC call from the auth info of its connection.
+func callIdentity(ctx context.Context) Identity {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}
+	}
+	id := Identity{}
+	if p.Addr != nil {
+		id.Address = p.Addr.String()
+	}
+	switch info := p.AuthInfo.(type) {
+	case credentials.TLSInfo:
+		id.Names = certificateNames(&info.State)
+	case peerAuthInfo:
+		id.Peer = info.peer
+	}
+	return id
+}
+
+func (a *Authorizer) authorizeCall(ctx context.Context, method string) error {
+	if !a.authorize(callIdentity(ctx), method, MethodRouteGroup(method)) {
+		return status.Error(codes.PermissionDenied, "the caller is not authorized to call "+method)
+	}
+	return nil
+}
+
+// UnaryInterceptor fails the denied unary calls with PermissionDenied.
+func (a *Authorizer) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorizeCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor fails the denied streaming calls with PermissionDenied.
+func (a *Authorizer) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorizeCall(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// ServerOptions are the gRPC server options that identify and authorize the calls. With a TLS config, the calls are
+// served over TLS and identified by their client certificates, verified against the client CAs of the config as the
+// HTTPS requests are. Otherwise the calls on unix sockets are identified by the credentials of their peers.
+func (a *Authorizer) ServerOptions(tlsConfig *tls.Config) []grpc.ServerOption {
+	creds := credentials.TransportCredentials(peerCredentialsTransport{})
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(a.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(a.StreamInterceptor),
+	}
+}
+
+// peerAuthInfo is the auth info of a connection with the credentials of its peer, nil if it is not a unix socket.
+type peerAuthInfo struct {
+	credentials.CommonAuthInfo
+	peer *PeerCredentials
+}
+
+func (peerAuthInfo) AuthType() string {
+	return "peercred"
+}
+
+// peerCredentialsTransport is the insecure transport, except that the server reads the credentials of the peers of
+// the unix sockets in the handshake.
+type peerCredentialsTransport struct{}
+
+func (peerCredentialsTransport) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, peerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+}
+
+func (peerCredentialsTransport) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	p, err := peerCredentials(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, peerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}, peer: p}, nil
+}
+
+func (peerCredentialsTransport) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "insecure"}
+}
+
+func (t peerCredentialsTransport) Clone() credentials.TransportCredentials {
+	return t
+}
+
+func (peerCredentialsTransport) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// peerPolicy lets the user of the test call IPAM, and anyone else nothing.
+func peerPolicy() *Policy {
+	//nolint:gosec // the uid is not negative
+	return &Policy{Callers: []Caller{{Name: "cni", UIDs: []uint32{uint32(os.Getuid())}, RouteGroups: []RouteGroup{IPAM}}}}
+}
+
+func TestMiddlewareAuthorizesUnixPeers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cns.sock")
+	listener, err := acn.NewListener(&url.URL{Scheme: "unix", Path: path})
+	require.NoError(t, err)
+	listener.Use(New(peerPolicy()).Middleware)
+	listener.AddHandler(cns.RequestIPConfigs, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	listener.AddHandler(cns.CreateOrUpdateNetworkContainer, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	require.NoError(t, listener.Start(make(chan error, 1)))
+	t.Cleanup(listener.Stop)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	post := func(path string) int {
+		t.Helper()
+		res, err := client.Post("http://cns"+path, "application/json", http.NoBody)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, post(cns.RequestIPConfigs))
+	assert.Equal(t, http.StatusForbidden, post(cns.CreateOrUpdateNetworkContainer))
+}
+
+func TestInterceptorsAuthorizeUnixPeers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cns.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	s := grpc.NewServer(New(peerPolicy()).ServerOptions(nil)...)
+	protos.RegisterCNSServer(s, protos.UnimplementedCNSServer{})
+	go s.Serve(l) //nolint:errcheck // stopped by the test
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := protos.NewCNSClient(conn)
+	ctx := context.Background()
+
+	// allowed calls reach the unimplemented server
+	_, err = client.RequestIPs(ctx, &protos.IPConfigsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = client.DeleteNetworkContainer(ctx, &protos.DeleteNetworkContainerRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.WatchIPStateEvents(ctx, &protos.WatchIPStateEventsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,225 @@
+package authz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("testlogs", 0, 0, os.TempDir()+"/")
+	os.Exit(m.Run())
+}
+
+// testCA is a locally generated CA that issues the test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate for the common name, DNS names and IPs issued by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, dnsNames []string, ips []net.IP, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newMTLSServer serves the handler behind the authorizer, verifying the client certificates against the CA. The clients
+// without a certificate are anonymous.
+func newMTLSServer(t *testing.T, ca *testCA, handler http.Handler) *httptest.Server {
+	t.Helper()
+	s := httptest.NewUnstartedServer(New(testPolicy).Middleware(handler))
+	s.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{ca.issue(t, "cns", nil, []net.IP{net.IPv4(127, 0, 0, 1)}, x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newMTLSClient(ca *testCA, certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: ca.pool, Certificates: certs},
+		},
+	}
+}
+
+func TestMiddlewareAuthorizesClientCertificates(t *testing.T) {
+	ca := newTestCA(t, "cns-ca")
+	served := 0
+	s := newMTLSServer(t, ca, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		served++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	post := func(client *http.Client, path string) *http.Response {
+		t.Helper()
+		res, err := client.Post(s.URL+path, "application/json", http.NoBody)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	assertDenied := func(res *http.Response) {
+		t.Helper()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		var body cns.Response
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, types.StatusUnauthorized, body.ReturnCode)
+	}
+
+	// the common name identifies DNC
+	dnc := newMTLSClient(ca, ca.issue(t, "dnc.azure.com", nil, nil, x509.ExtKeyUsageClientAuth))
+	assert.Equal(t, http.StatusOK, post(dnc, cns.CreateOrUpdateNetworkContainer).StatusCode)
+	assert.Equal(t, http.StatusOK, post(dnc, cns.V2Prefix+cns.SetOrchestratorType).StatusCode)
+	assertDenied(post(dnc, cns.RequestIPConfigs))
+	assertDenied(post(dnc, "/unknown"))
+
+	// so does a DNS name
+	dncSAN := newMTLSClient(ca, ca.issue(t, "other", []string{"dnc.azure.com"}, nil, x509.ExtKeyUsageClientAuth))
+	assert.Equal(t, http.StatusOK, post(dncSAN, cns.DeleteNetworkContainer).StatusCode)
+
+	// a caller without a certificate, or with an unknown one, is anonymous
+	anonymous := newMTLSClient(ca)
+	assert.Equal(t, http.StatusOK, post(anonymous, cns.GetHomeAz).StatusCode)
+	assertDenied(post(anonymous, cns.CreateOrUpdateNetworkContainer))
+	unknown := newMTLSClient(ca, ca.issue(t, "someone", nil, nil, x509.ExtKeyUsageClientAuth))
+	assertDenied(post(unknown, cns.DeleteNetworkContainer))
+
+	assert.Equal(t, 4, served)
+}
+
+func TestUnverifiedCertificatesAreRejected(t *testing.T) {
+	ca := newTestCA(t, "cns-ca")
+	s := newMTLSServer(t, ca, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// a certificate with the name of DNC from another CA, sent even though the server asks for the ones from its CA
+	other := newTestCA(t, "other-ca")
+	cert := other.issue(t, "dnc.azure.com", nil, nil, x509.ExtKeyUsageClientAuth)
+	client := newMTLSClient(ca)
+	client.Transport.(*http.Transport).TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &cert, nil
+	}
+	res, err := client.Post(s.URL+cns.CreateOrUpdateNetworkContainer, "application/json", http.NoBody)
+	if err == nil {
+		res.Body.Close()
+	}
+	require.Error(t, err)
+}
+
+func TestInterceptorsAuthorizeClientCertificates(t *testing.T) {
+	ca := newTestCA(t, "cns-ca")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(New(testPolicy).ServerOptions(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{ca.issue(t, "cns", nil, []net.IP{net.IPv4(127, 0, 0, 1)}, x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	})...)
+	protos.RegisterCNSServer(s, protos.UnimplementedCNSServer{})
+	go s.Serve(l) //nolint:errcheck // stopped by the test
+	t.Cleanup(s.Stop)
+
+	dial := func(cert *tls.Certificate) protos.CNSClient {
+		t.Helper()
+		config := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: ca.pool}
+		if cert != nil {
+			// sent even when the server asks for certificates from other CAs
+			config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return cert, nil
+			}
+		}
+		conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(config)))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return protos.NewCNSClient(conn)
+	}
+	ctx := context.Background()
+
+	// the common name identifies DNC, whose allowed calls reach the unimplemented server
+	dncCert := ca.issue(t, "dnc.azure.com", nil, nil, x509.ExtKeyUsageClientAuth)
+	dnc := dial(&dncCert)
+	_, err = dnc.DeleteNetworkContainer(ctx, &protos.DeleteNetworkContainerRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = dnc.RequestIPs(ctx, &protos.IPConfigsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// a caller without a certificate is anonymous
+	anonymous := dial(nil)
+	_, err = anonymous.DeleteNetworkContainer(ctx, &protos.DeleteNetworkContainerRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// a certificate from another CA fails the handshake
+	other := newTestCA(t, "other-ca")
+	cert := other.issue(t, "dnc.azure.com", nil, nil, x509.ExtKeyUsageClientAuth)
+	unverified := dial(&cert)
+	_, err = unverified.DeleteNetworkContainer(ctx, &protos.DeleteNetworkContainerRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,78 @@
+package authz
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+// PeerCredentials are the credentials of the process on the other end of a unix socket.
+type PeerCredentials struct {
+	UID uint32
+	GID uint32
+	PID int32
+}
+
+// Identity is who a request is from, as far as it can be verified.
+type Identity struct {
+	// Names are the subject common name and DNS names of the verified client certificate.
+	Names []string
+	// Peer are the credentials of the process on the other end of a unix socket.
+	Peer *PeerCredentials
+	// Address is the remote address of the request, for the audit log.
+	Address string
+}
+
+func (id Identity) String() string {
+	var parts []string
+	if len(id.Names) > 0 {
+		parts = append(parts, "names="+strings.Join(id.Names, ","))
+	}
+	if id.Peer != nil {
+		parts = append(parts, fmt.Sprintf("uid=%d gid=%d pid=%d", id.Peer.UID, id.Peer.GID, id.Peer.PID))
+	}
+	if id.Address != "" {
+		parts = append(parts, "address="+id.Address)
+	}
+	return strings.Join(parts, " ")
+}
+
+// certificateNames returns the subject common name and DNS names of the leaf of the first verified chain, which is
+// only set if the client certificate was verified against the client CAs.
+func certificateNames(state *tls.ConnectionState) []string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := state.VerifiedChains[0][0]
+	var names []string
+	if leaf.Subject.CommonName != "" {
+		names = append(names, leaf.Subject.CommonName)
+	}
+	return append(names, leaf.DNSNames...)
+}
+
+// peerCredentials returns the credentials of the peer if the connection is a unix socket, or nil.
+func peerCredentials(conn net.Conn) (*PeerCredentials, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, nil
+	}
+	return unixPeerCredentials(uc)
+}
+
+// requestIdentity returns the identity of an HTTP request from its TLS state or its unix socket.
+func requestIdentity(r *http.Request) (Identity, error) {
+	id := Identity{Names: certificateNames(r.TLS), Address: r.RemoteAddr}
+	if conn, ok := acn.ConnFromContext(r.Context()); ok {
+		peer, err := peerCredentials(conn)
+		if err != nil {
+			return id, err
+		}
+		id.Peer = peer
+	}
+	return id, nil
+}
@@ -0,0 +1,20 @@
+package authz
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var authorizedRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "authz_requests_total",
+		Help: "Number of REST requests and gRPC calls authorized, by caller, route group and whether they were allowed",
+	},
+	[]string{"caller", "route_group", "allowed"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		authorizedRequests,
+	)
+}
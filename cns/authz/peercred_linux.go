@@ -0,0 +1,27 @@
+package authz
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// unixPeerCredentials returns the credentials of the peer of the unix socket with SO_PEERCRED.
+func unixPeerCredentials(conn *net.UnixConn) (*PeerCredentials, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get raw connection")
+	}
+	var ucred *unix.Ucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, sockErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to control raw connection")
+	}
+	if sockErr != nil {
+		return nil, errors.Wrap(sockErr, "failed to get peer credentials")
+	}
+	return &PeerCredentials{UID: ucred.Uid, GID: ucred.Gid, PID: ucred.Pid}, nil
+}
@@ -0,0 +1,11 @@
+package authz
+
+import (
+	"net"
+)
+
+// unixPeerCredentials returns no credentials on Windows, which has no SO_PEERCRED, so that the callers on unix sockets
+// are anonymous unless they are identified by their client certificates.
+func unixPeerCredentials(*net.UnixConn) (*PeerCredentials, error) {
+	return nil, nil
+}
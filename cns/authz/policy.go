@@ -0,0 +1,139 @@
+// Package authz authorizes the callers of the CNS REST and gRPC APIs. A caller is identified by the verified
+// certificate it presented over mTLS or by the credentials of its process over a unix socket, and the policy file maps
+// the identities to the route groups they may call.
+package authz
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// RouteGroup is a group of related REST paths and gRPC methods that are authorized together.
+type RouteGroup string
+
+const (
+	// IPAM assigns and releases Pod IPs, the calls of the CNI.
+	IPAM RouteGroup = "ipam"
+	// IPAMRead reads the state of the IPs.
+	IPAMRead RouteGroup = "ipam-read"
+	// NetworkContainers creates, updates and deletes NCs and sets the orchestrator type, the calls of DNC.
+	NetworkContainers RouteGroup = "networkcontainers"
+	// NetworkContainersRead reads the NCs.
+	NetworkContainersRead RouteGroup = "networkcontainers-read"
+	// Network creates and deletes the host networks.
+	Network RouteGroup = "network"
+	// Endpoints reads and updates the state of the endpoints and their host NC APIPA endpoints.
+	Endpoints RouteGroup = "endpoints"
+	// Node reads the node information, such as its CPU cores, home AZ and NMAgent APIs.
+	Node RouteGroup = "node"
+	// Debug reads the debug state and the pprof profiles.
+	Debug RouteGroup = "debug"
+	// AllRouteGroups grants every route group.
+	AllRouteGroups RouteGroup = "*"
+)
+
+// RouteGroups are the route groups that can be granted in a policy.
+var RouteGroups = []RouteGroup{IPAM, IPAMRead, NetworkContainers, NetworkContainersRead, Network, Endpoints, Node, Debug}
+
+// AnonymousCaller is the name of the callers that match no caller of the policy.
+const AnonymousCaller = "anonymous"
+
+// Caller is an identity, such as the CNI binary, DNC or a debug tool, and the route groups it may call.
+// A request is from the caller if it matches any of the names, UIDs or GIDs.
+type Caller struct {
+	Name string
+	// CommonNames are matched against the subject common name and DNS names of the verified client certificate.
+	CommonNames []string
+	// UIDs are matched against the user of the process on the other end of a unix socket.
+	UIDs []uint32
+	// GIDs are matched against the group of the process on the other end of a unix socket.
+	GIDs        []uint32
+	RouteGroups []RouteGroup
+}
+
+// Policy maps the callers to the route groups they may call.
+type Policy struct {
+	// Callers are matched in order, and the first one that matches a request is its caller.
+	Callers []Caller
+	// AnonymousRouteGroups are the route groups that the requests matching no caller may call.
+	AnonymousRouteGroups []RouteGroup
+}
+
+// LoadPolicy reads and validates the JSON policy file at the path.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read policy file")
+	}
+	p := &Policy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse policy file %s", path)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", path)
+	}
+	return p, nil
+}
+
+// Validate checks that the callers are named and identifiable, and that only known route groups are granted.
+func (p *Policy) Validate() error {
+	names := map[string]bool{}
+	for i := range p.Callers {
+		c := &p.Callers[i]
+		if c.Name == "" || c.Name == AnonymousCaller {
+			return errors.Errorf("caller %d has an empty or reserved name %q", i, c.Name)
+		}
+		if names[c.Name] {
+			return errors.Errorf("caller %s is defined more than once", c.Name)
+		}
+		names[c.Name] = true
+		if len(c.CommonNames) == 0 && len(c.UIDs) == 0 && len(c.GIDs) == 0 {
+			return errors.Errorf("caller %s has no common names, UIDs or GIDs to match", c.Name)
+		}
+		if err := validateRouteGroups(c.RouteGroups); err != nil {
+			return errors.Wrapf(err, "caller %s", c.Name)
+		}
+	}
+	return errors.Wrap(validateRouteGroups(p.AnonymousRouteGroups), "anonymous callers")
+}
+
+func validateRouteGroups(groups []RouteGroup) error {
+	for _, g := range groups {
+		if g != AllRouteGroups && !slices.Contains(RouteGroups, g) {
+			return errors.Errorf("unknown route group %q", g)
+		}
+	}
+	return nil
+}
+
+// matches returns whether the identity is the caller's.
+func (c *Caller) matches(id Identity) bool {
+	for _, name := range id.Names {
+		if slices.Contains(c.CommonNames, name) {
+			return true
+		}
+	}
+	if id.Peer == nil {
+		return false
+	}
+	return slices.Contains(c.UIDs, id.Peer.UID) || slices.Contains(c.GIDs, id.Peer.GID)
+}
+
+// Authorize returns the name of the caller with the identity, and whether it may call the route group.
+// Routes outside every group are never authorized.
+func (p *Policy) Authorize(id Identity, group RouteGroup) (caller string, allowed bool) {
+	caller, groups := AnonymousCaller, p.AnonymousRouteGroups
+	for i := range p.Callers {
+		if p.Callers[i].matches(id) {
+			caller, groups = p.Callers[i].Name, p.Callers[i].RouteGroups
+			break
+		}
+	}
+	if group == "" {
+		return caller, false
+	}
+	return caller, slices.Contains(groups, AllRouteGroups) || slices.Contains(groups, group)
+}
@@ -0,0 +1,183 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = &Policy{
+	Callers: []Caller{
+		{Name: "dnc", CommonNames: []string{"dnc.azure.com"}, RouteGroups: []RouteGroup{NetworkContainers, NetworkContainersRead}},
+		{Name: "cni", UIDs: []uint32{0}, RouteGroups: []RouteGroup{IPAM, Endpoints, NetworkContainersRead}},
+		{Name: "debug", GIDs: []uint32{1000}, RouteGroups: []RouteGroup{AllRouteGroups}},
+	},
+	AnonymousRouteGroups: []RouteGroup{Node},
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		want    *Policy
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			policy: `{
+				"Callers": [
+					{"Name": "dnc", "CommonNames": ["dnc.azure.com"], "RouteGroups": ["networkcontainers", "networkcontainers-read"]},
+					{"Name": "cni", "UIDs": [0], "RouteGroups": ["ipam", "endpoints", "networkcontainers-read"]},
+					{"Name": "debug", "GIDs": [1000], "RouteGroups": ["*"]}
+				],
+				"AnonymousRouteGroups": ["node"]
+			}`,
+			want: testPolicy,
+		},
+		{
+			name:    "unknown route group",
+			policy:  `{"Callers": [{"Name": "cni", "UIDs": [0], "RouteGroups": ["ipam", "everything"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown anonymous route group",
+			policy:  `{"AnonymousRouteGroups": ["everything"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "caller without identities",
+			policy:  `{"Callers": [{"Name": "cni", "RouteGroups": ["ipam"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "reserved caller name",
+			policy:  `{"Callers": [{"Name": "anonymous", "UIDs": [0]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate caller",
+			policy:  `{"Callers": [{"Name": "cni", "UIDs": [0]}, {"Name": "cni", "GIDs": [0]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			policy:  `{"Callers": {}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.policy), 0o600))
+			got, err := LoadPolicy(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          Identity
+		group       RouteGroup
+		wantCaller  string
+		wantAllowed bool
+	}{
+		{
+			name:        "certificate name",
+			id:          Identity{Names: []string{"dnc.azure.com"}},
+			group:       NetworkContainers,
+			wantCaller:  "dnc",
+			wantAllowed: true,
+		},
+		{
+			name:       "certificate name without the group",
+			id:         Identity{Names: []string{"dnc.azure.com"}},
+			group:      IPAM,
+			wantCaller: "dnc",
+		},
+		{
+			name:        "peer uid",
+			id:          Identity{Peer: &PeerCredentials{UID: 0, GID: 0}},
+			group:       IPAM,
+			wantCaller:  "cni",
+			wantAllowed: true,
+		},
+		{
+			name:       "peer uid without the group",
+			id:         Identity{Peer: &PeerCredentials{UID: 0, GID: 0}},
+			group:      NetworkContainers,
+			wantCaller: "cni",
+		},
+		{
+			name:        "peer gid with every group",
+			id:          Identity{Peer: &PeerCredentials{UID: 1000, GID: 1000}},
+			group:       Debug,
+			wantCaller:  "debug",
+			wantAllowed: true,
+		},
+		{
+			name:       "every group but not unknown routes",
+			id:         Identity{Peer: &PeerCredentials{UID: 1000, GID: 1000}},
+			wantCaller: "debug",
+		},
+		{
+			name:        "first matching caller",
+			id:          Identity{Names: []string{"dnc.azure.com"}, Peer: &PeerCredentials{UID: 0}},
+			group:       NetworkContainers,
+			wantCaller:  "dnc",
+			wantAllowed: true,
+		},
+		{
+			name:        "anonymous",
+			id:          Identity{Names: []string{"someone"}, Peer: &PeerCredentials{UID: 1001, GID: 1001}},
+			group:       Node,
+			wantCaller:  AnonymousCaller,
+			wantAllowed: true,
+		},
+		{
+			name:       "anonymous without the group",
+			id:         Identity{Address: "127.0.0.1:1234"},
+			group:      Endpoints,
+			wantCaller: AnonymousCaller,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			caller, allowed := testPolicy.Authorize(tt.id, tt.group)
+			assert.Equal(t, tt.wantCaller, caller)
+			assert.Equal(t, tt.wantAllowed, allowed)
+		})
+	}
+}
+
+func TestRouteGroups(t *testing.T) {
+	assert.Equal(t, NetworkContainers, PathRouteGroup(cns.CreateOrUpdateNetworkContainer))
+	assert.Equal(t, NetworkContainers, PathRouteGroup(cns.V1Prefix+cns.SetOrchestratorType))
+	assert.Equal(t, IPAM, PathRouteGroup(cns.V2Prefix+cns.ReleaseIPAddressPath))
+	assert.Equal(t, Endpoints, PathRouteGroup(cns.EndpointPath+"0123456789abcdef"))
+	assert.Equal(t, Endpoints, PathRouteGroup(cns.V2Prefix+cns.EndpointPath+"0123456789abcdef"))
+	assert.Equal(t, Debug, PathRouteGroup("/debug/pprof/heap"))
+	assert.Equal(t, Debug, PathRouteGroup(cns.PathDebugIPAddresses))
+	assert.Equal(t, RouteGroup(""), PathRouteGroup("/network/unknown"))
+	assert.Equal(t, RouteGroup(""), PathRouteGroup("/"))
+
+	assert.Equal(t, IPAM, MethodRouteGroup(protos.CNS_RequestIPs_FullMethodName))
+	assert.Equal(t, Endpoints, MethodRouteGroup(protos.CNS_UpdateEndpoint_FullMethodName))
+	assert.Equal(t, RouteGroup(""), MethodRouteGroup("/cns.v1.CNS/Unknown"))
+}
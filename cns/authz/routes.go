@@ -0,0 +1,94 @@
+package authz
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+)
+
+// pathRouteGroups are the route groups of the REST paths, without their version prefix.
+var pathRouteGroups = map[string]RouteGroup{
+	cns.RequestIPConfig:      IPAM,
+	cns.RequestIPConfigs:     IPAM,
+	cns.ReleaseIPConfig:      IPAM,
+	cns.ReleaseIPConfigs:     IPAM,
+	cns.ReserveIPAddressPath: IPAM,
+	cns.ReleaseIPAddressPath: IPAM,
+
+	cns.GetHostLocalIPPath:          IPAMRead,
+	cns.GetIPAddressUtilizationPath: IPAMRead,
+	cns.GetUnhealthyIPAddressesPath: IPAMRead,
+	cns.PathIPStateEvents:           IPAMRead,
+
+	cns.CreateOrUpdateNetworkContainer: NetworkContainers,
+	cns.DeleteNetworkContainer:         NetworkContainers,
+	cns.PublishNetworkContainer:        NetworkContainers,
+	cns.UnpublishNetworkContainer:      NetworkContainers,
+	cns.SetOrchestratorType:            NetworkContainers,
+	cns.NetworkContainersURLPath:       NetworkContainers,
+	cns.AttachContainerToNetwork:       NetworkContainers,
+	cns.DetachContainerFromNetwork:     NetworkContainers,
+
+	cns.GetNetworkContainerByOrchestratorContext: NetworkContainersRead,
+	cns.GetAllNetworkContainers:                  NetworkContainersRead,
+	cns.GetInterfaceForContainer:                 NetworkContainersRead,
+
+	cns.SetEnvironmentPath:   Network,
+	cns.CreateNetworkPath:    Network,
+	cns.DeleteNetworkPath:    Network,
+	cns.CreateHnsNetworkPath: Network,
+	cns.DeleteHnsNetworkPath: Network,
+
+	cns.CreateHostNCApipaEndpointPath: Endpoints,
+	cns.DeleteHostNCApipaEndpointPath: Endpoints,
+
+	cns.NumberOfCPUCoresPath:     Node,
+	cns.NmAgentSupportedApisPath: Node,
+	cns.GetHomeAz:                Node,
+	cns.GetHealthReportPath:      Node,
+}
+
+// pathPrefixRouteGroups are the route groups of the REST paths under a prefix.
+var pathPrefixRouteGroups = map[string]RouteGroup{
+	cns.EndpointPath: Endpoints,
+	"/debug/":        Debug,
+}
+
+// methodRouteGroups are the route groups of the gRPC methods.
+var methodRouteGroups = map[string]RouteGroup{
+	protos.CNS_RequestIPs_FullMethodName:                     IPAM,
+	protos.CNS_ReleaseIPs_FullMethodName:                     IPAM,
+	protos.CNS_WatchIPStateEvents_FullMethodName:             IPAMRead,
+	protos.CNS_CreateOrUpdateNetworkContainer_FullMethodName: NetworkContainers,
+	protos.CNS_DeleteNetworkContainer_FullMethodName:         NetworkContainers,
+	protos.CNS_GetNetworkContainer_FullMethodName:            NetworkContainersRead,
+	protos.CNS_GetAllNetworkContainers_FullMethodName:        NetworkContainersRead,
+	protos.CNS_GetEndpoint_FullMethodName:                    Endpoints,
+	protos.CNS_UpdateEndpoint_FullMethodName:                 Endpoints,
+}
+
+// PathRouteGroup returns the route group of a REST path, with or without its version prefix, or "" if the path is
+// in no group.
+func PathRouteGroup(path string) RouteGroup {
+	for _, prefix := range []string{cns.V1Prefix, cns.V2Prefix} {
+		if p, ok := strings.CutPrefix(path, prefix); ok {
+			path = p
+			break
+		}
+	}
+	if g, ok := pathRouteGroups[path]; ok {
+		return g
+	}
+	for prefix, g := range pathPrefixRouteGroups {
+		if strings.HasPrefix(path, prefix) {
+			return g
+		}
+	}
+	return ""
+}
+
+// MethodRouteGroup returns the route group of a gRPC full method name, or "" if the method is in no group.
+func MethodRouteGroup(method string) RouteGroup {
+	return methodRouteGroups[method]
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,6 +9,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -36,11 +39,14 @@ var clientPaths = []string{
 	cns.PathDebugIPAddresses,
 	cns.PathDebugPodContext,
 	cns.PathDebugRestData,
+	cns.PathDebugIPLeases,
+	cns.PathIPStateEvents,
 	cns.UnpublishNetworkContainer,
 	cns.PublishNetworkContainer,
 	cns.CreateOrUpdateNetworkContainer,
 	cns.SetOrchestratorType,
 	cns.NumberOfCPUCores,
+	cns.GetHealthReportPath,
 	cns.NMAgentSupportedAPIs,
 	cns.DeleteNetworkContainer,
 	cns.NetworkContainersURLPath,
@@ -562,6 +568,107 @@ func (c *Client) GetPodOrchestratorContext(ctx context.Context) (map[string][]st
 	return resp.PodContext, nil
 }
 
+// GetIPLeases returns the sticky IP leases which have not expired.
+func (c *Client) GetIPLeases(ctx context.Context) ([]cns.IPLease, error) {
+	u := c.routes[cns.PathDebugIPLeases]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build request")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	var resp cns.GetIPLeasesResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode GetIPLeasesResponse")
+	}
+
+	if resp.Response.ReturnCode != 0 {
+		return nil, errors.New(resp.Response.Message)
+	}
+
+	return resp.IPLeases, nil
+}
+
+// ErrIPStateEventsLost is returned by WatchIPStateEvents when CNS no longer has the events after the sequence, or
+// never had them because it restarted. The caller should list the IPs again and watch from the next event.
+var ErrIPStateEventsLost = errors.New("IP state events after the sequence are not available")
+
+// WatchIPStateEvents streams the IP state transitions from CNS and calls fn with each of them in order. It resumes
+// after the event with the sequence since, or starts from the next event if since is 0. It returns when the
+// context is done, fn returns an error, or the stream ends, in which case it can be called again with the
+// Sequence of the last event handled to resume without missing any.
+func (c *Client) WatchIPStateEvents(ctx context.Context, since uint64, fn func(cns.IPStateEvent) error) error {
+	u := c.routes[cns.PathIPStateEvents]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if since > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(since, 10))
+	}
+
+	// the stream lasts longer than the request timeout of the client.
+	cli := c.client
+	if hc, ok := cli.(*http.Client); ok {
+		stream := *hc
+		stream.Timeout = 0
+		cli = &stream
+	}
+	res, err := cli.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "http request failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusGone {
+		return errors.Wrapf(ErrIPStateEventsLost, "sequence %d", since)
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("http response %d", res.StatusCode)
+	}
+
+	s := bufio.NewScanner(res.Body)
+	s.Buffer(nil, 1<<20) //nolint:gomnd // 1MiB is far larger than any event
+	var data []string
+	for s.Scan() {
+		line := s.Text()
+		if line != "" {
+			// only the data of each event is read, its id is also the Sequence in the data.
+			if field, value, _ := strings.Cut(line, ":"); field == "data" {
+				data = append(data, strings.TrimPrefix(value, " "))
+			}
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		var event cns.IPStateEvent
+		if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+			return errors.Wrap(err, "failed to decode IPStateEvent")
+		}
+		data = data[:0]
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck // the caller's own error
+	}
+	if err := s.Err(); err != nil {
+		return errors.Wrap(err, "failed to read IP state events")
+	}
+	return errors.New("IP state event stream ended")
+}
+
 // GetHTTPServiceData gets all public in-memory struct details for debugging purpose
 func (c *Client) GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPServiceDataResponse, error) {
 	u := c.routes[cns.PathDebugRestData]
@@ -632,6 +739,43 @@ func (c *Client) NumOfCPUCores(ctx context.Context) (*cns.NumOfCPUCoresResponse,
 	return &out, nil
 }
 
+// GetHealthReport checks that CNS is up and serving requests.
+func (c *Client) GetHealthReport(ctx context.Context) error {
+	// build the request
+	u := c.routes[cns.GetHealthReportPath]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "building http request")
+	}
+
+	// submit the request
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &ConnectionFailureErr{cause: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("http response %d", resp.StatusCode)
+	}
+
+	// decode the response
+	var out cns.Response
+	err = json.NewDecoder(resp.Body).Decode(&out)
+	if err != nil {
+		return errors.Wrap(err, "decoding response as JSON")
+	}
+
+	if out.ReturnCode != 0 {
+		return &CNSClientError{
+			Code: out.ReturnCode,
+			Err:  errors.New(out.Message),
+		}
+	}
+
+	return nil
+}
+
 // DeleteNetworkContainer destroys the requested network container matching the
 // provided ID.
 func (c *Client) DeleteNetworkContainer(ctx context.Context, ncID string) error {
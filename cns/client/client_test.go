@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"sort"
@@ -2285,6 +2286,128 @@ func TestGetPodOrchestratorContext(t *testing.T) {
 	}
 }
 
+func TestGetIPLeases(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	leases := []cns.IPLease{{PodName: "web-0", PodNamespace: "default", IPAddresses: []string{"10.0.0.4"}}}
+	tests := []struct {
+		name    string
+		mockdo  *mockdo
+		want    []cns.IPLease
+		wantErr bool
+	}{
+		{
+			name: "happy case",
+			mockdo: &mockdo{
+				objToReturn:            &cns.GetIPLeasesResponse{IPLeases: leases},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			want: leases,
+		},
+		{
+			name: "bad request",
+			mockdo: &mockdo{
+				errToReturn:            errBadRequest,
+				httpStatusCodeToReturn: http.StatusBadRequest,
+			},
+			wantErr: true,
+		},
+		{
+			name: "http status not ok",
+			mockdo: &mockdo{
+				httpStatusCodeToReturn: http.StatusInternalServerError,
+			},
+			wantErr: true,
+		},
+		{
+			name: "cns return code not zero",
+			mockdo: &mockdo{
+				objToReturn: &cns.GetIPLeasesResponse{
+					Response: cns.Response{
+						ReturnCode: types.UnexpectedError,
+					},
+				},
+				httpStatusCodeToReturn: http.StatusOK,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				client: tt.mockdo,
+				routes: emptyRoutes,
+			}
+			got, err := client.GetIPLeases(context.TODO())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWatchIPStateEvents(t *testing.T) {
+	podInfo := cns.NewPodInfo("", "", "web-0", "default")
+	events := []cns.IPStateEvent{
+		{Sequence: 5, ID: "a", IPAddress: "10.0.0.4", NCID: "nc", PodInfo: podInfo, OldState: types.Available, NewState: types.Assigned},
+		{Sequence: 6, ID: "a", IPAddress: "10.0.0.4", NCID: "nc", PodInfo: podInfo, OldState: types.Assigned, NewState: types.Available},
+		{Sequence: 7, ID: "b", IPAddress: "10.0.0.5", NCID: "nc", OldState: types.PendingProgramming, NewState: types.Available},
+	}
+	var lastEventID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventID = r.Header.Get("Last-Event-ID")
+		if lastEventID == "1" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keepalive\n\n")
+		for i := range events {
+			b, _ := json.Marshal(&events[i])
+			fmt.Fprintf(w, "id: %d\nevent: ipstate\ndata: %s\n\n", events[i].Sequence, b)
+		}
+	}))
+	defer server.Close()
+	routes, _ := buildRoutes(server.URL, clientPaths)
+	client := &Client{client: &http.Client{Timeout: time.Second}, routes: routes}
+
+	var got []cns.IPStateEvent
+	err := client.WatchIPStateEvents(context.TODO(), 4, func(e cns.IPStateEvent) error {
+		got = append(got, e)
+		return nil
+	})
+	// the stream ends after the events
+	require.Error(t, err)
+	assert.Equal(t, "4", lastEventID)
+	require.Len(t, got, len(events))
+	for i := range events {
+		assert.Equal(t, events[i].Sequence, got[i].Sequence)
+		assert.Equal(t, events[i].NewState, got[i].NewState)
+		if events[i].PodInfo == nil {
+			assert.Nil(t, got[i].PodInfo)
+		} else {
+			assert.True(t, events[i].PodInfo.Equals(got[i].PodInfo))
+		}
+	}
+
+	// the error of the callback stops the watch
+	errStop := errors.New("stop")
+	calls := 0
+	err = client.WatchIPStateEvents(context.TODO(), 0, func(cns.IPStateEvent) error {
+		calls++
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, lastEventID)
+
+	err = client.WatchIPStateEvents(context.TODO(), 1, func(cns.IPStateEvent) error { return nil })
+	require.ErrorIs(t, err, ErrIPStateEventsLost)
+}
+
 func TestGetHTTPServiceData(t *testing.T) {
 	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
 	tests := []struct {
@@ -2446,6 +2569,63 @@ func TestNumberOfCPUCores(t *testing.T) {
 	}
 }
 
+func TestGetHealthReport(t *testing.T) {
+	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
+	tests := []struct {
+		name      string
+		shouldErr bool
+		respCode  int
+		exp       *cns.Response
+	}{
+		{
+			"happy path",
+			false,
+			http.StatusOK,
+			&cns.Response{ReturnCode: 0},
+		},
+		{
+			"not found",
+			true,
+			http.StatusNotFound,
+			nil,
+		},
+		{
+			"unspecified error",
+			true,
+			http.StatusOK,
+			&cns.Response{
+				ReturnCode: types.UnexpectedError,
+				Message:    "unexpected error",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &Client{
+				client: &mockdo{
+					errToReturn:            nil,
+					objToReturn:            test.exp,
+					httpStatusCodeToReturn: test.respCode,
+				},
+				routes: emptyRoutes,
+			}
+
+			err := client.GetHealthReport(context.Background())
+			if err != nil && !test.shouldErr {
+				t.Fatal("unexpected error: err:", err)
+			}
+
+			if err == nil && test.shouldErr {
+				t.Fatal("expected an error but received none")
+			}
+		})
+	}
+}
+
 func TestNMASupportedAPIs(t *testing.T) {
 	emptyRoutes, _ := buildRoutes(defaultBaseURL, clientPaths)
 	tests := []struct {
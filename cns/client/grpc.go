@@ -0,0 +1,337 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultGRPCAddress is the default address of the CNS gRPC API.
+const DefaultGRPCAddress = "localhost:10093"
+
+// GRPCClient is a client of the CNS gRPC API, with the same methods as the Client of the REST API.
+type GRPCClient struct {
+	conn *grpc.ClientConn
+	cns  protos.CNSClient
+}
+
+// NewGRPC returns a new CNS gRPC client for the address, "unix:///path" for a unix socket or "host:port". Calls
+// without a deadline time out after the requestTimeout, except WatchIPStateEvents. The connection is insecure unless
+// transport credentials are passed in the opts.
+func NewGRPC(address string, requestTimeout time.Duration, opts ...grpc.DialOption) (*GRPCClient, error) {
+	if address == "" {
+		address = DefaultGRPCAddress
+	}
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(timeoutInterceptor(requestTimeout)),
+	}, opts...)
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial CNS at %s", address)
+	}
+	return &GRPCClient{
+		conn: conn,
+		cns:  protos.NewCNSClient(conn),
+	}, nil
+}
+
+// NewGRPCFromConn returns a new CNS gRPC client using the connection.
+func NewGRPCFromConn(conn *grpc.ClientConn) *GRPCClient {
+	return &GRPCClient{
+		conn: conn,
+		cns:  protos.NewCNSClient(conn),
+	}
+}
+
+// timeoutInterceptor sets a deadline on the unary calls without one.
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// WaitForReady connects to CNS and waits until the connection is ready. It fails as soon as the connection attempt
+// fails, such as when CNS is not listening, or when ctx is done.
+func (c *GRPCClient) WaitForReady(ctx context.Context) error {
+	c.conn.Connect()
+	for {
+		switch state := c.conn.GetState(); state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return errors.Errorf("connection to CNS at %s is %s", c.conn.Target(), state)
+		default:
+			if !c.conn.WaitForStateChange(ctx, state) {
+				return errors.Wrapf(ctx.Err(), "connection to CNS at %s is %s", c.conn.Target(), state)
+			}
+		}
+	}
+}
+
+// Close closes the connection to CNS.
+func (c *GRPCClient) Close() error {
+	return errors.Wrap(c.conn.Close(), "failed to close connection")
+}
+
+// grpcError converts the error of a call to the errors returned by the Client: UnsupportedAPI if the CNS does not
+// implement the call, and a ConnectionFailureErr if it could not be reached.
+func grpcError(err error) error {
+	switch status.Code(err) {
+	case codes.Unimplemented:
+		return &CNSClientError{
+			Code: types.UnsupportedAPI,
+			Err:  errors.Errorf("Unsupported API"),
+		}
+	case codes.Unavailable:
+		return &ConnectionFailureErr{cause: err}
+	default:
+		return errors.Wrap(err, "grpc request failed")
+	}
+}
+
+// responseError returns a CNSClientError for a failed response.
+func responseError(r *protos.Response) error {
+	if r.GetReturnCode() == 0 {
+		return nil
+	}
+	return &CNSClientError{
+		Code: types.ResponseCode(r.GetReturnCode()),
+		Err:  errors.New(r.GetMessage()),
+	}
+}
+
+// RequestIPs calls the RequestIPs in CNS, and releases the IPs if it fails.
+func (c *GRPCClient) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	var err error
+	defer func() {
+		if err != nil && !IsUnsupportedAPI(err) {
+			if e := c.ReleaseIPs(ctx, ipconfig); e != nil {
+				err = errors.Wrap(e, err.Error())
+			}
+		}
+	}()
+
+	res, err := c.cns.RequestIPs(ctx, protos.FromIPConfigsRequest(ipconfig))
+	if err != nil {
+		err = grpcError(err)
+		return nil, err
+	}
+	if err = responseError(res.GetResponse()); err != nil {
+		return nil, err
+	}
+	return res.ToCNS(), nil
+}
+
+// ReleaseIPs calls the ReleaseIPs in CNS, which releases the IPs of the Pod.
+func (c *GRPCClient) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest) error {
+	res, err := c.cns.ReleaseIPs(ctx, protos.FromIPConfigsRequest(ipconfig))
+	if err != nil {
+		return grpcError(err)
+	}
+	return responseError(res.GetResponse())
+}
+
+// RequestIPAddress requests the IP with RequestIPs, since the gRPC API has no single IP call.
+func (c *GRPCClient) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	res, err := c.RequestIPs(ctx, ipConfigsRequest(ipconfig))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.PodIPInfo) == 0 {
+		return nil, errors.New("no IP was assigned")
+	}
+	return &cns.IPConfigResponse{
+		PodIpInfo: res.PodIPInfo[0],
+		Response:  res.Response,
+	}, nil
+}
+
+// ReleaseIPAddress releases the IP with ReleaseIPs, since the gRPC API has no single IP call.
+func (c *GRPCClient) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequest) error {
+	return c.ReleaseIPs(ctx, ipConfigsRequest(ipconfig))
+}
+
+func ipConfigsRequest(ipconfig cns.IPConfigRequest) cns.IPConfigsRequest {
+	req := cns.IPConfigsRequest{
+		PodInterfaceID:      ipconfig.PodInterfaceID,
+		InfraContainerID:    ipconfig.InfraContainerID,
+		OrchestratorContext: ipconfig.OrchestratorContext,
+		Ifname:              ipconfig.Ifname,
+	}
+	if ipconfig.DesiredIPAddress != "" {
+		req.DesiredIPAddresses = []string{ipconfig.DesiredIPAddress}
+	}
+	return req
+}
+
+// GetNetworkContainer gets the NC of the Pod in the orchestrator context.
+func (c *GRPCClient) GetNetworkContainer(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error) {
+	res, err := c.cns.GetNetworkContainer(ctx, &protos.GetNetworkContainerRequest{OrchestratorContext: orchestratorContext})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := responseError(res.GetResponse()); err != nil {
+		return nil, err
+	}
+	nc := res.ToCNS()
+	return &nc, nil
+}
+
+// GetAllNetworkContainers gets the NCs of the Pod in the orchestrator context.
+func (c *GRPCClient) GetAllNetworkContainers(ctx context.Context, orchestratorContext []byte) ([]cns.GetNetworkContainerResponse, error) {
+	res, err := c.cns.GetAllNetworkContainers(ctx, &protos.GetNetworkContainerRequest{OrchestratorContext: orchestratorContext})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := responseError(res.GetResponse()); err != nil {
+		return nil, err
+	}
+	return res.ToCNS().NetworkContainers, nil
+}
+
+// CreateNetworkContainer creates the NC, or updates it if it exists.
+func (c *GRPCClient) CreateNetworkContainer(ctx context.Context, cncr cns.CreateNetworkContainerRequest) error { //nolint:gocritic // matches Client
+	if cncr.NetworkContainerid == "" {
+		return errors.New("empty request provided")
+	}
+	res, err := c.cns.CreateOrUpdateNetworkContainer(ctx, protos.FromCreateNetworkContainerRequest(&cncr))
+	if err != nil {
+		return grpcError(err)
+	}
+	return responseError(res)
+}
+
+// DeleteNetworkContainer deletes the NC.
+func (c *GRPCClient) DeleteNetworkContainer(ctx context.Context, ncID string) error {
+	if ncID == "" {
+		return errors.New("no network container ID provided")
+	}
+	res, err := c.cns.DeleteNetworkContainer(ctx, &protos.DeleteNetworkContainerRequest{NetworkContainerId: ncID})
+	if err != nil {
+		return grpcError(err)
+	}
+	return responseError(res)
+}
+
+// GetEndpoint gets the state of the endpoint.
+func (c *GRPCClient) GetEndpoint(ctx context.Context, endpointID string) (*restserver.GetEndpointResponse, error) {
+	res, err := c.cns.GetEndpoint(ctx, &protos.GetEndpointRequest{EndpointId: endpointID})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := responseError(res.GetResponse()); err != nil {
+		return nil, err
+	}
+	info, err := endpointInfo(res.GetEndpointInfo())
+	if err != nil {
+		return nil, err
+	}
+	return &restserver.GetEndpointResponse{
+		Response: restserver.Response{
+			ReturnCode: types.ResponseCode(res.GetResponse().GetReturnCode()),
+			Message:    res.GetResponse().GetMessage(),
+		},
+		EndpointInfo: info,
+	}, nil
+}
+
+func endpointInfo(x *protos.EndpointInfo) (restserver.EndpointInfo, error) {
+	info := restserver.EndpointInfo{
+		PodName:       x.GetPodName(),
+		PodNamespace:  x.GetPodNamespace(),
+		HnsEndpointID: x.GetHnsEndpointId(),
+		HostVethName:  x.GetHostVethName(),
+	}
+	if x.GetIfnameToIps() == nil {
+		return info, nil
+	}
+	parse := func(cidrs []string) ([]net.IPNet, error) {
+		var ipnets []net.IPNet
+		for _, cidr := range cidrs {
+			ip, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to parse endpoint IP")
+			}
+			ipnets = append(ipnets, net.IPNet{IP: ip, Mask: ipnet.Mask})
+		}
+		return ipnets, nil
+	}
+	info.IfnameToIPMap = make(map[string]*restserver.IPInfo, len(x.GetIfnameToIps()))
+	for ifname, ips := range x.GetIfnameToIps() {
+		ipv4, err := parse(ips.GetIpv4())
+		if err != nil {
+			return info, err
+		}
+		ipv6, err := parse(ips.GetIpv6())
+		if err != nil {
+			return info, err
+		}
+		info.IfnameToIPMap[ifname] = &restserver.IPInfo{IPv4: ipv4, IPv6: ipv6}
+	}
+	return info, nil
+}
+
+// UpdateEndpoint updates the state of the endpoint with the HNS endpoint ID or host veth name.
+func (c *GRPCClient) UpdateEndpoint(ctx context.Context, endpointID, hnsID, vethName string) (*cns.Response, error) {
+	res, err := c.cns.UpdateEndpoint(ctx, &protos.UpdateEndpointRequest{
+		EndpointId:    endpointID,
+		HnsEndpointId: hnsID,
+		HostVethName:  vethName,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := responseError(res); err != nil {
+		return nil, err
+	}
+	r := res.ToCNS()
+	return &r, nil
+}
+
+// WatchIPStateEvents streams the IP state events after the sequence to fn until the context is done, or fn or the
+// stream fails, as the Client does. It returns ErrIPStateEventsLost if the events after the sequence are not
+// available, or the watch fell behind.
+func (c *GRPCClient) WatchIPStateEvents(ctx context.Context, since uint64, fn func(cns.IPStateEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.cns.WatchIPStateEvents(ctx, &protos.WatchIPStateEventsRequest{Since: since})
+	if err != nil {
+		return grpcError(err)
+	}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err() //nolint:wrapcheck // the caller's context
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("IP state event stream ended")
+			}
+			if status.Code(err) == codes.OutOfRange {
+				return errors.Wrap(ErrIPStateEventsLost, status.Convert(err).Message())
+			}
+			return grpcError(err)
+		}
+		if err := fn(e.ToCNS()); err != nil {
+			return err
+		}
+	}
+}
@@ -53,8 +53,10 @@ func getCmd(ctx context.Context, client *client.Client, arg string) error {
 		states = append(states, types.PendingProgramming)
 	case types.PendingRelease:
 		states = append(states, types.PendingRelease)
+	case types.Cooldown:
+		states = append(states, types.Cooldown)
 	default:
-		states = append(states, types.Assigned, types.Available, types.PendingProgramming, types.PendingRelease)
+		states = append(states, types.Assigned, types.Available, types.PendingProgramming, types.PendingRelease, types.Cooldown)
 	}
 
 	addr, err := client.GetIPAddressesMatchingStates(ctx, states...)
@@ -0,0 +1,247 @@
+// poolsim replays a trace of Pods arriving on and departing from a Node against the CNS IP pool monitors, with an
+// in-memory IP store and a fake DNC, to evaluate NodeNetworkConfig scaler settings and monitor changes without a
+// cluster. It prints a summary of how long Pods waited for IPs and how many IPs were allocated for each monitor, and
+// optionally writes the timeline of the pool and the wait of each Pod as CSV.
+//
+//	poolsim -trace pods.csv -batch 16 -request-threshold 50 -release-threshold 150 -timeline timeline.csv
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns/ipampool/simulation"
+	v2 "github.com/Azure/azure-container-networking/cns/ipampool/v2"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	formatEvents = "events"
+	formatDemand = "demand"
+	formatScript = "script"
+
+	monitorV1  = "v1"
+	monitorV2  = "v2"
+	monitorAll = "all"
+
+	stdio = "-"
+
+	defaultBatch            = 16
+	defaultRequestThreshold = 50
+	defaultReleaseThreshold = 150
+)
+
+type options struct {
+	trace    string
+	format   string
+	monitor  string
+	timeline string
+	waits    string
+	logDir   string
+	cfg      simulation.Config
+	// predictor enables the v2 predictor with predictorOpts.
+	predictor     bool
+	predictorOpts v2.PredictorOptions
+}
+
+func parseFlags(args []string) (*options, error) {
+	o := &options{}
+	fs := flag.NewFlagSet("poolsim", flag.ContinueOnError)
+	fs.StringVar(&o.trace, "trace", "", "trace file to replay, - for stdin (required)")
+	fs.StringVar(&o.format, "format", formatEvents, "trace format: "+formatEvents+" (CSV of seconds,pod,arrive|depart), "+
+		formatDemand+" (CSV of seconds,demand) or "+formatScript+" (lines of '<time> arrive|depart <count>' and 'repeat <every> <times>')")
+	fs.StringVar(&o.monitor, "monitor", monitorAll, "pool monitor to simulate: "+monitorV1+", "+monitorV2+" or "+monitorAll)
+	fs.StringVar(&o.timeline, "timeline", "", "file to write the timeline of the pool to as CSV, - for stdout")
+	fs.StringVar(&o.waits, "waits", "", "file to write how long each pod waited for an IP to as CSV, - for stdout")
+	fs.StringVar(&o.logDir, "log-dir", os.TempDir(), "directory to write the monitor logs to")
+	fs.Int64Var(&o.cfg.Scaler.BatchSize, "batch", defaultBatch, "scaler batch size")
+	fs.Int64Var(&o.cfg.Scaler.RequestThresholdPercent, "request-threshold", defaultRequestThreshold, "scaler request threshold percent")
+	fs.Int64Var(&o.cfg.Scaler.ReleaseThresholdPercent, "release-threshold", defaultReleaseThreshold, "scaler release threshold percent")
+	fs.Int64Var(&o.cfg.Scaler.MaxIPCount, "max", v2.DefaultMaxIPs, "scaler max IP count")
+	fs.Int64Var(&o.cfg.InitialIPs, "initial-ips", 0, "IPs allocated to the node at the start (default the batch size)")
+	fs.DurationVar(&o.cfg.Step, "step", simulation.DefaultStep, "simulated time between monitor iterations")
+	fs.DurationVar(&o.cfg.Latency, "latency", simulation.DefaultLatency, "simulated time for the DNC to apply a NodeNetworkConfig spec")
+	fs.DurationVar(&o.cfg.Duration, "duration", 0, "simulated time to run for (default the end of the trace)")
+	fs.BoolVar(&o.predictor, "predictor", false, "enable the v2 demand predictor")
+	fs.DurationVar(&o.predictorOpts.Window, "predictor-window", v2.DefaultPredictorWindow, "demand history kept by the predictor")
+	fs.DurationVar(&o.predictorOpts.Resolution, "predictor-resolution", v2.DefaultPredictorResolution, "resolution of the demand history")
+	fs.DurationVar(&o.predictorOpts.Lead, "predictor-lead", v2.DefaultPredictorLead, "time ahead the demand is predicted")
+	fs.Float64Var(&o.predictorOpts.MinCorrelation, "predictor-min-correlation", v2.DefaultPredictorMinCorrelation,
+		"correlation the demand must repeat with to be predicted")
+	if err := fs.Parse(args); err != nil {
+		return nil, errors.Wrap(err, "failed to parse flags")
+	}
+	if o.trace == "" {
+		fs.Usage()
+		return nil, errors.New("-trace is required")
+	}
+	switch o.monitor {
+	case monitorV1, monitorV2, monitorAll:
+	default:
+		return nil, errors.Errorf("invalid -monitor %q", o.monitor)
+	}
+	if o.cfg.Scaler.BatchSize <= 0 || o.cfg.Scaler.MaxIPCount <= 0 {
+		return nil, errors.New("-batch and -max must be positive")
+	}
+	if o.predictor {
+		o.cfg.Predictor = &o.predictorOpts
+	}
+	return o, nil
+}
+
+func readTrace(path, format string) (simulation.Trace, error) {
+	var r io.Reader = os.Stdin
+	if path != stdio {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open trace")
+		}
+		defer f.Close()
+		r = f
+	}
+	switch format {
+	case formatEvents:
+		return simulation.ReadEvents(r) //nolint:wrapcheck // already wrapped
+	case formatDemand:
+		samples, err := simulation.ReadDemand(r)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // already wrapped
+		}
+		return simulation.FromDemand(samples), nil
+	case formatScript:
+		return simulation.ReadScript(r) //nolint:wrapcheck // already wrapped
+	default:
+		return nil, errors.Errorf("invalid -format %q", format)
+	}
+}
+
+// result is the result of simulating a monitor.
+type result struct {
+	monitor string
+	*simulation.Result
+}
+
+func run(ctx context.Context, args []string) error {
+	o, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	trace, err := readTrace(o.trace, o.format)
+	if err != nil {
+		return err
+	}
+
+	// the v1 monitor logs to the CNS logger, and the v2 monitor to a zap logger.
+	logger.InitLogger("poolsim-v1", log.LevelInfo, log.TargetLogfile, o.logDir)
+	zcfg := zap.NewProductionConfig()
+	zcfg.OutputPaths = []string{filepath.Join(o.logDir, "poolsim-v2.log")}
+	z, err := zcfg.Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to create logger")
+	}
+	defer z.Sync() //nolint:errcheck // best effort
+	o.cfg.Logger = z
+
+	var results []result
+	if o.monitor == monitorV1 || o.monitor == monitorAll {
+		res, err := simulation.RunV1(ctx, trace, o.cfg)
+		if err != nil {
+			return errors.Wrap(err, "failed to simulate v1 monitor")
+		}
+		results = append(results, result{monitor: monitorV1, Result: res})
+	}
+	if o.monitor == monitorV2 || o.monitor == monitorAll {
+		res, err := simulation.RunV2(ctx, trace, o.cfg)
+		if err != nil {
+			return errors.Wrap(err, "failed to simulate v2 monitor")
+		}
+		results = append(results, result{monitor: monitorV2, Result: res})
+	}
+
+	if err := writeCSV(o.timeline, timelineRecords(results)); err != nil {
+		return errors.Wrap(err, "failed to write timeline")
+	}
+	if err := writeCSV(o.waits, waitRecords(results)); err != nil {
+		return errors.Wrap(err, "failed to write waits")
+	}
+	// the summary goes to stderr when the CSV goes to stdout.
+	summary := os.Stdout
+	if o.timeline == stdio || o.waits == stdio {
+		summary = os.Stderr
+	}
+	for _, r := range results {
+		fmt.Fprintf(summary, "%s: %s\n", r.monitor, r.Result)
+	}
+	fmt.Fprintf(summary, "monitor logs are in %s\n", o.logDir)
+	return nil
+}
+
+func timelineRecords(results []result) [][]string {
+	records := [][]string{{"monitor", "seconds", "demand", "assigned", "waiting", "requested", "allocated", "pending_release"}}
+	for _, r := range results {
+		for _, p := range r.Timeline {
+			records = append(records, []string{
+				r.monitor,
+				strconv.FormatFloat(p.At.Seconds(), 'f', -1, 64),
+				strconv.FormatInt(p.Demand, 10),
+				strconv.FormatInt(p.Assigned, 10),
+				strconv.FormatInt(p.Demand-p.Assigned, 10),
+				strconv.FormatInt(p.Requested, 10),
+				strconv.FormatInt(p.Allocated, 10),
+				strconv.FormatInt(p.PendingRelease, 10),
+			})
+		}
+	}
+	return records
+}
+
+func waitRecords(results []result) [][]string {
+	records := [][]string{{"monitor", "pod", "arrived_seconds", "waited_seconds"}}
+	for _, r := range results {
+		for _, w := range r.Waits {
+			records = append(records, []string{
+				r.monitor,
+				w.Pod,
+				strconv.FormatFloat(w.Arrived.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(w.Waited.Seconds(), 'f', -1, 64),
+			})
+		}
+	}
+	return records
+}
+
+// writeCSV writes the records to the file at path, or to stdout if it is "-". Nothing is written if path is empty.
+func writeCSV(path string, records [][]string) error {
+	if path == "" {
+		return nil
+	}
+	var w io.Writer = os.Stdout
+	if path != stdio {
+		f, err := os.Create(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to create file")
+		}
+		defer f.Close()
+		w = f
+	}
+	return csv.NewWriter(w).WriteAll(records) //nolint:wrapcheck // wrapped by the caller
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1) //nolint:gocritic // exit on error
+	}
+}
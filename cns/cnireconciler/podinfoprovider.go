@@ -27,7 +27,7 @@ func NewCNSPodInfoProvider(endpointStore store.KeyValueStore) (cns.PodInfoByIPPr
 
 func newCNSPodInfoProvider(endpointStore store.KeyValueStore) (cns.PodInfoByIPProvider, error) {
 	var state map[string]*restserver.EndpointInfo
-	err := endpointStore.Read(restserver.EndpointStoreKey, &state)
+	err := store.ReadVersioned(endpointStore, restserver.EndpointStoreKey, &state)
 	if err != nil {
 		if errors.Is(err, store.ErrKeyNotFound) {
 			// Nothing to restore.
@@ -5,6 +5,7 @@ package common
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/Azure/azure-container-networking/cns/logger"
 	acn "github.com/Azure/azure-container-networking/common"
@@ -40,6 +41,8 @@ type ServiceConfig struct {
 	Store       store.KeyValueStore
 	ChannelMode string
 	TlsSettings tls.TlsSettings
+	// Middleware, if set, wraps the handler of the listener created by the service.
+	Middleware func(http.Handler) http.Handler
 }
 
 // NewService creates a new Service object.
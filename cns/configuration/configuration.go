@@ -28,6 +28,7 @@ const (
 type CNSConfig struct {
 	AZRSettings                 AZRSettings
 	AsyncPodDeletePath          string
+	AuthorizationSettings       AuthorizationSettings
 	CNIConflistFilepath         string
 	CNIConflistScenario         string
 	ChannelMode                 string
@@ -38,6 +39,10 @@ type CNSConfig struct {
 	EnableStateMigration        bool
 	EnableSubnetScarcity        bool
 	EnableSwiftV2               bool
+	GRPCSettings                GRPCSettings
+	IPAMv2PredictorSettings     IPAMv2PredictorSettings
+	IPCooldownSecs              int
+	IPRequestQueueSettings      IPRequestQueueSettings
 	InitializeFromCNI           bool
 	KeyVaultSettings            KeyVaultSettings
 	MSISettings                 MSISettings
@@ -47,9 +52,12 @@ type CNSConfig struct {
 	MetricsBindAddress          string
 	ProgramSNATIPTables         bool
 	SWIFTV2Mode                 SWIFTV2Mode
+	StickyIPLeaseSettings       StickyIPLeaseSettings
+	StoreBackend                string
 	SyncHostNCTimeoutMs         int
 	SyncHostNCVersionIntervalMs int
 	TLSCertificatePath          string
+	TLSClientCAPath             string
 	TLSEndpoint                 string
 	TLSPort                     string
 	TLSSubjectName              string
@@ -84,6 +92,10 @@ type TelemetrySettings struct {
 	SnapshotIntervalInMins int
 	// AppInsightsInstrumentationKey allows the user to override the default appinsights ikey
 	AppInsightsInstrumentationKey string
+	// TelemetryBackend selects where telemetry is sent, appinsights (default) or otlp
+	TelemetryBackend string
+	// OTLPEndpoint is the OTLP/HTTP endpoint of the OpenTelemetry collector for the otlp backend
+	OTLPEndpoint string
 }
 
 type ManagedSettings struct {
@@ -97,6 +109,50 @@ type AZRSettings struct {
 	PopulateHomeAzCacheRetryIntervalSecs int
 }
 
+type AuthorizationSettings struct {
+	// Enabled authorizes the callers of the REST and gRPC APIs against the policy file.
+	Enabled bool
+	// PolicyFilePath is the path of the policy file mapping the identities of the callers to the route groups they
+	// may call.
+	PolicyFilePath string
+}
+
+type GRPCSettings struct {
+	// Enabled serves the CNS gRPC API alongside the REST API.
+	Enabled bool
+	// Address is where the gRPC API is served, "unix:///path" for a unix socket or "host:port".
+	Address string
+}
+
+type IPAMv2PredictorSettings struct {
+	// Enabled makes the IPAMv2 pool monitor scale the pool ahead of the demand it predicts from the recent demand.
+	Enabled bool
+	// WindowSecs is how much demand history is kept to look for recurring bursts in.
+	WindowSecs int
+	// ResolutionSecs is the length of the buckets the demand history is kept in.
+	ResolutionSecs int
+	// LeadSecs is how far ahead the demand is predicted.
+	LeadSecs int
+	// MinCorrelation is how closely the demand must repeat, from 0 to 1, for its bursts to be predicted.
+	MinCorrelation float64
+}
+
+type IPRequestQueueSettings struct {
+	// Enabled makes requests for IPs wait for IPs to become Available when the pool is exhausted, instead of failing.
+	Enabled bool
+	// MaxDepth is the number of requests which can wait at once, further requests fail straight away.
+	MaxDepth int
+	// MaxWaitMs is how long a request waits for IPs before failing.
+	MaxWaitMs int
+}
+
+type StickyIPLeaseSettings struct {
+	// Enabled gives the Pods annotated with AnnotationPodStickyIP the IPs they held before when they are recreated on the node.
+	Enabled bool
+	// TTLSecs is how long a lease is kept after the Pod was last given or released its IPs.
+	TTLSecs int
+}
+
 type MSISettings struct {
 	ResourceID string
 }
@@ -195,12 +251,36 @@ func setKeyVaultSettingsDefaults(kvs *KeyVaultSettings) {
 	}
 }
 
+func setIPRequestQueueSettingsDefaults(ipRequestQueueSettings *IPRequestQueueSettings) {
+	if ipRequestQueueSettings.MaxDepth == 0 {
+		ipRequestQueueSettings.MaxDepth = 250 //nolint:gomnd // maxpods
+	}
+	if ipRequestQueueSettings.MaxWaitMs == 0 {
+		ipRequestQueueSettings.MaxWaitMs = 10000 //nolint:gomnd // default times
+	}
+}
+
+func setStickyIPLeaseSettingsDefaults(stickyIPLeaseSettings *StickyIPLeaseSettings) {
+	if stickyIPLeaseSettings.TTLSecs == 0 {
+		stickyIPLeaseSettings.TTLSecs = 600 //nolint:gomnd // default times
+	}
+}
+
+func setGRPCSettingsDefaults(grpcSettings *GRPCSettings) {
+	if grpcSettings.Address == "" {
+		grpcSettings.Address = "localhost:10093"
+	}
+}
+
 // SetCNSConfigDefaults set default values of CNS config if not specified
 func SetCNSConfigDefaults(config *CNSConfig) {
 	setTelemetrySettingDefaults(&config.TelemetrySettings)
 	setManagedSettingDefaults(&config.ManagedSettings)
 	setKeyVaultSettingsDefaults(&config.KeyVaultSettings)
 	setAZRSettingsDefaults(&config.AZRSettings)
+	setIPRequestQueueSettingsDefaults(&config.IPRequestQueueSettings)
+	setStickyIPLeaseSettingsDefaults(&config.StickyIPLeaseSettings)
+	setGRPCSettingsDefaults(&config.GRPCSettings)
 
 	if config.ChannelMode == "" {
 		config.ChannelMode = cns.Direct
@@ -212,6 +212,16 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 60,
 				},
+				IPRequestQueueSettings: IPRequestQueueSettings{
+					MaxDepth:  250,
+					MaxWaitMs: 10000,
+				},
+				StickyIPLeaseSettings: StickyIPLeaseSettings{
+					TTLSecs: 600,
+				},
+				GRPCSettings: GRPCSettings{
+					Address: "localhost:10093",
+				},
 				WireserverIP:       "168.63.129.16",
 				AsyncPodDeletePath: "/var/run/azure-vnet/deleteIDs",
 			},
@@ -239,6 +249,17 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 10,
 				},
+				IPRequestQueueSettings: IPRequestQueueSettings{
+					MaxDepth:  5,
+					MaxWaitMs: 100,
+				},
+				StickyIPLeaseSettings: StickyIPLeaseSettings{
+					TTLSecs: 60,
+				},
+				GRPCSettings: GRPCSettings{
+					Enabled: true,
+					Address: "unix:///var/run/azure-cns/cns.sock",
+				},
 			},
 			want: CNSConfig{
 				ChannelMode: "Other",
@@ -261,6 +282,17 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 10,
 				},
+				IPRequestQueueSettings: IPRequestQueueSettings{
+					MaxDepth:  5,
+					MaxWaitMs: 100,
+				},
+				StickyIPLeaseSettings: StickyIPLeaseSettings{
+					TTLSecs: 60,
+				},
+				GRPCSettings: GRPCSettings{
+					Enabled: true,
+					Address: "unix:///var/run/azure-cns/cns.sock",
+				},
 				WireserverIP:       "168.63.129.16",
 				AsyncPodDeletePath: "/var/run/azure-vnet/deleteIDs",
 			},
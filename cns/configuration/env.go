@@ -18,6 +18,8 @@ const (
 	EnvPodCIDRs       = "POD_CIDRs"
 	EnvServiceCIDRs   = "SERVICE_CIDRs"
 	EnvInfraVNETCIDRs = "INFRA_VNET_CIDRs"
+	// AnnotationPodStickyIP is the Pod annotation which opts the Pod in to sticky IP leases when set to "true"
+	AnnotationPodStickyIP = "kubernetes.azure.com/sticky-ip"
 )
 
 // ErrNodeNameUnset indicates the the $EnvNodeName variable is unset in the environment.
@@ -141,7 +141,8 @@ func (ipm *IPStateManager) MarkIPAsPendingRelease(numberOfIPsToMark int) (map[st
 	}()
 
 	for i := 0; i < numberOfIPsToMark; i++ {
-		id, err := ipm.AvailableIPIDStack.Pop()
+		var id string
+		id, err = ipm.AvailableIPIDStack.Pop()
 		if err != nil {
 			return ipm.PendingReleaseIPConfigState, err
 		}
@@ -16,6 +16,8 @@ var (
 	StatePendingProgramming = ipConfigStatePredicate(types.PendingProgramming)
 	// StatePendingRelease is a preset filter for types.PendingRelease.
 	StatePendingRelease = ipConfigStatePredicate(types.PendingRelease)
+	// StateCooldown is a preset filter for types.Cooldown.
+	StateCooldown = ipConfigStatePredicate(types.Cooldown)
 )
 
 var filters = map[types.IPState]IPConfigStatePredicate{
@@ -23,6 +25,7 @@ var filters = map[types.IPState]IPConfigStatePredicate{
 	types.Available:          StateAvailable,
 	types.PendingProgramming: StatePendingProgramming,
 	types.PendingRelease:     StatePendingRelease,
+	types.Cooldown:           StateCooldown,
 }
 
 // ipConfigStatePredicate returns a predicate function that compares an IPConfigurationStatus.State to
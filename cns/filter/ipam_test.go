@@ -37,6 +37,12 @@ var testStatuses = []struct {
 			ID: "pending-release",
 		},
 	},
+	{
+		State: types.Cooldown,
+		Status: cns.IPConfigurationStatus{
+			ID: "cooldown",
+		},
+	},
 }
 
 func TestMatchesAnyIPConfigState(t *testing.T) {
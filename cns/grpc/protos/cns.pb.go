@@ -0,0 +1,2603 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        v3.19.1
+// source: cns.proto
+
+package protos
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Response is the outcome of a call, return_code is a CNS ResponseCode.
+type Response struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReturnCode int32  `protobuf:"varint,1,opt,name=return_code,json=returnCode,proto3" json:"return_code,omitempty"`
+	Message    string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Response) GetReturnCode() int32 {
+	if x != nil {
+		return x.ReturnCode
+	}
+	return 0
+}
+
+func (x *Response) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type IPConfigsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DesiredIpAddresses []string `protobuf:"bytes,1,rep,name=desired_ip_addresses,json=desiredIpAddresses,proto3" json:"desired_ip_addresses,omitempty"`
+	PodInterfaceId     string   `protobuf:"bytes,2,opt,name=pod_interface_id,json=podInterfaceId,proto3" json:"pod_interface_id,omitempty"`
+	InfraContainerId   string   `protobuf:"bytes,3,opt,name=infra_container_id,json=infraContainerId,proto3" json:"infra_container_id,omitempty"`
+	// orchestrator_context is a JSON KubernetesPodInfo.
+	OrchestratorContext      []byte `protobuf:"bytes,4,opt,name=orchestrator_context,json=orchestratorContext,proto3" json:"orchestrator_context,omitempty"`
+	Ifname                   string `protobuf:"bytes,5,opt,name=ifname,proto3" json:"ifname,omitempty"`
+	SecondaryInterfacesExist bool   `protobuf:"varint,6,opt,name=secondary_interfaces_exist,json=secondaryInterfacesExist,proto3" json:"secondary_interfaces_exist,omitempty"`
+}
+
+func (x *IPConfigsRequest) Reset() {
+	*x = IPConfigsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigsRequest) ProtoMessage() {}
+
+func (x *IPConfigsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigsRequest.ProtoReflect.Descriptor instead.
+func (*IPConfigsRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *IPConfigsRequest) GetDesiredIpAddresses() []string {
+	if x != nil {
+		return x.DesiredIpAddresses
+	}
+	return nil
+}
+
+func (x *IPConfigsRequest) GetPodInterfaceId() string {
+	if x != nil {
+		return x.PodInterfaceId
+	}
+	return ""
+}
+
+func (x *IPConfigsRequest) GetInfraContainerId() string {
+	if x != nil {
+		return x.InfraContainerId
+	}
+	return ""
+}
+
+func (x *IPConfigsRequest) GetOrchestratorContext() []byte {
+	if x != nil {
+		return x.OrchestratorContext
+	}
+	return nil
+}
+
+func (x *IPConfigsRequest) GetIfname() string {
+	if x != nil {
+		return x.Ifname
+	}
+	return ""
+}
+
+func (x *IPConfigsRequest) GetSecondaryInterfacesExist() bool {
+	if x != nil {
+		return x.SecondaryInterfacesExist
+	}
+	return false
+}
+
+type IPConfigsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PodIpInfo []*PodIPInfo `protobuf:"bytes,1,rep,name=pod_ip_info,json=podIpInfo,proto3" json:"pod_ip_info,omitempty"`
+	Response  *Response    `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+}
+
+func (x *IPConfigsResponse) Reset() {
+	*x = IPConfigsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigsResponse) ProtoMessage() {}
+
+func (x *IPConfigsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigsResponse.ProtoReflect.Descriptor instead.
+func (*IPConfigsResponse) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *IPConfigsResponse) GetPodIpInfo() []*PodIPInfo {
+	if x != nil {
+		return x.PodIpInfo
+	}
+	return nil
+}
+
+func (x *IPConfigsResponse) GetResponse() *Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+type IPSubnet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpAddress    string `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	PrefixLength uint32 `protobuf:"varint,2,opt,name=prefix_length,json=prefixLength,proto3" json:"prefix_length,omitempty"`
+}
+
+func (x *IPSubnet) Reset() {
+	*x = IPSubnet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPSubnet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPSubnet) ProtoMessage() {}
+
+func (x *IPSubnet) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPSubnet.ProtoReflect.Descriptor instead.
+func (*IPSubnet) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IPSubnet) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPSubnet) GetPrefixLength() uint32 {
+	if x != nil {
+		return x.PrefixLength
+	}
+	return 0
+}
+
+type IPConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpSubnet         *IPSubnet `protobuf:"bytes,1,opt,name=ip_subnet,json=ipSubnet,proto3" json:"ip_subnet,omitempty"`
+	DnsServers       []string  `protobuf:"bytes,2,rep,name=dns_servers,json=dnsServers,proto3" json:"dns_servers,omitempty"`
+	GatewayIpAddress string    `protobuf:"bytes,3,opt,name=gateway_ip_address,json=gatewayIpAddress,proto3" json:"gateway_ip_address,omitempty"`
+}
+
+func (x *IPConfiguration) Reset() {
+	*x = IPConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfiguration) ProtoMessage() {}
+
+func (x *IPConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfiguration.ProtoReflect.Descriptor instead.
+func (*IPConfiguration) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IPConfiguration) GetIpSubnet() *IPSubnet {
+	if x != nil {
+		return x.IpSubnet
+	}
+	return nil
+}
+
+func (x *IPConfiguration) GetDnsServers() []string {
+	if x != nil {
+		return x.DnsServers
+	}
+	return nil
+}
+
+func (x *IPConfiguration) GetGatewayIpAddress() string {
+	if x != nil {
+		return x.GatewayIpAddress
+	}
+	return ""
+}
+
+type HostIPInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Gateway   string `protobuf:"bytes,1,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	PrimaryIp string `protobuf:"bytes,2,opt,name=primary_ip,json=primaryIp,proto3" json:"primary_ip,omitempty"`
+	Subnet    string `protobuf:"bytes,3,opt,name=subnet,proto3" json:"subnet,omitempty"`
+}
+
+func (x *HostIPInfo) Reset() {
+	*x = HostIPInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HostIPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostIPInfo) ProtoMessage() {}
+
+func (x *HostIPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostIPInfo.ProtoReflect.Descriptor instead.
+func (*HostIPInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HostIPInfo) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *HostIPInfo) GetPrimaryIp() string {
+	if x != nil {
+		return x.PrimaryIp
+	}
+	return ""
+}
+
+func (x *HostIPInfo) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+type Route struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpAddress        string `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	GatewayIpAddress string `protobuf:"bytes,2,opt,name=gateway_ip_address,json=gatewayIpAddress,proto3" json:"gateway_ip_address,omitempty"`
+	InterfaceToUse   string `protobuf:"bytes,3,opt,name=interface_to_use,json=interfaceToUse,proto3" json:"interface_to_use,omitempty"`
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Route) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Route) GetGatewayIpAddress() string {
+	if x != nil {
+		return x.GatewayIpAddress
+	}
+	return ""
+}
+
+func (x *Route) GetInterfaceToUse() string {
+	if x != nil {
+		return x.InterfaceToUse
+	}
+	return ""
+}
+
+type PodIPInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PodIpConfig                     *IPSubnet        `protobuf:"bytes,1,opt,name=pod_ip_config,json=podIpConfig,proto3" json:"pod_ip_config,omitempty"`
+	NetworkContainerPrimaryIpConfig *IPConfiguration `protobuf:"bytes,2,opt,name=network_container_primary_ip_config,json=networkContainerPrimaryIpConfig,proto3" json:"network_container_primary_ip_config,omitempty"`
+	HostPrimaryIpInfo               *HostIPInfo      `protobuf:"bytes,3,opt,name=host_primary_ip_info,json=hostPrimaryIpInfo,proto3" json:"host_primary_ip_info,omitempty"`
+	NicType                         string           `protobuf:"bytes,4,opt,name=nic_type,json=nicType,proto3" json:"nic_type,omitempty"`
+	InterfaceName                   string           `protobuf:"bytes,5,opt,name=interface_name,json=interfaceName,proto3" json:"interface_name,omitempty"`
+	MacAddress                      string           `protobuf:"bytes,6,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	SkipDefaultRoutes               bool             `protobuf:"varint,7,opt,name=skip_default_routes,json=skipDefaultRoutes,proto3" json:"skip_default_routes,omitempty"`
+	Routes                          []*Route         `protobuf:"bytes,8,rep,name=routes,proto3" json:"routes,omitempty"`
+}
+
+func (x *PodIPInfo) Reset() {
+	*x = PodIPInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodIPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodIPInfo) ProtoMessage() {}
+
+func (x *PodIPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodIPInfo.ProtoReflect.Descriptor instead.
+func (*PodIPInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PodIPInfo) GetPodIpConfig() *IPSubnet {
+	if x != nil {
+		return x.PodIpConfig
+	}
+	return nil
+}
+
+func (x *PodIPInfo) GetNetworkContainerPrimaryIpConfig() *IPConfiguration {
+	if x != nil {
+		return x.NetworkContainerPrimaryIpConfig
+	}
+	return nil
+}
+
+func (x *PodIPInfo) GetHostPrimaryIpInfo() *HostIPInfo {
+	if x != nil {
+		return x.HostPrimaryIpInfo
+	}
+	return nil
+}
+
+func (x *PodIPInfo) GetNicType() string {
+	if x != nil {
+		return x.NicType
+	}
+	return ""
+}
+
+func (x *PodIPInfo) GetInterfaceName() string {
+	if x != nil {
+		return x.InterfaceName
+	}
+	return ""
+}
+
+func (x *PodIPInfo) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+func (x *PodIPInfo) GetSkipDefaultRoutes() bool {
+	if x != nil {
+		return x.SkipDefaultRoutes
+	}
+	return false
+}
+
+func (x *PodIPInfo) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+type SecondaryIPConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpAddress string `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	NcVersion int64  `protobuf:"varint,2,opt,name=nc_version,json=ncVersion,proto3" json:"nc_version,omitempty"`
+}
+
+func (x *SecondaryIPConfig) Reset() {
+	*x = SecondaryIPConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecondaryIPConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecondaryIPConfig) ProtoMessage() {}
+
+func (x *SecondaryIPConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecondaryIPConfig.ProtoReflect.Descriptor instead.
+func (*SecondaryIPConfig) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SecondaryIPConfig) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *SecondaryIPConfig) GetNcVersion() int64 {
+	if x != nil {
+		return x.NcVersion
+	}
+	return 0
+}
+
+type MultiTenancyInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EncapType string `protobuf:"bytes,1,opt,name=encap_type,json=encapType,proto3" json:"encap_type,omitempty"`
+	Id        int64  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *MultiTenancyInfo) Reset() {
+	*x = MultiTenancyInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MultiTenancyInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultiTenancyInfo) ProtoMessage() {}
+
+func (x *MultiTenancyInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultiTenancyInfo.ProtoReflect.Descriptor instead.
+func (*MultiTenancyInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MultiTenancyInfo) GetEncapType() string {
+	if x != nil {
+		return x.EncapType
+	}
+	return ""
+}
+
+func (x *MultiTenancyInfo) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type NetworkInterfaceInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NicType    string `protobuf:"bytes,1,opt,name=nic_type,json=nicType,proto3" json:"nic_type,omitempty"`
+	MacAddress string `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+}
+
+func (x *NetworkInterfaceInfo) Reset() {
+	*x = NetworkInterfaceInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NetworkInterfaceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkInterfaceInfo) ProtoMessage() {}
+
+func (x *NetworkInterfaceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkInterfaceInfo.ProtoReflect.Descriptor instead.
+func (*NetworkInterfaceInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *NetworkInterfaceInfo) GetNicType() string {
+	if x != nil {
+		return x.NicType
+	}
+	return ""
+}
+
+func (x *NetworkInterfaceInfo) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+type NetworkContainerRequestPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type         string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	EndpointType string `protobuf:"bytes,2,opt,name=endpoint_type,json=endpointType,proto3" json:"endpoint_type,omitempty"`
+	// settings is JSON.
+	Settings []byte `protobuf:"bytes,3,opt,name=settings,proto3" json:"settings,omitempty"`
+}
+
+func (x *NetworkContainerRequestPolicy) Reset() {
+	*x = NetworkContainerRequestPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NetworkContainerRequestPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkContainerRequestPolicy) ProtoMessage() {}
+
+func (x *NetworkContainerRequestPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkContainerRequestPolicy.ProtoReflect.Descriptor instead.
+func (*NetworkContainerRequestPolicy) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *NetworkContainerRequestPolicy) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *NetworkContainerRequestPolicy) GetEndpointType() string {
+	if x != nil {
+		return x.EndpointType
+	}
+	return ""
+}
+
+func (x *NetworkContainerRequestPolicy) GetSettings() []byte {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type CreateNetworkContainerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HostPrimaryIp              string           `protobuf:"bytes,1,opt,name=host_primary_ip,json=hostPrimaryIp,proto3" json:"host_primary_ip,omitempty"`
+	Version                    string           `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	NetworkContainerType       string           `protobuf:"bytes,3,opt,name=network_container_type,json=networkContainerType,proto3" json:"network_container_type,omitempty"`
+	NetworkContainerId         string           `protobuf:"bytes,4,opt,name=network_container_id,json=networkContainerId,proto3" json:"network_container_id,omitempty"`
+	PrimaryInterfaceIdentifier string           `protobuf:"bytes,5,opt,name=primary_interface_identifier,json=primaryInterfaceIdentifier,proto3" json:"primary_interface_identifier,omitempty"`
+	AuthorizationToken         string           `protobuf:"bytes,6,opt,name=authorization_token,json=authorizationToken,proto3" json:"authorization_token,omitempty"`
+	LocalIpConfiguration       *IPConfiguration `protobuf:"bytes,7,opt,name=local_ip_configuration,json=localIpConfiguration,proto3" json:"local_ip_configuration,omitempty"`
+	OrchestratorContext        []byte           `protobuf:"bytes,8,opt,name=orchestrator_context,json=orchestratorContext,proto3" json:"orchestrator_context,omitempty"`
+	IpConfiguration            *IPConfiguration `protobuf:"bytes,9,opt,name=ip_configuration,json=ipConfiguration,proto3" json:"ip_configuration,omitempty"`
+	// secondary_ip_configs are keyed by IP ID.
+	SecondaryIpConfigs         map[string]*SecondaryIPConfig    `protobuf:"bytes,10,rep,name=secondary_ip_configs,json=secondaryIpConfigs,proto3" json:"secondary_ip_configs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	MultiTenancyInfo           *MultiTenancyInfo                `protobuf:"bytes,11,opt,name=multi_tenancy_info,json=multiTenancyInfo,proto3" json:"multi_tenancy_info,omitempty"`
+	CnetAddressSpace           []*IPSubnet                      `protobuf:"bytes,12,rep,name=cnet_address_space,json=cnetAddressSpace,proto3" json:"cnet_address_space,omitempty"`
+	Routes                     []*Route                         `protobuf:"bytes,13,rep,name=routes,proto3" json:"routes,omitempty"`
+	AllowHostToNcCommunication bool                             `protobuf:"varint,14,opt,name=allow_host_to_nc_communication,json=allowHostToNcCommunication,proto3" json:"allow_host_to_nc_communication,omitempty"`
+	AllowNcToHostCommunication bool                             `protobuf:"varint,15,opt,name=allow_nc_to_host_communication,json=allowNcToHostCommunication,proto3" json:"allow_nc_to_host_communication,omitempty"`
+	EndpointPolicies           []*NetworkContainerRequestPolicy `protobuf:"bytes,16,rep,name=endpoint_policies,json=endpointPolicies,proto3" json:"endpoint_policies,omitempty"`
+	NcStatus                   string                           `protobuf:"bytes,17,opt,name=nc_status,json=ncStatus,proto3" json:"nc_status,omitempty"`
+	NetworkInterfaceInfo       *NetworkInterfaceInfo            `protobuf:"bytes,18,opt,name=network_interface_info,json=networkInterfaceInfo,proto3" json:"network_interface_info,omitempty"`
+}
+
+func (x *CreateNetworkContainerRequest) Reset() {
+	*x = CreateNetworkContainerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateNetworkContainerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNetworkContainerRequest) ProtoMessage() {}
+
+func (x *CreateNetworkContainerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNetworkContainerRequest.ProtoReflect.Descriptor instead.
+func (*CreateNetworkContainerRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CreateNetworkContainerRequest) GetHostPrimaryIp() string {
+	if x != nil {
+		return x.HostPrimaryIp
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetNetworkContainerType() string {
+	if x != nil {
+		return x.NetworkContainerType
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetNetworkContainerId() string {
+	if x != nil {
+		return x.NetworkContainerId
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetPrimaryInterfaceIdentifier() string {
+	if x != nil {
+		return x.PrimaryInterfaceIdentifier
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetAuthorizationToken() string {
+	if x != nil {
+		return x.AuthorizationToken
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetLocalIpConfiguration() *IPConfiguration {
+	if x != nil {
+		return x.LocalIpConfiguration
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetOrchestratorContext() []byte {
+	if x != nil {
+		return x.OrchestratorContext
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetIpConfiguration() *IPConfiguration {
+	if x != nil {
+		return x.IpConfiguration
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetSecondaryIpConfigs() map[string]*SecondaryIPConfig {
+	if x != nil {
+		return x.SecondaryIpConfigs
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetMultiTenancyInfo() *MultiTenancyInfo {
+	if x != nil {
+		return x.MultiTenancyInfo
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetCnetAddressSpace() []*IPSubnet {
+	if x != nil {
+		return x.CnetAddressSpace
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetAllowHostToNcCommunication() bool {
+	if x != nil {
+		return x.AllowHostToNcCommunication
+	}
+	return false
+}
+
+func (x *CreateNetworkContainerRequest) GetAllowNcToHostCommunication() bool {
+	if x != nil {
+		return x.AllowNcToHostCommunication
+	}
+	return false
+}
+
+func (x *CreateNetworkContainerRequest) GetEndpointPolicies() []*NetworkContainerRequestPolicy {
+	if x != nil {
+		return x.EndpointPolicies
+	}
+	return nil
+}
+
+func (x *CreateNetworkContainerRequest) GetNcStatus() string {
+	if x != nil {
+		return x.NcStatus
+	}
+	return ""
+}
+
+func (x *CreateNetworkContainerRequest) GetNetworkInterfaceInfo() *NetworkInterfaceInfo {
+	if x != nil {
+		return x.NetworkInterfaceInfo
+	}
+	return nil
+}
+
+type DeleteNetworkContainerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NetworkContainerId string `protobuf:"bytes,1,opt,name=network_container_id,json=networkContainerId,proto3" json:"network_container_id,omitempty"`
+}
+
+func (x *DeleteNetworkContainerRequest) Reset() {
+	*x = DeleteNetworkContainerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteNetworkContainerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteNetworkContainerRequest) ProtoMessage() {}
+
+func (x *DeleteNetworkContainerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteNetworkContainerRequest.ProtoReflect.Descriptor instead.
+func (*DeleteNetworkContainerRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteNetworkContainerRequest) GetNetworkContainerId() string {
+	if x != nil {
+		return x.NetworkContainerId
+	}
+	return ""
+}
+
+type GetNetworkContainerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NetworkContainerId  string `protobuf:"bytes,1,opt,name=network_container_id,json=networkContainerId,proto3" json:"network_container_id,omitempty"`
+	OrchestratorContext []byte `protobuf:"bytes,2,opt,name=orchestrator_context,json=orchestratorContext,proto3" json:"orchestrator_context,omitempty"`
+}
+
+func (x *GetNetworkContainerRequest) Reset() {
+	*x = GetNetworkContainerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetNetworkContainerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNetworkContainerRequest) ProtoMessage() {}
+
+func (x *GetNetworkContainerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNetworkContainerRequest.ProtoReflect.Descriptor instead.
+func (*GetNetworkContainerRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetNetworkContainerRequest) GetNetworkContainerId() string {
+	if x != nil {
+		return x.NetworkContainerId
+	}
+	return ""
+}
+
+func (x *GetNetworkContainerRequest) GetOrchestratorContext() []byte {
+	if x != nil {
+		return x.OrchestratorContext
+	}
+	return nil
+}
+
+type GetNetworkContainerResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NetworkContainerId         string                `protobuf:"bytes,1,opt,name=network_container_id,json=networkContainerId,proto3" json:"network_container_id,omitempty"`
+	IpConfiguration            *IPConfiguration      `protobuf:"bytes,2,opt,name=ip_configuration,json=ipConfiguration,proto3" json:"ip_configuration,omitempty"`
+	Routes                     []*Route              `protobuf:"bytes,3,rep,name=routes,proto3" json:"routes,omitempty"`
+	CnetAddressSpace           []*IPSubnet           `protobuf:"bytes,4,rep,name=cnet_address_space,json=cnetAddressSpace,proto3" json:"cnet_address_space,omitempty"`
+	MultiTenancyInfo           *MultiTenancyInfo     `protobuf:"bytes,5,opt,name=multi_tenancy_info,json=multiTenancyInfo,proto3" json:"multi_tenancy_info,omitempty"`
+	PrimaryInterfaceIdentifier string                `protobuf:"bytes,6,opt,name=primary_interface_identifier,json=primaryInterfaceIdentifier,proto3" json:"primary_interface_identifier,omitempty"`
+	LocalIpConfiguration       *IPConfiguration      `protobuf:"bytes,7,opt,name=local_ip_configuration,json=localIpConfiguration,proto3" json:"local_ip_configuration,omitempty"`
+	Response                   *Response             `protobuf:"bytes,8,opt,name=response,proto3" json:"response,omitempty"`
+	AllowHostToNcCommunication bool                  `protobuf:"varint,9,opt,name=allow_host_to_nc_communication,json=allowHostToNcCommunication,proto3" json:"allow_host_to_nc_communication,omitempty"`
+	AllowNcToHostCommunication bool                  `protobuf:"varint,10,opt,name=allow_nc_to_host_communication,json=allowNcToHostCommunication,proto3" json:"allow_nc_to_host_communication,omitempty"`
+	NetworkInterfaceInfo       *NetworkInterfaceInfo `protobuf:"bytes,11,opt,name=network_interface_info,json=networkInterfaceInfo,proto3" json:"network_interface_info,omitempty"`
+}
+
+func (x *GetNetworkContainerResponse) Reset() {
+	*x = GetNetworkContainerResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetNetworkContainerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNetworkContainerResponse) ProtoMessage() {}
+
+func (x *GetNetworkContainerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNetworkContainerResponse.ProtoReflect.Descriptor instead.
+func (*GetNetworkContainerResponse) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetNetworkContainerResponse) GetNetworkContainerId() string {
+	if x != nil {
+		return x.NetworkContainerId
+	}
+	return ""
+}
+
+func (x *GetNetworkContainerResponse) GetIpConfiguration() *IPConfiguration {
+	if x != nil {
+		return x.IpConfiguration
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetCnetAddressSpace() []*IPSubnet {
+	if x != nil {
+		return x.CnetAddressSpace
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetMultiTenancyInfo() *MultiTenancyInfo {
+	if x != nil {
+		return x.MultiTenancyInfo
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetPrimaryInterfaceIdentifier() string {
+	if x != nil {
+		return x.PrimaryInterfaceIdentifier
+	}
+	return ""
+}
+
+func (x *GetNetworkContainerResponse) GetLocalIpConfiguration() *IPConfiguration {
+	if x != nil {
+		return x.LocalIpConfiguration
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetResponse() *Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *GetNetworkContainerResponse) GetAllowHostToNcCommunication() bool {
+	if x != nil {
+		return x.AllowHostToNcCommunication
+	}
+	return false
+}
+
+func (x *GetNetworkContainerResponse) GetAllowNcToHostCommunication() bool {
+	if x != nil {
+		return x.AllowNcToHostCommunication
+	}
+	return false
+}
+
+func (x *GetNetworkContainerResponse) GetNetworkInterfaceInfo() *NetworkInterfaceInfo {
+	if x != nil {
+		return x.NetworkInterfaceInfo
+	}
+	return nil
+}
+
+type GetAllNetworkContainersResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NetworkContainers []*GetNetworkContainerResponse `protobuf:"bytes,1,rep,name=network_containers,json=networkContainers,proto3" json:"network_containers,omitempty"`
+	Response          *Response                      `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+}
+
+func (x *GetAllNetworkContainersResponse) Reset() {
+	*x = GetAllNetworkContainersResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetAllNetworkContainersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAllNetworkContainersResponse) ProtoMessage() {}
+
+func (x *GetAllNetworkContainersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAllNetworkContainersResponse.ProtoReflect.Descriptor instead.
+func (*GetAllNetworkContainersResponse) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetAllNetworkContainersResponse) GetNetworkContainers() []*GetNetworkContainerResponse {
+	if x != nil {
+		return x.NetworkContainers
+	}
+	return nil
+}
+
+func (x *GetAllNetworkContainersResponse) GetResponse() *Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+type GetEndpointRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EndpointId string `protobuf:"bytes,1,opt,name=endpoint_id,json=endpointId,proto3" json:"endpoint_id,omitempty"`
+}
+
+func (x *GetEndpointRequest) Reset() {
+	*x = GetEndpointRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetEndpointRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEndpointRequest) ProtoMessage() {}
+
+func (x *GetEndpointRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEndpointRequest.ProtoReflect.Descriptor instead.
+func (*GetEndpointRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetEndpointRequest) GetEndpointId() string {
+	if x != nil {
+		return x.EndpointId
+	}
+	return ""
+}
+
+// IPInfo is the IPs of an interface of an endpoint, in CIDR notation.
+type IPInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ipv4 []string `protobuf:"bytes,1,rep,name=ipv4,proto3" json:"ipv4,omitempty"`
+	Ipv6 []string `protobuf:"bytes,2,rep,name=ipv6,proto3" json:"ipv6,omitempty"`
+}
+
+func (x *IPInfo) Reset() {
+	*x = IPInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPInfo) ProtoMessage() {}
+
+func (x *IPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPInfo.ProtoReflect.Descriptor instead.
+func (*IPInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *IPInfo) GetIpv4() []string {
+	if x != nil {
+		return x.Ipv4
+	}
+	return nil
+}
+
+func (x *IPInfo) GetIpv6() []string {
+	if x != nil {
+		return x.Ipv6
+	}
+	return nil
+}
+
+type EndpointInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PodName      string `protobuf:"bytes,1,opt,name=pod_name,json=podName,proto3" json:"pod_name,omitempty"`
+	PodNamespace string `protobuf:"bytes,2,opt,name=pod_namespace,json=podNamespace,proto3" json:"pod_namespace,omitempty"`
+	// ifname_to_ips are keyed by interface name.
+	IfnameToIps   map[string]*IPInfo `protobuf:"bytes,3,rep,name=ifname_to_ips,json=ifnameToIps,proto3" json:"ifname_to_ips,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	HnsEndpointId string             `protobuf:"bytes,4,opt,name=hns_endpoint_id,json=hnsEndpointId,proto3" json:"hns_endpoint_id,omitempty"`
+	HostVethName  string             `protobuf:"bytes,5,opt,name=host_veth_name,json=hostVethName,proto3" json:"host_veth_name,omitempty"`
+}
+
+func (x *EndpointInfo) Reset() {
+	*x = EndpointInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EndpointInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointInfo) ProtoMessage() {}
+
+func (x *EndpointInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointInfo.ProtoReflect.Descriptor instead.
+func (*EndpointInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *EndpointInfo) GetPodName() string {
+	if x != nil {
+		return x.PodName
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetPodNamespace() string {
+	if x != nil {
+		return x.PodNamespace
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetIfnameToIps() map[string]*IPInfo {
+	if x != nil {
+		return x.IfnameToIps
+	}
+	return nil
+}
+
+func (x *EndpointInfo) GetHnsEndpointId() string {
+	if x != nil {
+		return x.HnsEndpointId
+	}
+	return ""
+}
+
+func (x *EndpointInfo) GetHostVethName() string {
+	if x != nil {
+		return x.HostVethName
+	}
+	return ""
+}
+
+type GetEndpointResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Response     *Response     `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	EndpointInfo *EndpointInfo `protobuf:"bytes,2,opt,name=endpoint_info,json=endpointInfo,proto3" json:"endpoint_info,omitempty"`
+}
+
+func (x *GetEndpointResponse) Reset() {
+	*x = GetEndpointResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetEndpointResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEndpointResponse) ProtoMessage() {}
+
+func (x *GetEndpointResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEndpointResponse.ProtoReflect.Descriptor instead.
+func (*GetEndpointResponse) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetEndpointResponse) GetResponse() *Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *GetEndpointResponse) GetEndpointInfo() *EndpointInfo {
+	if x != nil {
+		return x.EndpointInfo
+	}
+	return nil
+}
+
+type UpdateEndpointRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EndpointId    string `protobuf:"bytes,1,opt,name=endpoint_id,json=endpointId,proto3" json:"endpoint_id,omitempty"`
+	HnsEndpointId string `protobuf:"bytes,2,opt,name=hns_endpoint_id,json=hnsEndpointId,proto3" json:"hns_endpoint_id,omitempty"`
+	HostVethName  string `protobuf:"bytes,3,opt,name=host_veth_name,json=hostVethName,proto3" json:"host_veth_name,omitempty"`
+}
+
+func (x *UpdateEndpointRequest) Reset() {
+	*x = UpdateEndpointRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateEndpointRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateEndpointRequest) ProtoMessage() {}
+
+func (x *UpdateEndpointRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateEndpointRequest.ProtoReflect.Descriptor instead.
+func (*UpdateEndpointRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UpdateEndpointRequest) GetEndpointId() string {
+	if x != nil {
+		return x.EndpointId
+	}
+	return ""
+}
+
+func (x *UpdateEndpointRequest) GetHnsEndpointId() string {
+	if x != nil {
+		return x.HnsEndpointId
+	}
+	return ""
+}
+
+func (x *UpdateEndpointRequest) GetHostVethName() string {
+	if x != nil {
+		return x.HostVethName
+	}
+	return ""
+}
+
+type WatchIPStateEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// since is the sequence of the last event handled, the stream resumes after it. If it is 0, the stream starts
+	// from the next event.
+	Since uint64 `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+}
+
+func (x *WatchIPStateEventsRequest) Reset() {
+	*x = WatchIPStateEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchIPStateEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchIPStateEventsRequest) ProtoMessage() {}
+
+func (x *WatchIPStateEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchIPStateEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchIPStateEventsRequest) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WatchIPStateEventsRequest) GetSince() uint64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+type PodInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name             string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace        string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	InfraContainerId string `protobuf:"bytes,3,opt,name=infra_container_id,json=infraContainerId,proto3" json:"infra_container_id,omitempty"`
+	InterfaceId      string `protobuf:"bytes,4,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+}
+
+func (x *PodInfo) Reset() {
+	*x = PodInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodInfo) ProtoMessage() {}
+
+func (x *PodInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodInfo.ProtoReflect.Descriptor instead.
+func (*PodInfo) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *PodInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PodInfo) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *PodInfo) GetInfraContainerId() string {
+	if x != nil {
+		return x.InfraContainerId
+	}
+	return ""
+}
+
+func (x *PodInfo) GetInterfaceId() string {
+	if x != nil {
+		return x.InterfaceId
+	}
+	return ""
+}
+
+type IPStateEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sequence  uint64 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Id        string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	IpAddress string `protobuf:"bytes,3,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	NcId      string `protobuf:"bytes,4,opt,name=nc_id,json=ncId,proto3" json:"nc_id,omitempty"`
+	// pod_info is unset when the IP is not assigned to, or released from, a Pod.
+	PodInfo   *PodInfo               `protobuf:"bytes,5,opt,name=pod_info,json=podInfo,proto3" json:"pod_info,omitempty"`
+	OldState  string                 `protobuf:"bytes,6,opt,name=old_state,json=oldState,proto3" json:"old_state,omitempty"`
+	NewState  string                 `protobuf:"bytes,7,opt,name=new_state,json=newState,proto3" json:"new_state,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+}
+
+func (x *IPStateEvent) Reset() {
+	*x = IPStateEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cns_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPStateEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPStateEvent) ProtoMessage() {}
+
+func (x *IPStateEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cns_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPStateEvent.ProtoReflect.Descriptor instead.
+func (*IPStateEvent) Descriptor() ([]byte, []int) {
+	return file_cns_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *IPStateEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *IPStateEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IPStateEvent) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPStateEvent) GetNcId() string {
+	if x != nil {
+		return x.NcId
+	}
+	return ""
+}
+
+func (x *IPStateEvent) GetPodInfo() *PodInfo {
+	if x != nil {
+		return x.PodInfo
+	}
+	return nil
+}
+
+func (x *IPStateEvent) GetOldState() string {
+	if x != nil {
+		return x.OldState
+	}
+	return ""
+}
+
+func (x *IPStateEvent) GetNewState() string {
+	if x != nil {
+		return x.NewState
+	}
+	return ""
+}
+
+func (x *IPStateEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_cns_proto protoreflect.FileDescriptor
+
+var file_cns_proto_rawDesc = []byte{
+	0x0a, 0x09, 0x63, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x63, 0x6e, 0x73,
+	0x2e, 0x76, 0x31, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x45, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x43, 0x6f, 0x64,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0xa5, 0x02, 0x0a, 0x10,
+	0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x30, 0x0a, 0x14, 0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x5f, 0x69, 0x70, 0x5f, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x12,
+	0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x49, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x70, 0x6f, 0x64, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66,
+	0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x6f,
+	0x64, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x64, 0x12, 0x2c, 0x0a, 0x12,
+	0x69, 0x6e, 0x66, 0x72, 0x61, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x69, 0x6e, 0x66, 0x72, 0x61, 0x43,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x31, 0x0a, 0x14, 0x6f, 0x72,
+	0x63, 0x68, 0x65, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65,
+	0x78, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x13, 0x6f, 0x72, 0x63, 0x68, 0x65, 0x73,
+	0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x69, 0x66, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69,
+	0x66, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x3c, 0x0a, 0x1a, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x61,
+	0x72, 0x79, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x73, 0x5f, 0x65, 0x78,
+	0x69, 0x73, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x18, 0x73, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x61, 0x72, 0x79, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x73, 0x45, 0x78,
+	0x69, 0x73, 0x74, 0x22, 0x74, 0x0a, 0x11, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x0b, 0x70, 0x6f, 0x64, 0x5f,
+	0x69, 0x70, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x09, 0x70, 0x6f, 0x64, 0x49, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x2c, 0x0a, 0x08, 0x72,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52,
+	0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x4e, 0x0a, 0x08, 0x49, 0x50, 0x53,
+	0x75, 0x62, 0x6e, 0x65, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69, 0x70, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x5f, 0x6c,
+	0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x70, 0x72, 0x65,
+	0x66, 0x69, 0x78, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x22, 0x8f, 0x01, 0x0a, 0x0f, 0x49, 0x50,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2d, 0x0a,
+	0x09, 0x69, 0x70, 0x5f, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e,
+	0x65, 0x74, 0x52, 0x08, 0x69, 0x70, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x12, 0x1f, 0x0a, 0x0b,
+	0x64, 0x6e, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0a, 0x64, 0x6e, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x12, 0x2c, 0x0a,
+	0x12, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x67, 0x61, 0x74, 0x65, 0x77,
+	0x61, 0x79, 0x49, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x5d, 0x0a, 0x0a, 0x48,
+	0x6f, 0x73, 0x74, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x67, 0x61, 0x74,
+	0x65, 0x77, 0x61, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x61, 0x74, 0x65,
+	0x77, 0x61, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69,
+	0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79,
+	0x49, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x22, 0x7e, 0x0a, 0x05, 0x52, 0x6f,
+	0x75, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x5f, 0x69, 0x70,
+	0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10,
+	0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x49, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x12, 0x28, 0x0a, 0x10, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x74, 0x6f,
+	0x5f, 0x75, 0x73, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x54, 0x6f, 0x55, 0x73, 0x65, 0x22, 0xa7, 0x03, 0x0a, 0x09, 0x50,
+	0x6f, 0x64, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x34, 0x0a, 0x0d, 0x70, 0x6f, 0x64, 0x5f,
+	0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e, 0x65,
+	0x74, 0x52, 0x0b, 0x70, 0x6f, 0x64, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x65,
+	0x0a, 0x23, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69,
+	0x6e, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x1f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e,
+	0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x49, 0x70, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x43, 0x0a, 0x14, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x70, 0x72,
+	0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x6f, 0x73,
+	0x74, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x11, 0x68, 0x6f, 0x73, 0x74, 0x50, 0x72, 0x69,
+	0x6d, 0x61, 0x72, 0x79, 0x49, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x69,
+	0x63, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x69,
+	0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x69,
+	0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x6d, 0x61, 0x63, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x6d, 0x61, 0x63, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x2e, 0x0a,
+	0x13, 0x73, 0x6b, 0x69, 0x70, 0x5f, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x72, 0x6f,
+	0x75, 0x74, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x11, 0x73, 0x6b, 0x69, 0x70,
+	0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x12, 0x25, 0x0a,
+	0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
+	0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x75, 0x74, 0x65, 0x52, 0x06, 0x72, 0x6f,
+	0x75, 0x74, 0x65, 0x73, 0x22, 0x51, 0x0a, 0x11, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x61, 0x72,
+	0x79, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x70, 0x5f,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69,
+	0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6e, 0x63, 0x5f, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6e, 0x63,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x41, 0x0a, 0x10, 0x4d, 0x75, 0x6c, 0x74, 0x69,
+	0x54, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1d, 0x0a, 0x0a, 0x65,
+	0x6e, 0x63, 0x61, 0x70, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x65, 0x6e, 0x63, 0x61, 0x70, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x22, 0x52, 0x0a, 0x14, 0x4e, 0x65,
+	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x6e,
+	0x66, 0x6f, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x69, 0x63, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x69, 0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1f, 0x0a,
+	0x0b, 0x6d, 0x61, 0x63, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x6d, 0x61, 0x63, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x74,
+	0x0a, 0x1d, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f,
+	0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x6e, 0x64, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x74, 0x74,
+	0x69, 0x6e, 0x67, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x73, 0x65, 0x74, 0x74,
+	0x69, 0x6e, 0x67, 0x73, 0x22, 0xd1, 0x09, 0x0a, 0x1d, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x70,
+	0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x68, 0x6f, 0x73, 0x74, 0x50, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x49, 0x70, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x34, 0x0a, 0x16, 0x6e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x30,
+	0x0a, 0x14, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69,
+	0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x6e, 0x65,
+	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x40, 0x0a, 0x1c, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x1a, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x49,
+	0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69,
+	0x65, 0x72, 0x12, 0x2f, 0x0a, 0x13, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x12, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x4d, 0x0a, 0x16, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x69, 0x70, 0x5f,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x14, 0x6c, 0x6f,
+	0x63, 0x61, 0x6c, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x31, 0x0a, 0x14, 0x6f, 0x72, 0x63, 0x68, 0x65, 0x73, 0x74, 0x72, 0x61, 0x74,
+	0x6f, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x13, 0x6f, 0x72, 0x63, 0x68, 0x65, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f,
+	0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x42, 0x0a, 0x10, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0f, 0x69, 0x70, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x6f, 0x0a, 0x14, 0x73, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x3d, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x53,
+	0x65, 0x63, 0x6f, 0x6e, 0x64, 0x61, 0x72, 0x79, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x12, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x61, 0x72,
+	0x79, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x12, 0x46, 0x0a, 0x12, 0x6d, 0x75,
+	0x6c, 0x74, 0x69, 0x5f, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x5f, 0x69, 0x6e, 0x66, 0x6f,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x4d, 0x75, 0x6c, 0x74, 0x69, 0x54, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x10, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x54, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x49, 0x6e,
+	0x66, 0x6f, 0x12, 0x3e, 0x0a, 0x12, 0x63, 0x6e, 0x65, 0x74, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x5f, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74,
+	0x52, 0x10, 0x63, 0x6e, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x53, 0x70, 0x61,
+	0x63, 0x65, 0x12, 0x25, 0x0a, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x18, 0x0d, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x75, 0x74,
+	0x65, 0x52, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x12, 0x42, 0x0a, 0x1e, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x74, 0x6f, 0x5f, 0x6e, 0x63, 0x5f, 0x63, 0x6f,
+	0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x1a, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x48, 0x6f, 0x73, 0x74, 0x54, 0x6f, 0x4e, 0x63,
+	0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x42, 0x0a,
+	0x1e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x6e, 0x63, 0x5f, 0x74, 0x6f, 0x5f, 0x68, 0x6f, 0x73,
+	0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x1a, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4e, 0x63, 0x54, 0x6f,
+	0x48, 0x6f, 0x73, 0x74, 0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x52, 0x0a, 0x11, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x70, 0x6f,
+	0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x18, 0x10, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x63,
+	0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e,
+	0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x79, 0x52, 0x10, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x69, 0x65, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x63, 0x5f, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x52, 0x0a, 0x16, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x12, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x14, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61,
+	0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x1a, 0x60, 0x0a, 0x17, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x61, 0x72, 0x79, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x2f, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x63, 0x6f,
+	0x6e, 0x64, 0x61, 0x72, 0x79, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x51, 0x0a, 0x1d, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x22, 0x81, 0x01, 0x0a, 0x1a,
+	0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69,
+	0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x6e, 0x65,
+	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x31, 0x0a, 0x14,
+	0x6f, 0x72, 0x63, 0x68, 0x65, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x5f, 0x63, 0x6f, 0x6e,
+	0x74, 0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x13, 0x6f, 0x72, 0x63, 0x68,
+	0x65, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x22,
+	0xdd, 0x05, 0x0a, 0x1b, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x30, 0x0a, 0x14, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61,
+	0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x6e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49,
+	0x64, 0x12, 0x42, 0x0a, 0x10, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0f, 0x69, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x18,
+	0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x6f, 0x75, 0x74, 0x65, 0x52, 0x06, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x73, 0x12, 0x3e, 0x0a, 0x12,
+	0x63, 0x6e, 0x65, 0x74, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x5f, 0x73, 0x70, 0x61,
+	0x63, 0x65, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x52, 0x10, 0x63, 0x6e, 0x65, 0x74,
+	0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x53, 0x70, 0x61, 0x63, 0x65, 0x12, 0x46, 0x0a, 0x12,
+	0x6d, 0x75, 0x6c, 0x74, 0x69, 0x5f, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x5f, 0x69, 0x6e,
+	0x66, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x54, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79, 0x49, 0x6e,
+	0x66, 0x6f, 0x52, 0x10, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x54, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x79,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x40, 0x0a, 0x1c, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x66, 0x69, 0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x1a, 0x70, 0x72, 0x69, 0x6d,
+	0x61, 0x72, 0x79, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x4d, 0x0a, 0x16, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f,
+	0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x14, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x42, 0x0a, 0x1e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x68, 0x6f, 0x73,
+	0x74, 0x5f, 0x74, 0x6f, 0x5f, 0x6e, 0x63, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x1a, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x48, 0x6f, 0x73, 0x74, 0x54, 0x6f, 0x4e, 0x63, 0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e,
+	0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x42, 0x0a, 0x1e, 0x61, 0x6c, 0x6c, 0x6f, 0x77,
+	0x5f, 0x6e, 0x63, 0x5f, 0x74, 0x6f, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x6d,
+	0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x1a, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4e, 0x63, 0x54, 0x6f, 0x48, 0x6f, 0x73, 0x74, 0x43, 0x6f,
+	0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x52, 0x0a, 0x16, 0x6e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65,
+	0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x14, 0x6e, 0x65, 0x74, 0x77, 0x6f,
+	0x72, 0x6b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22,
+	0xa3, 0x01, 0x0a, 0x1f, 0x47, 0x65, 0x74, 0x41, 0x6c, 0x6c, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x52, 0x0a, 0x12, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x23, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x52, 0x11, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e,
+	0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x73, 0x12, 0x2c, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x35, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x45, 0x6e, 0x64, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x65,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x30, 0x0a, 0x06,
+	0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x70, 0x76, 0x34, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x69, 0x70, 0x76, 0x34, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x70,
+	0x76, 0x36, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x69, 0x70, 0x76, 0x36, 0x22, 0xb7,
+	0x02, 0x0a, 0x0c, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x19, 0x0a, 0x08, 0x70, 0x6f, 0x64, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x70, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x6f,
+	0x64, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0c, 0x70, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
+	0x49, 0x0a, 0x0d, 0x69, 0x66, 0x6e, 0x61, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x5f, 0x69, 0x70, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x2e, 0x49, 0x66, 0x6e,
+	0x61, 0x6d, 0x65, 0x54, 0x6f, 0x49, 0x70, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0b, 0x69,
+	0x66, 0x6e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x49, 0x70, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x68, 0x6e,
+	0x73, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0d, 0x68, 0x6e, 0x73, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74,
+	0x49, 0x64, 0x12, 0x24, 0x0a, 0x0e, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x74, 0x68, 0x5f,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x68, 0x6f, 0x73, 0x74,
+	0x56, 0x65, 0x74, 0x68, 0x4e, 0x61, 0x6d, 0x65, 0x1a, 0x4e, 0x0a, 0x10, 0x49, 0x66, 0x6e, 0x61,
+	0x6d, 0x65, 0x54, 0x6f, 0x49, 0x70, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x24,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
+	0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x7e, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x2c, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39, 0x0a,
+	0x0d, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e,
+	0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0c, 0x65, 0x6e, 0x64, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x86, 0x01, 0x0a, 0x15, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e,
+	0x74, 0x49, 0x64, 0x12, 0x26, 0x0a, 0x0f, 0x68, 0x6e, 0x73, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x68, 0x6e,
+	0x73, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x24, 0x0a, 0x0e, 0x68,
+	0x6f, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x74, 0x68, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x68, 0x6f, 0x73, 0x74, 0x56, 0x65, 0x74, 0x68, 0x4e, 0x61, 0x6d,
+	0x65, 0x22, 0x31, 0x0a, 0x19, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x50, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x73,
+	0x69, 0x6e, 0x63, 0x65, 0x22, 0x8c, 0x01, 0x0a, 0x07, 0x50, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61,
+	0x63, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x69, 0x6e, 0x66, 0x72, 0x61, 0x5f, 0x63, 0x6f, 0x6e, 0x74,
+	0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10,
+	0x69, 0x6e, 0x66, 0x72, 0x61, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x21, 0x0a, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63,
+	0x65, 0x49, 0x64, 0x22, 0x8e, 0x02, 0x0a, 0x0c, 0x49, 0x50, 0x53, 0x74, 0x61, 0x74, 0x65, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12,
+	0x13, 0x0a, 0x05, 0x6e, 0x63, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x63, 0x49, 0x64, 0x12, 0x2a, 0x0a, 0x08, 0x70, 0x6f, 0x64, 0x5f, 0x69, 0x6e, 0x66, 0x6f,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x50, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x70, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x6c, 0x64, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x0a,
+	0x09, 0x6e, 0x65, 0x77, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x6e, 0x65, 0x77, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x38, 0x0a, 0x09, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x32, 0xdd, 0x05, 0x0a, 0x03, 0x43, 0x4e, 0x53, 0x12, 0x41, 0x0a, 0x0a,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x50, 0x73, 0x12, 0x18, 0x2e, 0x63, 0x6e, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x41, 0x0a, 0x0a, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x73, 0x12, 0x18, 0x2e,
+	0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x59, 0x0a, 0x1e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4f, 0x72, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61,
+	0x69, 0x6e, 0x65, 0x72, 0x12, 0x25, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61,
+	0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x51, 0x0a,
+	0x16, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x25, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10,
+	0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x5e, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f,
+	0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x22, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61,
+	0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x66, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x41, 0x6c, 0x6c, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x73, 0x12, 0x22, 0x2e, 0x63, 0x6e,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x27, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x6c, 0x6c, 0x4e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x46, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x45,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x1a, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74,
+	0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x41, 0x0a, 0x0e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x12, 0x1d, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x10, 0x2e, 0x63, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x4f, 0x0a, 0x12, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x50, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x21, 0x2e, 0x63, 0x6e, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x50, 0x53, 0x74, 0x61, 0x74, 0x65, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x63,
+	0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x74, 0x61, 0x74, 0x65, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x30, 0x01, 0x42, 0x44, 0x5a, 0x42, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x41, 0x7a, 0x75, 0x72, 0x65, 0x2f, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2d, 0x63,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x2d, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x69, 0x6e, 0x67, 0x2f, 0x63, 0x6e, 0x73, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x73, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_cns_proto_rawDescOnce sync.Once
+	file_cns_proto_rawDescData = file_cns_proto_rawDesc
+)
+
+func file_cns_proto_rawDescGZIP() []byte {
+	file_cns_proto_rawDescOnce.Do(func() {
+		file_cns_proto_rawDescData = protoimpl.X.CompressGZIP(file_cns_proto_rawDescData)
+	})
+	return file_cns_proto_rawDescData
+}
+
+var file_cns_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_cns_proto_goTypes = []interface{}{
+	(*Response)(nil),                        // 0: cns.v1.Response
+	(*IPConfigsRequest)(nil),                // 1: cns.v1.IPConfigsRequest
+	(*IPConfigsResponse)(nil),               // 2: cns.v1.IPConfigsResponse
+	(*IPSubnet)(nil),                        // 3: cns.v1.IPSubnet
+	(*IPConfiguration)(nil),                 // 4: cns.v1.IPConfiguration
+	(*HostIPInfo)(nil),                      // 5: cns.v1.HostIPInfo
+	(*Route)(nil),                           // 6: cns.v1.Route
+	(*PodIPInfo)(nil),                       // 7: cns.v1.PodIPInfo
+	(*SecondaryIPConfig)(nil),               // 8: cns.v1.SecondaryIPConfig
+	(*MultiTenancyInfo)(nil),                // 9: cns.v1.MultiTenancyInfo
+	(*NetworkInterfaceInfo)(nil),            // 10: cns.v1.NetworkInterfaceInfo
+	(*NetworkContainerRequestPolicy)(nil),   // 11: cns.v1.NetworkContainerRequestPolicy
+	(*CreateNetworkContainerRequest)(nil),   // 12: cns.v1.CreateNetworkContainerRequest
+	(*DeleteNetworkContainerRequest)(nil),   // 13: cns.v1.DeleteNetworkContainerRequest
+	(*GetNetworkContainerRequest)(nil),      // 14: cns.v1.GetNetworkContainerRequest
+	(*GetNetworkContainerResponse)(nil),     // 15: cns.v1.GetNetworkContainerResponse
+	(*GetAllNetworkContainersResponse)(nil), // 16: cns.v1.GetAllNetworkContainersResponse
+	(*GetEndpointRequest)(nil),              // 17: cns.v1.GetEndpointRequest
+	(*IPInfo)(nil),                          // 18: cns.v1.IPInfo
+	(*EndpointInfo)(nil),                    // 19: cns.v1.EndpointInfo
+	(*GetEndpointResponse)(nil),             // 20: cns.v1.GetEndpointResponse
+	(*UpdateEndpointRequest)(nil),           // 21: cns.v1.UpdateEndpointRequest
+	(*WatchIPStateEventsRequest)(nil),       // 22: cns.v1.WatchIPStateEventsRequest
+	(*PodInfo)(nil),                         // 23: cns.v1.PodInfo
+	(*IPStateEvent)(nil),                    // 24: cns.v1.IPStateEvent
+	nil,                                     // 25: cns.v1.CreateNetworkContainerRequest.SecondaryIpConfigsEntry
+	nil,                                     // 26: cns.v1.EndpointInfo.IfnameToIpsEntry
+	(*timestamppb.Timestamp)(nil),           // 27: google.protobuf.Timestamp
+}
+var file_cns_proto_depIdxs = []int32{
+	7,  // 0: cns.v1.IPConfigsResponse.pod_ip_info:type_name -> cns.v1.PodIPInfo
+	0,  // 1: cns.v1.IPConfigsResponse.response:type_name -> cns.v1.Response
+	3,  // 2: cns.v1.IPConfiguration.ip_subnet:type_name -> cns.v1.IPSubnet
+	3,  // 3: cns.v1.PodIPInfo.pod_ip_config:type_name -> cns.v1.IPSubnet
+	4,  // 4: cns.v1.PodIPInfo.network_container_primary_ip_config:type_name -> cns.v1.IPConfiguration
+	5,  // 5: cns.v1.PodIPInfo.host_primary_ip_info:type_name -> cns.v1.HostIPInfo
+	6,  // 6: cns.v1.PodIPInfo.routes:type_name -> cns.v1.Route
+	4,  // 7: cns.v1.CreateNetworkContainerRequest.local_ip_configuration:type_name -> cns.v1.IPConfiguration
+	4,  // 8: cns.v1.CreateNetworkContainerRequest.ip_configuration:type_name -> cns.v1.IPConfiguration
+	25, // 9: cns.v1.CreateNetworkContainerRequest.secondary_ip_configs:type_name -> cns.v1.CreateNetworkContainerRequest.SecondaryIpConfigsEntry
+	9,  // 10: cns.v1.CreateNetworkContainerRequest.multi_tenancy_info:type_name -> cns.v1.MultiTenancyInfo
+	3,  // 11: cns.v1.CreateNetworkContainerRequest.cnet_address_space:type_name -> cns.v1.IPSubnet
+	6,  // 12: cns.v1.CreateNetworkContainerRequest.routes:type_name -> cns.v1.Route
+	11, // 13: cns.v1.CreateNetworkContainerRequest.endpoint_policies:type_name -> cns.v1.NetworkContainerRequestPolicy
+	10, // 14: cns.v1.CreateNetworkContainerRequest.network_interface_info:type_name -> cns.v1.NetworkInterfaceInfo
+	4,  // 15: cns.v1.GetNetworkContainerResponse.ip_configuration:type_name -> cns.v1.IPConfiguration
+	6,  // 16: cns.v1.GetNetworkContainerResponse.routes:type_name -> cns.v1.Route
+	3,  // 17: cns.v1.GetNetworkContainerResponse.cnet_address_space:type_name -> cns.v1.IPSubnet
+	9,  // 18: cns.v1.GetNetworkContainerResponse.multi_tenancy_info:type_name -> cns.v1.MultiTenancyInfo
+	4,  // 19: cns.v1.GetNetworkContainerResponse.local_ip_configuration:type_name -> cns.v1.IPConfiguration
+	0,  // 20: cns.v1.GetNetworkContainerResponse.response:type_name -> cns.v1.Response
+	10, // 21: cns.v1.GetNetworkContainerResponse.network_interface_info:type_name -> cns.v1.NetworkInterfaceInfo
+	15, // 22: cns.v1.GetAllNetworkContainersResponse.network_containers:type_name -> cns.v1.GetNetworkContainerResponse
+	0,  // 23: cns.v1.GetAllNetworkContainersResponse.response:type_name -> cns.v1.Response
+	26, // 24: cns.v1.EndpointInfo.ifname_to_ips:type_name -> cns.v1.EndpointInfo.IfnameToIpsEntry
+	0,  // 25: cns.v1.GetEndpointResponse.response:type_name -> cns.v1.Response
+	19, // 26: cns.v1.GetEndpointResponse.endpoint_info:type_name -> cns.v1.EndpointInfo
+	23, // 27: cns.v1.IPStateEvent.pod_info:type_name -> cns.v1.PodInfo
+	27, // 28: cns.v1.IPStateEvent.timestamp:type_name -> google.protobuf.Timestamp
+	8,  // 29: cns.v1.CreateNetworkContainerRequest.SecondaryIpConfigsEntry.value:type_name -> cns.v1.SecondaryIPConfig
+	18, // 30: cns.v1.EndpointInfo.IfnameToIpsEntry.value:type_name -> cns.v1.IPInfo
+	1,  // 31: cns.v1.CNS.RequestIPs:input_type -> cns.v1.IPConfigsRequest
+	1,  // 32: cns.v1.CNS.ReleaseIPs:input_type -> cns.v1.IPConfigsRequest
+	12, // 33: cns.v1.CNS.CreateOrUpdateNetworkContainer:input_type -> cns.v1.CreateNetworkContainerRequest
+	13, // 34: cns.v1.CNS.DeleteNetworkContainer:input_type -> cns.v1.DeleteNetworkContainerRequest
+	14, // 35: cns.v1.CNS.GetNetworkContainer:input_type -> cns.v1.GetNetworkContainerRequest
+	14, // 36: cns.v1.CNS.GetAllNetworkContainers:input_type -> cns.v1.GetNetworkContainerRequest
+	17, // 37: cns.v1.CNS.GetEndpoint:input_type -> cns.v1.GetEndpointRequest
+	21, // 38: cns.v1.CNS.UpdateEndpoint:input_type -> cns.v1.UpdateEndpointRequest
+	22, // 39: cns.v1.CNS.WatchIPStateEvents:input_type -> cns.v1.WatchIPStateEventsRequest
+	2,  // 40: cns.v1.CNS.RequestIPs:output_type -> cns.v1.IPConfigsResponse
+	2,  // 41: cns.v1.CNS.ReleaseIPs:output_type -> cns.v1.IPConfigsResponse
+	0,  // 42: cns.v1.CNS.CreateOrUpdateNetworkContainer:output_type -> cns.v1.Response
+	0,  // 43: cns.v1.CNS.DeleteNetworkContainer:output_type -> cns.v1.Response
+	15, // 44: cns.v1.CNS.GetNetworkContainer:output_type -> cns.v1.GetNetworkContainerResponse
+	16, // 45: cns.v1.CNS.GetAllNetworkContainers:output_type -> cns.v1.GetAllNetworkContainersResponse
+	20, // 46: cns.v1.CNS.GetEndpoint:output_type -> cns.v1.GetEndpointResponse
+	0,  // 47: cns.v1.CNS.UpdateEndpoint:output_type -> cns.v1.Response
+	24, // 48: cns.v1.CNS.WatchIPStateEvents:output_type -> cns.v1.IPStateEvent
+	40, // [40:49] is the sub-list for method output_type
+	31, // [31:40] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
+}
+
+func init() { file_cns_proto_init() }
+func file_cns_proto_init() {
+	if File_cns_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_cns_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPSubnet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HostIPInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Route); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodIPInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecondaryIPConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MultiTenancyInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NetworkInterfaceInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NetworkContainerRequestPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateNetworkContainerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteNetworkContainerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetNetworkContainerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetNetworkContainerResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetAllNetworkContainersResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetEndpointRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EndpointInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetEndpointResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateEndpointRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchIPStateEventsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cns_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPStateEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_cns_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   27,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cns_proto_goTypes,
+		DependencyIndexes: file_cns_proto_depIdxs,
+		MessageInfos:      file_cns_proto_msgTypes,
+	}.Build()
+	File_cns_proto = out.File
+	file_cns_proto_rawDesc = nil
+	file_cns_proto_goTypes = nil
+	file_cns_proto_depIdxs = nil
+}
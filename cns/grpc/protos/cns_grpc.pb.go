@@ -0,0 +1,451 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.19.1
+// source: cns.proto
+
+package protos
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CNS_RequestIPs_FullMethodName                     = "/cns.v1.CNS/RequestIPs"
+	CNS_ReleaseIPs_FullMethodName                     = "/cns.v1.CNS/ReleaseIPs"
+	CNS_CreateOrUpdateNetworkContainer_FullMethodName = "/cns.v1.CNS/CreateOrUpdateNetworkContainer"
+	CNS_DeleteNetworkContainer_FullMethodName         = "/cns.v1.CNS/DeleteNetworkContainer"
+	CNS_GetNetworkContainer_FullMethodName            = "/cns.v1.CNS/GetNetworkContainer"
+	CNS_GetAllNetworkContainers_FullMethodName        = "/cns.v1.CNS/GetAllNetworkContainers"
+	CNS_GetEndpoint_FullMethodName                    = "/cns.v1.CNS/GetEndpoint"
+	CNS_UpdateEndpoint_FullMethodName                 = "/cns.v1.CNS/UpdateEndpoint"
+	CNS_WatchIPStateEvents_FullMethodName             = "/cns.v1.CNS/WatchIPStateEvents"
+)
+
+// CNSClient is the client API for CNS service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CNSClient interface {
+	// RequestIPs assigns IPs to a Pod, as POST /network/requestipconfigs.
+	RequestIPs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error)
+	// ReleaseIPs releases the IPs of a Pod, as POST /network/releaseipconfigs.
+	ReleaseIPs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error)
+	// CreateOrUpdateNetworkContainer creates or updates an NC, as the NodeNetworkConfig reconciler does.
+	CreateOrUpdateNetworkContainer(ctx context.Context, in *CreateNetworkContainerRequest, opts ...grpc.CallOption) (*Response, error)
+	// DeleteNetworkContainer deletes an NC.
+	DeleteNetworkContainer(ctx context.Context, in *DeleteNetworkContainerRequest, opts ...grpc.CallOption) (*Response, error)
+	// GetNetworkContainer gets the NC of a Pod, as POST /network/getnetworkcontainerbyorchestratorcontext.
+	GetNetworkContainer(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetNetworkContainerResponse, error)
+	// GetAllNetworkContainers gets the NCs of a Pod, as POST /network/getAllNetworkContainers.
+	GetAllNetworkContainers(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetAllNetworkContainersResponse, error)
+	// GetEndpoint gets the state of an endpoint, as GET /network/endpoints/{id}.
+	GetEndpoint(ctx context.Context, in *GetEndpointRequest, opts ...grpc.CallOption) (*GetEndpointResponse, error)
+	// UpdateEndpoint updates the state of an endpoint, as PATCH /network/endpoints/{id}.
+	UpdateEndpoint(ctx context.Context, in *UpdateEndpointRequest, opts ...grpc.CallOption) (*Response, error)
+	// WatchIPStateEvents streams the IP state transitions, as GET /network/ipstateevents.
+	WatchIPStateEvents(ctx context.Context, in *WatchIPStateEventsRequest, opts ...grpc.CallOption) (CNS_WatchIPStateEventsClient, error)
+}
+
+type cNSClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCNSClient(cc grpc.ClientConnInterface) CNSClient {
+	return &cNSClient{cc}
+}
+
+func (c *cNSClient) RequestIPs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error) {
+	out := new(IPConfigsResponse)
+	err := c.cc.Invoke(ctx, CNS_RequestIPs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) ReleaseIPs(ctx context.Context, in *IPConfigsRequest, opts ...grpc.CallOption) (*IPConfigsResponse, error) {
+	out := new(IPConfigsResponse)
+	err := c.cc.Invoke(ctx, CNS_ReleaseIPs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) CreateOrUpdateNetworkContainer(ctx context.Context, in *CreateNetworkContainerRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, CNS_CreateOrUpdateNetworkContainer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) DeleteNetworkContainer(ctx context.Context, in *DeleteNetworkContainerRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, CNS_DeleteNetworkContainer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetNetworkContainer(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetNetworkContainerResponse, error) {
+	out := new(GetNetworkContainerResponse)
+	err := c.cc.Invoke(ctx, CNS_GetNetworkContainer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetAllNetworkContainers(ctx context.Context, in *GetNetworkContainerRequest, opts ...grpc.CallOption) (*GetAllNetworkContainersResponse, error) {
+	out := new(GetAllNetworkContainersResponse)
+	err := c.cc.Invoke(ctx, CNS_GetAllNetworkContainers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) GetEndpoint(ctx context.Context, in *GetEndpointRequest, opts ...grpc.CallOption) (*GetEndpointResponse, error) {
+	out := new(GetEndpointResponse)
+	err := c.cc.Invoke(ctx, CNS_GetEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) UpdateEndpoint(ctx context.Context, in *UpdateEndpointRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, CNS_UpdateEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSClient) WatchIPStateEvents(ctx context.Context, in *WatchIPStateEventsRequest, opts ...grpc.CallOption) (CNS_WatchIPStateEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CNS_ServiceDesc.Streams[0], CNS_WatchIPStateEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cNSWatchIPStateEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CNS_WatchIPStateEventsClient interface {
+	Recv() (*IPStateEvent, error)
+	grpc.ClientStream
+}
+
+type cNSWatchIPStateEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *cNSWatchIPStateEventsClient) Recv() (*IPStateEvent, error) {
+	m := new(IPStateEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CNSServer is the server API for CNS service.
+// All implementations must embed UnimplementedCNSServer
+// for forward compatibility
+type CNSServer interface {
+	// RequestIPs assigns IPs to a Pod, as POST /network/requestipconfigs.
+	RequestIPs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error)
+	// ReleaseIPs releases the IPs of a Pod, as POST /network/releaseipconfigs.
+	ReleaseIPs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error)
+	// CreateOrUpdateNetworkContainer creates or updates an NC, as the NodeNetworkConfig reconciler does.
+	CreateOrUpdateNetworkContainer(context.Context, *CreateNetworkContainerRequest) (*Response, error)
+	// DeleteNetworkContainer deletes an NC.
+	DeleteNetworkContainer(context.Context, *DeleteNetworkContainerRequest) (*Response, error)
+	// GetNetworkContainer gets the NC of a Pod, as POST /network/getnetworkcontainerbyorchestratorcontext.
+	GetNetworkContainer(context.Context, *GetNetworkContainerRequest) (*GetNetworkContainerResponse, error)
+	// GetAllNetworkContainers gets the NCs of a Pod, as POST /network/getAllNetworkContainers.
+	GetAllNetworkContainers(context.Context, *GetNetworkContainerRequest) (*GetAllNetworkContainersResponse, error)
+	// GetEndpoint gets the state of an endpoint, as GET /network/endpoints/{id}.
+	GetEndpoint(context.Context, *GetEndpointRequest) (*GetEndpointResponse, error)
+	// UpdateEndpoint updates the state of an endpoint, as PATCH /network/endpoints/{id}.
+	UpdateEndpoint(context.Context, *UpdateEndpointRequest) (*Response, error)
+	// WatchIPStateEvents streams the IP state transitions, as GET /network/ipstateevents.
+	WatchIPStateEvents(*WatchIPStateEventsRequest, CNS_WatchIPStateEventsServer) error
+	mustEmbedUnimplementedCNSServer()
+}
+
+// UnimplementedCNSServer must be embedded to have forward compatible implementations.
+type UnimplementedCNSServer struct {
+}
+
+func (UnimplementedCNSServer) RequestIPs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestIPs not implemented")
+}
+func (UnimplementedCNSServer) ReleaseIPs(context.Context, *IPConfigsRequest) (*IPConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseIPs not implemented")
+}
+func (UnimplementedCNSServer) CreateOrUpdateNetworkContainer(context.Context, *CreateNetworkContainerRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrUpdateNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) DeleteNetworkContainer(context.Context, *DeleteNetworkContainerRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) GetNetworkContainer(context.Context, *GetNetworkContainerRequest) (*GetNetworkContainerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkContainer not implemented")
+}
+func (UnimplementedCNSServer) GetAllNetworkContainers(context.Context, *GetNetworkContainerRequest) (*GetAllNetworkContainersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllNetworkContainers not implemented")
+}
+func (UnimplementedCNSServer) GetEndpoint(context.Context, *GetEndpointRequest) (*GetEndpointResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEndpoint not implemented")
+}
+func (UnimplementedCNSServer) UpdateEndpoint(context.Context, *UpdateEndpointRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateEndpoint not implemented")
+}
+func (UnimplementedCNSServer) WatchIPStateEvents(*WatchIPStateEventsRequest, CNS_WatchIPStateEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchIPStateEvents not implemented")
+}
+func (UnimplementedCNSServer) mustEmbedUnimplementedCNSServer() {}
+
+// UnsafeCNSServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CNSServer will
+// result in compilation errors.
+type UnsafeCNSServer interface {
+	mustEmbedUnimplementedCNSServer()
+}
+
+func RegisterCNSServer(s grpc.ServiceRegistrar, srv CNSServer) {
+	s.RegisterService(&CNS_ServiceDesc, srv)
+}
+
+func _CNS_RequestIPs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).RequestIPs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_RequestIPs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).RequestIPs(ctx, req.(*IPConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_ReleaseIPs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).ReleaseIPs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_ReleaseIPs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).ReleaseIPs(ctx, req.(*IPConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_CreateOrUpdateNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).CreateOrUpdateNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_CreateOrUpdateNetworkContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).CreateOrUpdateNetworkContainer(ctx, req.(*CreateNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_DeleteNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).DeleteNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_DeleteNetworkContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).DeleteNetworkContainer(ctx, req.(*DeleteNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetNetworkContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetNetworkContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_GetNetworkContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetNetworkContainer(ctx, req.(*GetNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetAllNetworkContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkContainerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetAllNetworkContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_GetAllNetworkContainers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetAllNetworkContainers(ctx, req.(*GetNetworkContainerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_GetEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).GetEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_GetEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).GetEndpoint(ctx, req.(*GetEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_UpdateEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSServer).UpdateEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CNS_UpdateEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSServer).UpdateEndpoint(ctx, req.(*UpdateEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNS_WatchIPStateEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchIPStateEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CNSServer).WatchIPStateEvents(m, &cNSWatchIPStateEventsServer{stream})
+}
+
+type CNS_WatchIPStateEventsServer interface {
+	Send(*IPStateEvent) error
+	grpc.ServerStream
+}
+
+type cNSWatchIPStateEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *cNSWatchIPStateEventsServer) Send(m *IPStateEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CNS_ServiceDesc is the grpc.ServiceDesc for CNS service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CNS_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cns.v1.CNS",
+	HandlerType: (*CNSServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestIPs",
+			Handler:    _CNS_RequestIPs_Handler,
+		},
+		{
+			MethodName: "ReleaseIPs",
+			Handler:    _CNS_ReleaseIPs_Handler,
+		},
+		{
+			MethodName: "CreateOrUpdateNetworkContainer",
+			Handler:    _CNS_CreateOrUpdateNetworkContainer_Handler,
+		},
+		{
+			MethodName: "DeleteNetworkContainer",
+			Handler:    _CNS_DeleteNetworkContainer_Handler,
+		},
+		{
+			MethodName: "GetNetworkContainer",
+			Handler:    _CNS_GetNetworkContainer_Handler,
+		},
+		{
+			MethodName: "GetAllNetworkContainers",
+			Handler:    _CNS_GetAllNetworkContainers_Handler,
+		},
+		{
+			MethodName: "GetEndpoint",
+			Handler:    _CNS_GetEndpoint_Handler,
+		},
+		{
+			MethodName: "UpdateEndpoint",
+			Handler:    _CNS_UpdateEndpoint_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchIPStateEvents",
+			Handler:       _CNS_WatchIPStateEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cns.proto",
+}
@@ -0,0 +1,343 @@
+package protos
+
+import (
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// This file converts between the messages and the REST API contract in the cns package, which the messages
+// mirror. The From functions convert to a message and the ToCNS methods back, and are nil-safe.
+
+// FromResponse converts a CNS Response.
+func FromResponse(r cns.Response) *Response {
+	return &Response{ReturnCode: int32(r.ReturnCode), Message: r.Message}
+}
+
+// ToCNS converts the Response.
+func (x *Response) ToCNS() cns.Response {
+	return cns.Response{ReturnCode: types.ResponseCode(x.GetReturnCode()), Message: x.GetMessage()}
+}
+
+// FromIPConfigsRequest converts a CNS IPConfigsRequest.
+func FromIPConfigsRequest(r cns.IPConfigsRequest) *IPConfigsRequest {
+	return &IPConfigsRequest{
+		DesiredIpAddresses:       r.DesiredIPAddresses,
+		PodInterfaceId:           r.PodInterfaceID,
+		InfraContainerId:         r.InfraContainerID,
+		OrchestratorContext:      r.OrchestratorContext,
+		Ifname:                   r.Ifname,
+		SecondaryInterfacesExist: r.SecondaryInterfacesExist,
+	}
+}
+
+// ToCNS converts the IPConfigsRequest.
+func (x *IPConfigsRequest) ToCNS() cns.IPConfigsRequest {
+	return cns.IPConfigsRequest{
+		DesiredIPAddresses:       x.GetDesiredIpAddresses(),
+		PodInterfaceID:           x.GetPodInterfaceId(),
+		InfraContainerID:         x.GetInfraContainerId(),
+		OrchestratorContext:      x.GetOrchestratorContext(),
+		Ifname:                   x.GetIfname(),
+		SecondaryInterfacesExist: x.GetSecondaryInterfacesExist(),
+	}
+}
+
+// FromIPConfigsResponse converts a CNS IPConfigsResponse.
+func FromIPConfigsResponse(r *cns.IPConfigsResponse) *IPConfigsResponse {
+	if r == nil {
+		return &IPConfigsResponse{}
+	}
+	x := &IPConfigsResponse{Response: FromResponse(r.Response)}
+	for i := range r.PodIPInfo {
+		x.PodIpInfo = append(x.PodIpInfo, FromPodIPInfo(&r.PodIPInfo[i]))
+	}
+	return x
+}
+
+// ToCNS converts the IPConfigsResponse.
+func (x *IPConfigsResponse) ToCNS() *cns.IPConfigsResponse {
+	r := &cns.IPConfigsResponse{Response: x.GetResponse().ToCNS()}
+	for _, info := range x.GetPodIpInfo() {
+		r.PodIPInfo = append(r.PodIPInfo, info.ToCNS())
+	}
+	return r
+}
+
+// FromIPSubnet converts a CNS IPSubnet.
+func FromIPSubnet(s cns.IPSubnet) *IPSubnet {
+	return &IPSubnet{IpAddress: s.IPAddress, PrefixLength: uint32(s.PrefixLength)}
+}
+
+// ToCNS converts the IPSubnet.
+func (x *IPSubnet) ToCNS() cns.IPSubnet {
+	return cns.IPSubnet{IPAddress: x.GetIpAddress(), PrefixLength: uint8(x.GetPrefixLength())}
+}
+
+func fromIPSubnets(subnets []cns.IPSubnet) []*IPSubnet {
+	var x []*IPSubnet
+	for _, s := range subnets {
+		x = append(x, FromIPSubnet(s))
+	}
+	return x
+}
+
+func ipSubnetsToCNS(x []*IPSubnet) []cns.IPSubnet {
+	var subnets []cns.IPSubnet
+	for _, s := range x {
+		subnets = append(subnets, s.ToCNS())
+	}
+	return subnets
+}
+
+// FromIPConfiguration converts a CNS IPConfiguration.
+func FromIPConfiguration(c cns.IPConfiguration) *IPConfiguration {
+	return &IPConfiguration{
+		IpSubnet:         FromIPSubnet(c.IPSubnet),
+		DnsServers:       c.DNSServers,
+		GatewayIpAddress: c.GatewayIPAddress,
+	}
+}
+
+// ToCNS converts the IPConfiguration.
+func (x *IPConfiguration) ToCNS() cns.IPConfiguration {
+	return cns.IPConfiguration{
+		IPSubnet:         x.GetIpSubnet().ToCNS(),
+		DNSServers:       x.GetDnsServers(),
+		GatewayIPAddress: x.GetGatewayIpAddress(),
+	}
+}
+
+func fromRoutes(routes []cns.Route) []*Route {
+	var x []*Route
+	for _, r := range routes {
+		x = append(x, &Route{IpAddress: r.IPAddress, GatewayIpAddress: r.GatewayIPAddress, InterfaceToUse: r.InterfaceToUse})
+	}
+	return x
+}
+
+func routesToCNS(x []*Route) []cns.Route {
+	var routes []cns.Route
+	for _, r := range x {
+		routes = append(routes, cns.Route{IPAddress: r.GetIpAddress(), GatewayIPAddress: r.GetGatewayIpAddress(), InterfaceToUse: r.GetInterfaceToUse()})
+	}
+	return routes
+}
+
+// FromPodIPInfo converts a CNS PodIpInfo.
+func FromPodIPInfo(info *cns.PodIpInfo) *PodIPInfo {
+	return &PodIPInfo{
+		PodIpConfig:                     FromIPSubnet(info.PodIPConfig),
+		NetworkContainerPrimaryIpConfig: FromIPConfiguration(info.NetworkContainerPrimaryIPConfig),
+		HostPrimaryIpInfo: &HostIPInfo{
+			Gateway:   info.HostPrimaryIPInfo.Gateway,
+			PrimaryIp: info.HostPrimaryIPInfo.PrimaryIP,
+			Subnet:    info.HostPrimaryIPInfo.Subnet,
+		},
+		NicType:           string(info.NICType),
+		InterfaceName:     info.InterfaceName,
+		MacAddress:        info.MacAddress,
+		SkipDefaultRoutes: info.SkipDefaultRoutes,
+		Routes:            fromRoutes(info.Routes),
+	}
+}
+
+// ToCNS converts the PodIPInfo.
+func (x *PodIPInfo) ToCNS() cns.PodIpInfo {
+	return cns.PodIpInfo{
+		PodIPConfig:                     x.GetPodIpConfig().ToCNS(),
+		NetworkContainerPrimaryIPConfig: x.GetNetworkContainerPrimaryIpConfig().ToCNS(),
+		HostPrimaryIPInfo: cns.HostIPInfo{
+			Gateway:   x.GetHostPrimaryIpInfo().GetGateway(),
+			PrimaryIP: x.GetHostPrimaryIpInfo().GetPrimaryIp(),
+			Subnet:    x.GetHostPrimaryIpInfo().GetSubnet(),
+		},
+		NICType:           cns.NICType(x.GetNicType()),
+		InterfaceName:     x.GetInterfaceName(),
+		MacAddress:        x.GetMacAddress(),
+		SkipDefaultRoutes: x.GetSkipDefaultRoutes(),
+		Routes:            routesToCNS(x.GetRoutes()),
+	}
+}
+
+func fromMultiTenancyInfo(m cns.MultiTenancyInfo) *MultiTenancyInfo {
+	return &MultiTenancyInfo{EncapType: m.EncapType, Id: int64(m.ID)}
+}
+
+func (x *MultiTenancyInfo) toCNS() cns.MultiTenancyInfo {
+	return cns.MultiTenancyInfo{EncapType: x.GetEncapType(), ID: int(x.GetId())}
+}
+
+func fromNetworkInterfaceInfo(n cns.NetworkInterfaceInfo) *NetworkInterfaceInfo {
+	return &NetworkInterfaceInfo{NicType: string(n.NICType), MacAddress: n.MACAddress}
+}
+
+func (x *NetworkInterfaceInfo) toCNS() cns.NetworkInterfaceInfo {
+	return cns.NetworkInterfaceInfo{NICType: cns.NICType(x.GetNicType()), MACAddress: x.GetMacAddress()}
+}
+
+// FromCreateNetworkContainerRequest converts a CNS CreateNetworkContainerRequest.
+func FromCreateNetworkContainerRequest(r *cns.CreateNetworkContainerRequest) *CreateNetworkContainerRequest {
+	x := &CreateNetworkContainerRequest{
+		HostPrimaryIp:              r.HostPrimaryIP,
+		Version:                    r.Version,
+		NetworkContainerType:       r.NetworkContainerType,
+		NetworkContainerId:         r.NetworkContainerid,
+		PrimaryInterfaceIdentifier: r.PrimaryInterfaceIdentifier,
+		AuthorizationToken:         r.AuthorizationToken,
+		LocalIpConfiguration:       FromIPConfiguration(r.LocalIPConfiguration),
+		OrchestratorContext:        r.OrchestratorContext,
+		IpConfiguration:            FromIPConfiguration(r.IPConfiguration),
+		MultiTenancyInfo:           fromMultiTenancyInfo(r.MultiTenancyInfo),
+		CnetAddressSpace:           fromIPSubnets(r.CnetAddressSpace),
+		Routes:                     fromRoutes(r.Routes),
+		AllowHostToNcCommunication: r.AllowHostToNCCommunication,
+		AllowNcToHostCommunication: r.AllowNCToHostCommunication,
+		NcStatus:                   string(r.NCStatus),
+		NetworkInterfaceInfo:       fromNetworkInterfaceInfo(r.NetworkInterfaceInfo),
+	}
+	if r.SecondaryIPConfigs != nil {
+		x.SecondaryIpConfigs = make(map[string]*SecondaryIPConfig, len(r.SecondaryIPConfigs))
+		for id, c := range r.SecondaryIPConfigs {
+			x.SecondaryIpConfigs[id] = &SecondaryIPConfig{IpAddress: c.IPAddress, NcVersion: int64(c.NCVersion)}
+		}
+	}
+	for _, p := range r.EndpointPolicies {
+		x.EndpointPolicies = append(x.EndpointPolicies, &NetworkContainerRequestPolicy{
+			Type:         p.Type,
+			EndpointType: p.EndpointType,
+			Settings:     p.Settings,
+		})
+	}
+	return x
+}
+
+// ToCNS converts the CreateNetworkContainerRequest.
+func (x *CreateNetworkContainerRequest) ToCNS() *cns.CreateNetworkContainerRequest {
+	r := &cns.CreateNetworkContainerRequest{
+		HostPrimaryIP:              x.GetHostPrimaryIp(),
+		Version:                    x.GetVersion(),
+		NetworkContainerType:       x.GetNetworkContainerType(),
+		NetworkContainerid:         x.GetNetworkContainerId(),
+		PrimaryInterfaceIdentifier: x.GetPrimaryInterfaceIdentifier(),
+		AuthorizationToken:         x.GetAuthorizationToken(),
+		LocalIPConfiguration:       x.GetLocalIpConfiguration().ToCNS(),
+		OrchestratorContext:        x.GetOrchestratorContext(),
+		IPConfiguration:            x.GetIpConfiguration().ToCNS(),
+		MultiTenancyInfo:           x.GetMultiTenancyInfo().toCNS(),
+		CnetAddressSpace:           ipSubnetsToCNS(x.GetCnetAddressSpace()),
+		Routes:                     routesToCNS(x.GetRoutes()),
+		AllowHostToNCCommunication: x.GetAllowHostToNcCommunication(),
+		AllowNCToHostCommunication: x.GetAllowNcToHostCommunication(),
+		NCStatus:                   v1alpha.NCStatus(x.GetNcStatus()),
+		NetworkInterfaceInfo:       x.GetNetworkInterfaceInfo().toCNS(),
+	}
+	if x.GetSecondaryIpConfigs() != nil {
+		r.SecondaryIPConfigs = make(map[string]cns.SecondaryIPConfig, len(x.GetSecondaryIpConfigs()))
+		for id, c := range x.GetSecondaryIpConfigs() {
+			r.SecondaryIPConfigs[id] = cns.SecondaryIPConfig{IPAddress: c.GetIpAddress(), NCVersion: int(c.GetNcVersion())}
+		}
+	}
+	for _, p := range x.GetEndpointPolicies() {
+		r.EndpointPolicies = append(r.EndpointPolicies, cns.NetworkContainerRequestPolicies{
+			Type:         p.GetType(),
+			EndpointType: p.GetEndpointType(),
+			Settings:     p.GetSettings(),
+		})
+	}
+	return r
+}
+
+// FromGetNetworkContainerResponse converts a CNS GetNetworkContainerResponse.
+func FromGetNetworkContainerResponse(r *cns.GetNetworkContainerResponse) *GetNetworkContainerResponse {
+	return &GetNetworkContainerResponse{
+		NetworkContainerId:         r.NetworkContainerID,
+		IpConfiguration:            FromIPConfiguration(r.IPConfiguration),
+		Routes:                     fromRoutes(r.Routes),
+		CnetAddressSpace:           fromIPSubnets(r.CnetAddressSpace),
+		MultiTenancyInfo:           fromMultiTenancyInfo(r.MultiTenancyInfo),
+		PrimaryInterfaceIdentifier: r.PrimaryInterfaceIdentifier,
+		LocalIpConfiguration:       FromIPConfiguration(r.LocalIPConfiguration),
+		Response:                   FromResponse(r.Response),
+		AllowHostToNcCommunication: r.AllowHostToNCCommunication,
+		AllowNcToHostCommunication: r.AllowNCToHostCommunication,
+		NetworkInterfaceInfo:       fromNetworkInterfaceInfo(r.NetworkInterfaceInfo),
+	}
+}
+
+// ToCNS converts the GetNetworkContainerResponse.
+func (x *GetNetworkContainerResponse) ToCNS() cns.GetNetworkContainerResponse {
+	return cns.GetNetworkContainerResponse{
+		NetworkContainerID:         x.GetNetworkContainerId(),
+		IPConfiguration:            x.GetIpConfiguration().ToCNS(),
+		Routes:                     routesToCNS(x.GetRoutes()),
+		CnetAddressSpace:           ipSubnetsToCNS(x.GetCnetAddressSpace()),
+		MultiTenancyInfo:           x.GetMultiTenancyInfo().toCNS(),
+		PrimaryInterfaceIdentifier: x.GetPrimaryInterfaceIdentifier(),
+		LocalIPConfiguration:       x.GetLocalIpConfiguration().ToCNS(),
+		Response:                   x.GetResponse().ToCNS(),
+		AllowHostToNCCommunication: x.GetAllowHostToNcCommunication(),
+		AllowNCToHostCommunication: x.GetAllowNcToHostCommunication(),
+		NetworkInterfaceInfo:       x.GetNetworkInterfaceInfo().toCNS(),
+	}
+}
+
+// FromGetAllNetworkContainersResponse converts a CNS GetAllNetworkContainersResponse.
+func FromGetAllNetworkContainersResponse(r *cns.GetAllNetworkContainersResponse) *GetAllNetworkContainersResponse {
+	x := &GetAllNetworkContainersResponse{Response: FromResponse(r.Response)}
+	for i := range r.NetworkContainers {
+		x.NetworkContainers = append(x.NetworkContainers, FromGetNetworkContainerResponse(&r.NetworkContainers[i]))
+	}
+	return x
+}
+
+// ToCNS converts the GetAllNetworkContainersResponse.
+func (x *GetAllNetworkContainersResponse) ToCNS() cns.GetAllNetworkContainersResponse {
+	r := cns.GetAllNetworkContainersResponse{Response: x.GetResponse().ToCNS()}
+	for _, nc := range x.GetNetworkContainers() {
+		r.NetworkContainers = append(r.NetworkContainers, nc.ToCNS())
+	}
+	return r
+}
+
+// FromIPStateEvent converts a CNS IPStateEvent.
+func FromIPStateEvent(e *cns.IPStateEvent) *IPStateEvent {
+	x := &IPStateEvent{
+		Sequence:  e.Sequence,
+		Id:        e.ID,
+		IpAddress: e.IPAddress,
+		NcId:      e.NCID,
+		OldState:  string(e.OldState),
+		NewState:  string(e.NewState),
+		Timestamp: timestamppb.New(e.Timestamp),
+	}
+	if e.PodInfo != nil {
+		x.PodInfo = &PodInfo{
+			Name:             e.PodInfo.Name(),
+			Namespace:        e.PodInfo.Namespace(),
+			InfraContainerId: e.PodInfo.InfraContainerID(),
+			InterfaceId:      e.PodInfo.InterfaceID(),
+		}
+	}
+	return x
+}
+
+// ToCNS converts the IPStateEvent.
+func (x *IPStateEvent) ToCNS() cns.IPStateEvent {
+	e := cns.IPStateEvent{
+		Sequence:  x.GetSequence(),
+		ID:        x.GetId(),
+		IPAddress: x.GetIpAddress(),
+		NCID:      x.GetNcId(),
+		OldState:  types.IPState(x.GetOldState()),
+		NewState:  types.IPState(x.GetNewState()),
+	}
+	if x.GetTimestamp() != nil {
+		e.Timestamp = x.GetTimestamp().AsTime()
+	}
+	if p := x.GetPodInfo(); p != nil {
+		e.PodInfo = cns.NewPodInfo(p.GetInfraContainerId(), p.GetInterfaceId(), p.GetName(), p.GetNamespace())
+	}
+	return e
+}
@@ -0,0 +1,88 @@
+package protos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateNetworkContainerRequestRoundTrip(t *testing.T) {
+	req := &cns.CreateNetworkContainerRequest{
+		HostPrimaryIP:              "10.224.0.4",
+		Version:                    "1",
+		NetworkContainerType:       cns.Docker,
+		NetworkContainerid:         "nc",
+		PrimaryInterfaceIdentifier: "10.0.0.0/24",
+		LocalIPConfiguration: cns.IPConfiguration{
+			IPSubnet: cns.IPSubnet{IPAddress: "169.254.0.4", PrefixLength: 17},
+		},
+		OrchestratorContext: json.RawMessage(`{"PodName":"pod"}`),
+		IPConfiguration: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24},
+			DNSServers:       []string{"8.8.8.8"},
+			GatewayIPAddress: "10.0.0.1",
+		},
+		SecondaryIPConfigs: map[string]cns.SecondaryIPConfig{
+			"ip": {IPAddress: "10.0.0.5", NCVersion: 1},
+		},
+		MultiTenancyInfo:           cns.MultiTenancyInfo{EncapType: "Vlan", ID: 2},
+		CnetAddressSpace:           []cns.IPSubnet{{IPAddress: "10.1.0.0", PrefixLength: 16}},
+		Routes:                     []cns.Route{{IPAddress: "0.0.0.0/0", GatewayIPAddress: "10.0.0.1"}},
+		AllowHostToNCCommunication: true,
+		EndpointPolicies: []cns.NetworkContainerRequestPolicies{
+			{Type: "ACL", EndpointType: "APIPA", Settings: json.RawMessage(`{"Action":"Allow"}`)},
+		},
+		NCStatus:             v1alpha.NCUpdateSubnetFull,
+		NetworkInterfaceInfo: cns.NetworkInterfaceInfo{NICType: cns.DelegatedVMNIC, MACAddress: "00:00:5e:00:53:01"},
+	}
+	assert.Equal(t, req, FromCreateNetworkContainerRequest(req).ToCNS())
+}
+
+func TestIPConfigsResponseRoundTrip(t *testing.T) {
+	res := &cns.IPConfigsResponse{
+		PodIPInfo: []cns.PodIpInfo{
+			{
+				PodIPConfig: cns.IPSubnet{IPAddress: "10.0.0.5", PrefixLength: 24},
+				NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+					IPSubnet:         cns.IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24},
+					GatewayIPAddress: "10.0.0.1",
+				},
+				HostPrimaryIPInfo: cns.HostIPInfo{Gateway: "10.224.0.1", PrimaryIP: "10.224.0.4", Subnet: "10.224.0.0/16"},
+				NICType:           cns.InfraNIC,
+				SkipDefaultRoutes: true,
+			},
+		},
+		Response: cns.Response{ReturnCode: types.Success, Message: "ok"},
+	}
+	assert.Equal(t, res, FromIPConfigsResponse(res).ToCNS())
+
+	// a nil message converts to the zero value
+	assert.Equal(t, &cns.IPConfigsResponse{}, (*IPConfigsResponse)(nil).ToCNS())
+}
+
+func TestIPStateEventRoundTrip(t *testing.T) {
+	e := &cns.IPStateEvent{
+		Sequence:  7,
+		ID:        "ip",
+		IPAddress: "10.0.0.5",
+		NCID:      "nc",
+		PodInfo:   cns.NewPodInfo("infra", "pod-eth0", "pod", "ns"),
+		OldState:  types.Available,
+		NewState:  types.Assigned,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+	got := FromIPStateEvent(e).ToCNS()
+	require.NotNil(t, got.PodInfo)
+	assert.True(t, e.PodInfo.Equals(got.PodInfo))
+	got.PodInfo = e.PodInfo
+	assert.Equal(t, *e, got)
+
+	e.PodInfo = nil
+	assert.Nil(t, FromIPStateEvent(e).ToCNS().PodInfo)
+}
@@ -0,0 +1,226 @@
+// Package server serves the CNS gRPC API, backed by the same HTTPRestService as the REST API.
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/grpc/protos"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const unixScheme = "unix://"
+
+// Server implements the CNS gRPC service with the HTTPRestService.
+type Server struct {
+	protos.UnimplementedCNSServer
+	service *restserver.HTTPRestService
+	grpc    *grpc.Server
+}
+
+// New returns a new Server for the service.
+func New(service *restserver.HTTPRestService, opts ...grpc.ServerOption) *Server {
+	s := &Server{service: service}
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(logCalls)}, opts...)
+	s.grpc = grpc.NewServer(opts...)
+	protos.RegisterCNSServer(s.grpc, s)
+	return s
+}
+
+// Listen listens on the address, "unix:///path" for a unix socket or "host:port". A stale socket left by a previous
+// run of CNS is removed.
+func Listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, unixScheme); ok {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "failed to remove stale socket %s", path)
+		}
+		l, err := net.Listen("unix", path)
+		return l, errors.Wrapf(err, "failed to listen on %s", address)
+	}
+	l, err := net.Listen("tcp", address)
+	return l, errors.Wrapf(err, "failed to listen on %s", address)
+}
+
+// Serve serves on the listener until the context is done, then stops gracefully.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		s.grpc.GracefulStop()
+	}()
+	logger.Printf("[gRPC] Serving the CNS gRPC API on %s", l.Addr())
+	return errors.Wrap(s.grpc.Serve(l), "failed to serve")
+}
+
+// Stop stops the server, closing the listeners and the open streams.
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
+
+// logCalls logs the unary calls and how long they took, like the REST API logs its requests.
+func logCalls(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	code := types.Success
+	if r, ok := res.(interface{ GetResponse() *protos.Response }); ok {
+		code = types.ResponseCode(r.GetResponse().GetReturnCode())
+	} else if r, ok := res.(*protos.Response); ok {
+		code = types.ResponseCode(r.GetReturnCode())
+	}
+	logger.Printf("[gRPC] %s returned %s in %s, err: %v", info.FullMethod, code, time.Since(start), err)
+	return res, err
+}
+
+func response(code types.ResponseCode, message string) *protos.Response {
+	return &protos.Response{ReturnCode: int32(code), Message: message}
+}
+
+// RequestIPs assigns IPs to a Pod.
+func (s *Server) RequestIPs(ctx context.Context, req *protos.IPConfigsRequest) (*protos.IPConfigsResponse, error) {
+	res, err := s.service.RequestIPConfigs(ctx, req.ToCNS())
+	if res == nil {
+		res = &cns.IPConfigsResponse{Response: cns.Response{ReturnCode: types.UnexpectedError}}
+		if err != nil {
+			res.Response.Message = err.Error()
+		}
+	}
+	return protos.FromIPConfigsResponse(res), nil
+}
+
+// ReleaseIPs releases the IPs of a Pod.
+func (s *Server) ReleaseIPs(ctx context.Context, req *protos.IPConfigsRequest) (*protos.IPConfigsResponse, error) {
+	res, err := s.service.ReleaseIPConfigHandlerHelper(ctx, req.ToCNS())
+	if res == nil {
+		res = &cns.IPConfigsResponse{Response: cns.Response{ReturnCode: types.UnexpectedError}}
+		if err != nil {
+			res.Response.Message = err.Error()
+		}
+	}
+	return protos.FromIPConfigsResponse(res), nil
+}
+
+// CreateOrUpdateNetworkContainer creates or updates an NC.
+func (s *Server) CreateOrUpdateNetworkContainer(_ context.Context, req *protos.CreateNetworkContainerRequest) (*protos.Response, error) {
+	code := s.service.CreateOrUpdateNetworkContainerInternal(req.ToCNS())
+	if code != types.Success {
+		return response(code, "failed to create or update NC "+req.GetNetworkContainerId()+": "+code.String()), nil
+	}
+	return response(code, ""), nil
+}
+
+// DeleteNetworkContainer deletes an NC.
+func (s *Server) DeleteNetworkContainer(_ context.Context, req *protos.DeleteNetworkContainerRequest) (*protos.Response, error) {
+	if req.GetNetworkContainerId() == "" {
+		return response(types.NetworkContainerNotSpecified, "network container ID is empty"), nil
+	}
+	code := s.service.DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest{NetworkContainerid: req.GetNetworkContainerId()})
+	if code != types.Success {
+		return response(code, "failed to delete NC "+req.GetNetworkContainerId()+": "+code.String()), nil
+	}
+	return response(code, ""), nil
+}
+
+func getNetworkContainerRequest(req *protos.GetNetworkContainerRequest) cns.GetNetworkContainerRequest {
+	return cns.GetNetworkContainerRequest{
+		NetworkContainerid:  req.GetNetworkContainerId(),
+		OrchestratorContext: req.GetOrchestratorContext(),
+	}
+}
+
+// GetNetworkContainer gets the NC of a Pod.
+func (s *Server) GetNetworkContainer(_ context.Context, req *protos.GetNetworkContainerRequest) (*protos.GetNetworkContainerResponse, error) {
+	res, _ := s.service.GetNetworkContainerInternal(getNetworkContainerRequest(req))
+	return protos.FromGetNetworkContainerResponse(&res), nil
+}
+
+// GetAllNetworkContainers gets the NCs of a Pod.
+func (s *Server) GetAllNetworkContainers(_ context.Context, req *protos.GetNetworkContainerRequest) (*protos.GetAllNetworkContainersResponse, error) {
+	res := s.service.GetAllNetworkContainersInternal(getNetworkContainerRequest(req))
+	return protos.FromGetAllNetworkContainersResponse(&res), nil
+}
+
+// GetEndpoint gets the state of an endpoint.
+func (s *Server) GetEndpoint(_ context.Context, req *protos.GetEndpointRequest) (*protos.GetEndpointResponse, error) {
+	if req.GetEndpointId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "endpoint ID is empty")
+	}
+	res := s.service.GetEndpointInternal(req.GetEndpointId())
+	out := &protos.GetEndpointResponse{Response: response(res.Response.ReturnCode, res.Response.Message)}
+	if res.Response.ReturnCode == types.Success {
+		out.EndpointInfo = fromEndpointInfo(&res.EndpointInfo)
+	}
+	return out, nil
+}
+
+func fromEndpointInfo(info *restserver.EndpointInfo) *protos.EndpointInfo {
+	x := &protos.EndpointInfo{
+		PodName:       info.PodName,
+		PodNamespace:  info.PodNamespace,
+		HnsEndpointId: info.HnsEndpointID,
+		HostVethName:  info.HostVethName,
+	}
+	if info.IfnameToIPMap == nil {
+		return x
+	}
+	cidrs := func(ipnets []net.IPNet) []string {
+		var s []string
+		for i := range ipnets {
+			s = append(s, ipnets[i].String())
+		}
+		return s
+	}
+	x.IfnameToIps = make(map[string]*protos.IPInfo, len(info.IfnameToIPMap))
+	for ifname, ips := range info.IfnameToIPMap {
+		if ips == nil {
+			continue
+		}
+		x.IfnameToIps[ifname] = &protos.IPInfo{Ipv4: cidrs(ips.IPv4), Ipv6: cidrs(ips.IPv6)}
+	}
+	return x
+}
+
+// UpdateEndpoint updates the state of an endpoint.
+func (s *Server) UpdateEndpoint(_ context.Context, req *protos.UpdateEndpointRequest) (*protos.Response, error) {
+	res := s.service.UpdateEndpointInternal(req.GetEndpointId(), cns.EndpointRequest{
+		HnsEndpointID: req.GetHnsEndpointId(),
+		HostVethName:  req.GetHostVethName(),
+	})
+	return protos.FromResponse(res), nil
+}
+
+// WatchIPStateEvents streams the IP state events after the sequence in the request, or from the next event if it
+// is 0. It fails with OutOfRange if the events after the sequence are not available, or if the stream falls so far
+// behind that they are dropped, so that the client can list the IPs again and resume from the latest event.
+func (s *Server) WatchIPStateEvents(req *protos.WatchIPStateEventsRequest, stream protos.CNS_WatchIPStateEventsServer) error {
+	seq := req.GetSince()
+	if seq == 0 {
+		seq = s.service.LatestIPStateEvent()
+	}
+	ctx := stream.Context()
+	for {
+		events, changed, err := s.service.IPStateEventsSince(seq)
+		if err != nil {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+		for i := range events {
+			if err := stream.Send(protos.FromIPStateEvent(&events[i])); err != nil {
+				return err //nolint:wrapcheck // already a status
+			}
+			seq = events[i].Sequence
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	cnscli "github.com/Azure/azure-container-networking/cns/client"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testNCID      = "testNcId1"
+	testPrimaryIP = "10.0.0.4"
+)
+
+var testSecondaryIPs = map[string]string{
+	"a1b2c3d4-0000-0000-0000-000000000001": "10.0.0.5",
+	"a1b2c3d4-0000-0000-0000-000000000002": "10.0.0.6",
+}
+
+// newTestServer serves a new HTTPRestService on a unix socket and returns a client of it.
+func newTestServer(t *testing.T) *cnscli.GRPCClient {
+	t.Helper()
+	logger.InitLogger("azure-cns.log", 0, 0, t.TempDir()+"/")
+	config := common.ServiceConfig{}
+	service, err := restserver.NewHTTPRestService(&config, &fakes.WireserverClientFake{}, &fakes.WireserverProxyFake{}, &fakes.NMAgentClientFake{}, nil, nil, nil)
+	require.NoError(t, err)
+	service.SetNodeOrchestrator(&cns.SetOrchestratorTypeRequest{OrchestratorType: cns.KubernetesCRD})
+
+	address := "unix://" + filepath.Join(t.TempDir(), "cns.sock")
+	l, err := Listen(address)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- New(service).Serve(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	client, err := cnscli.NewGRPC(address, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	readyCtx, readyCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer readyCancel()
+	require.NoError(t, client.WaitForReady(readyCtx))
+	return client
+}
+
+func testCreateNCRequest() cns.CreateNetworkContainerRequest {
+	req := cns.CreateNetworkContainerRequest{
+		NetworkContainerType: "Docker",
+		NetworkContainerid:   testNCID,
+		IPConfiguration: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: testPrimaryIP, PrefixLength: 24},
+			DNSServers:       []string{"8.8.8.8"},
+			GatewayIPAddress: "10.0.0.1",
+		},
+		SecondaryIPConfigs: map[string]cns.SecondaryIPConfig{},
+		// the same as the default host version, so that the secondary IPs are Available.
+		Version: "-1",
+	}
+	for id, ip := range testSecondaryIPs {
+		req.SecondaryIPConfigs[id] = cns.SecondaryIPConfig{IPAddress: ip, NCVersion: -1}
+	}
+	return req
+}
+
+func TestIPAMRoundTrip(t *testing.T) {
+	client := newTestServer(t)
+	ctx := context.Background()
+	require.NoError(t, client.CreateNetworkContainer(ctx, testCreateNCRequest()))
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	events := make(chan cns.IPStateEvent, 10)
+	watched := make(chan error)
+	go func() {
+		// the events of the two IPs added with the NC.
+		watched <- client.WatchIPStateEvents(watchCtx, 2, func(e cns.IPStateEvent) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{PodName: "pod", PodNamespace: "ns"})
+	require.NoError(t, err)
+	req := cns.IPConfigsRequest{
+		PodInterfaceID:      "pod-eth0",
+		InfraContainerID:    "0123456789abcdef",
+		OrchestratorContext: orchestratorContext,
+	}
+	res, err := client.RequestIPs(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, res.PodIPInfo, 1)
+	info := res.PodIPInfo[0]
+	assert.Contains(t, []string{"10.0.0.5", "10.0.0.6"}, info.PodIPConfig.IPAddress)
+	assert.Equal(t, uint8(24), info.PodIPConfig.PrefixLength)
+	assert.Equal(t, testPrimaryIP, info.NetworkContainerPrimaryIPConfig.IPSubnet.IPAddress)
+	assert.Equal(t, []string{"8.8.8.8"}, info.NetworkContainerPrimaryIPConfig.DNSServers)
+
+	e := <-events
+	assert.Equal(t, uint64(3), e.Sequence)
+	assert.Equal(t, info.PodIPConfig.IPAddress, e.IPAddress)
+	assert.Equal(t, types.Available, e.OldState)
+	assert.Equal(t, types.Assigned, e.NewState)
+	require.NotNil(t, e.PodInfo)
+	assert.Equal(t, "pod", e.PodInfo.Name())
+	assert.Equal(t, "0123456789abcdef", e.PodInfo.InfraContainerID())
+
+	require.NoError(t, client.ReleaseIPs(ctx, req))
+	e = <-events
+	assert.Equal(t, types.Assigned, e.OldState)
+	assert.Equal(t, types.Available, e.NewState)
+
+	stopWatch()
+	require.ErrorIs(t, <-watched, context.Canceled)
+
+	// a sequence from a previous run of CNS
+	err = client.WatchIPStateEvents(ctx, 100, func(cns.IPStateEvent) error { return nil })
+	require.ErrorIs(t, err, cnscli.ErrIPStateEventsLost)
+
+	require.NoError(t, client.DeleteNetworkContainer(ctx, testNCID))
+}
+
+func TestCNSFailuresAreReturnedAsClientErrors(t *testing.T) {
+	client := newTestServer(t)
+	ctx := context.Background()
+
+	// the primary IP is required
+	req := testCreateNCRequest()
+	req.IPConfiguration.IPSubnet.IPAddress = ""
+	err := client.CreateNetworkContainer(ctx, req)
+	var cnsErr *cnscli.CNSClientError
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.InvalidPrimaryIPConfig, cnsErr.Code)
+
+	// CNS doesn't manage the endpoint state
+	_, err = client.GetEndpoint(ctx, "0123456789abcdef")
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.UnexpectedError, cnsErr.Code)
+	_, err = client.UpdateEndpoint(ctx, "0123456789abcdef", "hns", "")
+	require.ErrorAs(t, err, &cnsErr)
+	assert.Equal(t, types.UnexpectedError, cnsErr.Code)
+}
@@ -41,6 +41,14 @@ var (
 		},
 		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
 	)
+	IpamCooldownIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_ipam_cooldown_ips",
+			Help:        "IPs released by Pods but not yet available again (Cooldown).",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
+	)
 	IpamCurrentAvailableIPcount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cx_ipam_current_available_ips",
@@ -113,6 +121,14 @@ var (
 		},
 		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
 	)
+	IpamUnassignableIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_ipam_unassignable_ips",
+			Help:        "Available IPs which are excluded or reserved for Pods by the NodeNetworkConfig.",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{subnetLabel, subnetCIDRLabel, podnetARMIDLabel},
+	)
 	IpamSubnetExhaustionState = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cx_ipam_subnet_exhaustion_state",
@@ -135,6 +151,7 @@ func init() {
 		IpamAllocatedIPCount,
 		IpamAvailableIPCount,
 		IpamBatchSize,
+		IpamCooldownIPCount,
 		IpamCurrentAvailableIPcount,
 		IpamExpectedAvailableIPCount,
 		IpamMaxIPCount,
@@ -144,6 +161,7 @@ func init() {
 		IpamSecondaryIPCount,
 		IpamRequestedIPConfigCount,
 		IpamTotalIPCount,
+		IpamUnassignableIPCount,
 		IpamSubnetExhaustionState,
 		IpamSubnetExhaustionCount,
 	)
@@ -154,6 +172,7 @@ func observeIPPoolState(state ipPoolState, meta metaState) {
 	IpamAllocatedIPCount.WithLabelValues(labels...).Set(float64(state.allocatedToPods))
 	IpamAvailableIPCount.WithLabelValues(labels...).Set(float64(state.available))
 	IpamBatchSize.WithLabelValues(labels...).Set(float64(meta.batch))
+	IpamCooldownIPCount.WithLabelValues(labels...).Set(float64(state.cooldown))
 	IpamCurrentAvailableIPcount.WithLabelValues(labels...).Set(float64(state.currentAvailableIPs))
 	IpamExpectedAvailableIPCount.WithLabelValues(labels...).Set(float64(state.expectedAvailableIPs))
 	IpamMaxIPCount.WithLabelValues(labels...).Set(float64(meta.max))
@@ -163,6 +182,7 @@ func observeIPPoolState(state ipPoolState, meta metaState) {
 	IpamRequestedIPConfigCount.WithLabelValues(labels...).Set(float64(state.requestedIPs))
 	IpamSecondaryIPCount.WithLabelValues(labels...).Set(float64(state.secondaryIPs))
 	IpamTotalIPCount.WithLabelValues(labels...).Set(float64(state.secondaryIPs + int64(len(meta.primaryIPAddresses))))
+	IpamUnassignableIPCount.WithLabelValues(labels...).Set(float64(state.unassignable))
 	if meta.exhausted {
 		IpamSubnetExhaustionState.WithLabelValues(labels...).Set(float64(SubnetIPExhausted))
 	} else {
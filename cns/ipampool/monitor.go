@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/metric"
 	"github.com/Azure/azure-container-networking/cns/types"
@@ -43,6 +44,7 @@ type metaState struct {
 	minFreeCount       int64
 	notInUseCount      int64
 	primaryIPAddresses map[string]struct{}
+	reservations       *ipreservation.Set
 	subnet             string
 	subnetARMID        string
 	subnetCIDR         string
@@ -117,39 +119,9 @@ func (pm *Monitor) Start(ctx context.Context) error {
 				// if we have initialized and enter this case, we proceed out of the select and continue to reconcile.
 			}
 		case nnc := <-pm.nncSource: // received a new NodeNetworkConfig, extract the data from it and re-reconcile.
-			if len(nnc.Status.NetworkContainers) > 0 {
-				// Set SubnetName, SubnetAddressSpace and Pod Network ARM ID values to the global subnet, subnetCIDR and subnetARM variables.
-				pm.metastate.subnet = nnc.Status.NetworkContainers[0].SubnetName
-				pm.metastate.subnetCIDR = nnc.Status.NetworkContainers[0].SubnetAddressSpace
-				pm.metastate.subnetARMID = GenerateARMID(&nnc.Status.NetworkContainers[0])
+			if err := pm.applyNNC(&nnc); err != nil {
+				return err
 			}
-			pm.metastate.primaryIPAddresses = make(map[string]struct{})
-			// Add Primary IP to Map, if not present.
-			// This is only for Swift i.e. if NC Type is vnet.
-			for i := 0; i < len(nnc.Status.NetworkContainers); i++ {
-				nc := nnc.Status.NetworkContainers[i]
-				if nc.Type == "" || nc.Type == v1alpha.VNET {
-					pm.metastate.primaryIPAddresses[nc.PrimaryIP] = struct{}{}
-				}
-
-				if nc.Type == v1alpha.VNETBlock {
-					primaryPrefix, err := netip.ParsePrefix(nc.PrimaryIP)
-					if err != nil {
-						return errors.Wrapf(err, "unable to parse ip prefix: %s", nc.PrimaryIP)
-					}
-					pm.metastate.primaryIPAddresses[primaryPrefix.Addr().String()] = struct{}{}
-				}
-			}
-
-			scaler := nnc.Status.Scaler
-			pm.metastate.batch = scaler.BatchSize
-			pm.metastate.max = scaler.MaxIPCount
-			pm.metastate.minFreeCount, pm.metastate.maxFreeCount = CalculateMinFreeIPs(scaler), CalculateMaxFreeIPs(scaler)
-			pm.once.Do(func() {
-				pm.spec = nnc.Spec // set the spec from the NNC initially (afterwards we write the Spec so we know target state).
-				logger.Printf("[ipam-pool-monitor] set initial pool spec %+v", pm.spec)
-				close(pm.started) // close the init channel the first time we fully receive a NodeNetworkConfig.
-			})
 		}
 		// if control has flowed through the select(s) to this point, we can now reconcile.
 		err := pm.reconcile(ctx)
@@ -159,15 +131,79 @@ func (pm *Monitor) Start(ctx context.Context) error {
 	}
 }
 
+// Step runs one iteration of the reconcile loop of Start: the NodeNetworkConfig is applied if not nil, and the pool is
+// reconciled once the Monitor has received a NodeNetworkConfig. It drives the Monitor without Start, as in simulations.
+func (pm *Monitor) Step(ctx context.Context, nnc *v1alpha.NodeNetworkConfig) error {
+	if nnc != nil {
+		if err := pm.applyNNC(nnc); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-pm.started:
+	default:
+		return nil
+	}
+	return pm.reconcile(ctx)
+}
+
+// applyNNC extracts the subnet, primary IPs, scaler and reservations from the NodeNetworkConfig.
+func (pm *Monitor) applyNNC(nnc *v1alpha.NodeNetworkConfig) error {
+	if len(nnc.Status.NetworkContainers) > 0 {
+		// Set SubnetName, SubnetAddressSpace and Pod Network ARM ID values to the global subnet, subnetCIDR and subnetARM variables.
+		pm.metastate.subnet = nnc.Status.NetworkContainers[0].SubnetName
+		pm.metastate.subnetCIDR = nnc.Status.NetworkContainers[0].SubnetAddressSpace
+		pm.metastate.subnetARMID = GenerateARMID(&nnc.Status.NetworkContainers[0])
+	}
+	pm.metastate.primaryIPAddresses = make(map[string]struct{})
+	// Add Primary IP to Map, if not present.
+	// This is only for Swift i.e. if NC Type is vnet.
+	for i := 0; i < len(nnc.Status.NetworkContainers); i++ {
+		nc := nnc.Status.NetworkContainers[i]
+		if nc.Type == "" || nc.Type == v1alpha.VNET {
+			pm.metastate.primaryIPAddresses[nc.PrimaryIP] = struct{}{}
+		}
+
+		if nc.Type == v1alpha.VNETBlock {
+			primaryPrefix, err := netip.ParsePrefix(nc.PrimaryIP)
+			if err != nil {
+				return errors.Wrapf(err, "unable to parse ip prefix: %s", nc.PrimaryIP)
+			}
+			pm.metastate.primaryIPAddresses[primaryPrefix.Addr().String()] = struct{}{}
+		}
+	}
+
+	// the previous exclusions and reservations are kept if the new ones are invalid, as CNS does.
+	if reservations, err := ipreservation.New(&nnc.Spec); err != nil {
+		logger.Errorf("[ipam-pool-monitor] Failed to parse IP reservations: %v", err)
+	} else {
+		pm.metastate.reservations = reservations
+	}
+
+	scaler := nnc.Status.Scaler
+	pm.metastate.batch = scaler.BatchSize
+	pm.metastate.max = scaler.MaxIPCount
+	pm.metastate.minFreeCount, pm.metastate.maxFreeCount = CalculateMinFreeIPs(scaler), CalculateMaxFreeIPs(scaler)
+	pm.once.Do(func() {
+		pm.spec = nnc.Spec // set the spec from the NNC initially (afterwards we write the Spec so we know target state).
+		logger.Printf("[ipam-pool-monitor] set initial pool spec %+v", pm.spec)
+		close(pm.started) // close the init channel the first time we fully receive a NodeNetworkConfig.
+	})
+	return nil
+}
+
 // ipPoolState is the current actual state of the CNS IP pool.
 type ipPoolState struct {
 	// allocatedToPods are the IPs CNS gives to Pods.
 	allocatedToPods int64
 	// available are the IPs in state "Available".
 	available int64
-	// currentAvailableIPs are the current available IPs: allocated - assigned - pendingRelease.
+	// cooldown are the IPs in state "Cooldown".
+	cooldown int64
+	// currentAvailableIPs are the current available IPs: allocated - assigned - pendingRelease - cooldown - unassignable.
 	currentAvailableIPs int64
-	// expectedAvailableIPs are the "future" available IPs, if the requested IP count is honored: requested - assigned.
+	// expectedAvailableIPs are the "future" available IPs, if the requested IP count is honored:
+	// requested - assigned - cooldown - unassignable.
 	expectedAvailableIPs int64
 	// pendingProgramming are the IPs in state "PendingProgramming".
 	pendingProgramming int64
@@ -177,12 +213,15 @@ type ipPoolState struct {
 	requestedIPs int64
 	// secondaryIPs are all the IPs given to CNS by DNC, not including the primary IP of the NC.
 	secondaryIPs int64
+	// unassignable are the IPs in state "Available" which are excluded or reserved for Pods by the NodeNetworkConfig.
+	unassignable int64
 }
 
-func buildIPPoolState(ips map[string]cns.IPConfigurationStatus, spec v1alpha.NodeNetworkConfigSpec) ipPoolState {
+func buildIPPoolState(ips map[string]cns.IPConfigurationStatus, spec v1alpha.NodeNetworkConfigSpec, reservations *ipreservation.Set) ipPoolState {
 	state := ipPoolState{
 		secondaryIPs: int64(len(ips)),
 		requestedIPs: spec.RequestedIPCount,
+		unassignable: reservations.CountUnassignable(ips),
 	}
 	for i := range ips {
 		ip := ips[i]
@@ -195,10 +234,14 @@ func buildIPPoolState(ips map[string]cns.IPConfigurationStatus, spec v1alpha.Nod
 			state.pendingProgramming++
 		case types.PendingRelease:
 			state.pendingRelease++
+		case types.Cooldown:
+			state.cooldown++
 		}
 	}
-	state.currentAvailableIPs = state.secondaryIPs - state.allocatedToPods - state.pendingRelease
-	state.expectedAvailableIPs = state.requestedIPs - state.allocatedToPods
+	// IPs in Cooldown can be neither assigned nor released until their cooldown has passed, so they are not free.
+	// Neither are the IPs which are excluded or reserved for Pods which are not running.
+	state.currentAvailableIPs = state.secondaryIPs - state.allocatedToPods - state.pendingRelease - state.cooldown - state.unassignable
+	state.expectedAvailableIPs = state.requestedIPs - state.allocatedToPods - state.cooldown - state.unassignable
 	return state
 }
 
@@ -207,7 +250,7 @@ var statelogDownsample int
 func (pm *Monitor) reconcile(ctx context.Context) error {
 	allocatedIPs := pm.httpService.GetPodIPConfigState()
 	meta := pm.metastate
-	state := buildIPPoolState(allocatedIPs, pm.spec)
+	state := buildIPPoolState(allocatedIPs, pm.spec, meta.reservations)
 	observeIPPoolState(state, meta)
 
 	// log every 30th reconcile to reduce the AI load. we will always log when the monitor
@@ -3,12 +3,15 @@ package ipampool
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
 	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/stretchr/testify/assert"
 )
@@ -672,3 +675,40 @@ func TestCalculateIPs(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildIPPoolStateWithCooldown(t *testing.T) {
+	ips := map[string]cns.IPConfigurationStatus{}
+	for i, state := range []types.IPState{types.Assigned, types.Assigned, types.Available, types.Cooldown, types.Cooldown, types.PendingRelease} {
+		ip := cns.IPConfigurationStatus{ID: strconv.Itoa(i)}
+		ip.SetState(state)
+		ips[ip.ID] = ip
+	}
+
+	state := buildIPPoolState(ips, v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 6}, nil)
+	assert.Equal(t, int64(2), state.cooldown)
+	// IPs in Cooldown are not free until their cooldown has passed
+	assert.Equal(t, int64(1), state.currentAvailableIPs)
+	assert.Equal(t, int64(2), state.expectedAvailableIPs)
+}
+
+func TestBuildIPPoolStateWithReservations(t *testing.T) {
+	ips := map[string]cns.IPConfigurationStatus{}
+	for i, state := range []types.IPState{types.Assigned, types.Available, types.Available, types.Available, types.PendingRelease} {
+		ip := cns.IPConfigurationStatus{ID: strconv.Itoa(i), IPAddress: "10.0.0." + strconv.Itoa(i)}
+		ip.SetState(state)
+		ips[ip.ID] = ip
+	}
+	spec := v1alpha.NodeNetworkConfigSpec{
+		RequestedIPCount: 5,
+		ExcludedIPs:      []string{"10.0.0.0/31", "10.0.0.4"},
+		IPReservations:   []v1alpha.IPReservation{{IP: "10.0.0.2", PodName: "a", PodNamespace: "default"}},
+	}
+	reservations, err := ipreservation.New(&spec)
+	assert.NoError(t, err)
+
+	state := buildIPPoolState(ips, spec, reservations)
+	// only the Available IPs which are excluded or reserved are unassignable
+	assert.Equal(t, int64(2), state.unassignable)
+	assert.Equal(t, int64(1), state.currentAvailableIPs)
+	assert.Equal(t, int64(2), state.expectedAvailableIPs)
+}
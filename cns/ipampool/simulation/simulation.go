@@ -0,0 +1,350 @@
+// Package simulation replays IP demand traces against the v1 and v2 IP pool monitors, with a fake CNS IP store and a
+// fake DNC which allocates the requested IPs to the Node after a latency, to compare how they scale the pool.
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/ipampool"
+	v2 "github.com/Azure/azure-container-networking/cns/ipampool/v2"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStep is the default simulated time between iterations of the monitors.
+	DefaultStep = time.Second
+	// DefaultLatency is the default simulated time the DNC takes to allocate or release the requested IPs.
+	DefaultLatency = 10 * time.Second
+)
+
+// Config configures a simulation.
+type Config struct {
+	// Scaler is the scaler of the NodeNetworkConfig.
+	Scaler v1alpha.Scaler
+	// InitialIPs is the number of IPs allocated to the Node at the start. It defaults to a batch.
+	InitialIPs int64
+	// Step is the simulated time between iterations of the monitor. Events between steps take effect on the next step.
+	Step time.Duration
+	// Latency is the simulated time between the monitor updating the NodeNetworkConfig spec and the DNC updating the
+	// IPs allocated to the Node to match.
+	Latency time.Duration
+	// Duration is how long the simulation runs for, at least until the end of the trace.
+	Duration time.Duration
+	// Predictor enables the demand predictor of the v2 monitor. It is ignored by the v1 monitor.
+	Predictor *v2.PredictorOptions
+	// Logger is the logger of the v2 monitor. It defaults to discarding the logs. The v1 monitor logs to the CNS logger,
+	// which must be initialized.
+	Logger *zap.Logger
+}
+
+func (c *Config) setDefaults() {
+	if c.Step <= 0 {
+		c.Step = DefaultStep
+	}
+	if c.Latency <= 0 {
+		c.Latency = DefaultLatency
+	}
+	if c.InitialIPs <= 0 {
+		c.InitialIPs = c.Scaler.BatchSize
+	}
+	if c.Logger == nil {
+		c.Logger = zap.NewNop()
+	}
+}
+
+// Point is the state of the pool at a point of the simulation.
+type Point struct {
+	At time.Duration
+	// Demand is the number of Pods on the Node, which want an IP.
+	Demand int64
+	// Assigned is the number of Pods which have an IP, the rest are waiting for one.
+	Assigned int64
+	// Requested is the RequestedIPCount of the last NodeNetworkConfig spec written by the monitor.
+	Requested int64
+	// Allocated is the number of IPs allocated to the Node.
+	Allocated int64
+	// PendingRelease is the number of allocated IPs waiting to be released.
+	PendingRelease int64
+}
+
+// Wait is how long a Pod waited for an IP.
+type Wait struct {
+	Pod     string
+	Arrived time.Duration
+	Waited  time.Duration
+}
+
+// Result is the outcome of a simulation.
+type Result struct {
+	Timeline []Point
+	// Waits are how long each Pod waited for an IP, in the order they were assigned one.
+	Waits []Wait
+	// Unassigned is the number of Pods still waiting for an IP at the end of the simulation.
+	Unassigned int
+	// Shortfall is the total time Pods waited for an IP, in Pod-seconds.
+	Shortfall float64
+	// IPSeconds is the total time IPs were allocated to the Node, in IP-seconds, the cost of the pool.
+	IPSeconds float64
+	// PeakRequested is the largest number of IPs the monitor requested.
+	PeakRequested int64
+	// Scales is the number of times the monitor changed the requested number of IPs.
+	Scales int
+	// Failures is the number of iterations of the monitor which failed, and were retried on the next step as Start
+	// would.
+	Failures int
+}
+
+// WaitPercentile returns the time the given percentage of the Pods assigned IPs waited at most for them.
+func (r *Result) WaitPercentile(p float64) time.Duration {
+	if len(r.Waits) == 0 {
+		return 0
+	}
+	waits := make([]time.Duration, len(r.Waits))
+	for i := range r.Waits {
+		waits[i] = r.Waits[i].Waited
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	rank := int(math.Ceil(p/100*float64(len(waits)))) - 1 //nolint:gomnd // it's a percentage
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(waits) {
+		rank = len(waits) - 1
+	}
+	return waits[rank]
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("shortfall %.0f pod-seconds, pod wait p50 %s p99 %s max %s, %d pods unassigned, "+
+		"cost %.0f IP-seconds, peak request %d IPs, %d scaling requests, %d failures",
+		r.Shortfall, r.WaitPercentile(50), r.WaitPercentile(99), r.WaitPercentile(100), r.Unassigned, //nolint:gomnd // percentiles
+		r.IPSeconds, r.PeakRequested, r.Scales, r.Failures)
+}
+
+// stepFunc runs an iteration of a monitor at the given time of the simulation with the demand and the
+// NodeNetworkConfig, if it has been updated.
+type stepFunc func(ctx context.Context, now time.Time, demand int, nnc *v1alpha.NodeNetworkConfig) error
+
+// RunV1 replays the trace against the v1 pool monitor. The monitor doesn't see the demand, only the IPs assigned to
+// Pods.
+func RunV1(ctx context.Context, trace Trace, cfg Config) (*Result, error) {
+	cfg.setDefaults()
+	sim := newSimulation(&cfg)
+	pm := ipampool.NewMonitor(sim.store, sim, nil, &ipampool.Options{RefreshDelay: cfg.Step})
+	return sim.run(ctx, trace, func(ctx context.Context, _ time.Time, _ int, nnc *v1alpha.NodeNetworkConfig) error {
+		return pm.Step(ctx, nnc) //nolint:wrapcheck // the monitor's error is returned as is
+	})
+}
+
+// RunV2 replays the trace against the v2 pool monitor, with the predictor if configured.
+func RunV2(ctx context.Context, trace Trace, cfg Config) (*Result, error) {
+	cfg.setDefaults()
+	sim := newSimulation(&cfg)
+	pm := v2.NewMonitor(cfg.Logger, sim.store, sim, nil, nil, nil)
+	if cfg.Predictor != nil {
+		pm.WithPredictor(*cfg.Predictor)
+	}
+	return sim.run(ctx, trace, pm.Step)
+}
+
+// pendingSpec is a NodeNetworkConfig spec written by the monitor which the DNC has yet to apply.
+type pendingSpec struct {
+	due  time.Time
+	spec v1alpha.NodeNetworkConfigSpec
+}
+
+// pod is a Pod on the Node.
+type pod struct {
+	name    string
+	arrived time.Duration
+	// ip is the ID of the IP assigned to the Pod, empty while it waits for one.
+	ip string
+}
+
+// simulation is the fake CNS IP store and DNC the monitor is run against, and the simulated clock.
+type simulation struct {
+	cfg     *Config
+	store   *fakes.HTTPServiceFake
+	nnc     v1alpha.NodeNetworkConfig
+	pending []pendingSpec
+	// requested is the RequestedIPCount of the last spec written by the monitor.
+	requested int64
+	scales    int
+	now       time.Time
+	nextIP    netip.Addr
+	nextID    int
+	// pods are the Pods on the Node by name, and waiting those waiting for IPs in order of arrival.
+	pods    map[string]*pod
+	waiting []*pod
+}
+
+func newSimulation(cfg *Config) *simulation {
+	sim := &simulation{
+		cfg:    cfg,
+		store:  fakes.NewHTTPServiceFake(),
+		pods:   map[string]*pod{},
+		now:    time.Unix(0, 0),
+		nextIP: netip.MustParseAddr("10.0.0.1"),
+		nnc: v1alpha.NodeNetworkConfig{
+			Spec: v1alpha.NodeNetworkConfigSpec{RequestedIPCount: cfg.InitialIPs},
+			Status: v1alpha.NodeNetworkConfigStatus{
+				Scaler:            cfg.Scaler,
+				NetworkContainers: []v1alpha.NetworkContainer{{SubnetAddressSpace: "10.0.0.0/8"}},
+			},
+		},
+	}
+	sim.requested = cfg.InitialIPs
+	sim.allocate(cfg.InitialIPs)
+	return sim
+}
+
+// PatchSpec queues the spec to be applied by the DNC after the latency.
+func (sim *simulation) PatchSpec(_ context.Context, spec *v1alpha.NodeNetworkConfigSpec, _ string) (*v1alpha.NodeNetworkConfig, error) {
+	if spec.RequestedIPCount != sim.requested {
+		sim.scales++
+	}
+	sim.requested = spec.RequestedIPCount
+	s := *spec
+	s.IPsNotInUse = append([]string(nil), spec.IPsNotInUse...)
+	sim.pending = append(sim.pending, pendingSpec{due: sim.now.Add(sim.cfg.Latency), spec: s})
+	nnc := sim.nnc.DeepCopy()
+	nnc.Spec = s
+	return nnc, nil
+}
+
+func (sim *simulation) run(ctx context.Context, trace Trace, step stepFunc) (*Result, error) {
+	if len(trace) == 0 {
+		return nil, errors.New("empty trace")
+	}
+	res := &Result{PeakRequested: sim.requested}
+	start := sim.now
+	end := trace.End()
+	if sim.cfg.Duration > end {
+		end = sim.cfg.Duration
+	}
+	next := 0
+	// the monitor receives the initial NodeNetworkConfig on the first step
+	nnc := sim.nnc.DeepCopy()
+	for at := time.Duration(0); at <= end; at += sim.cfg.Step {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "simulation canceled")
+		}
+		sim.now = start.Add(at)
+		for ; next < len(trace) && trace[next].At <= at; next++ {
+			sim.handle(at, trace[next])
+		}
+		if applied := sim.applyDue(); applied != nil {
+			nnc = applied
+		}
+		res.Waits = sim.schedule(at, res.Waits)
+		demand := len(sim.pods)
+		if err := step(ctx, sim.now, demand, nnc); err != nil {
+			sim.cfg.Logger.Debug("monitor failed", zap.Duration("at", at), zap.Error(err))
+			res.Failures++
+		}
+		nnc = nil
+
+		p := sim.point(at, demand)
+		res.Timeline = append(res.Timeline, p)
+		res.Shortfall += float64(p.Demand-p.Assigned) * sim.cfg.Step.Seconds()
+		res.IPSeconds += float64(p.Allocated) * sim.cfg.Step.Seconds()
+		if sim.requested > res.PeakRequested {
+			res.PeakRequested = sim.requested
+		}
+	}
+	res.Scales = sim.scales
+	res.Unassigned = len(sim.waiting)
+	return res, nil
+}
+
+// applyDue applies the specs written by the monitor which are due as the DNC would: the IPs not in use are released,
+// and IPs are allocated up to the requested count. It returns the updated NodeNetworkConfig, or nil if none was due.
+func (sim *simulation) applyDue() *v1alpha.NodeNetworkConfig {
+	applied := false
+	for len(sim.pending) > 0 && !sim.pending[0].due.After(sim.now) {
+		sim.nnc.Spec = sim.pending[0].spec
+		sim.pending = sim.pending[1:]
+		sim.store.IPStateManager.RemovePendingReleaseIPConfigs(sim.nnc.Spec.IPsNotInUse)
+		if diff := sim.nnc.Spec.RequestedIPCount - int64(len(sim.store.GetPodIPConfigState())); diff > 0 {
+			sim.allocate(diff)
+		}
+		applied = true
+	}
+	if !applied {
+		return nil
+	}
+	return sim.nnc.DeepCopy()
+}
+
+// allocate adds n IPs to the Node.
+func (sim *simulation) allocate(n int64) {
+	ipConfigs := make([]cns.IPConfigurationStatus, n)
+	for i := range ipConfigs {
+		ipConfigs[i] = cns.IPConfigurationStatus{ID: fmt.Sprintf("ip-%d", sim.nextID), IPAddress: sim.nextIP.String()}
+		ipConfigs[i].SetState(types.Available)
+		sim.nextID++
+		sim.nextIP = sim.nextIP.Next()
+	}
+	sim.store.IPStateManager.AddIPConfigs(ipConfigs)
+}
+
+// handle adds an arriving Pod to the Pods waiting for IPs, or removes a departing Pod and releases its IP. Events for
+// Pods which are already on or not on the Node are ignored.
+func (sim *simulation) handle(at time.Duration, e Event) {
+	p, ok := sim.pods[e.Pod]
+	switch {
+	case !e.Departs && !ok:
+		p = &pod{name: e.Pod, arrived: at}
+		sim.pods[e.Pod] = p
+		sim.waiting = append(sim.waiting, p)
+	case e.Departs && ok:
+		delete(sim.pods, e.Pod)
+		if p.ip != "" {
+			_, _ = sim.store.IPStateManager.ReleaseIPConfig(p.ip)
+			return
+		}
+		for i := range sim.waiting {
+			if sim.waiting[i] == p {
+				sim.waiting = append(sim.waiting[:i], sim.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// schedule assigns IPs to the waiting Pods in order of arrival while there are Available IPs, and appends how long
+// they waited to waits.
+func (sim *simulation) schedule(at time.Duration, waits []Wait) []Wait {
+	for len(sim.waiting) > 0 {
+		ipConfig, err := sim.store.IPStateManager.ReserveIPConfig()
+		if err != nil {
+			break // the remaining Pods wait for IPs
+		}
+		p := sim.waiting[0]
+		sim.waiting = sim.waiting[1:]
+		p.ip = ipConfig.ID
+		waits = append(waits, Wait{Pod: p.name, Arrived: p.arrived, Waited: at - p.arrived})
+	}
+	return waits
+}
+
+func (sim *simulation) point(at time.Duration, demand int) Point {
+	return Point{
+		At:             at,
+		Demand:         int64(demand),
+		Assigned:       int64(demand - len(sim.waiting)),
+		Requested:      sim.requested,
+		Allocated:      int64(len(sim.store.GetPodIPConfigState())),
+		PendingRelease: int64(len(sim.store.GetPendingReleaseIPConfigs())),
+	}
+}
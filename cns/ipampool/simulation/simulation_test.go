@@ -0,0 +1,153 @@
+package simulation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/Azure/azure-container-networking/cns/ipampool/v2"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testScaler = v1alpha.Scaler{
+	BatchSize:               10,
+	RequestThresholdPercent: 50,
+	ReleaseThresholdPercent: 150,
+	MaxIPCount:              250,
+}
+
+// burstTrace is a baseline of 10 Pods with a burst to 60 Pods for two minutes every ten minutes, like a CronJob.
+func burstTrace(d time.Duration) []Sample {
+	var trace []Sample
+	for at := time.Duration(0); at <= d; at += 10 * time.Minute {
+		trace = append(trace, Sample{At: at, Demand: 10}, Sample{At: at + 5*time.Minute, Demand: 60}, Sample{At: at + 7*time.Minute, Demand: 10})
+	}
+	return trace
+}
+
+func TestReadDemand(t *testing.T) {
+	samples, err := ReadDemand(strings.NewReader("seconds,demand\n# comment\n\n10, 5\n0,1\n1.5,3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{{0, 1}, {1500 * time.Millisecond, 3}, {10 * time.Second, 5}}, samples)
+
+	_, err = ReadDemand(strings.NewReader("0,1\nten,5\n"))
+	require.Error(t, err)
+	_, err = ReadDemand(strings.NewReader("0,-1\n"))
+	require.Error(t, err)
+	_, err = ReadDemand(strings.NewReader("0,1,2\n"))
+	require.Error(t, err)
+
+	// the most recently arrived pods depart first
+	assert.Equal(t, Trace{
+		{At: 0, Pod: "pod-0"},
+		{At: 1500 * time.Millisecond, Pod: "pod-1"},
+		{At: 1500 * time.Millisecond, Pod: "pod-2"},
+		{At: 10 * time.Second, Pod: "pod-2", Departs: true},
+	}, FromDemand([]Sample{{0, 1}, {1500 * time.Millisecond, 3}, {10 * time.Second, 2}}))
+}
+
+func TestReadEvents(t *testing.T) {
+	trace, err := ReadEvents(strings.NewReader("seconds,pod,event\n5,a,depart\n0,a,arrive\n2, b, Arrive\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Trace{{At: 0, Pod: "a"}, {At: 2 * time.Second, Pod: "b"}, {At: 5 * time.Second, Pod: "a", Departs: true}}, trace)
+
+	_, err = ReadEvents(strings.NewReader("0,a,leave\n"))
+	require.Error(t, err)
+}
+
+func TestReadScript(t *testing.T) {
+	trace, err := ReadScript(strings.NewReader(`
+# two pods, and a burst of two more every minute
+0s arrive 2
+30s arrive 2
+45s depart 2
+repeat 1m 1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Trace{
+		{At: 0, Pod: "pod-0"},
+		{At: 0, Pod: "pod-1"},
+		{At: 30 * time.Second, Pod: "pod-2"},
+		{At: 30 * time.Second, Pod: "pod-3"},
+		{At: 45 * time.Second, Pod: "pod-3", Departs: true},
+		{At: 45 * time.Second, Pod: "pod-2", Departs: true},
+		{At: time.Minute, Pod: "pod-4"},
+		{At: time.Minute, Pod: "pod-5"},
+		{At: 90 * time.Second, Pod: "pod-6"},
+		{At: 90 * time.Second, Pod: "pod-7"},
+		{At: 105 * time.Second, Pod: "pod-7", Departs: true},
+		{At: 105 * time.Second, Pod: "pod-6", Departs: true},
+	}, trace)
+
+	for _, script := range []string{"1m arrive", "1m leave 2", "soon arrive 2", "0s arrive -1", "repeat 0s 2"} {
+		_, err := ReadScript(strings.NewReader(script))
+		require.Error(t, err, script)
+	}
+}
+
+func TestRun(t *testing.T) {
+	logger.InitLogger("testlogs", 0, 0, "./")
+	trace := FromDemand([]Sample{{0, 5}, {time.Minute, 40}, {3 * time.Minute, 5}})
+	for name, run := range map[string]func(context.Context, Trace, Config) (*Result, error){"v1": RunV1, "v2": RunV2} {
+		run := run
+		t.Run(name, func(t *testing.T) {
+			res, err := run(context.Background(), trace, Config{Scaler: testScaler, Latency: 5 * time.Second, Duration: 5 * time.Minute})
+			require.NoError(t, err)
+			require.Len(t, res.Timeline, 301)
+			assert.Zero(t, res.Failures)
+			assert.Positive(t, res.Shortfall)
+			assert.Len(t, res.Waits, 40)
+			assert.Zero(t, res.Unassigned)
+			assert.Zero(t, res.WaitPercentile(25))
+			assert.Positive(t, res.WaitPercentile(100))
+
+			// the pool scales up to meet the burst, and back down after it
+			peak := res.Timeline[2*60]
+			assert.Equal(t, peak.Demand, peak.Assigned)
+			assert.GreaterOrEqual(t, res.PeakRequested, int64(50))
+			last := res.Timeline[len(res.Timeline)-1]
+			assert.Equal(t, int64(5), last.Assigned)
+			assert.Less(t, last.Allocated, int64(30))
+			assert.Zero(t, last.PendingRelease)
+		})
+	}
+}
+
+func TestPredictorReducesShortfall(t *testing.T) {
+	trace := FromDemand(burstTrace(time.Hour))
+	cfg := Config{Scaler: testScaler, Latency: 30 * time.Second}
+	reactive, err := RunV2(context.Background(), trace, cfg)
+	require.NoError(t, err)
+
+	cfg.Predictor = &v2.PredictorOptions{}
+	predictive, err := RunV2(context.Background(), trace, cfg)
+	require.NoError(t, err)
+	t.Logf("reactive: %s", reactive)
+	t.Logf("predictive: %s", predictive)
+
+	// without the predictor, each burst waits for the latency
+	assert.InDelta(t, 6*50*30, reactive.Shortfall, 6*50*5)
+	// with it, only the bursts before the period is detected do
+	assert.Less(t, predictive.Shortfall, reactive.Shortfall/2)
+	assert.LessOrEqual(t, predictive.PeakRequested, testScaler.MaxIPCount)
+	assert.Less(t, predictive.WaitPercentile(50), reactive.WaitPercentile(50))
+}
+
+func TestWaits(t *testing.T) {
+	// the second pod departs while waiting, and the third waits until the end
+	trace := Trace{{At: 0, Pod: "a"}, {At: 0, Pod: "b"}, {At: 0, Pod: "c"}, {At: 2 * time.Second, Pod: "b", Departs: true}}
+	res, err := RunV2(context.Background(), trace, Config{
+		Scaler:     v1alpha.Scaler{BatchSize: 1, MaxIPCount: 2},
+		InitialIPs: 1,
+		Latency:    5 * time.Second,
+		Duration:   10 * time.Second,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Wait{{Pod: "a"}, {Pod: "c", Waited: 5 * time.Second}}, res.Waits)
+	assert.Zero(t, res.Unassigned)
+	assert.Equal(t, 5*time.Second, res.WaitPercentile(99))
+}
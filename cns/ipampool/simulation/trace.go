@@ -0,0 +1,219 @@
+package simulation
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Event is a Pod arriving on or departing from the Node at a point in a trace.
+type Event struct {
+	At  time.Duration
+	Pod string
+	// Departs is whether the Pod departs from the Node, rather than arrives on it.
+	Departs bool
+}
+
+// Trace is the Pods arriving on and departing from the Node, sorted by time.
+type Trace []Event
+
+// End is the time of the last event.
+func (t Trace) End() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].At
+}
+
+// Sample is the IP demand, the number of Pods scheduled on the Node, from a point in a trace on.
+type Sample struct {
+	At     time.Duration
+	Demand int
+}
+
+// FromDemand converts the demand samples to a trace of anonymous Pods arriving and departing, the most recently
+// arrived Pods departing first.
+func FromDemand(samples []Sample) Trace {
+	var pods anonymousPods
+	var trace Trace
+	for _, s := range samples {
+		trace = pods.scale(trace, s.At, s.Demand-len(pods.present))
+	}
+	return trace
+}
+
+// anonymousPods names the Pods of changes in the number of Pods.
+type anonymousPods struct {
+	next    int
+	present []string
+}
+
+// scale appends to the trace delta Pods arriving, or -delta of the most recently arrived Pods departing.
+func (a *anonymousPods) scale(trace Trace, at time.Duration, delta int) Trace {
+	for ; delta > 0; delta-- {
+		pod := fmt.Sprintf("pod-%d", a.next)
+		a.next++
+		a.present = append(a.present, pod)
+		trace = append(trace, Event{At: at, Pod: pod})
+	}
+	for ; delta < 0 && len(a.present) > 0; delta++ {
+		last := len(a.present) - 1
+		trace = append(trace, Event{At: at, Pod: a.present[last], Departs: true})
+		a.present = a.present[:last]
+	}
+	return trace
+}
+
+// ReadDemand reads demand samples from CSV records of the seconds since the start of the trace and the demand at
+// that time, such as "90,42". A header record and lines starting with '#' are skipped. The samples are returned
+// sorted by time.
+func ReadDemand(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	err := readCSV(r, 2, func(at time.Duration, record []string) error { //nolint:gomnd // seconds and demand
+		demand, err := strconv.Atoi(record[1])
+		if err != nil {
+			return errors.Wrapf(err, "invalid demand in trace record %v", record)
+		}
+		if demand < 0 {
+			return errors.Errorf("negative demand in trace record %v", record)
+		}
+		samples = append(samples, Sample{At: at, Demand: demand})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].At < samples[j].At })
+	return samples, nil
+}
+
+// ReadEvents reads a trace from CSV records of the seconds since the start of the trace, the Pod, and whether it
+// "arrive"s or "depart"s, such as "90,default/nginx-1,arrive". A header record and lines starting with '#' are
+// skipped.
+func ReadEvents(r io.Reader) (Trace, error) {
+	var trace Trace
+	err := readCSV(r, 3, func(at time.Duration, record []string) error { //nolint:gomnd // seconds, pod and event
+		e := Event{At: at, Pod: record[1]}
+		switch strings.ToLower(record[2]) {
+		case "arrive":
+		case "depart":
+			e.Departs = true
+		default:
+			return errors.Errorf("invalid event in trace record %v, must be arrive or depart", record)
+		}
+		trace = append(trace, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(trace, func(i, j int) bool { return trace[i].At < trace[j].At })
+	return trace, nil
+}
+
+// readCSV calls fn with the time and trimmed fields of each record of the CSV. The first record is skipped as a
+// header if its time is not a number.
+func readCSV(r io.Reader, fields int, fn func(time.Duration, []string) error) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = fields
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read trace")
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		secs, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			if first {
+				continue
+			}
+			return errors.Wrapf(err, "invalid time in trace record %v", record)
+		}
+		if secs < 0 {
+			return errors.Errorf("negative time in trace record %v", record)
+		}
+		if err := fn(time.Duration(secs*float64(time.Second)), record); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadScript reads a trace of anonymous Pods from a script with a step on each line:
+//
+//	<time> arrive <count>    count Pods arrive at the time, such as "1m30s arrive 20"
+//	<time> depart <count>    count of the most recently arrived Pods depart at the time
+//	repeat <every> <times>   the steps since the previous repeat are repeated times more, each every later
+//
+// Blank lines and lines starting with '#' are skipped.
+func ReadScript(r io.Reader) (Trace, error) {
+	type step struct {
+		at    time.Duration
+		delta int
+	}
+	var steps, block []step
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 { //nolint:gomnd // every step has 3 fields
+			return nil, errors.Errorf("line %d: expected 3 fields, got %d", line, len(fields))
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil || count < 0 {
+			return nil, errors.Errorf("line %d: invalid count %q", line, fields[2])
+		}
+		if fields[0] == "repeat" {
+			every, err := time.ParseDuration(fields[1])
+			if err != nil || every <= 0 {
+				return nil, errors.Errorf("line %d: invalid period %q", line, fields[1])
+			}
+			steps = append(steps, block...)
+			for i := 1; i <= count; i++ {
+				for _, st := range block {
+					steps = append(steps, step{at: st.at + time.Duration(i)*every, delta: st.delta})
+				}
+			}
+			block = nil
+			continue
+		}
+		at, err := time.ParseDuration(fields[0])
+		if err != nil || at < 0 {
+			return nil, errors.Errorf("line %d: invalid time %q", line, fields[0])
+		}
+		switch fields[1] {
+		case "arrive":
+			block = append(block, step{at: at, delta: count})
+		case "depart":
+			block = append(block, step{at: at, delta: -count})
+		default:
+			return nil, errors.Errorf("line %d: invalid step %q, must be arrive, depart or repeat", line, fields[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read script")
+	}
+	steps = append(steps, block...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at < steps[j].at })
+
+	var pods anonymousPods
+	var trace Trace
+	for _, st := range steps {
+		trace = pods.scale(trace, st.at, st.delta)
+	}
+	return trace, nil
+}
@@ -0,0 +1,49 @@
+package v2
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	predictedDemand = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cx_ipam_predicted_demand",
+			Help: "Peak IP demand the predictor expects within its lead time.",
+		},
+	)
+	demandRate = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cx_ipam_demand_rate",
+			Help: "Rate the IP demand is increasing at, in Pods per second, as seen by the predictor.",
+		},
+	)
+	demandPeriod = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cx_ipam_demand_period_seconds",
+			Help: "Period of the recurring IP demand bursts detected by the predictor, 0 if none.",
+		},
+	)
+	predictedScaleCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cx_ipam_predicted_scale_count_total",
+			Help: "Count of pool scaling requests sized by a predicted demand above the current demand, by reason.",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		predictedDemand,
+		demandRate,
+		demandPeriod,
+		predictedScaleCount,
+	)
+}
+
+func observePrediction(p prediction) {
+	predictedDemand.Set(float64(p.demand))
+	demandRate.Set(p.rate)
+	demandPeriod.Set(p.period.Seconds())
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
 	"github.com/Azure/azure-container-networking/crd/clustersubnetstate/api/v1alpha1"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/pkg/errors"
@@ -24,6 +26,7 @@ type nodeNetworkConfigSpecUpdater interface {
 }
 
 type ipStateStore interface {
+	GetPodIPConfigState() map[string]cns.IPConfigurationStatus
 	GetPendingReleaseIPConfigs() []cns.IPConfigurationStatus
 	MarkNIPsPendingRelease(n int) (map[string]cns.IPConfigurationStatus, error)
 }
@@ -42,6 +45,10 @@ type Monitor struct {
 	store        ipStateStore
 	demand       int64
 	request      int64
+	reservations *ipreservation.Set
+	predictor    *predictor
+	// predicted is the last prediction which raised the demand, kept to log only the changes.
+	predicted    prediction
 	demandSource <-chan int
 	cssSource    <-chan v1alpha1.ClusterSubnetState
 	nncSource    <-chan v1alpha.NodeNetworkConfig
@@ -61,32 +68,41 @@ func NewMonitor(z *zap.Logger, store ipStateStore, nnccli nodeNetworkConfigSpecU
 	}
 }
 
+// WithPredictor makes the Monitor request IPs for the peak demand it predicts from the recent demand history when
+// that is higher than the current demand, so that the pool is scaled up ahead of bursts of Pods. It must be called
+// before Start.
+func (pm *Monitor) WithPredictor(opts PredictorOptions) *Monitor {
+	pm.predictor = newPredictor(opts)
+	return pm
+}
+
 // Start begins the Monitor's pool reconcile loop.
 // On first run, it will block until a NodeNetworkConfig is received (through a call to Update()).
 // Subsequently, it will run run once per RefreshDelay and attempt to re-reconcile the pool.
 func (pm *Monitor) Start(ctx context.Context) error {
 	pm.z.Debug("starting")
+	// with a predictor, the pool is also reconciled on a tick so that it is scaled ahead of the predicted demand
+	// while the current demand doesn't change.
+	var tick <-chan time.Time
+	if pm.predictor != nil {
+		ticker := time.NewTicker(pm.predictor.opts.Resolution)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 	for {
 		// proceed when things happen:
 		select {
 		case <-ctx.Done(): // calling context has closed, we'll exit.
 			return errors.Wrap(ctx.Err(), "pool monitor context closed")
 		case demand := <-pm.demandSource: // updated demand for IPs, recalculate request
-			pm.demand = int64(demand)
+			pm.updateDemand(time.Now(), demand)
 			pm.z.Info("demand update", zap.Int64("demand", pm.demand))
+		case <-tick: // recalculate request for the predicted demand
 		case css := <-pm.cssSource: // received an updated ClusterSubnetState, recalculate request
 			pm.scaler.exhausted = css.Status.Exhausted
 			pm.z.Info("exhaustion update", zap.Bool("exhausted", pm.scaler.exhausted))
 		case nnc := <-pm.nncSource: // received a new NodeNetworkConfig, extract the data from it and recalculate request
-			pm.scaler.max = int64(math.Min(float64(nnc.Status.Scaler.MaxIPCount), DefaultMaxIPs))
-			pm.scaler.batch = int64(math.Min(math.Max(float64(nnc.Status.Scaler.BatchSize), 1), float64(pm.scaler.max)))
-			pm.scaler.buffer = math.Abs(float64(nnc.Status.Scaler.RequestThresholdPercent)) / 100 //nolint:gomnd // it's a percentage
-			pm.once.Do(func() {
-				pm.request = nnc.Spec.RequestedIPCount
-				close(pm.started) // close the init channel the first time we fully receive a NodeNetworkConfig.
-				pm.z.Debug("started", zap.Int64("initial request", pm.request))
-			})
-			pm.z.Info("scaler update", zap.Int64("batch", pm.scaler.batch), zap.Float64("buffer", pm.scaler.buffer), zap.Int64("max", pm.scaler.max), zap.Int64("request", pm.request))
+			pm.applyNNC(&nnc)
 		}
 		select {
 		case <-pm.started: // this blocks until we have initialized
@@ -95,13 +111,55 @@ func (pm *Monitor) Start(ctx context.Context) error {
 			continue // jumps to the next iteration of the outer for-loop
 		}
 		// if control has flowed through the select(s) to this point, we can now reconcile.
-		if err := pm.reconcile(ctx); err != nil {
+		if err := pm.reconcile(ctx, time.Now()); err != nil {
 			pm.z.Error("reconcile failed", zap.Error(err))
 		}
 	}
 }
 
-func (pm *Monitor) reconcile(ctx context.Context) error {
+// Step runs one iteration of the reconcile loop of Start at the given time: the demand is updated, the
+// NodeNetworkConfig is applied if not nil, and the pool is reconciled once the Monitor has received a
+// NodeNetworkConfig. It drives the Monitor without Start, as in simulations.
+func (pm *Monitor) Step(ctx context.Context, now time.Time, demand int, nnc *v1alpha.NodeNetworkConfig) error {
+	pm.updateDemand(now, demand)
+	if nnc != nil {
+		pm.applyNNC(nnc)
+	}
+	select {
+	case <-pm.started:
+	default:
+		return nil
+	}
+	return pm.reconcile(ctx, now)
+}
+
+func (pm *Monitor) updateDemand(now time.Time, demand int) {
+	pm.demand = int64(demand)
+	if pm.predictor != nil {
+		pm.predictor.observe(now, pm.demand)
+	}
+}
+
+// applyNNC extracts the scaler, request and reservations from the NodeNetworkConfig.
+func (pm *Monitor) applyNNC(nnc *v1alpha.NodeNetworkConfig) {
+	pm.scaler.max = int64(math.Min(float64(nnc.Status.Scaler.MaxIPCount), DefaultMaxIPs))
+	pm.scaler.batch = int64(math.Min(math.Max(float64(nnc.Status.Scaler.BatchSize), 1), float64(pm.scaler.max)))
+	pm.scaler.buffer = math.Abs(float64(nnc.Status.Scaler.RequestThresholdPercent)) / 100 //nolint:gomnd // it's a percentage
+	// the previous exclusions and reservations are kept if the new ones are invalid, as CNS does.
+	if reservations, err := ipreservation.New(&nnc.Spec); err != nil {
+		pm.z.Error("invalid IP reservations", zap.Error(err))
+	} else {
+		pm.reservations = reservations
+	}
+	pm.once.Do(func() {
+		pm.request = nnc.Spec.RequestedIPCount
+		close(pm.started) // close the init channel the first time we fully receive a NodeNetworkConfig.
+		pm.z.Debug("started", zap.Int64("initial request", pm.request))
+	})
+	pm.z.Info("scaler update", zap.Int64("batch", pm.scaler.batch), zap.Float64("buffer", pm.scaler.buffer), zap.Int64("max", pm.scaler.max), zap.Int64("request", pm.request))
+}
+
+func (pm *Monitor) reconcile(ctx context.Context, now time.Time) error {
 	// if the subnet is exhausted, locally overwrite the batch/minfree/maxfree in the meta copy for this iteration
 	// (until the controlplane owns this and modifies the scaler values for us directly instead of writing "exhausted")
 	// TODO(rbtr)
@@ -111,14 +169,28 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 		s.buffer = 1
 	}
 
+	demand := pm.demand
+	predicted := pm.predict(now)
+	if predicted.demand > demand {
+		demand = predicted.demand
+	}
+
+	// the IPs which are excluded or reserved for Pods which are not running can't meet the demand, so are added to it.
+	if pm.reservations != nil {
+		demand += pm.reservations.CountUnassignable(pm.store.GetPodIPConfigState())
+	}
+
 	// calculate the target state from the current pool state and scaler
-	target := calculateTargetIPCountOrMax(pm.demand, s.batch, s.max, s.buffer)
-	pm.z.Info("calculated new request", zap.Int64("demand", pm.demand), zap.Int64("batch", s.batch), zap.Int64("max", s.max), zap.Float64("buffer", s.buffer), zap.Int64("target", target))
+	target := calculateTargetIPCountOrMax(demand, s.batch, s.max, s.buffer)
+	pm.z.Info("calculated new request", zap.Int64("demand", demand), zap.Int64("batch", s.batch), zap.Int64("max", s.max), zap.Float64("buffer", s.buffer), zap.Int64("target", target))
 	delta := target - pm.request
 	if delta == 0 {
 		return nil
 	}
-	pm.z.Info("scaling pool", zap.Int64("delta", delta))
+	pm.z.Info("scaling pool", zap.Int64("delta", delta), zap.String("demand source", predicted.reason))
+	if predicted.demand > pm.demand {
+		predictedScaleCount.WithLabelValues(predicted.reason).Inc()
+	}
 	// try to release -delta IPs. this is no-op if delta is negative.
 	if _, err := pm.store.MarkNIPsPendingRelease(int(-delta)); err != nil {
 		return errors.Wrapf(err, "failed to mark sufficient IPs as PendingRelease, wanted %d", pm.request-target)
@@ -132,6 +204,26 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 	return nil
 }
 
+// predict returns the demand predicted at the given time, logging why when it raises the demand above the current
+// demand and that has changed. Without a predictor it is the current demand.
+func (pm *Monitor) predict(now time.Time) prediction {
+	if pm.predictor == nil {
+		return prediction{demand: pm.demand, reason: reasonCurrent}
+	}
+	p := pm.predictor.predict(now)
+	observePrediction(p)
+	if p.demand > pm.demand && p != pm.predicted {
+		pm.z.Info("predicted demand", zap.Int64("demand", pm.demand), zap.Int64("predicted", p.demand), zap.String("reason", p.reason),
+			zap.Float64("rate", p.rate), zap.Duration("period", p.period), zap.Duration("lead", pm.predictor.opts.Lead))
+	}
+	if p.demand > pm.demand {
+		pm.predicted = p
+	} else {
+		pm.predicted = prediction{}
+	}
+	return p
+}
+
 // buildNNCSpec translates CNS's map of IPs to be released and requested IP count into an NNC Spec.
 func (pm *Monitor) buildNNCSpec(request int64) v1alpha.NodeNetworkConfigSpec {
 	// Get All Pending IPs from CNS and populate it again.
@@ -5,8 +5,10 @@ import (
 	"math/rand"
 	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/google/uuid"
@@ -18,10 +20,15 @@ import (
 )
 
 type ipStateStoreMock struct {
+	ipConfigs               map[string]cns.IPConfigurationStatus
 	pendingReleaseIPConfigs map[string]cns.IPConfigurationStatus
 	err                     error
 }
 
+func (m *ipStateStoreMock) GetPodIPConfigState() map[string]cns.IPConfigurationStatus {
+	return m.ipConfigs
+}
+
 func (m *ipStateStoreMock) GetPendingReleaseIPConfigs() []cns.IPConfigurationStatus {
 	return maps.Values(m.pendingReleaseIPConfigs)
 }
@@ -53,6 +60,22 @@ func pendingReleaseGenerator(n int) map[string]cns.IPConfigurationStatus {
 	return m
 }
 
+// availableGenerator generates n Available IPConfigs from 10.0.0.0.
+func availableGenerator(n int) map[string]cns.IPConfigurationStatus {
+	m := make(map[string]cns.IPConfigurationStatus, n)
+	ip := netip.MustParseAddr("10.0.0.0")
+	for i := 0; i < n; i++ {
+		status := cns.IPConfigurationStatus{
+			ID:        uuid.New().String(),
+			IPAddress: ip.String(),
+		}
+		status.SetState(types.Available)
+		m[status.ID] = status
+		ip = ip.Next()
+	}
+	return m
+}
+
 func TestPendingReleaseIPConfigsGenerator(t *testing.T) {
 	t.Parallel()
 	n := rand.Intn(100) //nolint:gosec // test
@@ -122,6 +145,7 @@ func TestReconcile(t *testing.T) {
 		scaler             scaler
 		nnccli             nncClientMock
 		store              ipStateStoreMock
+		reservations       v1alpha.NodeNetworkConfigSpec
 		wantRequest        int64
 		wantPendingRelease int
 		wantErr            bool
@@ -313,6 +337,25 @@ func TestReconcile(t *testing.T) {
 			wantRequest:        32,
 			wantPendingRelease: 16,
 		},
+		// the IPs excluded or reserved for Pods which are not running are added to the demand
+		{
+			name:    "scale up for reservations",
+			demand:  20,
+			request: 16,
+			scaler: scaler{
+				batch:  16,
+				buffer: .5,
+				max:    250,
+			},
+			reservations: v1alpha.NodeNetworkConfigSpec{
+				ExcludedIPs: []string{"10.0.0.0/29"},
+			},
+			nnccli: nncClientMock{},
+			store: ipStateStoreMock{
+				ipConfigs: availableGenerator(8),
+			},
+			wantRequest: 48,
+		},
 		// normal scale down with previous pending release
 		{
 			name:    "single scale down with pending release",
@@ -335,15 +378,18 @@ func TestReconcile(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt := tt
 			t.Parallel()
+			reservations, err := ipreservation.New(&tt.reservations)
+			require.NoError(t, err)
 			pm := &Monitor{
-				z:       zap.NewNop(),
-				demand:  tt.demand,
-				request: tt.request,
-				scaler:  tt.scaler,
-				nnccli:  &tt.nnccli,
-				store:   &tt.store,
+				z:            zap.NewNop(),
+				demand:       tt.demand,
+				request:      tt.request,
+				reservations: reservations,
+				scaler:       tt.scaler,
+				nnccli:       &tt.nnccli,
+				store:        &tt.store,
 			}
-			err := pm.reconcile(context.Background())
+			err = pm.reconcile(context.Background(), time.Now())
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -0,0 +1,264 @@
+package v2
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultPredictorWindow is the default length of demand history the predictor looks for recurring bursts in.
+	DefaultPredictorWindow = time.Hour
+	// DefaultPredictorResolution is the default length of the buckets the demand history is kept in.
+	DefaultPredictorResolution = 10 * time.Second
+	// DefaultPredictorLead is the default time ahead the predictor predicts the demand for.
+	DefaultPredictorLead = time.Minute
+	// DefaultPredictorMinCorrelation is the default correlation the demand must repeat with to be predicted.
+	DefaultPredictorMinCorrelation = 0.7
+)
+
+// PredictorOptions configures the demand predictor of the Monitor. Zero values take the defaults.
+type PredictorOptions struct {
+	// Window is how much demand history is kept to look for recurring bursts in. It is at least one Resolution.
+	Window time.Duration
+	// Resolution is the length of the buckets the demand history is kept in, each holding the peak demand in it.
+	Resolution time.Duration
+	// Lead is how far ahead the demand is predicted. It should cover the time it takes for the NodeNetworkConfig
+	// to be updated with the requested IPs.
+	Lead time.Duration
+	// MinCorrelation is how closely the demand must repeat over a period, from 0 to 1, for its bursts to be predicted.
+	MinCorrelation float64
+}
+
+func (o *PredictorOptions) setDefaults() {
+	if o.Window <= 0 {
+		o.Window = DefaultPredictorWindow
+	}
+	if o.Resolution <= 0 {
+		o.Resolution = DefaultPredictorResolution
+	}
+	if o.Window < o.Resolution {
+		o.Window = o.Resolution
+	}
+	if o.Lead <= 0 {
+		o.Lead = DefaultPredictorLead
+	}
+	if o.MinCorrelation <= 0 || o.MinCorrelation > 1 {
+		o.MinCorrelation = DefaultPredictorMinCorrelation
+	}
+}
+
+// Reasons for the predicted demand.
+const (
+	reasonCurrent = "current"
+	reasonTrend   = "trend"
+	reasonBurst   = "burst"
+)
+
+// prediction is the peak demand the predictor expects within the lead time, and why.
+type prediction struct {
+	demand int64
+	// reason is which of the current demand, its trend, or a recurring burst the prediction comes from.
+	reason string
+	// rate is how fast the demand is increasing, in Pods per second.
+	rate float64
+	// period is how often the demand repeats, 0 if it doesn't.
+	period time.Duration
+}
+
+// predictor keeps a sliding window of demand samples and predicts the demand from its trend and recurring bursts,
+// such as those of CronJobs, so that the pool can be scaled before the Pods arrive.
+type predictor struct {
+	opts PredictorOptions
+	// buckets holds the peak demand of each Resolution of the Window, oldest first.
+	buckets []int64
+	// first is the start of the oldest bucket.
+	first time.Time
+	// current is the latest demand sample.
+	current int64
+	// period is the detected period of the demand in buckets, recalculated when a bucket is added.
+	period      int
+	periodStale bool
+}
+
+func newPredictor(opts PredictorOptions) *predictor {
+	opts.setDefaults()
+	return &predictor{opts: opts}
+}
+
+func (p *predictor) size() int {
+	return int(p.opts.Window / p.opts.Resolution)
+}
+
+func (p *predictor) leadBuckets() int {
+	return int(math.Ceil(float64(p.opts.Lead) / float64(p.opts.Resolution)))
+}
+
+// observe records a demand sample. The demand is held until the next sample, so the buckets between samples are
+// filled with the previous demand.
+func (p *predictor) observe(now time.Time, demand int64) {
+	p.advance(now)
+	last := len(p.buckets) - 1
+	if demand > p.buckets[last] {
+		p.buckets[last] = demand
+	}
+	p.current = demand
+}
+
+// advance adds the buckets up to now, and drops those which have left the window.
+func (p *predictor) advance(now time.Time) {
+	bucket := now.Truncate(p.opts.Resolution)
+	if len(p.buckets) == 0 {
+		p.first = bucket
+		p.buckets = append(p.buckets, p.current)
+		return
+	}
+	idx := int(bucket.Sub(p.first) / p.opts.Resolution)
+	if idx-len(p.buckets) >= p.size() {
+		// no samples for the whole window, start over
+		p.first = bucket
+		p.buckets = append(p.buckets[:0], p.current)
+		p.periodStale = true
+		return
+	}
+	// samples from before the newest bucket are counted in it
+	for len(p.buckets) <= idx {
+		p.buckets = append(p.buckets, p.current)
+		p.periodStale = true
+	}
+	if drop := len(p.buckets) - p.size(); drop > 0 {
+		p.buckets = append(p.buckets[:0], p.buckets[drop:]...)
+		p.first = p.first.Add(time.Duration(drop) * p.opts.Resolution)
+	}
+}
+
+// predict returns the peak demand expected within the lead time: the highest of the current demand, the current
+// demand extended by its rate of increase, and the peak demand seen at the same point of the previous periods.
+func (p *predictor) predict(now time.Time) prediction {
+	p.advance(now)
+	pred := prediction{demand: p.current, reason: reasonCurrent}
+
+	pred.rate = p.rate()
+	if pred.rate > 0 {
+		if trend := p.current + int64(math.Ceil(pred.rate*p.opts.Lead.Seconds())); trend > pred.demand {
+			pred.demand, pred.reason = trend, reasonTrend
+		}
+	}
+
+	if p.periodStale {
+		p.period = p.detectPeriod()
+		p.periodStale = false
+	}
+	if p.period > 0 {
+		pred.period = time.Duration(p.period) * p.opts.Resolution
+		if burst := p.burst(); burst > pred.demand {
+			pred.demand, pred.reason = burst, reasonBurst
+		}
+	}
+	return pred
+}
+
+// rate returns the slope of the least squares fit of the demand over the lead time, in Pods per second, or 0 unless
+// the demand has increased in each of the last two buckets. A single jump in the demand is not a trend.
+func (p *predictor) rate() float64 {
+	n := p.leadBuckets() + 1
+	if n < 3 { //nolint:gomnd // a line through two points is too noisy
+		n = 3
+	}
+	if n > len(p.buckets) {
+		n = len(p.buckets)
+	}
+	if n < 3 { //nolint:gomnd // need two increases
+		return 0
+	}
+	ys := p.buckets[len(p.buckets)-n:]
+	if ys[n-1] <= ys[n-2] || ys[n-2] <= ys[n-3] {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := float64(i)
+		sumX += x
+		sumY += float64(y)
+		sumXY += x * float64(y)
+		sumXX += x * x
+	}
+	fn := float64(n)
+	slope := (fn*sumXY - sumX*sumY) / (fn*sumXX - sumX*sumX)
+	return slope / p.opts.Resolution.Seconds()
+}
+
+// differences returns the change of each bucket from the previous one.
+func differences(buckets []int64) []float64 {
+	if len(buckets) < 2 { //nolint:gomnd // need two buckets for a change
+		return nil
+	}
+	changes := make([]float64, len(buckets)-1)
+	for i := range changes {
+		changes[i] = float64(buckets[i+1] - buckets[i])
+	}
+	return changes
+}
+
+// detectPeriod returns the period of the demand in buckets, the shortest lag at which the changes in the demand are
+// correlated with themselves at least MinCorrelation and nearly as strongly as at any lag, or 0 if there is none. The
+// history must hold at least two periods. The changes are correlated rather than the demand itself, as a trend or a
+// single burst in the demand makes it correlated with itself at many lags.
+func (p *predictor) detectPeriod() int {
+	changes := differences(p.buckets)
+	n := len(changes)
+	minLag := p.leadBuckets() + 1
+	best, bestLag := 0.0, 0
+	corrs := make([]float64, n/2+1)
+	for lag := minLag; lag <= n/2; lag++ {
+		corrs[lag] = correlation(changes[:n-lag], changes[lag:])
+		if corrs[lag] > best {
+			best, bestLag = corrs[lag], lag
+		}
+	}
+	if best < p.opts.MinCorrelation {
+		return 0
+	}
+	// the multiples of the period correlate as well, prefer the shortest
+	for lag := minLag; lag < bestLag; lag++ {
+		if corrs[lag] >= p.opts.MinCorrelation && corrs[lag] >= 0.9*best { //nolint:gomnd // nearly as strongly
+			return lag
+		}
+	}
+	return bestLag
+}
+
+// burst returns the peak demand seen in the lead time after the same point of each previous period.
+func (p *predictor) burst() int64 {
+	var peak int64
+	now := len(p.buckets) - 1
+	for start := now - p.period; start >= 0; start -= p.period {
+		for i := start + 1; i <= start+p.leadBuckets() && i < len(p.buckets); i++ {
+			if p.buckets[i] > peak {
+				peak = p.buckets[i]
+			}
+		}
+	}
+	return peak
+}
+
+// correlation returns the Pearson correlation coefficient of xs and ys, or 0 if either is constant.
+func correlation(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
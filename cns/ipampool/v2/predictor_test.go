@@ -0,0 +1,101 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Unix(0, 0)
+
+func TestPredictTrend(t *testing.T) {
+	p := newPredictor(PredictorOptions{})
+	// one more Pod every 10s
+	for i := 0; i <= 12; i++ {
+		p.observe(t0.Add(time.Duration(i)*10*time.Second), int64(10+i))
+	}
+	pred := p.predict(t0.Add(2 * time.Minute))
+	assert.Equal(t, reasonTrend, pred.reason)
+	assert.InDelta(t, 0.1, pred.rate, 0.001)
+	assert.Equal(t, int64(22+6), pred.demand)
+
+	// a single jump is not a trend
+	p.observe(t0.Add(130*time.Second), 50)
+	p.observe(t0.Add(140*time.Second), 50)
+	pred = p.predict(t0.Add(140 * time.Second))
+	assert.Equal(t, prediction{demand: 50, reason: reasonCurrent}, pred)
+}
+
+func TestPredictBurst(t *testing.T) {
+	p := newPredictor(PredictorOptions{})
+	// a baseline of 10 Pods with a burst to 60 Pods for two minutes every ten minutes
+	demand := func(at time.Duration) int64 {
+		if at%(10*time.Minute) >= 5*time.Minute && at%(10*time.Minute) < 7*time.Minute {
+			return 60
+		}
+		return 10
+	}
+	for at := time.Duration(0); at <= 24*time.Minute; at += time.Second {
+		p.observe(t0.Add(at), demand(at))
+		if at < 20*time.Minute {
+			// the period is not detected from less than two periods of history
+			require.Equal(t, demand(at), p.predict(t0.Add(at)).demand, "at %s", at)
+		}
+	}
+
+	// the next burst starts in a minute
+	pred := p.predict(t0.Add(24 * time.Minute))
+	assert.Equal(t, reasonBurst, pred.reason)
+	assert.Equal(t, int64(60), pred.demand)
+	assert.Equal(t, 10*time.Minute, pred.period)
+	// but not in the next minute and a half
+	p.observe(t0.Add(27*time.Minute+30*time.Second), 10)
+	pred = p.predict(t0.Add(27*time.Minute + 30*time.Second))
+	assert.Equal(t, reasonCurrent, pred.reason)
+	assert.Equal(t, int64(10), pred.demand)
+}
+
+func TestPredictNoPeriod(t *testing.T) {
+	p := newPredictor(PredictorOptions{})
+	// a single burst doesn't repeat
+	for at := time.Duration(0); at <= 30*time.Minute; at += 10 * time.Second {
+		d := int64(10)
+		if at >= 12*time.Minute && at < 14*time.Minute {
+			d = 60
+		}
+		p.observe(t0.Add(at), d)
+	}
+	pred := p.predict(t0.Add(30 * time.Minute))
+	assert.Equal(t, prediction{demand: 10, reason: reasonCurrent}, pred)
+}
+
+func TestPredictorWindow(t *testing.T) {
+	p := newPredictor(PredictorOptions{Window: time.Minute, Resolution: 10 * time.Second})
+	for at := time.Duration(0); at <= 5*time.Minute; at += 10 * time.Second {
+		p.observe(t0.Add(at), 1)
+	}
+	assert.Len(t, p.buckets, 6)
+	assert.Equal(t, t0.Add(4*time.Minute+10*time.Second), p.first)
+
+	// the history is dropped after a gap longer than the window
+	p.observe(t0.Add(time.Hour), 2)
+	assert.Equal(t, []int64{2}, p.buckets)
+	assert.Equal(t, t0.Add(time.Hour), p.first)
+}
+
+func TestPredictorWindowShorterThanResolution(t *testing.T) {
+	for _, opts := range []PredictorOptions{
+		{Window: time.Second, Resolution: 10 * time.Second},
+		{Window: time.Second, Resolution: -time.Second},
+	} {
+		p := newPredictor(opts)
+		require.GreaterOrEqual(t, p.opts.Window, p.opts.Resolution)
+		require.Positive(t, p.opts.Resolution)
+		for at := time.Duration(0); at <= time.Minute; at += 10 * time.Second {
+			p.observe(t0.Add(at), 1)
+		}
+		assert.Equal(t, prediction{demand: 1, reason: reasonCurrent}, p.predict(t0.Add(time.Minute)))
+	}
+}
@@ -0,0 +1,197 @@
+// Package ipreservation reads the IPs which a NodeNetworkConfig excludes from assignment or reserves for Pods.
+package ipreservation
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalid is returned when the exclusions or reservations of a NodeNetworkConfig can't be parsed.
+	ErrInvalid = errors.New("invalid IP exclusions or reservations")
+	// ErrConflict is returned when the exclusions or reservations conflict with the IPs assigned to Pods.
+	ErrConflict = errors.New("IP exclusions or reservations conflict with current assignments")
+)
+
+// Set is the IPs excluded from assignment and the IPs reserved for Pods. The nil Set excludes and reserves nothing.
+type Set struct {
+	excluded []netip.Prefix
+	// reserved maps the reserved IPs to the key of their Pod.
+	reserved map[netip.Addr]string
+	// pods maps the key of the Pods to their reserved IPs.
+	pods map[string][]string
+}
+
+// PodKey is the key a reservation is looked up by for the Pod.
+func PodKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// New parses the exclusions and reservations of the NodeNetworkConfigSpec. It returns a nil Set if there are none.
+func New(spec *v1alpha.NodeNetworkConfigSpec) (*Set, error) {
+	if len(spec.ExcludedIPs) == 0 && len(spec.IPReservations) == 0 {
+		return nil, nil //nolint:nilnil // the nil Set is usable
+	}
+
+	s := &Set{
+		reserved: make(map[netip.Addr]string, len(spec.IPReservations)),
+		pods:     make(map[string][]string, len(spec.IPReservations)),
+	}
+	for _, excluded := range spec.ExcludedIPs {
+		prefix, err := parsePrefix(excluded)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalid, "excluded IP %q is neither an IP nor a CIDR", excluded)
+		}
+		s.excluded = append(s.excluded, prefix)
+	}
+	for _, r := range spec.IPReservations {
+		if r.PodName == "" || r.PodNamespace == "" {
+			return nil, errors.Wrapf(ErrInvalid, "reservation of IP %q is missing the pod name or namespace", r.IP)
+		}
+		podKey := PodKey(r.PodNamespace, r.PodName)
+		ip, err := netip.ParseAddr(r.IP)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalid, "IP %q reserved for pod %s is not an IP", r.IP, podKey)
+		}
+		if other, ok := s.reserved[ip]; ok {
+			return nil, errors.Wrapf(ErrInvalid, "IP %s is reserved for both pod %s and pod %s", ip, other, podKey)
+		}
+		if prefix, ok := s.excludedBy(ip); ok {
+			return nil, errors.Wrapf(ErrInvalid, "IP %s reserved for pod %s is excluded by %s", ip, podKey, prefix)
+		}
+		s.reserved[ip] = podKey
+		s.pods[podKey] = append(s.pods[podKey], ip.String())
+	}
+	return s, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err //nolint:wrapcheck // wrapped by the caller
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+func (s *Set) excludedBy(ip netip.Addr) (netip.Prefix, bool) {
+	for _, prefix := range s.excluded {
+		if prefix.Contains(ip) {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// Len returns the number of reserved IPs.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.reserved)
+}
+
+// PodIPs returns the IPs reserved for the Pod.
+func (s *Set) PodIPs(podKey string) []string {
+	if s == nil {
+		return nil
+	}
+	return s.pods[podKey]
+}
+
+// Reserved returns whether the IP is reserved for a Pod.
+func (s *Set) Reserved(ip string) bool {
+	if s == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	_, ok := s.reserved[addr]
+	return ok
+}
+
+// Assignable returns whether the IP may be assigned to the Pod: it is not excluded, and is not reserved for another Pod.
+func (s *Set) Assignable(ip, podKey string) bool {
+	return s.check(ip, podKey) == nil
+}
+
+// Unreserved returns whether the IP may be assigned to any Pod.
+func (s *Set) Unreserved(ip string) bool {
+	return s.Assignable(ip, "")
+}
+
+// check returns why the IP may not be assigned to the Pod, or nil if it may.
+func (s *Set) check(ip, podKey string) error {
+	if s == nil {
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	if prefix, ok := s.excludedBy(addr); ok {
+		return fmt.Errorf("IP %s is excluded by %s", ip, prefix) //nolint:goerr113 // wrapped by the caller
+	}
+	if reservedFor, ok := s.reserved[addr]; ok && reservedFor != podKey {
+		return fmt.Errorf("IP %s is reserved for pod %s", ip, reservedFor) //nolint:goerr113 // wrapped by the caller
+	}
+	return nil
+}
+
+// CheckAssignable returns an error wrapping ErrConflict if the IP may not be assigned to the Pod.
+func (s *Set) CheckAssignable(ip, podKey string) error {
+	if err := s.check(ip, podKey); err != nil {
+		return errors.Wrapf(ErrConflict, "%v, requested for pod %s", err, podKey)
+	}
+	return nil
+}
+
+// CountUnassignable returns the number of Available IPs which can't be assigned to the Pods being scheduled, as they
+// are excluded or reserved. The pool monitors don't count these IPs as free.
+func (s *Set) CountUnassignable(ips map[string]cns.IPConfigurationStatus) int64 {
+	if s == nil {
+		return 0
+	}
+	var n int64
+	for _, ipConfig := range ips { //nolint:gocritic // ignore copy
+		if ipConfig.GetState() == types.Available && !s.Unreserved(ipConfig.IPAddress) {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate returns an error wrapping ErrConflict listing the IPs assigned to Pods which they are excluded or
+// reserved for others.
+func (s *Set) Validate(ips map[string]cns.IPConfigurationStatus) error {
+	if s == nil {
+		return nil
+	}
+	conflicts := []string{}
+	for _, ipConfig := range ips { //nolint:gocritic // ignore copy
+		if ipConfig.GetState() != types.Assigned || ipConfig.PodInfo == nil {
+			continue
+		}
+		podKey := PodKey(ipConfig.PodInfo.Namespace(), ipConfig.PodInfo.Name())
+		if err := s.check(ipConfig.IPAddress, podKey); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%v but is assigned to pod %s", err, podKey))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return errors.Wrap(ErrConflict, strings.Join(conflicts, "; "))
+}
@@ -0,0 +1,154 @@
+package ipreservation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha.NodeNetworkConfigSpec
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "none",
+			spec:    v1alpha.NodeNetworkConfigSpec{RequestedIPCount: 16},
+			wantNil: true,
+		},
+		{
+			name: "valid",
+			spec: v1alpha.NodeNetworkConfigSpec{
+				ExcludedIPs:    []string{"10.0.0.1", "10.0.1.0/28", "fd00::1"},
+				IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.2", PodName: "a", PodNamespace: "default"}},
+			},
+		},
+		{
+			name:    "invalid excluded IP",
+			spec:    v1alpha.NodeNetworkConfigSpec{ExcludedIPs: []string{"10.0.0.300"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid excluded CIDR",
+			spec:    v1alpha.NodeNetworkConfigSpec{ExcludedIPs: []string{"10.0.0.0/33"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid reserved IP",
+			spec:    v1alpha.NodeNetworkConfigSpec{IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.0/24", PodName: "a", PodNamespace: "default"}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing pod namespace",
+			spec:    v1alpha.NodeNetworkConfigSpec{IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.1", PodName: "a"}}},
+			wantErr: true,
+		},
+		{
+			name: "IP reserved twice",
+			spec: v1alpha.NodeNetworkConfigSpec{IPReservations: []v1alpha.IPReservation{
+				{IP: "10.0.0.1", PodName: "a", PodNamespace: "default"},
+				{IP: "10.0.0.1", PodName: "b", PodNamespace: "default"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "reserved IP excluded",
+			spec: v1alpha.NodeNetworkConfigSpec{
+				ExcludedIPs:    []string{"10.0.0.0/24"},
+				IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.1", PodName: "a", PodNamespace: "default"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&tt.spec)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, s == nil)
+		})
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	s, err := New(&v1alpha.NodeNetworkConfigSpec{
+		ExcludedIPs:    []string{"10.0.1.0/28"},
+		IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.2", PodName: "a", PodNamespace: "default"}},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, s.Unreserved("10.0.0.1"))
+	assert.False(t, s.Unreserved("10.0.1.15"))
+	assert.True(t, s.Unreserved("10.0.1.16"))
+	assert.False(t, s.Unreserved("10.0.0.2"))
+	assert.True(t, s.Assignable("10.0.0.2", PodKey("default", "a")))
+	assert.False(t, s.Assignable("10.0.0.2", PodKey("default", "b")))
+	require.ErrorIs(t, s.CheckAssignable("10.0.1.1", PodKey("default", "a")), ErrConflict)
+	assert.True(t, s.Reserved("10.0.0.2"))
+	assert.Equal(t, []string{"10.0.0.2"}, s.PodIPs(PodKey("default", "a")))
+
+	// the nil Set excludes and reserves nothing
+	var none *Set
+	assert.True(t, none.Unreserved("10.0.1.1"))
+	assert.Empty(t, none.PodIPs(PodKey("default", "a")))
+	assert.Zero(t, none.Len())
+}
+
+func newIPConfig(ip string, state types.IPState, podInfo cns.PodInfo) cns.IPConfigurationStatus {
+	ipConfig := cns.IPConfigurationStatus{ID: ip, IPAddress: ip, PodInfo: podInfo}
+	ipConfig.SetState(state)
+	return ipConfig
+}
+
+func TestCountUnassignable(t *testing.T) {
+	s, err := New(&v1alpha.NodeNetworkConfigSpec{
+		ExcludedIPs:    []string{"10.0.0.1"},
+		IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.2", PodName: "a", PodNamespace: "default"}},
+	})
+	require.NoError(t, err)
+
+	ips := map[string]cns.IPConfigurationStatus{}
+	for _, ipConfig := range []cns.IPConfigurationStatus{
+		newIPConfig("10.0.0.1", types.Available, nil),
+		newIPConfig("10.0.0.2", types.Available, nil),
+		newIPConfig("10.0.0.3", types.Available, nil),
+		newIPConfig("10.0.0.4", types.Assigned, cns.NewPodInfo("", "", "c", "default")),
+	} {
+		ips[ipConfig.ID] = ipConfig
+	}
+	assert.Equal(t, int64(2), s.CountUnassignable(ips))
+
+	ips["10.0.0.2"] = newIPConfig("10.0.0.2", types.Assigned, cns.NewPodInfo("", "", "a", "default"))
+	assert.Equal(t, int64(1), s.CountUnassignable(ips))
+}
+
+func TestValidate(t *testing.T) {
+	s, err := New(&v1alpha.NodeNetworkConfigSpec{
+		ExcludedIPs:    []string{"10.0.0.1"},
+		IPReservations: []v1alpha.IPReservation{{IP: "10.0.0.2", PodName: "a", PodNamespace: "default"}},
+	})
+	require.NoError(t, err)
+
+	ips := map[string]cns.IPConfigurationStatus{
+		"10.0.0.1": newIPConfig("10.0.0.1", types.Available, nil),
+		"10.0.0.2": newIPConfig("10.0.0.2", types.Assigned, cns.NewPodInfo("", "", "a", "default")),
+	}
+	require.NoError(t, s.Validate(ips))
+
+	ips["10.0.0.1"] = newIPConfig("10.0.0.1", types.Assigned, cns.NewPodInfo("", "", "b", "default"))
+	ips["10.0.0.2"] = newIPConfig("10.0.0.2", types.Assigned, cns.NewPodInfo("", "", "c", "default"))
+	err = s.Validate(ips)
+	require.ErrorIs(t, err, ErrConflict)
+	assert.Contains(t, err.Error(), "IP 10.0.0.1 is excluded by 10.0.0.1/32 but is assigned to pod default/b")
+	assert.Contains(t, err.Error(), "IP 10.0.0.2 is reserved for pod default/a but is assigned to pod default/c")
+}
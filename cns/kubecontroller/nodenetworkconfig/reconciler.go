@@ -2,6 +2,7 @@ package nodenetworkconfig
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -25,6 +26,7 @@ import (
 type cnsClient interface {
 	CreateOrUpdateNetworkContainerInternal(*cns.CreateNetworkContainerRequest) cnstypes.ResponseCode
 	MustEnsureNoStaleNCs(validNCIDs []string)
+	SetIPReservations(*v1alpha.NodeNetworkConfigSpec) error
 }
 
 type nodeNetworkConfigListener interface {
@@ -73,6 +75,13 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 
 	logger.Printf("[cns-rc] CRD Spec: %+v", nnc.Spec)
 
+	// the IP exclusions and reservations are set before the NCs so that new IPs are never assigned against them.
+	// if they conflict with the current assignments, the NCs are still updated and the reconcile is retried later.
+	reservationErr := r.cnscli.SetIPReservations(&nnc.Spec)
+	if reservationErr != nil {
+		logger.Errorf("[cns-rc] Error setting IP reservations: %v", reservationErr)
+	}
+
 	ipAssignments := 0
 
 	// during node upgrades, an nnc may be updated with new ncs. at any given time, only the ncs
@@ -139,6 +148,9 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		close(r.started)
 		logger.Printf("[cns-rc] CNS NNC Reconciler Started")
 	})
+	if reservationErr != nil {
+		return reconcile.Result{}, errors.Wrap(reservationErr, "failed to set IP reservations")
+	}
 	return reconcile.Result{}, nil
 }
 
@@ -156,6 +168,12 @@ func (r *Reconciler) Started(ctx context.Context) (bool, error) {
 	}
 }
 
+// ipReservationsChanged returns whether the IP exclusions or reservations differ between the NodeNetworkConfigSpecs.
+func ipReservationsChanged(oldSpec, newSpec *v1alpha.NodeNetworkConfigSpec) bool {
+	return !reflect.DeepEqual(oldSpec.ExcludedIPs, newSpec.ExcludedIPs) ||
+		!reflect.DeepEqual(oldSpec.IPReservations, newSpec.IPReservations)
+}
+
 // SetupWithManager Sets up the reconciler with a new manager, filtering using NodeNetworkConfigFilter on nodeName.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, node *v1.Node) error {
 	r.nnccli = nodenetworkconfig.NewClient(mgr.GetClient())
@@ -173,11 +191,17 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, node *v1.Node) error {
 		})).
 		WithEventFilter(predicate.Funcs{
 			// check that the generation is the same - status changes don't update generation.
+			// spec changes are made by CNS itself, except for the IP exclusions and reservations.
 			UpdateFunc: func(ue event.UpdateEvent) bool {
 				if ue.ObjectOld == nil || ue.ObjectNew == nil {
 					return false
 				}
-				return ue.ObjectOld.GetGeneration() == ue.ObjectNew.GetGeneration()
+				if ue.ObjectOld.GetGeneration() == ue.ObjectNew.GetGeneration() {
+					return true
+				}
+				oldNNC, okOld := ue.ObjectOld.(*v1alpha.NodeNetworkConfig)
+				newNNC, okNew := ue.ObjectNew.(*v1alpha.NodeNetworkConfig)
+				return okOld && okNew && ipReservationsChanged(&oldNNC.Spec, &newNNC.Spec)
 			},
 		}).
 		Complete(r)
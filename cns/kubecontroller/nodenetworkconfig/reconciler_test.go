@@ -23,9 +23,10 @@ type cnsClientState struct {
 }
 
 type mockCNSClient struct {
-	state            cnsClientState
-	createOrUpdateNC func(*cns.CreateNetworkContainerRequest) cnstypes.ResponseCode
-	update           func(*v1alpha.NodeNetworkConfig) error
+	state             cnsClientState
+	createOrUpdateNC  func(*cns.CreateNetworkContainerRequest) cnstypes.ResponseCode
+	update            func(*v1alpha.NodeNetworkConfig) error
+	setIPReservations func(*v1alpha.NodeNetworkConfigSpec) error
 }
 
 func (m *mockCNSClient) CreateOrUpdateNetworkContainerInternal(req *cns.CreateNetworkContainerRequest) cnstypes.ResponseCode {
@@ -46,6 +47,13 @@ func (m *mockCNSClient) MustEnsureNoStaleNCs(validNCIDs []string) {
 	}
 }
 
+func (m *mockCNSClient) SetIPReservations(spec *v1alpha.NodeNetworkConfigSpec) error {
+	if m.setIPReservations == nil {
+		return nil
+	}
+	return m.setIPReservations(spec)
+}
+
 func (m *mockCNSClient) Update(nnc *v1alpha.NodeNetworkConfig) error {
 	m.state.nnc = nnc
 	return m.update(nnc)
@@ -266,3 +274,54 @@ func TestReconcileStaleNCs(t *testing.T) {
 	assert.Contains(t, cnsClient.state.reqsByNCID, "nc3")
 	assert.Contains(t, cnsClient.state.reqsByNCID, "nc4")
 }
+
+// Tests that the NCs are still updated when the IP reservations conflict with the current assignments.
+func TestReconcileIPReservationConflict(t *testing.T) {
+	logger.InitLogger("", 0, 0, "")
+
+	nnc := &v1alpha.NodeNetworkConfig{
+		Status: validSwiftStatus,
+		Spec: v1alpha.NodeNetworkConfigSpec{
+			RequestedIPCount: 1,
+			ExcludedIPs:      []string{"10.0.0.0/24"},
+		},
+	}
+	errConflict := errors.New("conflict")
+	cnsClient := mockCNSClient{
+		state:             cnsClientState{reqsByNCID: make(map[string]*cns.CreateNetworkContainerRequest)},
+		createOrUpdateNC:  func(*cns.CreateNetworkContainerRequest) cnstypes.ResponseCode { return cnstypes.Success },
+		update:            func(*v1alpha.NodeNetworkConfig) error { return nil },
+		setIPReservations: func(*v1alpha.NodeNetworkConfigSpec) error { return errConflict },
+	}
+
+	r := NewReconciler(&cnsClient, &cnsClient, "")
+	r.nnccli = &mockNCGetter{get: func(context.Context, types.NamespacedName) (*v1alpha.NodeNetworkConfig, error) {
+		return nnc, nil
+	}}
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{})
+	require.ErrorIs(t, err, errConflict)
+	assert.Contains(t, cnsClient.state.reqsByNCID, validSwiftRequest.NetworkContainerid)
+	assert.Equal(t, nnc, cnsClient.state.nnc)
+	started, err := r.Started(context.Background())
+	require.NoError(t, err)
+	assert.True(t, started)
+}
+
+func TestIPReservationsChanged(t *testing.T) {
+	spec := v1alpha.NodeNetworkConfigSpec{
+		RequestedIPCount: 1,
+		IPReservations:   []v1alpha.IPReservation{{IP: "10.0.0.1", PodName: "a", PodNamespace: "default"}},
+	}
+	scaled := spec
+	scaled.RequestedIPCount = 2
+	assert.False(t, ipReservationsChanged(&spec, &scaled))
+
+	excluded := spec
+	excluded.ExcludedIPs = []string{"10.0.0.2"}
+	assert.True(t, ipReservationsChanged(&spec, &excluded))
+
+	reserved := spec
+	reserved.IPReservations = []v1alpha.IPReservation{{IP: "10.0.0.1", PodName: "b", PodNamespace: "default"}}
+	assert.True(t, ipReservationsChanged(&spec, &reserved))
+}
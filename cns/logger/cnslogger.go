@@ -49,6 +49,26 @@ func (c *CNSLogger) InitAIWithIKey(aiConfig aitelemetry.AIConfig, instrumentatio
 	c.DisableEventLogging = disableEventLogging
 }
 
+// InitOTLP sends the telemetry to the OTLP endpoint of an OpenTelemetry collector instead of AppInsights.
+func (c *CNSLogger) InitOTLP(aiConfig aitelemetry.AIConfig, endpoint string, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
+	th, err := aitelemetry.NewOTLPTelemetry(endpoint, aiConfig)
+	if err != nil {
+		c.logger.Errorf("Error initializing OTLP Telemetry:%v", err)
+		return
+	}
+
+	c.th = th
+	c.logger.Printf("OTLP Telemetry Handle created")
+	c.DisableMetricLogging = disableMetricLogging
+	c.DisableTraceLogging = disableTraceLogging
+	c.DisableEventLogging = disableEventLogging
+}
+
+// TelemetryHandle returns the telemetry handle initialized by InitAI or InitOTLP, nil if telemetry is disabled.
+func (c *CNSLogger) TelemetryHandle() aitelemetry.TelemetryHandle {
+	return c.th
+}
+
 // wait time for closing AI telemetry session.
 const waitTimeInSecs = 10
 
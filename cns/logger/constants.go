@@ -19,4 +19,9 @@ const (
 	AllowHostToNCCommunicationStr = "AllowHostToNCCommunication"
 	NetworkContainerTypeStr       = "NetworkContainerType"
 	OrchestratorContextStr        = "OrchestratorContext"
+	// CNS store recovery properties
+	CnsStoreRecoveredEventStr = "CNSStoreRecovered"
+	StoreFileStr              = "StoreFile"
+	BackupFileStr             = "BackupFile"
+	ErrorStr                  = "Error"
 )
@@ -29,6 +29,14 @@ func InitAIWithIKey(aiConfig aitelemetry.AIConfig, instrumentationKey string, di
 	Log.InitAIWithIKey(aiConfig, instrumentationKey, disableTraceLogging, disableMetricLogging, disableEventLogging)
 }
 
+func InitOTLP(aiConfig aitelemetry.AIConfig, endpoint string, disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
+	Log.InitOTLP(aiConfig, endpoint, disableTraceLogging, disableMetricLogging, disableEventLogging)
+}
+
+func TelemetryHandle() aitelemetry.TelemetryHandle {
+	return Log.TelemetryHandle()
+}
+
 func SetContextDetails(orchestrator, nodeID string) {
 	Log.SetContextDetails(orchestrator, nodeID)
 }
@@ -13,7 +13,6 @@ import (
 	"net/url"
 	"regexp"
 	"runtime"
-	"strings"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/hnsclient"
@@ -889,32 +888,7 @@ func (service *HTTPRestService) GetAllNetworkContainers(w http.ResponseWriter, r
 		return
 	}
 
-	getAllNetworkContainerResponses := service.getAllNetworkContainerResponses(req) // nolint
-
-	var resp cns.GetAllNetworkContainersResponse
-
-	failedNetworkContainerResponses := make([]cns.GetNetworkContainerResponse, 0)
-	for i := 0; i < len(getAllNetworkContainerResponses); i++ {
-		if getAllNetworkContainerResponses[i].Response.ReturnCode != types.Success {
-			failedNetworkContainerResponses = append(failedNetworkContainerResponses, getAllNetworkContainerResponses[i])
-		}
-	}
-
-	resp.NetworkContainers = getAllNetworkContainerResponses
-
-	if len(failedNetworkContainerResponses) > 0 {
-		failedToGetNCErrMsg := make([]string, 0)
-		for _, failedNetworkContainerResponse := range failedNetworkContainerResponses { // nolint
-			failedToGetNCErrMsg = append(failedToGetNCErrMsg, fmt.Sprintf("Failed to get NC %s due to %s", failedNetworkContainerResponse.NetworkContainerID, failedNetworkContainerResponse.Response.Message))
-		}
-
-		resp.Response.ReturnCode = types.UnexpectedError
-		resp.Response.Message = strings.Join(failedToGetNCErrMsg, "\n")
-	} else {
-		resp.Response.ReturnCode = types.Success
-		resp.Response.Message = "Successfully retrieved NCs"
-	}
-
+	resp := service.GetAllNetworkContainersInternal(req)
 	err = service.Listener.Encode(w, &resp)
 	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
 }
@@ -204,6 +204,9 @@ func TestMain(m *testing.M) {
 	// Cleanup.
 	service.Stop()
 	nmAgentServer.Stop()
+	if fileStore, err := store.NewJsonFileStore(cnsJsonFileName, processlock.NewMockFileLock(false), nil); err == nil {
+		fileStore.Remove()
+	}
 
 	os.Exit(exitCode)
 }
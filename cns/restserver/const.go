@@ -6,6 +6,7 @@ const (
 	// Key against which CNS state is persisted.
 	storeKey         = "ContainerNetworkService"
 	EndpointStoreKey = "Endpoints"
+	IPLeaseStoreKey  = "IPLeases"
 	attach           = "Attach"
 	detach           = "Detach"
 	// Rest service state identifier for named lock
@@ -442,6 +442,59 @@ func (service *HTTPRestService) GetNetworkContainerInternal(
 	return getNetworkContainerResponses[0], getNetworkContainerResponses[0].Response.ReturnCode
 }
 
+// GetAllNetworkContainersInternal gets the details of all the network containers of the orchestrator context. The
+// response fails if getting any of them failed.
+func (service *HTTPRestService) GetAllNetworkContainersInternal(
+	req cns.GetNetworkContainerRequest,
+) cns.GetAllNetworkContainersResponse {
+	getAllNetworkContainerResponses := service.getAllNetworkContainerResponses(req) // nolint
+
+	var resp cns.GetAllNetworkContainersResponse
+
+	failedNetworkContainerResponses := make([]cns.GetNetworkContainerResponse, 0)
+	for i := 0; i < len(getAllNetworkContainerResponses); i++ {
+		if getAllNetworkContainerResponses[i].Response.ReturnCode != types.Success {
+			failedNetworkContainerResponses = append(failedNetworkContainerResponses, getAllNetworkContainerResponses[i])
+		}
+	}
+
+	resp.NetworkContainers = getAllNetworkContainerResponses
+
+	if len(failedNetworkContainerResponses) > 0 {
+		failedToGetNCErrMsg := make([]string, 0)
+		for _, failedNetworkContainerResponse := range failedNetworkContainerResponses { // nolint
+			failedToGetNCErrMsg = append(failedToGetNCErrMsg, fmt.Sprintf("Failed to get NC %s due to %s", failedNetworkContainerResponse.NetworkContainerID, failedNetworkContainerResponse.Response.Message))
+		}
+
+		resp.Response.ReturnCode = types.UnexpectedError
+		resp.Response.Message = strings.Join(failedToGetNCErrMsg, "\n")
+	} else {
+		resp.Response.ReturnCode = types.Success
+		resp.Response.Message = "Successfully retrieved NCs"
+	}
+	return resp
+}
+
+// GetEndpointInternal gets the state of an endpoint, if CNS manages the endpoint state.
+func (service *HTTPRestService) GetEndpointInternal(endpointID string) GetEndpointResponse {
+	service.Lock()
+	defer service.Unlock()
+	if service.Options[common.OptManageEndpointState] == false {
+		return GetEndpointResponse{Response: Response(endpointStateNotManagedResponse())}
+	}
+	return service.getEndpointResponse(endpointID)
+}
+
+// UpdateEndpointInternal updates the state of an endpoint, if CNS manages the endpoint state.
+func (service *HTTPRestService) UpdateEndpointInternal(endpointID string, req cns.EndpointRequest) cns.Response {
+	service.Lock()
+	defer service.Unlock()
+	if service.Options[common.OptManageEndpointState] == false {
+		return endpointStateNotManagedResponse()
+	}
+	return service.updateEndpointResponse(endpointID, req)
+}
+
 // DeleteNetworkContainerInternal deletes a network container.
 func (service *HTTPRestService) DeleteNetworkContainerInternal(
 	req cns.DeleteNetworkContainerRequest,
@@ -27,6 +27,7 @@ var (
 	ErrNoNCs                  = errors.New("no NCs found in the CNS internal state")
 	ErrOptManageEndpointState = errors.New("CNS is not set to manage the endpoint state")
 	ErrEndpointStateNotFound  = errors.New("endpoint state could not be found in the statefile")
+	ErrNotEnoughIPs           = errors.New("not enough IPs available")
 )
 
 const (
@@ -50,7 +51,7 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 	// record a pod requesting an IP
 	service.podsPendingIPAssignment.Push(podInfo.Key())
 
-	podIPInfo, err := requestIPConfigsHelper(service, ipconfigsRequest) //nolint:contextcheck // appease linter for revert PR
+	podIPInfo, err := service.queuedRequestIPConfigs(ctx, ipconfigsRequest)
 	if err != nil {
 		return &cns.IPConfigsResponse{
 			Response: cns.Response{
@@ -61,6 +62,8 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 		}, err
 	}
 
+	service.recordIPLease(ctx, podInfo)
+
 	// record a pod assigned an IP
 	defer func() {
 		// observe IP assignment wait time
@@ -91,6 +94,25 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 	}, nil
 }
 
+// queuedRequestIPConfigs assigns the requested IPs, waiting in the IP request queue for IPs to become Available
+// if the queue is enabled and the pool is exhausted.
+func (service *HTTPRestService) queuedRequestIPConfigs(ctx context.Context, ipconfigsRequest cns.IPConfigsRequest) ([]cns.PodIpInfo, error) {
+	assign := func() ([]cns.PodIpInfo, error) {
+		return requestIPConfigsHelper(service, ipconfigsRequest) //nolint:contextcheck // appease linter for revert PR
+	}
+	if service.ipRequestQueue == nil {
+		return assign()
+	}
+	existing := func() ([]cns.PodIpInfo, bool, error) {
+		podInfo, err := cns.NewPodInfoFromIPConfigsRequest(ipconfigsRequest)
+		if err != nil {
+			return []cns.PodIpInfo{}, false, errors.Wrapf(err, "failed to parse IPConfigsRequest %v", ipconfigsRequest)
+		}
+		return service.GetExistingIPConfig(podInfo)
+	}
+	return service.ipRequestQueue.do(ctx, existing, assign)
+}
+
 // RequestIPConfigHandler requests an IPConfig from the CNS state
 func (service *HTTPRestService) RequestIPConfigHandler(w http.ResponseWriter, r *http.Request) {
 	var ipconfigRequest cns.IPConfigRequest
@@ -166,6 +188,18 @@ func (service *HTTPRestService) RequestIPConfigHandler(w http.ResponseWriter, r
 	logger.ResponseEx(service.Name+operationName, ipconfigsRequest, reserveResp, reserveResp.Response.ReturnCode, err)
 }
 
+// RequestIPConfigs assigns IPs to the Pod in the request, through the IPConfigsHandlerMiddleware if it is set. It is
+// shared by the REST and gRPC APIs.
+func (service *HTTPRestService) RequestIPConfigs(ctx context.Context, ipconfigsRequest cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+	// Check if IPConfigsHandlerMiddleware is set
+	if service.IPConfigsHandlerMiddleware != nil {
+		// Wrap the default datapath handlers with the middleware
+		wrappedHandler := service.IPConfigsHandlerMiddleware.IPConfigsRequestHandlerWrapper(service.requestIPConfigHandlerHelper, service.ReleaseIPConfigHandlerHelper)
+		return wrappedHandler(ctx, ipconfigsRequest)
+	}
+	return service.requestIPConfigHandlerHelper(ctx, ipconfigsRequest) // nolint:contextcheck // appease linter
+}
+
 // RequestIPConfigsHandler requests multiple IPConfigs from the CNS state
 func (service *HTTPRestService) RequestIPConfigsHandler(w http.ResponseWriter, r *http.Request) {
 	var ipconfigsRequest cns.IPConfigsRequest
@@ -175,17 +209,7 @@ func (service *HTTPRestService) RequestIPConfigsHandler(w http.ResponseWriter, r
 	if err != nil {
 		return
 	}
-	var ipConfigsResp *cns.IPConfigsResponse
-
-	// Check if IPConfigsHandlerMiddleware is set
-	if service.IPConfigsHandlerMiddleware != nil {
-		// Wrap the default datapath handlers with the middleware
-		wrappedHandler := service.IPConfigsHandlerMiddleware.IPConfigsRequestHandlerWrapper(service.requestIPConfigHandlerHelper, service.ReleaseIPConfigHandlerHelper)
-		ipConfigsResp, err = wrappedHandler(r.Context(), ipconfigsRequest)
-	} else {
-		ipConfigsResp, err = service.requestIPConfigHandlerHelper(r.Context(), ipconfigsRequest) // nolint:contextcheck // appease linter
-	}
-
+	ipConfigsResp, err := service.RequestIPConfigs(r.Context(), ipconfigsRequest)
 	if err != nil {
 		w.Header().Set(cnsReturnCode, ipConfigsResp.Response.ReturnCode.String())
 		err = service.Listener.Encode(w, &ipConfigsResp)
@@ -252,7 +276,7 @@ func (service *HTTPRestService) updateEndpointState(ipconfigsRequest cns.IPConfi
 			service.EndpointState[ipconfigsRequest.InfraContainerID] = endpointInfo
 		}
 
-		err := service.EndpointStateStore.Write(EndpointStoreKey, service.EndpointState)
+		err := store.WriteVersioned(service.EndpointStateStore, EndpointStoreKey, service.EndpointState)
 		if err != nil {
 			return fmt.Errorf("failed to write endpoint state to store: %w", err)
 		}
@@ -392,7 +416,7 @@ func (service *HTTPRestService) removeEndpointState(podInfo cns.PodInfo) error {
 	logger.Printf("[removeEndpointState] Removing endpoint state for infra container %s", podInfo.InfraContainerID())
 	if _, ok := service.EndpointState[podInfo.InfraContainerID()]; ok {
 		delete(service.EndpointState, podInfo.InfraContainerID())
-		err := service.EndpointStateStore.Write(EndpointStoreKey, service.EndpointState)
+		err := store.WriteVersioned(service.EndpointStateStore, EndpointStoreKey, service.EndpointState)
 		if err != nil {
 			return fmt.Errorf("failed to write endpoint state to store: %w", err)
 		}
@@ -409,30 +433,31 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 	service.Lock()
 	defer service.Unlock()
 
-	for uuid, existingIpConfig := range service.PodIPConfigState {
-		if existingIpConfig.GetState() == types.PendingProgramming {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo)
-			if err != nil {
-				return nil, err
-			}
-
-			pendingReleasedIps[uuid] = updatedIPConfig
-			if len(pendingReleasedIps) == totalIpsToRelease {
-				return pendingReleasedIps, nil
-			}
+	// limit the IPs to the remaining count, all of them are set to PendingRelease if there is no positive total
+	remaining := func() int {
+		if totalIpsToRelease <= 0 {
+			return -1
 		}
+		return totalIpsToRelease - len(pendingReleasedIps)
 	}
 
-	// if not all expected IPs are set to PendingRelease, then check the Available IPs
-	for uuid, existingIpConfig := range service.PodIPConfigState {
-		if existingIpConfig.GetState() == types.Available {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, existingIpConfig.PodInfo)
+	// start with the PendingProgramming IPs, and if not all expected IPs are set to PendingRelease, then check the Available IPs,
+	// keeping the IPs reserved for Pods
+	for _, state := range []types.IPState{types.PendingProgramming, types.Available} {
+		limit := remaining()
+		if state == types.Available && limit > 0 {
+			limit += service.ipReservations.Len()
+		}
+		for _, uuid := range service.ipIDsInStateUntransacted(state, limit) {
+			if state == types.Available && service.ipReservations.Reserved(service.PodIPConfigState[uuid].IPAddress) {
+				continue
+			}
+			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, service.PodIPConfigState[uuid].PodInfo)
 			if err != nil {
 				return nil, err
 			}
 
 			pendingReleasedIps[uuid] = updatedIPConfig
-
 			if len(pendingReleasedIps) == totalIpsToRelease {
 				return pendingReleasedIps, nil
 			}
@@ -454,36 +479,32 @@ func (service *HTTPRestService) MarkNIPsPendingRelease(n int) (map[string]cns.IP
 	defer service.Unlock()
 	// try to release from PendingProgramming
 	pendingProgrammingIPs := make(map[string]cns.IPConfigurationStatus)
-	for uuid, ipConfig := range service.PodIPConfigState { //nolint:gocritic // intentional value copy
-		if n <= 0 {
-			break
+	for _, uuid := range service.ipIDsInStateUntransacted(types.PendingProgramming, max(n, 0)) {
+		updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, service.PodIPConfigState[uuid].PodInfo)
+		if err != nil {
+			return nil, err
 		}
-		if ipConfig.GetState() == types.PendingProgramming {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, ipConfig.PodInfo)
-			if err != nil {
-				return nil, err
-			}
 
-			pendingProgrammingIPs[uuid] = updatedIPConfig
-			n--
-		}
+		pendingProgrammingIPs[uuid] = updatedIPConfig
+		n--
 	}
 
-	// try to release from Available
+	// try to release from Available, keeping the IPs reserved for Pods
 	availableIPs := make(map[string]cns.IPConfigurationStatus)
-	for uuid, ipConfig := range service.PodIPConfigState { //nolint:gocritic // intentional value copy
+	for _, uuid := range service.ipIDsInStateUntransacted(types.Available, max(n, 0)+service.ipReservations.Len()) {
 		if n <= 0 {
 			break
 		}
-		if ipConfig.GetState() == types.Available {
-			updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, ipConfig.PodInfo)
-			if err != nil {
-				return nil, err
-			}
-
-			availableIPs[uuid] = updatedIPConfig
-			n--
+		if service.ipReservations.Reserved(service.PodIPConfigState[uuid].IPAddress) {
+			continue
+		}
+		updatedIPConfig, err := service.updateIPConfigState(uuid, types.PendingRelease, service.PodIPConfigState[uuid].PodInfo)
+		if err != nil {
+			return nil, err
 		}
+
+		availableIPs[uuid] = updatedIPConfig
+		n--
 	}
 
 	// if we can release the requested quantity, return the IPs
@@ -506,6 +527,10 @@ func (service *HTTPRestService) MarkNIPsPendingRelease(n int) (map[string]cns.IP
 func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState types.IPState, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) {
 	if ipConfig, found := service.PodIPConfigState[ipID]; found {
 		logger.Printf("[updateIPConfigState] Changing IpId [%s] state to [%s], podInfo [%+v]. Current config [%+v]", ipID, updatedState, podInfo, ipConfig)
+		// the state middleware sees the Pod an IP is assigned to, and the Pod it was assigned to when it is released.
+		if podInfo != nil {
+			ipConfig.PodInfo = podInfo
+		}
 		ipConfig.SetState(updatedState)
 		ipConfig.PodInfo = podInfo
 		service.PodIPConfigState[ipID] = ipConfig
@@ -609,7 +634,7 @@ func (service *HTTPRestService) HandleDebugIPAddresses(w http.ResponseWriter, r
 func (service *HTTPRestService) GetAssignedIPConfigs() []cns.IPConfigurationStatus {
 	service.RLock()
 	defer service.RUnlock()
-	return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.StateAssigned)
+	return service.ipConfigsInStateUntransacted(types.Assigned)
 }
 
 // GetAvailableIPConfigs returns a filtered list of IPs which are in
@@ -617,7 +642,7 @@ func (service *HTTPRestService) GetAssignedIPConfigs() []cns.IPConfigurationStat
 func (service *HTTPRestService) GetAvailableIPConfigs() []cns.IPConfigurationStatus {
 	service.RLock()
 	defer service.RUnlock()
-	return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.StateAvailable)
+	return service.ipConfigsInStateUntransacted(types.Available)
 }
 
 // GetPendingProgramIPConfigs returns a filtered list of IPs which are in
@@ -625,7 +650,7 @@ func (service *HTTPRestService) GetAvailableIPConfigs() []cns.IPConfigurationSta
 func (service *HTTPRestService) GetPendingProgramIPConfigs() []cns.IPConfigurationStatus {
 	service.RLock()
 	defer service.RUnlock()
-	return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.StatePendingProgramming)
+	return service.ipConfigsInStateUntransacted(types.PendingProgramming)
 }
 
 // GetPendingReleaseIPConfigs returns a filtered list of IPs which are in
@@ -633,7 +658,15 @@ func (service *HTTPRestService) GetPendingProgramIPConfigs() []cns.IPConfigurati
 func (service *HTTPRestService) GetPendingReleaseIPConfigs() []cns.IPConfigurationStatus {
 	service.RLock()
 	defer service.RUnlock()
-	return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.StatePendingRelease)
+	return service.ipConfigsInStateUntransacted(types.PendingRelease)
+}
+
+// GetCooldownIPConfigs returns a filtered list of IPs which are in
+// Cooldown State.
+func (service *HTTPRestService) GetCooldownIPConfigs() []cns.IPConfigurationStatus {
+	service.RLock()
+	defer service.RUnlock()
+	return service.ipConfigsInStateUntransacted(types.Cooldown)
 }
 
 // assignIPConfig assigns the the ipconfig to the passed Pod, sets the state as Assigned, does not take a lock.
@@ -652,16 +685,18 @@ func (service *HTTPRestService) assignIPConfig(ipconfig cns.IPConfigurationStatu
 	return nil
 }
 
-// unassignIPConfig unassigns the ipconfig from the passed Pod, sets the state as Available, does not take a lock.
+// unassignIPConfig unassigns the ipconfig from the passed Pod, sets the state as Available, or as Cooldown if the
+// IP cooldown is enabled, does not take a lock.
 func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) { //nolint:gocritic // ignore hugeparam
-	ipconfig, err := service.updateIPConfigState(ipconfig.ID, types.Available, nil)
+	state := service.releasedIPStateUntransacted(ipconfig.IPAddress)
+	ipconfig, err := service.updateIPConfigState(ipconfig.ID, state, nil)
 	if err != nil {
 		return cns.IPConfigurationStatus{}, err
 	}
 
 	delete(service.PodIPIDByPodInterfaceKey, podInfo.Key())
-	logger.Printf("[setIPConfigAsAvailable] Deleted outdated pod info %s from PodIPIDByOrchestratorContext since IP %s with ID %s will be released and set as Available",
-		podInfo.Key(), ipconfig.IPAddress, ipconfig.ID)
+	logger.Printf("[setIPConfigAsAvailable] Deleted outdated pod info %s from PodIPIDByOrchestratorContext since IP %s with ID %s will be released and set as %s",
+		podInfo.Key(), ipconfig.IPAddress, ipconfig.ID, state)
 	return ipconfig, nil
 }
 
@@ -709,6 +744,7 @@ func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
 		return fmt.Errorf("[releaseIPConfigs] Failed to release one or more IPs. Not releasing any IPs for pod %+v", podInfo)
 	}
 
+	service.renewIPLeaseUntransacted(podInfo)
 	logger.Printf("[releaseIPConfigs] Successfully released all IPs for pod %+v", podInfo)
 	return nil
 }
@@ -783,6 +819,9 @@ func (service *HTTPRestService) AssignDesiredIPConfigs(podInfo cns.PodInfo, desi
 	ipConfigsToAssign := make([]cns.IPConfigurationStatus, 0)
 
 	for _, desiredIP := range desiredIPAddresses {
+		if err := service.ipReservations.CheckAssignable(desiredIP, ipReservationKey(podInfo)); err != nil {
+			return []cns.PodIpInfo{}, errors.Wrap(err, "[AssignDesiredIPConfigs] Desired IP is not assignable")
+		}
 		desiredIPMap[desiredIP] = struct{}{}
 	}
 
@@ -877,24 +916,13 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 	defer service.Unlock()
 	// Creates a slice of PodIpInfo with the size as number of NCs to hold the result for assigned IP configs
 	podIPInfo := make([]cns.PodIpInfo, numOfNCs)
-	// This map is used to store whether or not we have found an available IP from an NC when looping through the pool
-	ipsToAssign := make(map[string]cns.IPConfigurationStatus)
-
-	// Searches for available IPs in the pool
-	for _, ipState := range service.PodIPConfigState {
-		// check if an IP from this NC is already set side for assignment.
-		if _, ncAlreadyMarkedForAssignment := ipsToAssign[ipState.NCID]; ncAlreadyMarkedForAssignment {
-			continue
-		}
-		// Checks if the current IP is available
-		if ipState.GetState() != types.Available {
-			continue
-		}
-		ipsToAssign[ipState.NCID] = ipState
-		// Once one IP per container is found break out of the loop and stop searching
-		if len(ipsToAssign) == numOfNCs {
-			break
-		}
+	// This map is used to store the available IP found for each NC in the pool
+	ipsToAssign := service.availableIPConfigPerNCUntransacted()
+	if err := service.addReservedIPConfigPerNCUntransacted(podInfo, ipsToAssign); err != nil {
+		return podIPInfo, err
+	}
+	if len(ipsToAssign) != numOfNCs && service.ipCooldown > 0 {
+		service.addCooldownIPConfigPerNCUntransacted(ipsToAssign)
 	}
 
 	// Checks to make sure we found one IP for each NC
@@ -903,7 +931,7 @@ func (service *HTTPRestService) AssignAvailableIPConfigs(podInfo cns.PodInfo) ([
 			if _, found := ipsToAssign[ncID]; found {
 				continue
 			}
-			return podIPInfo, errors.Errorf("not enough IPs available for %s, waiting on Azure CNS to allocate more with NC Status: %s",
+			return podIPInfo, fmt.Errorf("%w for %s, waiting on Azure CNS to allocate more with NC Status: %s", ErrNotEnoughIPs,
 				ncID, string(service.state.ContainerStatus[ncID].CreateNetworkContainerRequest.NCStatus))
 		}
 	}
@@ -956,8 +984,11 @@ func requestIPConfigsHelper(service *HTTPRestService, req cns.IPConfigsRequest)
 		return podIPInfo, err
 	}
 
-	// if the desired IP configs are not specified, assign any free IPConfigs
+	// if the desired IP configs are not specified, assign the IPs leased to the pod or any free IPConfigs
 	if len(req.DesiredIPAddresses) == 0 {
+		if podIPInfo, ok := service.assignLeasedIPConfigs(podInfo); ok {
+			return podIPInfo, nil
+		}
 		return service.AssignAvailableIPConfigs(podInfo)
 	}
 
@@ -987,10 +1018,7 @@ func (service *HTTPRestService) EndpointHandlerAPI(w http.ResponseWriter, r *htt
 	defer service.Unlock()
 	// Check if CNS is managing the CNI statefile
 	if service.Options[common.OptManageEndpointState] == false {
-		response := cns.Response{
-			ReturnCode: types.UnexpectedError,
-			Message:    fmt.Sprintf("[EndpointHandlerAPI] EndpointHandlerAPI failed with error: %s", ErrOptManageEndpointState),
-		}
+		response := endpointStateNotManagedResponse()
 		err := service.Listener.Encode(w, &response)
 		logger.Response(service.Name, response, response.ReturnCode, err)
 		return
@@ -1009,6 +1037,21 @@ func (service *HTTPRestService) EndpointHandlerAPI(w http.ResponseWriter, r *htt
 func (service *HTTPRestService) GetEndpointHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Printf("[GetEndpointState] GetEndpoint for %s", r.URL.Path)
 	endpointID := strings.TrimPrefix(r.URL.Path, cns.EndpointPath)
+	response := service.getEndpointResponse(endpointID)
+	w.Header().Set(cnsReturnCode, response.Response.ReturnCode.String())
+	err := service.Listener.Encode(w, &response)
+	logger.Response(service.Name, response, response.Response.ReturnCode, err)
+}
+
+func endpointStateNotManagedResponse() cns.Response {
+	return cns.Response{
+		ReturnCode: types.UnexpectedError,
+		Message:    fmt.Sprintf("[EndpointHandlerAPI] EndpointHandlerAPI failed with error: %s", ErrOptManageEndpointState),
+	}
+}
+
+// getEndpointResponse gets the state of the endpoint. The service lock must be held.
+func (service *HTTPRestService) getEndpointResponse(endpointID string) GetEndpointResponse {
 	endpointInfo, err := service.GetEndpointHelper(endpointID)
 	// Check if the request is valid
 	if err != nil {
@@ -1026,21 +1069,15 @@ func (service *HTTPRestService) GetEndpointHandler(w http.ResponseWriter, r *htt
 				},
 			}
 		}
-		w.Header().Set(cnsReturnCode, response.Response.ReturnCode.String())
-		err = service.Listener.Encode(w, &response)
-		logger.Response(service.Name, response, response.Response.ReturnCode, err)
-		return
+		return response
 	}
-	response := GetEndpointResponse{
+	return GetEndpointResponse{
 		Response: Response{
 			ReturnCode: types.Success,
 			Message:    "[GetEndpointState] GetEndpoint retruned successfully",
 		},
 		EndpointInfo: *endpointInfo,
 	}
-	w.Header().Set(cnsReturnCode, response.Response.ReturnCode.String())
-	err = service.Listener.Encode(w, &response)
-	logger.Response(service.Name, response, response.Response.ReturnCode, err)
 }
 
 // GetEndpointHelper returns the state of the given endpointId
@@ -1053,7 +1090,7 @@ func (service *HTTPRestService) GetEndpointHelper(endpointID string) (*EndpointI
 		return nil, ErrStoreEmpty
 	}
 
-	err := service.EndpointStateStore.Read(EndpointStoreKey, &service.EndpointState)
+	err := store.ReadVersioned(service.EndpointStateStore, EndpointStoreKey, &service.EndpointState)
 	if err != nil {
 
 		if errors.Is(err, store.ErrKeyNotFound) {
@@ -1071,10 +1108,12 @@ func (service *HTTPRestService) GetEndpointHelper(endpointID string) (*EndpointI
 	// This part is a temprory fix if we have endpoint states belong to CNI version 1.4.X on Windows since the states don't have the containerID
 	// In case there was no endpoint founded with ContainerID as the key,
 	// then [First 8 character of containerid]-eth0 will be tried
-	legacyEndpointID := endpointID[:ContainerIDLength] + "-" + InterfaceName
-	if endpointInfo, ok := service.EndpointState[legacyEndpointID]; ok {
-		logger.Warnf("[GetEndpointState] Found existing endpoint state for container %s", legacyEndpointID)
-		return endpointInfo, nil
+	if len(endpointID) >= ContainerIDLength {
+		legacyEndpointID := endpointID[:ContainerIDLength] + "-" + InterfaceName
+		if endpointInfo, ok := service.EndpointState[legacyEndpointID]; ok {
+			logger.Warnf("[GetEndpointState] Found existing endpoint state for container %s", legacyEndpointID)
+			return endpointInfo, nil
+		}
 	}
 	return nil, ErrEndpointStateNotFound
 }
@@ -1098,36 +1137,32 @@ func (service *HTTPRestService) UpdateEndpointHandler(w http.ResponseWriter, r *
 		logger.Response(service.Name, response, response.ReturnCode, err)
 		return
 	}
+	response := service.updateEndpointResponse(endpointID, req)
+	w.Header().Set(cnsReturnCode, response.ReturnCode.String())
+	err = service.Listener.Encode(w, &response)
+	logger.Response(service.Name, response, response.ReturnCode, err)
+}
+
+// updateEndpointResponse updates the state of the endpoint. The service lock must be held.
+func (service *HTTPRestService) updateEndpointResponse(endpointID string, req cns.EndpointRequest) cns.Response {
 	if req.HostVethName == "" && req.HnsEndpointID == "" {
 		logger.Warnf("[updateEndpoint] No HnsEndpointID or HostVethName has been provided")
-		response := cns.Response{
+		return cns.Response{
 			ReturnCode: types.InvalidRequest,
 			Message:    "[updateEndpoint] No HnsEndpointID or HostVethName has been provided",
 		}
-		w.Header().Set(cnsReturnCode, response.ReturnCode.String())
-		err = service.Listener.Encode(w, &response)
-		logger.Response(service.Name, response, response.ReturnCode, err)
-		return
 	}
 	// Update the endpoint state
-	err = service.UpdateEndpointHelper(endpointID, req)
-	if err != nil {
-		response := cns.Response{
+	if err := service.UpdateEndpointHelper(endpointID, req); err != nil {
+		return cns.Response{
 			ReturnCode: types.UnexpectedError,
 			Message:    fmt.Sprintf("[updateEndpoint] updateEndpoint failed with error: %s", err.Error()),
 		}
-		w.Header().Set(cnsReturnCode, response.ReturnCode.String())
-		err = service.Listener.Encode(w, &response)
-		logger.Response(service.Name, response, response.ReturnCode, err)
-		return
 	}
-	response := cns.Response{
+	return cns.Response{
 		ReturnCode: types.Success,
 		Message:    "[updateEndpoint] updateEndpoint retruned successfully",
 	}
-	w.Header().Set(cnsReturnCode, response.ReturnCode.String())
-	err = service.Listener.Encode(w, &response)
-	logger.Response(service.Name, response, response.ReturnCode, err)
 }
 
 // UpdateEndpointHelper updates the state of the given endpointId with HNSId or VethName
@@ -1147,7 +1182,7 @@ func (service *HTTPRestService) UpdateEndpointHelper(endpointID string, req cns.
 			logger.Printf("[updateEndpoint] update the endpoint %s with vethName  %s", endpointID, req.HostVethName)
 		}
 
-		err := service.EndpointStateStore.Write(EndpointStoreKey, service.EndpointState)
+		err := store.WriteVersioned(service.EndpointStateStore, EndpointStoreKey, service.EndpointState)
 		if err != nil {
 			return fmt.Errorf("[updateEndpoint] failed to write endpoint state to store for pod %s :  %w", endpointInfo.PodName, err)
 		}
@@ -153,6 +153,30 @@ func UpdatePodIPConfigState(t *testing.T, svc *HTTPRestService, ipconfigs map[st
 			svc.PodIPConfigState[ipID] = ipconfig
 		}
 	}
+	svc.invalidateIPIndexUntransacted()
+	return nil
+}
+
+// Test function to populate the IPConfigState through updateIPConfigState, so that the IP state index sees the assigned IPs
+func UpdateIndexedPodIPConfigState(t *testing.T, svc *HTTPRestService, ipconfigs map[string]cns.IPConfigurationStatus, ncID string) error {
+	secondaryIPConfigs := make(map[string]cns.SecondaryIPConfig)
+	for _, ipconfig := range ipconfigs { //nolint:gocritic // ignore copy
+		secondaryIPConfigs[ipconfig.ID] = cns.SecondaryIPConfig{
+			IPAddress: ipconfig.IPAddress,
+			NCVersion: -1,
+		}
+	}
+
+	createAndValidateNCRequest(t, secondaryIPConfigs, ncID, "-1")
+
+	for ipID, ipconfig := range ipconfigs { //nolint:gocritic // ignore copy
+		if ipconfig.GetState() == types.Assigned {
+			svc.PodIPIDByPodInterfaceKey[ipconfig.PodInfo.Key()] = append(svc.PodIPIDByPodInterfaceKey[ipconfig.PodInfo.Key()], ipID)
+			if _, err := svc.updateIPConfigState(ipID, types.Assigned, ipconfig.PodInfo); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -1294,7 +1318,7 @@ func IPAMMarkExistingIPConfigAsPending(t *testing.T, ncStates []ncState) {
 		state2 := NewPodState(ncStates[i].ips[1], ipIDs[i][1], ncStates[i].ncID, types.Available, 0)
 		ipconfigs[state1.ID] = state1
 		ipconfigs[state2.ID] = state2
-		err := UpdatePodIPConfigState(t, svc, ipconfigs, ncStates[i].ncID)
+		err := UpdateIndexedPodIPConfigState(t, svc, ipconfigs, ncStates[i].ncID)
 		if err != nil {
 			t.Fatalf("Expected to not fail adding IPs to state: %+v", err)
 		}
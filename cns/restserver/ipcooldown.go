@@ -0,0 +1,78 @@
+package restserver
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// EnableIPCooldown makes the IPs released by Pods wait in the Cooldown state for the given duration before they are
+// Available again, so that traffic sent to a released Pod by peers with stale conntrack entries, ipsets or DNS caches
+// does not reach the next Pod given its IP.
+func (service *HTTPRestService) EnableIPCooldown(cooldown time.Duration) {
+	service.Lock()
+	defer service.Unlock()
+	service.ipCooldown = cooldown
+}
+
+// releasedIPStateUntransacted returns the state the IP is put in when released by its Pod. Reserved IPs skip the
+// cooldown, as they are only assigned to the Pod which released them.
+func (service *HTTPRestService) releasedIPStateUntransacted(ip string) types.IPState {
+	if service.ipCooldown > 0 && !service.ipReservations.Reserved(ip) {
+		return types.Cooldown
+	}
+	return types.Available
+}
+
+// ExpireIPCooldowns makes the IPs which have been in Cooldown for the cooldown duration Available.
+func (service *HTTPRestService) ExpireIPCooldowns() {
+	service.Lock()
+	defer service.Unlock()
+	service.expireIPCooldownsUntransacted(time.Now())
+}
+
+func (service *HTTPRestService) expireIPCooldownsUntransacted(now time.Time) {
+	for _, ipConfig := range service.ipConfigsInStateUntransacted(types.Cooldown) { //nolint:gocritic // ignore copy
+		if now.Sub(ipConfig.LastStateTransition) < service.ipCooldown {
+			continue
+		}
+		if _, err := service.updateIPConfigState(ipConfig.ID, types.Available, nil); err != nil {
+			logger.Errorf("[expireIPCooldowns] Failed to mark IPConfig [%+v] as Available. err: %v", ipConfig, err)
+		}
+	}
+}
+
+// addCooldownIPConfigPerNCUntransacted adds to ipsToAssign the IP released the longest ago of each NC missing from it,
+// so that when the pool is tight the requests are not failed until a cooldown passes.
+func (service *HTTPRestService) addCooldownIPConfigPerNCUntransacted(ipsToAssign map[string]cns.IPConfigurationStatus) {
+	for ncID := range service.state.ContainerStatus {
+		if _, found := ipsToAssign[ncID]; found {
+			continue
+		}
+		if ipConfig, found := service.leastRecentlyReleasedIPConfigUntransacted(ncID); found {
+			logger.Printf("[AssignAvailableIPConfigs] No Available IPs for %s, assigning IP %s in Cooldown since %s",
+				ncID, ipConfig.IPAddress, ipConfig.LastStateTransition.Format(time.RFC3339))
+			ipsToAssign[ncID] = ipConfig
+		}
+	}
+}
+
+// leastRecentlyReleasedIPConfigUntransacted returns the IP of the NC which has been in Cooldown for the longest.
+func (service *HTTPRestService) leastRecentlyReleasedIPConfigUntransacted(ncID string) (cns.IPConfigurationStatus, bool) {
+	var (
+		lrr   cns.IPConfigurationStatus
+		found bool
+	)
+	for _, ipConfig := range service.ncIPConfigsInStateUntransacted(ncID, types.Cooldown) { //nolint:gocritic // ignore copy
+		if !service.ipReservations.Unreserved(ipConfig.IPAddress) {
+			continue
+		}
+		if !found || ipConfig.LastStateTransition.Before(lrr.LastStateTransition) {
+			lrr = ipConfig
+			found = true
+		}
+	}
+	return lrr, found
+}
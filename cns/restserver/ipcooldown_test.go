@@ -0,0 +1,67 @@
+package restserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleasedIPsCoolDownBeforeReuse(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	svc.EnableIPCooldown(time.Minute)
+
+	podIPInfo, err := svc.AssignAvailableIPConfigs(testPodInfo(0))
+	require.NoError(t, err)
+	released := podIPInfo[0].PodIPConfig.IPAddress
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(0)))
+
+	cooldown := svc.GetCooldownIPConfigs()
+	require.Len(t, cooldown, 1)
+	require.Equal(t, released, cooldown[0].IPAddress)
+
+	// the next pod is given the other IP rather than the one just released
+	podIPInfo, err = svc.AssignAvailableIPConfigs(testPodInfo(1))
+	require.NoError(t, err)
+	require.NotEqual(t, released, podIPInfo[0].PodIPConfig.IPAddress)
+	requireIndexConsistent(t, svc)
+
+	// the released IP is Available once its cooldown has passed
+	svc.expireIPCooldownsUntransacted(time.Now().Add(time.Second))
+	require.Len(t, svc.GetCooldownIPConfigs(), 1)
+	svc.expireIPCooldownsUntransacted(time.Now().Add(time.Minute))
+	require.Empty(t, svc.GetCooldownIPConfigs())
+	require.Len(t, svc.GetAvailableIPConfigs(), 1)
+	requireIndexConsistent(t, svc)
+}
+
+// Tests that the IP released the longest ago is assigned when there are no Available IPs.
+func TestCooldownIPsAssignedLeastRecentlyReleasedFirst(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 3)
+	svc.EnableIPCooldown(time.Hour)
+
+	releasedAt := map[string]time.Time{}
+	for i := 0; i < 3; i++ {
+		podIPInfo, err := svc.AssignAvailableIPConfigs(testPodInfo(i))
+		require.NoError(t, err)
+		require.NoError(t, svc.releaseIPConfigs(testPodInfo(i)))
+		releasedAt[podIPInfo[0].PodIPConfig.IPAddress] = time.Now().Add(time.Duration(i-3) * time.Minute)
+	}
+	for ipID, ipConfig := range svc.PodIPConfigState { //nolint:gocritic // ignore copy
+		ipConfig.LastStateTransition = releasedAt[ipConfig.IPAddress]
+		svc.PodIPConfigState[ipID] = ipConfig
+	}
+	require.Empty(t, svc.GetAvailableIPConfigs())
+
+	var previous time.Time
+	for i := 3; i < 6; i++ {
+		podIPInfo, err := svc.AssignAvailableIPConfigs(testPodInfo(i))
+		require.NoError(t, err)
+		at := releasedAt[podIPInfo[0].PodIPConfig.IPAddress]
+		require.True(t, at.After(previous))
+		previous = at
+	}
+
+	_, err := svc.AssignAvailableIPConfigs(testPodInfo(6))
+	require.ErrorIs(t, err, ErrNotEnoughIPs)
+}
@@ -0,0 +1,214 @@
+package restserver
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EnableIPLeases gives the Pods annotated with configuration.AnnotationPodStickyIP the IPs they held before when they
+// are recreated on the node, such as a StatefulSet Pod rescheduled on the same node, if the IPs are still free.
+// A lease is kept while the Pod holds its IPs and for ttl after it releases them, and is persisted in the endpoint
+// state store.
+// The annotation of the Pods is read with cli.
+func (service *HTTPRestService) EnableIPLeases(cli client.Reader, ttl time.Duration) error {
+	service.Lock()
+	defer service.Unlock()
+
+	service.ipLeaseClient = cli
+	service.ipLeaseTTL = ttl
+	service.ipLeases = make(map[string]*cns.IPLease)
+
+	if service.EndpointStateStore == nil {
+		return nil
+	}
+	if err := store.ReadVersioned(service.EndpointStateStore, IPLeaseStoreKey, &service.ipLeases); err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrStoreEmpty) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to restore IP leases")
+	}
+	if service.ipLeases == nil {
+		service.ipLeases = make(map[string]*cns.IPLease)
+	}
+	logger.Printf("[IPLeases] Restored %d IP leases", len(service.ipLeases))
+	return nil
+}
+
+func ipLeaseKey(podInfo cns.PodInfo) string {
+	return podInfo.Namespace() + "/" + podInfo.Name()
+}
+
+// recordIPLease records the IPs assigned to the Pod if it has opted in to sticky IPs.
+func (service *HTTPRestService) recordIPLease(ctx context.Context, podInfo cns.PodInfo) {
+	service.RLock()
+	cli := service.ipLeaseClient
+	service.RUnlock()
+	if cli == nil {
+		return
+	}
+
+	pod := &v1.Pod{}
+	if err := cli.Get(ctx, k8stypes.NamespacedName{Namespace: podInfo.Namespace(), Name: podInfo.Name()}, pod); err != nil {
+		logger.Errorf("[IPLeases] Failed to get pod %s to check for the sticky IP annotation: %v", ipLeaseKey(podInfo), err)
+		return
+	}
+	if pod.Annotations[configuration.AnnotationPodStickyIP] != "true" {
+		return
+	}
+
+	service.Lock()
+	defer service.Unlock()
+	lease := &cns.IPLease{
+		PodName:      podInfo.Name(),
+		PodNamespace: podInfo.Namespace(),
+	}
+	for _, ipID := range service.PodIPIDByPodInterfaceKey[podInfo.Key()] {
+		ipConfig, ok := service.PodIPConfigState[ipID]
+		if !ok {
+			continue
+		}
+		lease.IPConfigIDs = append(lease.IPConfigIDs, ipID)
+		lease.IPAddresses = append(lease.IPAddresses, ipConfig.IPAddress)
+	}
+	service.ipLeases[ipLeaseKey(podInfo)] = lease
+	logger.Printf("[IPLeases] Leased IPs %v to pod %s", lease.IPAddresses, ipLeaseKey(podInfo))
+	service.saveIPLeasesUntransacted()
+}
+
+// renewIPLeaseUntransacted starts the TTL of the Pod's lease when it releases its IPs.
+func (service *HTTPRestService) renewIPLeaseUntransacted(podInfo cns.PodInfo) {
+	lease, ok := service.ipLeases[ipLeaseKey(podInfo)]
+	if !ok {
+		return
+	}
+	lease.ExpiresAt = time.Now().Add(service.ipLeaseTTL)
+	service.saveIPLeasesUntransacted()
+}
+
+// ipLeaseExpiredUntransacted returns whether the lease has outlived its TTL. A lease does not expire while its Pod
+// still holds any of the leased IPs.
+func (service *HTTPRestService) ipLeaseExpiredUntransacted(lease *cns.IPLease, now time.Time) bool {
+	if !now.After(lease.ExpiresAt) {
+		return false
+	}
+	for _, ipID := range lease.IPConfigIDs {
+		ipConfig, ok := service.PodIPConfigState[ipID]
+		if ok && ipConfig.GetState() == types.Assigned && ipConfig.PodInfo != nil &&
+			ipConfig.PodInfo.Name() == lease.PodName && ipConfig.PodInfo.Namespace() == lease.PodNamespace {
+			return false
+		}
+	}
+	return true
+}
+
+// assignLeasedIPConfigs assigns the Pod the IPs of its lease, if it has one and the IPs are Available or in Cooldown.
+// It returns false if the IPs could not be assigned, in which case the Pod should be assigned any Available IPs.
+func (service *HTTPRestService) assignLeasedIPConfigs(podInfo cns.PodInfo) ([]cns.PodIpInfo, bool) {
+	service.Lock()
+	defer service.Unlock()
+
+	key := ipLeaseKey(podInfo)
+	lease, ok := service.ipLeases[key]
+	if !ok {
+		return nil, false
+	}
+	if service.ipLeaseExpiredUntransacted(lease, time.Now()) {
+		delete(service.ipLeases, key)
+		service.saveIPLeasesUntransacted()
+		return nil, false
+	}
+	if len(lease.IPConfigIDs) != len(service.state.ContainerStatus) || len(service.ipReservations.PodIPs(ipReservationKey(podInfo))) > 0 {
+		return nil, false
+	}
+
+	ipConfigs := make([]cns.IPConfigurationStatus, len(lease.IPConfigIDs))
+	for i, ipID := range lease.IPConfigIDs {
+		ipConfig, ok := service.PodIPConfigState[ipID]
+		if !ok || ipConfig.IPAddress != lease.IPAddresses[i] {
+			logger.Printf("[IPLeases] Leased IP %s of pod %s is no longer allocated", lease.IPAddresses[i], key)
+			return nil, false
+		}
+		if !service.ipReservations.Assignable(ipConfig.IPAddress, ipReservationKey(podInfo)) {
+			logger.Printf("[IPLeases] Leased IP %s of pod %s is excluded or reserved", lease.IPAddresses[i], key)
+			return nil, false
+		}
+		if state := ipConfig.GetState(); state != types.Available && state != types.Cooldown {
+			logger.Printf("[IPLeases] Leased IP %s of pod %s is %s", lease.IPAddresses[i], key, state)
+			return nil, false
+		}
+		ipConfigs[i] = ipConfig
+	}
+
+	podIPInfo := make([]cns.PodIpInfo, len(ipConfigs))
+	for i := range ipConfigs {
+		if err := service.assignIPConfig(ipConfigs[i], podInfo); err == nil {
+			err = service.populateIPConfigInfoUntransacted(ipConfigs[i], &podIPInfo[i])
+			if err == nil {
+				continue
+			}
+		}
+		// put the IPs back as they were and let the Pod be assigned other IPs
+		for _, ipConfig := range ipConfigs { //nolint:gocritic // ignore copy
+			if _, err := service.updateIPConfigState(ipConfig.ID, ipConfig.GetState(), nil); err != nil {
+				logger.Errorf("[IPLeases] Failed to revert IPConfig [%+v]. err: %v", ipConfig, err)
+			}
+		}
+		delete(service.PodIPIDByPodInterfaceKey, podInfo.Key())
+		return nil, false
+	}
+
+	logger.Printf("[IPLeases] Assigned leased IPs %v to pod %s", lease.IPAddresses, key)
+	return podIPInfo, true
+}
+
+// saveIPLeasesUntransacted drops the expired leases and persists the others.
+func (service *HTTPRestService) saveIPLeasesUntransacted() {
+	now := time.Now()
+	for key, lease := range service.ipLeases {
+		if service.ipLeaseExpiredUntransacted(lease, now) {
+			delete(service.ipLeases, key)
+		}
+	}
+
+	if service.EndpointStateStore == nil {
+		return
+	}
+	if err := store.WriteVersioned(service.EndpointStateStore, IPLeaseStoreKey, service.ipLeases); err != nil {
+		logger.Errorf("[IPLeases] Failed to persist IP leases: %v", err)
+	}
+}
+
+// HandleDebugIPLeases lists the IP leases which have not expired.
+func (service *HTTPRestService) HandleDebugIPLeases(w http.ResponseWriter, r *http.Request) { //nolint
+	service.RLock()
+	defer service.RUnlock()
+
+	now := time.Now()
+	resp := cns.GetIPLeasesResponse{IPLeases: []cns.IPLease{}}
+	for _, lease := range service.ipLeases {
+		if service.ipLeaseExpiredUntransacted(lease, now) {
+			continue
+		}
+		resp.IPLeases = append(resp.IPLeases, *lease)
+	}
+	sort.Slice(resp.IPLeases, func(i, j int) bool {
+		if resp.IPLeases[i].PodNamespace != resp.IPLeases[j].PodNamespace {
+			return resp.IPLeases[i].PodNamespace < resp.IPLeases[j].PodNamespace
+		}
+		return resp.IPLeases[i].PodName < resp.IPLeases[j].PodName
+	})
+	err := service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
@@ -0,0 +1,131 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newStickyPod(podInfo cns.PodInfo, sticky bool) *v1.Pod {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podInfo.Name(), Namespace: podInfo.Namespace()}}
+	if sticky {
+		pod.Annotations = map[string]string{configuration.AnnotationPodStickyIP: "true"}
+	}
+	return pod
+}
+
+// requestAndReleaseIP requests an IP for the pod as the CNI would, then releases it.
+func requestAndReleaseIP(t *testing.T, svc *HTTPRestService, podInfo cns.PodInfo) string {
+	resp, err := svc.requestIPConfigHandlerHelper(context.Background(), newIPConfigsRequest(t, podInfo))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(podInfo))
+	return resp.PodIPInfo[0].PodIPConfig.IPAddress
+}
+
+func TestIPLeaseReassignsPreviousIP(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 10)
+	svc.EndpointStateStore = store.NewMockStore("")
+	web0, other := testPodInfo(0), testPodInfo(1)
+	cli := fake.NewClientBuilder().WithObjects(newStickyPod(web0, true), newStickyPod(other, false)).Build()
+	require.NoError(t, svc.EnableIPLeases(cli, time.Minute))
+
+	leased := requestAndReleaseIP(t, svc, web0)
+	require.Contains(t, svc.ipLeases, ipLeaseKey(web0))
+	requestAndReleaseIP(t, svc, other)
+	require.NotContains(t, svc.ipLeases, ipLeaseKey(other))
+
+	podIPInfo, err := requestIPConfigsHelper(svc, newIPConfigsRequest(t, web0))
+	require.NoError(t, err)
+	require.Equal(t, leased, podIPInfo[0].PodIPConfig.IPAddress)
+
+	// the leases survive a restart of CNS
+	restarted := newIndexTestService(t, testNCID, 10)
+	restarted.EndpointStateStore = svc.EndpointStateStore
+	require.NoError(t, restarted.EnableIPLeases(cli, time.Minute))
+	require.Equal(t, []string{leased}, restarted.ipLeases[ipLeaseKey(web0)].IPAddresses)
+}
+
+func TestIPLeaseNotUsedWhenIPTaken(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	web0 := testPodInfo(0)
+	require.NoError(t, svc.EnableIPLeases(fake.NewClientBuilder().WithObjects(newStickyPod(web0, true)).Build(), time.Minute))
+
+	leased := requestAndReleaseIP(t, svc, web0)
+	podIPInfo, err := svc.AssignDesiredIPConfigs(testPodInfo(1), []string{leased})
+	require.NoError(t, err)
+	require.Equal(t, leased, podIPInfo[0].PodIPConfig.IPAddress)
+
+	podIPInfo, err = requestIPConfigsHelper(svc, newIPConfigsRequest(t, web0))
+	require.NoError(t, err)
+	require.NotEqual(t, leased, podIPInfo[0].PodIPConfig.IPAddress)
+	require.Len(t, svc.GetAssignedIPConfigs(), 2)
+}
+
+func TestIPLeaseExpires(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	web0 := testPodInfo(0)
+	require.NoError(t, svc.EnableIPLeases(fake.NewClientBuilder().WithObjects(newStickyPod(web0, true)).Build(), time.Minute))
+
+	requestAndReleaseIP(t, svc, web0)
+	svc.ipLeases[ipLeaseKey(web0)].ExpiresAt = time.Now().Add(-time.Second)
+
+	_, ok := svc.assignLeasedIPConfigs(web0)
+	require.False(t, ok)
+	require.Empty(t, svc.ipLeases)
+}
+
+func TestIPLeaseHeldPastTTL(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 10)
+	web0, web1 := testPodInfo(0), testPodInfo(1)
+	cli := fake.NewClientBuilder().WithObjects(newStickyPod(web0, true), newStickyPod(web1, true)).Build()
+	require.NoError(t, svc.EnableIPLeases(cli, time.Millisecond))
+
+	resp, err := svc.requestIPConfigHandlerHelper(context.Background(), newIPConfigsRequest(t, web0))
+	require.NoError(t, err)
+	leased := resp.PodIPInfo[0].PodIPConfig.IPAddress
+	time.Sleep(10 * time.Millisecond)
+
+	// saving the lease of another pod past the TTL keeps the lease of the pod still holding its IPs
+	requestAndReleaseIP(t, svc, web1)
+	require.Contains(t, svc.ipLeases, ipLeaseKey(web0))
+	require.True(t, svc.ipLeases[ipLeaseKey(web0)].ExpiresAt.IsZero())
+
+	// the TTL starts when the pod releases its IPs
+	svc.ipLeaseTTL = time.Minute
+	require.NoError(t, svc.releaseIPConfigs(web0))
+	require.WithinDuration(t, time.Now().Add(time.Minute), svc.ipLeases[ipLeaseKey(web0)].ExpiresAt, 10*time.Second)
+
+	podIPInfo, err := requestIPConfigsHelper(svc, newIPConfigsRequest(t, web0))
+	require.NoError(t, err)
+	require.Equal(t, leased, podIPInfo[0].PodIPConfig.IPAddress)
+}
+
+func TestHandleDebugIPLeases(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	web0 := testPodInfo(0)
+	require.NoError(t, svc.EnableIPLeases(fake.NewClientBuilder().WithObjects(newStickyPod(web0, true)).Build(), time.Minute))
+	leased := requestAndReleaseIP(t, svc, web0)
+	svc.ipLeases["default/expired"] = &cns.IPLease{PodName: "expired", PodNamespace: "default", ExpiresAt: time.Now().Add(-time.Second)}
+
+	w := httptest.NewRecorder()
+	svc.HandleDebugIPLeases(w, httptest.NewRequest(http.MethodGet, cns.PathDebugIPLeases, http.NoBody))
+
+	var resp cns.GetIPLeasesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, types.Success, resp.Response.ReturnCode)
+	require.Len(t, resp.IPLeases, 1)
+	require.Equal(t, web0.Name(), resp.IPLeases[0].PodName)
+	require.Equal(t, []string{leased}, resp.IPLeases[0].IPAddresses)
+}
@@ -0,0 +1,176 @@
+package restserver
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+// ipRequestQueue parks the requests for IPs which fail because the pool is exhausted, and retries them in the order
+// they arrived as IPs become Available, instead of failing them back to the CNI straight away.
+//
+// Only the waiter at the head of the queue retries, so that a request can't be starved by the ones behind it. When
+// the head leaves the queue, whether it got its IPs or not, the next waiter is woken to retry in turn.
+type ipRequestQueue struct {
+	maxDepth int
+	maxWait  time.Duration
+	mu       sync.Mutex
+	waiters  *list.List // of *ipRequestWaiter
+}
+
+type ipRequestWaiter struct {
+	// ready is signalled when the waiter should retry, it is buffered so that the signal is never lost.
+	ready chan struct{}
+}
+
+func newIPRequestQueue(maxDepth int, maxWait time.Duration) *ipRequestQueue {
+	return &ipRequestQueue{
+		maxDepth: maxDepth,
+		maxWait:  maxWait,
+		waiters:  list.New(),
+	}
+}
+
+// EnableIPRequestQueue makes the requests for IPs wait up to maxWait for IPs to become Available when the pool is
+// exhausted, with at most maxDepth requests waiting at once. Further requests fail as when the queue is disabled.
+func (service *HTTPRestService) EnableIPRequestQueue(maxDepth int, maxWait time.Duration) {
+	service.Lock()
+	defer service.Unlock()
+	service.ipRequestQueue = newIPRequestQueue(maxDepth, maxWait)
+}
+
+// ipRequestQueueMiddleware wakes the head of the IP request queue when an IP becomes Available, or goes into
+// Cooldown as those are assigned too when no IPs are Available. It is attached to each IPConfigurationStatus with
+// WithStateMiddleware, and runs with the service lock held.
+func (service *HTTPRestService) ipRequestQueueMiddleware(_ *cns.IPConfigurationStatus, state types.IPState) {
+	if (state == types.Available || state == types.Cooldown) && service.ipRequestQueue != nil {
+		service.ipRequestQueue.notify()
+	}
+}
+
+// do calls assign, and if it fails because there are not enough IPs available, waits in the queue to call it again
+// until it succeeds, fails with another error, the context is done or the max wait has passed.
+// existing returns the IPs the pod already holds, which are returned straight away whether others are waiting or not.
+func (q *ipRequestQueue) do(ctx context.Context, existing func() ([]cns.PodIpInfo, bool, error),
+	assign func() ([]cns.PodIpInfo, error),
+) ([]cns.PodIpInfo, error) {
+	var (
+		podIPInfo []cns.PodIpInfo
+		err       error
+	)
+
+	if q.len() == 0 {
+		podIPInfo, err = assign()
+		if !errors.Is(err, ErrNotEnoughIPs) {
+			return podIPInfo, err
+		}
+	} else {
+		// new allocations arriving while others are waiting go to the back of the queue without trying first
+		if podIPInfo, ok, existingErr := existing(); existingErr != nil || ok {
+			return podIPInfo, existingErr
+		}
+	}
+
+	e, ok := q.enqueue()
+	if !ok {
+		if err == nil {
+			return assign()
+		}
+		return podIPInfo, err
+	}
+
+	start := time.Now()
+	assigned := false
+	defer func() {
+		q.leave(e)
+		ipRequestQueueWaitTime.WithLabelValues(strconv.FormatBool(assigned)).Observe(time.Since(start).Seconds())
+	}()
+
+	ready := e.Value.(*ipRequestWaiter).ready
+	timer := time.NewTimer(q.maxWait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ready:
+		case <-timer.C:
+			if err == nil {
+				err = ErrNotEnoughIPs
+			}
+			return podIPInfo, errors.Wrapf(err, "timed out after %s in the IP request queue", q.maxWait)
+		case <-ctx.Done():
+			if err == nil {
+				err = ErrNotEnoughIPs
+			}
+			return podIPInfo, errors.Wrapf(err, "left the IP request queue: %v", ctx.Err())
+		}
+
+		podIPInfo, err = assign()
+		if !errors.Is(err, ErrNotEnoughIPs) {
+			assigned = err == nil
+			return podIPInfo, err
+		}
+		logger.Printf("[ipRequestQueue] Still not enough IPs available after waiting %s", time.Since(start))
+	}
+}
+
+func (q *ipRequestQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiters.Len()
+}
+
+// enqueue adds a waiter to the back of the queue, or returns false if the queue is full.
+// A waiter added to an empty queue is signalled at once, in case an IP became Available before it was added.
+func (q *ipRequestQueue) enqueue() (*list.Element, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiters.Len() >= q.maxDepth {
+		return nil, false
+	}
+
+	e := q.waiters.PushBack(&ipRequestWaiter{ready: make(chan struct{}, 1)})
+	if q.waiters.Len() == 1 {
+		q.signalHeadUntransacted()
+	}
+	ipRequestQueueDepth.Set(float64(q.waiters.Len()))
+	return e, true
+}
+
+// leave removes the waiter from the queue, and signals the new head if it was the head.
+func (q *ipRequestQueue) leave(e *list.Element) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	wasHead := q.waiters.Front() == e
+	q.waiters.Remove(e)
+	if wasHead {
+		q.signalHeadUntransacted()
+	}
+	ipRequestQueueDepth.Set(float64(q.waiters.Len()))
+}
+
+// notify signals the head of the queue to retry.
+func (q *ipRequestQueue) notify() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.signalHeadUntransacted()
+}
+
+func (q *ipRequestQueue) signalHeadUntransacted() {
+	head := q.waiters.Front()
+	if head == nil {
+		return
+	}
+	select {
+	case head.Value.(*ipRequestWaiter).ready <- struct{}{}:
+	default:
+	}
+}
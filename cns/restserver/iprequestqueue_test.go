@@ -0,0 +1,187 @@
+package restserver
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+type queuedRequestResult struct {
+	podIPInfo []cns.PodIpInfo
+	err       error
+}
+
+func newIPConfigsRequest(t *testing.T, podInfo cns.PodInfo) cns.IPConfigsRequest {
+	b, err := podInfo.OrchestratorContext()
+	require.NoError(t, err)
+	return cns.IPConfigsRequest{
+		PodInterfaceID:      podInfo.InterfaceID(),
+		InfraContainerID:    podInfo.InfraContainerID(),
+		OrchestratorContext: b,
+	}
+}
+
+// startQueuedRequest requests IPs for the pod in the background, and waits for it to join the queue.
+func startQueuedRequest(ctx context.Context, t *testing.T, svc *HTTPRestService, podInfo cns.PodInfo) <-chan queuedRequestResult {
+	depth := svc.ipRequestQueue.len()
+	req := newIPConfigsRequest(t, podInfo)
+	result := make(chan queuedRequestResult, 1)
+	go func() {
+		podIPInfo, err := svc.queuedRequestIPConfigs(ctx, req)
+		result <- queuedRequestResult{podIPInfo: podIPInfo, err: err}
+	}()
+	require.Eventually(t, func() bool { return svc.ipRequestQueue.len() == depth+1 }, time.Second, time.Millisecond)
+	return result
+}
+
+func receiveResult(t *testing.T, result <-chan queuedRequestResult) queuedRequestResult {
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued request")
+		return queuedRequestResult{}
+	}
+}
+
+// newQueueTestNCRequest returns the request for the NC of newIndexTestService scaled to the given number of IPs.
+func newQueueTestNCRequest(ncID string, numIPs int) *cns.CreateNetworkContainerRequest {
+	secondaryIPConfigs := make(map[string]cns.SecondaryIPConfig, numIPs)
+	ip := netip.MustParseAddr("10.1.0.0")
+	for i := 0; i < numIPs; i++ {
+		ip = ip.Next()
+		secondaryIPConfigs[fmt.Sprintf("%s-%d", ncID, i)] = newSecondaryIPConfig(ip.String(), -1)
+	}
+
+	return &cns.CreateNetworkContainerRequest{
+		NetworkContainerType: dockerContainerType,
+		NetworkContainerid:   ncID,
+		IPConfiguration: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: primaryIP, PrefixLength: 16},
+			DNSServers:       dnsservers,
+			GatewayIPAddress: gatewayIP,
+		},
+		SecondaryIPConfigs: secondaryIPConfigs,
+		Version:            "-1",
+	}
+}
+
+func TestIPRequestQueueAssignsReleasedIPsInOrder(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	svc.EnableIPRequestQueue(10, time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(i)))
+		require.NoError(t, err)
+	}
+
+	first := startQueuedRequest(context.Background(), t, svc, testPodInfo(2))
+	second := startQueuedRequest(context.Background(), t, svc, testPodInfo(3))
+
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(0)))
+	r := receiveResult(t, first)
+	require.NoError(t, r.err)
+	require.Len(t, r.podIPInfo, 1)
+	require.Equal(t, 1, svc.ipRequestQueue.len())
+
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(1)))
+	r = receiveResult(t, second)
+	require.NoError(t, r.err)
+	require.Len(t, r.podIPInfo, 1)
+	require.Equal(t, 0, svc.ipRequestQueue.len())
+	require.Len(t, svc.GetAssignedIPConfigs(), 2)
+}
+
+// Tests that the waiting requests are assigned the IPs released into Cooldown.
+func TestIPRequestQueueAssignsCooldownIPs(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 1)
+	svc.EnableIPCooldown(time.Hour)
+	svc.EnableIPRequestQueue(10, time.Minute)
+	released, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(0)))
+	require.NoError(t, err)
+
+	result := startQueuedRequest(context.Background(), t, svc, testPodInfo(1))
+
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(0)))
+	r := receiveResult(t, result)
+	require.NoError(t, r.err)
+	require.Equal(t, released[0].PodIPConfig, r.podIPInfo[0].PodIPConfig)
+	require.Equal(t, 0, svc.ipRequestQueue.len())
+	require.Empty(t, svc.GetCooldownIPConfigs())
+}
+
+// Tests that the waiting requests are assigned the IPs added to the NC by an NNC update.
+func TestIPRequestQueueAssignsNewIPs(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 1)
+	svc.EnableIPRequestQueue(10, time.Minute)
+	_, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(0)))
+	require.NoError(t, err)
+
+	results := []<-chan queuedRequestResult{}
+	for i := 1; i < 4; i++ {
+		results = append(results, startQueuedRequest(context.Background(), t, svc, testPodInfo(i)))
+	}
+
+	// scale up the NC as when the NNC is updated with more IPs
+	require.Equal(t, types.Success, svc.CreateOrUpdateNetworkContainerInternal(newQueueTestNCRequest(testNCID, 4)))
+
+	for _, result := range results {
+		r := receiveResult(t, result)
+		require.NoError(t, r.err)
+	}
+	require.Len(t, svc.GetAssignedIPConfigs(), 4)
+	require.Empty(t, svc.GetAvailableIPConfigs())
+}
+
+// Tests that a pod which already holds IPs gets them back at once, even while other requests are waiting.
+func TestIPRequestQueueReturnsExistingIPs(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 1)
+	svc.EnableIPRequestQueue(10, 50*time.Millisecond)
+	assigned, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(0)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := startQueuedRequest(ctx, t, svc, testPodInfo(1))
+
+	// the CNI retries the ADD of the pod holding the only IP
+	podIPInfo, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(0)))
+	require.NoError(t, err)
+	require.Equal(t, assigned, podIPInfo)
+	require.Equal(t, 1, svc.ipRequestQueue.len())
+
+	cancel()
+	require.ErrorIs(t, receiveResult(t, result).err, ErrNotEnoughIPs)
+}
+
+func TestIPRequestQueueTimesOut(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 0)
+	svc.EnableIPRequestQueue(10, 10*time.Millisecond)
+
+	_, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(0)))
+	require.ErrorIs(t, err, ErrNotEnoughIPs)
+	require.Equal(t, 0, svc.ipRequestQueue.len())
+}
+
+func TestIPRequestQueueFull(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 0)
+	svc.EnableIPRequestQueue(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := startQueuedRequest(ctx, t, svc, testPodInfo(0))
+
+	// the second request fails straight away rather than waiting behind the first
+	_, err := svc.queuedRequestIPConfigs(context.Background(), newIPConfigsRequest(t, testPodInfo(1)))
+	require.ErrorIs(t, err, ErrNotEnoughIPs)
+	require.Equal(t, 1, svc.ipRequestQueue.len())
+
+	// the first request leaves the queue when its context is done
+	cancel()
+	require.ErrorIs(t, receiveResult(t, result).err, ErrNotEnoughIPs)
+	require.Equal(t, 0, svc.ipRequestQueue.len())
+}
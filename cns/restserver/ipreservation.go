@@ -0,0 +1,68 @@
+package restserver
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+)
+
+// SetIPReservations makes CNS never assign the IPs the NodeNetworkConfigSpec excludes, and assign the IPs it reserves
+// for Pods only to those Pods. If the exclusions or reservations are invalid or conflict with the IPs assigned to
+// Pods, an error is returned and the previous ones are kept.
+func (service *HTTPRestService) SetIPReservations(spec *v1alpha.NodeNetworkConfigSpec) error {
+	reservations, err := ipreservation.New(spec)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse IP reservations")
+	}
+
+	service.Lock()
+	defer service.Unlock()
+	if err := reservations.Validate(service.PodIPConfigState); err != nil {
+		return errors.Wrap(err, "failed to validate IP reservations")
+	}
+	if reservations != nil || service.ipReservations != nil {
+		logger.Printf("[IPReservations] Updated IP reservations, %d excluded IPs or CIDRs and %d reserved IPs",
+			len(spec.ExcludedIPs), reservations.Len())
+	}
+	service.ipReservations = reservations
+	return nil
+}
+
+func ipReservationKey(podInfo cns.PodInfo) string {
+	return ipreservation.PodKey(podInfo.Namespace(), podInfo.Name())
+}
+
+// addReservedIPConfigPerNCUntransacted replaces in ipsToAssign the IPs of the NCs the Pod has reserved IPs in with the
+// reserved IPs. The error wraps ErrNotEnoughIPs if a reserved IP is not in the pool yet.
+func (service *HTTPRestService) addReservedIPConfigPerNCUntransacted(podInfo cns.PodInfo, ipsToAssign map[string]cns.IPConfigurationStatus) error {
+	key := ipReservationKey(podInfo)
+	reservedIPs := service.ipReservations.PodIPs(key)
+	if len(reservedIPs) == 0 {
+		return nil
+	}
+
+	wanted := make(map[string]struct{}, len(reservedIPs))
+	for _, ip := range reservedIPs {
+		wanted[ip] = struct{}{}
+	}
+	found := 0
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		if _, ok := wanted[ipConfig.IPAddress]; !ok {
+			continue
+		}
+		found++
+		if state := ipConfig.GetState(); state != types.Available && state != types.Cooldown {
+			return fmt.Errorf("IP %s reserved for pod %s is %s", ipConfig.IPAddress, key, state) //nolint:goerr113 // return error
+		}
+		ipsToAssign[ipConfig.NCID] = ipConfig
+	}
+	if found != len(reservedIPs) {
+		return fmt.Errorf("%w: IPs %v reserved for pod %s are not all allocated to the node yet", ErrNotEnoughIPs, reservedIPs, key)
+	}
+	return nil
+}
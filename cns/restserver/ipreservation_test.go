@@ -0,0 +1,143 @@
+package restserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/ipreservation"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/stretchr/testify/require"
+)
+
+// The IPs of newIndexTestService are 10.1.0.1 to 10.1.0.N.
+func TestIPReservationsHonoredByAssignAvailableIPConfigs(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 4)
+	pod0 := testPodInfo(0)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		ExcludedIPs:    []string{"10.1.0.1", "10.1.0.2/32"},
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.3", PodName: pod0.Name(), PodNamespace: pod0.Namespace()}},
+	}))
+
+	// the other pods are only assigned the IP neither excluded nor reserved
+	podIPInfo, err := svc.AssignAvailableIPConfigs(testPodInfo(1))
+	require.NoError(t, err)
+	require.Equal(t, "10.1.0.4", podIPInfo[0].PodIPConfig.IPAddress)
+	_, err = svc.AssignAvailableIPConfigs(testPodInfo(2))
+	require.ErrorIs(t, err, ErrNotEnoughIPs)
+
+	// the pod is assigned its reserved IP
+	podIPInfo, err = svc.AssignAvailableIPConfigs(pod0)
+	require.NoError(t, err)
+	require.Equal(t, "10.1.0.3", podIPInfo[0].PodIPConfig.IPAddress)
+	requireIndexConsistent(t, svc)
+}
+
+func TestIPReservationsHonoredByAssignDesiredIPConfigs(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 4)
+	pod0 := testPodInfo(0)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		ExcludedIPs:    []string{"10.1.0.1"},
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.3", PodName: pod0.Name(), PodNamespace: pod0.Namespace()}},
+	}))
+
+	_, err := svc.AssignDesiredIPConfigs(testPodInfo(1), []string{"10.1.0.1"})
+	require.ErrorIs(t, err, ipreservation.ErrConflict)
+	_, err = svc.AssignDesiredIPConfigs(testPodInfo(1), []string{"10.1.0.3"})
+	require.ErrorIs(t, err, ipreservation.ErrConflict)
+
+	podIPInfo, err := svc.AssignDesiredIPConfigs(pod0, []string{"10.1.0.3"})
+	require.NoError(t, err)
+	require.Equal(t, "10.1.0.3", podIPInfo[0].PodIPConfig.IPAddress)
+}
+
+func TestIPReservationNotYetAllocated(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	pod0 := testPodInfo(0)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.9", PodName: pod0.Name(), PodNamespace: pod0.Namespace()}},
+	}))
+
+	// the pod waits for its reserved IP rather than being assigned another
+	_, err := svc.AssignAvailableIPConfigs(pod0)
+	require.ErrorIs(t, err, ErrNotEnoughIPs)
+	require.Empty(t, svc.GetAssignedIPConfigs())
+}
+
+func TestSetIPReservationsConflict(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	podIPInfo, err := svc.AssignAvailableIPConfigs(testPodInfo(0))
+	require.NoError(t, err)
+	assigned := podIPInfo[0].PodIPConfig.IPAddress
+	free := svc.GetAvailableIPConfigs()[0].IPAddress
+
+	err = svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{ExcludedIPs: []string{assigned}})
+	require.ErrorIs(t, err, ipreservation.ErrConflict)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{ExcludedIPs: []string{free}}))
+
+	// the IP can't be reserved for another pod while it is assigned, and the previous exclusions are kept
+	err = svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: assigned, PodName: "other", PodNamespace: "default"}},
+	})
+	require.ErrorIs(t, err, ipreservation.ErrConflict)
+	require.False(t, svc.ipReservations.Unreserved(free))
+
+	err = svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{ExcludedIPs: []string{"not-an-ip"}})
+	require.ErrorIs(t, err, ipreservation.ErrInvalid)
+}
+
+func TestIPReservationsKeptByMarkNIPsPendingRelease(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 3)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.2", PodName: "pod-0", PodNamespace: "default"}},
+	}))
+
+	released, err := svc.MarkNIPsPendingRelease(2)
+	require.NoError(t, err)
+	for _, ipConfig := range released { //nolint:gocritic // ignore copy
+		require.NotEqual(t, "10.1.0.2", ipConfig.IPAddress)
+	}
+	_, err = svc.MarkNIPsPendingRelease(1)
+	require.Error(t, err)
+	requireIndexConsistent(t, svc)
+}
+
+func TestIPReservationsKeptByMarkIPAsPendingRelease(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 3)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.2", PodName: "pod-0", PodNamespace: "default"}},
+	}))
+
+	released, err := svc.MarkIPAsPendingRelease(2)
+	require.NoError(t, err)
+	require.Len(t, released, 2)
+	for _, ipConfig := range released { //nolint:gocritic // ignore copy
+		require.NotEqual(t, "10.1.0.2", ipConfig.IPAddress)
+	}
+
+	// without a total, all of the IPs but the reserved ones are set to PendingRelease
+	svc = newIndexTestService(t, testNCID, 3)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.2", PodName: "pod-0", PodNamespace: "default"}},
+	}))
+	released, err = svc.MarkIPAsPendingRelease(0)
+	require.NoError(t, err)
+	require.Len(t, released, 2)
+	require.Len(t, svc.ipConfigsInStateUntransacted(types.Available), 1)
+	requireIndexConsistent(t, svc)
+}
+
+func TestReservedIPSkipsCooldown(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	svc.EnableIPCooldown(time.Minute)
+	pod0 := testPodInfo(0)
+	require.NoError(t, svc.SetIPReservations(&v1alpha.NodeNetworkConfigSpec{
+		IPReservations: []v1alpha.IPReservation{{IP: "10.1.0.1", PodName: pod0.Name(), PodNamespace: pod0.Namespace()}},
+	}))
+
+	_, err := svc.AssignAvailableIPConfigs(pod0)
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(pod0))
+	require.Empty(t, svc.GetCooldownIPConfigs())
+	require.Len(t, svc.ipConfigsInStateUntransacted(types.Available), 2)
+}
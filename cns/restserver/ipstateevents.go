@@ -0,0 +1,190 @@
+package restserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultIPStateEventBuffer is how many of the latest IP state events are kept for subscribers to resume from.
+	defaultIPStateEventBuffer = 4096
+	// ipStateEventsKeepAlive is how often a comment is sent to subscribers when there are no events, so that
+	// proxies don't close idle streams and subscribers notice when CNS goes away.
+	ipStateEventsKeepAlive = 30 * time.Second
+)
+
+// ErrIPStateEventsLost is returned when the events after a sequence are no longer, or were never, in the event log.
+var ErrIPStateEventsLost = errors.New("IP state events after the sequence are not available")
+
+// ipStateEventLog keeps the latest IP state events in a ring buffer, the event with sequence s at s modulo its
+// size, and wakes the subscribers when an event is published. It has its own lock, since events are published by
+// the state middleware with the service lock held, and read by subscribers without it.
+type ipStateEventLog struct {
+	sync.Mutex
+	events []cns.IPStateEvent
+	// next is the sequence of the next event.
+	next uint64
+	// changed is closed and replaced when an event is published.
+	changed chan struct{}
+}
+
+func newIPStateEventLog(size int) *ipStateEventLog {
+	return &ipStateEventLog{
+		events:  make([]cns.IPStateEvent, size),
+		next:    1,
+		changed: make(chan struct{}),
+	}
+}
+
+// publish assigns the event the next sequence and adds it to the log.
+func (l *ipStateEventLog) publish(e cns.IPStateEvent) { //nolint:gocritic // events are stored by value
+	l.Lock()
+	defer l.Unlock()
+	e.Sequence = l.next
+	l.events[l.next%uint64(len(l.events))] = e
+	l.next++
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// latest returns the sequence of the latest event, 0 if there are none.
+func (l *ipStateEventLog) latest() uint64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.next - 1
+}
+
+// since returns the events after the sequence, and a channel which is closed when another event is published.
+// It returns ErrIPStateEventsLost if some of the events have been dropped from the log, or if the sequence is
+// after the latest event, as when it was read from a previous run of CNS.
+func (l *ipStateEventLog) since(seq uint64) ([]cns.IPStateEvent, <-chan struct{}, error) {
+	l.Lock()
+	defer l.Unlock()
+	size := uint64(len(l.events))
+	if seq >= l.next || (l.next > size && seq < l.next-size-1) {
+		return nil, nil, errors.Wrapf(ErrIPStateEventsLost, "sequence %d, latest %d", seq, l.next-1)
+	}
+	events := make([]cns.IPStateEvent, 0, l.next-seq-1)
+	for s := seq + 1; s < l.next; s++ {
+		events = append(events, l.events[s%size])
+	}
+	return events, l.changed, nil
+}
+
+// LatestIPStateEvent returns the sequence of the latest IP state event, 0 if there are none.
+func (service *HTTPRestService) LatestIPStateEvent() uint64 {
+	if service.ipStateEvents == nil {
+		return 0
+	}
+	return service.ipStateEvents.latest()
+}
+
+// IPStateEventsSince returns the IP state events after the sequence, and a channel which is closed when another
+// event is published. It returns ErrIPStateEventsLost if the events after the sequence are not available.
+func (service *HTTPRestService) IPStateEventsSince(seq uint64) ([]cns.IPStateEvent, <-chan struct{}, error) {
+	if service.ipStateEvents == nil {
+		return nil, nil, errors.Wrap(ErrIPStateEventsLost, "IP state events are not enabled")
+	}
+	return service.ipStateEvents.since(seq)
+}
+
+// ipStateEventsMiddleware publishes an event for the transition of the IP to the new state, if it differs from
+// the current one. It is attached to each IPConfigurationStatus with WithStateMiddleware, and runs with the service
+// lock held before the state of the IP is updated.
+func (service *HTTPRestService) ipStateEventsMiddleware(ipConfig *cns.IPConfigurationStatus, state types.IPState) {
+	if ipConfig.GetState() == state {
+		return
+	}
+	service.ipStateEvents.publish(cns.IPStateEvent{
+		ID:        ipConfig.ID,
+		IPAddress: ipConfig.IPAddress,
+		NCID:      ipConfig.NCID,
+		PodInfo:   ipConfig.PodInfo,
+		OldState:  ipConfig.GetState(),
+		NewState:  state,
+		Timestamp: time.Now(),
+	})
+}
+
+// HandleIPStateEvents streams the IP state events as server-sent events, from the sequence in the "since" query
+// parameter or the Last-Event-ID header on, or from the next event if neither is set. It responds with
+// 410 Gone if the events after the sequence are not available, and ends the stream if the subscriber falls so far
+// behind that they are dropped, so that it can list the IPs again and resume from the latest event.
+func (service *HTTPRestService) HandleIPStateEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || service.ipStateEvents == nil {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	seq := service.ipStateEvents.latest()
+	from := r.URL.Query().Get("since")
+	if from == "" {
+		from = r.Header.Get("Last-Event-ID")
+	}
+	if from != "" {
+		var err error
+		if seq, err = strconv.ParseUint(from, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid sequence %q", from), http.StatusBadRequest)
+			return
+		}
+	}
+	events, changed, err := service.ipStateEvents.since(seq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
+	logger.Printf("[IPStateEvents] Streaming IP state events after sequence %d to %s", seq, r.RemoteAddr)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(ipStateEventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		for i := range events {
+			if err := writeIPStateEvent(w, &events[i]); err != nil {
+				logger.Errorf("[IPStateEvents] Failed to write IP state event to %s: %v", r.RemoteAddr, err)
+				return
+			}
+			seq = events[i].Sequence
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			events = nil
+			continue
+		case <-changed:
+		}
+		if events, changed, err = service.ipStateEvents.since(seq); err != nil {
+			logger.Printf("[IPStateEvents] Ending the stream to %s, which fell behind: %v", r.RemoteAddr, err)
+			return
+		}
+	}
+}
+
+func writeIPStateEvent(w http.ResponseWriter, e *cns.IPStateEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal IP state event")
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: ipstate\ndata: %s\n\n", e.Sequence, b)
+	return errors.Wrap(err, "failed to write IP state event")
+}
@@ -0,0 +1,152 @@
+package restserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPStateEventLog(t *testing.T) {
+	l := newIPStateEventLog(3)
+	events, _, err := l.since(0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	_, _, err = l.since(1)
+	require.ErrorIs(t, err, ErrIPStateEventsLost)
+
+	for i := 0; i < 4; i++ {
+		l.publish(cns.IPStateEvent{ID: strconv.Itoa(i)})
+	}
+	assert.Equal(t, uint64(4), l.latest())
+
+	// the first event has been dropped
+	_, _, err = l.since(0)
+	require.ErrorIs(t, err, ErrIPStateEventsLost)
+	events, changed, err := l.since(1)
+	require.NoError(t, err)
+	require.Equal(t, []cns.IPStateEvent{{Sequence: 2, ID: "1"}, {Sequence: 3, ID: "2"}, {Sequence: 4, ID: "3"}}, events)
+	events, _, err = l.since(4)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	// a sequence from a previous run of CNS
+	_, _, err = l.since(5)
+	require.ErrorIs(t, err, ErrIPStateEventsLost)
+
+	select {
+	case <-changed:
+		t.Fatal("changed before an event was published")
+	default:
+	}
+	l.publish(cns.IPStateEvent{ID: "4"})
+	<-changed
+}
+
+func TestIPStateEventsFollowStateTransitions(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	events, _, err := svc.ipStateEvents.since(0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, testNCID, e.NCID)
+		assert.Equal(t, types.IPState(""), e.OldState)
+		assert.Equal(t, types.Available, e.NewState)
+		assert.Nil(t, e.PodInfo)
+	}
+
+	seq := svc.ipStateEvents.latest()
+	assigned, err := svc.AssignAvailableIPConfigs(testPodInfo(0))
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(0)))
+
+	events, _, err = svc.ipStateEvents.since(seq)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	// the assignment and the release both carry the Pod
+	assert.Equal(t, types.Available, events[0].OldState)
+	assert.Equal(t, types.Assigned, events[0].NewState)
+	assert.Equal(t, types.Assigned, events[1].OldState)
+	assert.Equal(t, types.Available, events[1].NewState)
+	for _, e := range events {
+		assert.Equal(t, assigned[0].PodIPConfig.IPAddress, e.IPAddress)
+		require.NotNil(t, e.PodInfo)
+		assert.Equal(t, testPodInfo(0).Key(), e.PodInfo.Key())
+	}
+	assert.Equal(t, seq+1, events[0].Sequence)
+	assert.Equal(t, seq+2, events[1].Sequence)
+
+	// updates which leave the state as it is are not published
+	seq = svc.ipStateEvents.latest()
+	_, err = svc.updateIPConfigState(events[1].ID, types.Available, nil)
+	require.NoError(t, err)
+	assert.Equal(t, seq, svc.ipStateEvents.latest())
+}
+
+// readIPStateEvents reads n events from the server-sent event stream.
+func readIPStateEvents(t *testing.T, s *bufio.Scanner, n int) []cns.IPStateEvent {
+	t.Helper()
+	var events []cns.IPStateEvent
+	for len(events) < n && s.Scan() {
+		data, ok := strings.CutPrefix(s.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e cns.IPStateEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &e))
+		events = append(events, e)
+	}
+	require.Len(t, events, n)
+	return events
+}
+
+func TestHandleIPStateEvents(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 2)
+	server := httptest.NewServer(http.HandlerFunc(svc.HandleIPStateEvents))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	get := func(query string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+query, http.NoBody)
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	// without a sequence, the stream starts from the next event
+	res := get("")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	_, err := svc.AssignAvailableIPConfigs(testPodInfo(0))
+	require.NoError(t, err)
+	events := readIPStateEvents(t, bufio.NewScanner(res.Body), 1)
+	assert.Equal(t, uint64(3), events[0].Sequence)
+	assert.Equal(t, types.Assigned, events[0].NewState)
+	assert.Equal(t, testPodInfo(0).Key(), events[0].PodInfo.Key())
+
+	// resuming from a sequence replays the events after it
+	resumed := get("?since=1")
+	defer resumed.Body.Close()
+	require.Equal(t, http.StatusOK, resumed.StatusCode)
+	events = readIPStateEvents(t, bufio.NewScanner(resumed.Body), 2)
+	assert.Equal(t, uint64(2), events[0].Sequence)
+	assert.Equal(t, uint64(3), events[1].Sequence)
+
+	gone := get("?since=10")
+	defer gone.Body.Close()
+	assert.Equal(t, http.StatusGone, gone.StatusCode)
+	invalid := get("?since=latest")
+	defer invalid.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+}
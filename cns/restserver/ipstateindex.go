@@ -0,0 +1,237 @@
+package restserver
+
+import (
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/filter"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// ipStateIndex indexes the IDs of the IPs in PodIPConfigState by NC and by state, so that an IP in a given
+// state can be found without scanning the whole map. It is kept up to date by the state middleware of the
+// IPConfigurationStatus added to PodIPConfigState, and like PodIPConfigState it is guarded by the service lock.
+type ipStateIndex struct {
+	// IDs of the IPs of each NC in each state.
+	ipIDs map[string]map[types.IPState]*ipIDSet
+	// NC and state of each indexed IP.
+	entries map[string]ipStateIndexEntry
+	// valid is set when the index is built, and cleared when PodIPConfigState is written without the index.
+	valid bool
+}
+
+type ipStateIndexEntry struct {
+	ncID  string
+	state types.IPState
+}
+
+// ipIDSet is a set of IP IDs which can be added to, removed from and picked from in constant time.
+// The IDs are kept in a slice rather than as map keys, since iterating a map that once held many keys
+// costs as much as when it held them all.
+type ipIDSet struct {
+	ids []string
+	pos map[string]int
+}
+
+func (s *ipIDSet) add(ipID string) {
+	if _, ok := s.pos[ipID]; ok {
+		return
+	}
+	s.pos[ipID] = len(s.ids)
+	s.ids = append(s.ids, ipID)
+}
+
+func (s *ipIDSet) remove(ipID string) {
+	i, ok := s.pos[ipID]
+	if !ok {
+		return
+	}
+	last := len(s.ids) - 1
+	s.ids[i] = s.ids[last]
+	s.pos[s.ids[i]] = i
+	s.ids = s.ids[:last]
+	delete(s.pos, ipID)
+}
+
+// list returns the IDs in the set, it is only valid until the set is modified.
+func (s *ipIDSet) list() []string {
+	if s == nil {
+		return nil
+	}
+	return s.ids
+}
+
+func newIPStateIndex() *ipStateIndex {
+	return &ipStateIndex{
+		ipIDs:   make(map[string]map[types.IPState]*ipIDSet),
+		entries: make(map[string]ipStateIndexEntry),
+		valid:   true,
+	}
+}
+
+// stateMiddleware moves the IP to the set of its new state. It is attached to each IPConfigurationStatus
+// with WithStateMiddleware, and is safe to run more than once for the same transition.
+func (x *ipStateIndex) stateMiddleware(ipConfig *cns.IPConfigurationStatus, state types.IPState) {
+	x.set(ipConfig.ID, ipConfig.NCID, state)
+}
+
+func (x *ipStateIndex) set(ipID, ncID string, state types.IPState) {
+	x.remove(ipID)
+
+	states, ok := x.ipIDs[ncID]
+	if !ok {
+		states = make(map[types.IPState]*ipIDSet)
+		x.ipIDs[ncID] = states
+	}
+	ids, ok := states[state]
+	if !ok {
+		ids = &ipIDSet{pos: make(map[string]int)}
+		states[state] = ids
+	}
+
+	ids.add(ipID)
+	x.entries[ipID] = ipStateIndexEntry{ncID: ncID, state: state}
+}
+
+func (x *ipStateIndex) remove(ipID string) {
+	if x == nil {
+		return
+	}
+
+	entry, ok := x.entries[ipID]
+	if !ok {
+		return
+	}
+
+	x.ipIDs[entry.ncID][entry.state].remove(ipID)
+	delete(x.entries, ipID)
+}
+
+// ipIndexUsableUntransacted returns whether the index is in sync with PodIPConfigState. It is not once the map
+// was written without the index, in which case the callers fall back to scanning the map.
+func (service *HTTPRestService) ipIndexUsableUntransacted() bool {
+	return service.ipIndex != nil && service.ipIndex.valid
+}
+
+// invalidateIPIndexUntransacted marks the index as out of sync, it is called after PodIPConfigState is written
+// with IPConfigurationStatus which do not update the index.
+func (service *HTTPRestService) invalidateIPIndexUntransacted() {
+	if service.ipIndex != nil {
+		service.ipIndex.valid = false
+	}
+}
+
+// indexedIPConfigUntransacted returns the IP with the given ID if it still has the NC and state it is indexed with.
+func (service *HTTPRestService) indexedIPConfigUntransacted(ipID, ncID string, state types.IPState) (cns.IPConfigurationStatus, bool) {
+	ipConfig, ok := service.PodIPConfigState[ipID]
+	if !ok || ipConfig.NCID != ncID || ipConfig.GetState() != state {
+		return cns.IPConfigurationStatus{}, false
+	}
+	return ipConfig, true
+}
+
+// ipConfigsInStateUntransacted returns the IPs in the given state.
+func (service *HTTPRestService) ipConfigsInStateUntransacted(state types.IPState) []cns.IPConfigurationStatus {
+	if !service.ipIndexUsableUntransacted() {
+		return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.PredicatesForStates(state)...)
+	}
+
+	ipConfigs := []cns.IPConfigurationStatus{}
+	for ncID, states := range service.ipIndex.ipIDs {
+		for _, ipID := range states[state].list() {
+			if ipConfig, ok := service.indexedIPConfigUntransacted(ipID, ncID, state); ok {
+				ipConfigs = append(ipConfigs, ipConfig)
+			}
+		}
+	}
+	return ipConfigs
+}
+
+// ipIDsInStateUntransacted returns the IDs of up to limit IPs in the given state, or of all of them if limit is negative.
+func (service *HTTPRestService) ipIDsInStateUntransacted(state types.IPState, limit int) []string {
+	ipIDs := []string{}
+	if limit == 0 {
+		return ipIDs
+	}
+
+	if !service.ipIndexUsableUntransacted() {
+		for ipID, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+			if ipConfig.GetState() != state {
+				continue
+			}
+			ipIDs = append(ipIDs, ipID)
+			if len(ipIDs) == limit {
+				return ipIDs
+			}
+		}
+		return ipIDs
+	}
+
+	for ncID, states := range service.ipIndex.ipIDs {
+		for _, ipID := range states[state].list() {
+			if _, ok := service.indexedIPConfigUntransacted(ipID, ncID, state); !ok {
+				continue
+			}
+			ipIDs = append(ipIDs, ipID)
+			if len(ipIDs) == limit {
+				return ipIDs
+			}
+		}
+	}
+	return ipIDs
+}
+
+// availableIPConfigPerNCUntransacted returns an Available IP of each NC in the service state, keyed by NC, which is
+// neither excluded nor reserved. NCs without such an IP are missing from the result.
+func (service *HTTPRestService) availableIPConfigPerNCUntransacted() map[string]cns.IPConfigurationStatus {
+	numOfNCs := len(service.state.ContainerStatus)
+	ipsToAssign := make(map[string]cns.IPConfigurationStatus, numOfNCs)
+
+	if !service.ipIndexUsableUntransacted() {
+		// Searches for available IPs in the pool
+		for _, ipState := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+			// check if an IP from this NC is already set side for assignment.
+			if _, ncAlreadyMarkedForAssignment := ipsToAssign[ipState.NCID]; ncAlreadyMarkedForAssignment {
+				continue
+			}
+			// Checks if the current IP is available and neither excluded nor reserved
+			if ipState.GetState() != types.Available || !service.ipReservations.Unreserved(ipState.IPAddress) {
+				continue
+			}
+			ipsToAssign[ipState.NCID] = ipState
+			// Once one IP per container is found break out of the loop and stop searching
+			if len(ipsToAssign) == numOfNCs {
+				break
+			}
+		}
+		return ipsToAssign
+	}
+
+	for ncID := range service.state.ContainerStatus {
+		for _, ipID := range service.ipIndex.ipIDs[ncID][types.Available].list() {
+			if ipConfig, ok := service.indexedIPConfigUntransacted(ipID, ncID, types.Available); ok && service.ipReservations.Unreserved(ipConfig.IPAddress) {
+				ipsToAssign[ncID] = ipConfig
+				break
+			}
+		}
+	}
+	return ipsToAssign
+}
+
+// ncIPConfigsInStateUntransacted returns the IPs of the NC in the given state.
+func (service *HTTPRestService) ncIPConfigsInStateUntransacted(ncID string, state types.IPState) []cns.IPConfigurationStatus {
+	ipConfigs := []cns.IPConfigurationStatus{}
+	if !service.ipIndexUsableUntransacted() {
+		for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+			if ipConfig.NCID == ncID && ipConfig.GetState() == state {
+				ipConfigs = append(ipConfigs, ipConfig)
+			}
+		}
+		return ipConfigs
+	}
+
+	for _, ipID := range service.ipIndex.ipIDs[ncID][state].list() {
+		if ipConfig, ok := service.indexedIPConfigUntransacted(ipID, ncID, state); ok {
+			ipConfigs = append(ipConfigs, ipConfig)
+		}
+	}
+	return ipConfigs
+}
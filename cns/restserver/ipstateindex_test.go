@@ -0,0 +1,186 @@
+package restserver
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+// newIndexTestService returns a service with an NC of the given number of Available IPs.
+func newIndexTestService(tb testing.TB, ncID string, numIPs int) *HTTPRestService {
+	svc := getTestService()
+
+	secondaryIPConfigs := make(map[string]cns.SecondaryIPConfig, numIPs)
+	ip := netip.MustParseAddr("10.1.0.0")
+	for i := 0; i < numIPs; i++ {
+		ip = ip.Next()
+		secondaryIPConfigs[fmt.Sprintf("%s-%d", ncID, i)] = newSecondaryIPConfig(ip.String(), -1)
+	}
+
+	req := &cns.CreateNetworkContainerRequest{
+		NetworkContainerType: dockerContainerType,
+		NetworkContainerid:   ncID,
+		IPConfiguration: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: primaryIP, PrefixLength: 16},
+			DNSServers:       dnsservers,
+			GatewayIPAddress: gatewayIP,
+		},
+		SecondaryIPConfigs: secondaryIPConfigs,
+		Version:            "-1",
+	}
+	require.Equal(tb, types.Success, svc.CreateOrUpdateNetworkContainerInternal(req))
+	return svc
+}
+
+func testPodInfo(i int) cns.PodInfo {
+	return cns.NewPodInfo(strconv.Itoa(i)+"-eth0", "pod-"+strconv.Itoa(i), "pod-"+strconv.Itoa(i), "default")
+}
+
+// requireIndexConsistent checks that the index holds exactly the IPs of PodIPConfigState in their current state.
+func requireIndexConsistent(t *testing.T, svc *HTTPRestService) {
+	t.Helper()
+	require.True(t, svc.ipIndexUsableUntransacted())
+	for ipID, ipConfig := range svc.PodIPConfigState { //nolint:gocritic // ignore copy
+		require.Equal(t, ipStateIndexEntry{ncID: ipConfig.NCID, state: ipConfig.GetState()}, svc.ipIndex.entries[ipID])
+		require.Contains(t, svc.ipIndex.ipIDs[ipConfig.NCID][ipConfig.GetState()].list(), ipID)
+	}
+}
+
+func TestIPStateIndexFollowsStateTransitions(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 10)
+	requireIndexConsistent(t, svc)
+	require.Len(t, svc.ipIndex.ipIDs[testNCID][types.Available].list(), 10)
+
+	for i := 0; i < 4; i++ {
+		_, err := svc.AssignAvailableIPConfigs(testPodInfo(i))
+		require.NoError(t, err)
+	}
+	requireIndexConsistent(t, svc)
+	require.Len(t, svc.GetAssignedIPConfigs(), 4)
+	require.Len(t, svc.GetAvailableIPConfigs(), 6)
+
+	require.NoError(t, svc.releaseIPConfigs(testPodInfo(0)))
+	requireIndexConsistent(t, svc)
+	require.Len(t, svc.GetAvailableIPConfigs(), 7)
+
+	released, err := svc.MarkNIPsPendingRelease(3)
+	require.NoError(t, err)
+	require.Len(t, released, 3)
+	requireIndexConsistent(t, svc)
+	require.Len(t, svc.GetPendingReleaseIPConfigs(), 3)
+	require.Len(t, svc.GetAvailableIPConfigs(), 4)
+
+	// marking more IPs than are available reverts the change
+	_, err = svc.MarkNIPsPendingRelease(5)
+	require.Error(t, err)
+	requireIndexConsistent(t, svc)
+	require.Len(t, svc.GetAvailableIPConfigs(), 4)
+
+	// IPs removed from the NC are removed from the index
+	for ipID := range released {
+		code, _ := svc.removeToBeDeletedIPStateUntransacted(ipID, false)
+		require.Equal(t, types.Success, code)
+	}
+	requireIndexConsistent(t, svc)
+	require.Empty(t, svc.GetPendingReleaseIPConfigs())
+}
+
+// Tests that the IP state is still found when PodIPConfigState is modified without the index.
+func TestIPStateIndexFallsBackToScan(t *testing.T) {
+	svc := newIndexTestService(t, testNCID, 3)
+	require.True(t, svc.ipIndexUsableUntransacted())
+
+	// swap an IP for one in another state, which leaves as many IPs in the map as in the index
+	var swapped string
+	for ipID := range svc.PodIPConfigState {
+		swapped = ipID
+		break
+	}
+	ipConfig, err := NewPodStateWithOrchestratorContext(svc.PodIPConfigState[swapped].IPAddress, swapped, testNCID, types.Assigned, 24, 0, testPod3Info)
+	require.NoError(t, err)
+	svc.PodIPConfigState[swapped] = ipConfig
+	svc.PodIPIDByPodInterfaceKey[testPod3Info.Key()] = []string{swapped}
+	svc.invalidateIPIndexUntransacted()
+	require.False(t, svc.ipIndexUsableUntransacted())
+	require.Len(t, svc.GetAvailableIPConfigs(), 2)
+	require.Len(t, svc.GetAssignedIPConfigs(), 1)
+
+	_, err = svc.AssignAvailableIPConfigs(testPod1Info)
+	require.NoError(t, err)
+	require.Len(t, svc.GetAssignedIPConfigs(), 2)
+
+	for ipID, ipConfig := range svc.PodIPConfigState {
+		if ipConfig.GetState() == types.Available {
+			delete(svc.PodIPConfigState, ipID)
+		}
+	}
+	_, err = svc.AssignAvailableIPConfigs(testPod2Info)
+	require.Error(t, err)
+}
+
+var benchmarkIPCounts = []int{250, 2000, 16000}
+
+// benchmarkIPStateLookup runs f on a service with the given number of IPs, 90% of them Assigned,
+// once using the index and once scanning PodIPConfigState as before the index was added.
+func benchmarkIPStateLookup(b *testing.B, f func(b *testing.B, svc *HTTPRestService)) {
+	for _, numIPs := range benchmarkIPCounts {
+		svc := newIndexTestService(b, testNCID, numIPs)
+		for i := 0; i < numIPs*9/10; i++ {
+			_, err := svc.AssignAvailableIPConfigs(testPodInfo(i))
+			require.NoError(b, err)
+		}
+		ipIndex := svc.ipIndex
+
+		b.Run(fmt.Sprintf("index/%d", numIPs), func(b *testing.B) {
+			svc.ipIndex = ipIndex
+			f(b, svc)
+		})
+		b.Run(fmt.Sprintf("scan/%d", numIPs), func(b *testing.B) {
+			svc.ipIndex = nil
+			f(b, svc)
+		})
+	}
+}
+
+func BenchmarkAssignAndReleaseIPConfigs(b *testing.B) {
+	podInfo := testPodInfo(-1)
+	benchmarkIPStateLookup(b, func(b *testing.B, svc *HTTPRestService) {
+		for i := 0; i < b.N; i++ {
+			if _, err := svc.AssignAvailableIPConfigs(podInfo); err != nil {
+				b.Fatal(err)
+			}
+			if err := svc.releaseIPConfigs(podInfo); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMarkNIPsPendingRelease(b *testing.B) {
+	benchmarkIPStateLookup(b, func(b *testing.B, svc *HTTPRestService) {
+		for i := 0; i < b.N; i++ {
+			released, err := svc.MarkNIPsPendingRelease(1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for ipID := range released {
+				if _, err := svc.updateIPConfigState(ipID, types.Available, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGetAvailableIPConfigs(b *testing.B) {
+	benchmarkIPStateLookup(b, func(b *testing.B, svc *HTTPRestService) {
+		for i := 0; i < b.N; i++ {
+			svc.GetAvailableIPConfigs()
+		}
+	})
+}
@@ -16,6 +16,8 @@ type ipState struct {
 	programmingIPs int64
 	// releasingIPs are the IPs in state "PendingReleasr".
 	releasingIPs int64
+	// cooldownIPs are the IPs in state "Cooldown".
+	cooldownIPs int64
 }
 
 func (service *HTTPRestService) buildIPState() *ipState {
@@ -28,6 +30,7 @@ func (service *HTTPRestService) buildIPState() *ipState {
 		availableIPs:   0,
 		programmingIPs: 0,
 		releasingIPs:   0,
+		cooldownIPs:    0,
 	}
 
 	//nolint:gocritic // This has to iterate over the IP Config state to get the counts.
@@ -45,14 +48,18 @@ func (service *HTTPRestService) buildIPState() *ipState {
 		if ipConfig.GetState() == types.PendingRelease {
 			state.releasingIPs++
 		}
+		if ipConfig.GetState() == types.Cooldown {
+			state.cooldownIPs++
+		}
 	}
 
-	logger.Printf("[IP Usage] Allocated IPs: %d, Assigned IPs: %d, Available IPs: %d, PendingProgramming IPs: %d, PendingRelease IPs: %d",
+	logger.Printf("[IP Usage] Allocated IPs: %d, Assigned IPs: %d, Available IPs: %d, PendingProgramming IPs: %d, PendingRelease IPs: %d, Cooldown IPs: %d",
 		state.allocatedIPs,
 		state.assignedIPs,
 		state.availableIPs,
 		state.programmingIPs,
 		state.releasingIPs,
+		state.cooldownIPs,
 	)
 	return &state
 }
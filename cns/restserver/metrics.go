@@ -29,6 +29,21 @@ var (
 		},
 		[]string{"url", "verb", "cns_return_code"},
 	)
+	ipRequestQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ip_request_queue_depth",
+			Help: "Number of IP requests waiting for IPs to become Available",
+		},
+	)
+	ipRequestQueueWaitTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "ip_request_queue_wait_seconds",
+			Help: "Time spent by IP requests waiting in the IP request queue, by whether they were assigned IPs",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
+		},
+		[]string{"ok"},
+	)
 	ipAssignmentLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name: "ip_assignment_latency_seconds",
@@ -102,11 +117,21 @@ var (
 		},
 		[]string{},
 	)
+	cooldownIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cx_cooldown_ips_v2",
+			Help:        "Count of IPs in Cooldown State",
+			ConstLabels: prometheus.Labels{customerMetricLabel: customerMetricLabelValue},
+		},
+		[]string{},
+	)
 )
 
 func init() {
 	metrics.Registry.MustRegister(
 		HTTPRequestLatency,
+		ipRequestQueueDepth,
+		ipRequestQueueWaitTime,
 		ipAssignmentLatency,
 		ipConfigStatusStateTransitionTime,
 		syncHostNCVersionCount,
@@ -116,6 +141,7 @@ func init() {
 		availableIPCount,
 		pendingProgrammingIPCount,
 		pendingReleaseIPCount,
+		cooldownIPCount,
 	)
 }
 
@@ -149,4 +175,5 @@ func publishIPStateMetrics(state *ipState) {
 	availableIPCount.WithLabelValues(labels...).Set(float64(state.availableIPs))
 	pendingProgrammingIPCount.WithLabelValues(labels...).Set(float64(state.programmingIPs))
 	pendingReleaseIPCount.WithLabelValues(labels...).Set(float64(state.releasingIPs))
+	cooldownIPCount.WithLabelValues(labels...).Set(float64(state.cooldownIPs))
 }
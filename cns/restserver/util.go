@@ -47,6 +47,12 @@ func (service *HTTPRestService) removeNetworkInfo(networkName string) {
 	delete(service.state.Networks, networkName)
 }
 
+func init() {
+	store.RegisterSchema(storeKey, store.EnvelopeMigration)
+	store.RegisterSchema(EndpointStoreKey, store.EnvelopeMigration)
+	store.RegisterSchema(IPLeaseStoreKey, store.EnvelopeMigration)
+}
+
 // saveState writes CNS state to persistent store.
 func (service *HTTPRestService) saveState() error {
 	// Skip if a store is not provided.
@@ -57,7 +63,7 @@ func (service *HTTPRestService) saveState() error {
 
 	// Update time stamp.
 	service.state.TimeStamp = time.Now()
-	err := service.store.Write(storeKey, &service.state)
+	err := store.WriteVersioned(service.store, storeKey, &service.state)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to save state, err: %v", err)
 	}
@@ -76,7 +82,7 @@ func (service *HTTPRestService) restoreState() {
 	}
 
 	// Read any persisted state.
-	err := service.store.Read(storeKey, &service.state)
+	err := store.ReadVersioned(service.store, storeKey, &service.state)
 	if err != nil {
 		if err == store.ErrKeyNotFound {
 			// Nothing to restore.
@@ -92,7 +98,7 @@ func (service *HTTPRestService) restoreState() {
 	logger.Printf("[Azure CNS]  Restored state, %+v\n", service.state)
 
 	if service.Options[acn.OptManageEndpointState] == true {
-		err := service.EndpointStateStore.Read(EndpointStoreKey, &service.EndpointState)
+		err := store.ReadVersioned(service.EndpointStateStore, EndpointStoreKey, &service.EndpointState)
 		if err != nil {
 			if errors.Is(err, store.ErrKeyNotFound) {
 				// Nothing to restore.
@@ -305,7 +311,13 @@ func (service *HTTPRestService) addIPConfigStateUntransacted(ncID string, hostVe
 			IPAddress: ipconfig.IPAddress,
 			PodInfo:   nil,
 		}
-		ipconfigStatus.WithStateMiddleware(stateTransitionMiddleware)
+		ipconfigStatus.WithStateMiddleware(stateTransitionMiddleware, service.ipRequestQueueMiddleware)
+		if service.ipIndex != nil {
+			ipconfigStatus.WithStateMiddleware(service.ipIndex.stateMiddleware)
+		}
+		if service.ipStateEvents != nil {
+			ipconfigStatus.WithStateMiddleware(service.ipStateEventsMiddleware)
+		}
 		ipconfigStatus.SetState(newIPCNSStatus)
 		logger.Printf("[Azure-Cns] Add IP %s as %s", ipconfig.IPAddress, newIPCNSStatus)
 
@@ -348,6 +360,7 @@ func (service *HTTPRestService) removeToBeDeletedIPStateUntransacted(
 		ipID,
 		service.PodIPConfigState[ipID])
 	delete(service.PodIPConfigState, ipID)
+	service.ipIndex.remove(ipID)
 	return 0, ""
 }
 
@@ -27,6 +27,8 @@ func (s Server) Start(log *zap.Logger, addr string) {
 	e.GET(cns.PathDebugIPAddresses, echo.WrapHandler(http.HandlerFunc(s.HandleDebugIPAddresses)))
 	e.GET(cns.PathDebugPodContext, echo.WrapHandler(http.HandlerFunc(s.HandleDebugPodContext)))
 	e.GET(cns.PathDebugRestData, echo.WrapHandler(http.HandlerFunc(s.HandleDebugRestData)))
+	e.GET(cns.PathDebugIPLeases, echo.WrapHandler(http.HandlerFunc(s.HandleDebugIPLeases)))
+	e.GET(cns.PathIPStateEvents, echo.WrapHandler(http.HandlerFunc(s.HandleIPStateEvents)))
 	e.GET(cns.GetNetworkContainerByOrchestratorContext, echo.WrapHandler(http.HandlerFunc(s.GetNetworkContainerByOrchestratorContext)))
 	e.GET(cns.GetAllNetworkContainers, echo.WrapHandler(http.HandlerFunc(s.GetAllNetworkContainers)))
 	e.GET(cns.CreateHostNCApipaEndpointPath, echo.WrapHandler(http.HandlerFunc(s.CreateHostNCApipaEndpoint)))
@@ -6,10 +6,12 @@ package cns
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/cns/common"
@@ -34,6 +36,8 @@ type Service struct {
 	*common.Service
 	EndpointType string
 	Listener     *acn.Listener
+	// TLSConfig is the config of the HTTPS listener, nil if it is not enabled.
+	TLSConfig *tls.Config
 }
 
 // NewService creates a new Service object.
@@ -80,6 +84,10 @@ func (service *Service) Initialize(config *common.ServiceConfig) error {
 			return err
 		}
 
+		if config.Middleware != nil {
+			listener.Use(config.Middleware)
+		}
+
 		if config.TlsSettings.TLSPort != "" {
 			// listener.URL.Host will always be hostname:port, passed in to CNS via CNS command
 			// else it will default to localhost
@@ -97,6 +105,7 @@ func (service *Service) Initialize(config *common.ServiceConfig) error {
 			if err := listener.StartTLS(config.ErrChan, tlsConfig, tlsAddress); err != nil {
 				return err
 			}
+			service.TLSConfig = tlsConfig
 		}
 
 		logger.Printf("HTTP listener will be started later after CNS state has been reconciled")
@@ -110,15 +119,43 @@ func (service *Service) Initialize(config *common.ServiceConfig) error {
 }
 
 func getTLSConfig(tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
-	if tlsSettings.TLSCertificatePath != "" {
-		return getTLSConfigFromFile(tlsSettings)
+	var tlsConfig *tls.Config
+	var err error
+	switch {
+	case tlsSettings.TLSCertificatePath != "":
+		tlsConfig, err = getTLSConfigFromFile(tlsSettings)
+	case tlsSettings.KeyVaultURL != "":
+		tlsConfig, err = getTLSConfigFromKeyVault(tlsSettings, errChan)
+	default:
+		return nil, errors.Errorf("invalid tls settings: %+v", tlsSettings)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	if tlsSettings.KeyVaultURL != "" {
-		return getTLSConfigFromKeyVault(tlsSettings, errChan)
+	if tlsSettings.TLSClientCAPath != "" {
+		if err := setClientAuth(tlsConfig, tlsSettings.TLSClientCAPath); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil, errors.Errorf("invalid tls settings: %+v", tlsSettings)
+	return tlsConfig, nil
+}
+
+// setClientAuth requires the clients to present a certificate issued by one of the CAs in the PEM file, so that they
+// can be authorized by their identity.
+func setClientAuth(tlsConfig *tls.Config, caPath string) error {
+	pem, err := os.ReadFile(caPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read client CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.Errorf("no certificates found in client CA file %s", caPath)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return nil
 }
 
 func getTLSConfigFromFile(tlsSettings localtls.TlsSettings) (*tls.Config, error) {
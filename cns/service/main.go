@@ -22,6 +22,7 @@ import (
 	"github.com/Azure/azure-container-networking/cnm/ipam"
 	"github.com/Azure/azure-container-networking/cnm/network"
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/authz"
 	cnsclient "github.com/Azure/azure-container-networking/cns/client"
 	cnscli "github.com/Azure/azure-container-networking/cns/cmd/cli"
 	"github.com/Azure/azure-container-networking/cns/cniconflist"
@@ -29,6 +30,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/configuration"
 	"github.com/Azure/azure-container-networking/cns/fsnotify"
+	grpcserver "github.com/Azure/azure-container-networking/cns/grpc/server"
 	"github.com/Azure/azure-container-networking/cns/healthserver"
 	"github.com/Azure/azure-container-networking/cns/hnsclient"
 	"github.com/Azure/azure-container-networking/cns/imds"
@@ -65,6 +67,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -457,6 +460,21 @@ func sendRegisterNodeRequest(ctx context.Context, httpClient httpDoer, httpRestS
 	return nil
 }
 
+// startGRPCServer serves the CNS gRPC API on the address, backed by the HTTPRestService, until the context is done.
+func startGRPCServer(ctx context.Context, httpRestService *restserver.HTTPRestService, address string, opts ...grpc.ServerOption) error {
+	l, err := grpcserver.Listen(address)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for gRPC")
+	}
+	s := grpcserver.New(httpRestService, opts...)
+	go func() {
+		if err := s.Serve(ctx, l); err != nil {
+			logger.Errorf("[Azure CNS] gRPC server stopped with err:%v", err)
+		}
+	}()
+	return nil
+}
+
 func startTelemetryService(ctx context.Context) {
 	var config aitelemetry.AIConfig
 
@@ -571,7 +589,9 @@ func main() {
 			DebugMode:                    ts.DebugMode,
 		}
 
-		if aiKey := cnsconfig.TelemetrySettings.AppInsightsInstrumentationKey; aiKey != "" {
+		if ts.TelemetryBackend == aitelemetry.BackendOTLP {
+			logger.InitOTLP(aiConfig, ts.OTLPEndpoint, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
+		} else if aiKey := cnsconfig.TelemetrySettings.AppInsightsInstrumentationKey; aiKey != "" {
 			logger.InitAIWithIKey(aiConfig, aiKey, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
 		} else {
 			logger.InitAI(aiConfig, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
@@ -624,6 +644,12 @@ func main() {
 		fmt.Printf("failed to create logger: %v", err)
 		os.Exit(1)
 	}
+	// also send the zap logs to the collector when telemetry is exported over OTLP
+	if th := logger.TelemetryHandle(); th != nil && cnsconfig.TelemetrySettings.TelemetryBackend == aitelemetry.BackendOTLP &&
+		!cnsconfig.TelemetrySettings.DisableTrace {
+		otlpCore := aitelemetry.NewOTLPCore(zconfig.Level, th)
+		z = z.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core { return zapcore.NewTee(core, otlpCore) }))
+	}
 
 	// start the healthz/readyz/metrics server
 	readyCh := make(chan interface{})
@@ -692,9 +718,21 @@ func main() {
 		return
 	}
 
+	// Report json store files that had to be recovered from a backup generation.
+	store.RecoveryHandler = func(fileName, backupFileName string, err error) {
+		logger.LogEvent(aitelemetry.Event{
+			EventName: logger.CnsStoreRecoveredEventStr,
+			Properties: map[string]string{
+				logger.StoreFileStr:  fileName,
+				logger.BackupFileStr: backupFileName,
+				logger.ErrorStr:      err.Error(),
+			},
+		})
+	}
+
 	// Create the key value store.
 	storeFileName := storeFileLocation + name + ".json"
-	config.Store, err = store.NewJsonFileStore(storeFileName, lockclient, nil)
+	config.Store, err = store.NewStore(cnsconfig.StoreBackend, storeFileName, lockclient, nil)
 	if err != nil {
 		logger.Errorf("Failed to create store file: %s, due to error %v\n", storeFileName, err)
 		return
@@ -718,7 +756,7 @@ func main() {
 		// Create the key value store.
 		storeFileName := endpointStorePath + endpointStoreName + ".json"
 		logger.Printf("EndpointStoreState path is %s", storeFileName)
-		endpointStateStore, err = store.NewJsonFileStore(storeFileName, endpointStoreLock, nil)
+		endpointStateStore, err = store.NewStore(cnsconfig.StoreBackend, storeFileName, endpointStoreLock, nil)
 		if err != nil {
 			logger.Errorf("Failed to create endpoint state store file: %s, due to error %v\n", storeFileName, err)
 			return
@@ -764,11 +802,13 @@ func main() {
 	}
 
 	logger.Printf("[Azure CNS] Initialize HTTPRestService")
+	var authorizer *authz.Authorizer
 	if httpRestService != nil {
 		if cnsconfig.UseHTTPS {
 			config.TlsSettings = localtls.TlsSettings{
 				TLSSubjectName:                     cnsconfig.TLSSubjectName,
 				TLSCertificatePath:                 cnsconfig.TLSCertificatePath,
+				TLSClientCAPath:                    cnsconfig.TLSClientCAPath,
 				TLSPort:                            cnsconfig.TLSPort,
 				KeyVaultURL:                        cnsconfig.KeyVaultSettings.URL,
 				KeyVaultCertificateName:            cnsconfig.KeyVaultSettings.CertificateName,
@@ -777,6 +817,16 @@ func main() {
 			}
 		}
 
+		if cnsconfig.AuthorizationSettings.Enabled {
+			policy, err := authz.LoadPolicy(cnsconfig.AuthorizationSettings.PolicyFilePath)
+			if err != nil {
+				logger.Errorf("Failed to load the authorization policy, err:%v.\n", err)
+				return
+			}
+			authorizer = authz.New(policy)
+			config.Middleware = authorizer.Middleware
+		}
+
 		err = httpRestService.Init(&config)
 		if err != nil {
 			logger.Errorf("Failed to init HTTPService, err:%v.\n", err)
@@ -872,6 +922,18 @@ func main() {
 		}
 	}
 
+	if httpRestService != nil && cnsconfig.GRPCSettings.Enabled {
+		logger.Printf("[Azure CNS] Start gRPC listener")
+		var opts []grpc.ServerOption
+		if authorizer != nil {
+			opts = authorizer.ServerOptions(httpRestService.TLSConfig)
+		}
+		if err = startGRPCServer(rootCtx, httpRestService, cnsconfig.GRPCSettings.Address, opts...); err != nil {
+			logger.Errorf("Failed to start CNS gRPC API, err:%v.\n", err)
+			return
+		}
+	}
+
 	if cnsconfig.EnableAsyncPodDelete {
 		// Start fs watcher here
 		cnsclient, err := cnsclient.New("", cnsReqTimeout) //nolint
@@ -1194,6 +1256,15 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	}
 	httpRestServiceImplementation.SetNodeOrchestrator(&orchestrator)
 
+	if cnsconfig.IPRequestQueueSettings.Enabled {
+		httpRestServiceImplementation.EnableIPRequestQueue(cnsconfig.IPRequestQueueSettings.MaxDepth,
+			time.Duration(cnsconfig.IPRequestQueueSettings.MaxWaitMs)*time.Millisecond)
+	}
+
+	if cnsconfig.IPCooldownSecs > 0 {
+		httpRestServiceImplementation.EnableIPCooldown(time.Duration(cnsconfig.IPCooldownSecs) * time.Second)
+	}
+
 	// build default clientset.
 	kubeConfig, err := ctrl.GetConfig()
 	if err != nil {
@@ -1233,7 +1304,7 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 			return errors.Wrap(err, "failed to create CNS EndpointState From CNI")
 		}
 		// endpoint state needs tobe loaded in memory so the subsequent Delete calls remove the state and release the IPs.
-		if err = httpRestServiceImplementation.EndpointStateStore.Read(restserver.EndpointStoreKey, &httpRestServiceImplementation.EndpointState); err != nil {
+		if err = store.ReadVersioned(httpRestServiceImplementation.EndpointStateStore, restserver.EndpointStoreKey, &httpRestServiceImplementation.EndpointState); err != nil {
 			return errors.Wrap(err, "failed to restore endpoint state")
 		}
 	}
@@ -1332,7 +1403,8 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		},
 	}
 
-	if cnsconfig.WatchPods {
+	// the sticky IP leases read the annotation of the Pods on this node
+	if cnsconfig.WatchPods || cnsconfig.StickyIPLeaseSettings.Enabled {
 		cacheOpts.ByObject[&corev1.Pod{}] = cache.ByObject{
 			Field: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}),
 		}
@@ -1364,7 +1436,17 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	ipDemandCh := make(chan int)
 	if cnsconfig.EnableIPAMv2 {
 		nncCh := make(chan v1alpha.NodeNetworkConfig)
-		poolMonitor = ipampoolv2.NewMonitor(z, httpRestServiceImplementation, cachedscopedcli, ipDemandCh, nncCh, cssCh).AsV1(nncCh)
+		v2Monitor := ipampoolv2.NewMonitor(z, httpRestServiceImplementation, cachedscopedcli, ipDemandCh, nncCh, cssCh)
+		if predictor := cnsconfig.IPAMv2PredictorSettings; predictor.Enabled {
+			// unset values take the predictor defaults
+			v2Monitor.WithPredictor(ipampoolv2.PredictorOptions{
+				Window:         time.Duration(predictor.WindowSecs) * time.Second,
+				Resolution:     time.Duration(predictor.ResolutionSecs) * time.Second,
+				Lead:           time.Duration(predictor.LeadSecs) * time.Second,
+				MinCorrelation: predictor.MinCorrelation,
+			})
+		}
+		poolMonitor = v2Monitor.AsV1(nncCh)
 	} else {
 		poolOpts := ipampool.Options{
 			RefreshDelay: poolIPAMRefreshRateInMilliseconds * time.Millisecond,
@@ -1423,6 +1505,13 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		httpRestService.AttachIPConfigsHandlerMiddleware(swiftV2Middleware)
 	}
 
+	if cnsconfig.StickyIPLeaseSettings.Enabled {
+		if err := httpRestServiceImplementation.EnableIPLeases(manager.GetClient(),
+			time.Duration(cnsconfig.StickyIPLeaseSettings.TTLSecs)*time.Second); err != nil {
+			return errors.Wrap(err, "failed to enable sticky IP leases")
+		}
+	}
+
 	// start the pool Monitor before the Reconciler, since it needs to be ready to receive an
 	// NodeNetworkConfig update by the time the Reconciler tries to send it.
 	go func() {
@@ -1484,6 +1573,23 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		}
 	}()
 	logger.Printf("Initialized SyncHostNCVersion loop.")
+
+	if cnsconfig.IPCooldownSecs > 0 {
+		go func() {
+			logger.Printf("Starting IP cooldown expiry loop.")
+			// Periodically make the IPs which have finished their cooldown Available
+			tickerChannel := time.Tick(time.Second)
+			for {
+				select {
+				case <-tickerChannel:
+					httpRestServiceImplementation.ExpireIPCooldowns()
+				case <-ctx.Done():
+					logger.Printf("Stopping IP cooldown expiry loop.")
+					return
+				}
+			}
+		}()
+	}
 	return nil
 }
 
@@ -1532,7 +1638,7 @@ func PopulateCNSEndpointState(endpointStateStore store.KeyValueStore) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to create CNS Endpoint state from CNI")
 	}
-	err = endpointStateStore.Write(restserver.EndpointStoreKey, endpointState)
+	err = store.WriteVersioned(endpointStateStore, restserver.EndpointStoreKey, endpointState)
 	if err != nil {
 		return fmt.Errorf("failed to write endpoint state to store: %w", err)
 	}
@@ -13,4 +13,6 @@ const (
 	PendingRelease IPState = "PendingRelease"
 	// PendingProgramming IPConfigState for allocated IPs pending programming.
 	PendingProgramming IPState = "PendingProgramming"
+	// Cooldown IPConfigState for allocated IPs released by Pods which are not reassigned until the cooldown has passed.
+	Cooldown IPState = "Cooldown"
 )
@@ -68,6 +68,14 @@ const (
 	OptHelp      = "help"
 	OptHelpAlias = "h"
 
+	// Dump persisted state.
+	OptDumpState      = "dump-state"
+	OptDumpStateAlias = "ds"
+
+	// Schema version of dumped state.
+	OptTargetVersion      = "target-version"
+	OptTargetVersionAlias = "tv"
+
 	// CNI binary location
 	OptNetPluginPath      = "net-plugin-path"
 	OptNetPluginPathAlias = "np"
@@ -4,6 +4,7 @@
 package common
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"net"
@@ -25,8 +26,11 @@ type Listener struct {
 	listener     net.Listener
 	tlsListener  net.Listener
 	mux          *http.ServeMux
+	handler      http.Handler
 }
 
+type connContextKey struct{}
+
 // NewListener creates a new Listener.
 func NewListener(u *url.URL) (*Listener, error) {
 	listener := Listener{
@@ -36,16 +40,36 @@ func NewListener(u *url.URL) (*Listener, error) {
 	}
 
 	listener.mux = http.NewServeMux()
+	listener.handler = listener.mux
 
 	return &listener, nil
 }
 
+// Use wraps the handler of the listener in a middleware, which sees every request before the mux.
+// It must be called before the listener is started.
+func (l *Listener) Use(middleware func(http.Handler) http.Handler) {
+	l.handler = middleware(l.handler)
+}
+
+// ConnFromContext returns the connection a request was received on, from the context of the request.
+func ConnFromContext(ctx context.Context) (net.Conn, bool) {
+	conn, ok := ctx.Value(connContextKey{}).(net.Conn)
+	return conn, ok
+}
+
+func (l *Listener) server() *http.Server {
+	return &http.Server{
+		Handler: l.handler,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, connContextKey{}, conn)
+		},
+	}
+}
+
 // StartTLS creates the listener socket and starts the HTTPS server.
 func (l *Listener) StartTLS(errChan chan<- error, tlsConfig *tls.Config, address string) error {
-	server := http.Server{
-		TLSConfig: tlsConfig,
-		Handler:   l.mux,
-	}
+	server := l.server()
+	server.TLSConfig = tlsConfig
 
 	// listen on a separate endpoint for secure tls connections
 	list, err := net.Listen(l.protocol, address)
@@ -79,7 +103,7 @@ func (l *Listener) Start(errChan chan<- error) error {
 
 	// Launch goroutine for servicing requests.
 	go func() {
-		errChan <- http.Serve(l.listener, l.mux)
+		errChan <- l.server().Serve(l.listener)
 	}()
 
 	l.active = true
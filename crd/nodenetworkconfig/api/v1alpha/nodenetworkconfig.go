@@ -46,6 +46,19 @@ type NodeNetworkConfigSpec struct {
 	// +kubebuilder:validation:Optional
 	RequestedIPCount int64    `json:"requestedIPCount"`
 	IPsNotInUse      []string `json:"ipsNotInUse,omitempty"`
+	// ExcludedIPs are IPs, or CIDRs of IPs, which CNS never assigns to Pods.
+	// +kubebuilder:validation:Optional
+	ExcludedIPs []string `json:"excludedIPs,omitempty"`
+	// IPReservations are IPs which CNS assigns only to the given Pods.
+	// +kubebuilder:validation:Optional
+	IPReservations []IPReservation `json:"ipReservations,omitempty"`
+}
+
+// IPReservation reserves an IP for a Pod.
+type IPReservation struct {
+	IP           string `json:"ip"`
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
 }
 
 // Status indicates the NNC reconcile status
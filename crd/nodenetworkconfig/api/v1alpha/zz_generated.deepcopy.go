@@ -23,6 +23,21 @@ func (in *IPAssignment) DeepCopy() *IPAssignment {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservation) DeepCopyInto(out *IPReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservation.
+func (in *IPReservation) DeepCopy() *IPReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkContainer) DeepCopyInto(out *NetworkContainer) {
 	*out = *in
@@ -110,6 +125,16 @@ func (in *NodeNetworkConfigSpec) DeepCopyInto(out *NodeNetworkConfigSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludedIPs != nil {
+		in, out := &in.ExcludedIPs, &out.ExcludedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPReservations != nil {
+		in, out := &in.IPReservations, &out.IPReservations
+		*out = make([]IPReservation, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigSpec.
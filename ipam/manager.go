@@ -18,6 +18,10 @@ const (
 	storeKey = "IPAM"
 )
 
+func init() {
+	store.RegisterSchema(storeKey, store.EnvelopeMigration)
+}
+
 // AddressManager manages the set of address spaces and pools allocated to containers.
 type addressManager struct {
 	Version    string
@@ -101,7 +105,7 @@ func (am *addressManager) restore(rehydrateIpamInfoOnReboot bool) error {
 	}
 
 	// Read any persisted state.
-	err := am.store.Read(storeKey, am)
+	err := store.ReadVersioned(am.store, storeKey, am)
 	if err != nil {
 		if err == store.ErrKeyNotFound {
 			logger.Info("store key not found")
@@ -171,7 +175,7 @@ func (am *addressManager) save() error {
 	am.TimeStamp = time.Now()
 
 	logger.Info("saving ipam state")
-	err := am.store.Write(storeKey, am)
+	err := store.WriteVersioned(am.store, storeKey, am)
 	if err == nil {
 		logger.Info("Save succeeded")
 	} else {
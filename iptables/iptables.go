@@ -7,6 +7,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/cni/log"
 	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +43,8 @@ const (
 	Accept     = "ACCEPT"
 	Drop       = "DROP"
 	Masquerade = "MASQUERADE"
+	Dnat       = "DNAT"
+	Mark       = "MARK"
 )
 
 // actions
@@ -80,23 +83,57 @@ const (
 	TCP = "tcp"
 )
 
+// iptables backends
+const (
+	BackendLegacy = "legacy"
+	BackendNft    = "nft"
+)
+
 var DisableIPTableLock bool
 
+// Backend selects how clients created by NewClient program rules.
+// The legacy backend runs the iptables binaries, the nft backend programs nftables over netlink.
+var Backend = BackendLegacy
+
+var errUnsupportedBackend = errors.New("unsupported iptables backend")
+
 type IPTableEntry struct {
 	Version string
 	Params  string
 }
 
-type Client struct{}
+type Client struct {
+	backend string
+}
 
 func NewClient() *Client {
 	return &Client{}
 }
 
+// NewClientWithBackend creates a client that always uses the given backend, regardless of Backend.
+func NewClientWithBackend(backend string) *Client {
+	return &Client{backend: backend}
+}
+
+func (c *Client) getBackend() string {
+	if c.backend != "" {
+		return c.backend
+	}
+	return Backend
+}
+
 // Run iptables command
 func (c *Client) RunCmd(version, params string) error {
 	var cmd string
 
+	switch c.getBackend() {
+	case BackendNft:
+		return runNftCmd(version, params)
+	case BackendLegacy, "":
+	default:
+		return errors.Wrapf(errUnsupportedBackend, "%s", c.getBackend())
+	}
+
 	p := platform.NewExecClient(logger)
 	iptCmd := iptables
 	if version == V6 {
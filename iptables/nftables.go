@@ -0,0 +1,408 @@
+package iptables
+
+// This file parses the iptables parameters accepted by Client into a backend neutral form,
+// so that they can be programmed through nftables instead of the iptables binaries.
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errNftUnsupportedOption = errors.New("iptables option not supported by the nftables backend")
+	errNftInvalidCommand    = errors.New("invalid iptables command")
+	errNftRuleNotFound      = errors.New("nftables rule does not exist")
+)
+
+// nftOp is the iptables command being translated.
+type nftOp int
+
+const (
+	nftOpAppend nftOp = iota
+	nftOpInsert
+	nftOpCheck
+	nftOpDelete
+	nftOpNewChain
+	nftOpList
+)
+
+// nftCommand is a parsed iptables command line.
+type nftCommand struct {
+	op       nftOp
+	table    string
+	chain    string
+	position int
+	rule     *ruleSpec
+}
+
+// ruleSpec holds the matches and target of a rule in the iptables syntax subset used by CNI.
+type ruleSpec struct {
+	src, dst             *net.IPNet
+	srcInvert, dstInvert bool
+	inIface, outIface    string
+	inInvert, outInvert  bool
+	protocol             string
+	sport, dport         string
+	sportInv, dportInv   bool
+	srcType, dstType     string
+	srcTypeInv           bool
+	dstTypeInv           bool
+	ctStates             []string
+	hasMark              bool
+	markInvert           bool
+	mark, markMask       uint32
+	comment              string
+	target               string
+	toSource             string
+	toDestination        string
+	hasSetMark           bool
+	setMarkXor           bool
+	setMark, setMarkMask uint32
+}
+
+// splitParams splits an iptables parameter string into arguments, honouring double quotes.
+func splitParams(params string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  bool
+		inArg   bool
+	)
+
+	for _, r := range params {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quoted {
+		return nil, errors.Wrapf(errNftInvalidCommand, "unterminated quote in %q", params)
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// parseNftCommand parses the parameters of an iptables invocation.
+func parseNftCommand(params string) (*nftCommand, error) {
+	args, err := splitParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &nftCommand{table: Filter, op: -1}
+	var ruleArgs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", errors.Wrapf(errNftInvalidCommand, "option %s requires a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var op nftOp
+		switch arg {
+		case "-t", "--table":
+			if cmd.table, err = next(); err != nil {
+				return nil, err
+			}
+			continue
+		case "-w", "--wait":
+			// The lock timeout only applies to the iptables binaries.
+			if i+1 < len(args) {
+				if _, convErr := strconv.Atoi(args[i+1]); convErr == nil {
+					i++
+				}
+			}
+			continue
+		case "-n", "--numeric":
+			continue
+		case "-A", "--append":
+			op = nftOpAppend
+		case "-I", "--insert":
+			op = nftOpInsert
+		case "-C", "--check":
+			op = nftOpCheck
+		case "-D", "--delete":
+			op = nftOpDelete
+		case "-N", "--new-chain":
+			op = nftOpNewChain
+		case "-L", "--list", "-nL":
+			op = nftOpList
+		default:
+			ruleArgs = append(ruleArgs, arg)
+			continue
+		}
+
+		if cmd.op != -1 {
+			return nil, errors.Wrapf(errNftInvalidCommand, "more than one command in %q", params)
+		}
+
+		cmd.op = op
+		if cmd.chain, err = next(); err != nil {
+			return nil, err
+		}
+
+		if op == nftOpInsert {
+			cmd.position = 1
+			if i+1 < len(args) {
+				if pos, convErr := strconv.Atoi(args[i+1]); convErr == nil {
+					cmd.position = pos
+					i++
+				}
+			}
+		}
+	}
+
+	switch cmd.op {
+	case -1:
+		return nil, errors.Wrapf(errNftInvalidCommand, "no command in %q", params)
+	case nftOpNewChain, nftOpList:
+		if len(ruleArgs) != 0 {
+			return nil, errors.Wrapf(errNftInvalidCommand, "unexpected arguments %v", ruleArgs)
+		}
+		return cmd, nil
+	}
+
+	if cmd.rule, err = parseRuleSpec(ruleArgs); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// parseRuleSpec parses the match and target arguments of a rule.
+func parseRuleSpec(args []string) (*ruleSpec, error) {
+	rule := &ruleSpec{}
+	invert := false
+	inTarget := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", errors.Wrapf(errNftInvalidCommand, "option %s requires a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		if arg == "!" {
+			invert = true
+			continue
+		}
+
+		// Every supported option takes exactly one value.
+		var value string
+		var err error
+		if strings.HasPrefix(arg, "-") {
+			if value, err = next(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case arg == "-s" || arg == "--source":
+			rule.src, err = parseAddress(value)
+			rule.srcInvert = invert
+		case arg == "-d" || arg == "--destination":
+			rule.dst, err = parseAddress(value)
+			rule.dstInvert = invert
+		case arg == "-i" || arg == "--in-interface":
+			rule.inIface, rule.inInvert = value, invert
+		case arg == "-o" || arg == "--out-interface":
+			rule.outIface, rule.outInvert = value, invert
+		case arg == "-p" || arg == "--protocol":
+			if invert {
+				return nil, errors.Wrapf(errNftUnsupportedOption, "! %s", arg)
+			}
+			rule.protocol = strings.ToLower(value)
+		case arg == "-m" || arg == "--match":
+			switch value {
+			case "tcp", "udp", "addrtype", "state", "conntrack", "mark", "comment":
+			default:
+				return nil, errors.Wrapf(errNftUnsupportedOption, "-m %s", value)
+			}
+		case arg == "--dport" || arg == "--destination-port":
+			rule.dport, rule.dportInv = value, invert
+		case arg == "--sport" || arg == "--source-port":
+			rule.sport, rule.sportInv = value, invert
+		case arg == "--dst-type":
+			rule.dstType, rule.dstTypeInv = strings.ToUpper(value), invert
+		case arg == "--src-type":
+			rule.srcType, rule.srcTypeInv = strings.ToUpper(value), invert
+		case arg == "--state" || arg == "--ctstate":
+			if invert {
+				return nil, errors.Wrapf(errNftUnsupportedOption, "! %s", arg)
+			}
+			rule.ctStates = strings.Split(strings.ToUpper(value), ",")
+		case arg == "--mark" && !inTarget:
+			rule.hasMark, rule.markInvert = true, invert
+			rule.mark, rule.markMask, err = parseMark(value)
+		case arg == "--comment":
+			rule.comment = value
+		case arg == "-j" || arg == "--jump":
+			rule.target = value
+			inTarget = true
+		case inTarget && (arg == "--to" || arg == "--to-source") && rule.target == Snat:
+			rule.toSource = value
+		case inTarget && (arg == "--to" || arg == "--to-destination") && rule.target == Dnat:
+			rule.toDestination = value
+		case inTarget && (arg == "--set-mark" || arg == "--set-xmark") && rule.target == Mark:
+			rule.hasSetMark, rule.setMarkXor = true, arg == "--set-xmark"
+			rule.setMark, rule.setMarkMask, err = parseMark(value)
+		default:
+			return nil, errors.Wrapf(errNftUnsupportedOption, "%s", arg)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		invert = false
+	}
+
+	if rule.target == "" {
+		return nil, errors.Wrap(errNftInvalidCommand, "rule has no target")
+	}
+
+	if (rule.sport != "" || rule.dport != "") && rule.protocol != TCP && rule.protocol != UDP {
+		return nil, errors.Wrap(errNftInvalidCommand, "port match requires -p tcp or -p udp")
+	}
+
+	return rule, nil
+}
+
+// parseAddress parses an address or prefix in iptables notation.
+func parseAddress(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		ip, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.Wrapf(errNftInvalidCommand, "invalid prefix %s", value)
+		}
+		if ip.To4() != nil {
+			ipNet.IP = ipNet.IP.To4()
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, errors.Wrapf(errNftInvalidCommand, "invalid address %s", value)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// parseMark parses a mark in value[/mask] notation.
+func parseMark(value string) (mark, mask uint32, err error) {
+	mask = ^uint32(0)
+	parts := strings.SplitN(value, "/", 2)
+
+	v, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(errNftInvalidCommand, "invalid mark %s", value)
+	}
+
+	if len(parts) == 2 {
+		m, err := strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, 0, errors.Wrapf(errNftInvalidCommand, "invalid mark mask %s", value)
+		}
+		mask = uint32(m)
+	}
+
+	return uint32(v), mask, nil
+}
+
+// String returns the canonical iptables form of the rule. Equivalent rules written with a
+// different option order produce the same string, which is used to identify programmed rules.
+func (r *ruleSpec) String() string {
+	var parts []string
+	add := func(invert bool, s ...string) {
+		if invert {
+			parts = append(parts, "!")
+		}
+		parts = append(parts, s...)
+	}
+
+	if r.src != nil {
+		add(r.srcInvert, "-s", r.src.String())
+	}
+	if r.dst != nil {
+		add(r.dstInvert, "-d", r.dst.String())
+	}
+	if r.inIface != "" {
+		add(r.inInvert, "-i", r.inIface)
+	}
+	if r.outIface != "" {
+		add(r.outInvert, "-o", r.outIface)
+	}
+	if r.protocol != "" {
+		add(false, "-p", r.protocol)
+	}
+	if r.sport != "" {
+		add(r.sportInv, "--sport", r.sport)
+	}
+	if r.dport != "" {
+		add(r.dportInv, "--dport", r.dport)
+	}
+	if r.srcType != "" {
+		add(r.srcTypeInv, "--src-type", r.srcType)
+	}
+	if r.dstType != "" {
+		add(r.dstTypeInv, "--dst-type", r.dstType)
+	}
+	if len(r.ctStates) != 0 {
+		add(false, "--ctstate", strings.Join(r.ctStates, ","))
+	}
+	if r.hasMark {
+		add(r.markInvert, "--mark", fmt.Sprintf("0x%x/0x%x", r.mark, r.markMask))
+	}
+	if r.comment != "" {
+		add(false, "--comment", strconv.Quote(r.comment))
+	}
+
+	add(false, "-j", r.target)
+
+	if r.toSource != "" {
+		add(false, "--to-source", r.toSource)
+	}
+	if r.toDestination != "" {
+		add(false, "--to-destination", r.toDestination)
+	}
+	if r.hasSetMark {
+		option := "--set-mark"
+		if r.setMarkXor {
+			option = "--set-xmark"
+		}
+		add(false, option, fmt.Sprintf("0x%x/0x%x", r.setMark, r.setMarkMask))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,648 @@
+//go:build linux
+// +build linux
+
+package iptables
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Netfilter verdicts and conntrack state bits that are not defined in the unix package.
+const (
+	nfDrop   = 0
+	nfAccept = 1
+
+	ctStateInvalid     = 1
+	ctStateEstablished = 2
+	ctStateRelated     = 4
+	ctStateNew         = 8
+	ctStateUntracked   = 64
+)
+
+// Address types matched by -m addrtype, as reported by the fib expression.
+var addrTypes = map[string]uint32{
+	"UNSPEC":      unix.RTN_UNSPEC,
+	"UNICAST":     unix.RTN_UNICAST,
+	"LOCAL":       unix.RTN_LOCAL,
+	"BROADCAST":   unix.RTN_BROADCAST,
+	"ANYCAST":     unix.RTN_ANYCAST,
+	"MULTICAST":   unix.RTN_MULTICAST,
+	"BLACKHOLE":   unix.RTN_BLACKHOLE,
+	"UNREACHABLE": unix.RTN_UNREACHABLE,
+	"PROHIBIT":    unix.RTN_PROHIBIT,
+}
+
+var ctStates = map[string]uint32{
+	"INVALID":     ctStateInvalid,
+	"ESTABLISHED": ctStateEstablished,
+	"RELATED":     ctStateRelated,
+	"NEW":         ctStateNew,
+	"UNTRACKED":   ctStateUntracked,
+}
+
+// Target extensions that have no native translation.
+var unsupportedTargets = map[string]bool{
+	"LOG":         true,
+	"NFLOG":       true,
+	"REJECT":      true,
+	"REDIRECT":    true,
+	"CONNMARK":    true,
+	"CT":          true,
+	"NOTRACK":     true,
+	"TCPMSS":      true,
+	"TPROXY":      true,
+	"CLASSIFY":    true,
+	"CHECKSUM":    true,
+	"NETMAP":      true,
+	"DSCP":        true,
+	"TOS":         true,
+	"TTL":         true,
+	"HMARK":       true,
+	"AUDIT":       true,
+	"TRACE":       true,
+	"SECMARK":     true,
+	"CONNSECMARK": true,
+}
+
+var l4Protocols = map[string]byte{
+	"icmp":   unix.IPPROTO_ICMP,
+	TCP:      unix.IPPROTO_TCP,
+	UDP:      unix.IPPROTO_UDP,
+	"icmpv6": unix.IPPROTO_ICMPV6,
+}
+
+// nftExpr is a single nftables expression of a rule.
+// String returns the expression in the format printed by nft --debug=netlink.
+type nftExpr interface {
+	name() string
+	data() []byte
+	String() string
+}
+
+// Netlink attribute helpers. Attribute headers use host byte order, nftables values use network byte order.
+
+func nlAttr(attrType uint16, value []byte) []byte {
+	l := unix.SizeofNlAttr + len(value)
+	b := make([]byte, (l+unix.NLA_ALIGNTO-1) & ^(unix.NLA_ALIGNTO-1))
+	binary.NativeEndian.PutUint16(b[0:2], uint16(l))
+	binary.NativeEndian.PutUint16(b[2:4], attrType)
+	copy(b[unix.SizeofNlAttr:], value)
+	return b
+}
+
+func nlNested(attrType uint16, children ...[]byte) []byte {
+	return nlAttr(attrType|unix.NLA_F_NESTED, bytes.Join(children, nil))
+}
+
+func nlString(attrType uint16, value string) []byte {
+	return nlAttr(attrType, append([]byte(value), 0))
+}
+
+func nlUint32(attrType uint16, value uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, value)
+	return nlAttr(attrType, b)
+}
+
+func nlUint64(attrType uint16, value uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, value)
+	return nlAttr(attrType, b)
+}
+
+func nativeUint32(value uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, value)
+	return b
+}
+
+// formatData prints register data as 32 bit words, the way nft does.
+func formatData(b []byte) string {
+	words := make([]string, 0, (len(b)+3)/4)
+	for i := 0; i < len(b); i += 4 {
+		word := make([]byte, 4)
+		copy(word, b[i:])
+		words = append(words, fmt.Sprintf("0x%08x", binary.NativeEndian.Uint32(word)))
+	}
+	return strings.Join(words, " ")
+}
+
+type payloadExpr struct {
+	base   uint32
+	offset uint32
+	len    uint32
+}
+
+func (payloadExpr) name() string { return "payload" }
+
+func (e payloadExpr) data() []byte {
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_PAYLOAD_DREG, unix.NFT_REG_1),
+		nlUint32(unix.NFTA_PAYLOAD_BASE, e.base),
+		nlUint32(unix.NFTA_PAYLOAD_OFFSET, e.offset),
+		nlUint32(unix.NFTA_PAYLOAD_LEN, e.len),
+	}, nil)
+}
+
+func (e payloadExpr) String() string {
+	base := "network"
+	if e.base == unix.NFT_PAYLOAD_TRANSPORT_HEADER {
+		base = "transport"
+	}
+	return fmt.Sprintf("[ payload load %db @ %s header + %d => reg 1 ]", e.len, base, e.offset)
+}
+
+type metaExpr struct {
+	key uint32
+	set bool
+}
+
+var metaKeys = map[uint32]string{
+	unix.NFT_META_MARK:    "mark",
+	unix.NFT_META_IIFNAME: "iifname",
+	unix.NFT_META_OIFNAME: "oifname",
+	unix.NFT_META_L4PROTO: "l4proto",
+}
+
+func (metaExpr) name() string { return "meta" }
+
+func (e metaExpr) data() []byte {
+	reg := uint16(unix.NFTA_META_DREG)
+	if e.set {
+		reg = unix.NFTA_META_SREG
+	}
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_META_KEY, e.key),
+		nlUint32(reg, unix.NFT_REG_1),
+	}, nil)
+}
+
+func (e metaExpr) String() string {
+	if e.set {
+		return fmt.Sprintf("[ meta set %s with reg 1 ]", metaKeys[e.key])
+	}
+	return fmt.Sprintf("[ meta load %s => reg 1 ]", metaKeys[e.key])
+}
+
+type cmpExpr struct {
+	op    uint32
+	value []byte
+}
+
+var cmpOps = map[uint32]string{
+	unix.NFT_CMP_EQ:  "eq",
+	unix.NFT_CMP_NEQ: "neq",
+	unix.NFT_CMP_LT:  "lt",
+	unix.NFT_CMP_LTE: "lte",
+	unix.NFT_CMP_GT:  "gt",
+	unix.NFT_CMP_GTE: "gte",
+}
+
+func (cmpExpr) name() string { return "cmp" }
+
+func (e cmpExpr) data() []byte {
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_CMP_SREG, unix.NFT_REG_1),
+		nlUint32(unix.NFTA_CMP_OP, e.op),
+		nlNested(unix.NFTA_CMP_DATA, nlAttr(unix.NFTA_DATA_VALUE, e.value)),
+	}, nil)
+}
+
+func (e cmpExpr) String() string {
+	return fmt.Sprintf("[ cmp %s reg 1 %s ]", cmpOps[e.op], formatData(e.value))
+}
+
+type bitwiseExpr struct {
+	mask []byte
+	xor  []byte
+}
+
+func (bitwiseExpr) name() string { return "bitwise" }
+
+func (e bitwiseExpr) data() []byte {
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_BITWISE_SREG, unix.NFT_REG_1),
+		nlUint32(unix.NFTA_BITWISE_DREG, unix.NFT_REG_1),
+		nlUint32(unix.NFTA_BITWISE_LEN, uint32(len(e.mask))),
+		nlNested(unix.NFTA_BITWISE_MASK, nlAttr(unix.NFTA_DATA_VALUE, e.mask)),
+		nlNested(unix.NFTA_BITWISE_XOR, nlAttr(unix.NFTA_DATA_VALUE, e.xor)),
+	}, nil)
+}
+
+func (e bitwiseExpr) String() string {
+	return fmt.Sprintf("[ bitwise reg 1 = ( reg 1 & %s ) ^ %s ]", formatData(e.mask), formatData(e.xor))
+}
+
+type immediateExpr struct {
+	reg     uint32
+	value   []byte
+	verdict int32
+	chain   string
+}
+
+var verdicts = map[int32]string{
+	nfDrop:          "drop",
+	nfAccept:        "accept",
+	unix.NFT_RETURN: "return",
+	unix.NFT_JUMP:   "jump",
+}
+
+func (immediateExpr) name() string { return "immediate" }
+
+func (e immediateExpr) data() []byte {
+	var value []byte
+	if e.reg == unix.NFT_REG_VERDICT {
+		verdict := [][]byte{nlUint32(unix.NFTA_VERDICT_CODE, uint32(e.verdict))}
+		if e.chain != "" {
+			verdict = append(verdict, nlString(unix.NFTA_VERDICT_CHAIN, e.chain))
+		}
+		value = nlNested(unix.NFTA_DATA_VERDICT, verdict...)
+	} else {
+		value = nlAttr(unix.NFTA_DATA_VALUE, e.value)
+	}
+
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_IMMEDIATE_DREG, e.reg),
+		nlNested(unix.NFTA_IMMEDIATE_DATA, value),
+	}, nil)
+}
+
+func (e immediateExpr) String() string {
+	if e.reg != unix.NFT_REG_VERDICT {
+		return fmt.Sprintf("[ immediate reg %d %s ]", e.reg, formatData(e.value))
+	}
+	if e.chain != "" {
+		return fmt.Sprintf("[ immediate reg 0 %s -> %s ]", verdicts[e.verdict], e.chain)
+	}
+	return fmt.Sprintf("[ immediate reg 0 %s ]", verdicts[e.verdict])
+}
+
+type counterExpr struct{}
+
+func (counterExpr) name() string   { return "counter" }
+func (counterExpr) data() []byte   { return nil }
+func (counterExpr) String() string { return "[ counter pkts 0 bytes 0 ]" }
+
+type ctStateExpr struct{}
+
+func (ctStateExpr) name() string { return "ct" }
+
+func (ctStateExpr) data() []byte {
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_CT_KEY, unix.NFT_CT_STATE),
+		nlUint32(unix.NFTA_CT_DREG, unix.NFT_REG_1),
+	}, nil)
+}
+
+func (ctStateExpr) String() string { return "[ ct load state => reg 1 ]" }
+
+type fibExpr struct {
+	flags uint32
+}
+
+func (fibExpr) name() string { return "fib" }
+
+func (e fibExpr) data() []byte {
+	return bytes.Join([][]byte{
+		nlUint32(unix.NFTA_FIB_DREG, unix.NFT_REG_1),
+		nlUint32(unix.NFTA_FIB_RESULT, unix.NFT_FIB_RESULT_ADDRTYPE),
+		nlUint32(unix.NFTA_FIB_FLAGS, e.flags),
+	}, nil)
+}
+
+func (e fibExpr) String() string {
+	addr := "daddr"
+	if e.flags == unix.NFTA_FIB_F_SADDR {
+		addr = "saddr"
+	}
+	return fmt.Sprintf("[ fib %s type => reg 1 ]", addr)
+}
+
+type natExpr struct {
+	natType  uint32
+	family   uint32
+	withPort bool
+}
+
+func (natExpr) name() string { return "nat" }
+
+func (e natExpr) data() []byte {
+	attrs := [][]byte{
+		nlUint32(unix.NFTA_NAT_TYPE, e.natType),
+		nlUint32(unix.NFTA_NAT_FAMILY, e.family),
+		nlUint32(unix.NFTA_NAT_REG_ADDR_MIN, unix.NFT_REG_1),
+	}
+	if e.withPort {
+		attrs = append(attrs,
+			nlUint32(unix.NFTA_NAT_REG_PROTO_MIN, unix.NFT_REG_2),
+			nlUint32(unix.NFTA_NAT_FLAGS, unix.NF_NAT_RANGE_PROTO_SPECIFIED))
+	}
+	return bytes.Join(attrs, nil)
+}
+
+func (e natExpr) String() string {
+	natType, family := "snat", "ip"
+	if e.natType == unix.NFT_NAT_DNAT {
+		natType = "dnat"
+	}
+	if e.family == unix.NFPROTO_IPV6 {
+		family = "ip6"
+	}
+	if e.withPort {
+		return fmt.Sprintf("[ nat %s %s addr_min reg 1 proto_min reg 2 flags 0x%x ]",
+			natType, family, unix.NF_NAT_RANGE_PROTO_SPECIFIED)
+	}
+	return fmt.Sprintf("[ nat %s %s addr_min reg 1 ]", natType, family)
+}
+
+type masqExpr struct{}
+
+func (masqExpr) name() string   { return "masq" }
+func (masqExpr) data() []byte   { return nil }
+func (masqExpr) String() string { return "[ masq ]" }
+
+// encodeExprs encodes expressions as the value of the NFTA_RULE_EXPRESSIONS attribute.
+func encodeExprs(exprs []nftExpr) []byte {
+	elems := make([][]byte, 0, len(exprs))
+	for _, e := range exprs {
+		attrs := [][]byte{nlString(unix.NFTA_EXPR_NAME, e.name())}
+		if d := e.data(); d != nil {
+			attrs = append(attrs, nlNested(unix.NFTA_EXPR_DATA, d))
+		}
+		elems = append(elems, nlNested(unix.NFTA_LIST_ELEM, attrs...))
+	}
+	return nlNested(unix.NFTA_RULE_EXPRESSIONS, elems...)
+}
+
+// cmpOp returns the equality operator for an optionally inverted match.
+func cmpOp(invert bool) uint32 {
+	if invert {
+		return unix.NFT_CMP_NEQ
+	}
+	return unix.NFT_CMP_EQ
+}
+
+// addressExprs matches the source or destination address of the network header.
+func addressExprs(family byte, ipNet *net.IPNet, src, invert bool) ([]nftExpr, error) {
+	ip := ipNet.IP.To4()
+	offset := uint32(12)
+	if !src {
+		offset = 16
+	}
+
+	if family == unix.NFPROTO_IPV6 {
+		ip = ipNet.IP.To16()
+		offset = 8
+		if !src {
+			offset = 24
+		}
+		if ipNet.IP.To4() != nil {
+			return nil, errors.Wrapf(errNftInvalidCommand, "%s is not an IPv6 address", ipNet)
+		}
+	} else if ip == nil {
+		return nil, errors.Wrapf(errNftInvalidCommand, "%s is not an IPv4 address", ipNet)
+	}
+
+	exprs := []nftExpr{payloadExpr{base: unix.NFT_PAYLOAD_NETWORK_HEADER, offset: offset, len: uint32(len(ip))}}
+
+	ones, bits := ipNet.Mask.Size()
+	if ones != bits {
+		mask := []byte(ipNet.Mask)
+		exprs = append(exprs, bitwiseExpr{mask: mask, xor: make([]byte, len(mask))})
+		ip = ip.Mask(ipNet.Mask)
+	}
+
+	return append(exprs, cmpExpr{op: cmpOp(invert), value: ip}), nil
+}
+
+// ifaceExprs matches the input or output interface name. A trailing '+' matches a prefix.
+func ifaceExprs(key uint32, name string, invert bool) []nftExpr {
+	value := append([]byte(name), 0)
+	if strings.HasSuffix(name, "+") {
+		value = []byte(strings.TrimSuffix(name, "+"))
+	}
+	return []nftExpr{metaExpr{key: key}, cmpExpr{op: cmpOp(invert), value: value}}
+}
+
+// portExprs matches a transport port or port range.
+func portExprs(port string, src, invert bool) ([]nftExpr, error) {
+	offset := uint32(2)
+	if src {
+		offset = 0
+	}
+	exprs := []nftExpr{payloadExpr{base: unix.NFT_PAYLOAD_TRANSPORT_HEADER, offset: offset, len: 2}}
+
+	bounds := strings.SplitN(port, ":", 2)
+	values := make([][]byte, 0, len(bounds))
+	for _, bound := range bounds {
+		p, err := strconv.ParseUint(bound, 10, 16)
+		if err != nil {
+			return nil, errors.Wrapf(errNftInvalidCommand, "invalid port %s", port)
+		}
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, uint16(p))
+		values = append(values, b)
+	}
+
+	if len(values) == 1 {
+		return append(exprs, cmpExpr{op: cmpOp(invert), value: values[0]}), nil
+	}
+
+	if invert {
+		return nil, errors.Wrapf(errNftUnsupportedOption, "! port range %s", port)
+	}
+
+	return append(exprs,
+		cmpExpr{op: unix.NFT_CMP_GTE, value: values[0]},
+		cmpExpr{op: unix.NFT_CMP_LTE, value: values[1]}), nil
+}
+
+// natExprs loads the translated address and optional port into registers and applies the nat.
+func natExprs(family byte, natType uint32, to string) ([]nftExpr, error) {
+	host, port := to, ""
+	if h, p, err := net.SplitHostPort(to); err == nil {
+		host, port = h, p
+	}
+
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return nil, errors.Wrapf(errNftInvalidCommand, "invalid nat address %s", to)
+	}
+
+	natFamily := uint32(unix.NFPROTO_IPV4)
+	value := ip.To4()
+	if family == unix.NFPROTO_IPV6 {
+		natFamily = unix.NFPROTO_IPV6
+		value = ip.To16()
+	}
+	if value == nil || (family == unix.NFPROTO_IPV6 && ip.To4() != nil) {
+		return nil, errors.Wrapf(errNftInvalidCommand, "nat address %s does not match the rule family", to)
+	}
+
+	exprs := []nftExpr{immediateExpr{reg: unix.NFT_REG_1, value: value}}
+	if port != "" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, errors.Wrapf(errNftUnsupportedOption, "nat port %s", port)
+		}
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, uint16(p))
+		exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_2, value: b})
+	}
+
+	return append(exprs, natExpr{natType: natType, family: natFamily, withPort: port != ""}), nil
+}
+
+// exprs translates the rule into nftables expressions for the given netfilter family,
+// following the layout iptables-nft uses for the same rule.
+func (r *ruleSpec) exprs(family byte) ([]nftExpr, error) {
+	var exprs []nftExpr
+
+	if r.inIface != "" {
+		exprs = append(exprs, ifaceExprs(unix.NFT_META_IIFNAME, r.inIface, r.inInvert)...)
+	}
+
+	if r.outIface != "" {
+		exprs = append(exprs, ifaceExprs(unix.NFT_META_OIFNAME, r.outIface, r.outInvert)...)
+	}
+
+	if r.src != nil {
+		e, err := addressExprs(family, r.src, true, r.srcInvert)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+
+	if r.dst != nil {
+		e, err := addressExprs(family, r.dst, false, r.dstInvert)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+
+	if r.protocol != "" && r.protocol != "all" {
+		proto, ok := l4Protocols[r.protocol]
+		if !ok {
+			return nil, errors.Wrapf(errNftUnsupportedOption, "-p %s", r.protocol)
+		}
+		exprs = append(exprs, metaExpr{key: unix.NFT_META_L4PROTO}, cmpExpr{op: unix.NFT_CMP_EQ, value: []byte{proto}})
+	}
+
+	if r.sport != "" {
+		e, err := portExprs(r.sport, true, r.sportInv)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+
+	if r.dport != "" {
+		e, err := portExprs(r.dport, false, r.dportInv)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+
+	for _, m := range []struct {
+		addrType string
+		invert   bool
+		flags    uint32
+	}{
+		{r.srcType, r.srcTypeInv, unix.NFTA_FIB_F_SADDR},
+		{r.dstType, r.dstTypeInv, unix.NFTA_FIB_F_DADDR},
+	} {
+		if m.addrType == "" {
+			continue
+		}
+		addrType, ok := addrTypes[m.addrType]
+		if !ok {
+			return nil, errors.Wrapf(errNftUnsupportedOption, "address type %s", m.addrType)
+		}
+		exprs = append(exprs, fibExpr{flags: m.flags}, cmpExpr{op: cmpOp(m.invert), value: nativeUint32(addrType)})
+	}
+
+	if len(r.ctStates) != 0 {
+		var mask uint32
+		for _, state := range r.ctStates {
+			bit, ok := ctStates[state]
+			if !ok {
+				return nil, errors.Wrapf(errNftUnsupportedOption, "conntrack state %s", state)
+			}
+			mask |= bit
+		}
+		exprs = append(exprs,
+			ctStateExpr{},
+			bitwiseExpr{mask: nativeUint32(mask), xor: nativeUint32(0)},
+			cmpExpr{op: unix.NFT_CMP_NEQ, value: nativeUint32(0)})
+	}
+
+	if r.hasMark {
+		exprs = append(exprs, metaExpr{key: unix.NFT_META_MARK})
+		if r.markMask != ^uint32(0) {
+			exprs = append(exprs, bitwiseExpr{mask: nativeUint32(r.markMask), xor: nativeUint32(0)})
+		}
+		exprs = append(exprs, cmpExpr{op: cmpOp(r.markInvert), value: nativeUint32(r.mark & r.markMask)})
+	}
+
+	exprs = append(exprs, counterExpr{})
+
+	switch r.target {
+	case Accept:
+		exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_VERDICT, verdict: nfAccept})
+	case Drop:
+		exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_VERDICT, verdict: nfDrop})
+	case Return:
+		exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_VERDICT, verdict: unix.NFT_RETURN})
+	case Masquerade:
+		exprs = append(exprs, masqExpr{})
+	case Snat, Dnat:
+		natType, to := uint32(unix.NFT_NAT_SNAT), r.toSource
+		if r.target == Dnat {
+			natType, to = unix.NFT_NAT_DNAT, r.toDestination
+		}
+		if to == "" {
+			return nil, errors.Wrapf(errNftInvalidCommand, "%s target requires a translated address", r.target)
+		}
+		e, err := natExprs(family, natType, to)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	case Mark:
+		if !r.hasSetMark {
+			return nil, errors.Wrap(errNftInvalidCommand, "MARK target requires --set-mark")
+		}
+		if r.setMarkMask == ^uint32(0) {
+			exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_1, value: nativeUint32(r.setMark)})
+		} else {
+			// --set-xmark is (mark & ~mask) ^ value, --set-mark is (mark & ~mask) | value.
+			mask := ^r.setMarkMask
+			if !r.setMarkXor {
+				mask &^= r.setMark
+			}
+			exprs = append(exprs,
+				metaExpr{key: unix.NFT_META_MARK},
+				bitwiseExpr{mask: nativeUint32(mask), xor: nativeUint32(r.setMark)})
+		}
+		exprs = append(exprs, metaExpr{key: unix.NFT_META_MARK, set: true})
+	default:
+		if unsupportedTargets[r.target] {
+			return nil, errors.Wrapf(errNftUnsupportedOption, "-j %s", r.target)
+		}
+		// Any other target is a jump to a chain in the same table.
+		exprs = append(exprs, immediateExpr{reg: unix.NFT_REG_VERDICT, verdict: unix.NFT_JUMP, chain: r.target})
+	}
+
+	return exprs, nil
+}
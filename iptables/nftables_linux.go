@@ -0,0 +1,631 @@
+//go:build linux
+// +build linux
+
+package iptables
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	nftReceiveTimeout = 10 * time.Second
+	sizeofNfgenmsg    = 4
+	nlaTypeMask       = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+)
+
+// baseChain describes how a standard iptables chain is registered with netfilter.
+type baseChain struct {
+	hook      uint32
+	priority  int32
+	chainType string
+}
+
+// Standard chains use the same hooks and priorities as iptables-nft, so both tools see the same ruleset.
+var baseChains = map[string]map[string]baseChain{
+	Filter: {
+		Input:   {unix.NF_INET_LOCAL_IN, 0, "filter"},
+		Forward: {unix.NF_INET_FORWARD, 0, "filter"},
+		Output:  {unix.NF_INET_LOCAL_OUT, 0, "filter"},
+	},
+	Nat: {
+		Prerouting:  {unix.NF_INET_PRE_ROUTING, -100, "nat"},
+		Input:       {unix.NF_INET_LOCAL_IN, 100, "nat"},
+		Output:      {unix.NF_INET_LOCAL_OUT, -100, "nat"},
+		Postrouting: {unix.NF_INET_POST_ROUTING, 100, "nat"},
+	},
+	Mangle: {
+		Prerouting:  {unix.NF_INET_PRE_ROUTING, -150, "filter"},
+		Input:       {unix.NF_INET_LOCAL_IN, -150, "filter"},
+		Forward:     {unix.NF_INET_FORWARD, -150, "filter"},
+		Output:      {unix.NF_INET_LOCAL_OUT, -150, "route"},
+		Postrouting: {unix.NF_INET_POST_ROUTING, -150, "filter"},
+	},
+}
+
+// nftMessage is a single nf_tables netlink request.
+type nftMessage struct {
+	msgType uint16
+	flags   uint16
+	family  byte
+	attrs   [][]byte
+}
+
+// nftConn is a netlink socket to the nf_tables subsystem.
+type nftConn struct {
+	fd  int
+	seq uint32
+}
+
+func newNftConn() (*nftConn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open netfilter socket")
+	}
+
+	timeout := unix.NsecToTimeval(nftReceiveTimeout.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		unix.Close(fd)
+		return nil, errors.Wrap(err, "failed to set netfilter socket timeout")
+	}
+
+	if err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, errors.Wrap(err, "failed to bind netfilter socket")
+	}
+
+	return &nftConn{fd: fd}, nil
+}
+
+func (c *nftConn) close() {
+	unix.Close(c.fd)
+}
+
+// serialize encodes a message with the given sequence number.
+func (m *nftMessage) serialize(seq uint32, subsys uint16) []byte {
+	payload := bytes.Join(m.attrs, nil)
+	b := make([]byte, unix.NLMSG_HDRLEN+sizeofNfgenmsg+len(payload))
+
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], subsys<<8|m.msgType)
+	binary.NativeEndian.PutUint16(b[6:8], unix.NLM_F_REQUEST|m.flags)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+
+	b[16] = m.family
+	b[17] = unix.NFNETLINK_V0
+	if subsys == 0 {
+		// Batch delimiters carry the subsystem they apply to as resource id.
+		binary.BigEndian.PutUint16(b[18:20], unix.NFNL_SUBSYS_NFTABLES)
+	}
+
+	copy(b[unix.NLMSG_HDRLEN+sizeofNfgenmsg:], payload)
+	return b
+}
+
+// commit sends the messages as a single batch. The kernel applies either all of them or none.
+func (c *nftConn) commit(msgs []nftMessage) error {
+	var buf bytes.Buffer
+	pending := make(map[uint32]bool, len(msgs))
+
+	begin := nftMessage{msgType: unix.NFNL_MSG_BATCH_BEGIN}
+	buf.Write(begin.serialize(atomic.AddUint32(&c.seq, 1), 0))
+
+	for i := range msgs {
+		seq := atomic.AddUint32(&c.seq, 1)
+		msgs[i].flags |= unix.NLM_F_ACK
+		buf.Write(msgs[i].serialize(seq, unix.NFNL_SUBSYS_NFTABLES))
+		pending[seq] = true
+	}
+
+	end := nftMessage{msgType: unix.NFNL_MSG_BATCH_END}
+	buf.Write(end.serialize(atomic.AddUint32(&c.seq, 1), 0))
+
+	if err := unix.Sendto(c.fd, buf.Bytes(), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return errors.Wrap(err, "failed to send nftables batch")
+	}
+
+	// Every message in the batch is acknowledged, or answered with the error that aborted the batch.
+	var batchErr error
+	for len(pending) > 0 {
+		replies, err := c.receive()
+		if err != nil {
+			return errors.Wrap(err, "failed to receive nftables batch result")
+		}
+
+		for _, reply := range replies {
+			if !pending[reply.Header.Seq] || reply.Header.Type != unix.NLMSG_ERROR {
+				continue
+			}
+			delete(pending, reply.Header.Seq)
+			if err := replyError(&reply); err != nil && batchErr == nil {
+				batchErr = err
+			}
+		}
+	}
+
+	return batchErr
+}
+
+// query sends a get request and returns the attributes of each reply.
+func (c *nftConn) query(msg nftMessage) ([]map[uint16][]byte, error) {
+	seq := atomic.AddUint32(&c.seq, 1)
+	if err := unix.Sendto(c.fd, msg.serialize(seq, unix.NFNL_SUBSYS_NFTABLES), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, errors.Wrap(err, "failed to send nftables request")
+	}
+
+	var results []map[uint16][]byte
+	for {
+		replies, err := c.receive()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to receive nftables response")
+		}
+
+		for _, reply := range replies {
+			if reply.Header.Seq != seq {
+				continue
+			}
+
+			switch reply.Header.Type {
+			case unix.NLMSG_DONE:
+				return results, nil
+			case unix.NLMSG_ERROR:
+				if err := replyError(&reply); err != nil {
+					return nil, err
+				}
+				return results, nil
+			}
+
+			if len(reply.Data) >= sizeofNfgenmsg {
+				results = append(results, parseNftAttrs(reply.Data[sizeofNfgenmsg:]))
+			}
+
+			if reply.Header.Flags&unix.NLM_F_MULTI == 0 {
+				return results, nil
+			}
+		}
+	}
+}
+
+func (c *nftConn) receive() ([]syscall.NetlinkMessage, error) {
+	buf := make([]byte, 1<<16)
+	n, _, err := unix.Recvfrom(c.fd, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	return syscall.ParseNetlinkMessage(buf[:n])
+}
+
+// replyError returns the error carried by a netlink error message, or nil for an ack.
+func replyError(reply *syscall.NetlinkMessage) error {
+	if len(reply.Data) < 4 {
+		return errors.New("truncated netlink error message")
+	}
+	if errno := int32(binary.NativeEndian.Uint32(reply.Data[0:4])); errno != 0 {
+		return syscall.Errno(-errno)
+	}
+	return nil
+}
+
+// parseNftAttrs parses the top level attributes of a reply, keyed by type.
+func parseNftAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= unix.SizeofNlAttr {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		if l < unix.SizeofNlAttr || l > len(b) {
+			break
+		}
+		attrType := binary.NativeEndian.Uint16(b[2:4]) & nlaTypeMask
+		attrs[attrType] = b[unix.SizeofNlAttr:l]
+
+		aligned := (l + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+		if aligned >= len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
+
+// nftFamily returns the netfilter family for an iptables version.
+func nftFamily(version string) byte {
+	if version == V6 {
+		return unix.NFPROTO_IPV6
+	}
+	return unix.NFPROTO_IPV4
+}
+
+func newTableMessage(family byte, table string) nftMessage {
+	return nftMessage{
+		msgType: unix.NFT_MSG_NEWTABLE,
+		flags:   unix.NLM_F_CREATE,
+		family:  family,
+		attrs:   [][]byte{nlString(unix.NFTA_TABLE_NAME, table)},
+	}
+}
+
+// newChainMessages returns the messages that create a chain and its table if they do not exist yet.
+// Standard chains are registered as base chains on their netfilter hook.
+func newChainMessages(family byte, table, chain string, exclusive bool) []nftMessage {
+	msg := nftMessage{
+		msgType: unix.NFT_MSG_NEWCHAIN,
+		flags:   unix.NLM_F_CREATE,
+		family:  family,
+		attrs: [][]byte{
+			nlString(unix.NFTA_CHAIN_TABLE, table),
+			nlString(unix.NFTA_CHAIN_NAME, chain),
+		},
+	}
+
+	if exclusive {
+		msg.flags |= unix.NLM_F_EXCL
+	}
+
+	if base, ok := baseChains[table][chain]; ok {
+		msg.attrs = append(msg.attrs,
+			nlNested(unix.NFTA_CHAIN_HOOK,
+				nlUint32(unix.NFTA_HOOK_HOOKNUM, base.hook),
+				nlUint32(unix.NFTA_HOOK_PRIORITY, uint32(base.priority))),
+			nlUint32(unix.NFTA_CHAIN_POLICY, nfAccept),
+			nlString(unix.NFTA_CHAIN_TYPE, base.chainType))
+	}
+
+	return []nftMessage{newTableMessage(family, table), msg}
+}
+
+// chainExists checks whether the chain is present in the nftables ruleset.
+func (c *nftConn) chainExists(family byte, table, chain string) error {
+	_, err := c.query(nftMessage{
+		msgType: unix.NFT_MSG_GETCHAIN,
+		family:  family,
+		attrs: [][]byte{
+			nlString(unix.NFTA_CHAIN_TABLE, table),
+			nlString(unix.NFTA_CHAIN_NAME, chain),
+		},
+	})
+	return err
+}
+
+// nftaRulePositionID refers an inserted rule to a rule added earlier in the same batch by its NFTA_RULE_ID.
+// It is not defined in the unix package.
+const nftaRulePositionID = 0xa
+
+// nftRule is a rule of a chain, either dumped from the ruleset or added by the batch being built.
+type nftRule struct {
+	// handle identifies a rule of the ruleset, id a rule added by the batch.
+	handle   uint64
+	id       uint32
+	userdata []byte
+	exprs    []byte
+}
+
+// matches checks whether the rule was programmed from the given spec. Rules programmed by this backend carry
+// their spec as userdata, the others, such as the rules iptables-nft programmed, are matched on their expressions.
+func (r *nftRule) matches(spec string, exprs []byte) bool {
+	if string(r.userdata) == spec {
+		return true
+	}
+	return exprs != nil && exprListMatches(exprs, r.exprs)
+}
+
+// nlAttrEntry is a netlink attribute with its flags split from its type.
+type nlAttrEntry struct {
+	attrType uint16
+	nested   bool
+	value    []byte
+}
+
+// parseNlAttrList parses a list of attributes, keeping their order and repeated types.
+func parseNlAttrList(b []byte) []nlAttrEntry {
+	var attrs []nlAttrEntry
+	for len(b) >= unix.SizeofNlAttr {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		if l < unix.SizeofNlAttr || l > len(b) {
+			break
+		}
+		attrType := binary.NativeEndian.Uint16(b[2:4])
+		attrs = append(attrs, nlAttrEntry{
+			attrType: attrType & nlaTypeMask,
+			nested:   attrType&unix.NLA_F_NESTED != 0,
+			value:    b[unix.SizeofNlAttr:l],
+		})
+
+		aligned := (l + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+		if aligned >= len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
+
+// exprListMatches compares the expressions this backend programs for a rule with the expressions of a rule in the
+// ruleset. The counters of the rule are not compared, and the attributes the kernel reports in addition to those
+// this backend sets, such as defaults, are ignored.
+func exprListMatches(want, got []byte) bool {
+	wantExprs, gotExprs := parseNlAttrList(want), parseNlAttrList(got)
+	if len(wantExprs) != len(gotExprs) {
+		return false
+	}
+
+	for i := range wantExprs {
+		wantExpr, gotExpr := parseNftAttrs(wantExprs[i].value), parseNftAttrs(gotExprs[i].value)
+		name := string(bytes.TrimRight(wantExpr[unix.NFTA_EXPR_NAME], "\x00"))
+		if name != string(bytes.TrimRight(gotExpr[unix.NFTA_EXPR_NAME], "\x00")) {
+			return false
+		}
+		if name == (counterExpr{}).name() {
+			continue
+		}
+		if !attrsContain(wantExpr[unix.NFTA_EXPR_DATA], gotExpr[unix.NFTA_EXPR_DATA]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attrsContain checks whether got holds each of the attributes of want with the same value.
+func attrsContain(want, got []byte) bool {
+	gotAttrs := make(map[uint16][]byte)
+	for _, attr := range parseNlAttrList(got) {
+		gotAttrs[attr.attrType] = attr.value
+	}
+
+	for _, attr := range parseNlAttrList(want) {
+		value, ok := gotAttrs[attr.attrType]
+		if !ok {
+			return false
+		}
+		if attr.nested {
+			if !attrsContain(attr.value, value) {
+				return false
+			}
+		} else if !bytes.Equal(attr.value, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// chainRules returns the rules of a chain in evaluation order, or none if the chain does not exist.
+func (c *nftConn) chainRules(family byte, table, chain string) ([]nftRule, error) {
+	dumped, err := c.query(nftMessage{
+		msgType: unix.NFT_MSG_GETRULE,
+		flags:   unix.NLM_F_DUMP,
+		family:  family,
+		attrs: [][]byte{
+			nlString(unix.NFTA_RULE_TABLE, table),
+			nlString(unix.NFTA_RULE_CHAIN, chain),
+		},
+	})
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rules := make([]nftRule, 0, len(dumped))
+	for _, rule := range dumped {
+		if string(bytes.TrimRight(rule[unix.NFTA_RULE_CHAIN], "\x00")) != chain || len(rule[unix.NFTA_RULE_HANDLE]) != 8 {
+			continue
+		}
+		rules = append(rules, nftRule{
+			handle:   binary.BigEndian.Uint64(rule[unix.NFTA_RULE_HANDLE]),
+			userdata: rule[unix.NFTA_RULE_USERDATA],
+			exprs:    rule[unix.NFTA_RULE_EXPRESSIONS],
+		})
+	}
+
+	return rules, nil
+}
+
+// ruleExprs returns the NFTA_RULE_EXPRESSIONS value this backend programs for a rule, or nil if the rule
+// cannot be translated.
+func ruleExprs(family byte, rule *ruleSpec) []byte {
+	exprs, err := rule.exprs(family)
+	if err != nil {
+		return nil
+	}
+	return encodeExprs(exprs)[unix.SizeofNlAttr:]
+}
+
+// nftBatch builds the messages of a batch of commands. It follows the rules of each chain the batch changes,
+// so that the positions and deletes of a command refer to the chain as the commands before it in the batch
+// leave it, including the rules they add.
+type nftBatch struct {
+	conn   *nftConn
+	family byte
+	chains map[string][]nftRule
+	nextID uint32
+	msgs   []nftMessage
+}
+
+func newNftBatch(conn *nftConn, family byte) *nftBatch {
+	return &nftBatch{conn: conn, family: family, chains: make(map[string][]nftRule)}
+}
+
+// rules returns the rules of a chain as the batch leaves it, reading them from the ruleset on first use.
+func (b *nftBatch) rules(table, chain string) ([]nftRule, error) {
+	key := table + " " + chain
+	if rules, ok := b.chains[key]; ok {
+		return rules, nil
+	}
+	rules, err := b.conn.chainRules(b.family, table, chain)
+	if err != nil {
+		return nil, err
+	}
+	b.chains[key] = rules
+	return rules, nil
+}
+
+// add appends the messages that apply a command which changes the ruleset.
+func (b *nftBatch) add(cmd *nftCommand) error {
+	switch cmd.op {
+	case nftOpNewChain:
+		b.chains[cmd.table+" "+cmd.chain] = []nftRule{}
+		b.msgs = append(b.msgs, newChainMessages(b.family, cmd.table, cmd.chain, true)...)
+		return nil
+
+	case nftOpDelete:
+		rules, err := b.rules(cmd.table, cmd.chain)
+		if err != nil {
+			return err
+		}
+		spec := cmd.rule.String()
+		exprs := ruleExprs(b.family, cmd.rule)
+		for i := range rules {
+			if !rules[i].matches(spec, exprs) {
+				continue
+			}
+
+			msg := nftMessage{
+				msgType: unix.NFT_MSG_DELRULE,
+				family:  b.family,
+				attrs: [][]byte{
+					nlString(unix.NFTA_RULE_TABLE, cmd.table),
+					nlString(unix.NFTA_RULE_CHAIN, cmd.chain),
+				},
+			}
+			if rules[i].handle != 0 {
+				msg.attrs = append(msg.attrs, nlUint64(unix.NFTA_RULE_HANDLE, rules[i].handle))
+			} else {
+				msg.attrs = append(msg.attrs, nlUint32(unix.NFTA_RULE_ID, rules[i].id))
+			}
+			b.chains[cmd.table+" "+cmd.chain] = append(rules[:i:i], rules[i+1:]...)
+			b.msgs = append(b.msgs, msg)
+			return nil
+		}
+		return errors.Wrapf(errNftRuleNotFound, "%s %s: %s", cmd.table, cmd.chain, spec)
+
+	case nftOpAppend, nftOpInsert:
+		exprs, err := cmd.rule.exprs(b.family)
+		if err != nil {
+			return err
+		}
+		rules, err := b.rules(cmd.table, cmd.chain)
+		if err != nil {
+			return err
+		}
+
+		b.nextID++
+		added := nftRule{id: b.nextID, userdata: []byte(cmd.rule.String()), exprs: encodeExprs(exprs)[unix.SizeofNlAttr:]}
+		rule := nftMessage{
+			msgType: unix.NFT_MSG_NEWRULE,
+			flags:   unix.NLM_F_CREATE,
+			family:  b.family,
+			attrs: [][]byte{
+				nlString(unix.NFTA_RULE_TABLE, cmd.table),
+				nlString(unix.NFTA_RULE_CHAIN, cmd.chain),
+				encodeExprs(exprs),
+				nlAttr(unix.NFTA_RULE_USERDATA, added.userdata),
+				nlUint32(unix.NFTA_RULE_ID, added.id),
+			},
+		}
+
+		// Insert before the rule currently at the requested position, or append if the chain is shorter.
+		idx := len(rules)
+		if cmd.op == nftOpInsert {
+			idx = cmd.position - 1
+		}
+		switch {
+		case idx >= len(rules):
+			idx = len(rules)
+			rule.flags |= unix.NLM_F_APPEND
+		case idx > 0 && rules[idx].handle != 0:
+			rule.attrs = append(rule.attrs, nlUint64(unix.NFTA_RULE_POSITION, rules[idx].handle))
+		case idx > 0:
+			rule.attrs = append(rule.attrs, nlUint32(nftaRulePositionID, rules[idx].id))
+		}
+		rules = append(rules[:idx:idx], append([]nftRule{added}, rules[idx:]...)...)
+		b.chains[cmd.table+" "+cmd.chain] = rules
+
+		msgs := newChainMessages(b.family, cmd.table, cmd.chain, false)
+		if _, ok := baseChains[cmd.table][cmd.chain]; !ok {
+			// User chains must already exist, as with iptables.
+			msgs = msgs[:1]
+		}
+
+		logger.Info("Programming nftables rule", zap.String("table", cmd.table), zap.String("chain", cmd.chain),
+			zap.String("rule", cmd.rule.String()))
+		b.msgs = append(b.msgs, append(msgs, rule)...)
+		return nil
+
+	default:
+		return errors.Wrapf(errNftInvalidCommand, "command %d does not change the ruleset", cmd.op)
+	}
+}
+
+// runNftCmd programs the iptables command given by params through nftables.
+func runNftCmd(version, params string) error {
+	cmd, err := parseNftCommand(params)
+	if err != nil {
+		return err
+	}
+
+	family := nftFamily(version)
+
+	conn, err := newNftConn()
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	switch cmd.op {
+	case nftOpList:
+		return conn.chainExists(family, cmd.table, cmd.chain)
+
+	case nftOpCheck:
+		rules, err := conn.chainRules(family, cmd.table, cmd.chain)
+		if err != nil {
+			return err
+		}
+		spec := cmd.rule.String()
+		exprs := ruleExprs(family, cmd.rule)
+		for i := range rules {
+			if rules[i].matches(spec, exprs) {
+				return nil
+			}
+		}
+		return errors.Wrapf(errNftRuleNotFound, "%s %s: %s", cmd.table, cmd.chain, spec)
+
+	default:
+		batch := newNftBatch(conn, family)
+		if err := batch.add(cmd); err != nil {
+			return err
+		}
+		return conn.commit(batch.msgs)
+	}
+}
+
+// runNftTransaction programs a list of iptables commands through nftables as a single batch,
+// so either all of them take effect or none does.
+func runNftTransaction(version string, params []string) error {
+	conn, err := newNftConn()
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	batch := newNftBatch(conn, nftFamily(version))
+	for _, p := range params {
+		cmd, err := parseNftCommand(p)
+		if err != nil {
+			return err
+		}
+
+		if err := batch.add(cmd); err != nil {
+			return err
+		}
+	}
+
+	return conn.commit(batch.msgs)
+}
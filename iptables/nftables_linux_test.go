@@ -0,0 +1,422 @@
+//go:build linux
+// +build linux
+
+package iptables
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// TestNftTranslation checks that the commands the legacy backend runs and the rules the nft backend
+// programs are equivalent. The expected expressions are the ones iptables-nft generates for the same
+// iptables rule, in nft --debug=netlink format.
+func TestNftTranslation(t *testing.T) {
+	c := NewClient()
+
+	tests := []struct {
+		name       string
+		version    string
+		entry      IPTableEntry
+		wantParams string
+		wantExprs  []string
+	}{
+		{
+			name:    "snat dns to nc primary ip",
+			version: V4,
+			entry: c.GetInsertIptableRuleCmd(V4, Nat, Swift,
+				" -m addrtype ! --dst-type local -s 10.240.0.0/16 -d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.240.0.4"),
+			wantParams: "-t nat -I SWIFT 1  -m addrtype ! --dst-type local -s 10.240.0.0/16 -d 168.63.129.16 -p udp --dport 53 -j SNAT --to 10.240.0.4",
+			wantExprs: []string{
+				"[ payload load 4b @ network header + 12 => reg 1 ]",
+				"[ bitwise reg 1 = ( reg 1 & 0x0000ffff ) ^ 0x00000000 ]",
+				"[ cmp eq reg 1 0x0000f00a ]",
+				"[ payload load 4b @ network header + 16 => reg 1 ]",
+				"[ cmp eq reg 1 0x10813fa8 ]",
+				"[ meta load l4proto => reg 1 ]",
+				"[ cmp eq reg 1 0x00000011 ]",
+				"[ payload load 2b @ transport header + 2 => reg 1 ]",
+				"[ cmp eq reg 1 0x00003500 ]",
+				"[ fib daddr type => reg 1 ]",
+				"[ cmp neq reg 1 0x00000002 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 1 0x0400f00a ]",
+				"[ nat snat ip addr_min reg 1 ]",
+			},
+		},
+		{
+			name:       "masquerade snat bridge subnet",
+			version:    V4,
+			entry:      c.GetInsertIptableRuleCmd(V4, Nat, Postrouting, "-s 169.254.128.0/17", Masquerade),
+			wantParams: "-t nat -I POSTROUTING 1 -s 169.254.128.0/17 -j MASQUERADE",
+			wantExprs: []string{
+				"[ payload load 4b @ network header + 12 => reg 1 ]",
+				"[ bitwise reg 1 = ( reg 1 & 0x0080ffff ) ^ 0x00000000 ]",
+				"[ cmp eq reg 1 0x0080fea9 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ masq ]",
+			},
+		},
+		{
+			name:       "ipv6 snat to host address",
+			version:    V6,
+			entry:      c.GetInsertIptableRuleCmd(V6, Nat, Postrouting, "-s fd00:10::/64", "SNAT --to fd00::5"),
+			wantParams: "-t nat -I POSTROUTING 1 -s fd00:10::/64 -j SNAT --to fd00::5",
+			wantExprs: []string{
+				"[ payload load 16b @ network header + 8 => reg 1 ]",
+				"[ bitwise reg 1 = ( reg 1 & 0xffffffff 0xffffffff 0x00000000 0x00000000 ) ^ 0x00000000 0x00000000 0x00000000 0x00000000 ]",
+				"[ cmp eq reg 1 0x100000fd 0x00000000 0x00000000 0x00000000 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 1 0x000000fd 0x00000000 0x00000000 0x05000000 ]",
+				"[ nat snat ip6 addr_min reg 1 ]",
+			},
+		},
+		{
+			name:       "port mapping to container port",
+			version:    V4,
+			entry:      c.GetAppendIptableRuleCmd(V4, Nat, Prerouting, "-p tcp -m tcp --dport 8080", "DNAT --to-destination 10.0.0.5:80"),
+			wantParams: "-t nat -A PREROUTING -p tcp -m tcp --dport 8080 -j DNAT --to-destination 10.0.0.5:80",
+			wantExprs: []string{
+				"[ meta load l4proto => reg 1 ]",
+				"[ cmp eq reg 1 0x00000006 ]",
+				"[ payload load 2b @ transport header + 2 => reg 1 ]",
+				"[ cmp eq reg 1 0x0000901f ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 1 0x0500000a ]",
+				"[ immediate reg 2 0x00005000 ]",
+				"[ nat dnat ip addr_min reg 1 proto_min reg 2 flags 0x2 ]",
+			},
+		},
+		{
+			name:       "port mapping on host address",
+			version:    V4,
+			entry:      c.GetAppendIptableRuleCmd(V4, Nat, Output, "-d 10.1.0.4 -p udp --dport 5353", "DNAT --to-destination 10.0.0.5:53"),
+			wantParams: "-t nat -A OUTPUT -d 10.1.0.4 -p udp --dport 5353 -j DNAT --to-destination 10.0.0.5:53",
+			wantExprs: []string{
+				"[ payload load 4b @ network header + 16 => reg 1 ]",
+				"[ cmp eq reg 1 0x0400010a ]",
+				"[ meta load l4proto => reg 1 ]",
+				"[ cmp eq reg 1 0x00000011 ]",
+				"[ payload load 2b @ transport header + 2 => reg 1 ]",
+				"[ cmp eq reg 1 0x0000e914 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 1 0x0500000a ]",
+				"[ immediate reg 2 0x00003500 ]",
+				"[ nat dnat ip addr_min reg 1 proto_min reg 2 flags 0x2 ]",
+			},
+		},
+		{
+			name:       "jump to swift chain",
+			version:    V4,
+			entry:      c.GetAppendIptableRuleCmd(V4, Nat, Postrouting, "", Swift),
+			wantParams: "-t nat -A POSTROUTING  -j SWIFT",
+			wantExprs: []string{
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 0 jump -> SWIFT ]",
+			},
+		},
+		{
+			name:    "accept established snat bridge traffic",
+			version: V4,
+			entry: c.GetInsertIptableRuleCmd(V4, Filter, CNIInputChain,
+				" -i azSnatbr -m state --state ESTABLISHED,RELATED", Accept),
+			wantParams: "-t filter -I AZURECNIINPUT 1  -i azSnatbr -m state --state ESTABLISHED,RELATED -j ACCEPT",
+			wantExprs: []string{
+				"[ meta load iifname => reg 1 ]",
+				"[ cmp eq reg 1 0x6e537a61 0x72627461 0x00000000 ]",
+				"[ ct load state => reg 1 ]",
+				"[ bitwise reg 1 = ( reg 1 & 0x00000006 ) ^ 0x00000000 ]",
+				"[ cmp neq reg 1 0x00000000 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 0 accept ]",
+			},
+		},
+		{
+			name:       "clear kube-proxy mark",
+			version:    V6,
+			entry:      c.GetInsertIptableRuleCmd(V6, Mangle, Postrouting, "", "MARK --set-mark 0x0"),
+			wantParams: "-t mangle -I POSTROUTING 1  -j MARK --set-mark 0x0",
+			wantExprs: []string{
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 1 0x00000000 ]",
+				"[ meta set mark with reg 1 ]",
+			},
+		},
+		{
+			name:       "block wireserver http",
+			version:    V4,
+			entry:      c.GetInsertIptableRuleCmd(V4, Filter, Forward, "-d 168.63.129.16 -p tcp -m tcp --dport 80", Drop),
+			wantParams: "-t filter -I FORWARD 1 -d 168.63.129.16 -p tcp -m tcp --dport 80 -j DROP",
+			wantExprs: []string{
+				"[ payload load 4b @ network header + 16 => reg 1 ]",
+				"[ cmp eq reg 1 0x10813fa8 ]",
+				"[ meta load l4proto => reg 1 ]",
+				"[ cmp eq reg 1 0x00000006 ]",
+				"[ payload load 2b @ transport header + 2 => reg 1 ]",
+				"[ cmp eq reg 1 0x00005000 ]",
+				"[ counter pkts 0 bytes 0 ]",
+				"[ immediate reg 0 drop ]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.version, tt.entry.Version)
+			require.Equal(t, tt.wantParams, tt.entry.Params)
+
+			cmd, err := parseNftCommand(tt.entry.Params)
+			require.NoError(t, err)
+
+			exprs, err := cmd.rule.exprs(nftFamily(tt.version))
+			require.NoError(t, err)
+
+			got := make([]string, 0, len(exprs))
+			for _, e := range exprs {
+				got = append(got, e.String())
+			}
+			require.Equal(t, tt.wantExprs, got)
+
+			// The encoded rule must be a single well formed attribute.
+			encoded := encodeExprs(exprs)
+			attrs := parseNftAttrs(encoded)
+			require.Len(t, attrs, 1)
+			require.Contains(t, attrs, uint16(unix.NFTA_RULE_EXPRESSIONS))
+		})
+	}
+}
+
+func TestNftRuleSpecCanonical(t *testing.T) {
+	a, err := parseNftCommand("-t nat -C SWIFT -p udp -m udp --dport 53 -d 168.63.129.16 -j SNAT --to-source 10.0.0.4")
+	require.NoError(t, err)
+	b, err := parseNftCommand("-t nat -C SWIFT -d 168.63.129.16/32 -p udp --dport 53 -j SNAT --to 10.0.0.4")
+	require.NoError(t, err)
+
+	require.Equal(t, a.rule.String(), b.rule.String())
+	require.Equal(t, "-d 168.63.129.16/32 -p udp --dport 53 -j SNAT --to-source 10.0.0.4", a.rule.String())
+}
+
+func TestNftParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		op      nftOp
+		table   string
+		chain   string
+		pos     int
+		wantErr error
+	}{
+		{name: "new chain", params: "-t nat -N SWIFT", op: nftOpNewChain, table: Nat, chain: Swift},
+		{name: "list chain", params: "-t nat -nL SWIFT", op: nftOpList, table: Nat, chain: Swift},
+		{name: "default table", params: "-A FORWARD -j ACCEPT", op: nftOpAppend, table: Filter, chain: Forward},
+		{name: "insert position", params: "-w 60 -t filter -I INPUT 3 -j DROP", op: nftOpInsert, table: Filter, chain: Input, pos: 3},
+		{name: "quoted comment", params: `-t filter -A INPUT -m comment --comment "allow all" -j ACCEPT`, op: nftOpAppend, table: Filter, chain: Input},
+		{name: "no command", params: "-t nat -j ACCEPT", wantErr: errNftInvalidCommand},
+		{name: "no target", params: "-t nat -A POSTROUTING -s 10.0.0.0/8", wantErr: errNftInvalidCommand},
+		{name: "port without protocol", params: "-A INPUT --dport 80 -j ACCEPT", wantErr: errNftInvalidCommand},
+		{name: "unsupported match", params: "-A INPUT -m recent --name x -j ACCEPT", wantErr: errNftUnsupportedOption},
+		{name: "unsupported option", params: "-A INPUT --tcp-flags SYN SYN -j ACCEPT", wantErr: errNftUnsupportedOption},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseNftCommand(tt.params)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.op, cmd.op)
+			require.Equal(t, tt.table, cmd.table)
+			require.Equal(t, tt.chain, cmd.chain)
+			require.Equal(t, tt.pos, cmd.position)
+		})
+	}
+}
+
+func TestNftUnsupportedTranslation(t *testing.T) {
+	for _, params := range []string{
+		"-A INPUT -j LOG",
+		"-A INPUT -p tcp ! --dport 1000:2000 -j ACCEPT",
+		"-A INPUT -p sctp -j ACCEPT",
+		"-t nat -A POSTROUTING -s fd00::/64 -j MASQUERADE",
+	} {
+		cmd, err := parseNftCommand(params)
+		require.NoError(t, err, params)
+		_, err = cmd.rule.exprs(nftFamily(V4))
+		require.Error(t, err, params)
+	}
+}
+
+// nftTestNamespace moves the test thread to a private network namespace for the duration of the test,
+// and returns an nft backend client with the SWIFT chain created in it.
+func nftTestNamespace(t *testing.T) *Client {
+	t.Helper()
+	runtime.LockOSThread()
+	t.Cleanup(runtime.UnlockOSThread)
+
+	hostNs, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		t.Skipf("cannot open network namespace: %v", err)
+	}
+	t.Cleanup(func() { hostNs.Close() })
+
+	if err = unix.Unshare(unix.CLONE_NEWNET); err != nil {
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+	t.Cleanup(func() {
+		unix.Setns(int(hostNs.Fd()), unix.CLONE_NEWNET) //nolint:errcheck // restore the test thread namespace
+	})
+
+	c := NewClientWithBackend(BackendNft)
+	if err = c.CreateChain(V4, Nat, Swift); err != nil {
+		if errors.Is(err, unix.EPROTONOSUPPORT) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOENT) {
+			t.Skipf("nftables is not available: %v", err)
+		}
+		require.NoError(t, err)
+	}
+	return c
+}
+
+// TestNftBackend programs rules through the nft backend in a private network namespace.
+func TestNftBackend(t *testing.T) {
+	c := nftTestNamespace(t)
+
+	require.True(t, c.ChainExists(V4, Nat, Swift))
+	require.False(t, c.ChainExists(V4, Nat, "NOTEXIST"))
+
+	match := " -m addrtype ! --dst-type local -s 10.240.0.0/16 -d 168.63.129.16 -p udp --dport 53"
+	target := "SNAT --to 10.240.0.4"
+
+	require.NoError(t, c.AppendIptableRule(V4, Nat, Postrouting, "", Swift))
+	require.NoError(t, c.InsertIptableRule(V4, Nat, Swift, match, target))
+	require.True(t, c.RuleExists(V4, Nat, Swift, match, target))
+
+	// Programming the same rule again is a no-op.
+	require.NoError(t, c.InsertIptableRule(V4, Nat, Swift, match, target))
+	conn, err := newNftConn()
+	require.NoError(t, err)
+	defer conn.close()
+	rules, err := conn.chainRules(nftFamily(V4), Nat, Swift)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	require.NoError(t, c.AppendIptableRule(V4, Nat, Prerouting, "-p tcp -m tcp --dport 8080", "DNAT --to-destination 10.0.0.5:80"))
+	require.True(t, c.RuleExists(V4, Nat, Prerouting, "-p tcp --dport 8080", "DNAT --to 10.0.0.5:80"))
+
+	// A failing batch leaves nothing behind.
+	require.Error(t, c.AppendIptableRule(V4, Nat, Postrouting, "-s 10.0.0.0/8", "NOTEXIST"))
+	require.False(t, c.RuleExists(V4, Nat, Postrouting, "-s 10.0.0.0/8", "NOTEXIST"))
+
+	require.NoError(t, c.DeleteIptableRule(V4, Nat, Swift, match, target))
+	require.False(t, c.RuleExists(V4, Nat, Swift, match, target))
+	require.Error(t, c.DeleteIptableRule(V4, Nat, Swift, match, target))
+
+	// A transaction is sent as one batch, so a failing rule leaves nothing behind.
+	accept := "-s 169.254.0.1 -d 169.254.0.4"
+	tx := c.NewTransaction()
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIOutputChain, accept, Accept))
+	require.NoError(t, tx.AppendIptableRule(V4, Filter, CNIOutputChain, "", "NOTEXIST"))
+	require.Error(t, tx.Commit())
+	require.False(t, c.ChainExists(V4, Filter, CNIOutputChain))
+
+	tx = c.NewTransaction()
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIOutputChain, accept, Accept))
+	require.NoError(t, tx.Commit())
+	require.True(t, c.RuleExists(V4, Filter, Output, "", CNIOutputChain))
+	require.True(t, c.RuleExists(V4, Filter, CNIOutputChain, accept, Accept))
+
+	tx = c.NewTransaction()
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, CNIOutputChain, accept, Accept))
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, CNIOutputChain, accept, Accept))
+	require.NoError(t, tx.Commit())
+	require.False(t, c.RuleExists(V4, Filter, CNIOutputChain, accept, Accept))
+}
+
+// nftChainSpecs returns the specs of the rules of a chain in evaluation order.
+func nftChainSpecs(t *testing.T, table, chain string) []string {
+	t.Helper()
+	conn, err := newNftConn()
+	require.NoError(t, err)
+	defer conn.close()
+
+	rules, err := conn.chainRules(nftFamily(V4), table, chain)
+	require.NoError(t, err)
+	specs := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		specs = append(specs, string(rule.userdata))
+	}
+	return specs
+}
+
+// TestNftBatchPositions checks that the commands of a batch see the rules added by the commands before them.
+func TestNftBatchPositions(t *testing.T) {
+	nftTestNamespace(t)
+
+	require.NoError(t, runNftTransaction(V4, []string{
+		"-t filter -N AZURECNIOUTPUT",
+		"-t filter -A AZURECNIOUTPUT -s 10.0.0.1 -j ACCEPT",
+		"-t filter -A AZURECNIOUTPUT -s 10.0.0.3 -j ACCEPT",
+		"-t filter -I AZURECNIOUTPUT 2 -s 10.0.0.2 -j ACCEPT",
+		"-t filter -A AZURECNIOUTPUT -s 10.0.0.4 -j ACCEPT",
+		"-t filter -D AZURECNIOUTPUT -s 10.0.0.4 -j ACCEPT",
+	}))
+	require.Equal(t, []string{"-s 10.0.0.1/32 -j ACCEPT", "-s 10.0.0.2/32 -j ACCEPT", "-s 10.0.0.3/32 -j ACCEPT"},
+		nftChainSpecs(t, Filter, CNIOutputChain))
+
+	// positions mix the rules of the ruleset with those of the batch
+	require.NoError(t, runNftTransaction(V4, []string{
+		"-t filter -I AZURECNIOUTPUT 1 -s 10.0.0.0 -j ACCEPT",
+		"-t filter -I AZURECNIOUTPUT 3 -s 10.0.0.5 -j ACCEPT",
+		"-t filter -D AZURECNIOUTPUT -s 10.0.0.3 -j ACCEPT",
+	}))
+	require.Equal(t, []string{"-s 10.0.0.0/32 -j ACCEPT", "-s 10.0.0.1/32 -j ACCEPT", "-s 10.0.0.5/32 -j ACCEPT", "-s 10.0.0.2/32 -j ACCEPT"},
+		nftChainSpecs(t, Filter, CNIOutputChain))
+}
+
+// TestNftForeignRules checks that the rules programmed without the userdata of this backend, as iptables-nft
+// does, are matched on their expressions.
+func TestNftForeignRules(t *testing.T) {
+	c := nftTestNamespace(t)
+
+	match, target := "-d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.0.0.4"
+	cmd, err := parseNftCommand("-t nat -A SWIFT " + match + " -j " + target)
+	require.NoError(t, err)
+	exprs, err := cmd.rule.exprs(nftFamily(V4))
+	require.NoError(t, err)
+
+	conn, err := newNftConn()
+	require.NoError(t, err)
+	defer conn.close()
+	require.NoError(t, conn.commit([]nftMessage{{
+		msgType: unix.NFT_MSG_NEWRULE,
+		flags:   unix.NLM_F_CREATE | unix.NLM_F_APPEND,
+		family:  nftFamily(V4),
+		attrs: [][]byte{
+			nlString(unix.NFTA_RULE_TABLE, Nat),
+			nlString(unix.NFTA_RULE_CHAIN, Swift),
+			encodeExprs(exprs),
+		},
+	}}))
+
+	require.True(t, c.RuleExists(V4, Nat, Swift, match, target))
+	require.False(t, c.RuleExists(V4, Nat, Swift, match, "SNAT --to 10.0.0.5"))
+
+	// the rule is not programmed again
+	require.NoError(t, c.AppendIptableRule(V4, Nat, Swift, match, target))
+	require.Len(t, nftChainSpecs(t, Nat, Swift), 1)
+
+	require.NoError(t, c.DeleteIptableRule(V4, Nat, Swift, match, target))
+	require.Empty(t, nftChainSpecs(t, Nat, Swift))
+}
@@ -0,0 +1,13 @@
+package iptables
+
+import "github.com/pkg/errors"
+
+// runNftCmd is not supported on windows, which has no nftables.
+func runNftCmd(_, _ string) error {
+	return errors.New("nftables backend is only supported on linux")
+}
+
+// runNftTransaction is not supported on windows, which has no nftables.
+func runNftTransaction(_ string, _ []string) error {
+	return errors.New("nftables backend is only supported on linux")
+}
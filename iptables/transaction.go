@@ -0,0 +1,557 @@
+package iptables
+
+// This file batches iptables changes so that they are applied together or not at all.
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	iptablesRestore  = "iptables-restore"
+	ip6tablesRestore = "ip6tables-restore"
+	iptablesSave     = "iptables-save"
+	ip6tablesSave    = "ip6tables-save"
+	restoreNoFlush   = "--noflush"
+	restoreCommit    = "COMMIT"
+	restoreTimeout   = (lockTimeout + 10) * time.Second
+)
+
+// action creating a chain
+const createChain = "N"
+
+// action deleting a chain, only used when rolling back
+const deleteChain = "X"
+
+// txEntry is a single change queued in a transaction.
+type txEntry struct {
+	version string
+	action  string
+	table   string
+	chain   string
+	match   string
+	target  string
+}
+
+// rule returns the change in iptables-restore syntax, which is the iptables syntax without the table.
+func (e txEntry) rule() string {
+	args := []string{"-" + e.action, e.chain}
+	if e.action == createChain || e.action == deleteChain {
+		return strings.Join(args, " ")
+	}
+
+	if e.action == Insert {
+		args = append(args, "1")
+	}
+
+	if match := strings.TrimSpace(e.match); match != "" {
+		args = append(args, match)
+	}
+
+	return strings.Join(append(args, "-j", e.target), " ")
+}
+
+// key identifies the chain or rule the change applies to.
+func (e txEntry) key() string {
+	if e.action == createChain || e.action == deleteChain {
+		return fmt.Sprintf("%s %s %s", e.version, e.table, e.chain)
+	}
+	return fmt.Sprintf("%s %s %s %s -j %s", e.version, e.table, e.chain, strings.TrimSpace(e.match), e.target)
+}
+
+// inverse returns the change that undoes this one.
+// A deleted rule is restored at the end of its chain, since its original position is not known.
+func (e txEntry) inverse() txEntry {
+	switch e.action {
+	case createChain:
+		e.action = deleteChain
+	case Delete:
+		e.action = Append
+	default:
+		e.action = Delete
+	}
+	return e
+}
+
+// TransactionInterface queues chain and rule changes and applies them together on Commit.
+type TransactionInterface interface {
+	CreateChain(version, tableName, chainName string) error
+	InsertIptableRule(version, tableName, chainName, match, target string) error
+	AppendIptableRule(version, tableName, chainName, match, target string) error
+	DeleteIptableRule(version, tableName, chainName, match, target string) error
+	Commit() error
+}
+
+// Transaction collects chain creations, inserts, appends and deletes and applies them on Commit.
+// The legacy backend writes all changes of an ip version to a single iptables-restore --noflush call,
+// the nft backend sends them as one nftables batch.
+// Chains and rules that already exist are not added again and deletes of missing rules are dropped,
+// so the methods can be used in place of the Client methods of the same name.
+type Transaction struct {
+	backend string
+	entries []txEntry
+
+	save        func(version, tableName string) (string, error)
+	chainExists func(version, tableName, chainName string) bool
+	ruleExists  func(version, tableName, chainName, match, target string) bool
+	restore     func(version, data string) error
+	runNft      func(version string, params []string) error
+}
+
+// NewTransaction starts a transaction that is committed with the backend of the client.
+func (c *Client) NewTransaction() TransactionInterface {
+	return &Transaction{
+		backend:     c.getBackend(),
+		save:        runSave,
+		chainExists: c.ChainExists,
+		ruleExists:  c.RuleExists,
+		restore:     runRestore,
+		runNft:      runNftTransaction,
+	}
+}
+
+// CreateChain queues the creation of a chain.
+func (t *Transaction) CreateChain(version, tableName, chainName string) error {
+	t.entries = append(t.entries, txEntry{version: version, action: createChain, table: tableName, chain: chainName})
+	return nil
+}
+
+// InsertIptableRule queues the insertion of a rule at the beginning of a chain.
+func (t *Transaction) InsertIptableRule(version, tableName, chainName, match, target string) error {
+	t.entries = append(t.entries, txEntry{version: version, action: Insert, table: tableName, chain: chainName, match: match, target: target})
+	return nil
+}
+
+// AppendIptableRule queues the addition of a rule at the end of a chain.
+func (t *Transaction) AppendIptableRule(version, tableName, chainName, match, target string) error {
+	t.entries = append(t.entries, txEntry{version: version, action: Append, table: tableName, chain: chainName, match: match, target: target})
+	return nil
+}
+
+// DeleteIptableRule queues the deletion of a rule.
+func (t *Transaction) DeleteIptableRule(version, tableName, chainName, match, target string) error {
+	t.entries = append(t.entries, txEntry{version: version, action: Delete, table: tableName, chain: chainName, match: match, target: target})
+	return nil
+}
+
+// tableState tells which chains and rules of a table exist.
+type tableState interface {
+	hasChain(chainName string) bool
+	hasRule(chainName, match, target string) bool
+}
+
+// savedTable is a table as printed by iptables-save. Rules are compared in the canonical form of
+// the nft backend, so that the rules queued by callers match the way iptables-save prints them.
+// Rules outside of the syntax the nft backend understands are compared as written.
+type savedTable struct {
+	chains map[string]bool
+	rules  map[string]bool
+}
+
+func parseSavedTable(data string) *savedTable {
+	table := &savedTable{chains: make(map[string]bool), rules: make(map[string]bool)}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, ":"):
+			if fields := strings.Fields(line[1:]); len(fields) > 0 {
+				table.chains[fields[0]] = true
+			}
+		case strings.HasPrefix(line, "-A "):
+			args, err := splitParams(line)
+			if err != nil || len(args) < 2 {
+				continue
+			}
+			table.rules[ruleKey(args[1], args[2:])] = true
+		}
+	}
+	return table
+}
+
+func (s *savedTable) hasChain(chainName string) bool {
+	return s.chains[chainName]
+}
+
+func (s *savedTable) hasRule(chainName, match, target string) bool {
+	args, err := splitParams(match + " -j " + target)
+	if err != nil {
+		return false
+	}
+	return s.rules[ruleKey(chainName, args)]
+}
+
+// ruleKey identifies a rule of a chain regardless of how its options are written.
+func ruleKey(chainName string, args []string) string {
+	rule, err := parseRuleSpec(args)
+	if err != nil {
+		return chainName + " " + strings.Join(args, " ")
+	}
+
+	// iptables-save prints conntrack states in a fixed order and --set-mark as the equivalent --set-xmark.
+	sort.Strings(rule.ctStates)
+	if rule.hasSetMark && !rule.setMarkXor {
+		rule.setMarkXor = true
+		rule.setMarkMask |= rule.setMark
+	}
+
+	return chainName + " " + rule.String()
+}
+
+// liveTable looks every chain and rule up when it is planned, which the nft backend does over netlink.
+type liveTable struct {
+	t         *Transaction
+	version   string
+	tableName string
+}
+
+func (l liveTable) hasChain(chainName string) bool {
+	return l.t.chainExists(l.version, l.tableName, chainName)
+}
+
+func (l liveTable) hasRule(chainName, match, target string) bool {
+	return l.t.ruleExists(l.version, l.tableName, chainName, match, target)
+}
+
+// tableState reads the state of a table. The legacy backend reads the whole table with a single
+// iptables-save instead of running iptables once per queued change.
+func (t *Transaction) tableState(version, tableName string) (tableState, error) {
+	if t.backend == BackendNft {
+		return liveTable{t: t, version: version, tableName: tableName}, nil
+	}
+
+	data, err := t.save(version, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return parseSavedTable(data), nil
+}
+
+// plan drops the queued changes that are already in effect. A rule that is added and then deleted
+// within the transaction is dropped altogether.
+// The state of the tables is read right before the changes are applied. A chain created or a rule deleted
+// by someone else in between makes iptables-restore fail, which rolls the transaction back.
+func (t *Transaction) plan() ([]txEntry, error) {
+	var (
+		planned []txEntry
+		dropped = make(map[int]bool)
+		chains  = make(map[string]bool)
+		rules   = make(map[string]bool)
+		added   = make(map[string]int)
+		states  = make(map[string]tableState)
+	)
+
+	inEffect := func(e txEntry) (bool, error) {
+		state, ok := states[e.version+" "+e.table]
+		if !ok {
+			var err error
+			if state, err = t.tableState(e.version, e.table); err != nil {
+				return false, err
+			}
+			states[e.version+" "+e.table] = state
+		}
+
+		if e.action == createChain {
+			return state.hasChain(e.chain), nil
+		}
+		return state.hasRule(e.chain, e.match, e.target), nil
+	}
+
+	for _, e := range t.entries {
+		key := e.key()
+
+		switch e.action {
+		case createChain:
+			exists, ok := chains[key]
+			if !ok {
+				var err error
+				if exists, err = inEffect(e); err != nil {
+					return nil, err
+				}
+			}
+			chains[key] = true
+			if exists {
+				continue
+			}
+		case Delete:
+			if i, ok := added[key]; ok {
+				dropped[i] = true
+				delete(added, key)
+				rules[key] = false
+				continue
+			}
+			exists, ok := rules[key]
+			if !ok {
+				var err error
+				if exists, err = inEffect(e); err != nil {
+					return nil, err
+				}
+			}
+			rules[key] = false
+			if !exists {
+				continue
+			}
+		default:
+			exists, ok := rules[key]
+			if !ok {
+				var err error
+				if exists, err = inEffect(e); err != nil {
+					return nil, err
+				}
+			}
+			rules[key] = true
+			if exists {
+				continue
+			}
+			added[key] = len(planned)
+		}
+
+		planned = append(planned, e)
+	}
+
+	result := make([]txEntry, 0, len(planned))
+	for i, e := range planned {
+		if !dropped[i] {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+// Commit applies the queued changes and clears the transaction.
+func (t *Transaction) Commit() error {
+	planned, err := t.plan()
+	t.entries = nil
+	if err != nil {
+		return errors.Wrap(err, "failed to read iptables state")
+	}
+
+	if len(planned) == 0 {
+		logger.Info("No iptables changes to commit")
+		return nil
+	}
+
+	// the changes of each ip version are committed separately, the versions committed before one fails are undone
+	var committed []txEntry
+	for _, version := range []string{V4, V6} {
+		var entries []txEntry
+		for _, e := range planned {
+			if e.version == version {
+				entries = append(entries, e)
+			}
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		switch t.backend {
+		case BackendNft:
+			err = t.commitNft(version, entries)
+		case BackendLegacy, "":
+			err = t.commitRestore(version, entries)
+		default:
+			err = errors.Wrapf(errUnsupportedBackend, "%s", t.backend)
+		}
+
+		if err != nil {
+			t.undo(committed)
+			return err
+		}
+		committed = append(committed, entries...)
+	}
+
+	return nil
+}
+
+// undo reverts committed changes, in reverse order, with the backend of the transaction.
+func (t *Transaction) undo(committed []txEntry) {
+	for _, version := range []string{V4, V6} {
+		var undo []txEntry
+		for i := len(committed) - 1; i >= 0; i-- {
+			if committed[i].version == version {
+				undo = append(undo, committed[i].inverse())
+			}
+		}
+
+		if len(undo) == 0 {
+			continue
+		}
+
+		logger.Info("Undoing committed iptables changes", zap.String("version", version), zap.Int("changes", len(undo)))
+		var err error
+		if t.backend == BackendNft {
+			err = t.runNft(version, nftParams(undo))
+		} else {
+			err = t.restore(version, restoreData(undo))
+		}
+		if err != nil {
+			logger.Error("Failed to undo committed iptables changes", zap.String("version", version), zap.Error(err))
+		}
+	}
+}
+
+// commitNft programs the changes as one nftables batch, which the kernel applies atomically.
+func (t *Transaction) commitNft(version string, entries []txEntry) error {
+	logger.Info("Committing iptables transaction", zap.String("version", version), zap.Int("changes", len(entries)))
+	return errors.Wrap(t.runNft(version, nftParams(entries)), "failed to commit nftables batch")
+}
+
+// nftParams renders the changes as the iptables parameters the nft backend translates.
+func nftParams(entries []txEntry) []string {
+	params := make([]string, 0, len(entries))
+	for _, e := range entries {
+		params = append(params, fmt.Sprintf("-t %s %s", e.table, e.rule()))
+	}
+	return params
+}
+
+// commitRestore programs the changes with iptables-restore and undoes them if it fails.
+func (t *Transaction) commitRestore(version string, entries []txEntry) error {
+	data := restoreData(entries)
+	logger.Info("Committing iptables transaction", zap.String("version", version), zap.String("data", data))
+
+	err := t.restore(version, data)
+	if err == nil {
+		return nil
+	}
+
+	logger.Error("iptables-restore failed, rolling back", zap.String("version", version), zap.Error(err))
+	t.rollback(entries)
+
+	return errors.Wrap(err, "failed to commit iptables transaction")
+}
+
+// rollback undoes the changes of the tables that were committed before iptables-restore failed.
+// iptables-restore commits one table at a time and each table either takes all of its changes or
+// none, so looking at the first change of a table is enough to tell whether it has to be undone.
+func (t *Transaction) rollback(entries []txEntry) {
+	for _, table := range restoreTables(entries) {
+		var changes []txEntry
+		for _, e := range entries {
+			if e.table == table {
+				changes = append(changes, e)
+			}
+		}
+
+		state, err := t.tableState(changes[0].version, table)
+		if err != nil {
+			logger.Error("Failed to read iptables table for rollback", zap.String("table", table), zap.Error(err))
+			continue
+		}
+
+		if !applied(state, changes[0]) {
+			continue
+		}
+
+		undo := make([]txEntry, 0, len(changes))
+		for i := len(changes) - 1; i >= 0; i-- {
+			undo = append(undo, changes[i].inverse())
+		}
+
+		if err := t.restore(changes[0].version, restoreData(undo)); err != nil {
+			logger.Error("Failed to roll back iptables table", zap.String("table", table), zap.Error(err))
+		}
+	}
+}
+
+// applied checks whether a change is in effect.
+func applied(state tableState, e txEntry) bool {
+	switch e.action {
+	case createChain:
+		return state.hasChain(e.chain)
+	case Delete:
+		return !state.hasRule(e.chain, e.match, e.target)
+	default:
+		return state.hasRule(e.chain, e.match, e.target)
+	}
+}
+
+// restoreTables returns the tables changed by the entries in order of first use.
+func restoreTables(entries []txEntry) []string {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !seen[e.table] {
+			seen[e.table] = true
+			tables = append(tables, e.table)
+		}
+	}
+	return tables
+}
+
+// restoreData renders the changes as iptables-restore input, with one section per table.
+func restoreData(entries []txEntry) string {
+	var b strings.Builder
+	for _, table := range restoreTables(entries) {
+		fmt.Fprintf(&b, "*%s\n", table)
+		for _, e := range entries {
+			if e.table == table {
+				fmt.Fprintf(&b, "%s\n", e.rule())
+			}
+		}
+		fmt.Fprintf(&b, "%s\n", restoreCommit)
+	}
+	return b.String()
+}
+
+// runSave returns a table in iptables-save format.
+func runSave(version, tableName string) (string, error) {
+	saveCmd := iptablesSave
+	if version == V6 {
+		saveCmd = ip6tablesSave
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, saveCmd, "-t", tableName)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrapf(err, "%s failed: %s", saveCmd, msg)
+		}
+		return "", errors.Wrapf(err, "%s failed", saveCmd)
+	}
+
+	return string(out), nil
+}
+
+// runRestore feeds data to iptables-restore without flushing the existing rules.
+func runRestore(version, data string) error {
+	restoreCmd := iptablesRestore
+	if version == V6 {
+		restoreCmd = ip6tablesRestore
+	}
+
+	args := []string{restoreNoFlush}
+	if !DisableIPTableLock {
+		args = append(args, "-w", strconv.Itoa(lockTimeout))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, restoreCmd, args...)
+	cmd.Stdin = strings.NewReader(data)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.Wrapf(err, "%s failed: %s", restoreCmd, msg)
+		}
+		return errors.Wrapf(err, "%s failed", restoreCmd)
+	}
+
+	return nil
+}
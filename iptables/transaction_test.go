@@ -0,0 +1,255 @@
+package iptables
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var errMockRestore = errors.New("mock iptables-restore failure")
+
+// fakeRuleset records the tables in iptables-save format and the iptables-restore input received.
+type fakeRuleset struct {
+	tables   map[string]string
+	saves    []string
+	restores []string
+	failures int
+}
+
+func newFakeTransaction(backend string, ruleset *fakeRuleset) *Transaction {
+	return &Transaction{
+		backend: backend,
+		save: func(version, tableName string) (string, error) {
+			ruleset.saves = append(ruleset.saves, version+" "+tableName)
+			return ruleset.tables[tableName], nil
+		},
+		chainExists: func(_, _, _ string) bool {
+			return false
+		},
+		ruleExists: func(_, _, _, _, _ string) bool {
+			return false
+		},
+		restore: func(_, data string) error {
+			ruleset.restores = append(ruleset.restores, data)
+			if ruleset.failures > 0 {
+				ruleset.failures--
+				return errMockRestore
+			}
+			return nil
+		},
+		runNft: func(_ string, params []string) error {
+			for _, p := range params {
+				ruleset.restores = append(ruleset.restores, p)
+			}
+			return nil
+		},
+	}
+}
+
+func TestTransactionRestoreData(t *testing.T) {
+	ruleset := &fakeRuleset{
+		tables: map[string]string{
+			Filter: `*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:AZURECNIINPUT - [0:0]
+-A INPUT -j AZURECNIINPUT
+COMMIT
+`,
+		},
+	}
+
+	tx := newFakeTransaction(BackendLegacy, ruleset)
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIOutputChain, "-s 169.254.0.1 -d 169.254.0.4", Accept))
+	require.NoError(t, tx.InsertIptableRule(V4, Nat, Postrouting, "-s 169.254.0.0/16", Masquerade))
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIInputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Input, "", CNIInputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIInputChain, " -i azSnatbr -m state --state ESTABLISHED,RELATED", Accept))
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+	require.NoError(t, tx.AppendIptableRule(V4, Filter, Forward, "", Accept))
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, Forward, "-d 10.0.0.1", Drop))
+	require.NoError(t, tx.Commit())
+
+	expected := `*filter
+-N AZURECNIOUTPUT
+-I OUTPUT 1 -j AZURECNIOUTPUT
+-I AZURECNIOUTPUT 1 -s 169.254.0.1 -d 169.254.0.4 -j ACCEPT
+-I AZURECNIINPUT 1 -i azSnatbr -m state --state ESTABLISHED,RELATED -j ACCEPT
+-A FORWARD -j ACCEPT
+COMMIT
+*nat
+-I POSTROUTING 1 -s 169.254.0.0/16 -j MASQUERADE
+COMMIT
+`
+	require.Equal(t, []string{expected}, ruleset.restores)
+	// Each table is read once, however many changes it has.
+	require.Equal(t, []string{V4 + " " + Filter, V4 + " " + Nat}, ruleset.saves)
+
+	// The transaction is cleared once committed.
+	require.NoError(t, tx.Commit())
+	require.Len(t, ruleset.restores, 1)
+}
+
+func TestTransactionPlan(t *testing.T) {
+	ruleset := &fakeRuleset{
+		tables: map[string]string{
+			Filter: "*filter\n:FORWARD ACCEPT [0:0]\n-A FORWARD -d 10.0.0.1/32 -j DROP\nCOMMIT\n",
+		},
+	}
+
+	tx := newFakeTransaction(BackendLegacy, ruleset)
+	// Added and deleted within the transaction.
+	require.NoError(t, tx.AppendIptableRule(V4, Filter, Forward, "", Accept))
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, Forward, "", Accept))
+	// Deleted twice.
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, Forward, "-d 10.0.0.1", Drop))
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, Forward, "-d 10.0.0.1", Drop))
+	// Deleted but missing.
+	require.NoError(t, tx.DeleteIptableRule(V4, Filter, Forward, "-d 10.0.0.2", Drop))
+	// IPv6 changes are committed separately.
+	require.NoError(t, tx.InsertIptableRule(V6, Nat, Postrouting, "-s fd00::/64", Masquerade))
+
+	require.Equal(t, []txEntry{
+		{version: V4, action: Delete, table: Filter, chain: Forward, match: "-d 10.0.0.1", target: Drop},
+		{version: V6, action: Insert, table: Nat, chain: Postrouting, match: "-s fd00::/64", target: Masquerade},
+	}, mustPlan(t, tx))
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t, []string{
+		"*filter\n-D FORWARD -d 10.0.0.1 -j DROP\nCOMMIT\n",
+		"*nat\n-I POSTROUTING 1 -s fd00::/64 -j MASQUERADE\nCOMMIT\n",
+	}, ruleset.restores)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ruleset := &fakeRuleset{tables: map[string]string{}}
+
+	tx := newFakeTransaction(BackendLegacy, ruleset)
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Nat, Postrouting, "-s 169.254.0.0/16", Masquerade))
+
+	// iptables-restore committed the filter table before failing on the nat table.
+	tx.restore = func(_, data string) error {
+		ruleset.restores = append(ruleset.restores, data)
+		if len(ruleset.restores) == 1 {
+			ruleset.tables[Filter] = "*filter\n:OUTPUT ACCEPT [0:0]\n:AZURECNIOUTPUT - [0:0]\n-A OUTPUT -j AZURECNIOUTPUT\nCOMMIT\n"
+			return errMockRestore
+		}
+		return nil
+	}
+
+	require.ErrorIs(t, tx.Commit(), errMockRestore)
+	require.Len(t, ruleset.restores, 2)
+	require.Equal(t, "*filter\n-D OUTPUT -j AZURECNIOUTPUT\n-X AZURECNIOUTPUT\nCOMMIT\n", ruleset.restores[1])
+}
+
+func TestTransactionUndoesCommittedVersions(t *testing.T) {
+	for _, backend := range []string{BackendLegacy, BackendNft} {
+		ruleset := &fakeRuleset{tables: map[string]string{}}
+
+		tx := newFakeTransaction(backend, ruleset)
+		require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+		require.NoError(t, tx.InsertIptableRule(V4, Filter, Output, "", CNIOutputChain))
+		require.NoError(t, tx.InsertIptableRule(V6, Filter, Output, "", CNIOutputChain))
+
+		// the ipv4 changes are committed before the ipv6 ones fail
+		var applied []string
+		apply := func(version, data string) error {
+			if version == V6 {
+				return errMockRestore
+			}
+			applied = append(applied, data)
+			return nil
+		}
+		tx.restore = apply
+		tx.runNft = func(version string, params []string) error {
+			return apply(version, strings.Join(params, "\n")+"\n")
+		}
+
+		require.ErrorIs(t, tx.Commit(), errMockRestore, backend)
+		require.Len(t, applied, 2, backend)
+		require.Contains(t, applied[1], "-D OUTPUT -j AZURECNIOUTPUT\n", backend)
+		require.Contains(t, applied[1], "-X AZURECNIOUTPUT\n", backend)
+		require.Less(t, strings.Index(applied[1], "-D OUTPUT"), strings.Index(applied[1], "-X AZURECNIOUTPUT"), backend)
+	}
+}
+
+func mustPlan(t *testing.T, tx *Transaction) []txEntry {
+	t.Helper()
+	planned, err := tx.plan()
+	require.NoError(t, err)
+	return planned
+}
+
+func TestTransactionMatchesSavedRules(t *testing.T) {
+	ruleset := &fakeRuleset{
+		tables: map[string]string{
+			Filter: `*filter
+:INPUT ACCEPT [0:0]
+:AZURECNIINPUT - [0:0]
+-A AZURECNIINPUT -i azSnatbr -m state --state RELATED,ESTABLISHED -j ACCEPT
+-A AZURECNIINPUT -s 169.254.0.4/32 -d 169.254.0.1/32 -j ACCEPT
+COMMIT
+`,
+			Nat: `*nat
+:POSTROUTING ACCEPT [0:0]
+:SWIFT - [0:0]
+-A SWIFT -d 168.63.129.16/32 -p udp -m udp --dport 53 -j SNAT --to-source 10.0.0.4
+-A POSTROUTING -m comment --comment "kube-proxy rule" -m statistic --mode random --probability 0.5 -j RETURN
+COMMIT
+`,
+		},
+	}
+
+	tx := newFakeTransaction(BackendLegacy, ruleset)
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIInputChain))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIInputChain, " -i azSnatbr -m state --state ESTABLISHED,RELATED", Accept))
+	require.NoError(t, tx.InsertIptableRule(V4, Filter, CNIInputChain, "-s 169.254.0.4 -d 169.254.0.1", Accept))
+	require.NoError(t, tx.InsertIptableRule(V4, Nat, Swift, "-d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.0.0.4"))
+	require.NoError(t, tx.DeleteIptableRule(V4, Nat, Postrouting, "-m comment --comment \"kube-proxy rule\" -m statistic --mode random --probability 0.5", Return))
+	require.NoError(t, tx.AppendIptableRule(V4, Nat, Swift, "-d 168.63.129.16 -p tcp --dport 53", "SNAT --to 10.0.0.4"))
+
+	require.Equal(t, []txEntry{
+		{version: V4, action: Delete, table: Nat, chain: Postrouting, match: "-m comment --comment \"kube-proxy rule\" -m statistic --mode random --probability 0.5", target: Return},
+		{version: V4, action: Append, table: Nat, chain: Swift, match: "-d 168.63.129.16 -p tcp --dport 53", target: "SNAT --to 10.0.0.4"},
+	}, mustPlan(t, tx))
+}
+
+func TestTransactionSaveFailure(t *testing.T) {
+	ruleset := &fakeRuleset{}
+	tx := newFakeTransaction(BackendLegacy, ruleset)
+	tx.save = func(_, _ string) (string, error) {
+		return "", errMockRestore
+	}
+
+	require.NoError(t, tx.CreateChain(V4, Filter, CNIOutputChain))
+	require.ErrorIs(t, tx.Commit(), errMockRestore)
+	require.Empty(t, ruleset.restores)
+}
+
+func TestTransactionNft(t *testing.T) {
+	ruleset := &fakeRuleset{}
+
+	tx := newFakeTransaction(BackendNft, ruleset)
+	require.NoError(t, tx.CreateChain(V4, Nat, Swift))
+	require.NoError(t, tx.AppendIptableRule(V4, Nat, Postrouting, "", Swift))
+	require.NoError(t, tx.InsertIptableRule(V4, Nat, Swift, "-d 168.63.129.16 -p udp --dport 53", "SNAT --to 10.0.0.4"))
+	require.NoError(t, tx.Commit())
+
+	require.Equal(t, []string{
+		"-t nat -N SWIFT",
+		"-t nat -A POSTROUTING -j SWIFT",
+		"-t nat -I SWIFT 1 -d 168.63.129.16 -p udp --dport 53 -j SNAT --to 10.0.0.4",
+	}, ruleset.restores)
+
+	tx = newFakeTransaction("unknown", ruleset)
+	require.NoError(t, tx.CreateChain(V4, Nat, Swift))
+	require.ErrorIs(t, tx.Commit(), errUnsupportedBackend)
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Microsoft. All rights reserved.
+// MIT License
+
+package netlink
+
+import "net"
+
+// EventType identifies the kind of change reported by an Event.
+type EventType int
+
+const (
+	EventLinkAdd EventType = iota
+	EventLinkDelete
+	EventRouteAdd
+	EventRouteDelete
+	EventAddrAdd
+	EventAddrDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLinkAdd:
+		return "LinkAdd"
+	case EventLinkDelete:
+		return "LinkDelete"
+	case EventRouteAdd:
+		return "RouteAdd"
+	case EventRouteDelete:
+		return "RouteDelete"
+	case EventAddrAdd:
+		return "AddrAdd"
+	case EventAddrDelete:
+		return "AddrDelete"
+	default:
+		return "Unknown"
+	}
+}
+
+// Addr represents an IP address assigned to a network interface.
+type Addr struct {
+	LinkIndex int
+	IPNet     *net.IPNet
+}
+
+// Event is a link, route or address change notification received from the kernel.
+// Only the field matching the event type is set.
+type Event struct {
+	Type  EventType
+	Link  *LinkInfo
+	Route *Route
+	Addr  *Addr
+}
@@ -47,6 +47,7 @@ type Link interface {
 type LinkInfo struct {
 	Type        string
 	Name        string
+	Index       int
 	Flags       net.Flags
 	MTU         uint
 	TxQLen      uint
@@ -424,43 +425,66 @@ func (Netlink) SetLinkHairpin(bridgeName string, on bool) error {
 
 // SetOrRemoveLinkAddress sets/removes static arp entry based on mode
 func (Netlink) SetOrRemoveLinkAddress(linkInfo LinkInfo, mode, linkState int) error {
-	s, err := getSocket()
+	iface, err := net.InterfaceByName(linkInfo.Name)
 	if err != nil {
 		return err
 	}
 
-	var req *message
-	state := 0
-	if mode == ADD {
-		req = newRequest(unix.RTM_NEWNEIGH, unix.NLM_F_CREATE|unix.NLM_F_REPLACE|unix.NLM_F_ACK)
-	} else {
-		req = newRequest(unix.RTM_DELNEIGH, unix.NLM_F_ACK)
+	neigh := &Neigh{
+		LinkIndex:    iface.Index,
+		State:        linkState,
+		IP:           linkInfo.IPAddr,
+		HardwareAddr: linkInfo.MacAddress,
 	}
-	state = linkState
 
-	iface, err := net.InterfaceByName(linkInfo.Name)
+	return setNeigh(neigh, mode == ADD)
+}
+
+// ListLinks returns all network interfaces.
+func (Netlink) ListLinks() ([]*LinkInfo, error) {
+	s, err := getSocket()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg := neighMsg{
-		Family: uint8(GetIPAddressFamily(linkInfo.IPAddr)),
-		Index:  uint32(iface.Index),
-		State:  uint16(state),
+	req := newRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP)
+	req.addPayload(newIfInfoMsg())
+
+	msgs, err := s.sendAndWaitForResponse(req)
+	if err != nil {
+		return nil, err
 	}
 
-	req.addPayload(&msg)
+	links := make([]*LinkInfo, 0, len(msgs))
+	for _, msg := range msgs {
+		if len(msg.data) < unix.SizeofIfInfomsg {
+			continue
+		}
+		links = append(links, deserializeLink(msg))
+	}
+
+	return links, nil
+}
 
-	ipData := linkInfo.IPAddr.To4()
-	if ipData == nil {
-		ipData = linkInfo.IPAddr.To16()
+// GetLinkByName returns the network interface with the given name.
+func (Netlink) GetLinkByName(name string) (*LinkInfo, error) {
+	s, err := getSocket()
+	if err != nil {
+		return nil, err
 	}
 
-	dstData := newRtAttr(NDA_DST, ipData)
-	req.addPayload(dstData)
+	req := newRequest(unix.RTM_GETLINK, 0)
+	req.addPayload(newIfInfoMsg())
+	req.addPayload(newAttributeStringZ(unix.IFLA_IFNAME, name))
 
-	hwData := newRtAttr(NDA_LLADDR, []byte(linkInfo.MacAddress))
-	req.addPayload(hwData)
+	msgs, err := s.sendAndWaitForResponse(req)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.sendAndWaitForAck(req)
+	if len(msgs) == 0 || len(msgs[0].data) < unix.SizeofIfInfomsg {
+		return nil, errors.Errorf("link %s not found", name)
+	}
+
+	return deserializeLink(msgs[0]), nil
 }
@@ -1,9 +1,11 @@
 package netlink
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
 )
 
 const BadEth = "badeth"
@@ -22,15 +24,25 @@ type MockNetlink struct {
 	errorString   string
 	deleteRouteFn routeValidateFn
 	addRouteFn    routeValidateFn
+	events        chan Event
+	rules         []*Rule
+	neighs        []*Neigh
 }
 
 func NewMockNetlink(returnError bool, errorString string) *MockNetlink {
 	return &MockNetlink{
 		returnError: returnError,
 		errorString: errorString,
+		events:      make(chan Event),
 	}
 }
 
+// InjectEvent delivers an event to the subscriber returned by Subscribe.
+// It blocks until an active subscription picks up the event.
+func (f *MockNetlink) InjectEvent(event Event) {
+	f.events <- event
+}
+
 func (f *MockNetlink) SetDeleteRouteValidationFn(fn routeValidateFn) {
 	f.deleteRouteFn = fn
 }
@@ -119,3 +131,94 @@ func (f *MockNetlink) DeleteIPRoute(r *Route) error {
 	}
 	return f.error()
 }
+
+func (f *MockNetlink) Subscribe(ctx context.Context) (<-chan Event, error) {
+	if err := f.error(); err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case event := <-f.events:
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (f *MockNetlink) ListLinks() ([]*LinkInfo, error) {
+	return nil, f.error()
+}
+
+func (f *MockNetlink) GetLinkByName(name string) (*LinkInfo, error) {
+	if name == BadEth {
+		return nil, ErrorMockNetlink
+	}
+	if err := f.error(); err != nil {
+		return nil, err
+	}
+	return &LinkInfo{Name: name}, nil
+}
+
+// AddRule records the rule so that it is returned by later calls to ListRules.
+func (f *MockNetlink) AddRule(rule *Rule) error {
+	if err := f.error(); err != nil {
+		return err
+	}
+	f.rules = append(f.rules, rule)
+	return nil
+}
+
+func (f *MockNetlink) DeleteRule(rule *Rule) error {
+	if err := f.error(); err != nil {
+		return err
+	}
+	for i := range f.rules {
+		if reflect.DeepEqual(f.rules[i], rule) {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return nil
+		}
+	}
+	return newErrorMockNetlink("rule not found")
+}
+
+func (f *MockNetlink) ListRules(int) ([]*Rule, error) {
+	return f.rules, f.error()
+}
+
+// SetNeigh records the neighbor entry so that it is returned by later calls to ListNeigh.
+func (f *MockNetlink) SetNeigh(neigh *Neigh) error {
+	if err := f.error(); err != nil {
+		return err
+	}
+	f.neighs = append(f.neighs, neigh)
+	return nil
+}
+
+func (f *MockNetlink) DeleteNeigh(neigh *Neigh) error {
+	if err := f.error(); err != nil {
+		return err
+	}
+	for i := range f.neighs {
+		if reflect.DeepEqual(f.neighs[i], neigh) {
+			f.neighs = append(f.neighs[:i], f.neighs[i+1:]...)
+			return nil
+		}
+	}
+	return newErrorMockNetlink("neighbor not found")
+}
+
+func (f *MockNetlink) ListNeigh(int, int) ([]*Neigh, error) {
+	return f.neighs, f.error()
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Neigh represents a neighbor (ARP or NDP) cache entry.
+type Neigh struct {
+	LinkIndex    int
+	Family       int
+	State        int
+	Flags        int
+	Type         int
+	IP           net.IP
+	HardwareAddr net.HardwareAddr
+}
+
+// deserializeNeigh decodes a netlink message into a Neigh struct.
+func deserializeNeigh(msg *message) *Neigh {
+	// Parse neighbor message.
+	neigh := Neigh{
+		Family:    int(msg.data[0]),
+		LinkIndex: int(encoder.Uint32(msg.data[4:8])),
+		State:     int(encoder.Uint16(msg.data[8:10])),
+		Flags:     int(msg.data[10]),
+		Type:      int(msg.data[11]),
+	}
+
+	// Populate neighbor attributes.
+	for _, attr := range msg.getAttributes(nil) {
+		switch attr.Type {
+		case NDA_DST:
+			neigh.IP = net.IP(attr.value)
+		case NDA_LLADDR:
+			neigh.HardwareAddr = net.HardwareAddr(attr.value)
+		}
+	}
+
+	return &neigh
+}
+
+// ListNeigh returns the neighbor entries of the given address family.
+// A non-zero linkIndex limits the result to entries on that interface.
+func (Netlink) ListNeigh(linkIndex int, family int) ([]*Neigh, error) {
+	s, err := getSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	req := newRequest(unix.RTM_GETNEIGH, unix.NLM_F_DUMP)
+	req.addPayload(&neighMsg{
+		Family: uint8(family),
+		Index:  uint32(linkIndex),
+	})
+
+	msgs, err := s.sendAndWaitForResponse(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var neighs []*Neigh
+	for _, msg := range msgs {
+		if len(msg.data) < unix.SizeofNdMsg {
+			continue
+		}
+
+		neigh := deserializeNeigh(msg)
+
+		// Filter by link index.
+		if linkIndex != 0 && linkIndex != neigh.LinkIndex {
+			continue
+		}
+
+		neighs = append(neighs, neigh)
+	}
+
+	return neighs, nil
+}
+
+// setNeigh sends a neighbor entry set request.
+func setNeigh(neigh *Neigh, add bool) error {
+	var req *message
+
+	s, err := getSocket()
+	if err != nil {
+		return err
+	}
+
+	if add {
+		req = newRequest(unix.RTM_NEWNEIGH, unix.NLM_F_CREATE|unix.NLM_F_REPLACE|unix.NLM_F_ACK)
+	} else {
+		req = newRequest(unix.RTM_DELNEIGH, unix.NLM_F_ACK)
+	}
+
+	family := neigh.Family
+	if family == 0 {
+		family = GetIPAddressFamily(neigh.IP)
+	}
+
+	msg := neighMsg{
+		Family: uint8(family),
+		Index:  uint32(neigh.LinkIndex),
+		State:  uint16(neigh.State),
+		Flags:  uint8(neigh.Flags),
+		Type:   uint8(neigh.Type),
+	}
+
+	req.addPayload(&msg)
+
+	ipData := neigh.IP.To4()
+	if ipData == nil {
+		ipData = neigh.IP.To16()
+	}
+
+	req.addPayload(newRtAttr(NDA_DST, ipData))
+
+	if neigh.HardwareAddr != nil {
+		req.addPayload(newRtAttr(NDA_LLADDR, []byte(neigh.HardwareAddr)))
+	}
+
+	return s.sendAndWaitForAck(req)
+}
+
+// SetNeigh adds or replaces a neighbor entry.
+func (Netlink) SetNeigh(neigh *Neigh) error {
+	return setNeigh(neigh, true)
+}
+
+// DeleteNeigh deletes a neighbor entry.
+func (Netlink) DeleteNeigh(neigh *Neigh) error {
+	return setNeigh(neigh, false)
+}
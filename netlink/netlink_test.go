@@ -7,8 +7,10 @@
 package netlink
 
 import (
+	"context"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sys/unix"
@@ -375,3 +377,225 @@ func TestAddDeleteRoute(t *testing.T) {
 		t.Errorf("DeleteLink failed: %+v", err)
 	}
 }
+
+// waitForEvent returns the first event that matches the given type and predicate.
+func waitForEvent(t *testing.T, events <-chan Event, eventType EventType, match func(Event) bool) Event {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "event channel closed")
+			if event.Type == eventType && match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event", eventType)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nl := NewNetlink()
+	events, err := nl.Subscribe(ctx)
+	require.NoError(t, err)
+
+	err = nl.AddLink(&BridgeLink{
+		LinkInfo: LinkInfo{
+			Type: LINK_TYPE_BRIDGE,
+			Name: ifName,
+		},
+	})
+	require.NoError(t, err)
+
+	bridge, err := net.InterfaceByName(ifName)
+	require.NoError(t, err)
+
+	event := waitForEvent(t, events, EventLinkAdd, func(e Event) bool { return e.Link.Name == ifName })
+	require.Equal(t, bridge.Index, event.Link.Index)
+
+	err = nl.SetLinkState(ifName, true)
+	require.NoError(t, err)
+
+	ip := net.ParseIP("192.168.0.4")
+	_, ipNet, _ := net.ParseCIDR("192.168.0.4/24")
+	err = nl.AddIPAddress(ifName, ip, ipNet)
+	require.NoError(t, err)
+
+	event = waitForEvent(t, events, EventAddrAdd, func(e Event) bool { return e.Addr.LinkIndex == bridge.Index })
+	require.Equal(t, "192.168.0.4/24", event.Addr.IPNet.String())
+
+	// Adding the address installs a connected route on the link.
+	event = waitForEvent(t, events, EventRouteAdd, func(e Event) bool { return e.Route.LinkIndex == bridge.Index })
+	require.Equal(t, unix.AF_INET, event.Route.Family)
+
+	err = nl.DeleteLink(ifName)
+	require.NoError(t, err)
+
+	waitForEvent(t, events, EventLinkDelete, func(e Event) bool { return e.Link.Index == bridge.Index })
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestMockSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	nl := NewMockNetlink(false, "")
+	events, err := nl.Subscribe(ctx)
+	require.NoError(t, err)
+
+	_, dst, _ := net.ParseCIDR("10.0.0.0/24")
+	go nl.InjectEvent(Event{Type: EventRouteDelete, Route: &Route{Dst: dst}})
+
+	event := <-events
+	require.Equal(t, EventRouteDelete, event.Type)
+	require.Equal(t, dst, event.Route.Dst)
+
+	cancel()
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestListLinksAndGetLinkByName(t *testing.T) {
+	nl := NewNetlink()
+	err := nl.AddLink(&BridgeLink{
+		LinkInfo: LinkInfo{
+			Type: LINK_TYPE_BRIDGE,
+			Name: ifName,
+		},
+	})
+	require.NoError(t, err)
+	defer nl.DeleteLink(ifName) //nolint:errcheck // best effort cleanup
+
+	bridge, err := net.InterfaceByName(ifName)
+	require.NoError(t, err)
+
+	link, err := nl.GetLinkByName(ifName)
+	require.NoError(t, err)
+	require.Equal(t, bridge.Index, link.Index)
+	require.Equal(t, LINK_TYPE_BRIDGE, link.Type)
+	require.Equal(t, bridge.HardwareAddr, link.MacAddress)
+
+	links, err := nl.ListLinks()
+	require.NoError(t, err)
+
+	found := false
+	for _, l := range links {
+		if l.Name == ifName {
+			found = true
+			require.Equal(t, bridge.Index, l.Index)
+		}
+	}
+	require.True(t, found, "link %s not listed", ifName)
+
+	_, err = nl.GetLinkByName("nonexistent0")
+	require.Error(t, err)
+}
+
+func TestAddDeleteRule(t *testing.T) {
+	nl := NewNetlink()
+	_, src, _ := net.ParseCIDR("10.10.0.0/16")
+	rule := &Rule{
+		Family:   unix.AF_INET,
+		Priority: 3000,
+		Table:    300,
+		Mark:     0x14d,
+		Src:      src,
+		IifName:  ifName,
+	}
+
+	err := nl.AddRule(rule)
+	require.NoError(t, err)
+
+	findRule := func() *Rule {
+		rules, err := nl.ListRules(unix.AF_INET)
+		require.NoError(t, err)
+		for _, r := range rules {
+			if r.Priority == rule.Priority && r.Table == rule.Table {
+				return r
+			}
+		}
+		return nil
+	}
+
+	added := findRule()
+	require.NotNil(t, added)
+	require.Equal(t, rule.Mark, added.Mark)
+	require.Equal(t, src.String(), added.Src.String())
+	require.Equal(t, ifName, added.IifName)
+
+	// Adding the same rule again is rejected.
+	require.Error(t, nl.AddRule(rule))
+
+	err = nl.DeleteRule(rule)
+	require.NoError(t, err)
+	require.Nil(t, findRule())
+}
+
+func TestSetDeleteNeigh(t *testing.T) {
+	nl := NewNetlink()
+	err := nl.AddLink(&BridgeLink{
+		LinkInfo: LinkInfo{
+			Type: LINK_TYPE_BRIDGE,
+			Name: ifName,
+		},
+	})
+	require.NoError(t, err)
+	defer nl.DeleteLink(ifName) //nolint:errcheck // best effort cleanup
+
+	link, err := nl.GetLinkByName(ifName)
+	require.NoError(t, err)
+
+	mac, _ := net.ParseMAC("aa:b3:4d:5e:e2:4a")
+	neigh := &Neigh{
+		LinkIndex:    link.Index,
+		State:        NUD_PERMANENT,
+		IP:           net.ParseIP("192.168.0.2"),
+		HardwareAddr: mac,
+	}
+
+	err = nl.SetNeigh(neigh)
+	require.NoError(t, err)
+
+	neighs, err := nl.ListNeigh(link.Index, unix.AF_INET)
+	require.NoError(t, err)
+	require.Len(t, neighs, 1)
+	require.True(t, neighs[0].IP.Equal(neigh.IP))
+	require.Equal(t, mac, neighs[0].HardwareAddr)
+	require.Equal(t, NUD_PERMANENT, neighs[0].State)
+
+	err = nl.DeleteNeigh(neigh)
+	require.NoError(t, err)
+
+	neighs, err = nl.ListNeigh(link.Index, unix.AF_INET)
+	require.NoError(t, err)
+	require.Empty(t, neighs)
+}
+
+func TestMockRulesAndNeighs(t *testing.T) {
+	nl := NewMockNetlink(false, "")
+
+	rule := &Rule{Mark: 1, Table: 2}
+	require.NoError(t, nl.AddRule(rule))
+	rules, err := nl.ListRules(unix.AF_INET)
+	require.NoError(t, err)
+	require.Equal(t, []*Rule{rule}, rules)
+	require.NoError(t, nl.DeleteRule(&Rule{Mark: 1, Table: 2}))
+	require.Error(t, nl.DeleteRule(rule))
+
+	neigh := &Neigh{LinkIndex: 1, IP: net.ParseIP("10.0.0.1")}
+	require.NoError(t, nl.SetNeigh(neigh))
+	neighs, err := nl.ListNeigh(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []*Neigh{neigh}, neighs)
+	require.NoError(t, nl.DeleteNeigh(neigh))
+
+	_, err = nl.GetLinkByName(BadEth)
+	require.Error(t, err)
+}
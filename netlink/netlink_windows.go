@@ -3,7 +3,14 @@
 
 package netlink
 
-import "net"
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+var errSubscribeNotSupported = errors.New("netlink subscriptions are not supported on Windows")
 
 // Link represents a network interface.
 type Link interface {
@@ -12,6 +19,10 @@ type Link interface {
 
 type Route struct{}
 
+type Rule struct{}
+
+type Neigh struct{}
+
 // LinkInfo respresents the common properties of all network interfaces.
 type LinkInfo struct {
 	Type string
@@ -85,3 +96,39 @@ func (Netlink) AddIPRoute(route *Route) error {
 func (Netlink) DeleteIPRoute(route *Route) error {
 	return nil
 }
+
+func (Netlink) Subscribe(ctx context.Context) (<-chan Event, error) {
+	return nil, errSubscribeNotSupported
+}
+
+func (Netlink) ListLinks() ([]*LinkInfo, error) {
+	return nil, nil
+}
+
+func (Netlink) GetLinkByName(name string) (*LinkInfo, error) {
+	return nil, nil
+}
+
+func (Netlink) AddRule(rule *Rule) error {
+	return nil
+}
+
+func (Netlink) DeleteRule(rule *Rule) error {
+	return nil
+}
+
+func (Netlink) ListRules(family int) ([]*Rule, error) {
+	return nil, nil
+}
+
+func (Netlink) SetNeigh(neigh *Neigh) error {
+	return nil
+}
+
+func (Netlink) DeleteNeigh(neigh *Neigh) error {
+	return nil
+}
+
+func (Netlink) ListNeigh(linkIndex int, family int) ([]*Neigh, error) {
+	return nil, nil
+}
@@ -4,6 +4,7 @@
 package netlink
 
 import (
+	"context"
 	"net"
 )
 
@@ -24,4 +25,13 @@ type NetlinkInterface interface {
 	GetIPRoute(filter *Route) ([]*Route, error)
 	AddIPRoute(route *Route) error
 	DeleteIPRoute(route *Route) error
+	Subscribe(ctx context.Context) (<-chan Event, error)
+	ListLinks() ([]*LinkInfo, error)
+	GetLinkByName(name string) (*LinkInfo, error)
+	AddRule(rule *Rule) error
+	DeleteRule(rule *Rule) error
+	ListRules(family int) ([]*Rule, error)
+	SetNeigh(neigh *Neigh) error
+	DeleteNeigh(neigh *Neigh) error
+	ListNeigh(linkIndex int, family int) ([]*Neigh, error)
 }
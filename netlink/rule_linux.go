@@ -0,0 +1,189 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Rule represents a netlink policy routing rule.
+type Rule struct {
+	Family   int
+	Priority int
+	Table    int
+	Mark     int
+	Mask     int
+	Src      *net.IPNet
+	Dst      *net.IPNet
+	IifName  string
+	OifName  string
+}
+
+// newRuleMsg creates a new rule header, which shares its layout with rtmsg.
+func newRuleMsg(family int) *rtMsg {
+	return &rtMsg{
+		RtMsg: unix.RtMsg{
+			Family: uint8(family),
+			Type:   unix.FR_ACT_TO_TBL,
+		},
+	}
+}
+
+// deserializeRule decodes a netlink message into a Rule struct.
+func deserializeRule(msg *message) *Rule {
+	// Parse rule header.
+	hdr := deserializeRtMsg(msg.data)
+	attrs := msg.getAttributes(hdr)
+
+	// Initialize a new rule object.
+	rule := Rule{
+		Family: int(hdr.Family),
+		Table:  int(hdr.Table),
+	}
+
+	// Populate rule attributes.
+	for _, attr := range attrs {
+		switch attr.Type {
+		case unix.FRA_TABLE:
+			rule.Table = int(encoder.Uint32(attr.value[0:4]))
+		case unix.FRA_PRIORITY:
+			rule.Priority = int(encoder.Uint32(attr.value[0:4]))
+		case unix.FRA_FWMARK:
+			rule.Mark = int(encoder.Uint32(attr.value[0:4]))
+		case unix.FRA_FWMASK:
+			rule.Mask = int(encoder.Uint32(attr.value[0:4]))
+		case unix.FRA_SRC:
+			rule.Src = &net.IPNet{
+				IP:   attr.value,
+				Mask: net.CIDRMask(int(hdr.Src_len), 8*len(attr.value)),
+			}
+		case unix.FRA_DST:
+			rule.Dst = &net.IPNet{
+				IP:   attr.value,
+				Mask: net.CIDRMask(int(hdr.Dst_len), 8*len(attr.value)),
+			}
+		case unix.FRA_IIFNAME:
+			rule.IifName = string(trimNull(attr.value))
+		case unix.FRA_OIFNAME:
+			rule.OifName = string(trimNull(attr.value))
+		}
+	}
+
+	return &rule
+}
+
+// ListRules returns the policy routing rules of the given address family.
+func (Netlink) ListRules(family int) ([]*Rule, error) {
+	s, err := getSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	req := newRequest(unix.RTM_GETRULE, unix.NLM_F_DUMP)
+	req.addPayload(newRuleMsg(family))
+
+	msgs, err := s.sendAndWaitForResponse(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*Rule, 0, len(msgs))
+	for _, msg := range msgs {
+		if len(msg.data) < unix.SizeofRtMsg {
+			continue
+		}
+		rules = append(rules, deserializeRule(msg))
+	}
+
+	return rules, nil
+}
+
+// setRule sends a policy routing rule set request.
+func setRule(rule *Rule, add bool) error {
+	var msgType, flags int
+
+	s, err := getSocket()
+	if err != nil {
+		return err
+	}
+
+	if add {
+		msgType = unix.RTM_NEWRULE
+		flags = unix.NLM_F_CREATE | unix.NLM_F_EXCL | unix.NLM_F_ACK
+	} else {
+		msgType = unix.RTM_DELRULE
+		flags = unix.NLM_F_ACK
+	}
+
+	req := newRequest(msgType, flags)
+
+	family := rule.Family
+	if family == 0 {
+		family = unix.AF_INET
+	}
+
+	msg := newRuleMsg(family)
+
+	// Tables above 255 only fit in the table attribute.
+	if rule.Table > 0 && rule.Table < 256 {
+		msg.Table = uint8(rule.Table)
+	} else {
+		msg.Table = unix.RT_TABLE_UNSPEC
+	}
+
+	req.addPayload(msg)
+
+	if rule.Table > 0 {
+		req.addPayload(newAttributeUint32(unix.FRA_TABLE, uint32(rule.Table)))
+	}
+
+	if rule.Priority > 0 {
+		req.addPayload(newAttributeUint32(unix.FRA_PRIORITY, uint32(rule.Priority)))
+	}
+
+	if rule.Mark != 0 {
+		req.addPayload(newAttributeUint32(unix.FRA_FWMARK, uint32(rule.Mark)))
+	}
+
+	if rule.Mask != 0 {
+		req.addPayload(newAttributeUint32(unix.FRA_FWMASK, uint32(rule.Mask)))
+	}
+
+	if rule.Src != nil {
+		prefixLength, _ := rule.Src.Mask.Size()
+		msg.Src_len = uint8(prefixLength)
+		req.addPayload(newAttributeIpAddress(unix.FRA_SRC, rule.Src.IP))
+	}
+
+	if rule.Dst != nil {
+		prefixLength, _ := rule.Dst.Mask.Size()
+		msg.Dst_len = uint8(prefixLength)
+		req.addPayload(newAttributeIpAddress(unix.FRA_DST, rule.Dst.IP))
+	}
+
+	if rule.IifName != "" {
+		req.addPayload(newAttributeStringZ(unix.FRA_IIFNAME, rule.IifName))
+	}
+
+	if rule.OifName != "" {
+		req.addPayload(newAttributeStringZ(unix.FRA_OIFNAME, rule.OifName))
+	}
+
+	return s.sendAndWaitForAck(req)
+}
+
+// AddRule adds a policy routing rule.
+func (Netlink) AddRule(rule *Rule) error {
+	return setRule(rule, true)
+}
+
+// DeleteRule deletes a policy routing rule.
+func (Netlink) DeleteRule(rule *Rule) error {
+	return setRule(rule, false)
+}
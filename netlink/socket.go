@@ -179,24 +179,7 @@ func (s *socket) receiveResponse(sent *message) ([]*message, error) {
 			// Log response message.
 			log.Debugf("[netlink] Received %+v\n", msg)
 
-			// Parse body.
-			msg.payload = append(msg.payload, nil)
-
-			// Parse attributes.
-			// Ignore failures as not all messages have attributes.
-			nlAttrs, _ := syscall.ParseNetlinkRouteAttr(&nlMsg)
-
-			// Convert to attribute objects.
-			for _, nlAttr := range nlAttrs {
-				attr := attribute{
-					NlAttr: unix.NlAttr{
-						Len:  nlAttr.Attr.Len,
-						Type: nlAttr.Attr.Type,
-					},
-					value: nlAttr.Value,
-				}
-				msg.payload = append(msg.payload, &attr)
-			}
+			msg.parseAttributes(&nlMsg)
 
 			multi = ((msg.Flags & unix.NLM_F_MULTI) != 0)
 			done = (msg.Type == unix.NLMSG_DONE)
@@ -218,3 +201,67 @@ func (s *socket) receiveResponse(sent *message) ([]*message, error) {
 
 	return messages, nil
 }
+
+// Parses the route attributes of a received netlink message into the message payload.
+func (msg *message) parseAttributes(nlMsg *syscall.NetlinkMessage) {
+	// Parse body.
+	msg.payload = append(msg.payload, nil)
+
+	// Parse attributes.
+	// Ignore failures as not all messages have attributes.
+	var nlAttrs []syscall.NetlinkRouteAttr
+	switch nlMsg.Header.Type {
+	case unix.RTM_NEWRULE, unix.RTM_DELRULE:
+		// Rule messages start with a fib_rule_hdr, which has the same size as rtmsg.
+		nlAttrs = parseRouteAttrs(nlMsg.Data, unix.SizeofRtMsg)
+	case unix.RTM_NEWNEIGH, unix.RTM_DELNEIGH:
+		nlAttrs = parseRouteAttrs(nlMsg.Data, unix.SizeofNdMsg)
+	default:
+		nlAttrs, _ = syscall.ParseNetlinkRouteAttr(nlMsg)
+	}
+
+	// Convert to attribute objects.
+	for _, nlAttr := range nlAttrs {
+		attr := attribute{
+			NlAttr: unix.NlAttr{
+				Len:  nlAttr.Attr.Len,
+				Type: nlAttr.Attr.Type,
+			},
+			value: nlAttr.Value,
+		}
+		msg.payload = append(msg.payload, &attr)
+	}
+}
+
+// Parses the route attributes that follow a family-specific header of the given length.
+// The syscall package only knows the header lengths of link, address and route messages.
+func parseRouteAttrs(b []byte, hdrLen int) []syscall.NetlinkRouteAttr {
+	var attrs []syscall.NetlinkRouteAttr
+
+	if len(b) < hdrLen {
+		return nil
+	}
+
+	b = b[rtaAlignOf(hdrLen):]
+	for len(b) >= unix.SizeofRtAttr {
+		l := int(encoder.Uint16(b[0:2]))
+		if l < unix.SizeofRtAttr || l > len(b) {
+			break
+		}
+
+		attrs = append(attrs, syscall.NetlinkRouteAttr{
+			Attr: syscall.RtAttr{
+				Len:  uint16(l),
+				Type: encoder.Uint16(b[2:4]),
+			},
+			Value: b[unix.SizeofRtAttr:l],
+		})
+
+		if rtaAlignOf(l) >= len(b) {
+			break
+		}
+		b = b[rtaAlignOf(l):]
+	}
+
+	return attrs
+}
@@ -0,0 +1,273 @@
+// Copyright 2024 Microsoft. All rights reserved.
+// MIT License
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/Azure/azure-container-networking/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Size of the channel buffering events for a subscriber.
+	eventChannelSize = 64
+	// Interval at which a blocked receive wakes up to check whether the subscription was cancelled.
+	subscribeReceiveTimeout = 500 * time.Millisecond
+)
+
+// Multicast groups joined by Subscribe.
+var subscribeGroups = []int{
+	unix.RTNLGRP_LINK,
+	unix.RTNLGRP_IPV4_ROUTE,
+	unix.RTNLGRP_IPV6_ROUTE,
+	unix.RTNLGRP_IPV4_IFADDR,
+}
+
+// Subscribe joins the link, route and IPv4 address multicast groups and streams the resulting
+// notifications as events. The returned channel is closed when ctx is cancelled or the socket fails.
+func (Netlink) Subscribe(ctx context.Context) (<-chan Event, error) {
+	s, err := newMulticastSocket(subscribeGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, eventChannelSize)
+
+	go func() {
+		defer close(events)
+		defer s.close()
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+
+			nlMsgs, err := s.receive()
+			if err != nil {
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+					continue
+				}
+
+				log.Printf("[netlink] Subscription receive err=%v\n", err)
+				return
+			}
+
+			for i := range nlMsgs {
+				event, ok := deserializeEvent(&nlMsgs[i])
+				if !ok {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Creates a netlink socket that is a member of the given rtnetlink multicast groups.
+func newMulticastSocket(groups []int) (*socket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		log.Printf("[netlink] Failed to create multicast socket, err=%v\n", err)
+		return nil, err
+	}
+
+	s := &socket{
+		fd: fd,
+	}
+
+	s.sa.Family = unix.AF_NETLINK
+
+	if err = unix.Bind(fd, &s.sa); err != nil {
+		unix.Close(fd)
+		log.Printf("[netlink] Failed to bind multicast socket, err=%v\n", err)
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if err = unix.SetsockoptInt(fd, unix.SOL_NETLINK, unix.NETLINK_ADD_MEMBERSHIP, group); err != nil {
+			unix.Close(fd)
+			log.Printf("[netlink] Failed to join multicast group %d, err=%v\n", group, err)
+			return nil, err
+		}
+	}
+
+	timeout := unix.NsecToTimeval(subscribeReceiveTimeout.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	log.Debugf("[netlink] Multicast socket created for groups %v.\n", groups)
+	return s, nil
+}
+
+// deserializeEvent decodes a multicast notification into an Event.
+// Returns false for message types that are not reported to subscribers.
+func deserializeEvent(nlMsg *syscall.NetlinkMessage) (Event, bool) {
+	msg := &message{
+		NlMsghdr: unix.NlMsghdr{
+			Len:   nlMsg.Header.Len,
+			Type:  nlMsg.Header.Type,
+			Flags: nlMsg.Header.Flags,
+			Seq:   nlMsg.Header.Seq,
+			Pid:   nlMsg.Header.Pid,
+		},
+		data: nlMsg.Data,
+	}
+
+	switch msg.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		if len(msg.data) < unix.SizeofIfInfomsg {
+			return Event{}, false
+		}
+		msg.parseAttributes(nlMsg)
+		event := Event{Type: EventLinkAdd, Link: deserializeLink(msg)}
+		if msg.Type == unix.RTM_DELLINK {
+			event.Type = EventLinkDelete
+		}
+		return event, true
+
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		if len(msg.data) < unix.SizeofRtMsg {
+			return Event{}, false
+		}
+		msg.parseAttributes(nlMsg)
+		route, err := deserializeRoute(msg)
+		if err != nil {
+			return Event{}, false
+		}
+		event := Event{Type: EventRouteAdd, Route: route}
+		if msg.Type == unix.RTM_DELROUTE {
+			event.Type = EventRouteDelete
+		}
+		return event, true
+
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		if len(msg.data) < unix.SizeofIfAddrmsg {
+			return Event{}, false
+		}
+		msg.parseAttributes(nlMsg)
+		event := Event{Type: EventAddrAdd, Addr: deserializeAddr(msg)}
+		if msg.Type == unix.RTM_DELADDR {
+			event.Type = EventAddrDelete
+		}
+		return event, true
+	}
+
+	return Event{}, false
+}
+
+// deserializeLink decodes a link message into a LinkInfo struct.
+func deserializeLink(msg *message) *LinkInfo {
+	ifInfo := (*unix.IfInfomsg)(unsafe.Pointer(&msg.data[0:unix.SizeofIfInfomsg][0]))
+
+	linkInfo := &LinkInfo{
+		Index: int(ifInfo.Index),
+		Flags: linkFlags(ifInfo.Flags),
+	}
+
+	for _, attr := range msg.getAttributes(nil) {
+		switch attr.Type {
+		case unix.IFLA_IFNAME:
+			linkInfo.Name = string(trimNull(attr.value))
+		case unix.IFLA_MTU:
+			linkInfo.MTU = uint(encoder.Uint32(attr.value[0:4]))
+		case unix.IFLA_TXQLEN:
+			linkInfo.TxQLen = uint(encoder.Uint32(attr.value[0:4]))
+		case unix.IFLA_LINK:
+			linkInfo.ParentIndex = int(encoder.Uint32(attr.value[0:4]))
+		case unix.IFLA_ADDRESS:
+			linkInfo.MacAddress = net.HardwareAddr(attr.value)
+		case unix.IFLA_LINKINFO:
+			for _, nested := range parseRouteAttrs(attr.value, 0) {
+				if nested.Attr.Type == IFLA_INFO_KIND {
+					linkInfo.Type = string(trimNull(nested.Value))
+				}
+			}
+		}
+	}
+
+	return linkInfo
+}
+
+// deserializeAddr decodes an address message into an Addr struct.
+func deserializeAddr(msg *message) *Addr {
+	ifAddr := (*unix.IfAddrmsg)(unsafe.Pointer(&msg.data[0:unix.SizeofIfAddrmsg][0]))
+
+	addr := &Addr{
+		LinkIndex: int(ifAddr.Index),
+	}
+
+	// IFA_LOCAL is the interface address, IFA_ADDRESS is the peer address on point-to-point links.
+	var local, address net.IP
+	for _, attr := range msg.getAttributes(nil) {
+		switch attr.Type {
+		case unix.IFA_LOCAL:
+			local = net.IP(attr.value)
+		case unix.IFA_ADDRESS:
+			address = net.IP(attr.value)
+		}
+	}
+
+	ip := local
+	if ip == nil {
+		ip = address
+	}
+
+	if ip != nil {
+		addr.IPNet = &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(int(ifAddr.Prefixlen), 8*len(ip)),
+		}
+	}
+
+	return addr
+}
+
+// linkFlags converts kernel interface flags to net.Flags.
+func linkFlags(rawFlags uint32) net.Flags {
+	var flags net.Flags
+	if rawFlags&unix.IFF_UP != 0 {
+		flags |= net.FlagUp
+	}
+	if rawFlags&unix.IFF_BROADCAST != 0 {
+		flags |= net.FlagBroadcast
+	}
+	if rawFlags&unix.IFF_LOOPBACK != 0 {
+		flags |= net.FlagLoopback
+	}
+	if rawFlags&unix.IFF_POINTOPOINT != 0 {
+		flags |= net.FlagPointToPoint
+	}
+	if rawFlags&unix.IFF_MULTICAST != 0 {
+		flags |= net.FlagMulticast
+	}
+	return flags
+}
+
+// trimNull strips the trailing null terminator from a netlink string attribute.
+func trimNull(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
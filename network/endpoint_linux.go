@@ -17,6 +17,7 @@ import (
 	"github.com/Azure/azure-container-networking/ovsctl"
 	"github.com/Azure/azure-container-networking/platform"
 	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -518,6 +519,93 @@ func (nm *networkManager) updateRoutes(existingEp *EndpointInfo, targetEp *Endpo
 	return nil
 }
 
+// checkEndpointImpl verifies that the host veth still exists and that the container interface in the endpoint's
+// netns still carries the stored addresses and routes.
+func (nm *networkManager) checkEndpointImpl(ep *endpoint, ifName string) error {
+	if ep.HostIfName != "" {
+		if _, err := nm.netio.GetNetworkInterfaceByName(ep.HostIfName); err != nil {
+			return fmt.Errorf("host interface %s not found: %w", ep.HostIfName, errEndpointStateMismatch)
+		}
+	}
+
+	if ep.NetworkNameSpace == "" {
+		return nil
+	}
+
+	ns, err := nm.nsClient.OpenNamespace(ep.NetworkNameSpace)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	if err = ns.Enter(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := ns.Exit(); err != nil {
+			logger.Error("[checkEndpointImpl] Failed to exit netns with", zap.Error(err))
+		}
+	}()
+
+	containerIf, err := nm.netio.GetNetworkInterfaceByName(ifName)
+	if err != nil {
+		return fmt.Errorf("container interface %s not found in netns %s: %w", ifName, ep.NetworkNameSpace, errEndpointStateMismatch)
+	}
+
+	addrs, err := nm.netio.GetNetworkInterfaceAddrs(containerIf)
+	if err != nil {
+		return err
+	}
+
+	liveAddrs := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		liveAddrs[addr.String()] = struct{}{}
+	}
+
+	for _, ipAddr := range ep.IPAddresses {
+		if _, ok := liveAddrs[ipAddr.String()]; !ok {
+			return fmt.Errorf("address %s not found on %s: %w", ipAddr.String(), ifName, errEndpointStateMismatch)
+		}
+	}
+
+	if len(ep.Routes) == 0 {
+		return nil
+	}
+
+	routes, err := nm.netlink.GetIPRoute(&netlink.Route{LinkIndex: containerIf.Index})
+	if err != nil {
+		return err
+	}
+
+	liveRoutes := make(map[string]struct{}, len(routes))
+	for _, route := range routes {
+		liveRoutes[routeDstKey(route)] = struct{}{}
+	}
+
+	for _, route := range ep.Routes {
+		if _, ok := liveRoutes[route.Dst.String()]; !ok {
+			return fmt.Errorf("route to %s not found on %s: %w", route.Dst.String(), ifName, errEndpointStateMismatch)
+		}
+	}
+
+	return nil
+}
+
+// routeDstKey returns the destination prefix of a netlink route in the same form as RouteInfo.Dst.
+// The kernel omits the destination attribute for default routes.
+func routeDstKey(route *netlink.Route) string {
+	if route.Dst != nil {
+		return route.Dst.String()
+	}
+
+	if route.Family == unix.AF_INET6 {
+		return Ipv6DefaultRouteDstPrefix.String()
+	}
+
+	return Ipv4DefaultRouteDstPrefix.String()
+}
+
 func getDefaultGateway(routes []RouteInfo) net.IP {
 	_, defDstIP, _ := net.ParseCIDR("0.0.0.0/0")
 	for _, route := range routes {
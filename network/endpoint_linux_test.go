@@ -134,4 +134,69 @@ var _ = Describe("Test TestEndpointLinux", func() {
 			Expect(err).ToNot(BeNil())
 		})
 	})
+
+	Describe("Test checkEndpointImpl", func() {
+		It("Should succeed when the host and container interfaces exist", func() {
+			nm := &networkManager{
+				netlink:  netlink.NewMockNetlink(false, ""),
+				netio:    netio.NewMockNetIO(false, 0),
+				nsClient: NewMockNamespaceClient(),
+			}
+			ep := &endpoint{
+				Id:               "768e8deb-eth1",
+				HostIfName:       "azv768e8de",
+				NetworkNameSpace: "testns",
+			}
+
+			err := nm.checkEndpointImpl(ep, "eth0")
+			Expect(err).To(BeNil())
+		})
+		It("Should fail when the host veth is missing", func() {
+			nm := &networkManager{
+				netlink:  netlink.NewMockNetlink(false, ""),
+				netio:    netio.NewMockNetIO(true, 1),
+				nsClient: NewMockNamespaceClient(),
+			}
+			ep := &endpoint{
+				Id:               "768e8deb-eth1",
+				HostIfName:       "azv768e8de",
+				NetworkNameSpace: "testns",
+			}
+
+			err := nm.checkEndpointImpl(ep, "eth0")
+			Expect(errors.Is(err, errEndpointStateMismatch)).To(BeTrue())
+		})
+		It("Should fail when a stored address is missing from the container interface", func() {
+			nm := &networkManager{
+				netlink:  netlink.NewMockNetlink(false, ""),
+				netio:    netio.NewMockNetIO(false, 0),
+				nsClient: NewMockNamespaceClient(),
+			}
+			_, ipNet, _ := net.ParseCIDR("10.240.0.4/16")
+			ep := &endpoint{
+				Id:               "768e8deb-eth1",
+				HostIfName:       "azv768e8de",
+				NetworkNameSpace: "testns",
+				IPAddresses:      []net.IPNet{*ipNet},
+			}
+
+			err := nm.checkEndpointImpl(ep, "eth0")
+			Expect(errors.Is(err, errEndpointStateMismatch)).To(BeTrue())
+		})
+		It("Should fail when entering the netns fails", func() {
+			nm := &networkManager{
+				netlink:  netlink.NewMockNetlink(false, ""),
+				netio:    netio.NewMockNetIO(false, 0),
+				nsClient: NewMockNamespaceClient(),
+			}
+			ep := &endpoint{
+				Id:               "768e8deb-eth1",
+				HostIfName:       "azv768e8de",
+				NetworkNameSpace: failToEnterNamespaceName,
+			}
+
+			err := nm.checkEndpointImpl(ep, "eth0")
+			Expect(err).ToNot(BeNil())
+		})
+	})
 })
@@ -25,31 +25,45 @@ func AddSnatEndpoint(snatClient *snat.Client) error {
 	return nil
 }
 
+// AddSnatEndpointRules programs the iptables rules of the snat bridge in a single transaction, so that a
+// failure does not leave the node with only part of them.
 func AddSnatEndpointRules(snatClient *snat.Client, hostToNC, ncToHost bool, nl netlink.NetlinkInterface, plc platform.ExecClient) error {
+	tx := snatClient.NewTransaction()
+
 	// Allow specific Private IPs via Snat Bridge
-	if err := snatClient.AllowIPAddressesOnSnatBridge(); err != nil {
+	if err := snatClient.AllowIPAddressesOnSnatBridge(tx); err != nil {
 		return errors.Wrap(err, "failed to allow ip addresses on snat bridge")
 	}
 
 	// Block Private IPs via Snat Bridge
-	if err := snatClient.BlockIPAddressesOnSnatBridge(); err != nil {
+	if err := snatClient.BlockIPAddressesOnSnatBridge(tx); err != nil {
 		return errors.Wrap(err, "failed to block ip addresses on snat bridge")
 	}
-	if err := snatClient.EnableIPForwarding(); err != nil {
+	if err := snatClient.EnableIPForwarding(tx); err != nil {
 		return errors.Wrap(err, "failed to enable ip forwarding")
 	}
 
 	if hostToNC {
-		if err := snatClient.AllowInboundFromHostToNC(); err != nil {
+		if err := snatClient.AllowInboundFromHostToNC(tx); err != nil {
 			return errors.Wrap(err, "failed to allow inbound from host to nc")
 		}
 	}
 
 	if ncToHost {
-		if err := snatClient.AllowInboundFromNCToHost(); err != nil {
+		if err := snatClient.AllowInboundFromNCToHost(tx); err != nil {
 			return errors.Wrap(err, "failed to allow inbound from nc to host")
 		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit snat endpoint iptables rules")
+	}
+
+	if hostToNC || ncToHost {
+		if err := snatClient.AddStaticArpEntryForNC(); err != nil {
+			return errors.Wrap(err, "failed to add static arp entry for nc")
+		}
+	}
 	return nil
 }
 
@@ -85,17 +99,29 @@ func DeleteSnatEndpoint(snatClient *snat.Client) error {
 }
 
 func DeleteSnatEndpointRules(snatClient *snat.Client, hostToNC, ncToHost bool) {
+	tx := snatClient.NewTransaction()
+
 	if hostToNC {
-		err := snatClient.DeleteInboundFromHostToNC()
+		err := snatClient.DeleteInboundFromHostToNC(tx)
 		if err != nil {
 			logger.Error("failed to delete inbound from host to nc rules", zap.Error(err))
 		}
 	}
 
 	if ncToHost {
-		err := snatClient.DeleteInboundFromNCToHost()
+		err := snatClient.DeleteInboundFromNCToHost(tx)
 		if err != nil {
 			logger.Error("failed to delete inbound from nc to host rules", zap.Error(err))
 		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit snat endpoint iptables rule deletion", zap.Error(err))
+	}
+
+	if hostToNC || ncToHost {
+		if err := snatClient.DeleteStaticArpEntryForNC(); err != nil {
+			logger.Error("failed to delete static arp entry for nc", zap.Error(err))
+		}
+	}
 }
@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/network/snat"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var errMockCommit = errors.New("mock commit failure")
+
+func TestAddSnatEndpointRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		commitErr error
+	}{
+		{
+			name: "rules committed",
+		},
+		{
+			name:      "commit failure",
+			commitErr: errMockCommit,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			nl := netlink.NewMockNetlink(false, "")
+			plc := platform.NewMockExecClient(false)
+			tx := &fakeTransaction{commitErr: tt.commitErr}
+			snatClient := snat.NewSnatClient("azSnatveth0", "azSnatveth1", "169.254.0.4/16", "169.254.0.1/16", "",
+				[]string{"10.0.0.4"}, false, nl, plc, fakeIPTablesClient{tx: tx})
+
+			err := AddSnatEndpointRules(&snatClient, false, false, nl, plc)
+			require.True(t, tx.committed)
+			require.NotEmpty(t, tx.changes)
+			if tt.commitErr != nil {
+				require.ErrorIs(t, err, tt.commitErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDeleteSnatEndpointRules(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	plc := platform.NewMockExecClient(false)
+	tx := &fakeTransaction{}
+	snatClient := snat.NewSnatClient("azSnatveth0", "azSnatveth1", "169.254.0.4/16", "169.254.0.1/16", "",
+		nil, false, nl, plc, fakeIPTablesClient{tx: tx})
+
+	DeleteSnatEndpointRules(&snatClient, true, true)
+	require.True(t, tx.committed)
+	require.Equal(t, []string{
+		"-t filter -D AZURECNIOUTPUT -s 169.254.0.1 -d 169.254.0.4 -j ACCEPT",
+		"-t filter -D AZURECNIINPUT -s 169.254.0.4 -d 169.254.0.1 -j ACCEPT",
+	}, tx.changes)
+}
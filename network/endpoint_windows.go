@@ -492,6 +492,27 @@ func (ep *endpoint) getInfoImpl(epInfo *EndpointInfo) {
 	epInfo.Data["hnsid"] = ep.HnsId
 }
 
+// checkEndpointImpl verifies that the HNS endpoint backing the endpoint still exists.
+func (nm *networkManager) checkEndpointImpl(ep *endpoint, _ string) error {
+	if useHnsV2, err := UseHnsV2(ep.NetNs); useHnsV2 {
+		if err != nil {
+			return err
+		}
+
+		if _, err = Hnsv2.GetEndpointByID(ep.HnsId); err != nil {
+			return fmt.Errorf("hcn endpoint %s not found: %w", ep.HnsId, errEndpointStateMismatch)
+		}
+
+		return nil
+	}
+
+	if _, err := Hnsv1.GetHNSEndpointByID(ep.HnsId); err != nil {
+		return fmt.Errorf("hns endpoint %s not found: %w", ep.HnsId, errEndpointStateMismatch)
+	}
+
+	return nil
+}
+
 // updateEndpointImpl in windows does nothing for now
 func (nm *networkManager) updateEndpointImpl(nw *network, existingEpInfo *EndpointInfo, targetEpInfo *EndpointInfo) (*endpoint, error) {
 	return nil, nil
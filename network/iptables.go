@@ -1,9 +1,12 @@
 package network
 
+import "github.com/Azure/azure-container-networking/iptables"
+
 type ipTablesClient interface {
 	InsertIptableRule(version, tableName, chainName, match, target string) error
 	AppendIptableRule(version, tableName, chainName, match, target string) error
 	DeleteIptableRule(version, tableName, chainName, match, target string) error
 	CreateChain(version, tableName, chainName string) error
 	RunCmd(version, params string) error
+	NewTransaction() iptables.TransactionInterface
 }
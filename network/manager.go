@@ -38,6 +38,10 @@ const (
 	EndpointIfIndex      = 0 // Azure CNI supports only one interface
 )
 
+func init() {
+	store.RegisterSchema(storeKey, store.EnvelopeMigration)
+}
+
 var Ipv4DefaultRouteDstPrefix = net.IPNet{
 	IP:   net.IPv4zero,
 	Mask: net.IPv4Mask(0, 0, 0, 0),
@@ -101,6 +105,8 @@ type NetworkManager interface {
 	CreateEndpoint(client apipaClient, networkID string, epInfo []*EndpointInfo) error
 	DeleteEndpoint(networkID string, endpointID string, epInfo *EndpointInfo) error
 	GetEndpointInfo(networkID string, endpointID string) (*EndpointInfo, error)
+	// CheckEndpoint returns an error if the live state of the endpoint no longer matches the stored endpoint state
+	CheckEndpoint(networkID string, endpointID string, ifName string) error
 	GetAllEndpoints(networkID string) (map[string]*EndpointInfo, error)
 	GetEndpointInfoBasedOnPODDetails(networkID string, podName string, podNameSpace string, doExactMatchForPodName bool) (*EndpointInfo, error)
 	AttachEndpoint(networkID string, endpointID string, sandboxKey string) (*endpoint, error)
@@ -177,7 +183,7 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 	// Ignore the persisted state if it is older than the last reboot time.
 
 	// Read any persisted state.
-	err := nm.store.Read(storeKey, nm)
+	err := store.ReadVersioned(nm.store, storeKey, nm)
 	if err != nil {
 		if err == store.ErrKeyNotFound {
 			logger.Info("network store key not found")
@@ -274,7 +280,7 @@ func (nm *networkManager) save() error {
 	// Update time stamp.
 	nm.TimeStamp = time.Now()
 
-	err := nm.store.Write(storeKey, nm)
+	err := store.WriteVersioned(nm.store, storeKey, nm)
 	if err == nil {
 		logger.Info("Save succeeded")
 	} else {
@@ -542,6 +548,29 @@ func (nm *networkManager) GetEndpointInfo(networkID, endpointID string) (*Endpoi
 	return ep.getInfo(), nil
 }
 
+// CheckEndpoint verifies that the interfaces, addresses and routes of the given endpoint are still programmed.
+func (nm *networkManager) CheckEndpoint(networkID, endpointID, ifName string) error {
+	nm.Lock()
+	defer nm.Unlock()
+
+	if nm.IsStatelessCNIMode() {
+		_, err := nm.GetEndpointState(networkID, endpointID)
+		return err
+	}
+
+	nw, err := nm.getNetwork(networkID)
+	if err != nil {
+		return err
+	}
+
+	ep, err := nw.getEndpoint(endpointID)
+	if err != nil {
+		return err
+	}
+
+	return nm.checkEndpointImpl(ep, ifName)
+}
+
 func (nm *networkManager) GetAllEndpoints(networkId string) (map[string]*EndpointInfo, error) {
 	nm.Lock()
 	defer nm.Unlock()
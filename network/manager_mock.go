@@ -105,6 +105,14 @@ func (nm *MockNetworkManager) GetEndpointInfo(_, endpointID string) (*EndpointIn
 	return nil, errEndpointNotFound
 }
 
+// CheckEndpoint mock
+func (nm *MockNetworkManager) CheckEndpoint(_, endpointID, _ string) error {
+	if _, exists := nm.TestEndpointInfoMap[endpointID]; !exists {
+		return errEndpointNotFound
+	}
+	return nil
+}
+
 // GetEndpointInfoBasedOnPODDetails mock
 func (nm *MockNetworkManager) GetEndpointInfoBasedOnPODDetails(networkID string, podName string, podNameSpace string, doExactMatchForPodName bool) (*EndpointInfo, error) {
 	return &EndpointInfo{}, nil
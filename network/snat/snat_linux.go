@@ -36,6 +36,7 @@ type ipTablesClient interface {
 	AppendIptableRule(version, tableName, chainName, match, target string) error
 	DeleteIptableRule(version, tableName, chainName, match, target string) error
 	CreateChain(version, tableName, chainName string) error
+	NewTransaction() iptables.TransactionInterface
 }
 
 var errorSnatClient = errors.New("SnatClient Error")
@@ -128,10 +129,15 @@ func (client *Client) CreateSnatEndpoint() error {
 	return nil
 }
 
+// NewTransaction starts an iptables transaction that the snat rules can be added to and committed at once
+func (client *Client) NewTransaction() iptables.TransactionInterface {
+	return client.ipTablesClient.NewTransaction()
+}
+
 // AllowIPAddressesOnSnatBridge adds iptables rules  that allows only specific Private IPs via linux bridge
-func (client *Client) AllowIPAddressesOnSnatBridge() error {
+func (client *Client) AllowIPAddressesOnSnatBridge(tx iptables.TransactionInterface) error {
 	nu := networkutils.NewNetworkUtils(client.netlink, client.plClient)
-	if err := nu.AllowIPAddresses(client.ipTablesClient, SnatBridgeName, client.SkipAddressesFromBlock, iptables.Insert); err != nil {
+	if err := nu.AllowIPAddresses(tx, SnatBridgeName, client.SkipAddressesFromBlock, iptables.Insert); err != nil {
 		logger.Error("AllowIPAddresses failed with", zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
@@ -140,9 +146,9 @@ func (client *Client) AllowIPAddressesOnSnatBridge() error {
 }
 
 // BlockIPAddressesOnSnatBridge adds iptables rules  that blocks all private IPs flowing via linux bridge
-func (client *Client) BlockIPAddressesOnSnatBridge() error {
+func (client *Client) BlockIPAddressesOnSnatBridge(tx iptables.TransactionInterface) error {
 	nu := networkutils.NewNetworkUtils(client.netlink, client.plClient)
-	if err := nu.BlockIPAddresses(client.ipTablesClient, SnatBridgeName, iptables.Append); err != nil {
+	if err := nu.BlockIPAddresses(tx, SnatBridgeName, iptables.Append); err != nil {
 		logger.Error("AllowIPAddresses failed with", zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
@@ -180,110 +186,78 @@ func getNCLocalAndGatewayIP(client *Client) (brIP, contIP net.IP) {
 }
 
 // This function adds iptables rules that allows only host to NC communication and not the other way
-func (client *Client) AllowInboundFromHostToNC() error {
+func (client *Client) AllowInboundFromHostToNC(tx iptables.TransactionInterface) error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
 
 	// Create CNI Output chain
-	if err := client.ipTablesClient.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
+	if err := tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Creating failed with", zap.Any("CNIOutputChain", iptables.CNIOutputChain), zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
 	// Forward traffic from Ouptut chain to CNI Output chain
-	if err := client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
+	if err := tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Creating failed with", zap.Any("CNIOutputChain", iptables.CNIOutputChain), zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
 	// Allow connection from Host to NC
 	matchCondition := fmt.Sprintf("-s %s -d %s", bridgeIP.String(), containerIP.String())
-	err := client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
+	err := tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
 	if err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting output rule failed with ", zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
 	// Create cniinput chain
-	if err = client.ipTablesClient.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
+	if err = tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Creating failed with", zap.Any("CNIOutputChain", iptables.CNIOutputChain), zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
 	// Forward from Input to cniinput chain
-	if err = client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
+	if err = tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting forward rule to failed with", zap.Any("CNIOutputChain", iptables.CNIOutputChain), zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
 	// Accept packets from NC only if established connection
 	matchCondition = fmt.Sprintf(" -i %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
-	err = client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
+	err = tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
 	if err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting input rule failed with", zap.Error(err))
 		return newErrorSnatClient(err.Error())
 	}
 
-	snatContainerVeth, _ := net.InterfaceByName(client.containerSnatVethName)
-
-	// Add static arp entry for localIP to prevent arp going out of VM
-	logger.Info("Adding static arp entry for ip", zap.Any("containerIP", containerIP),
-		zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()))
-	linkInfo := netlink.LinkInfo{
-		Name:       SnatBridgeName,
-		IPAddr:     containerIP,
-		MacAddress: snatContainerVeth.HardwareAddr,
-	}
-
-	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
-	if err != nil {
-		logger.Error("AllowInboundFromHostToNC: Error adding static arp entry for ip", zap.Any("containerIP", containerIP),
-			zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()), zap.Error(err))
-		return newErrorSnatClient(err.Error())
-	}
-
 	return nil
 }
 
-func (client *Client) DeleteInboundFromHostToNC() error {
+func (client *Client) DeleteInboundFromHostToNC(tx iptables.TransactionInterface) error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
 
 	// Delete allow connection from Host to NC
 	matchCondition := fmt.Sprintf("-s %s -d %s", bridgeIP.String(), containerIP.String())
-	err := client.ipTablesClient.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
+	err := tx.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
 	if err != nil {
 		logger.Error("DeleteInboundFromHostToNC: Error removing output rule", zap.Error(err))
 	}
 
-	// Remove static arp entry added for container local IP
-	logger.Info("Removing static arp entry for ip", zap.Any("containerIP", containerIP))
-	linkInfo := netlink.LinkInfo{
-		Name:       SnatBridgeName,
-		IPAddr:     containerIP,
-		MacAddress: nil,
-	}
-
-	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.REMOVE, netlink.NUD_INCOMPLETE)
-	if err != nil {
-		logger.Error("AllowInboundFromHostToNC: Error removing static arp entry for ip", zap.Any("containerIP", containerIP),
-			zap.Error(err))
-	}
-
 	return err
 }
 
 // This function adds iptables rules that allows only NC to Host communication and not the other way
-func (client *Client) AllowInboundFromNCToHost() error {
+func (client *Client) AllowInboundFromNCToHost(tx iptables.TransactionInterface) error {
 	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
 
 	// Create CNI Input chain
-	if err := client.ipTablesClient.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
+	if err := tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIInputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Creating failed with", zap.String("CNIInputChain", iptables.CNIInputChain),
 			zap.Error(err))
 		return err
 	}
 
 	// Forward traffic from Input to cniinput chain
-	if err := client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
+	if err := tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Input, "", iptables.CNIInputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting forward rule to failed with", zap.String("CNIInputChain", iptables.CNIInputChain),
 			zap.Error(err))
 		return err
@@ -291,21 +265,21 @@ func (client *Client) AllowInboundFromNCToHost() error {
 
 	// Allow NC to Host connection
 	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
-	err := client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
+	err := tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
 	if err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting output rule failed with", zap.Error(err))
 		return err
 	}
 
 	// Create CNI output chain
-	if err = client.ipTablesClient.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
+	if err = tx.CreateChain(iptables.V4, iptables.Filter, iptables.CNIOutputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Creating failed with", zap.String("CNIInputChain", iptables.CNIInputChain),
 			zap.Error(err))
 		return err
 	}
 
 	// Forward traffic from Output to CNI Output chain
-	if err = client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
+	if err = tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.Output, "", iptables.CNIOutputChain); err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting forward rule to failed with", zap.String("CNIInputChain", iptables.CNIInputChain),
 			zap.Error(err))
 		return err
@@ -313,16 +287,38 @@ func (client *Client) AllowInboundFromNCToHost() error {
 
 	// Accept packets from Host only if established connection
 	matchCondition = fmt.Sprintf(" -o %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
-	err = client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
+	err = tx.InsertIptableRule(iptables.V4, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept)
 	if err != nil {
 		logger.Error("AllowInboundFromHostToNC: Inserting input rule failed with", zap.Error(err))
 		return err
 	}
 
-	snatContainerVeth, _ := net.InterfaceByName(client.containerSnatVethName)
+	return nil
+}
+
+func (client *Client) DeleteInboundFromNCToHost(tx iptables.TransactionInterface) error {
+	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
+
+	// Delete allow NC to Host connection
+	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
+	err := tx.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
+	if err != nil {
+		logger.Error("DeleteInboundFromNCToHost: Error removing output rule", zap.Error(err))
+	}
+
+	return err
+}
+
+// AddStaticArpEntryForNC adds a static arp entry for the NC local IP to prevent arp going out of VM
+func (client *Client) AddStaticArpEntryForNC() error {
+	_, containerIP := getNCLocalAndGatewayIP(client)
+	snatContainerVeth, err := net.InterfaceByName(client.containerSnatVethName)
+	if err != nil {
+		return newErrorSnatClient(err.Error())
+	}
 
-	// Add static arp entry for localIP to prevent arp going out of VM
-	logger.Info("Adding static arp entry for ip", zap.Any("containerIP", containerIP), zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()))
+	logger.Info("Adding static arp entry for ip", zap.Any("containerIP", containerIP),
+		zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()))
 	linkInfo := netlink.LinkInfo{
 		Name:       SnatBridgeName,
 		IPAddr:     containerIP,
@@ -331,24 +327,18 @@ func (client *Client) AllowInboundFromNCToHost() error {
 
 	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT)
 	if err != nil {
-		logger.Error("AllowInboundFromNCToHost: Error adding static arp entry for ip", zap.Any("containerIP", containerIP),
+		logger.Error("AddStaticArpEntryForNC: Error adding static arp entry for ip", zap.Any("containerIP", containerIP),
 			zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()), zap.Error(err))
+		return newErrorSnatClient(err.Error())
 	}
 
-	return err
+	return nil
 }
 
-func (client *Client) DeleteInboundFromNCToHost() error {
-	bridgeIP, containerIP := getNCLocalAndGatewayIP(client)
-
-	// Delete allow NC to Host connection
-	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
-	err := client.ipTablesClient.DeleteIptableRule(iptables.V4, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept)
-	if err != nil {
-		logger.Error("DeleteInboundFromNCToHost: Error removing output rule", zap.Error(err))
-	}
+// DeleteStaticArpEntryForNC removes the static arp entry added for the NC local IP
+func (client *Client) DeleteStaticArpEntryForNC() error {
+	_, containerIP := getNCLocalAndGatewayIP(client)
 
-	// Remove static arp entry added for container local IP
 	logger.Info("Removing static arp entry for ip", zap.Any("containerIP", containerIP))
 	linkInfo := netlink.LinkInfo{
 		Name:       SnatBridgeName,
@@ -356,9 +346,9 @@ func (client *Client) DeleteInboundFromNCToHost() error {
 		MacAddress: nil,
 	}
 
-	err = client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.REMOVE, netlink.NUD_INCOMPLETE)
+	err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.REMOVE, netlink.NUD_INCOMPLETE)
 	if err != nil {
-		logger.Error("DeleteInboundFromNCToHost: Error removing static arp entry for ip",
+		logger.Error("DeleteStaticArpEntryForNC: Error removing static arp entry for ip",
 			zap.Any("containerIP", containerIP), zap.Error(err))
 	}
 
@@ -487,7 +477,7 @@ func (client *Client) addVlanDropRule() error {
 }
 
 // This function enables ip forwarding in VM and allow forwarding packets from the interface
-func (client *Client) EnableIPForwarding() error {
+func (client *Client) EnableIPForwarding(tx iptables.TransactionInterface) error {
 	// Enable ip forwading on linux vm.
 	// sysctl -w net.ipv4.ip_forward=1
 	_, err := client.plClient.ExecuteCommand(enableIPForwardCmd)
@@ -496,7 +486,7 @@ func (client *Client) EnableIPForwarding() error {
 	}
 
 	// Append a rule in forward chain to allow forwarding from bridge
-	if err := client.ipTablesClient.AppendIptableRule(iptables.V4, iptables.Filter, iptables.Forward, "", iptables.Accept); err != nil {
+	if err := tx.AppendIptableRule(iptables.V4, iptables.Filter, iptables.Forward, "", iptables.Accept); err != nil {
 		return errors.Wrap(err, "appending forward chain rule to allow traffic from snat bridge failed")
 	}
 
@@ -47,17 +47,23 @@ func TestAllowInboundFromHostToNC(t *testing.T) {
 		t.Errorf("Error adding dummy interface %v", err)
 	}
 
-	if err := client.AllowInboundFromHostToNC(); err != nil {
-		t.Errorf("Error adding inbound rule: %v", err)
-	}
-
-	if err := client.AllowInboundFromHostToNC(); err != nil {
-		t.Errorf("Error adding existing inbound rule: %v", err)
-	}
-
-	if err := client.DeleteInboundFromHostToNC(); err != nil {
+	for i := 0; i < 2; i++ {
+		tx := iptc.NewTransaction()
+		if err := client.AllowInboundFromHostToNC(tx); err != nil {
+			t.Errorf("Error adding inbound rule: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Errorf("Error committing inbound rule: %v", err)
+		}
+	}
+
+	tx := iptc.NewTransaction()
+	if err := client.DeleteInboundFromHostToNC(tx); err != nil {
 		t.Errorf("Error removing inbound rule: %v", err)
 	}
+	if err := tx.Commit(); err != nil {
+		t.Errorf("Error committing inbound rule removal: %v", err)
+	}
 
 	if err := nl.DeleteLink(anyInterface); err != nil {
 		t.Errorf("Error removing any interface link: %v", err)
@@ -96,17 +102,23 @@ func TestAllowInboundFromNCToHost(t *testing.T) {
 		t.Errorf("Error adding dummy interface %v", err)
 	}
 
-	if err := client.AllowInboundFromNCToHost(); err != nil {
-		t.Errorf("Error adding inbound rule: %v", err)
-	}
-
-	if err := client.AllowInboundFromNCToHost(); err != nil {
-		t.Errorf("Error adding existing inbound rule: %v", err)
+	for i := 0; i < 2; i++ {
+		tx := iptc.NewTransaction()
+		if err := client.AllowInboundFromNCToHost(tx); err != nil {
+			t.Errorf("Error adding inbound rule: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Errorf("Error committing inbound rule: %v", err)
+		}
 	}
 
-	if err := client.DeleteInboundFromNCToHost(); err != nil {
+	tx := iptc.NewTransaction()
+	if err := client.DeleteInboundFromNCToHost(tx); err != nil {
 		t.Errorf("Error removing inbound rule: %v", err)
 	}
+	if err := tx.Commit(); err != nil {
+		t.Errorf("Error committing inbound rule removal: %v", err)
+	}
 
 	if err := nl.DeleteLink(anyInterface); err != nil {
 		t.Errorf("Error removing any interface link: %v", err)
@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	vishnetlink "github.com/vishvananda/netlink"
 	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -412,10 +413,12 @@ func (client *TransparentVlanEndpointClient) AddVnetRules(epInfo *EndpointInfo)
 	}
 
 	// Packets that are marked should go to the tunneling table
-	newRule := vishnetlink.NewRule()
-	newRule.Mark = tunnelingMark
-	newRule.Table = tunnelingTable
-	rules, err := vishnetlink.RuleList(vishnetlink.FAMILY_V4)
+	newRule := &netlink.Rule{
+		Family: unix.AF_INET,
+		Mark:   tunnelingMark,
+		Table:  tunnelingTable,
+	}
+	rules, err := client.netlink.ListRules(unix.AF_INET)
 	if err != nil {
 		return errors.Wrap(err, "unable to get existing ip rule list")
 	}
@@ -427,7 +430,7 @@ func (client *TransparentVlanEndpointClient) AddVnetRules(epInfo *EndpointInfo)
 		}
 	}
 	if !ruleExists {
-		if err := vishnetlink.RuleAdd(newRule); err != nil {
+		if err := client.netlink.AddRule(newRule); err != nil {
 			return errors.Wrap(err, "failed to add rule that forwards packet with mark to tunneling routing table")
 		}
 	}
@@ -608,13 +611,18 @@ func (client *TransparentVlanEndpointClient) AddDefaultArp(interfaceName, destMa
 	if err != nil {
 		return errors.Wrap(err, "unable to parse mac")
 	}
-	linkInfo := netlink.LinkInfo{
-		Name:       interfaceName,
-		IPAddr:     virtualGwNet.IP,
-		MacAddress: hardwareAddr,
+	link, err := client.netlink.GetLinkByName(interfaceName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get link %s", interfaceName)
+	}
+	neigh := &netlink.Neigh{
+		LinkIndex:    link.Index,
+		State:        netlink.NUD_PERMANENT,
+		IP:           virtualGwNet.IP,
+		HardwareAddr: hardwareAddr,
 	}
 
-	if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT); err != nil {
+	if err := client.netlink.SetNeigh(neigh); err != nil {
 		return fmt.Errorf("adding arp entry failed: %w", err)
 	}
 	return nil
@@ -625,7 +633,7 @@ func (client *TransparentVlanEndpointClient) DeleteEndpoints(ep *endpoint) error
 	err := ExecuteInNS(client.nsClient, client.vnetNSName, func() error {
 		// Passing in functionality to get number of routes after deletion
 		getNumRoutesLeft := func() (int, error) {
-			routes, err := vishnetlink.RouteList(nil, vishnetlink.FAMILY_V4)
+			routes, err := client.netlink.GetIPRoute(&netlink.Route{Family: unix.AF_INET})
 			if err != nil {
 				return 0, errors.Wrap(err, "failed to get num routes left")
 			}
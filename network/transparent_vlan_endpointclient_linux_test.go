@@ -4,9 +4,11 @@
 package network
 
 import (
+	"fmt"
 	"net"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
 	"github.com/Azure/azure-container-networking/network/networkutils"
@@ -963,3 +965,94 @@ func TestRunWithRetries(t *testing.T) {
 		})
 	}
 }
+
+// fakeTransaction records the queued iptables changes instead of programming them.
+type fakeTransaction struct {
+	changes   []string
+	committed bool
+	commitErr error
+}
+
+func (tx *fakeTransaction) CreateChain(_, table, chain string) error {
+	tx.changes = append(tx.changes, fmt.Sprintf("-t %s -N %s", table, chain))
+	return nil
+}
+
+func (tx *fakeTransaction) InsertIptableRule(_, table, chain, match, target string) error {
+	tx.changes = append(tx.changes, fmt.Sprintf("-t %s -I %s %s -j %s", table, chain, match, target))
+	return nil
+}
+
+func (tx *fakeTransaction) AppendIptableRule(_, table, chain, match, target string) error {
+	tx.changes = append(tx.changes, fmt.Sprintf("-t %s -A %s %s -j %s", table, chain, match, target))
+	return nil
+}
+
+func (tx *fakeTransaction) DeleteIptableRule(_, table, chain, match, target string) error {
+	tx.changes = append(tx.changes, fmt.Sprintf("-t %s -D %s %s -j %s", table, chain, match, target))
+	return nil
+}
+
+func (tx *fakeTransaction) Commit() error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+type fakeIPTablesClient struct {
+	tx *fakeTransaction
+}
+
+func (fakeIPTablesClient) InsertIptableRule(_, _, _, _, _ string) error { return nil }
+func (fakeIPTablesClient) AppendIptableRule(_, _, _, _, _ string) error { return nil }
+func (fakeIPTablesClient) DeleteIptableRule(_, _, _, _, _ string) error { return nil }
+func (fakeIPTablesClient) CreateChain(_, _, _ string) error             { return nil }
+func (fakeIPTablesClient) RunCmd(_, _ string) error                     { return nil }
+func (c fakeIPTablesClient) NewTransaction() iptables.TransactionInterface {
+	if c.tx != nil {
+		return c.tx
+	}
+	return &fakeTransaction{}
+}
+
+func TestTransparentVlanAddVnetRules(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	plc := platform.NewMockExecClient(false)
+	client := &TransparentVlanEndpointClient{
+		vlanIfName:     "eth0.1",
+		netlink:        nl,
+		iptablesClient: fakeIPTablesClient{},
+		netUtilsClient: networkutils.NewNetworkUtils(nl, plc),
+	}
+
+	// Adding the rules twice must leave a single tunneling rule behind.
+	require.NoError(t, client.AddVnetRules(&EndpointInfo{}))
+	require.NoError(t, client.AddVnetRules(&EndpointInfo{}))
+
+	rules, err := nl.ListRules(0)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, tunnelingMark, rules[0].Mark)
+	require.Equal(t, tunnelingTable, rules[0].Table)
+
+	client.netlink = netlink.NewMockNetlink(true, "")
+	require.Error(t, client.AddVnetRules(&EndpointInfo{}))
+}
+
+func TestTransparentVlanAddDefaultArp(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	client := &TransparentVlanEndpointClient{
+		netlink: nl,
+	}
+
+	require.NoError(t, client.AddDefaultArp("eth0.1", azureMac))
+
+	neighs, err := nl.ListNeigh(0, 0)
+	require.NoError(t, err)
+	require.Len(t, neighs, 1)
+	require.Equal(t, netlink.NUD_PERMANENT, neighs[0].State)
+	require.Equal(t, azureMac, neighs[0].HardwareAddr.String())
+	require.True(t, neighs[0].IP.Equal(net.ParseIP("169.254.2.1")))
+
+	require.Error(t, client.AddDefaultArp(netlink.BadEth, azureMac))
+	require.Error(t, client.AddDefaultArp("eth0.1", "not-a-mac"))
+}
@@ -31,6 +31,7 @@ func newDebugCmd() *cobra.Command {
 	debugCmd.AddCommand(newParseIPTableCmd())
 	debugCmd.AddCommand(newConvertIPTableCmd())
 	debugCmd.AddCommand(newGetTuples())
+	debugCmd.AddCommand(newPlanCmd())
 
 	return debugCmd
 }
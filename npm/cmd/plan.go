@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+
+	npmconfig "github.com/Azure/azure-container-networking/npm/config"
+	"github.com/Azure/azure-container-networking/npm/http/api"
+	npmcommon "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/debug"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/pkg/models"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	errNoManifests       = fmt.Errorf("must specify manifests when planning only for manifests")
+	errUnexpectedCache   = fmt.Errorf("expected an NPM v2 cache")
+	errNoIp6tablesFile   = fmt.Errorf("must specify an ip6tables save file with the cache file when IPv6 is enabled")
+	errDiffFileWithCache = fmt.Errorf("ip6tables and ipset save files can only be used with a cache file")
+)
+
+func newPlanCmd() *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Print the ipsets and iptables rules (or HNS ACLs) which NPM would program for NetworkPolicy, Pod and Namespace manifests and the NPM cache, and diff them with the node",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifests, _ := cmd.Flags().GetStringSlice("manifests")
+			manifestsOnly, _ := cmd.Flags().GetBool("manifests-only")
+			nodeName, _ := cmd.Flags().GetString("node-name")
+			npmCacheF, _ := cmd.Flags().GetString("cache-file")
+			iptableSaveF, _ := cmd.Flags().GetString("iptables-file")
+			ip6tableSaveF, _ := cmd.Flags().GetString("ip6tables-file")
+			ipsetSaveF, _ := cmd.Flags().GetString("ipset-file")
+
+			config := &npmconfig.Config{}
+			err := viper.Unmarshal(config)
+			if err != nil {
+				return fmt.Errorf("failed to load config with err %w", err)
+			}
+
+			// the live filter tables must be read before planning, since booting up the dataplane detects the iptables version
+			var objects *debug.PlanObjects
+			var liveTable, liveIPv6Table *NPMIPtable.Table
+			var liveIPSets map[string]*parse.IPSet
+			enableIPv6 := config.Toggles.EnableIPv6 && !util.IsWindowsDP()
+			c := &debug.Converter{EnableV2NPM: true}
+			switch {
+			case manifestsOnly:
+				if len(manifests) == 0 {
+					return errNoManifests
+				}
+				objects = &debug.PlanObjects{}
+			case npmCacheF == "" && iptableSaveF == "":
+				if ip6tableSaveF != "" || ipsetSaveF != "" {
+					return errDiffFileWithCache
+				}
+				c.NPMDebugEndpointHost = "http://localhost"
+				c.NPMDebugEndpointPort = api.DefaultHttpPort
+				if err := c.NpmCache(); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				if !util.IsWindowsDP() {
+					liveTable, err = parse.Iptables(util.IptablesFilterTable)
+					if err != nil {
+						return fmt.Errorf("%w", err)
+					}
+					if enableIPv6 {
+						liveIPv6Table, err = parse.Ip6tables(util.IptablesFilterTable)
+						if err != nil {
+							return fmt.Errorf("%w", err)
+						}
+					}
+					liveIPSets, err = parse.IPSetSave()
+					if err != nil {
+						return fmt.Errorf("%w", err)
+					}
+				}
+			case npmCacheF != "" && iptableSaveF != "":
+				if enableIPv6 && ip6tableSaveF == "" {
+					return errNoIp6tablesFile
+				}
+				if err := c.NpmCacheFromFile(npmCacheF); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				liveTable, err = parse.IptablesFile(util.IptablesFilterTable, iptableSaveF)
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				if ip6tableSaveF != "" {
+					liveIPv6Table, err = parse.IptablesFile(util.IptablesFilterTable, ip6tableSaveF)
+					if err != nil {
+						return fmt.Errorf("%w", err)
+					}
+				}
+				if ipsetSaveF != "" {
+					liveIPSets, err = parse.IPSetSaveFile(ipsetSaveF)
+					if err != nil {
+						return fmt.Errorf("%w", err)
+					}
+				}
+			default:
+				return errSpecifyBothFiles
+			}
+
+			if objects == nil {
+				cache, ok := c.NPMCache.(*npmcommon.Cache)
+				if !ok {
+					return errUnexpectedCache
+				}
+				objects = debug.ObjectsFromCache(cache)
+			}
+			if err := objects.AddManifests(manifests...); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			plan, err := debug.NewPlan(nodeName, planDataplaneCfg(config), objects)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			debug.PrettyPrintPlan(plan)
+			if liveTable != nil {
+				debug.PrettyPrintDiff("iptables AZURE-NPM chains", debug.DiffIptables(liveTable, plan.FilterTable()))
+			}
+			if liveIPv6Table != nil {
+				debug.PrettyPrintDiff("ip6tables AZURE-NPM chains", debug.DiffIptables(liveIPv6Table, plan.IPv6FilterTable()))
+			}
+			if liveIPSets != nil {
+				debug.PrettyPrintDiff("ipset members", debug.DiffIPSets(liveIPSets, plan.IPSets()))
+			}
+			return nil
+		},
+	}
+
+	planCmd.Flags().StringSliceP("manifests", "f", []string{}, "Set the NetworkPolicy, Pod and Namespace manifest file paths (optional)")
+	planCmd.Flags().Bool("manifests-only", false, "Plan only for the manifests, without the NPM cache or a diff")
+	planCmd.Flags().String("node-name", models.GetNodeName(), "Set the name of the node which pods are planned for")
+	planCmd.Flags().StringP("iptables-file", "i", "", "Set the iptable-save file path to diff with (optional, but required when using a cache file)")
+	planCmd.Flags().StringP("cache-file", "c", "", "Set the NPM cache file path (optional, but required when using an iptables file)")
+	planCmd.Flags().String("ip6tables-file", "", "Set the ip6tables-save file path to diff with (optional, but required with a cache file when IPv6 is enabled)")
+	planCmd.Flags().String("ipset-file", "", "Set the ipset save file path to diff with (optional, only used with a cache file)")
+
+	return planCmd
+}
+
+// planDataplaneCfg returns the config of a dataplane which programs ipsets and policies like NPM would
+func planDataplaneCfg(config *npmconfig.Config) *dataplane.Config {
+	networkName := config.WindowsNetworkName
+	if networkName == "" {
+		networkName = util.AzureNetworkName
+	}
+	ipsetMode := ipsets.ApplyAllIPSets
+	if config.Toggles.ApplyIPSetsOnNeed {
+		ipsetMode = ipsets.ApplyOnNeed
+	}
+
+	cfg := &dataplane.Config{
+		IPSetManagerCfg: &ipsets.IPSetManagerCfg{
+			IPSetMode:   ipsetMode,
+			NetworkName: networkName,
+		},
+		PolicyManagerCfg: &policies.PolicyManagerCfg{
+			PolicyMode:           policies.IPSetPolicyMode,
+			PlaceAzureChainFirst: config.Toggles.PlaceAzureChainFirst,
+			MaxBatchedACLsPerPod: config.MaxBatchedACLsPerPod,
+		},
+	}
+	if !util.IsWindowsDP() {
+		cfg.EnableIPv6 = config.Toggles.EnableIPv6
+		cfg.Audit = auditCfg(config)
+	}
+	return cfg
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+const (
+	planCmdString = "plan"
+
+	iptableSaveV2File = "../pkg/dataplane/testdata/iptablesave-v2"
+	ipsetSaveFile     = "../pkg/dataplane/testdata/ipsetsave"
+	npmCacheV2File    = "../pkg/dataplane/testdata/npmcachev2.json"
+	planManifestsFile = "../pkg/dataplane/testdata/plan-manifests.yaml"
+
+	manifestsFlag     = "-f"
+	manifestsOnlyFlag = "--manifests-only"
+	nodeNameFlag      = "--node-name"
+	ip6tablesFileFlag = "--ip6tables-file"
+	ipsetFileFlag     = "--ipset-file"
+)
+
+// (TODO) test case where HTTP request made for NPM cache
+func TestPlanCmd(t *testing.T) {
+	baseArgs := []string{debugCmdString, planCmdString}
+
+	tests := []*testCases{
+		{
+			name:    "unknown shorthand flag",
+			args:    concatArgs(baseArgs, unknownShorthandFlag),
+			wantErr: true,
+		},
+		{
+			name:    "iptables save file but no cache file",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File),
+			wantErr: true,
+		},
+		{
+			name:    "cache file but no iptables save file",
+			args:    concatArgs(baseArgs, npmCacheFlag, npmCacheV2File),
+			wantErr: true,
+		},
+		{
+			name:    "bad cache file",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "bad iptables save file",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, nonExistingFile, npmCacheFlag, npmCacheV2File),
+			wantErr: true,
+		},
+		{
+			name:    "bad manifest",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File, manifestsFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "ipset save file but no cache file",
+			args:    concatArgs(baseArgs, ipsetFileFlag, ipsetSaveFile),
+			wantErr: true,
+		},
+		{
+			name:    "bad ipset save file",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File, ipsetFileFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "bad ip6tables save file",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File, ip6tablesFileFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "manifests only without manifests",
+			args:    concatArgs(baseArgs, manifestsOnlyFlag),
+			wantErr: true,
+		},
+		{
+			name:    "correct files",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File),
+			wantErr: false,
+		},
+		{
+			name:    "correct files with manifests",
+			args:    concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File, manifestsFlag, planManifestsFile, nodeNameFlag, "node1"),
+			wantErr: false,
+		},
+		{
+			name: "correct files with ip6tables and ipset save files",
+			args: concatArgs(baseArgs, iptablesSaveFileFlag, iptableSaveV2File, npmCacheFlag, npmCacheV2File,
+				ip6tablesFileFlag, iptableSaveV2File, ipsetFileFlag, ipsetSaveFile),
+			wantErr: false,
+		},
+		{
+			name:    "manifests only",
+			args:    concatArgs(baseArgs, manifestsOnlyFlag, manifestsFlag, planManifestsFile, nodeNameFlag, "node1"),
+			wantErr: false,
+		},
+	}
+
+	testCommand(t, tests)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/nflog"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -22,6 +23,8 @@ import (
 	"github.com/spf13/viper"
 	"k8s.io/apimachinery/pkg/util/wait"
 	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -70,6 +73,19 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	if util.IsWindowsDP() {
 		config.Toggles.EnableV2NPM = true
 		klog.Infof("NPM is running on Windows Dataplane. Enabling V2 NPM")
+		if config.Toggles.EnableIPv6 {
+			config.Toggles.EnableIPv6 = false
+			klog.Infof("IPv6 is not supported on Windows Dataplane. Disabling IPv6")
+		}
+		if config.Toggles.EnableAdminNetworkPolicies {
+			config.Toggles.EnableAdminNetworkPolicies = false
+			klog.Infof("AdminNetworkPolicies are not supported on Windows Dataplane. Disabling AdminNetworkPolicies")
+		}
+		if len(config.Audit.Namespaces) > 0 || len(config.Audit.NetworkPolicies) > 0 {
+			config.Audit.Namespaces = nil
+			config.Audit.NetworkPolicies = nil
+			klog.Infof("policy verdict logging is not supported on Windows Dataplane. Disabling audit mode")
+		}
 	} else {
 		klog.Infof("NPM is running on Linux Dataplane")
 	}
@@ -157,6 +173,14 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 
 		npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
+		npmV2DataplaneCfg.EnableIPv6 = config.Toggles.EnableIPv6
+		npmV2DataplaneCfg.Audit = auditCfg(&config)
+		if config.DriftVerifyIntervalInSeconds != 0 {
+			npmV2DataplaneCfg.DriftInterval = time.Duration(config.DriftVerifyIntervalInSeconds) * time.Second
+		} else {
+			npmV2DataplaneCfg.DriftInterval = time.Duration(npmconfig.DefaultConfig.DriftVerifyIntervalInSeconds) * time.Second
+		}
+		npmV2DataplaneCfg.RepairDrift = config.Toggles.RepairDrift
 		if config.Toggles.ApplyIPSetsOnNeed {
 			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
 		} else {
@@ -174,14 +198,35 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 		npmV2DataplaneCfg.NodeIP = nodeIP
 
-		dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
+		var v2Dataplane *dataplane.DataPlane
+		v2Dataplane, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
 		if err != nil {
 			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create dataplane with error %v", err)
 			return fmt.Errorf("failed to create dataplane with error %w", err)
 		}
-		dp.RunPeriodicTasks()
+		v2Dataplane.RunPeriodicTasks()
+		dp = v2Dataplane
+
+		if npmV2DataplaneCfg.Audit.Enabled() {
+			klog.Infof("auditing namespaces %v and policies %v", config.Audit.Namespaces, config.Audit.NetworkPolicies)
+			listener := nflog.NewListener(npmV2DataplaneCfg.Audit.NFLOGGroup, v2Dataplane)
+			go func() {
+				if err := listener.Run(stopChannel); err != nil {
+					metrics.SendErrorLogAndMetric(util.NpmID, "error: stopped logging policy verdicts due to %v", err)
+				}
+			}()
+		}
 	}
 	npMgr := npm.NewNetworkPolicyManager(config, factory, dp, exec.New(), version, k8sServerVersion)
+	if config.Toggles.EnableV2NPM && config.Toggles.EnableAdminNetworkPolicies {
+		// AdminNetworkPolicies aren't part of client-go, so they're watched with a dynamic client
+		var dynamicClient *dynamic.DynamicClient
+		dynamicClient, err = dynamic.NewForConfig(k8sConfig)
+		if err != nil {
+			return fmt.Errorf("failed to generate dynamic client with cluster config: %w", err)
+		}
+		npMgr.WatchAdminNetworkPolicies(dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resyncPeriod))
+	}
 	err = metrics.CreateTelemetryHandle(config.NPMVersion(), version, npm.GetAIMetadata())
 	if err != nil {
 		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
@@ -209,6 +254,25 @@ func initLogging() error {
 	return nil
 }
 
+// auditCfg returns the audit config of the v2 dataplane, with defaults for the NFLOG group and rate limit
+func auditCfg(config *npmconfig.Config) *policies.AuditCfg {
+	audit := &policies.AuditCfg{
+		Namespaces: config.Audit.Namespaces,
+		PolicyKeys: config.Audit.NetworkPolicies,
+	}
+	if config.Audit.NFLOGGroup > 0 {
+		audit.NFLOGGroup = config.Audit.NFLOGGroup
+	} else {
+		audit.NFLOGGroup = npmconfig.DefaultConfig.Audit.NFLOGGroup
+	}
+	if config.Audit.RateLimitPerSecond > 0 {
+		audit.RateLimitPerSecond = config.Audit.RateLimitPerSecond
+	} else {
+		audit.RateLimitPerSecond = npmconfig.DefaultConfig.Audit.RateLimitPerSecond
+	}
+	return audit
+}
+
 func k8sServerVersion(kubeclientset kubernetes.Interface) *k8sversion.Info {
 	var err error
 	var serverVersion *k8sversion.Info
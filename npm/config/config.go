@@ -12,6 +12,9 @@ const (
 	defaultListeningPort        = 10091
 	defaultGrpcPort             = 10092
 	defaultGrpcServicePort      = 9002
+	defaultAuditNFLOGGroup      = 100
+	defaultAuditRateLimit       = 10
+	defaultDriftVerifyInterval  = 300
 	// ConfigEnvPath is what's used by viper to load config path
 	ConfigEnvPath = "NPM_CONFIG"
 
@@ -40,6 +43,13 @@ var DefaultConfig = Config{
 	MaxPendingNetPols:            defaultMaxPendingNetPols,
 	NetPolInvervalInMilliseconds: defaultNetPolInterval,
 
+	DriftVerifyIntervalInSeconds: defaultDriftVerifyInterval,
+
+	Audit: AuditConfig{
+		NFLOGGroup:         defaultAuditNFLOGGroup,
+		RateLimitPerSecond: defaultAuditRateLimit,
+	},
+
 	Toggles: Toggles{
 		EnablePrometheusMetrics: true,
 		EnablePprof:             true,
@@ -81,6 +91,25 @@ type Config struct {
 	MaxPendingNetPols            int     `json:"MaxPendingNetPols,omitempty"`
 	NetPolInvervalInMilliseconds int     `json:"NetPolInvervalInMilliseconds,omitempty"`
 	Toggles                      Toggles `json:"Toggles,omitempty"`
+	// DriftVerifyIntervalInSeconds is how often the ipsets and iptables chains in the kernel are compared with NPM's state.
+	// A negative value disables the verification. It applies for Linux v2 only.
+	DriftVerifyIntervalInSeconds int `json:"DriftVerifyIntervalInSeconds,omitempty"`
+	// Audit applies for Linux v2 only.
+	Audit AuditConfig `json:"Audit,omitempty"`
+}
+
+// AuditConfig logs the verdicts of the selected policies and counts them in Prometheus metrics.
+// No policy is audited by default.
+type AuditConfig struct {
+	// Namespaces whose NetworkPolicies are audited. "*" audits every policy, including AdminNetworkPolicies.
+	Namespaces []string `json:"Namespaces,omitempty"`
+	// NetworkPolicies are audited policies in the format "<namespace>/<name>",
+	// or "AdminNetworkPolicy/<name>" and "BaselineAdminNetworkPolicy/<name>".
+	NetworkPolicies []string `json:"NetworkPolicies,omitempty"`
+	// NFLOGGroup is the netlink group of the logged packets
+	NFLOGGroup int `json:"NFLOGGroup,omitempty"`
+	// RateLimitPerSecond limits the logged packets for each rule of an audited policy
+	RateLimitPerSecond int `json:"RateLimitPerSecond,omitempty"`
 }
 
 type Toggles struct {
@@ -94,6 +123,14 @@ type Toggles struct {
 	ApplyInBackground bool
 	// NetPolInBackground
 	NetPolInBackground bool
+	// EnableIPv6 enforces NetPols for IPv6 and dual-stack Pods. It applies for Linux v2 only
+	EnableIPv6 bool
+	// EnableAdminNetworkPolicies enforces AdminNetworkPolicies and BaselineAdminNetworkPolicies. It applies for Linux v2 only.
+	// NPM won't start until the policy.networking.k8s.io CRDs are installed.
+	EnableAdminNetworkPolicies bool
+	// RepairDrift reapplies the ipsets and chains that drifted from NPM's state in the kernel.
+	// Drift is only reported otherwise. It applies for Linux v2 only.
+	RepairDrift bool
 }
 
 type Flags struct {
@@ -41,3 +41,52 @@ func TotalIPTablesRestoreFailures(op OperationKind) (int, error) {
 		operationLabel: string(op),
 	}))
 }
+
+// IncPolicyVerdicts counts a packet logged by an audited policy.
+// The namespace is empty for AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+func IncPolicyVerdicts(policy, ns, verdict string) {
+	labels := prometheus.Labels{
+		policyLabel:    policy,
+		namespaceLabel: ns,
+		verdictLabel:   verdict,
+	}
+	policyVerdicts.With(labels).Inc()
+}
+
+func TotalPolicyVerdicts(policy, ns, verdict string) (int, error) {
+	return counterValue(policyVerdicts.With(prometheus.Labels{
+		policyLabel:    policy,
+		namespaceLabel: ns,
+		verdictLabel:   verdict,
+	}))
+}
+
+// DriftKind is the kind of kernel state which the drift verification compares with NPM's state.
+type DriftKind string
+
+const (
+	IPSetDrift DriftKind = "ipset"
+	ChainDrift DriftKind = "chain"
+)
+
+// SetKernelDrift records the number of ipsets or chains which drifted in the last drift verification.
+func SetKernelDrift(kind DriftKind, numDrifted int) {
+	kernelDrift.With(prometheus.Labels{kindLabel: string(kind)}).Set(float64(numDrifted))
+}
+
+// IncDriftRepairs counts a re-apply of drifted ipsets or chains.
+func IncDriftRepairs(kind DriftKind, hadError bool) {
+	labels := getErrorLabels(hadError)
+	labels[kindLabel] = string(kind)
+	driftRepairs.With(labels).Inc()
+}
+
+func GetKernelDrift(kind DriftKind) (int, error) {
+	return getVecValue(kernelDrift, prometheus.Labels{kindLabel: string(kind)})
+}
+
+func TotalDriftRepairs(kind DriftKind, hadError bool) (int, error) {
+	labels := getErrorLabels(hadError)
+	labels[kindLabel] = string(kind)
+	return counterValue(driftRepairs.With(labels))
+}
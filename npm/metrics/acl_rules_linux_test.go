@@ -47,3 +47,45 @@ func TestIncIPTablesRestoreFailures(t *testing.T) {
 	require.Nil(t, err, "failed to get metric")
 	require.Equal(t, 1, count, "should have failed to update once")
 }
+
+func TestIncPolicyVerdicts(t *testing.T) {
+	IncPolicyVerdicts("x/deny-all", "x", "DEFAULT-DENY")
+	IncPolicyVerdicts("x/deny-all", "x", "DEFAULT-DENY")
+	IncPolicyVerdicts("x/deny-all", "x", "ALLOW")
+
+	count, err := TotalPolicyVerdicts("x/deny-all", "x", "DEFAULT-DENY")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have logged default deny twice")
+
+	count, err = TotalPolicyVerdicts("x/deny-all", "x", "ALLOW")
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have logged allow once")
+}
+
+func TestKernelDrift(t *testing.T) {
+	SetKernelDrift(IPSetDrift, 3)
+	SetKernelDrift(ChainDrift, 1)
+	SetKernelDrift(IPSetDrift, 2)
+
+	val, err := GetKernelDrift(IPSetDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, val, "should have the last number of drifted ipsets")
+
+	val, err = GetKernelDrift(ChainDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, val, "should have the last number of drifted chains")
+}
+
+func TestIncDriftRepairs(t *testing.T) {
+	IncDriftRepairs(ChainDrift, false)
+	IncDriftRepairs(ChainDrift, true)
+	IncDriftRepairs(ChainDrift, false)
+
+	count, err := TotalDriftRepairs(ChainDrift, false)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have repaired chains twice")
+
+	count, err = TotalDriftRepairs(ChainDrift, true)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have failed to repair chains once")
+}
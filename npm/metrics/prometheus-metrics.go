@@ -118,13 +118,25 @@ var (
 	setPolicyFailures     *prometheus.CounterVec
 )
 
-const linuxPrefix = "linux"
+const (
+	linuxPrefix = "linux"
+
+	policyLabel    = "policy"
+	namespaceLabel = "namespace"
+	verdictLabel   = "verdict"
+	kindLabel      = "kind"
+)
 
 // linux metrics added in v1.5.5
 var (
 	itpablesRestoreLatency  *prometheus.HistogramVec
 	iptablesDeleteLatency   prometheus.Histogram
 	iptablesRestoreFailures *prometheus.CounterVec
+	// policyVerdicts counts the NFLOG packets of audited policies
+	policyVerdicts *prometheus.CounterVec
+	// kernelDrift is the number of ipsets and chains which differed from NPM's state in the last drift verification
+	kernelDrift  *prometheus.GaugeVec
+	driftRepairs *prometheus.CounterVec
 )
 
 type RegistryType string
@@ -196,6 +208,9 @@ func InitializeAll() {
 		register(itpablesRestoreLatency, "iptables_restore_latency_seconds", NodeMetrics)
 		register(iptablesDeleteLatency, "iptables_delete_latency_seconds", NodeMetrics)
 		register(iptablesRestoreFailures, "iptables_restore_failure_total", NodeMetrics)
+		register(policyVerdicts, "policy_verdicts_total", NodeMetrics)
+		register(kernelDrift, "kernel_drift", NodeMetrics)
+		register(driftRepairs, "kernel_drift_repairs_total", NodeMetrics)
 	}
 
 	log.Logf("Finished initializing all Prometheus metrics")
@@ -354,6 +369,36 @@ func InitializeLinuxMetrics() {
 		},
 		[]string{operationLabel},
 	)
+
+	policyVerdicts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "policy_verdicts_total",
+			Subsystem: linuxPrefix,
+			Help:      "Number of logged packets of audited policies by policy, namespace, and verdict labels. Logging is rate limited for each rule",
+		},
+		[]string{policyLabel, namespaceLabel, verdictLabel},
+	)
+
+	kernelDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "kernel_drift",
+			Subsystem: linuxPrefix,
+			Help:      "Number of NPM ipsets and iptables chains which differed from the expected state in the last drift verification by kind label (ipset/chain)",
+		},
+		[]string{kindLabel},
+	)
+
+	driftRepairs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kernel_drift_repairs_total",
+			Subsystem: linuxPrefix,
+			Help:      "Number of times drifted NPM ipsets or iptables chains were re-applied by kind label (ipset/chain) and had_error label",
+		},
+		[]string{kindLabel, hadErrorLabel},
+	)
 }
 
 // GetHandler returns the HTTP handler for the metrics endpoint
@@ -0,0 +1,21 @@
+// Package v1alpha1 contains the subset of the policy.networking.k8s.io v1alpha1 API
+// (https://github.com/kubernetes-sigs/network-policy-api) that NPM enforces.
+// The types mirror the upstream ones field for field so that objects from the dynamic client
+// can be converted to them with runtime.DefaultUnstructuredConverter.
+// +groupName=policy.networking.k8s.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GroupVersion is group version of the AdminNetworkPolicy APIs
+	GroupVersion = schema.GroupVersion{Group: "policy.networking.k8s.io", Version: "v1alpha1"}
+
+	// AdminNetworkPolicyResource is the resource to watch AdminNetworkPolicies with
+	AdminNetworkPolicyResource = GroupVersion.WithResource("adminnetworkpolicies")
+
+	// BaselineAdminNetworkPolicyResource is the resource to watch BaselineAdminNetworkPolicies with
+	BaselineAdminNetworkPolicyResource = GroupVersion.WithResource("baselineadminnetworkpolicies")
+)
@@ -0,0 +1,166 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdminNetworkPolicy is a cluster-scoped policy which namespace owners cannot override.
+// Its rules are evaluated before any NetworkPolicy.
+type AdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   AdminNetworkPolicySpec   `json:"spec"`
+	Status AdminNetworkPolicyStatus `json:"status,omitempty"`
+}
+
+// AdminNetworkPolicyStatus defines the observed state of AdminNetworkPolicy.
+type AdminNetworkPolicyStatus struct {
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+// AdminNetworkPolicySpec defines the desired state of AdminNetworkPolicy.
+type AdminNetworkPolicySpec struct {
+	// Priority is a value from 0 to 1000. Policies with lower priorities are evaluated first.
+	// The order of policies with the same priority is undefined.
+	Priority int32 `json:"priority"`
+
+	// Subject defines the pods to which this AdminNetworkPolicy applies.
+	Subject AdminNetworkPolicySubject `json:"subject"`
+
+	// Ingress is the list of ingress rules, evaluated in order.
+	Ingress []AdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+
+	// Egress is the list of egress rules, evaluated in order.
+	Egress []AdminNetworkPolicyEgressRule `json:"egress,omitempty"`
+}
+
+// AdminNetworkPolicySubject selects the pods of a policy. Exactly one field must be set.
+type AdminNetworkPolicySubject struct {
+	// Namespaces selects all the pods in the selected namespaces.
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+
+	// Pods selects the selected pods in the selected namespaces.
+	Pods *NamespacedPod `json:"pods,omitempty"`
+}
+
+// NamespacedPod selects pods with a podSelector in the namespaces selected by a namespaceSelector.
+type NamespacedPod struct {
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	PodSelector       metav1.LabelSelector `json:"podSelector"`
+}
+
+// AdminNetworkPolicyIngressRule matches traffic from the peers to the subject's ports.
+type AdminNetworkPolicyIngressRule struct {
+	Name   string                          `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction    `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort       `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyEgressRule matches traffic from the subject to the peers' ports.
+type AdminNetworkPolicyEgressRule struct {
+	Name   string                         `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction   `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort      `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyRuleAction is the verdict of a matching rule.
+type AdminNetworkPolicyRuleAction string
+
+const (
+	// AdminNetworkPolicyRuleActionAllow allows the traffic without evaluating any lower-priority policy.
+	AdminNetworkPolicyRuleActionAllow AdminNetworkPolicyRuleAction = "Allow"
+	// AdminNetworkPolicyRuleActionDeny drops the traffic without evaluating any lower-priority policy.
+	AdminNetworkPolicyRuleActionDeny AdminNetworkPolicyRuleAction = "Deny"
+	// AdminNetworkPolicyRuleActionPass skips the rest of the AdminNetworkPolicies,
+	// leaving the verdict to NetworkPolicies and then the BaselineAdminNetworkPolicy.
+	AdminNetworkPolicyRuleActionPass AdminNetworkPolicyRuleAction = "Pass"
+)
+
+// AdminNetworkPolicyIngressPeer selects the sources of ingress traffic. Exactly one field must be set.
+type AdminNetworkPolicyIngressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+}
+
+// AdminNetworkPolicyEgressPeer selects the destinations of egress traffic. Exactly one field must be set.
+type AdminNetworkPolicyEgressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+	// Nodes selects the IPs of the selected nodes.
+	Nodes *metav1.LabelSelector `json:"nodes,omitempty"`
+	// Networks selects IPs in the CIDRs.
+	Networks []CIDR `json:"networks,omitempty"`
+}
+
+// CIDR is an IPv4 or IPv6 CIDR, e.g. "10.0.0.0/8" or "fd00::/8".
+type CIDR string
+
+// AdminNetworkPolicyPort selects destination ports. Exactly one field must be set.
+type AdminNetworkPolicyPort struct {
+	PortNumber *Port      `json:"portNumber,omitempty"`
+	NamedPort  *string    `json:"namedPort,omitempty"`
+	PortRange  *PortRange `json:"portRange,omitempty"`
+}
+
+// Port is a port number and its protocol, which defaults to TCP.
+type Port struct {
+	Protocol corev1.Protocol `json:"protocol"`
+	Port     int32           `json:"port"`
+}
+
+// PortRange is an inclusive range of ports and their protocol, which defaults to TCP.
+type PortRange struct {
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	Start    int32           `json:"start"`
+	End      int32           `json:"end"`
+}
+
+// BaselineAdminNetworkPolicy is a cluster-scoped policy which NetworkPolicies can override.
+// Its rules are evaluated for the pods that no NetworkPolicy selects. There can only be one, named "default".
+type BaselineAdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   BaselineAdminNetworkPolicySpec   `json:"spec"`
+	Status BaselineAdminNetworkPolicyStatus `json:"status,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyStatus defines the observed state of BaselineAdminNetworkPolicy.
+type BaselineAdminNetworkPolicyStatus struct {
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+// BaselineAdminNetworkPolicySpec defines the desired state of BaselineAdminNetworkPolicy.
+type BaselineAdminNetworkPolicySpec struct {
+	Subject AdminNetworkPolicySubject               `json:"subject"`
+	Ingress []BaselineAdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress  []BaselineAdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyIngressRule is AdminNetworkPolicyIngressRule without the Pass action.
+type BaselineAdminNetworkPolicyIngressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer      `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyEgressRule is AdminNetworkPolicyEgressRule without the Pass action.
+type BaselineAdminNetworkPolicyEgressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer       `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyRuleAction is the verdict of a matching rule.
+type BaselineAdminNetworkPolicyRuleAction string
+
+const (
+	BaselineAdminNetworkPolicyRuleActionAllow BaselineAdminNetworkPolicyRuleAction = "Allow"
+	BaselineAdminNetworkPolicyRuleActionDeny  BaselineAdminNetworkPolicyRuleAction = "Deny"
+)
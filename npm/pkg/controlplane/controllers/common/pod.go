@@ -8,9 +8,11 @@ import (
 )
 
 type NpmPod struct {
-	Name           string
-	Namespace      string
-	PodIP          string
+	Name      string
+	Namespace string
+	PodIP     string
+	// PodIPs are the IPs in the ipsets: PodIP, and in a dual-stack dataplane, the Pod's IP of the other family
+	PodIPs         []string `json:",omitempty"`
 	Labels         map[string]string
 	ContainerPorts []corev1.ContainerPort
 	Phase          corev1.PodPhase
@@ -0,0 +1,285 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/adminnetworkpolicy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+)
+
+var (
+	errAdminNetPolKeyFormat = errors.New("invalid admin network policy key format")
+	errAdminNetPolDecoding  = errors.New("failed to decode admin network policy")
+)
+
+// adminNetPolTranslator decodes an AdminNetworkPolicy or BaselineAdminNetworkPolicy and translates it.
+// The spec is cached to ignore updates which don't change it.
+type adminNetPolTranslator func(obj *unstructured.Unstructured) (spec interface{}, npmNetPol *policies.NPMNetworkPolicy, err error)
+
+// AdminNetworkPolicyController programs the AdminNetworkPolicies or the BaselineAdminNetworkPolicies of the cluster.
+// Both are cluster-scoped custom resources which are watched with a dynamic informer,
+// since their API isn't part of client-go.
+type AdminNetworkPolicyController struct {
+	sync.RWMutex
+	tier       policies.PolicyTier
+	lister     cache.GenericLister
+	workqueue  workqueue.RateLimitingInterface
+	rawSpecMap map[string]interface{} // Key is <policyname>
+	translate  adminNetPolTranslator
+	dp         dataplane.GenericDataplane
+}
+
+// NewAdminNetworkPolicyController creates the controller for AdminNetworkPolicies.
+// anpInformer is the dynamic informer for v1alpha1.AdminNetworkPolicyResource.
+func NewAdminNetworkPolicyController(anpInformer informers.GenericInformer, dp dataplane.GenericDataplane) *AdminNetworkPolicyController {
+	translate := func(obj *unstructured.Unstructured) (interface{}, *policies.NPMNetworkPolicy, error) {
+		anpObj := &v1alpha1.AdminNetworkPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), anpObj); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", errAdminNetPolDecoding, err.Error())
+		}
+		npmNetPol, err := translation.TranslateAdminNetworkPolicy(anpObj)
+		return &anpObj.Spec, npmNetPol, err
+	}
+	return newAdminNetworkPolicyController(policies.AdminTier, anpInformer, translate, dp)
+}
+
+// NewBaselineAdminNetworkPolicyController creates the controller for BaselineAdminNetworkPolicies.
+// banpInformer is the dynamic informer for v1alpha1.BaselineAdminNetworkPolicyResource.
+func NewBaselineAdminNetworkPolicyController(banpInformer informers.GenericInformer, dp dataplane.GenericDataplane) *AdminNetworkPolicyController {
+	translate := func(obj *unstructured.Unstructured) (interface{}, *policies.NPMNetworkPolicy, error) {
+		banpObj := &v1alpha1.BaselineAdminNetworkPolicy{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), banpObj); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", errAdminNetPolDecoding, err.Error())
+		}
+		npmNetPol, err := translation.TranslateBaselineAdminNetworkPolicy(banpObj)
+		return &banpObj.Spec, npmNetPol, err
+	}
+	return newAdminNetworkPolicyController(policies.BaselineTier, banpInformer, translate, dp)
+}
+
+func newAdminNetworkPolicyController(tier policies.PolicyTier, informer informers.GenericInformer, translate adminNetPolTranslator,
+	dp dataplane.GenericDataplane) *AdminNetworkPolicyController { //nolint // gofumpt
+	adminNetPolController := &AdminNetworkPolicyController{
+		tier:       tier,
+		lister:     informer.Lister(),
+		workqueue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), string(tier)),
+		rawSpecMap: make(map[string]interface{}),
+		translate:  translate,
+		dp:         dp,
+	}
+
+	informer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    adminNetPolController.addAdminNetworkPolicy,
+			UpdateFunc: adminNetPolController.updateAdminNetworkPolicy,
+			DeleteFunc: adminNetPolController.deleteAdminNetworkPolicy,
+		},
+	)
+	return adminNetPolController
+}
+
+func (c *AdminNetworkPolicyController) GetCache() map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+	return c.rawSpecMap
+}
+
+// policyKey returns the PolicyKey of the translated policy, which is unique across tiers.
+func (c *AdminNetworkPolicyController) policyKey(name string) string {
+	return fmt.Sprintf("%s/%s", c.tier, name)
+}
+
+// getAdminNetworkPolicyKey returns the name of the policy if obj is a valid policy object.
+// If not, it returns error.
+func (c *AdminNetworkPolicyController) getAdminNetworkPolicyKey(obj interface{}) (string, error) {
+	var key string
+	_, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return key, fmt.Errorf("cannot cast obj (%v) to %s obj err: %w", obj, c.tier, errAdminNetPolKeyFormat)
+	}
+
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		return key, fmt.Errorf("error due to %w", err)
+	}
+
+	return key, nil
+}
+
+func (c *AdminNetworkPolicyController) addAdminNetworkPolicy(obj interface{}) {
+	key, err := c.getAdminNetworkPolicyKey(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) updateAdminNetworkPolicy(old, newObj interface{}) {
+	key, err := c.getAdminNetworkPolicyKey(newObj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	newPolicy, _ := newObj.(*unstructured.Unstructured)
+	oldPolicy, ok := old.(*unstructured.Unstructured)
+	if ok && oldPolicy.GetResourceVersion() == newPolicy.GetResourceVersion() {
+		// Periodic resync will send update events for all known policies.
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) deleteAdminNetworkPolicy(obj interface{}) {
+	policyObj, ok := obj.(*unstructured.Unstructured)
+	// DeleteFunc gets the final state of the resource (if it is known).
+	// Otherwise, it gets an object of type DeletedFinalStateUnknown.
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			metrics.SendErrorLogAndMetric(util.NetpolID, "[%s DELETE EVENT] Received unexpected object type: %v", c.tier, obj)
+			return
+		}
+
+		if policyObj, ok = tombstone.Obj.(*unstructured.Unstructured); !ok {
+			metrics.SendErrorLogAndMetric(util.NetpolID, "[%s DELETE EVENT] Received unexpected object type (error decoding object tombstone, invalid type): %v", c.tier, obj)
+			return
+		}
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(policyObj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	klog.Infof("Starting %s worker", c.tier)
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	klog.Infof("Started %s worker", c.tier)
+	<-stopCh
+	klog.Infof("Shutting down %s workers", c.tier)
+}
+
+func (c *AdminNetworkPolicyController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AdminNetworkPolicyController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.workqueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			c.workqueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v, err %w", obj, errWorkqueueFormatting))
+			return nil
+		}
+		if err := c.syncAdminNetPol(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %w, requeuing", key, err)
+		}
+		c.workqueue.Forget(obj)
+		klog.Infof("Successfully synced %s '%s'", c.tier, key)
+		return nil
+	}(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		metrics.SendErrorLogAndMetric(util.NetpolID, "syncAdminNetPol error due to %v", err)
+		return true
+	}
+
+	return true
+}
+
+// syncAdminNetPol compares the actual state with the desired, and attempts to converge the two.
+func (c *AdminNetworkPolicyController) syncAdminNetPol(key string) error {
+	obj, err := c.lister.Get(key)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("%s %s is not found, may be it is deleted", c.tier, key)
+			return c.cleanUpAdminNetworkPolicy(key)
+		}
+		return err
+	}
+
+	policyObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("cannot cast obj (%v) to %s obj err: %w", obj, c.tier, errAdminNetPolKeyFormat))
+		return nil //nolint HandleError  is used instead of returning error to caller
+	}
+
+	if policyObj.GetDeletionTimestamp() != nil || policyObj.GetDeletionGracePeriodSeconds() != nil {
+		return c.cleanUpAdminNetworkPolicy(key)
+	}
+
+	spec, npmNetPol, err := c.translate(policyObj)
+	if err != nil {
+		klog.Errorf("Failed to translate %s %s: %s", c.tier, key, err.Error())
+		// Returning nil to prevent re-queuing since this is not a transient error.
+		return nil
+	}
+
+	if cachedSpec, ok := c.rawSpecMap[key]; ok && reflect.DeepEqual(cachedSpec, spec) {
+		return nil
+	}
+
+	// DP update policy call will delete the old rules if this policy already exists in kernel
+	if err := c.dp.UpdatePolicy(npmNetPol); err != nil {
+		return fmt.Errorf("[syncAdminNetPol] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+
+	c.rawSpecMap[key] = spec
+	return nil
+}
+
+// cleanUpAdminNetworkPolicy removes the policy from the dataplane if it was applied.
+func (c *AdminNetworkPolicyController) cleanUpAdminNetworkPolicy(key string) error {
+	if _, ok := c.rawSpecMap[key]; !ok {
+		return nil
+	}
+
+	if err := c.dp.RemovePolicy(c.policyKey(key)); err != nil {
+		return fmt.Errorf("[cleanUpAdminNetworkPolicy] Error: failed to remove policy due to %w", err)
+	}
+
+	delete(c.rawSpecMap, key)
+	return nil
+}
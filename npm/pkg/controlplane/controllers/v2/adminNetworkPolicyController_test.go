@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/adminnetworkpolicy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+type adminNetPolFixture struct {
+	t *testing.T
+
+	anpInformer  informers.GenericInformer
+	banpInformer informers.GenericInformer
+
+	anpController  *AdminNetworkPolicyController
+	banpController *AdminNetworkPolicyController
+}
+
+func newAdminNetPolFixture(t *testing.T, dp dataplane.GenericDataplane) *adminNetPolFixture {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, noResyncPeriodFunc())
+	f := &adminNetPolFixture{
+		t:            t,
+		anpInformer:  factory.ForResource(v1alpha1.AdminNetworkPolicyResource),
+		banpInformer: factory.ForResource(v1alpha1.BaselineAdminNetworkPolicyResource),
+	}
+	f.anpController = NewAdminNetworkPolicyController(f.anpInformer, dp)
+	f.banpController = NewBaselineAdminNetworkPolicyController(f.banpInformer, dp)
+
+	// Do not start informer to avoid unnecessary event triggers
+	return f
+}
+
+func toUnstructured(t *testing.T, obj interface{}) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: content}
+}
+
+func createANP(priority int32) *v1alpha1.AdminNetworkPolicy {
+	return &v1alpha1.AdminNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "AdminNetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "deny-tenant-b",
+			ResourceVersion: "1",
+		},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+						{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "b"}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addToInformer simulates an add or update event and processes it
+func addToInformer(f *adminNetPolFixture, informer informers.GenericInformer, c *AdminNetworkPolicyController, obj *unstructured.Unstructured) {
+	require.NoError(f.t, informer.Informer().GetIndexer().Update(obj))
+	c.addAdminNetworkPolicy(obj)
+	if c.workqueue.Len() == 0 {
+		return
+	}
+	c.processNextWorkItem()
+}
+
+func TestAddUpdateDeleteAdminNetworkPolicy(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("AdminNetworkPolicies are only supported in Linux")
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	var applied []*policies.NPMNetworkPolicy
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		applied = append(applied, policy)
+		return nil
+	}).Times(2)
+	dp.EXPECT().RemovePolicy("AdminNetworkPolicy/deny-tenant-b").Return(nil).Times(1)
+
+	// 1. add
+	anp := createANP(10)
+	addToInformer(f, f.anpInformer, f.anpController, toUnstructured(t, anp))
+	require.Len(t, applied, 1)
+	require.Equal(t, "AdminNetworkPolicy/deny-tenant-b", applied[0].PolicyKey)
+	require.Equal(t, policies.AdminTier, applied[0].Tier)
+	require.Equal(t, int32(10), applied[0].Priority)
+	require.Len(t, f.anpController.GetCache(), 1)
+
+	// 2. same spec with a new resource version is a no-op
+	anp.ResourceVersion = "2"
+	addToInformer(f, f.anpInformer, f.anpController, toUnstructured(t, anp))
+	require.Len(t, applied, 1)
+
+	// 3. new priority is reprogrammed
+	anp.ResourceVersion = "3"
+	anp.Spec.Priority = 20
+	addToInformer(f, f.anpInformer, f.anpController, toUnstructured(t, anp))
+	require.Len(t, applied, 2)
+	require.Equal(t, int32(20), applied[1].Priority)
+
+	// 4. delete with a tombstone
+	obj := toUnstructured(t, anp)
+	require.NoError(t, f.anpInformer.Informer().GetIndexer().Delete(obj))
+	f.anpController.deleteAdminNetworkPolicy(cache.DeletedFinalStateUnknown{Key: anp.Name, Obj: obj})
+	f.anpController.processNextWorkItem()
+	require.Empty(t, f.anpController.GetCache())
+}
+
+func TestAddBaselineAdminNetworkPolicy(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("AdminNetworkPolicies are only supported in Linux")
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	var applied *policies.NPMNetworkPolicy
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(policy *policies.NPMNetworkPolicy) error {
+		applied = policy
+		return nil
+	}).Times(1)
+
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "BaselineAdminNetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{},
+			},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+						{Networks: []v1alpha1.CIDR{"0.0.0.0/0"}},
+					},
+				},
+			},
+		},
+	}
+	addToInformer(f, f.banpInformer, f.banpController, toUnstructured(t, banp))
+	require.NotNil(t, applied)
+	require.Equal(t, "BaselineAdminNetworkPolicy/default", applied.PolicyKey)
+	require.Equal(t, policies.BaselineTier, applied.Tier)
+	require.Len(t, f.banpController.GetCache(), 1)
+}
+
+func TestAdminNetworkPolicyTranslationFailure(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("AdminNetworkPolicies are only supported in Linux")
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// no calls to the dataplane
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminNetPolFixture(t, dp)
+
+	anp := createANP(10)
+	anp.Spec.Egress = []v1alpha1.AdminNetworkPolicyEgressRule{
+		{
+			Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+			To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+				{Nodes: &metav1.LabelSelector{}},
+			},
+		},
+	}
+	addToInformer(f, f.anpInformer, f.anpController, toUnstructured(t, anp))
+	require.Empty(t, f.anpController.GetCache())
+	// not requeued since the error isn't transient
+	require.Equal(t, 0, f.anpController.workqueue.Len())
+}
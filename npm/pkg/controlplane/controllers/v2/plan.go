@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeinformers "k8s.io/client-go/informers"
+)
+
+// SyncObjects syncs the namespaces, then the pods, then the network policies with the dataplane like the controllers do after NPM starts.
+// It runs without informers or workers, so it can plan what NPM programs for a set of objects.
+func SyncObjects(dp dataplane.GenericDataplane, namespaces []*corev1.Namespace, pods []*corev1.Pod,
+	netPols []*networkingv1.NetworkPolicy, enableIPv6 bool,
+) error {
+	// the factory is never started, so it doesn't need a clientset
+	factory := kubeinformers.NewSharedInformerFactory(nil, 0)
+	npmNamespaceCache := &NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
+	nsc := NewNamespaceController(factory.Core().V1().Namespaces(), dp, npmNamespaceCache)
+	pc := NewPodController(factory.Core().V1().Pods(), dp, npmNamespaceCache, enableIPv6)
+	npc := NewNetworkPolicyController(factory.Networking().V1().NetworkPolicies(), dp)
+
+	nsIndexer := factory.Core().V1().Namespaces().Informer().GetIndexer()
+	for _, nsObj := range namespaces {
+		key, needSync := nsc.needSync(nsObj, "ADD")
+		if !needSync {
+			continue
+		}
+		if err := nsIndexer.Add(nsObj); err != nil {
+			return fmt.Errorf("failed to add namespace %s to informer cache: %w", key, err)
+		}
+		if err := nsc.syncNamespace(key); err != nil {
+			return fmt.Errorf("failed to sync namespace %s: %w", key, err)
+		}
+	}
+
+	podIndexer := factory.Core().V1().Pods().Informer().GetIndexer()
+	for _, podObj := range pods {
+		key, needSync := pc.needSync(addEvent, podObj)
+		if !needSync || isCompletePod(podObj) {
+			continue
+		}
+		if err := podIndexer.Add(podObj); err != nil {
+			return fmt.Errorf("failed to add pod %s to informer cache: %w", key, err)
+		}
+		if err := pc.syncPod(key); err != nil {
+			return fmt.Errorf("failed to sync pod %s: %w", key, err)
+		}
+	}
+
+	netPolIndexer := factory.Networking().V1().NetworkPolicies().Informer().GetIndexer()
+	for _, netPolObj := range netPols {
+		key, err := npc.getNetworkPolicyKey(netPolObj)
+		if err != nil {
+			return err
+		}
+		if err := netPolIndexer.Add(netPolObj); err != nil {
+			return fmt.Errorf("failed to add network policy %s to informer cache: %w", key, err)
+		}
+		if err := npc.syncNetPol(key); err != nil {
+			return fmt.Errorf("failed to sync network policy %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -56,6 +56,7 @@ type podFixture struct {
 	kubeobjects []runtime.Object
 
 	dp            dataplane.GenericDataplane
+	enableIPv6    bool
 	podController *PodController
 	kubeInformer  kubeinformers.SharedInformerFactory
 }
@@ -84,7 +85,7 @@ func (f *podFixture) newPodController(_ chan struct{}) {
 	f.kubeInformer = kubeinformers.NewSharedInformerFactory(kubeclient, noResyncPeriodFunc())
 
 	npmNamespaceCache := &NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache)
+	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache, f.enableIPv6)
 
 	for _, pod := range f.podLister {
 		err := f.kubeInformer.Core().V1().Pods().Informer().GetIndexer().Add(pod)
@@ -381,6 +382,57 @@ func TestAddPod(t *testing.T) {
 	checkNpmPodWithInput("TestAddPod", f, podObj)
 }
 
+func TestAddPodDualStack(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("IPv6 is only supported in Linux")
+	}
+
+	labels := map[string]string{
+		"app": "test-pod",
+	}
+	podObj := createPod("test-pod", "test-namespace", "0", "1.2.3.4", labels, NonHostNetwork, corev1.PodRunning)
+	podObj.Status.PodIPs = []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "2001:db8::4"}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newFixture(t, dp)
+	f.enableIPv6 = true
+	f.podLister = append(f.podLister, podObj)
+	f.kubeobjects = append(f.kubeobjects, podObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.newPodController(stopCh)
+
+	mockIPSets := []*ipsets.IPSetMetadata{
+		ipsets.NewIPSetMetadata("test-namespace", ipsets.Namespace),
+		ipsets.NewIPSetMetadata("app", ipsets.KeyLabelOfPod),
+		ipsets.NewIPSetMetadata("app:test-pod", ipsets.KeyValueLabelOfPod),
+	}
+	namedPortSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata("app:test-pod", ipsets.NamedPorts)}
+	for _, podIP := range []string{"1.2.3.4", "2001:db8::4"} {
+		podMetadata := dataplane.NewPodMetadata("test-namespace/test-pod", podIP, "")
+		dp.EXPECT().AddToSets(mockIPSets[:1], podMetadata).Return(nil).Times(1)
+		dp.EXPECT().AddToSets(mockIPSets[1:], podMetadata).Return(nil).Times(1)
+		dp.EXPECT().
+			AddToSets(namedPortSet, dataplane.NewPodMetadata("test-namespace/test-pod", podIP+",8080", "")).
+			Return(nil).Times(1)
+	}
+	dp.EXPECT().AddToLists([]*ipsets.IPSetMetadata{kubeAllNamespaces}, mockIPSets[:1]).Return(nil).Times(1)
+	dp.EXPECT().ApplyDataPlane().Return(nil).Times(1)
+
+	addPod(t, f, podObj)
+	testCases := []expectedValues{
+		{1, 1, 0, podPromVals{1, 1, 0, 0, 0, 0, 0}},
+	}
+	// sleep in case rate limiter adds back to workqueue
+	time.Sleep(sleepDurationForRateLimiter)
+	checkPodTestResult("TestAddPodDualStack", f, testCases)
+	checkNpmPodWithInput("TestAddPodDualStack", f, podObj)
+	require.Equal(t, []string{"1.2.3.4", "2001:db8::4"}, f.podController.podMap["test-namespace/test-pod"].PodIPs)
+}
+
 func TestAddHostNetworkPod(t *testing.T) {
 	labels := map[string]string{
 		"app": "test-pod",
@@ -1059,6 +1111,35 @@ func TestHasValidPodIP(t *testing.T) {
 	require.False(t, hasValidPodIP(podObj))
 }
 
+func TestPodIPs(t *testing.T) {
+	tests := []struct {
+		name       string
+		enableIPv6 bool
+		podIP      string
+		podIPs     []string
+		want       []string
+	}{
+		{"ipv4", false, "1.2.3.4", []string{"1.2.3.4"}, []string{"1.2.3.4"}},
+		{"dual-stack without ipv6", false, "1.2.3.4", []string{"1.2.3.4", "2001:db8::4"}, []string{"1.2.3.4"}},
+		{"ipv6 without ipv6", false, "2001:db8::4", []string{"2001:db8::4"}, nil},
+		{"dual-stack", true, "1.2.3.4", []string{"1.2.3.4", "2001:db8::4"}, []string{"1.2.3.4", "2001:db8::4"}},
+		{"dual-stack ipv6 primary", true, "2001:db8::4", []string{"2001:db8::4", "1.2.3.4"}, []string{"2001:db8::4", "1.2.3.4"}},
+		{"ipv6", true, "2001:db8::4", []string{"2001:db8::4"}, []string{"2001:db8::4"}},
+		{"invalid", true, "not-an-ip", []string{"not-an-ip"}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			podObj := createPod("test-pod", "test-namespace", "0", tt.podIP, nil, NonHostNetwork, corev1.PodRunning)
+			for _, ip := range tt.podIPs {
+				podObj.Status.PodIPs = append(podObj.Status.PodIPs, corev1.PodIP{IP: ip})
+			}
+			c := &PodController{enableIPv6: tt.enableIPv6}
+			require.Equal(t, tt.want, c.podIPs(podObj))
+		})
+	}
+}
+
 func TestIsCompletePod(t *testing.T) {
 	var zeroGracePeriod int64
 	var defaultGracePeriod int64 = 30
@@ -0,0 +1,297 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/adminnetworkpolicy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// ErrUnsupportedAdminNetworkPolicy is returned when an AdminNetworkPolicy or BaselineAdminNetworkPolicy is translated in windows.
+	ErrUnsupportedAdminNetworkPolicy = errors.New("unsupported admin network policy on windows")
+	// ErrUnsupportedNodesPeer is returned when an egress rule selects nodes.
+	ErrUnsupportedNodesPeer = errors.New("unsupported nodes peer in admin network policy")
+	// ErrUnsupportedSubjectNamespaceSelector is returned when the subject's namespace selector has multiple values in a matchExpression,
+	// since the pod selector of a policy can't be a union of namespace selectors.
+	ErrUnsupportedSubjectNamespaceSelector = errors.New("unsupported multiple values in a matchExpression of the subject's namespace selector")
+	errUnknownAdminAction                  = errors.New("unknown admin network policy rule action")
+)
+
+// adminPeer holds the fields of an ingress peer or an egress peer. Ingress peers can't select nodes or networks.
+type adminPeer struct {
+	namespaces *metav1.LabelSelector
+	pods       *v1alpha1.NamespacedPod
+	nodes      *metav1.LabelSelector
+	networks   []v1alpha1.CIDR
+}
+
+// adminVerdict returns the verdict of ACLs for a rule's action.
+func adminVerdict(action v1alpha1.AdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionPass:
+		return policies.Passed, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownAdminAction, action)
+	}
+}
+
+// namespacedPodSelector translates a NamespacedPod to translatedIPSets, children of translated IPSets, and a SetInfo list for each flattened namespace selector.
+func namespacedPodSelector(policyKey string, matchType policies.MatchType, pods *v1alpha1.NamespacedPod) (*podSelectorResult, [][]policies.SetInfo, error) {
+	psResult, err := podSelector(policyKey, matchType, &pods.PodSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flattenNSSelector, err := flattenNameSpaceSelector(&pods.NamespaceSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setInfos := make([][]policies.SetInfo, 0, len(flattenNSSelector))
+	for i := range flattenNSSelector {
+		nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+		psResult.psSets = append(psResult.psSets, nsSelectorIPSets...)
+		setInfos = append(setInfos, append(nsSelectorList, psResult.psList...))
+	}
+	return psResult, setInfos, nil
+}
+
+// adminSubject translates the subject of an AdminNetworkPolicy or BaselineAdminNetworkPolicy to the pod selector of npmNetPol.
+func adminSubject(npmNetPol *policies.NPMNetworkPolicy, subject *v1alpha1.AdminNetworkPolicySubject) error {
+	if subject.Namespaces != nil {
+		flattenNSSelector, err := flattenNameSpaceSelector(subject.Namespaces)
+		if err != nil {
+			return err
+		}
+		if len(flattenNSSelector) != 1 {
+			return ErrUnsupportedSubjectNamespaceSelector
+		}
+
+		nsSelectorIPSets, nsSelectorList := nameSpaceSelector(policies.EitherMatch, &flattenNSSelector[0])
+		npmNetPol.PodSelectorIPSets = nsSelectorIPSets
+		npmNetPol.ChildPodSelectorIPSets = []*ipsets.TranslatedIPSet{}
+		npmNetPol.PodSelectorList = nsSelectorList
+		return nil
+	}
+
+	if subject.Pods != nil {
+		psResult, setInfos, err := namespacedPodSelector(npmNetPol.PolicyKey, policies.EitherMatch, subject.Pods)
+		if err != nil {
+			return err
+		}
+		if len(setInfos) != 1 {
+			return ErrUnsupportedSubjectNamespaceSelector
+		}
+
+		npmNetPol.PodSelectorIPSets = psResult.psSets
+		npmNetPol.ChildPodSelectorIPSets = psResult.childPSSets
+		npmNetPol.PodSelectorList = setInfos[0]
+	}
+	return nil
+}
+
+// networksIPSet returns the translatedIPSet of the CIDRs in a networks peer.
+// Its name follows the contract of ipBlockSetName() with the policy's tier as the namespace.
+func networksIPSet(npmNetPol *policies.NPMNetworkPolicy, policyName string, direction policies.Direction, ruleIndex, peerIndex int,
+	networks []v1alpha1.CIDR) (*ipsets.TranslatedIPSet, error) { //nolint // gofumpt
+	members := make([]string, 0, len(networks))
+	for _, network := range networks {
+		cidr := string(network)
+		if !util.IsIPV4(cidr) && !util.IsIPV6(cidr) {
+			return nil, ErrUnsupportedIPAddress
+		}
+		// Ipset doesn't allow 0.0.0.0/0 or ::/0 to be added, so they are split in half.
+		if splitCIDRs, ok := splitAllCIDRs[cidr]; ok {
+			members = append(members, splitCIDRs...)
+			continue
+		}
+		members = append(members, cidr)
+	}
+
+	setName := ipBlockSetName(policyName, string(npmNetPol.Tier), direction, ruleIndex, peerIndex)
+	return ipsets.NewTranslatedIPSet(setName, ipsets.CIDRBlocks, members...), nil
+}
+
+// adminPeerSetInfos translates a peer to the SetInfo list of each ACL it needs and updates the rule IPSets of npmNetPol.
+func adminPeerSetInfos(npmNetPol *policies.NPMNetworkPolicy, policyName string, direction policies.Direction, matchType policies.MatchType, ruleIndex, peerIndex int,
+	peer *adminPeer) ([][]policies.SetInfo, error) { //nolint // gofumpt
+	switch {
+	case peer.namespaces != nil:
+		flattenNSSelector, err := flattenNameSpaceSelector(peer.namespaces)
+		if err != nil {
+			return nil, err
+		}
+
+		setInfos := make([][]policies.SetInfo, 0, len(flattenNSSelector))
+		for i := range flattenNSSelector {
+			nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+			setInfos = append(setInfos, nsSelectorList)
+		}
+		return setInfos, nil
+	case peer.pods != nil:
+		psResult, setInfos, err := namespacedPodSelector(npmNetPol.PolicyKey, matchType, peer.pods)
+		if err != nil {
+			return nil, err
+		}
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.psSets...)
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.childPSSets...)
+		return setInfos, nil
+	case peer.nodes != nil:
+		return nil, ErrUnsupportedNodesPeer
+	case len(peer.networks) > 0:
+		networksSet, err := networksIPSet(npmNetPol, policyName, direction, ruleIndex, peerIndex, peer.networks)
+		if err != nil {
+			return nil, err
+		}
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, networksSet)
+		setInfo := policies.NewSetInfo(networksSet.Metadata.Name, ipsets.CIDRBlocks, included, matchType)
+		return [][]policies.SetInfo{{setInfo}}, nil
+	default:
+		// the API server requires exactly one field, so there is nothing to match
+		return nil, nil
+	}
+}
+
+// adminPortACL applies a port of a rule to an ACL and updates the rule IPSets of npmNetPol. The protocol defaults to TCP.
+func adminPortACL(npmNetPol *policies.NPMNetworkPolicy, acl *policies.ACLPolicy, port *v1alpha1.AdminNetworkPolicyPort) {
+	protocol := func(protocol string) policies.Protocol {
+		if protocol == "" {
+			return policies.TCP
+		}
+		return policies.Protocol(protocol)
+	}
+
+	switch {
+	case port.PortNumber != nil:
+		acl.DstPorts = policies.Ports{Port: port.PortNumber.Port}
+		acl.Protocol = protocol(string(port.PortNumber.Protocol))
+	case port.PortRange != nil:
+		acl.DstPorts = policies.Ports{Port: port.PortRange.Start, EndPort: port.PortRange.End}
+		acl.Protocol = protocol(string(port.PortRange.Protocol))
+	case port.NamedPort != nil:
+		// the named port IPSet has the protocol of each container port
+		npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, ipsets.NewTranslatedIPSet(*port.NamedPort, ipsets.NamedPorts))
+		acl.AddSetInfo([]policies.SetInfo{policies.NewSetInfo(*port.NamedPort, ipsets.NamedPorts, included, policies.DstDstMatch)})
+	}
+}
+
+// adminRule translates an ingress or egress rule to ACLs for each peer and port, in order.
+// Unlike NetworkPolicies, traffic that no rule matches isn't dropped, so there is no default drop ACL.
+func adminRule(npmNetPol *policies.NPMNetworkPolicy, policyName string, action v1alpha1.AdminNetworkPolicyRuleAction, direction policies.Direction, ruleIndex int,
+	peers []*adminPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) error { //nolint // gofumpt
+	verdict, err := adminVerdict(action)
+	if err != nil {
+		return err
+	}
+
+	matchType := policies.SrcMatch
+	if direction == policies.Egress {
+		matchType = policies.DstMatch
+	}
+
+	for peerIndex, peer := range peers {
+		setInfos, err := adminPeerSetInfos(npmNetPol, policyName, direction, matchType, ruleIndex, peerIndex, peer)
+		if err != nil {
+			return err
+		}
+
+		for _, setInfo := range setInfos {
+			if ports == nil || len(*ports) == 0 {
+				acl := policies.NewACLPolicy(verdict, direction)
+				acl.AddSetInfo(setInfo)
+				npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+				continue
+			}
+
+			for i := range *ports {
+				acl := policies.NewACLPolicy(verdict, direction)
+				acl.AddSetInfo(setInfo)
+				adminPortACL(npmNetPol, acl, &(*ports)[i])
+				npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+			}
+		}
+	}
+	return nil
+}
+
+func ingressAdminPeers(from []v1alpha1.AdminNetworkPolicyIngressPeer) []*adminPeer {
+	peers := make([]*adminPeer, 0, len(from))
+	for i := range from {
+		peers = append(peers, &adminPeer{namespaces: from[i].Namespaces, pods: from[i].Pods})
+	}
+	return peers
+}
+
+func egressAdminPeers(to []v1alpha1.AdminNetworkPolicyEgressPeer) []*adminPeer {
+	peers := make([]*adminPeer, 0, len(to))
+	for i := range to {
+		peers = append(peers, &adminPeer{namespaces: to[i].Namespaces, pods: to[i].Pods, nodes: to[i].Nodes, networks: to[i].Networks})
+	}
+	return peers
+}
+
+// TranslateAdminNetworkPolicy translates an AdminNetworkPolicy object to an NPMNetworkPolicy object in the AdminTier.
+func TranslateAdminNetworkPolicy(anpObj *v1alpha1.AdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() {
+		return nil, ErrUnsupportedAdminNetworkPolicy
+	}
+
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(policies.AdminTier, anpObj.Name, anpObj.Spec.Priority)
+	if err := adminSubject(npmNetPol, &anpObj.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for i := range anpObj.Spec.Ingress {
+		rule := &anpObj.Spec.Ingress[i]
+		if err := adminRule(npmNetPol, anpObj.Name, rule.Action, policies.Ingress, i, ingressAdminPeers(rule.From), rule.Ports); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range anpObj.Spec.Egress {
+		rule := &anpObj.Spec.Egress[i]
+		if err := adminRule(npmNetPol, anpObj.Name, rule.Action, policies.Egress, i, egressAdminPeers(rule.To), rule.Ports); err != nil {
+			return nil, err
+		}
+	}
+	return npmNetPol, nil
+}
+
+// TranslateBaselineAdminNetworkPolicy translates a BaselineAdminNetworkPolicy object to an NPMNetworkPolicy object in the BaselineTier.
+func TranslateBaselineAdminNetworkPolicy(banpObj *v1alpha1.BaselineAdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() {
+		return nil, ErrUnsupportedAdminNetworkPolicy
+	}
+
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(policies.BaselineTier, banpObj.Name, 0)
+	if err := adminSubject(npmNetPol, &banpObj.Spec.Subject); err != nil {
+		return nil, err
+	}
+
+	for i := range banpObj.Spec.Ingress {
+		rule := &banpObj.Spec.Ingress[i]
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		if err := adminRule(npmNetPol, banpObj.Name, action, policies.Ingress, i, ingressAdminPeers(rule.From), rule.Ports); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range banpObj.Spec.Egress {
+		rule := &banpObj.Spec.Egress[i]
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		if err := adminRule(npmNetPol, banpObj.Name, action, policies.Egress, i, egressAdminPeers(rule.To), rule.Ports); err != nil {
+			return nil, err
+		}
+	}
+	return npmNetPol, nil
+}
@@ -0,0 +1,294 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/apis/adminnetworkpolicy/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTranslateAdminNetworkPolicy(t *testing.T) {
+	tenantSelector := metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"tenant": "a",
+		},
+	}
+	appSelector := metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app": "db",
+		},
+	}
+	namedPort := "metrics"
+
+	tests := []struct {
+		name      string
+		anp       *v1alpha1.AdminNetworkPolicy
+		npmNetPol *policies.NPMNetworkPolicy
+		wantErr   error
+	}{
+		{
+			name: "namespaces subject with deny, pass, and allow rules in order",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 5,
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &tenantSelector,
+					},
+					Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+						{
+							Name:   "deny-all",
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+								{Namespaces: &metav1.LabelSelector{}},
+							},
+							Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+								{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolUDP, Port: 53}},
+								{PortRange: &v1alpha1.PortRange{Start: 8080, End: 8090}},
+							},
+						},
+						{
+							Name:   "pass-db",
+							Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+							From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+								{Pods: &v1alpha1.NamespacedPod{NamespaceSelector: tenantSelector, PodSelector: appSelector}},
+							},
+							Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+								{NamedPort: &namedPort},
+							},
+						},
+					},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Name:   "allow-internet",
+							Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+							To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+								{Networks: []v1alpha1.CIDR{"0.0.0.0/0", "fd00::/8"}},
+							},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey:   "AdminNetworkPolicy/tenant-a",
+				ACLPolicyID: "azure-acl-AdminNetworkPolicy-tenant-a",
+				Tier:        policies.AdminTier,
+				Priority:    5,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("tenant:a", ipsets.KeyValueLabelOfNamespace),
+				},
+				ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("tenant:a", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+					ipsets.NewTranslatedIPSet("app:db", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("tenant:a", ipsets.KeyValueLabelOfNamespace),
+					ipsets.NewTranslatedIPSet(namedPort, ipsets.NamedPorts),
+					ipsets.NewTranslatedIPSet("tenant-a-in-ns-AdminNetworkPolicy-0-0OUT", ipsets.CIDRBlocks, "0.0.0.0/1", "128.0.0.0/1", "fd00::/8"),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Dropped,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+						},
+						DstPorts: policies.Ports{Port: 53},
+						Protocol: policies.UDP,
+					},
+					{
+						Target:    policies.Dropped,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+						},
+						DstPorts: policies.Ports{Port: 8080, EndPort: 8090},
+						Protocol: policies.TCP,
+					},
+					{
+						Target:    policies.Passed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("tenant:a", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+							policies.NewSetInfo("app:db", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+						},
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo(namedPort, ipsets.NamedPorts, included, policies.DstDstMatch),
+						},
+					},
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Egress,
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo("tenant-a-in-ns-AdminNetworkPolicy-0-0OUT", ipsets.CIDRBlocks, included, policies.DstMatch),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "pods subject",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "db"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 10,
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Pods: &v1alpha1.NamespacedPod{NamespaceSelector: tenantSelector, PodSelector: appSelector},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey:   "AdminNetworkPolicy/db",
+				ACLPolicyID: "azure-acl-AdminNetworkPolicy-db",
+				Tier:        policies.AdminTier,
+				Priority:    10,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("app:db", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("tenant:a", ipsets.KeyValueLabelOfNamespace),
+				},
+				ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("tenant:a", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+					policies.NewSetInfo("app:db", ipsets.KeyValueLabelOfPod, included, policies.EitherMatch),
+				},
+			},
+		},
+		{
+			name: "subject namespace selector with multiple values",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "multiple-values"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{
+								{Key: "tenant", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedSubjectNamespaceSelector,
+		},
+		{
+			name: "nodes peer",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "nodes"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &tenantSelector,
+					},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+								{Nodes: &metav1.LabelSelector{}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedNodesPeer,
+		},
+		{
+			name: "invalid network",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-network"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &tenantSelector,
+					},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+								{Networks: []v1alpha1.CIDR{"not-a-cidr"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedIPAddress,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			npmNetPol, err := TranslateAdminNetworkPolicy(tt.anp)
+			if util.IsWindowsDP() {
+				require.ErrorIs(t, err, ErrUnsupportedAdminNetworkPolicy)
+				return
+			}
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.npmNetPol, npmNetPol)
+			policies.NormalizePolicy(npmNetPol)
+			require.NoError(t, policies.ValidatePolicy(npmNetPol))
+		})
+	}
+}
+
+func TestTranslateBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{},
+			},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				{
+					Name:   "default-deny",
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+						{Namespaces: &metav1.LabelSelector{}},
+					},
+				},
+			},
+		},
+	}
+
+	npmNetPol, err := TranslateBaselineAdminNetworkPolicy(banp)
+	if util.IsWindowsDP() {
+		require.ErrorIs(t, err, ErrUnsupportedAdminNetworkPolicy)
+		return
+	}
+	require.NoError(t, err)
+
+	expected := &policies.NPMNetworkPolicy{
+		PolicyKey:   "BaselineAdminNetworkPolicy/default",
+		ACLPolicyID: "azure-acl-BaselineAdminNetworkPolicy-default",
+		Tier:        policies.BaselineTier,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+		PodSelectorList: []policies.SetInfo{
+			policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+		},
+		RuleIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ACLs: []*policies.ACLPolicy{
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Ingress,
+				SrcList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, npmNetPol)
+	policies.NormalizePolicy(npmNetPol)
+	require.NoError(t, policies.ValidatePolicy(npmNetPol))
+}
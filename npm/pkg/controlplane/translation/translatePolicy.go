@@ -32,8 +32,14 @@ var (
 	ErrInvalidMatchExpressionValues = errors.New(
 		"matchExpression label values must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character",
 	)
-	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6, is used
+	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6 on windows, is used
 	ErrUnsupportedIPAddress = errors.New("unsupported IP address")
+
+	// splitAllCIDRs are the halves of the CIDRs matching every address, which ipset doesn't allow to be added.
+	splitAllCIDRs = map[string][]string{
+		"0.0.0.0/0": {"0.0.0.0/1", "128.0.0.0/1"},
+		"::/0":      {"::/1", "8000::/1"},
+	}
 )
 
 type podSelectorResult struct {
@@ -183,13 +189,13 @@ func ipBlockIPSet(policyName, ns string, direction policies.Direction, ipBlockSe
 	// A solution is split 0.0.0.0/0 in half which convert to 0.0.0.0/1 and 128.0.0.0/1.
 	// splitCIDRSet is used to handle case where IPBlock has "0.0.0.0/0" in CIDR and "0.0.0.0/1" or "128.0.0.0/1"  in Except.
 	// splitCIDRSet has two entries ("0.0.0.0/1" and "128.0.0.0/1") as key.
+	// The same goes for ::/0, which is split into ::/1 and 8000::/1.
 	splitCIDRLen := 2
 	splitCIDRSet := make(map[string]int, splitCIDRLen)
-	if ipBlockRule.CIDR == "0.0.0.0/0" {
-		// two cidrs (0.0.0.0/1 and 128.0.0.0/1) for 0.0.0.0/0 + except.
+	if splitCIDRs, ok := splitAllCIDRs[ipBlockRule.CIDR]; ok {
+		// two cidrs (e.g. 0.0.0.0/1 and 128.0.0.0/1 for 0.0.0.0/0) + except.
 		members = make([]string, lenOfDeDupExcepts+splitCIDRLen)
 		// in case of "0.0.0.0/0", "0.0.0.0/1" or "0.0.0.0/1 nomatch" comes eariler than "128.0.0.0/1" or "128.0.0.0/1 nomatch".
-		splitCIDRs := []string{"0.0.0.0/1", "128.0.0.0/1"}
 		for _, cidr := range splitCIDRs {
 			members[indexOfMembers] = cidr
 			splitCIDRSet[cidr] = indexOfMembers
@@ -227,7 +233,8 @@ func ipBlockRule(policyName, ns string, direction policies.Direction, matchType
 		return nil, policies.SetInfo{}, nil
 	}
 
-	if !util.IsIPV4(ipBlockRule.CIDR) {
+	// IPv6 ipBlocks are only supported in Linux, where the dataplane pairs each CIDR set with an inet6 set
+	if !util.IsIPV4(ipBlockRule.CIDR) && (util.IsWindowsDP() || !util.IsIPV6(ipBlockRule.CIDR)) {
 		return nil, policies.SetInfo{}, ErrUnsupportedIPAddress
 	}
 
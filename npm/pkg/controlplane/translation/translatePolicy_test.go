@@ -466,6 +466,34 @@ func TestIPBlockIPSet(t *testing.T) {
 			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"0.0.0.0/1 nomatch", "128.0.0.0/1 nomatch"}...),
 			skipWindows:     true,
 		},
+		{
+			name:        "ipv6 cidr and except",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR:   "fd00::/64",
+				Except: []string{"fd00::/80"},
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"fd00::/64", "fd00::/80 nomatch"}...),
+			skipWindows:     true,
+		},
+		{
+			name:        "cidr: ::/0",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR: "::/0",
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"::/1", "8000::/1"}...),
+		},
+		{
+			name:        "cidr: ::/0 and except: 8000::/1 and fd00::/8",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR:   "::/0",
+				Except: []string{"8000::/1", "fd00::/8"},
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"::/1", "8000::/1 nomatch", "fd00::/8 nomatch"}...),
+			skipWindows:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -541,11 +569,32 @@ func TestIPBlockRule(t *testing.T) {
 			setInfo:         policies.NewSetInfo("test-network-policy-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
 			skipWindows:     true,
 		},
+		{
+			name:        "ipv6",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR:   "2002::1234:abcd:ffff:c0a8:101/64",
+				Except: []string{"2002::1234:abcd:ffff:c0a8:101/128"},
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"2002::1234:abcd:ffff:c0a8:101/64", "2002::1234:abcd:ffff:c0a8:101/128 nomatch"}...),
+			setInfo:         policies.NewSetInfo("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
+			skipWindows:     true,
+		},
 		{
 			name:        "invalid ipv6",
 			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
 			ipBlockRule: &networkingv1.IPBlock{
-				CIDR: "2002::1234:abcd:ffff:c0a8:101/64",
+				CIDR: "2002::1234:abcd:ffff:c0a8:101/129",
+			},
+			translatedIPSet: nil,
+			setInfo:         policies.SetInfo{},
+			wantErr:         true,
+		},
+		{
+			name:        "ipv4-mapped ipv6",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR: "::ffff:10.0.0.0/104",
 			},
 			translatedIPSet: nil,
 			setInfo:         policies.SetInfo{},
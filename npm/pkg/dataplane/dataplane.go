@@ -43,6 +43,13 @@ type Config struct {
 	NetPolInBackground bool
 	MaxPendingNetPols  int
 	NetPolInterval     time.Duration
+	// EnableIPv6 enforces policies for IPv6 and dual-stack Pods. It is only supported in Linux.
+	EnableIPv6 bool
+	// DriftInterval is how often the ipsets and iptables chains in the kernel are verified in Linux.
+	// The kernel isn't verified if it is zero.
+	DriftInterval time.Duration
+	// RepairDrift reprograms the ipsets and chains which drifted from what NPM programmed
+	RepairDrift bool
 	*ipsets.IPSetManagerCfg
 	*policies.PolicyManagerCfg
 }
@@ -71,7 +78,13 @@ func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChann
 	if util.IsWindowsDP() {
 		klog.Infof("[DataPlane] enabling AddEmptySetToLists for Windows")
 		cfg.IPSetManagerCfg.AddEmptySetToLists = true
+		if cfg.EnableIPv6 {
+			klog.Infof("[DataPlane] disabling IPv6 since it is not supported in Windows")
+			cfg.EnableIPv6 = false
+		}
 	}
+	cfg.IPSetManagerCfg.EnableIPv6 = cfg.EnableIPv6
+	cfg.PolicyManagerCfg.EnableIPv6 = cfg.EnableIPv6
 
 	dp := &DataPlane{
 		Config:    cfg,
@@ -166,6 +179,22 @@ func (dp *DataPlane) RunPeriodicTasks() {
 		}
 	}()
 
+	if dp.DriftInterval > 0 && !util.IsWindowsDP() {
+		go func() {
+			ticker := time.NewTicker(dp.DriftInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-dp.stopChannel:
+					return
+				case <-ticker.C:
+					dp.verifyDrift()
+				}
+			}
+		}()
+	}
+
 	if dp.netPolInBackground {
 		go func() {
 			ticker := time.NewTicker(dp.NetPolInterval)
@@ -221,6 +250,20 @@ func (dp *DataPlane) RunPeriodicTasks() {
 	}()
 }
 
+// verifyDrift verifies the ipsets before the chains which reference them, and repairs both if configured to.
+// Drift is reported through metrics.
+func (dp *DataPlane) verifyDrift() {
+	// locks ipset manager
+	if _, err := dp.ipsetMgr.VerifyIPSets(dp.RepairDrift); err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to verify ipsets in the kernel. err: %s", err.Error())
+	}
+
+	// locks policy manager
+	if _, err := dp.policyMgr.VerifyChains(dp.RepairDrift); err != nil {
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "[DataPlane] failed to verify iptables chains in the kernel. err: %s", err.Error())
+	}
+}
+
 func (dp *DataPlane) GetIPSet(setName string) *ipsets.IPSet {
 	return dp.ipsetMgr.GetIPSet(setName)
 }
@@ -636,6 +679,11 @@ func (dp *DataPlane) GetAllIPSets() map[string]string {
 	return dp.ipsetMgr.GetAllIPSets()
 }
 
+// AuditedPolicy returns the applied policy for the tag of an NFLOG prefix. It's only used in Linux.
+func (dp *DataPlane) AuditedPolicy(tag string) (policies.AuditedPolicy, bool) {
+	return dp.policyMgr.AuditedPolicy(tag)
+}
+
 // GetAllPolicies is deprecated and only used in the goalstateprocessor, which is deprecated
 func (dp *DataPlane) GetAllPolicies() []string {
 	return nil
@@ -663,6 +711,9 @@ func (dp *DataPlane) createIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 			// ipblock can have either cidr (CIDR in IPBlock) or "cidr + " " (space) + nomatch" (Except in IPBlock)
 			// (TODO) need to revise it for windows
 			for _, ipblock := range set.Members {
+				if dp.skipIPBlock(ipblock) {
+					continue
+				}
 				err := dp.ipsetMgr.AddToSets([]*ipsets.IPSetMetadata{set.Metadata}, ipblock, "")
 				if err != nil {
 					return npmerrors.Errorf(npmErrorString, false, fmt.Sprintf("[DataPlane] failed to AddToSet in addIPSetReferences with err: %s", err.Error()))
@@ -681,6 +732,16 @@ func (dp *DataPlane) createIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 	return nil
 }
 
+// skipIPBlock returns whether the ipblock is IPv6 while IPv6 is disabled.
+// These are skipped instead of failing the policy, since no Pod can have an IPv6 peer then.
+func (dp *DataPlane) skipIPBlock(ipblock string) bool {
+	if dp.EnableIPv6 || !strings.Contains(ipblock, ":") {
+		return false
+	}
+	klog.Infof("[DataPlane] skipping IPv6 ipblock %s since IPv6 is disabled", ipblock)
+	return true
+}
+
 func (dp *DataPlane) deleteIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, netpolName string, referenceType ipsets.ReferenceType) error {
 	for _, set := range sets {
 		prefixName := set.Metadata.GetPrefixName()
@@ -705,6 +766,9 @@ func (dp *DataPlane) deleteIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 			// ipblock can have either cidr (CIDR in IPBlock) or "cidr + " " (space) + nomatch" (Except in IPBlock)
 			// (TODO) need to revise it for windows
 			for _, ipblock := range set.Members {
+				if dp.skipIPBlock(ipblock) {
+					continue
+				}
 				err := dp.ipsetMgr.RemoveFromSets([]*ipsets.IPSetMetadata{set.Metadata}, ipblock, "")
 				if err != nil {
 					return npmerrors.Errorf(npmErrorString, false, fmt.Sprintf("[DataPlane] failed to RemoveFromSet in deleteIPSetReferences with err: %s", err.Error()))
@@ -175,3 +175,39 @@ func TestNetPolInBackgroundFailureToAddFirstTime(t *testing.T) {
 
 	require.Equal(t, 1, dp.netPolQueue.len(), "expected one netpol to still be in the queue after it fails when adding one at a time")
 }
+
+func TestVerifyDrift(t *testing.T) {
+	metrics.ReinitializeAll()
+
+	cfg := *netpolInBackgroundCfg
+	cfg.DriftInterval = time.Minute
+	cfg.RepairDrift = true
+
+	calls := getBootupTestCalls()
+	calls = append(calls,
+		// no ipsets are expected in the kernel
+		testutils.TestCmd{Cmd: []string{"ipset", "save"}, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "azure-npm-"}, ExitCode: 1},
+		// someone ran iptables -X after bootup
+		testutils.TestCmd{Cmd: []string{"iptables-save", "-t", "filter"}, Stdout: "*filter\n:FORWARD ACCEPT [0:0]\nCOMMIT\n"},
+		testutils.TestCmd{Cmd: []string{"iptables-restore", "-w", "60", "-T", "filter", "--noflush"}},
+	)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	dp, err := NewDataPlane("testnode", ioshim, &cfg, nil)
+	require.NoError(t, err)
+
+	dp.verifyDrift()
+
+	numDrifted, err := metrics.GetKernelDrift(metrics.IPSetDrift)
+	require.NoError(t, err)
+	require.Equal(t, 0, numDrifted)
+
+	numDrifted, err = metrics.GetKernelDrift(metrics.ChainDrift)
+	require.NoError(t, err)
+	require.Equal(t, 9, numDrifted, "expected all base chains to drift")
+
+	numRepairs, err := metrics.TotalDriftRepairs(metrics.ChainDrift, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, numRepairs)
+}
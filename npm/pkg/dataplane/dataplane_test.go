@@ -85,6 +85,18 @@ func TestNewDataPlane(t *testing.T) {
 	assert.NotNil(t, dp)
 }
 
+func TestSkipIPBlock(t *testing.T) {
+	dp := &DataPlane{Config: &Config{}}
+	require.False(t, dp.skipIPBlock("10.0.0.0/8"))
+	require.False(t, dp.skipIPBlock("10.0.0.0/8 nomatch"))
+	require.True(t, dp.skipIPBlock("2001:db8::/32"))
+	require.True(t, dp.skipIPBlock("2001:db8::/48 nomatch"))
+
+	dp.EnableIPv6 = true
+	require.False(t, dp.skipIPBlock("10.0.0.0/8"))
+	require.False(t, dp.skipIPBlock("2001:db8::/32"))
+}
+
 func TestCreateAndDeleteIpSets(t *testing.T) {
 	metrics.InitializeAll()
 
@@ -37,4 +37,6 @@ const (
 	// stored file with json compatible form (i.e., can call json.Unmarshal)
 	npmCacheFileV1 = "../testdata/npmcachev1.json"
 	npmCacheFileV2 = "../testdata/npmcachev2.json"
+	// stored file with a namespace, a pod and a network policy
+	planManifestsFile = "../testdata/plan-manifests.yaml"
 )
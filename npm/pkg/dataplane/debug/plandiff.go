@@ -0,0 +1,402 @@
+package debug
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+const (
+	azureNPMChainPrefix = "AZURE-NPM"
+
+	// ipset restore flags, as written by the ipset manager
+	ipsetCreateFlag  = "-N"
+	ipsetFlushFlag   = "-F"
+	ipsetAddFlag     = "-A"
+	ipsetDeleteFlag  = "-D"
+	ipsetDestroyFlag = "-X"
+	ipsetFamilyFlag  = "family"
+)
+
+// FilterTable returns the IPv4 filter table after running the plan's iptables commands and iptables-restore payloads on an empty table.
+// The rules are written like iptables-save would, so the table can be compared with one read from a node.
+func (p *Plan) FilterTable() *NPMIPtable.Table {
+	return p.filterTable(util.IptablesLegacy)
+}
+
+// IPv6FilterTable returns the IPv6 filter table after running the plan's ip6tables commands and ip6tables-restore payloads on an
+// empty table, written like ip6tables-save would. It only has chains when the plan is for a dual-stack dataplane.
+func (p *Plan) IPv6FilterTable() *NPMIPtable.Table {
+	return p.filterTable(util.Ip6tablesLegacy)
+}
+
+func (p *Plan) commands() []*PlannedCommand {
+	commands := make([]*PlannedCommand, 0, len(p.BootupCommands)+len(p.Commands))
+	commands = append(commands, p.BootupCommands...)
+	return append(commands, p.Commands...)
+}
+
+// filterTable runs the commands of the iptables binary, e.g. iptables or ip6tables, in their legacy or nft variants
+func (p *Plan) filterTable(iptables string) *NPMIPtable.Table {
+	chains := make(map[string][]string)
+	for _, command := range p.commands() {
+		name := filepath.Base(command.Name)
+		if !strings.HasPrefix(name, iptables) || strings.HasSuffix(name, "-save") {
+			continue
+		}
+		if strings.HasSuffix(name, "-restore") {
+			for _, line := range strings.Split(command.Stdin, "\n") {
+				applyIptablesLine(chains, strings.Fields(line))
+			}
+			continue
+		}
+		applyIptablesLine(chains, iptablesCommandFields(command.Args))
+	}
+
+	chainNames := make([]string, 0, len(chains))
+	for chain := range chains {
+		chainNames = append(chainNames, chain)
+	}
+	sort.Strings(chainNames)
+
+	var iptablesSave strings.Builder
+	iptablesSave.WriteString("*" + util.IptablesFilterTable + "\n")
+	for _, chain := range chainNames {
+		iptablesSave.WriteString(fmt.Sprintf(":%s - [0:0]\n", chain))
+	}
+	for _, chain := range chainNames {
+		for _, spec := range chains[chain] {
+			iptablesSave.WriteString(fmt.Sprintf("-A %s %s\n", chain, parse.SaveSpec(spec)))
+		}
+	}
+	iptablesSave.WriteString("COMMIT\n")
+	return parse.IptablesBytes(util.IptablesFilterTable, []byte(iptablesSave.String()))
+}
+
+// iptablesCommandFields drops the wait and table flags of an iptables command
+func iptablesCommandFields(args []string) []string {
+	fields := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case util.IptablesWaitFlag, util.IptablesTableFlag:
+			i++
+		default:
+			fields = append(fields, args[i])
+		}
+	}
+	return fields
+}
+
+// applyIptablesLine applies an iptables-restore line or iptables command to the chains.
+// Like iptables-restore, declaring an existing chain flushes it.
+func applyIptablesLine(chains map[string][]string, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	if strings.HasPrefix(fields[0], ":") {
+		chains[fields[0][1:]] = []string{}
+		return
+	}
+	if len(fields) < 2 {
+		if fields[0] == util.IptablesFlushFlag {
+			for chain := range chains {
+				chains[chain] = []string{}
+			}
+		}
+		return
+	}
+
+	chain := fields[1]
+	switch fields[0] {
+	case util.IptablesChainCreationFlag:
+		if _, ok := chains[chain]; !ok {
+			chains[chain] = []string{}
+		}
+	case util.IptablesFlushFlag:
+		chains[chain] = []string{}
+	case util.IptablesDestroyFlag:
+		delete(chains, chain)
+	case util.IptablesAppendFlag:
+		chains[chain] = append(chains[chain], strings.Join(fields[2:], " "))
+	case util.IptablesInsertionFlag:
+		position := 1
+		specFields := fields[2:]
+		if len(specFields) > 0 {
+			if n, err := strconv.Atoi(specFields[0]); err == nil {
+				position = n
+				specFields = specFields[1:]
+			}
+		}
+		rules := chains[chain]
+		if position < 1 || position > len(rules)+1 {
+			return
+		}
+		rules = append(rules[:position-1], append([]string{strings.Join(specFields, " ")}, rules[position-1:]...)...)
+		chains[chain] = rules
+	case util.IptablesDeletionFlag:
+		rules := chains[chain]
+		spec := strings.Join(fields[2:], " ")
+		if n, err := strconv.Atoi(spec); err == nil {
+			if n >= 1 && n <= len(rules) {
+				chains[chain] = append(rules[:n-1], rules[n:]...)
+			}
+			return
+		}
+		for i, rule := range rules {
+			if rule == spec {
+				chains[chain] = append(rules[:i], rules[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// IPSets returns the NPM ipsets after running the plan's ipset restore payloads with no sets,
+// with their members written like ipset save would.
+func (p *Plan) IPSets() map[string]*parse.IPSet {
+	sets := make(map[string]*parse.IPSet)
+	for _, command := range p.commands() {
+		if filepath.Base(command.Name) != util.Ipset || len(command.Args) == 0 || command.Args[0] != util.IpsetRestoreFlag {
+			continue
+		}
+		for _, line := range strings.Split(command.Stdin, "\n") {
+			applyIPSetLine(sets, strings.Fields(line))
+		}
+	}
+	return sets
+}
+
+// applyIPSetLine applies an ipset restore line to the sets.
+func applyIPSetLine(sets map[string]*parse.IPSet, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	if len(fields) < 2 {
+		switch fields[0] {
+		case ipsetFlushFlag:
+			for _, set := range sets {
+				set.Members = []string{}
+			}
+		case ipsetDestroyFlag:
+			for name := range sets {
+				delete(sets, name)
+			}
+		}
+		return
+	}
+
+	name := fields[1]
+	set, ok := sets[name]
+	switch fields[0] {
+	case ipsetCreateFlag:
+		if ok {
+			return
+		}
+		set = &parse.IPSet{Name: name, Members: []string{}}
+		for i := 2; i < len(fields); i++ {
+			switch {
+			case fields[i] == ipsetFamilyFlag && i+1 < len(fields):
+				set.Family = fields[i+1]
+				i++
+			case set.Type == "" && !strings.HasPrefix(fields[i], "-"):
+				set.Type = fields[i]
+			}
+		}
+		sets[name] = set
+	case ipsetFlushFlag:
+		if ok {
+			set.Members = []string{}
+		}
+	case ipsetDestroyFlag:
+		delete(sets, name)
+	case ipsetAddFlag:
+		member := ipsetSaveMember(fields[2:])
+		if ok && !contains(set.Members, member) {
+			set.Members = append(set.Members, member)
+		}
+	case ipsetDeleteFlag:
+		if !ok {
+			return
+		}
+		member := ipsetSaveMember(fields[2:])
+		for i, m := range set.Members {
+			if m == member {
+				set.Members = append(set.Members[:i], set.Members[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ipsetSaveMember writes a member like ipset save would, without the prefix length of a single address
+func ipsetSaveMember(fields []string) string {
+	if len(fields) > 0 {
+		fields[0] = strings.TrimSuffix(strings.TrimSuffix(fields[0], "/32"), "/128")
+	}
+	return strings.Join(fields, " ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffIPSets compares the members of the NPM ipsets in two sets of ipsets.
+// Sets and members only in the live sets are prefixed with "-", and ones only in the planned sets are prefixed with "+".
+func DiffIPSets(live, planned map[string]*parse.IPSet) []string {
+	names := make([]string, 0, len(live)+len(planned))
+	for name := range live {
+		if strings.HasPrefix(name, util.AzureNpmPrefix) {
+			names = append(names, name)
+		}
+	}
+	for name := range planned {
+		if _, ok := live[name]; !ok && strings.HasPrefix(name, util.AzureNpmPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := make([]string, 0)
+	for _, name := range names {
+		liveSet, inLive := live[name]
+		plannedSet, inPlan := planned[name]
+		var liveMembers, plannedMembers []string
+		switch {
+		case !inPlan:
+			diff = append(diff, "- create "+name)
+			liveMembers = liveSet.Members
+		case !inLive:
+			diff = append(diff, "+ create "+name)
+			plannedMembers = plannedSet.Members
+		default:
+			liveMembers = liveSet.Members
+			plannedMembers = plannedSet.Members
+		}
+		// the order of members doesn't matter
+		liveMembers = sortedCopy(liveMembers)
+		plannedMembers = sortedCopy(plannedMembers)
+		for _, line := range diffLines(liveMembers, plannedMembers) {
+			diff = append(diff, fmt.Sprintf("%s add %s %s", line[:1], name, line[2:]))
+		}
+	}
+	return diff
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// DiffIptables compares the rules of the AZURE-NPM chains in two tables.
+// Chains and rules only in the live table are prefixed with "-", and ones only in the planned table are prefixed with "+".
+func DiffIptables(live, planned *NPMIPtable.Table) []string {
+	liveChains := npmChainRules(live)
+	plannedChains := npmChainRules(planned)
+
+	chainNames := make([]string, 0, len(liveChains)+len(plannedChains))
+	for chain := range liveChains {
+		chainNames = append(chainNames, chain)
+	}
+	for chain := range plannedChains {
+		if _, ok := liveChains[chain]; !ok {
+			chainNames = append(chainNames, chain)
+		}
+	}
+	sort.Strings(chainNames)
+
+	diff := make([]string, 0)
+	for _, chain := range chainNames {
+		liveRules, inLive := liveChains[chain]
+		plannedRules, inPlan := plannedChains[chain]
+		switch {
+		case !inPlan:
+			diff = append(diff, "- :"+chain)
+		case !inLive:
+			diff = append(diff, "+ :"+chain)
+		}
+		for _, line := range diffLines(liveRules, plannedRules) {
+			diff = append(diff, fmt.Sprintf("%s -A %s %s", line[:1], chain, line[2:]))
+		}
+	}
+	return diff
+}
+
+// PrettyPrintDiff prints the diff of DiffIptables or DiffIPSets under a header naming what was compared.
+func PrettyPrintDiff(header string, diff []string) {
+	fmt.Println("# " + header)
+	if len(diff) == 0 {
+		fmt.Println("# no difference")
+		return
+	}
+	for _, line := range diff {
+		fmt.Println(line)
+	}
+}
+
+func npmChainRules(table *NPMIPtable.Table) map[string][]string {
+	chains := make(map[string][]string)
+	if table == nil {
+		return chains
+	}
+	for name, chain := range table.Chains {
+		if !strings.HasPrefix(name, azureNPMChainPrefix) {
+			continue
+		}
+		rules := make([]string, 0, len(chain.Rules))
+		for _, rule := range chain.Rules {
+			rules = append(rules, parse.RuleString(rule))
+		}
+		chains[name] = rules
+	}
+	return chains
+}
+
+// diffLines returns the lines only in a prefixed with "- " and the lines only in b prefixed with "+ ",
+// in the order of a longest common subsequence.
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]string, 0)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	return diff
+}
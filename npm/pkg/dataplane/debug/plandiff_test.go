@@ -0,0 +1,199 @@
+package debug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterTable(t *testing.T) {
+	plan := &Plan{
+		BootupCommands: []*PlannedCommand{
+			{
+				Name: "iptables-nft-restore",
+				Args: []string{"-w", "60", "-T", "filter", "--noflush"},
+				Stdin: strings.Join([]string{
+					"*filter",
+					":AZURE-NPM - -",
+					":AZURE-NPM-INGRESS - -",
+					"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+					"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400",
+					"COMMIT",
+					"",
+				}, "\n"),
+			},
+			{Name: "iptables-nft", Args: []string{"-w", "60", "-I", "FORWARD", "-j", "AZURE-NPM"}},
+		},
+		Commands: []*PlannedCommand{
+			{
+				Name: "iptables-nft-restore",
+				Args: []string{"-w", "60", "-T", "filter", "--noflush"},
+				Stdin: strings.Join([]string{
+					"*filter",
+					":AZURE-NPM-INGRESS-123 - -",
+					"-A AZURE-NPM-INGRESS-123 -j MARK --set-mark 0x400/0x400 -p TCP --dport 80",
+					"-I AZURE-NPM-INGRESS 1 -j AZURE-NPM-INGRESS-123 -m set --match-set azure-npm-1 dst",
+					"COMMIT",
+					"",
+				}, "\n"),
+			},
+			// ip6tables isn't in the IPv4 table
+			{Name: "ip6tables-nft", Args: []string{"-w", "60", "-F", "AZURE-NPM"}},
+			{Name: "iptables-nft", Args: []string{"-w", "60", "-D", "FORWARD", "-j", "AZURE-NPM"}},
+		},
+	}
+
+	table := plan.FilterTable()
+	require.Len(t, table.Chains, 4)
+	require.Len(t, table.Chains["FORWARD"].Rules, 0)
+	require.Len(t, table.Chains["AZURE-NPM"].Rules, 1)
+	require.Equal(t, []string{
+		"-m set --match-set azure-npm-1 dst -j AZURE-NPM-INGRESS-123",
+		"-m mark --mark 0x400/0x400 -j DROP",
+	}, npmChainRules(table)["AZURE-NPM-INGRESS"])
+	// the rule is written like iptables-save would
+	require.Equal(t, []string{
+		"-p tcp -m tcp --dport 80 -j MARK --set-xmark 0x400/0x400",
+	}, npmChainRules(table)["AZURE-NPM-INGRESS-123"])
+}
+
+func TestIPv6FilterTable(t *testing.T) {
+	plan := &Plan{
+		Commands: []*PlannedCommand{
+			{Name: "iptables-nft", Args: []string{"-w", "60", "-N", "AZURE-NPM"}},
+			{
+				Name: "ip6tables-nft-restore",
+				Args: []string{"-w", "60", "-T", "filter", "--noflush"},
+				Stdin: strings.Join([]string{
+					"*filter",
+					":AZURE-NPM - -",
+					":AZURE-NPM-INGRESS - -",
+					"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+					"COMMIT",
+					"",
+				}, "\n"),
+			},
+		},
+	}
+
+	table := plan.IPv6FilterTable()
+	require.Len(t, table.Chains, 2)
+	require.Equal(t, []string{"-j AZURE-NPM-INGRESS"}, npmChainRules(table)["AZURE-NPM"])
+	require.Len(t, plan.FilterTable().Chains, 1)
+}
+
+func TestPlanIPSets(t *testing.T) {
+	plan := &Plan{
+		BootupCommands: []*PlannedCommand{
+			{Name: "ipset", Args: []string{"list", "--name"}},
+		},
+		Commands: []*PlannedCommand{
+			{
+				Name: "ipset",
+				Args: []string{"restore"},
+				Stdin: strings.Join([]string{
+					"-N azure-npm-1 --exist nethash maxelem 4294967295",
+					"-N azure-npm-2 --exist nethash maxelem 4294967295 family inet6",
+					"-N azure-npm-3 --exist setlist",
+					"-N azure-npm-4 --exist nethash",
+					"-A azure-npm-1 10.0.0.1",
+					"-A azure-npm-1 10.0.0.2/32",
+					"-A azure-npm-1 10.0.0.0/28 nomatch",
+					"-A azure-npm-2 fd00::1/128",
+					"-A azure-npm-3 azure-npm-1",
+					"",
+				}, "\n"),
+			},
+			{
+				Name: "ipset",
+				Args: []string{"restore"},
+				Stdin: strings.Join([]string{
+					"-D azure-npm-1 10.0.0.1",
+					"-F azure-npm-4",
+					"-X azure-npm-4",
+					"",
+				}, "\n"),
+			},
+		},
+	}
+
+	require.Equal(t, map[string]*parse.IPSet{
+		"azure-npm-1": {Name: "azure-npm-1", Type: "nethash", Members: []string{"10.0.0.2", "10.0.0.0/28 nomatch"}},
+		"azure-npm-2": {Name: "azure-npm-2", Type: "nethash", Family: "inet6", Members: []string{"fd00::1"}},
+		"azure-npm-3": {Name: "azure-npm-3", Type: "setlist", Members: []string{"azure-npm-1"}},
+	}, plan.IPSets())
+}
+
+func TestDiffIPSets(t *testing.T) {
+	live := parse.IPSets([]byte(strings.Join([]string{
+		"create azure-npm-1 hash:net family inet hashsize 1024 maxelem 4294967295",
+		"add azure-npm-1 10.0.0.2",
+		"add azure-npm-1 10.0.0.1",
+		"create azure-npm-stale hash:net family inet hashsize 1024 maxelem 4294967295",
+		"add azure-npm-stale 10.0.0.3",
+		"create kube-other hash:net family inet hashsize 1024 maxelem 65536",
+		"",
+	}, "\n")))
+	planned := map[string]*parse.IPSet{
+		"azure-npm-1":   {Name: "azure-npm-1", Members: []string{"10.0.0.1", "10.0.0.4"}},
+		"azure-npm-new": {Name: "azure-npm-new", Members: []string{"fd00::1"}},
+	}
+
+	require.Equal(t, []string{
+		"- add azure-npm-1 10.0.0.2",
+		"+ add azure-npm-1 10.0.0.4",
+		"+ create azure-npm-new",
+		"+ add azure-npm-new fd00::1",
+		"- create azure-npm-stale",
+		"- add azure-npm-stale 10.0.0.3",
+	}, DiffIPSets(live, planned))
+	require.Empty(t, DiffIPSets(planned, planned))
+}
+
+func TestDiffIptables(t *testing.T) {
+	live := parse.IptablesBytes(util.IptablesFilterTable, []byte(strings.Join([]string{
+		"*filter",
+		":FORWARD ACCEPT [0:0]",
+		":AZURE-NPM - [0:0]",
+		":AZURE-NPM-STALE - [0:0]",
+		"-A FORWARD -j AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-STALE",
+		"-A AZURE-NPM -m comment --comment \"ACCEPT\" -j ACCEPT",
+		"COMMIT",
+		"",
+	}, "\n")))
+	planned := parse.IptablesBytes(util.IptablesFilterTable, []byte(strings.Join([]string{
+		"*filter",
+		":AZURE-NPM - [0:0]",
+		":AZURE-NPM-NEW - [0:0]",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-NEW",
+		"-A AZURE-NPM -m comment --comment ACCEPT -j ACCEPT",
+		"-A AZURE-NPM-NEW -j DROP",
+		"COMMIT",
+		"",
+	}, "\n")))
+
+	require.Equal(t, []string{
+		"- -A AZURE-NPM -j AZURE-NPM-STALE",
+		"+ -A AZURE-NPM -j AZURE-NPM-NEW",
+		"+ :AZURE-NPM-NEW",
+		"+ -A AZURE-NPM-NEW -j DROP",
+		"- :AZURE-NPM-STALE",
+	}, DiffIptables(live, planned))
+	require.Empty(t, DiffIptables(planned, planned))
+}
+
+func TestDiffLines(t *testing.T) {
+	require.Empty(t, diffLines(nil, nil))
+	require.Equal(t, []string{"+ a"}, diffLines(nil, []string{"a"}))
+	require.Equal(t, []string{"- a"}, diffLines([]string{"a"}, nil))
+	require.Equal(t,
+		[]string{"- b", "+ x", "+ e"},
+		diffLines([]string{"a", "b", "c", "d"}, []string{"a", "x", "c", "d", "e"}),
+	)
+}
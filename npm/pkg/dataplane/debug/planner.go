@@ -0,0 +1,366 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	npmcommon "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	utilexec "k8s.io/utils/exec"
+)
+
+var ErrUnsupportedObject = fmt.Errorf("unsupported object kind")
+
+// PlanObjects are the Kubernetes objects which a plan is made for.
+type PlanObjects struct {
+	Namespaces      []*corev1.Namespace
+	Pods            []*corev1.Pod
+	NetworkPolicies []*networkingv1.NetworkPolicy
+}
+
+// ObjectsFromCache returns the namespaces and pods in an NPM cache.
+func ObjectsFromCache(cache *npmcommon.Cache) *PlanObjects {
+	objects := &PlanObjects{}
+
+	nsNames := make([]string, 0, len(cache.NsMap))
+	for name := range cache.NsMap {
+		nsNames = append(nsNames, name)
+	}
+	sort.Strings(nsNames)
+	for _, name := range nsNames {
+		ns := cache.NsMap[name]
+		objects.Namespaces = append(objects.Namespaces, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: ns.Name, Labels: ns.LabelsMap},
+		})
+	}
+
+	podKeys := make([]string, 0, len(cache.PodMap))
+	for key := range cache.PodMap {
+		podKeys = append(podKeys, key)
+	}
+	sort.Strings(podKeys)
+	for _, key := range podKeys {
+		npmPod := cache.PodMap[key]
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: npmPod.Name, Namespace: npmPod.Namespace, Labels: npmPod.Labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Ports: npmPod.ContainerPorts}}},
+			Status:     corev1.PodStatus{Phase: npmPod.Phase, PodIP: npmPod.PodIP},
+		}
+		for _, ip := range npmPod.PodIPs {
+			pod.Status.PodIPs = append(pod.Status.PodIPs, corev1.PodIP{IP: ip})
+		}
+		objects.Pods = append(objects.Pods, pod)
+	}
+
+	return objects
+}
+
+// AddManifests adds the NetworkPolicies, Pods and Namespaces in YAML or JSON manifest files.
+// An object replaces an object of the same kind with the same namespace and name, e.g. one from an NPM cache.
+func (o *PlanObjects) AddManifests(manifestFiles ...string) error {
+	for _, manifestFile := range manifestFiles {
+		if err := o.addManifest(manifestFile); err != nil {
+			return fmt.Errorf("failed to add objects from %s : %w", manifestFile, err)
+		}
+	}
+	return nil
+}
+
+func (o *PlanObjects) addManifest(manifestFile string) error {
+	f, err := os.Open(manifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+		if err := o.addDocument(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *PlanObjects) addDocument(doc []byte) error {
+	jsonDoc, err := utilyaml.ToJSON(doc)
+	if err != nil {
+		return fmt.Errorf("failed to convert manifest to JSON: %w", err)
+	}
+	if string(jsonDoc) == "null" {
+		// a document with only comments
+		return nil
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(jsonDoc, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	switch typedObj := obj.(type) {
+	case *corev1.Namespace:
+		o.addNamespace(typedObj)
+	case *corev1.Pod:
+		o.addPod(typedObj)
+	case *networkingv1.NetworkPolicy:
+		o.addNetworkPolicy(typedObj)
+	case *corev1.List:
+		// e.g. from kubectl get -o yaml
+		for _, item := range typedObj.Items {
+			if err := o.addDocument(item.Raw); err != nil {
+				return err
+			}
+		}
+	case *corev1.NamespaceList:
+		for i := range typedObj.Items {
+			o.addNamespace(&typedObj.Items[i])
+		}
+	case *corev1.PodList:
+		for i := range typedObj.Items {
+			o.addPod(&typedObj.Items[i])
+		}
+	case *networkingv1.NetworkPolicyList:
+		for i := range typedObj.Items {
+			o.addNetworkPolicy(&typedObj.Items[i])
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedObject, obj.GetObjectKind().GroupVersionKind().String())
+	}
+	return nil
+}
+
+func (o *PlanObjects) addNamespace(nsObj *corev1.Namespace) {
+	for i, existing := range o.Namespaces {
+		if sameObject(existing, nsObj) {
+			o.Namespaces[i] = nsObj
+			return
+		}
+	}
+	o.Namespaces = append(o.Namespaces, nsObj)
+}
+
+func (o *PlanObjects) addPod(podObj *corev1.Pod) {
+	for i, existing := range o.Pods {
+		if sameObject(existing, podObj) {
+			o.Pods[i] = podObj
+			return
+		}
+	}
+	o.Pods = append(o.Pods, podObj)
+}
+
+func (o *PlanObjects) addNetworkPolicy(netPolObj *networkingv1.NetworkPolicy) {
+	for i, existing := range o.NetworkPolicies {
+		if sameObject(existing, netPolObj) {
+			o.NetworkPolicies[i] = netPolObj
+			return
+		}
+	}
+	o.NetworkPolicies = append(o.NetworkPolicies, netPolObj)
+}
+
+func sameObject(a, b metav1.Object) bool {
+	return a.GetNamespace() == b.GetNamespace() && a.GetName() == b.GetName()
+}
+
+// PlannedCommand is a command which the dataplane would run.
+// Stdin is the payload of ipset and iptables-restore commands.
+type PlannedCommand struct {
+	Name  string
+	Args  []string
+	Stdin string
+}
+
+func (c *PlannedCommand) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Plan is what the NPM v2 dataplane would program for a set of objects after booting up.
+type Plan struct {
+	// BootupCommands are run when the dataplane boots up.
+	BootupCommands []*PlannedCommand
+	// Commands are run while syncing the objects.
+	Commands []*PlannedCommand
+	// HNSPolicies are the SetPolicies and endpoint ACLs in HNS. They're only planned in Windows.
+	HNSPolicies []string
+}
+
+// NewPlan runs the objects through the NPM v2 controllers and a dataplane which records its commands instead of running them.
+// In Windows, the dataplane programs a fake HNS with an endpoint for each pod on the node.
+func NewPlan(nodeName string, cfg *dataplane.Config, objects *PlanObjects) (*Plan, error) {
+	recorder := &recordingExec{}
+	ioShim, err := newPlanIOShim(recorder, nodeName, cfg.NetworkName, objects.Pods)
+	if err != nil {
+		return nil, err
+	}
+
+	stopChannel := make(chan struct{})
+	defer close(stopChannel)
+	dp, err := dataplane.NewDataPlane(nodeName, ioShim, cfg, stopChannel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataplane: %w", err)
+	}
+	bootupCommands := recorder.reset()
+
+	if err := controllersv2.SyncObjects(dp, objects.Namespaces, objects.Pods, objects.NetworkPolicies, cfg.EnableIPv6); err != nil {
+		return nil, fmt.Errorf("failed to sync objects with dataplane: %w", err)
+	}
+
+	return &Plan{
+		BootupCommands: bootupCommands,
+		Commands:       recorder.reset(),
+		HNSPolicies:    hnsPolicies(ioShim),
+	}, nil
+}
+
+// PrettyPrintPlan prints the commands run while syncing the objects with their payloads, then any HNS policies.
+func PrettyPrintPlan(plan *Plan) {
+	for _, command := range plan.Commands {
+		fmt.Printf("# %s\n", command.String())
+		if command.Stdin != "" {
+			fmt.Print(command.Stdin)
+			if !strings.HasSuffix(command.Stdin, "\n") {
+				fmt.Println()
+			}
+		}
+	}
+	for _, policy := range plan.HNSPolicies {
+		fmt.Println(policy)
+	}
+}
+
+// recordingExec records commands instead of running them. Commands succeed with no output,
+// except grep, which finds no matches since nothing is programmed.
+type recordingExec struct {
+	sync.Mutex
+	commands []*PlannedCommand
+}
+
+func (e *recordingExec) Command(cmd string, args ...string) utilexec.Cmd {
+	e.Lock()
+	defer e.Unlock()
+	command := &PlannedCommand{Name: cmd, Args: args}
+	e.commands = append(e.commands, command)
+	return &recordedCmd{command: command}
+}
+
+func (e *recordingExec) CommandContext(_ context.Context, cmd string, args ...string) utilexec.Cmd {
+	return e.Command(cmd, args...)
+}
+
+func (e *recordingExec) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+// reset returns the commands recorded since the last reset
+func (e *recordingExec) reset() []*PlannedCommand {
+	e.Lock()
+	defer e.Unlock()
+	commands := e.commands
+	e.commands = nil
+	return commands
+}
+
+type recordedCmd struct {
+	command *PlannedCommand
+	stdin   io.Reader
+}
+
+func (c *recordedCmd) Run() error {
+	return c.record()
+}
+
+func (c *recordedCmd) CombinedOutput() ([]byte, error) {
+	return []byte{}, c.record()
+}
+
+func (c *recordedCmd) Output() ([]byte, error) {
+	return []byte{}, c.record()
+}
+
+func (c *recordedCmd) SetDir(_ string) {}
+
+func (c *recordedCmd) SetStdin(in io.Reader) {
+	c.stdin = in
+}
+
+func (c *recordedCmd) SetStdout(_ io.Writer) {}
+
+func (c *recordedCmd) SetStderr(_ io.Writer) {}
+
+func (c *recordedCmd) SetEnv(_ []string) {}
+
+func (c *recordedCmd) StdoutPipe() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *recordedCmd) StderrPipe() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *recordedCmd) Start() error {
+	return c.record()
+}
+
+func (c *recordedCmd) Wait() error {
+	return nil
+}
+
+func (c *recordedCmd) Stop() {}
+
+func (c *recordedCmd) record() error {
+	if c.stdin != nil {
+		stdin, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin of command [%s]: %w", c.command.String(), err)
+		}
+		c.command.Stdin = string(stdin)
+	}
+	if filepath.Base(c.command.Name) == ioutil.Grep {
+		return noMatchesError{}
+	}
+	return nil
+}
+
+// noMatchesError is the exit error of grep when no lines match.
+type noMatchesError struct{}
+
+func (noMatchesError) Error() string {
+	return "exit status 1"
+}
+
+func (e noMatchesError) String() string {
+	return e.Error()
+}
+
+func (noMatchesError) Exited() bool {
+	return true
+}
+
+func (noMatchesError) ExitStatus() int {
+	return 1
+}
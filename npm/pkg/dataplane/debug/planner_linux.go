@@ -0,0 +1,15 @@
+package debug
+
+import (
+	"github.com/Azure/azure-container-networking/common"
+	corev1 "k8s.io/api/core/v1"
+	utilexec "k8s.io/utils/exec"
+)
+
+func newPlanIOShim(exec utilexec.Interface, _, _ string, _ []*corev1.Pod) (*common.IOShim, error) {
+	return &common.IOShim{Exec: exec}, nil
+}
+
+func hnsPolicies(_ *common.IOShim) []string {
+	return nil
+}
@@ -0,0 +1,100 @@
+package debug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlan(t *testing.T) {
+	objects := &PlanObjects{}
+	require.NoError(t, objects.AddManifests(planManifestsFile))
+
+	cfg := &dataplane.Config{
+		IPSetManagerCfg: &ipsets.IPSetManagerCfg{
+			IPSetMode: ipsets.ApplyAllIPSets,
+		},
+		PolicyManagerCfg: &policies.PolicyManagerCfg{
+			PolicyMode: policies.IPSetPolicyMode,
+		},
+	}
+	plan, err := NewPlan("node1", cfg, objects)
+	require.NoError(t, err)
+	require.NotEmpty(t, plan.BootupCommands)
+	require.Empty(t, plan.HNSPolicies)
+
+	var ipsetPayloads, iptablesPayloads strings.Builder
+	for _, command := range plan.Commands {
+		switch {
+		case command.Name == "ipset":
+			ipsetPayloads.WriteString(command.Stdin)
+		case strings.HasPrefix(command.Name, "iptables") && strings.HasSuffix(command.Name, "-restore"):
+			iptablesPayloads.WriteString(command.Stdin)
+		}
+	}
+	podSet := ipsets.NewIPSetMetadata("app:db", ipsets.KeyValueLabelOfPod)
+	require.Contains(t, ipsetPayloads.String(), "-A "+podSet.GetHashedName()+" 10.224.0.10\n")
+	require.Contains(t, iptablesPayloads.String(), "INGRESS-POLICY-x/allow-web-TO-podlabel-app:db-AND-ns-x-IN-ns-x")
+	require.Contains(t, iptablesPayloads.String(), "-p TCP --dport 5432")
+
+	plannedSets := plan.IPSets()
+	require.Contains(t, plannedSets[podSet.GetHashedName()].Members, "10.224.0.10")
+	require.Empty(t, DiffIPSets(plannedSets, plannedSets))
+	require.Empty(t, plan.IPv6FilterTable().Chains)
+
+	planned := plan.FilterTable()
+	require.Empty(t, DiffIptables(planned, planned))
+
+	live, err := parse.IptablesFile(util.IptablesFilterTable, iptableSaveFileV2)
+	require.NoError(t, err)
+	diff := DiffIptables(live, planned)
+	require.Contains(t, diff, "+ -A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS")
+	// a policy in the live table isn't in the plan
+	require.Contains(t, diff, "- :AZURE-NPM-EGRESS-3618314628")
+	hasPolicyRule := false
+	for _, line := range diff {
+		if strings.HasPrefix(line, "+ -A AZURE-NPM-INGRESS-") && strings.Contains(line, "-p tcp -m tcp --dport 5432") {
+			hasPolicyRule = true
+		}
+	}
+	require.True(t, hasPolicyRule, "expected the policy's rule in the diff: %v", diff)
+}
+
+func TestNewDualStackPlan(t *testing.T) {
+	objects := &PlanObjects{}
+	require.NoError(t, objects.AddManifests(planManifestsFile))
+
+	cfg := &dataplane.Config{
+		IPSetManagerCfg: &ipsets.IPSetManagerCfg{
+			IPSetMode: ipsets.ApplyAllIPSets,
+		},
+		PolicyManagerCfg: &policies.PolicyManagerCfg{
+			PolicyMode: policies.IPSetPolicyMode,
+		},
+		EnableIPv6: true,
+	}
+	plan, err := NewPlan("node1", cfg, objects)
+	require.NoError(t, err)
+
+	// the policy's chains are in both tables
+	ipv4Chains := npmChainRules(plan.FilterTable())
+	ipv6Chains := npmChainRules(plan.IPv6FilterTable())
+	require.NotEmpty(t, ipv6Chains)
+	for chain := range ipv4Chains {
+		require.Contains(t, ipv6Chains, chain)
+	}
+
+	hasIPv6Set := false
+	for _, set := range plan.IPSets() {
+		if set.Family == "inet6" {
+			hasIPv6Set = true
+		}
+	}
+	require.True(t, hasIPv6Set, "expected inet6 sets in the plan")
+}
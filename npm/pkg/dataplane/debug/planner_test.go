@@ -0,0 +1,45 @@
+package debug
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	npmcommon "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAddManifests(t *testing.T) {
+	cache := &npmcommon.Cache{
+		NsMap: map[string]*npmcommon.Namespace{
+			"x": {Name: "x", LabelsMap: map[string]string{"team": "old"}},
+			"y": {Name: "y", LabelsMap: map[string]string{}},
+		},
+		PodMap: map[string]*npmcommon.NpmPod{
+			"x/a": {Name: "a", Namespace: "x", PodIP: "10.224.0.9", Labels: map[string]string{"app": "old"}, Phase: corev1.PodRunning},
+			"y/b": {Name: "b", Namespace: "y", PodIP: "10.224.0.11", PodIPs: []string{"10.224.0.11", "fd00::11"}, Phase: corev1.PodRunning},
+		},
+	}
+	objects := ObjectsFromCache(cache)
+	require.Len(t, objects.Namespaces, 2)
+	require.Len(t, objects.Pods, 2)
+	require.Equal(t, []corev1.PodIP{{IP: "10.224.0.11"}, {IP: "fd00::11"}}, objects.Pods[1].Status.PodIPs)
+
+	// the manifests replace namespace x and pod x/a
+	require.NoError(t, objects.AddManifests(planManifestsFile))
+	require.Len(t, objects.Namespaces, 2)
+	require.Equal(t, "x", objects.Namespaces[0].Name)
+	require.Equal(t, map[string]string{"team": "backend"}, objects.Namespaces[0].Labels)
+	require.Len(t, objects.Pods, 2)
+	require.Equal(t, "10.224.0.10", objects.Pods[0].Status.PodIP)
+	require.Equal(t, map[string]string{"app": "db"}, objects.Pods[0].Labels)
+	require.Len(t, objects.NetworkPolicies, 1)
+	require.Equal(t, "allow-web", objects.NetworkPolicies[0].Name)
+
+	require.Error(t, objects.AddManifests("non-existing-manifest.yaml"))
+
+	configMapFile := filepath.Join(t.TempDir(), "configmap.yaml")
+	require.NoError(t, os.WriteFile(configMapFile, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n"), 0o600))
+	require.ErrorIs(t, objects.AddManifests(configMapFile), ErrUnsupportedObject)
+}
@@ -0,0 +1,79 @@
+package debug
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/network/hnswrapper"
+	"github.com/Microsoft/hcsshim/hcn"
+	corev1 "k8s.io/api/core/v1"
+	utilexec "k8s.io/utils/exec"
+)
+
+// newPlanIOShim uses a fake HNS with the network and an endpoint for each pod on the node
+func newPlanIOShim(exec utilexec.Interface, nodeName, networkName string, pods []*corev1.Pod) (*common.IOShim, error) {
+	hns := hnswrapper.NewHnsv2wrapperFake()
+	network := &hcn.HostComputeNetwork{
+		Id:   common.FakeHNSNetworkID,
+		Name: networkName,
+	}
+	if _, err := hns.CreateNetwork(network); err != nil {
+		return nil, fmt.Errorf("failed to create network in fake HNS: %w", err)
+	}
+
+	for _, pod := range pods {
+		if pod.Spec.NodeName != nodeName || pod.Spec.HostNetwork || pod.Status.PodIP == "" {
+			continue
+		}
+		endpointID := fmt.Sprintf("%s-%s", pod.Namespace, pod.Name)
+		endpoint := &hcn.HostComputeEndpoint{
+			Id:                 endpointID,
+			Name:               endpointID,
+			HostComputeNetwork: common.FakeHNSNetworkID,
+			IpConfigurations: []hcn.IpConfig{
+				{
+					IpAddress: pod.Status.PodIP,
+				},
+			},
+		}
+		if _, err := hns.CreateEndpoint(endpoint); err != nil {
+			return nil, fmt.Errorf("failed to create endpoint for pod %s/%s in fake HNS: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	return &common.IOShim{Exec: exec, Hns: hns}, nil
+}
+
+// hnsPolicies returns the SetPolicies of the network and the ACLs of each endpoint in the fake HNS
+func hnsPolicies(ioShim *common.IOShim) []string {
+	hns, ok := ioShim.Hns.(*hnswrapper.Hnsv2wrapperFake)
+	if !ok {
+		return nil
+	}
+
+	policies := make([]string, 0)
+	setPolicies := hns.Cache.AllSetPolicies(common.FakeHNSNetworkID)
+	setIDs := make([]string, 0, len(setPolicies))
+	for setID := range setPolicies {
+		setIDs = append(setIDs, setID)
+	}
+	sort.Strings(setIDs)
+	for _, setID := range setIDs {
+		setPolicy := setPolicies[setID]
+		policies = append(policies, fmt.Sprintf("SetPolicy %s: name=%s type=%s values=%s", setID, setPolicy.Name, setPolicy.Type, setPolicy.Values))
+	}
+
+	endpointACLs := hns.Cache.GetAllACLs()
+	endpointIDs := make([]string, 0, len(endpointACLs))
+	for endpointID := range endpointACLs {
+		endpointIDs = append(endpointIDs, endpointID)
+	}
+	sort.Strings(endpointIDs)
+	for _, endpointID := range endpointIDs {
+		for _, acl := range endpointACLs[endpointID] {
+			policies = append(policies, fmt.Sprintf("ACL %s (%s): %+v", endpointID, hns.Cache.EndpointIP(endpointID), *acl))
+		}
+	}
+	return policies
+}
@@ -42,6 +42,12 @@ func (setMetadata *IPSetMetadata) GetHashedName() string {
 	return util.GetHashedName(prefixedName)
 }
 
+// GetIPv6HashedName returns the name of the inet6 set that holds the IPv6 members of the hash set with the hashed name.
+// In a dual-stack dataplane, each hash set is a pair of kernel sets since an ipset has a single family.
+func GetIPv6HashedName(hashedName string) string {
+	return util.GetHashedName(hashedName)
+}
+
 // TODO join with colon instead of dash for easier readability?
 func (setMetadata *IPSetMetadata) GetPrefixName() string {
 	switch setMetadata.Type {
@@ -62,6 +62,8 @@ type IPSetManagerCfg struct {
 	// This is necessary for HNS (Windows); otherwise, an allow ACL with a list condition
 	// allows all IPs if the list has no members.
 	AddEmptySetToLists bool
+	// EnableIPv6 allows IPv6 members in hash sets. In Linux, each hash set then has an inet6 twin for its IPv6 members.
+	EnableIPv6 bool
 }
 
 func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetManager {
@@ -238,7 +240,7 @@ func (iMgr *IPSetManager) AddToSets(addToSets []*IPSetMetadata, ip, podKey strin
 		return nil
 	}
 
-	if !validateIPSetMemberIP(ip) {
+	if !iMgr.validMemberIP(ip) {
 		msg := fmt.Sprintf("error: failed to add to sets: invalid ip %s", ip)
 		metrics.SendErrorLogAndMetric(util.IpsmID, msg)
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
@@ -274,7 +276,7 @@ func (iMgr *IPSetManager) RemoveFromSets(removeFromSets []*IPSetMetadata, ip, po
 		return nil
 	}
 
-	if !validateIPSetMemberIP(ip) {
+	if !iMgr.validMemberIP(ip) {
 		msg := fmt.Sprintf("error: failed to add to sets: invalid ip %s", ip)
 		metrics.SendErrorLogAndMetric(util.IpsmID, msg)
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
@@ -472,6 +474,17 @@ func (iMgr *IPSetManager) ApplyIPSets() error {
 	return nil
 }
 
+/*
+VerifyIPSets compares the kernel with the sets which should be in the kernel and aren't waiting to be applied.
+It returns the prefixed names of the sets which are missing from the kernel or whose kernel members differ,
+e.g. after another process ran ipset flush. If repair is true, the sets are recreated and their members are fixed.
+*/
+func (iMgr *IPSetManager) VerifyIPSets(repair bool) ([]string, error) {
+	iMgr.Lock()
+	defer iMgr.Unlock()
+	return iMgr.verifyIPSets(repair)
+}
+
 func (iMgr *IPSetManager) GetAllIPSets() map[string]string {
 	iMgr.RLock()
 	defer iMgr.RUnlock()
@@ -583,6 +596,22 @@ func (iMgr *IPSetManager) clearDirtyCache() {
 	iMgr.dirtyCache.reset()
 }
 
+// validMemberIP is validateIPSetMemberIP, except that it also allows IPv6 members if IPv6 is enabled.
+func (iMgr *IPSetManager) validMemberIP(ip string) bool {
+	return validateIPSetMemberIP(ip) || (iMgr.iMgrCfg.EnableIPv6 && util.IsIPV6(memberIP(ip)))
+}
+
+// isIPv6Member returns whether the member of a hash set is an IPv6 address or CIDR, possibly with a port or nomatch.
+func isIPv6Member(member string) bool {
+	return strings.Contains(memberIP(member), ":")
+}
+
+// memberIP returns the IP or CIDR of a hash set member without its port or nomatch.
+func memberIP(member string) string {
+	ipDetails := strings.Split(member, ",")
+	return strings.Split(ipDetails[0], " ")[0]
+}
+
 // validateIPSetMemberIP helps valid if a member added to an HashSet has valid IP or CIDR
 func validateIPSetMemberIP(ip string) bool {
 	// possible formats
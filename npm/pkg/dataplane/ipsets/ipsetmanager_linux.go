@@ -3,6 +3,8 @@ package ipsets
 import (
 	"errors"
 	"fmt"
+	"net"
+	"sort"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
@@ -39,6 +41,9 @@ const (
 	ipsetIPPortHashFlag = "hash:ip,port"
 	ipsetMaxelemName    = "maxelem"
 	ipsetMaxelemNum     = "4294967295"
+	ipsetFamilyName     = "family"
+	ipsetFamilyInet     = "inet"
+	ipsetFamilyInet6    = "inet6"
 
 	// constants for parsing ipset save
 	createStringWithSpace = "create "
@@ -376,6 +381,7 @@ func (iMgr *IPSetManager) applyIPSetsWithSaveFile() error {
 See error handling in applyIPSetsWithSaveFile().
 
 overall format for ipset restore file:
+
 	[creates]  (random order)
 	[deletes and adds] (sets in random order, where each set has deletes first (random order), then adds (random order))
 	[flushes]  (random order)
@@ -426,6 +432,241 @@ func (iMgr *IPSetManager) ipsetSave() ([]byte, error) {
 	return saveFile, nil
 }
 
+// kernelSetDrift is how the kernel sets of a cached set differ from what they should be.
+// Members are keyed by the name of their kernel set, which is an inet6 twin for IPv6 members in a dual-stack dataplane.
+type kernelSetDrift struct {
+	set *IPSet
+	// missing is true if a kernel set of the cached set doesn't exist
+	missing bool
+	// typeProblem is true if a kernel set has a different type or family. These sets aren't repaired.
+	typeProblem     bool
+	membersToAdd    map[string]map[string]struct{}
+	membersToDelete map[string]map[string]struct{}
+}
+
+func (d *kernelSetDrift) hasDrift() bool {
+	return d.missing || d.typeProblem || len(d.membersToAdd) > 0 || len(d.membersToDelete) > 0
+}
+
+/*
+verifyIPSets compares ipset save output with the sets which should be in the kernel.
+Sets in the dirty cache are skipped since the next apply will update them anyway.
+
+The repair is a restore file which creates the missing sets, then deletes and adds members:
+[flag meanings: -N (create), -D (delete), -A (add)]
+
+	-N set1
+	-D set2 1.2.3.4
+	-A set1 7.7.7.7
+	-A set2 8.8.8.8
+*/
+func (iMgr *IPSetManager) verifyIPSets(repair bool) ([]string, error) {
+	saveFile, err := iMgr.ipsetSave()
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper("ipset save failed when verifying ipsets", err)
+	}
+
+	drifts := iMgr.kernelSetDrifts(parse.IPSets(saveFile))
+	driftedNames := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		driftedNames = append(driftedNames, drift.set.Name)
+		if drift.typeProblem {
+			metrics.SendErrorLogAndMetric(util.IpsmID, "error: set %s has a different type in the kernel and won't be repaired", drift.set.Name)
+		}
+	}
+	sort.Strings(driftedNames)
+	metrics.SetKernelDrift(metrics.IPSetDrift, len(driftedNames))
+	if len(driftedNames) > 0 {
+		klog.Infof("[IPSetManager] found %d ipsets which drifted from the kernel: %+v", len(driftedNames), driftedNames)
+	}
+
+	if !repair || len(drifts) == 0 {
+		return driftedNames, nil
+	}
+
+	creator := iMgr.fileCreatorForDrift(maxTryCount, drifts)
+	restoreError := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
+	metrics.IncDriftRepairs(metrics.IPSetDrift, restoreError != nil)
+	if restoreError != nil {
+		return driftedNames, npmerrors.SimpleErrorWrapper("ipset restore failed when repairing drifted ipsets", restoreError)
+	}
+	return driftedNames, nil
+}
+
+// kernelSetDrifts returns the drift of each set which should be in the kernel and isn't dirty.
+func (iMgr *IPSetManager) kernelSetDrifts(kernelSets map[string]*parse.IPSet) []*kernelSetDrift {
+	drifts := make([]*kernelSetDrift, 0)
+	for prefixedName, set := range iMgr.setMap {
+		if !iMgr.shouldBeInKernel(set) || iMgr.dirtyCache.isSetToAddOrUpdate(prefixedName) || iMgr.dirtyCache.isSetToDelete(prefixedName) {
+			continue
+		}
+
+		drift := &kernelSetDrift{
+			set:             set,
+			membersToAdd:    make(map[string]map[string]struct{}),
+			membersToDelete: make(map[string]map[string]struct{}),
+		}
+		for kernelName, expectedMembers := range iMgr.expectedKernelSets(set) {
+			kernelSet, ok := kernelSets[kernelName]
+			if !ok {
+				drift.missing = true
+				addKernelMembers(drift.membersToAdd, kernelName, expectedMembers)
+				continue
+			}
+
+			if kernelSet.Type != kernelSetType(set) || kernelSet.Family != iMgr.kernelSetFamily(set, kernelName) {
+				drift.typeProblem = true
+				continue
+			}
+
+			kernelMembers := make(map[string]struct{}, len(kernelSet.Members))
+			for _, member := range kernelSet.Members {
+				kernelMembers[kernelMember(member)] = struct{}{}
+			}
+			for member := range expectedMembers {
+				if _, ok := kernelMembers[member]; !ok {
+					addKernelMembers(drift.membersToAdd, kernelName, map[string]struct{}{member: {}})
+				}
+			}
+			for member := range kernelMembers {
+				if _, ok := expectedMembers[member]; !ok {
+					addKernelMembers(drift.membersToDelete, kernelName, map[string]struct{}{member: {}})
+				}
+			}
+		}
+
+		if drift.hasDrift() {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
+// expectedKernelSets returns the members which each kernel set of the cached set should have, keyed by kernel set name.
+func (iMgr *IPSetManager) expectedKernelSets(set *IPSet) map[string]map[string]struct{} {
+	expected := map[string]map[string]struct{}{
+		set.HashedName: {},
+	}
+	if iMgr.hasIPv6Twin(set.Name) {
+		expected[GetIPv6HashedName(set.HashedName)] = make(map[string]struct{})
+	}
+
+	var members []string
+	if set.Kind == HashSet {
+		members = make([]string, 0, len(set.IPPodKey))
+		for ip := range set.IPPodKey {
+			members = append(members, ip)
+		}
+	} else {
+		members = make([]string, 0, len(set.MemberIPSets))
+		for _, member := range set.MemberIPSets {
+			members = append(members, member.HashedName)
+		}
+	}
+
+	for _, member := range members {
+		setName, kernelMembers := iMgr.memberSetNames(set, member)
+		for _, m := range kernelMembers {
+			expected[setName][kernelMember(m)] = struct{}{}
+		}
+	}
+	return expected
+}
+
+// kernelSetType returns the type of the cached set in ipset save output.
+func kernelSetType(set *IPSet) string {
+	if set.Kind == ListSet {
+		return ipsetSetListString
+	}
+	if set.Type == NamedPorts {
+		return ipsetIPPortHashString
+	}
+	return ipsetNetHashString
+}
+
+// kernelSetFamily returns the family of the kernel set in ipset save output. Lists have no family.
+func (iMgr *IPSetManager) kernelSetFamily(set *IPSet, kernelName string) string {
+	if set.Kind == ListSet {
+		return ""
+	}
+	if kernelName != set.HashedName {
+		return ipsetFamilyInet6
+	}
+	return ipsetFamilyInet
+}
+
+// kernelMember returns the member like ipset save prints it, e.g. 10.0.0.1 instead of 10.0.0.1/32.
+// Members which aren't IPs or CIDRs, like the member sets of a list, are returned as is.
+func kernelMember(member string) string {
+	ip := memberIP(member)
+	var normalizedIP string
+	if _, ipNet, err := net.ParseCIDR(ip); err == nil {
+		ones, bits := ipNet.Mask.Size()
+		if ones == bits {
+			normalizedIP = ipNet.IP.String()
+		} else {
+			normalizedIP = ipNet.String()
+		}
+	} else if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		normalizedIP = parsedIP.String()
+	} else {
+		return member
+	}
+	return normalizedIP + member[len(ip):]
+}
+
+func addKernelMembers(membersBySet map[string]map[string]struct{}, kernelName string, members map[string]struct{}) {
+	if len(members) == 0 {
+		return
+	}
+	if _, ok := membersBySet[kernelName]; !ok {
+		membersBySet[kernelName] = make(map[string]struct{}, len(members))
+	}
+	for member := range members {
+		membersBySet[kernelName][member] = struct{}{}
+	}
+}
+
+// fileCreatorForDrift creates missing sets first so that member sets exist before they're added to lists.
+// Sets with a type problem are skipped.
+func (iMgr *IPSetManager) fileCreatorForDrift(maxTryCount int, drifts []*kernelSetDrift) *ioutil.FileCreator {
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount, ipsetRestoreLineFailurePattern)
+	for _, drift := range drifts {
+		if drift.missing && !drift.typeProblem {
+			iMgr.createSetForApply(creator, drift.set)
+		}
+	}
+
+	for _, drift := range drifts {
+		if drift.typeProblem {
+			continue
+		}
+		prefixedName := drift.set.Name // to appease golint complaints about function literal
+		errorHandlers := []*ioutil.LineErrorHandler{
+			{
+				Definition: ioutil.AlwaysMatchDefinition,
+				Method:     ioutil.Continue,
+				Callback: func() {
+					metrics.SendErrorLogAndMetric(util.IpsmID, "skipping repair line for set %s due to unknown error", prefixedName)
+				},
+			},
+		}
+		// the create lines are in the add/update section so that a failed create skips the members of the set
+		sectionID := sectionID(addOrUpdateSectionPrefix, prefixedName)
+		for kernelName, members := range drift.membersToDelete {
+			for member := range members {
+				creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, kernelName, member) // delete member
+			}
+		}
+		for kernelName, members := range drift.membersToAdd {
+			for member := range members {
+				creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, kernelName, member) // add member
+			}
+		}
+	}
+	return creator
+}
+
 // NOTE: duplicate code in the first step of this function and fileCreatorForApply
 func (iMgr *IPSetManager) fileCreatorForApplyWithSaveFile(maxTryCount int, saveFile []byte) *ioutil.FileCreator {
 	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount, ipsetRestoreLineFailurePattern) // TODO make the line failure pattern into a definition constant eventually
@@ -703,6 +944,9 @@ func (iMgr *IPSetManager) flushSetForApply(creator *ioutil.FileCreator, prefixed
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetFlushFlag, hashedName) // flush set
+	if iMgr.hasIPv6Twin(prefixedName) {
+		creator.AddLine(sectionID, errorHandlers, ipsetFlushFlag, GetIPv6HashedName(hashedName)) // flush inet6 set
+	}
 }
 
 func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefixedName string) {
@@ -726,6 +970,9 @@ func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefix
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetDestroyFlag, hashedName) // destroy set
+	if iMgr.hasIPv6Twin(prefixedName) {
+		creator.AddLine(sectionID, errorHandlers, ipsetDestroyFlag, GetIPv6HashedName(hashedName)) // destroy inet6 set
+	}
 }
 
 func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IPSet) {
@@ -762,6 +1009,38 @@ func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IP
 	}
 	sectionID := sectionID(addOrUpdateSectionPrefix, prefixedName)
 	creator.AddLine(sectionID, errorHandlers, specs...) // create set
+	if iMgr.hasIPv6Twin(prefixedName) {
+		ipv6Specs := []string{ipsetCreateFlag, GetIPv6HashedName(set.HashedName), ipsetExistFlag, methodFlag, ipsetFamilyName, ipsetFamilyInet6}
+		ipv6Specs = append(ipv6Specs, specs[4:]...)
+		creator.AddLine(sectionID, errorHandlers, ipv6Specs...) // create inet6 set
+	}
+}
+
+// hasIPv6Twin returns whether the set with the prefixed name has an inet6 twin for its IPv6 members.
+// The lists have none since a list:set can hold sets of both families.
+func (iMgr *IPSetManager) hasIPv6Twin(prefixedName string) bool {
+	return iMgr.iMgrCfg.EnableIPv6 && !isListName(prefixedName)
+}
+
+// isListName returns whether the prefixed name is the name of a list.
+// The name is enough to tell since the set may already be gone from the cache when destroying it.
+func isListName(prefixedName string) bool {
+	return strings.HasPrefix(prefixedName, util.NamespaceLabelPrefix) || strings.HasPrefix(prefixedName, util.NestedLabelPrefix)
+}
+
+// memberSetNames returns the names of the kernel sets to add the member to or delete it from.
+// In a dual-stack dataplane, IPv6 members go to the inet6 twin of a hash set, and lists hold both twins of a member set.
+func (iMgr *IPSetManager) memberSetNames(set *IPSet, member string) (setName string, members []string) {
+	if !iMgr.iMgrCfg.EnableIPv6 {
+		return set.HashedName, []string{member}
+	}
+	if set.Kind == ListSet {
+		return set.HashedName, []string{member, GetIPv6HashedName(member)}
+	}
+	if isIPv6Member(member) {
+		return GetIPv6HashedName(set.HashedName), []string{member}
+	}
+	return set.HashedName, []string{member}
 }
 
 func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -774,7 +1053,10 @@ func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set
 			},
 		},
 	}
-	creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, set.HashedName, member) // delete member
+	setName, members := iMgr.memberSetNames(set, member)
+	for _, m := range members {
+		creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, setName, m) // delete member
+	}
 }
 
 func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -808,7 +1090,10 @@ func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IP
 			},
 		}
 	}
-	creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, set.HashedName, member) // add member
+	setName, members := iMgr.memberSetNames(set, member)
+	for _, m := range members {
+		creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, setName, m) // add member
+	}
 }
 
 func sectionID(prefix, prefixedName string) string {
@@ -10,6 +10,7 @@ import (
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/metrics/promutil"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
@@ -992,6 +993,62 @@ func TestDeleteMembers(t *testing.T) {
 	require.False(t, wasFileAltered, "file should not be altered")
 }
 
+func TestApplyDualStack(t *testing.T) {
+	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{IPSetMode: ApplyAllIPSets, NetworkName: "azure", EnableIPv6: true}, ioshim)
+
+	// create to destroy later
+	iMgr.CreateIPSets([]*IPSetMetadata{TestKVPodSet.Metadata, TestNestedLabelList.Metadata})
+	// clear dirty cache, otherwise a set deletion will be a no-op
+	iMgr.clearDirtyCache()
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "2001:db8::1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "2001:db8::1,tcp:8080", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "2001:db8::/32", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "2001:db8::/64 nomatch", "a"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.DeleteIPSet(TestKVPodSet.PrefixName, util.SoftDelete)
+	iMgr.DeleteIPSet(TestNestedLabelList.PrefixName, util.SoftDelete)
+
+	nsSetIPv6 := GetIPv6HashedName(TestNSSet.HashedName)
+	namedportSetIPv6 := GetIPv6HashedName(TestNamedportSet.HashedName)
+	cidrSetIPv6 := GetIPv6HashedName(TestCIDRSet.HashedName)
+	kvPodSetIPv6 := GetIPv6HashedName(TestKVPodSet.HashedName)
+	expectedLines := []string{
+		fmt.Sprintf("-N %s --exist nethash", TestNSSet.HashedName),
+		fmt.Sprintf("-N %s --exist nethash family inet6", nsSetIPv6),
+		fmt.Sprintf("-N %s --exist hash:ip,port", TestNamedportSet.HashedName),
+		fmt.Sprintf("-N %s --exist hash:ip,port family inet6", namedportSetIPv6),
+		fmt.Sprintf("-N %s --exist nethash maxelem 4294967295", TestCIDRSet.HashedName),
+		fmt.Sprintf("-N %s --exist nethash family inet6 maxelem 4294967295", cidrSetIPv6),
+		fmt.Sprintf("-N %s --exist setlist", TestKeyNSList.HashedName),
+		fmt.Sprintf("-A %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s 2001:db8::1", nsSetIPv6),
+		fmt.Sprintf("-A %s 2001:db8::1,tcp:8080", namedportSetIPv6),
+		fmt.Sprintf("-A %s 2001:db8::/32", cidrSetIPv6),
+		fmt.Sprintf("-A %s 2001:db8::/64 nomatch", cidrSetIPv6),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, nsSetIPv6),
+		fmt.Sprintf("-F %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("-F %s", kvPodSetIPv6),
+		fmt.Sprintf("-F %s", TestNestedLabelList.HashedName),
+		fmt.Sprintf("-X %s", TestKVPodSet.HashedName),
+		fmt.Sprintf("-X %s", kvPodSetIPv6),
+		fmt.Sprintf("-X %s", TestNestedLabelList.HashedName),
+		"",
+	}
+	sortedExpectedLines := testAndSortRestoreFileLines(t, expectedLines)
+	creator := iMgr.fileCreatorForApply(len(calls))
+	actualLines := testAndSortRestoreFileString(t, creator.ToString())
+	dptestutils.AssertEqualLines(t, sortedExpectedLines, actualLines)
+	wasFileAltered, err := creator.RunCommandOnceWithFile("ipset", "restore")
+	require.NoError(t, err, "ipset restore should be successful")
+	require.False(t, wasFileAltered, "file should not be altered")
+}
+
 func TestUpdateWithIdenticalSaveFile(t *testing.T) {
 	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
 	ioshim := common.NewMockIOShim(calls)
@@ -1759,6 +1816,216 @@ func TestFailureOnLastLine(t *testing.T) {
 	}
 }
 
+func TestVerifyIPSetsWithoutDrift(t *testing.T) {
+	metrics.ReinitializeAll()
+	calls := []testutils.TestCmd{
+		{Cmd: ipsetSaveStringSlice, PipedToCommand: true},
+		{Cmd: []string{"grep", "azure-npm-"}, Stdout: strings.Join([]string{
+			fmt.Sprintf(createNethashFormat, TestNSSet.HashedName),
+			fmt.Sprintf("add %s 10.0.0.1", TestNSSet.HashedName),
+			fmt.Sprintf(createNethashFormat, TestCIDRSet.HashedName),
+			fmt.Sprintf("add %s 10.1.0.0/16", TestCIDRSet.HashedName),
+			fmt.Sprintf("add %s 10.1.2.3", TestCIDRSet.HashedName),
+			fmt.Sprintf(createListFormat, TestKeyNSList.HashedName),
+			fmt.Sprintf("add %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		}, "\n")},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(applyAlwaysCfg, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.1.0.0/16", "a"))
+	// ipset save prints a /32 CIDR as an IP
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.1.2.3/32", "a"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.clearDirtyCache()
+
+	drifted, err := iMgr.VerifyIPSets(true)
+	require.NoError(t, err)
+	require.Empty(t, drifted)
+
+	numDrifted, err := metrics.GetKernelDrift(metrics.IPSetDrift)
+	promutil.NotifyIfErrors(t, err)
+	require.Equal(t, 0, numDrifted)
+}
+
+func TestVerifyIPSetsWithDrift(t *testing.T) {
+	// the kernel:
+	// - is missing a member of the ns set
+	// - has an extra member in the key pod set
+	// - is missing the kv pod set
+	// - has the named port set with the wrong type
+	// - has the list with all its members
+	saveFile := strings.Join([]string{
+		fmt.Sprintf(createNethashFormat, TestNSSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf(createNethashFormat, TestKeyPodSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.5", TestKeyPodSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.6", TestKeyPodSet.HashedName),
+		fmt.Sprintf(createNethashFormat, TestNamedportSet.HashedName),
+		fmt.Sprintf(createListFormat, TestKeyNSList.HashedName),
+		fmt.Sprintf("add %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("add %s %s", TestKeyNSList.HashedName, TestKVPodSet.HashedName),
+	}, "\n")
+	expectedDrifted := []string{TestNSSet.PrefixName, TestKeyPodSet.PrefixName, TestKVPodSet.PrefixName, TestNamedportSet.PrefixName}
+	sort.Strings(expectedDrifted)
+
+	tests := []struct {
+		name         string
+		repair       bool
+		restoreCalls []testutils.TestCmd
+		wantErr      bool
+	}{
+		{
+			name:   "no repair",
+			repair: false,
+		},
+		{
+			name:         "repair",
+			repair:       true,
+			restoreCalls: []testutils.TestCmd{fakeRestoreSuccessCommand},
+		},
+		{
+			name:   "failed repair",
+			repair: true,
+			restoreCalls: []testutils.TestCmd{
+				{Cmd: ipsetRestoreStringSlice, ExitCode: 1},
+				{Cmd: ipsetRestoreStringSlice, ExitCode: 1},
+				{Cmd: ipsetRestoreStringSlice, ExitCode: 1},
+				{Cmd: ipsetRestoreStringSlice, ExitCode: 1},
+				{Cmd: ipsetRestoreStringSlice, ExitCode: 1},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.ReinitializeAll()
+			calls := []testutils.TestCmd{
+				{Cmd: ipsetSaveStringSlice, PipedToCommand: true},
+				{Cmd: []string{"grep", "azure-npm-"}, Stdout: saveFile},
+			}
+			calls = append(calls, tt.restoreCalls...)
+			ioshim := common.NewMockIOShim(calls)
+			defer ioshim.VerifyCalls(t, calls)
+			iMgr := NewIPSetManager(applyAlwaysCfg, ioshim)
+
+			require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+			require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.2", "b"))
+			require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.5", "c"))
+			require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKVPodSet.Metadata}, "10.0.0.7", "d"))
+			iMgr.CreateIPSets([]*IPSetMetadata{TestNamedportSet.Metadata})
+			require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata, TestKVPodSet.Metadata}))
+			iMgr.clearDirtyCache()
+
+			drifted, err := iMgr.VerifyIPSets(tt.repair)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, expectedDrifted, drifted)
+
+			numDrifted, err := metrics.GetKernelDrift(metrics.IPSetDrift)
+			promutil.NotifyIfErrors(t, err)
+			require.Equal(t, len(expectedDrifted), numDrifted)
+
+			numRepairs, err := metrics.TotalDriftRepairs(metrics.IPSetDrift, false)
+			promutil.NotifyIfErrors(t, err)
+			numFailedRepairs, err := metrics.TotalDriftRepairs(metrics.IPSetDrift, true)
+			promutil.NotifyIfErrors(t, err)
+			switch {
+			case !tt.repair:
+				require.Equal(t, 0, numRepairs)
+				require.Equal(t, 0, numFailedRepairs)
+			case tt.wantErr:
+				require.Equal(t, 0, numRepairs)
+				require.Equal(t, 1, numFailedRepairs)
+			default:
+				require.Equal(t, 1, numRepairs)
+				require.Equal(t, 0, numFailedRepairs)
+			}
+		})
+	}
+}
+
+func TestFileCreatorForDrift(t *testing.T) {
+	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{IPSetMode: ApplyAllIPSets, NetworkName: "azure", EnableIPv6: true}, ioshim)
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "2001:db8::1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.5", "b"))
+	iMgr.CreateIPSets([]*IPSetMetadata{TestNamedportSet.Metadata})
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestKeyPodSet.Metadata}))
+	iMgr.clearDirtyCache()
+
+	// the kernel:
+	// - is missing the inet6 twin of the ns set (e.g. after ipset destroy)
+	// - has an extra IPv6 member in the twin of the key pod set
+	// - has the named port set with the wrong family, which isn't repaired
+	// - is missing the list
+	nsSetIPv6 := GetIPv6HashedName(TestNSSet.HashedName)
+	keyPodSetIPv6 := GetIPv6HashedName(TestKeyPodSet.HashedName)
+	namedportSetIPv6 := GetIPv6HashedName(TestNamedportSet.HashedName)
+	saveFile := strings.Join([]string{
+		fmt.Sprintf(createNethashFormat, TestNSSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf(createNethashFormat, TestKeyPodSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.5", TestKeyPodSet.HashedName),
+		fmt.Sprintf("create %s hash:net family inet6 hashsize 1024 maxelem 65536", keyPodSetIPv6),
+		fmt.Sprintf("add %s 2001:db8::5", keyPodSetIPv6),
+		fmt.Sprintf(createPorthashFormat, TestNamedportSet.HashedName),
+		fmt.Sprintf(createPorthashFormat, namedportSetIPv6),
+	}, "\n")
+
+	drifts := iMgr.kernelSetDrifts(parse.IPSets([]byte(saveFile)))
+	require.Len(t, drifts, 4)
+
+	expectedLines := []string{
+		fmt.Sprintf("-N %s --exist nethash", TestNSSet.HashedName),
+		fmt.Sprintf("-N %s --exist nethash family inet6", nsSetIPv6),
+		fmt.Sprintf("-N %s --exist setlist", TestKeyNSList.HashedName),
+		fmt.Sprintf("-A %s 2001:db8::1", nsSetIPv6),
+		fmt.Sprintf("-D %s 2001:db8::5", keyPodSetIPv6),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, TestKeyPodSet.HashedName),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, keyPodSetIPv6),
+		"",
+	}
+	sortedExpectedLines := testAndSortRestoreFileLines(t, expectedLines)
+	creator := iMgr.fileCreatorForDrift(len(calls), drifts)
+	actualLines := testAndSortRestoreFileString(t, creator.ToString())
+	dptestutils.AssertEqualLines(t, sortedExpectedLines, actualLines)
+	wasFileAltered, err := creator.RunCommandOnceWithFile("ipset", "restore")
+	require.NoError(t, err, "ipset restore should be successful")
+	require.False(t, wasFileAltered, "file should not be altered")
+}
+
+func TestKernelMember(t *testing.T) {
+	tests := []struct {
+		member   string
+		expected string
+	}{
+		{member: "10.0.0.1", expected: "10.0.0.1"},
+		{member: "10.0.0.1/32", expected: "10.0.0.1"},
+		{member: "10.0.0.1/24", expected: "10.0.0.0/24"},
+		{member: "10.0.0.0/24 nomatch", expected: "10.0.0.0/24 nomatch"},
+		{member: "10.0.0.1,tcp:8080", expected: "10.0.0.1,tcp:8080"},
+		{member: "2001:0db8:0000::1", expected: "2001:db8::1"},
+		{member: "2001:db8::1/128", expected: "2001:db8::1"},
+		{member: "2001:db8::/64 nomatch", expected: "2001:db8::/64 nomatch"},
+		{member: TestNSSet.HashedName, expected: TestNSSet.HashedName},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, kernelMember(tt.member), "unexpected kernel member for %s", tt.member)
+	}
+}
+
 func testAndSortRestoreFileString(t *testing.T, multilineString string) []string {
 	return testAndSortRestoreFileLines(t, strings.Split(multilineString, "\n"))
 }
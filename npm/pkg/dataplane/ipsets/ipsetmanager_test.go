@@ -1567,6 +1567,19 @@ func TestValidateIPSetMemberIP(t *testing.T) {
 	}
 }
 
+func TestValidateIPv6MemberIP(t *testing.T) {
+	members := []string{"2001:db8::1", "2001:db8::/64", "2001:db8::/64 nomatch", "2001:db8::1,tcp:8080", "::/1"}
+	for _, enableIPv6 := range []bool{false, true} {
+		iMgr := NewIPSetManager(&IPSetManagerCfg{IPSetMode: ApplyAllIPSets, EnableIPv6: enableIPv6}, common.NewMockIOShim(nil))
+		for _, member := range members {
+			require.Equal(t, enableIPv6, iMgr.validMemberIP(member), member)
+		}
+		require.True(t, iMgr.validMemberIP("10.0.0.1,tcp:8080"))
+		require.False(t, iMgr.validMemberIP("2001:db8::/129"))
+		require.False(t, iMgr.validMemberIP("::ffff:10.0.0.1"))
+	}
+}
+
 func assertExpectedInfo(t *testing.T, iMgr *IPSetManager, info *expectedInfo) {
 	// 1. assert cache contents
 	// 1.1. make sure the main cache is equal, including members and references
@@ -195,6 +195,11 @@ func (iMgr *IPSetManager) resetIPSets() error {
 	return nil
 }
 
+// verifyIPSets is a no-op in Windows, where SetPolicies in HNS aren't verified yet.
+func (iMgr *IPSetManager) verifyIPSets(_ bool) ([]string, error) {
+	return nil, nil
+}
+
 func (iMgr *IPSetManager) applyIPSets() error {
 	network, err := iMgr.getHCnNetwork()
 	if err != nil {
@@ -0,0 +1,150 @@
+package nflog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	receiveBufferSize = 1 << 16
+	// the socket is polled so the listener can be stopped
+	receiveTimeout = time.Second
+)
+
+// PolicyResolver returns the audited policy for the tag of an NFLOG prefix.
+type PolicyResolver interface {
+	AuditedPolicy(tag string) (policies.AuditedPolicy, bool)
+}
+
+// Listener logs and counts the verdicts of audited policies.
+type Listener struct {
+	group    uint16
+	resolver PolicyResolver
+	seq      uint32
+}
+
+func NewListener(group int, resolver PolicyResolver) *Listener {
+	return &Listener{
+		group:    uint16(group),
+		resolver: resolver,
+	}
+}
+
+// Run binds to the NFLOG group and handles packets until stopCh is closed.
+func (l *Listener) Run(stopCh <-chan struct{}) error {
+	fd, err := l.open()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	klog.Infof("listening for policy verdicts on NFLOG group %d", l.group)
+	buf := make([]byte, receiveBufferSize)
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			switch {
+			case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+				continue
+			case errors.Is(err, unix.ENOBUFS):
+				// the kernel dropped messages since the socket buffer was full
+				klog.Warningf("dropped policy verdicts on NFLOG group %d", l.group)
+				continue
+			default:
+				return fmt.Errorf("failed to receive from NFLOG group %d: %w", l.group, err)
+			}
+		}
+
+		packets, err := parseMessages(buf[:n])
+		if err != nil {
+			klog.Errorf("failed to parse NFLOG messages. err: %s", err.Error())
+		}
+		l.handle(packets)
+	}
+}
+
+// open binds a netfilter netlink socket to the group, copying enough of each packet to parse its flow.
+func (l *Listener) open() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create netfilter netlink socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to bind netfilter netlink socket: %w", err)
+	}
+	tv := unix.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to set receive timeout on netfilter netlink socket: %w", err)
+	}
+
+	// binding the address families is required before Linux 3.17 and a no-op since
+	for _, family := range []uint8{afInet, afInet6} {
+		if err := l.request(fd, configMessage(l.nextSeq(), family, 0, cmdAttribute(nfulnlCfgCmdPfBind))); err != nil {
+			klog.Infof("ignoring failure to bind address family %d to nfnetlink_log. err: %s", family, err.Error())
+		}
+	}
+	if err := l.request(fd, configMessage(l.nextSeq(), unix.AF_UNSPEC, l.group, cmdAttribute(nfulnlCfgCmdBind))); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to bind NFLOG group %d: %w", l.group, err)
+	}
+	if err := l.request(fd, configMessage(l.nextSeq(), unix.AF_UNSPEC, l.group, copyPacketAttribute())); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to set copy mode of NFLOG group %d: %w", l.group, err)
+	}
+	return fd, nil
+}
+
+func (l *Listener) nextSeq() uint32 {
+	l.seq++
+	return l.seq
+}
+
+// request sends a config message and waits for its ACK
+func (l *Listener) request(fd int, msg []byte) error {
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send netlink request: %w", err)
+	}
+	buf := make([]byte, unix.Getpagesize())
+	n, _, err := unix.Recvfrom(fd, buf, 0)
+	if err != nil {
+		return fmt.Errorf("failed to receive netlink ACK: %w", err)
+	}
+	_, err = parseMessages(buf[:n])
+	return err
+}
+
+// handle logs and counts the packets of audited policies. Packets from other NFLOG rules on the group are ignored.
+func (l *Listener) handle(packets []*packet) {
+	for _, p := range packets {
+		verdict, tag, ok := policies.ParseAuditPrefix(p.prefix)
+		if !ok {
+			continue
+		}
+		policy, ok := l.resolver.AuditedPolicy(tag)
+		if !ok {
+			// the policy was removed after the packet was logged
+			policy = policies.AuditedPolicy{PolicyKey: tag}
+		}
+
+		metrics.IncPolicyVerdicts(policy.PolicyKey, policy.Namespace, string(verdict))
+		keysAndValues := []interface{}{"policy", policy.PolicyKey, "namespace", policy.Namespace, "verdict", verdict}
+		if f := parseFlow(p.family, p.payload); f != nil {
+			keysAndValues = append(keysAndValues, "protocol", f.protocol, "src", f.source(), "dst", f.destination())
+		}
+		klog.InfoS("policy verdict", keysAndValues...)
+	}
+}
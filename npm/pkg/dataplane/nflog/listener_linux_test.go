@@ -0,0 +1,49 @@
+package nflog
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver map[string]policies.AuditedPolicy
+
+func (r fakeResolver) AuditedPolicy(tag string) (policies.AuditedPolicy, bool) {
+	policy, ok := r[tag]
+	return policy, ok
+}
+
+func TestHandle(t *testing.T) {
+	metrics.ReinitializeAll()
+
+	resolver := fakeResolver{
+		"azure-acl-x-allow-web": {PolicyKey: "x/allow-web", Namespace: "x"},
+	}
+	l := NewListener(100, resolver)
+
+	payload := tcpPayload("10.0.0.1", "10.0.0.2", 40000, 80)
+	datagram := packetMessage(afInet, "ALLOW:azure-acl-x-allow-web", payload)
+	datagram = append(datagram, packetMessage(afInet, "DEFAULT-DENY:azure-acl-x-allow-web", payload)...)
+	datagram = append(datagram, packetMessage(afInet, "DEFAULT-DENY:azure-acl-x-allow-web", payload)...)
+	// the policy was removed
+	datagram = append(datagram, packetMessage(afInet, "DROP:azure-acl-AdminNetworkPolicy-old", payload)...)
+	// another NFLOG rule on the group
+	datagram = append(datagram, packetMessage(afInet, "some other prefix", payload)...)
+	packets, err := parseMessages(datagram)
+	require.NoError(t, err)
+	l.handle(packets)
+
+	count, err := metrics.TotalPolicyVerdicts("x/allow-web", "x", string(policies.AuditAllow))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	count, err = metrics.TotalPolicyVerdicts("x/allow-web", "x", string(policies.AuditDefaultDeny))
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = metrics.TotalPolicyVerdicts("azure-acl-AdminNetworkPolicy-old", "", string(policies.AuditDrop))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
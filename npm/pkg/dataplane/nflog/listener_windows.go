@@ -0,0 +1,25 @@
+package nflog
+
+import (
+	"errors"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+)
+
+var errNotSupported = errors.New("policy verdict logging is only supported in Linux")
+
+// PolicyResolver returns the audited policy for the tag of an NFLOG prefix.
+type PolicyResolver interface {
+	AuditedPolicy(tag string) (policies.AuditedPolicy, bool)
+}
+
+// Listener isn't supported in Windows.
+type Listener struct{}
+
+func NewListener(_ int, _ PolicyResolver) *Listener {
+	return &Listener{}
+}
+
+func (l *Listener) Run(_ <-chan struct{}) error {
+	return errNotSupported
+}
@@ -0,0 +1,239 @@
+// Package nflog reads the packets which the NFLOG rules of audited policies send to an nfnetlink_log group.
+package nflog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// netlink and nfnetlink_log constants from linux/netlink.h, linux/netfilter/nfnetlink.h and linux/netfilter/nfnetlink_log.h
+const (
+	nlmsgHeaderLen = 16
+	nlmsgError     = 2
+	nlmsgDone      = 3
+	nlmFRequest    = 0x1
+	nlmFAck        = 0x4
+
+	nfgenmsgLen    = 4
+	nfnetlinkV0    = 0
+	nfnlSubsysUlog = 4
+
+	nfulnlMsgPacket = 0
+	nfulnlMsgConfig = 1
+
+	nfulaCfgCmd  = 1
+	nfulaCfgMode = 2
+	nfulaPayload = 9
+	nfulaPrefix  = 10
+
+	nfulnlCfgCmdBind   = 1
+	nfulnlCfgCmdPfBind = 3
+	nfulnlCopyPacket   = 2
+
+	nlaHeaderLen = 4
+	nlaTypeMask  = 0x3fff
+
+	afInet  = 2
+	afInet6 = 10
+
+	// enough of the payload for the IP and transport headers
+	copyRange = 128
+)
+
+var (
+	errTruncatedMessage   = errors.New("truncated netlink message")
+	errTruncatedAttribute = errors.New("truncated netlink attribute")
+)
+
+// packet is an NFLOG packet message.
+type packet struct {
+	family  uint8
+	prefix  string
+	payload []byte
+}
+
+// align rounds up to the 4 byte alignment of netlink messages and attributes
+func align(length int) int {
+	return (length + 3) &^ 3
+}
+
+// configMessage is an NFULNL_MSG_CONFIG request for the family and group.
+func configMessage(seq uint32, family uint8, group uint16, attributes ...[]byte) []byte {
+	body := make([]byte, nfgenmsgLen)
+	body[0] = family
+	body[1] = nfnetlinkV0
+	binary.BigEndian.PutUint16(body[2:], group)
+	for _, attribute := range attributes {
+		body = append(body, attribute...)
+	}
+
+	msg := make([]byte, nlmsgHeaderLen, nlmsgHeaderLen+len(body))
+	binary.NativeEndian.PutUint32(msg[0:], uint32(nlmsgHeaderLen+len(body)))
+	binary.NativeEndian.PutUint16(msg[4:], nfnlSubsysUlog<<8|nfulnlMsgConfig)
+	binary.NativeEndian.PutUint16(msg[6:], nlmFRequest|nlmFAck)
+	binary.NativeEndian.PutUint32(msg[8:], seq)
+	return append(msg, body...)
+}
+
+func attribute(attrType uint16, value []byte) []byte {
+	attr := make([]byte, align(nlaHeaderLen+len(value)))
+	binary.NativeEndian.PutUint16(attr[0:], uint16(nlaHeaderLen+len(value)))
+	binary.NativeEndian.PutUint16(attr[2:], attrType)
+	copy(attr[nlaHeaderLen:], value)
+	return attr
+}
+
+func cmdAttribute(cmd uint8) []byte {
+	return attribute(nfulaCfgCmd, []byte{cmd})
+}
+
+func copyPacketAttribute() []byte {
+	// struct nfulnl_msg_config_mode
+	mode := make([]byte, 6)
+	binary.BigEndian.PutUint32(mode[0:], copyRange)
+	mode[4] = nfulnlCopyPacket
+	return attribute(nfulaCfgMode, mode)
+}
+
+// parseMessages returns the NFLOG packets in a netlink datagram.
+// An error returned for an NLMSG_ERROR message is nil for an ACK.
+func parseMessages(b []byte) ([]*packet, error) {
+	packets := make([]*packet, 0)
+	for len(b) >= nlmsgHeaderLen {
+		msgLen := int(binary.NativeEndian.Uint32(b[0:]))
+		msgType := binary.NativeEndian.Uint16(b[4:])
+		if msgLen < nlmsgHeaderLen || msgLen > len(b) {
+			return packets, errTruncatedMessage
+		}
+		body := b[nlmsgHeaderLen:msgLen]
+
+		switch {
+		case msgType == nlmsgError:
+			if len(body) < 4 {
+				return packets, errTruncatedMessage
+			}
+			if errno := int32(binary.NativeEndian.Uint32(body)); errno != 0 {
+				return packets, fmt.Errorf("netlink request failed with errno %d", -errno)
+			}
+		case msgType == nlmsgDone:
+			return packets, nil
+		case msgType == nfnlSubsysUlog<<8|nfulnlMsgPacket:
+			p, err := parsePacket(body)
+			if err != nil {
+				return packets, err
+			}
+			packets = append(packets, p)
+		}
+
+		if align(msgLen) >= len(b) {
+			break
+		}
+		b = b[align(msgLen):]
+	}
+	return packets, nil
+}
+
+func parsePacket(body []byte) (*packet, error) {
+	if len(body) < nfgenmsgLen {
+		return nil, errTruncatedMessage
+	}
+	p := &packet{family: body[0]}
+	attributes := body[nfgenmsgLen:]
+	for len(attributes) >= nlaHeaderLen {
+		attrLen := int(binary.NativeEndian.Uint16(attributes[0:]))
+		attrType := binary.NativeEndian.Uint16(attributes[2:]) & nlaTypeMask
+		if attrLen < nlaHeaderLen || attrLen > len(attributes) {
+			return nil, errTruncatedAttribute
+		}
+		value := attributes[nlaHeaderLen:attrLen]
+
+		switch attrType {
+		case nfulaPrefix:
+			p.prefix = strings.TrimRight(string(value), "\x00")
+		case nfulaPayload:
+			p.payload = value
+		}
+
+		if align(attrLen) >= len(attributes) {
+			break
+		}
+		attributes = attributes[align(attrLen):]
+	}
+	return p, nil
+}
+
+// flow is the 5-tuple of a logged packet. Ports are zero for protocols without ports.
+type flow struct {
+	protocol string
+	src      net.IP
+	dst      net.IP
+	srcPort  uint16
+	dstPort  uint16
+}
+
+func (f *flow) source() string {
+	return hostPort(f.src, f.srcPort)
+}
+
+func (f *flow) destination() string {
+	return hostPort(f.dst, f.dstPort)
+}
+
+func hostPort(ip net.IP, port uint16) string {
+	if port == 0 {
+		return ip.String()
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
+
+// parseFlow returns the flow of an IPv4 or IPv6 payload, or nil if the payload is too short.
+func parseFlow(family uint8, payload []byte) *flow {
+	var f flow
+	var protocol uint8
+	var transport []byte
+	switch family {
+	case afInet:
+		if len(payload) < 20 {
+			return nil
+		}
+		headerLen := int(payload[0]&0x0f) * 4
+		protocol = payload[9]
+		f.src = net.IP(payload[12:16])
+		f.dst = net.IP(payload[16:20])
+		if headerLen <= len(payload) {
+			transport = payload[headerLen:]
+		}
+	case afInet6:
+		if len(payload) < 40 {
+			return nil
+		}
+		// extension headers aren't followed
+		protocol = payload[6]
+		f.src = net.IP(payload[8:24])
+		f.dst = net.IP(payload[24:40])
+		transport = payload[40:]
+	default:
+		return nil
+	}
+
+	switch protocol {
+	case 6:
+		f.protocol = "TCP"
+	case 17:
+		f.protocol = "UDP"
+	case 132:
+		f.protocol = "SCTP"
+	default:
+		f.protocol = strconv.Itoa(int(protocol))
+		return &f
+	}
+	if len(transport) >= 4 {
+		f.srcPort = binary.BigEndian.Uint16(transport[0:])
+		f.dstPort = binary.BigEndian.Uint16(transport[2:])
+	}
+	return &f
+}
@@ -0,0 +1,113 @@
+package nflog
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// packetMessage is an NFULNL_MSG_PACKET message like the kernel sends
+func packetMessage(family uint8, prefix string, payload []byte) []byte {
+	body := []byte{family, nfnetlinkV0, 0, 0}
+	// the kernel sends other attributes like the packet header
+	body = append(body, attribute(1, []byte{0x08, 0x00, 0x03, 0x00})...)
+	body = append(body, attribute(nfulaPrefix, append([]byte(prefix), 0))...)
+	body = append(body, attribute(nfulaPayload, payload)...)
+
+	msg := make([]byte, nlmsgHeaderLen)
+	binary.NativeEndian.PutUint32(msg[0:], uint32(nlmsgHeaderLen+len(body)))
+	binary.NativeEndian.PutUint16(msg[4:], nfnlSubsysUlog<<8|nfulnlMsgPacket)
+	return append(msg, body...)
+}
+
+func errorMessage(errno int32) []byte {
+	msg := make([]byte, nlmsgHeaderLen+4)
+	binary.NativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:], nlmsgError)
+	binary.NativeEndian.PutUint32(msg[nlmsgHeaderLen:], uint32(errno))
+	return msg
+}
+
+// tcpPayload is an IPv4 header without options followed by the TCP ports
+func tcpPayload(src, dst string, srcPort, dstPort uint16) []byte {
+	payload := make([]byte, 24)
+	payload[0] = 0x45
+	payload[9] = 6
+	copy(payload[12:16], net.ParseIP(src).To4())
+	copy(payload[16:20], net.ParseIP(dst).To4())
+	binary.BigEndian.PutUint16(payload[20:], srcPort)
+	binary.BigEndian.PutUint16(payload[22:], dstPort)
+	return payload
+}
+
+func TestConfigMessage(t *testing.T) {
+	msg := configMessage(7, afInet, 100, cmdAttribute(nfulnlCfgCmdBind))
+	require.Len(t, msg, nlmsgHeaderLen+nfgenmsgLen+8)
+	require.Equal(t, uint32(len(msg)), binary.NativeEndian.Uint32(msg[0:]))
+	require.Equal(t, uint16(nfnlSubsysUlog<<8|nfulnlMsgConfig), binary.NativeEndian.Uint16(msg[4:]))
+	require.Equal(t, uint16(nlmFRequest|nlmFAck), binary.NativeEndian.Uint16(msg[6:]))
+	require.Equal(t, uint32(7), binary.NativeEndian.Uint32(msg[8:]))
+	// nfgenmsg has the group in network byte order
+	require.Equal(t, []byte{afInet, nfnetlinkV0, 0, 100}, msg[nlmsgHeaderLen:nlmsgHeaderLen+nfgenmsgLen])
+	// the command attribute is padded to 4 bytes
+	require.Equal(t, uint16(5), binary.NativeEndian.Uint16(msg[nlmsgHeaderLen+nfgenmsgLen:]))
+	require.Equal(t, uint8(nfulnlCfgCmdBind), msg[nlmsgHeaderLen+nfgenmsgLen+nlaHeaderLen])
+}
+
+func TestParseMessages(t *testing.T) {
+	payload := tcpPayload("10.0.0.1", "10.0.0.2", 40000, 80)
+	datagram := packetMessage(afInet, "ALLOW:azure-acl-x-allow-web", payload)
+	datagram = append(datagram, packetMessage(afInet, "DEFAULT-DENY:azure-acl-x-allow-web", payload)...)
+
+	packets, err := parseMessages(datagram)
+	require.NoError(t, err)
+	require.Len(t, packets, 2)
+	require.Equal(t, "ALLOW:azure-acl-x-allow-web", packets[0].prefix)
+	require.Equal(t, "DEFAULT-DENY:azure-acl-x-allow-web", packets[1].prefix)
+	require.Equal(t, uint8(afInet), packets[1].family)
+	require.Equal(t, payload, packets[1].payload)
+
+	// an ACK has no packets
+	packets, err = parseMessages(errorMessage(0))
+	require.NoError(t, err)
+	require.Empty(t, packets)
+
+	_, err = parseMessages(errorMessage(-1))
+	require.Error(t, err)
+
+	_, err = parseMessages(datagram[:len(datagram)-1])
+	require.ErrorIs(t, err, errTruncatedMessage)
+}
+
+func TestParseFlow(t *testing.T) {
+	f := parseFlow(afInet, tcpPayload("10.0.0.1", "10.0.0.2", 40000, 80))
+	require.NotNil(t, f)
+	require.Equal(t, "TCP", f.protocol)
+	require.Equal(t, "10.0.0.1:40000", f.source())
+	require.Equal(t, "10.0.0.2:80", f.destination())
+
+	ipv6 := make([]byte, 48)
+	ipv6[0] = 0x60
+	ipv6[6] = 17
+	copy(ipv6[8:24], net.ParseIP("fd00::1"))
+	copy(ipv6[24:40], net.ParseIP("fd00::2"))
+	binary.BigEndian.PutUint16(ipv6[40:], 5353)
+	binary.BigEndian.PutUint16(ipv6[42:], 53)
+	f = parseFlow(afInet6, ipv6)
+	require.NotNil(t, f)
+	require.Equal(t, "UDP", f.protocol)
+	require.Equal(t, "[fd00::1]:5353", f.source())
+	require.Equal(t, "[fd00::2]:53", f.destination())
+
+	// ICMP has no ports
+	icmp := tcpPayload("10.0.0.1", "10.0.0.2", 0, 0)
+	icmp[9] = 1
+	f = parseFlow(afInet, icmp)
+	require.NotNil(t, f)
+	require.Equal(t, "1", f.protocol)
+	require.Equal(t, "10.0.0.2", f.destination())
+
+	require.Nil(t, parseFlow(afInet, []byte{0x45}))
+}
@@ -0,0 +1,83 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+var (
+	ipsetCreateBytes = []byte("create ")
+	ipsetAddBytes    = []byte("add ")
+)
+
+// IPSet is a set in ipset save output.
+type IPSet struct {
+	Name string
+	// Type is the set type, e.g. hash:net or list:set
+	Type string
+	// Family is inet or inet6 for hash sets, and empty for lists
+	Family  string
+	Members []string
+}
+
+// IPSets creates a map of set name and set object from ipset save output.
+// Lines other than create and add lines are skipped, as are add lines for sets which weren't created beforehand.
+func IPSets(ipsetSave []byte) map[string]*IPSet {
+	sets := make(map[string]*IPSet)
+	curReadIndex := 0
+	for curReadIndex < len(ipsetSave) {
+		line, nextReadIndex := Line(curReadIndex, ipsetSave)
+		curReadIndex = nextReadIndex
+		line = bytes.TrimRight(line, "\n")
+		switch {
+		case bytes.HasPrefix(line, ipsetCreateBytes):
+			fields := strings.Fields(string(line[len(ipsetCreateBytes):]))
+			if len(fields) < 2 {
+				continue
+			}
+			set := &IPSet{Name: fields[0], Type: fields[1], Members: make([]string, 0)}
+			for i := 2; i+1 < len(fields); i++ {
+				if fields[i] == "family" {
+					set.Family = fields[i+1]
+					break
+				}
+			}
+			sets[set.Name] = set
+		case bytes.HasPrefix(line, ipsetAddBytes):
+			// members can have more than one field, e.g. 10.0.0.0/24 nomatch
+			fields := strings.SplitN(string(line[len(ipsetAddBytes):]), " ", 2)
+			if len(fields) < 2 {
+				continue
+			}
+			set, ok := sets[fields[0]]
+			if !ok {
+				continue
+			}
+			set.Members = append(set.Members, fields[1])
+		}
+	}
+	return sets
+}
+
+// IPSetSave creates a map of set name and set object by calling ipset save within node.
+func IPSetSave() (map[string]*IPSet, error) {
+	output, err := exec.Command(util.Ipset, util.IpsetSaveFlag).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return IPSets(output), nil
+}
+
+// IPSetSaveFile creates a map of set name and set object by reading from an ipset save file.
+func IPSetSaveFile(ipsetSaveFile string) (map[string]*IPSet, error) {
+	output, err := os.ReadFile(ipsetSaveFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return IPSets(output), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/common"
@@ -26,8 +27,17 @@ var (
 	MinOptionLength = 2
 )
 
+const (
+	setXMarkFlag      = "--set-xmark"
+	defaultMarkMask   = "/0xffffffff"
+	limitBurstFlag    = "--limit-burst"
+	defaultLimitBurst = "5"
+)
+
 type IPTablesParser struct {
 	IOShim *common.IOShim
+	// IptablesSave is the save command to run, e.g. util.Ip6tablesSave. It defaults to util.IptablesSave.
+	IptablesSave string
 }
 
 // runCommand returns (stdout, stderr, error)
@@ -54,7 +64,11 @@ func (i *IPTablesParser) runCommand(command string, args ...string) ([]byte, err
 func (i *IPTablesParser) Iptables(tableName string) (*NPMIPtable.Table, error) {
 	cmdArgs := []string{util.IptablesTableFlag, string(tableName)}
 
-	output, err := i.runCommand(util.IptablesSave, cmdArgs...)
+	iptablesSave := i.IptablesSave
+	if iptablesSave == "" {
+		iptablesSave = util.IptablesSave
+	}
+	output, err := i.runCommand(iptablesSave, cmdArgs...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,10 +79,19 @@ func (i *IPTablesParser) Iptables(tableName string) (*NPMIPtable.Table, error) {
 
 // Iptables creates a Go object from specified iptable by calling iptables-save within node.
 func Iptables(tableName string) (*NPMIPtable.Table, error) {
+	return iptablesSave(util.IptablesSave, tableName)
+}
+
+// Ip6tables creates a Go object from specified ip6table by calling ip6tables-save within node.
+func Ip6tables(tableName string) (*NPMIPtable.Table, error) { //nolint (avoid warning to capitalize this p)
+	return iptablesSave(util.Ip6tablesSave, tableName)
+}
+
+func iptablesSave(saveCommand, tableName string) (*NPMIPtable.Table, error) {
 	iptableBuffer := bytes.NewBuffer(nil)
 	// TODO: need to get iptable's lock
 	cmdArgs := []string{util.IptablesTableFlag, string(tableName)}
-	cmd := exec.Command(util.IptablesSave, cmdArgs...) //nolint:gosec // client usage is filter table only
+	cmd := exec.Command(saveCommand, cmdArgs...) //nolint:gosec // client usage is filter table only
 
 	cmd.Stdout = iptableBuffer
 	stderrBuffer := bytes.NewBuffer(nil)
@@ -99,6 +122,111 @@ func IptablesFile(tableName, iptableSaveFile string) (*NPMIPtable.Table, error)
 	return &NPMIPtable.Table{Name: tableName, Chains: chains}, nil
 }
 
+// IptablesBytes creates a Go object from specified iptable in iptables-save output.
+func IptablesBytes(tableName string, iptableBuffer []byte) *NPMIPtable.Table {
+	chains := parseIptablesChainObject(tableName, iptableBuffer)
+	return &NPMIPtable.Table{Name: tableName, Chains: chains}
+}
+
+// SaveSpec writes a rule spec for iptables or iptables-restore like iptables-save would:
+// the protocol is lowercase, ports are matched by the protocol's module, MARK targets set an xmark,
+// and rate limits are per sec with the default burst.
+func SaveSpec(spec string) string {
+	fields := strings.Fields(spec)
+	saveFields := make([]string, 0, len(fields))
+	protocol := ""
+	inModule := false
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch field {
+		case util.IptablesProtFlag:
+			inModule = false
+			saveFields = append(saveFields, field)
+			if i+1 < len(fields) {
+				i++
+				protocol = strings.ToLower(fields[i])
+				saveFields = append(saveFields, protocol)
+			}
+			continue
+		case util.IptablesModuleFlag:
+			inModule = true
+		case util.IptablesJumpFlag:
+			inModule = false
+		case util.IptablesDstPortFlag, util.IptablesSrcPortFlag:
+			if !inModule && protocol != "" {
+				saveFields = append(saveFields, util.IptablesModuleFlag, protocol)
+				inModule = true
+			}
+		case util.IptablesSetMarkFlag:
+			saveFields = append(saveFields, setXMarkFlag)
+			if i+1 < len(fields) {
+				i++
+				mark := fields[i]
+				if !strings.Contains(mark, "/") {
+					mark += defaultMarkMask
+				}
+				saveFields = append(saveFields, mark)
+			}
+			continue
+		case util.IptablesLimitFlag:
+			saveFields = append(saveFields, field)
+			if i+1 < len(fields) {
+				i++
+				saveFields = append(saveFields, strings.Replace(strings.Replace(fields[i], "/second", "/sec", 1), "/minute", "/min", 1))
+			}
+			if !strings.Contains(spec, limitBurstFlag) {
+				saveFields = append(saveFields, limitBurstFlag, defaultLimitBurst)
+			}
+			continue
+		}
+		saveFields = append(saveFields, field)
+	}
+	return strings.Join(saveFields, " ")
+}
+
+// RuleFromSpec creates an iptable rule object from a rule spec for iptables or iptables-restore, with the chain name excluded.
+func RuleFromSpec(spec string) *NPMIPtable.Rule {
+	return parseRuleFromLine([]byte(SaveSpec(spec)))
+}
+
+// RuleString writes a rule object with sorted options and unquoted values, so equal rules have equal strings.
+func RuleString(rule *NPMIPtable.Rule) string {
+	fields := make([]string, 0)
+	if rule.Protocol != "" {
+		fields = append(fields, util.IptablesProtFlag, strings.ToLower(rule.Protocol))
+	}
+	for _, module := range rule.Modules {
+		fields = append(fields, util.IptablesModuleFlag, module.Verb)
+		fields = append(fields, optionFields(module.OptionValueMap)...)
+	}
+	if rule.Target != nil {
+		fields = append(fields, util.IptablesJumpFlag, rule.Target.Name)
+		fields = append(fields, optionFields(rule.Target.OptionValueMap)...)
+	}
+	return strings.Join(fields, " ")
+}
+
+func optionFields(optionValueMap map[string][]string) []string {
+	options := make([]string, 0, len(optionValueMap))
+	for option := range optionValueMap {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	fields := make([]string, 0)
+	for _, option := range options {
+		if strings.HasPrefix(option, util.NegationPrefix) {
+			fields = append(fields, util.IptablesNotFlag, "--"+strings.TrimPrefix(option, util.NegationPrefix))
+		} else {
+			fields = append(fields, "--"+option)
+		}
+		for _, value := range optionValueMap[option] {
+			fields = append(fields, strings.Trim(value, `"`))
+		}
+	}
+	return fields
+}
+
 // parseIptablesChainObject creates a map of iptable chain name and iptable chain object.
 // There are some unimplemented flags but they should not affect the current desired functionalities.
 func parseIptablesChainObject(tableName string, iptableBuffer []byte) map[string]*NPMIPtable.Chain {
@@ -43,6 +43,24 @@ func TestParseIptablesObject(t *testing.T) {
 	}
 }
 
+func TestParseIptablesObjectBytes(t *testing.T) {
+	iptablesSave := strings.Join([]string{
+		"*filter",
+		":AZURE-NPM - [0:0]",
+		"-A AZURE-NPM -m mark --mark 0x3000 -j AZURE-NPM-ACCEPT",
+		"COMMIT",
+		"",
+	}, "\n")
+
+	table := IptablesBytes(util.IptablesFilterTable, []byte(iptablesSave))
+	if len(table.Chains) != 1 || len(table.Chains["AZURE-NPM"].Rules) != 1 {
+		t.Fatalf("expected one chain with one rule, got %v", table)
+	}
+	if target := table.Chains["AZURE-NPM"].Rules[0].Target.Name; target != "AZURE-NPM-ACCEPT" {
+		t.Fatalf("expected target AZURE-NPM-ACCEPT, got %s", target)
+	}
+}
+
 func TestParseLine(t *testing.T) {
 	type test struct {
 		input    string
@@ -247,3 +265,64 @@ func TestParseModule(t *testing.T) {
 		})
 	}
 }
+
+func TestRuleFromSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		saveLine string
+	}{
+		{
+			name:     "port and comment",
+			spec:     "-j AZURE-NPM-INGRESS-ALLOW-MARK -p TCP --dport 8000 -m set --match-set azure-npm-3260345197 src -m comment --comment ALLOW-FROM-x",
+			saveLine: "-p tcp -m tcp --dport 8000 -m set --match-set azure-npm-3260345197 src -m comment --comment ALLOW-FROM-x -j AZURE-NPM-INGRESS-ALLOW-MARK",
+		},
+		{
+			name:     "mark without mask",
+			spec:     "-j MARK --set-mark 0x4000 -m comment --comment DROP-ALL",
+			saveLine: `-m comment --comment "DROP-ALL" -j MARK --set-xmark 0x4000/0xffffffff`,
+		},
+		{
+			name:     "negated mark",
+			spec:     "-j AZURE-NPM-ANP-INGRESS-1 -m mark ! --mark 0x100/0x100",
+			saveLine: "-m mark ! --mark 0x100/0x100 -j AZURE-NPM-ANP-INGRESS-1",
+		},
+		{
+			name:     "rate limit",
+			spec:     "-j NFLOG --nflog-group 5 --nflog-prefix NPM-ALLOW-1 -m comment --comment ALLOW-ALL -m limit --limit 10/second",
+			saveLine: `-m comment --comment ALLOW-ALL -m limit --limit 10/sec --limit-burst 5 -j NFLOG --nflog-prefix "NPM-ALLOW-1" --nflog-group 5`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := RuleString(RuleFromSpec(tt.spec))
+			want := RuleString(parseRuleFromLine([]byte(tt.saveLine)))
+			if got != want {
+				t.Errorf("got '%s', expected '%s'", got, want)
+			}
+		})
+	}
+}
+
+func TestIPSets(t *testing.T) {
+	ipsetSave := strings.Join([]string{
+		"add azure-npm-1 10.0.0.1",
+		"create azure-npm-1 hash:net family inet hashsize 1024 maxelem 65536",
+		"add azure-npm-1 10.0.0.1",
+		"add azure-npm-1 10.0.0.0/28 nomatch",
+		"create azure-npm-2 list:set size 8",
+		"add azure-npm-2 azure-npm-1",
+		"create azure-npm-3 hash:ip,port family inet6 hashsize 1024 maxelem 65536",
+		"",
+	}, "\n")
+
+	expected := map[string]*IPSet{
+		"azure-npm-1": {Name: "azure-npm-1", Type: "hash:net", Family: "inet", Members: []string{"10.0.0.1", "10.0.0.0/28 nomatch"}},
+		"azure-npm-2": {Name: "azure-npm-2", Type: "list:set", Members: []string{"azure-npm-1"}},
+		"azure-npm-3": {Name: "azure-npm-3", Type: "hash:ip,port", Family: "inet6", Members: []string{}},
+	}
+	if sets := IPSets([]byte(ipsetSave)); !reflect.DeepEqual(expected, sets) {
+		t.Errorf("got '%+v', expected '%+v'", sets, expected)
+	}
+}
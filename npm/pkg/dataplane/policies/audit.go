@@ -0,0 +1,136 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+// AuditVerdict is the verdict logged for a packet which matched an ACL of an audited policy.
+type AuditVerdict string
+
+const (
+	AuditAllow AuditVerdict = "ALLOW"
+	// AuditDrop is logged for the Deny rules of AdminNetworkPolicies and BaselineAdminNetworkPolicies
+	AuditDrop AuditVerdict = "DROP"
+	// AuditDefaultDeny is logged for the default deny of a NetworkPolicy
+	AuditDefaultDeny AuditVerdict = "DEFAULT-DENY"
+	AuditPass        AuditVerdict = "PASS"
+
+	// AllAuditedNamespaces audits the policies of every namespace, as well as the admin tiers
+	AllAuditedNamespaces = "*"
+
+	auditPrefixSeparator = ":"
+	// the kernel truncates NFLOG prefixes to 63 characters
+	maxAuditPrefixLength = 63
+	// the longest verdict and the separator
+	maxAuditTagLength = maxAuditPrefixLength - len(AuditDefaultDeny) - len(auditPrefixSeparator)
+)
+
+// AuditCfg logs the verdicts of the selected policies to an NFLOG group. It only affects Linux.
+type AuditCfg struct {
+	// Namespaces whose NetworkPolicies are audited. AllAuditedNamespaces audits every policy.
+	Namespaces []string
+	// PolicyKeys of individually audited policies e.g. "namespace/name" or "AdminNetworkPolicy/name"
+	PolicyKeys []string
+	// NFLOGGroup is the netlink group that the in-daemon listener reads
+	NFLOGGroup int
+	// RateLimitPerSecond is the maximum rate of logged packets for each ACL
+	RateLimitPerSecond int
+}
+
+// Enabled is true if any policy is audited.
+func (cfg *AuditCfg) Enabled() bool {
+	return cfg != nil && (len(cfg.Namespaces) > 0 || len(cfg.PolicyKeys) > 0)
+}
+
+func (cfg *AuditCfg) audits(networkPolicy *NPMNetworkPolicy) bool {
+	if util.IsWindowsDP() || !cfg.Enabled() {
+		return false
+	}
+	for _, ns := range cfg.Namespaces {
+		if ns == AllAuditedNamespaces || (networkPolicy.Tier == NetworkPolicyTier && ns == networkPolicy.Namespace) {
+			return true
+		}
+	}
+	for _, policyKey := range cfg.PolicyKeys {
+		if policyKey == networkPolicy.PolicyKey {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditedPolicy is the policy which an NFLOG prefix is tagged for.
+type AuditedPolicy struct {
+	PolicyKey string
+	// Namespace is empty for the admin tiers
+	Namespace string
+}
+
+// auditedPolicies maps the audit tags of the applied policies to the policies.
+// It's read by the NFLOG listener while policies are added and removed.
+type auditedPolicies struct {
+	sync.RWMutex
+	tags map[string]AuditedPolicy
+}
+
+func newAuditedPolicies() *auditedPolicies {
+	return &auditedPolicies{
+		tags: make(map[string]AuditedPolicy),
+	}
+}
+
+func (a *auditedPolicies) add(networkPolicy *NPMNetworkPolicy) {
+	a.Lock()
+	defer a.Unlock()
+	a.tags[networkPolicy.auditTag()] = AuditedPolicy{
+		PolicyKey: networkPolicy.PolicyKey,
+		Namespace: networkPolicy.Namespace,
+	}
+}
+
+func (a *auditedPolicies) remove(networkPolicy *NPMNetworkPolicy) {
+	a.Lock()
+	defer a.Unlock()
+	delete(a.tags, networkPolicy.auditTag())
+}
+
+// AuditedPolicy returns the applied policy for the tag of an NFLOG prefix.
+func (pMgr *PolicyManager) AuditedPolicy(tag string) (AuditedPolicy, bool) {
+	pMgr.auditedPolicies.RLock()
+	defer pMgr.auditedPolicies.RUnlock()
+	policy, ok := pMgr.auditedPolicies.tags[tag]
+	return policy, ok
+}
+
+// auditTag is the ACLPolicyID, shortened with a hash if the NFLOG prefix would be truncated.
+func (networkPolicy *NPMNetworkPolicy) auditTag() string {
+	if len(networkPolicy.ACLPolicyID) <= maxAuditTagLength {
+		return networkPolicy.ACLPolicyID
+	}
+	hash := util.Hash(networkPolicy.ACLPolicyID)
+	return fmt.Sprintf("%s-%s", networkPolicy.ACLPolicyID[:maxAuditTagLength-len(hash)-1], hash)
+}
+
+// auditPrefix is the NFLOG prefix e.g. "DEFAULT-DENY:azure-acl-x-deny-all"
+func auditPrefix(verdict AuditVerdict, tag string) string {
+	return string(verdict) + auditPrefixSeparator + tag
+}
+
+// ParseAuditPrefix returns the verdict and tag of an NFLOG prefix written for an audited policy.
+func ParseAuditPrefix(prefix string) (verdict AuditVerdict, tag string, ok bool) {
+	v, tag, found := strings.Cut(prefix, auditPrefixSeparator)
+	if !found || tag == "" {
+		return "", "", false
+	}
+	verdict = AuditVerdict(v)
+	switch verdict {
+	case AuditAllow, AuditDrop, AuditDefaultDeny, AuditPass:
+		return verdict, tag, true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,60 @@
+package policies
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditCfgAudits(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("audit mode is only supported in Linux")
+	}
+
+	cfg := &AuditCfg{
+		Namespaces: []string{"x"},
+		PolicyKeys: []string{"y/audited"},
+	}
+	require.True(t, cfg.audits(NewNPMNetworkPolicy("any", "x")))
+	require.True(t, cfg.audits(NewNPMNetworkPolicy("audited", "y")))
+	require.False(t, cfg.audits(NewNPMNetworkPolicy("other", "y")))
+	require.False(t, cfg.audits(NewAdminNPMNetworkPolicy(AdminTier, "x", 0)), "a namespace doesn't audit the admin tiers")
+
+	cfg = &AuditCfg{Namespaces: []string{AllAuditedNamespaces}}
+	require.True(t, cfg.audits(NewNPMNetworkPolicy("any", "z")))
+	require.True(t, cfg.audits(NewAdminNPMNetworkPolicy(BaselineTier, "default", 0)))
+
+	var disabled *AuditCfg
+	require.False(t, disabled.Enabled())
+	require.False(t, disabled.audits(NewNPMNetworkPolicy("any", "x")))
+}
+
+func TestAuditPrefix(t *testing.T) {
+	shortPolicy := NewNPMNetworkPolicy("deny-all", "x")
+	prefix := auditPrefix(AuditDefaultDeny, shortPolicy.auditTag())
+	require.Equal(t, "DEFAULT-DENY:azure-acl-x-deny-all", prefix)
+
+	verdict, tag, ok := ParseAuditPrefix(prefix)
+	require.True(t, ok)
+	require.Equal(t, AuditDefaultDeny, verdict)
+	require.Equal(t, shortPolicy.ACLPolicyID, tag)
+
+	// the tag of a long ACLPolicyID is shortened with a hash so the prefix isn't truncated
+	longPolicy := NewNPMNetworkPolicy(strings.Repeat("a", 63), strings.Repeat("b", 63))
+	otherLongPolicy := NewNPMNetworkPolicy(strings.Repeat("a", 62), strings.Repeat("b", 63))
+	prefix = auditPrefix(AuditDefaultDeny, longPolicy.auditTag())
+	require.Len(t, prefix, maxAuditPrefixLength)
+	require.NotEqual(t, longPolicy.auditTag(), otherLongPolicy.auditTag())
+
+	verdict, tag, ok = ParseAuditPrefix(prefix)
+	require.True(t, ok)
+	require.Equal(t, AuditDefaultDeny, verdict)
+	require.Equal(t, longPolicy.auditTag(), tag)
+
+	for _, invalid := range []string{"", "ALLOW", "ALLOW:", "ACCEPT:azure-acl-x-y", "some other prefix"} {
+		_, _, ok = ParseAuditPrefix(invalid)
+		require.False(t, ok, "prefix %q should be invalid", invalid)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
 	"github.com/Azure/azure-container-networking/npm/util"
 	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
 	"github.com/Azure/azure-container-networking/npm/util/ioutil"
@@ -23,6 +26,8 @@ const (
 
 	// transferred from iptm.go and not sure why this length is important
 	minLineNumberStringLength int = 3
+
+	numBaseChainLines = 9
 )
 
 var (
@@ -33,6 +38,10 @@ var (
 		util.IptablesAzureIngressAllowMarkChain,
 		util.IptablesAzureEgressChain,
 		util.IptablesAzureAcceptChain,
+		util.IptablesAzureAdminIngressChain,
+		util.IptablesAzureAdminEgressChain,
+		util.IptablesAzureBaselineIngressChain,
+		util.IptablesAzureBaselineEgressChain,
 	}
 	// Should not be used directly. Initialized from iptablesAzureChains on first use of isAzureChain().
 	iptablesAzureChainsMap map[string]struct{}
@@ -193,7 +202,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		util.IptablesRestore = util.IptablesRestoreLegacy
 
 		// 0. delete the deprecated jump to deprecated AZURE-NPM in legacy iptables
-		deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
+		deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(ipv4, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 		if deprecatedErrCode == 0 {
 			klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 		} else if deprecatedErr != nil {
@@ -203,7 +212,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		}
 
 		// 0. delete the deprecated jump to current AZURE-NPM in legacy iptables
-		deprecatedErrCode, deprecatedErr = pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
+		deprecatedErrCode, deprecatedErr = pMgr.ignoreErrorsAndRunIPTablesCommand(ipv4, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
 		if deprecatedErrCode == 0 {
 			klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 		} else if deprecatedErr != nil {
@@ -222,7 +231,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		// So flush all the chains and then destroy them
 		var aggregateError error
 		for chain := range currentChains {
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesFlushFlag, chain)
+			errCode, err := pMgr.runIPTablesCommand(ipv4, util.IptablesFlushFlag, chain)
 			if err != nil && errCode != doesNotExistErrorCode {
 				// add to staleChains if it's not one of the iptablesAzureChains
 				pMgr.staleChains.add(chain)
@@ -236,7 +245,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 		}
 
 		for chain := range currentChains {
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesDestroyFlag, chain)
+			errCode, err := pMgr.runIPTablesCommand(ipv4, util.IptablesDestroyFlag, chain)
 			if err != nil && errCode != doesNotExistErrorCode {
 				// add to staleChains if it's not one of the iptablesAzureChains
 				pMgr.staleChains.add(chain)
@@ -263,7 +272,7 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 	klog.Info("cleaning up default iptables")
 
 	// 1. delete the deprecated jump to AZURE-NPM
-	deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
+	deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(ipv4, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 	if deprecatedErrCode == 0 {
 		klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 	} else if deprecatedErr != nil {
@@ -280,17 +289,46 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 	klog.Infof("found %d current chains in the default iptables", len(currentChains))
 
 	// 2. cleanup old NPM chains, and configure base chains and their rules.
-	creator := pMgr.creatorForBootup(currentChains)
-	if err := restore(creator); err != nil {
+	creator := pMgr.creatorForBootup(ipv4, currentChains)
+	if err := restore(ipv4, creator); err != nil {
 		return npmerrors.SimpleErrorWrapper("failed to run iptables-restore for bootup", err)
 	}
 
 	// 3. add/reposition the jump to AZURE-NPM
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
+	if err := pMgr.positionAzureChainJumpRule(ipv4); err != nil {
 		baseErrString := "failed to add/reposition jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error: %s", baseErrString, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err) // we used to ignore this error in v1
 	}
+
+	if pMgr.EnableIPv6 {
+		return pMgr.bootupIPv6()
+	}
+	return nil
+}
+
+// bootupIPv6 is steps 2 and 3 of bootup for ip6tables.
+// There is nothing deprecated to clean up since only the v2 dataplane writes to ip6tables.
+func (pMgr *PolicyManager) bootupIPv6() error {
+	klog.Info("cleaning up default ip6tables")
+
+	currentChains, err := ioutil.AllCurrentAzureIPv6Chains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	if err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to get current ip6tables chains for bootup", err)
+	}
+
+	klog.Infof("found %d current chains in the default ip6tables", len(currentChains))
+
+	creator := pMgr.creatorForBootup(ipv6, currentChains)
+	if err := restore(ipv6, creator); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to run ip6tables-restore for bootup", err)
+	}
+
+	if err := pMgr.positionAzureChainJumpRule(ipv6); err != nil {
+		baseErrString := "failed to add/reposition jump from FORWARD chain to AZURE-NPM chain in ip6tables"
+		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error: %s", baseErrString, err.Error())
+		return npmerrors.SimpleErrorWrapper(baseErrString, err)
+	}
 	return nil
 }
 
@@ -298,10 +336,12 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 // - creates the jump rule from FORWARD chain to AZURE-NPM chain (if it does not exist) and makes sure it's after the jumps to KUBE-FORWARD & KUBE-SERVICES chains (if they exist).
 // - cleans up stale policy chains. It can be forced to stop this process if reconcileManager.forceLock() is called.
 func (pMgr *PolicyManager) reconcile() {
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
-		msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM due to %s", err.Error())
-		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
-		klog.Error(msg)
+	for _, family := range pMgr.families() {
+		if err := pMgr.positionAzureChainJumpRule(family); err != nil {
+			msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM in %s iptables due to %s", family, err.Error())
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+			klog.Error(msg)
+		}
 	}
 
 	pMgr.reconcileManager.Lock()
@@ -320,7 +360,7 @@ func (pMgr *PolicyManager) reconcile() {
 	}
 }
 
-// cleanupChains deletes all the chains in the given list.
+// cleanupChains deletes all the chains in the given list, from ip6tables too in a dual-stack dataplane.
 // If a chain fails to delete and it isn't one of the iptablesAzureChains, then it is added to the staleChains.
 // This is a separate function for with a slice argument so that UTs can have deterministic behavior for ioshim.
 func (pMgr *PolicyManager) cleanupChains(chains []string) error {
@@ -335,15 +375,17 @@ deleteLoop:
 			}
 			break deleteLoop
 		default:
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesDestroyFlag, chain)
-			if err != nil && errCode != doesNotExistErrorCode {
-				// add to staleChains if it's not one of the iptablesAzureChains
-				pMgr.staleChains.add(chain)
-				currentErrString := fmt.Sprintf("failed to clean up chain %s with err [%v]", chain, err)
-				if aggregateError == nil {
-					aggregateError = npmerrors.SimpleError(currentErrString)
-				} else {
-					aggregateError = npmerrors.SimpleErrorWrapper(fmt.Sprintf("%s and had previous error", currentErrString), aggregateError)
+			for _, family := range pMgr.families() {
+				errCode, err := pMgr.runIPTablesCommand(family, util.IptablesDestroyFlag, chain)
+				if err != nil && errCode != doesNotExistErrorCode {
+					// add to staleChains if it's not one of the iptablesAzureChains
+					pMgr.staleChains.add(chain)
+					currentErrString := fmt.Sprintf("failed to clean up chain %s in %s iptables with err [%v]", chain, family, err)
+					if aggregateError == nil {
+						aggregateError = npmerrors.SimpleError(currentErrString)
+					} else {
+						aggregateError = npmerrors.SimpleErrorWrapper(fmt.Sprintf("%s and had previous error", currentErrString), aggregateError)
+					}
 				}
 			}
 		}
@@ -354,18 +396,199 @@ deleteLoop:
 	return nil
 }
 
+// expectedChain has the rules which a chain should have.
+// The unordered lines come first in any order, since the jumps to NetworkPolicy chains are inserted at the top of
+// AZURE-NPM-INGRESS and AZURE-NPM-EGRESS as policies are added.
+type expectedChain struct {
+	unorderedLines [][]string
+	lines          [][]string
+}
+
+/*
+verifyChains compares iptables-save output with the base chains and the chains of the cached policies.
+It returns the chains which are missing or whose rules differ, e.g. after another process ran iptables -F.
+Other chains, like stale policy chains, are ignored.
+
+The repair declares the drifted chains in an iptables-restore file, which flushes them, and then appends their rules:
+
+	*filter
+	:AZURE-NPM-INGRESS - -
+	:AZURE-NPM-INGRESS-123456 - -
+	-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-123456 ...
+	-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x4000 ...
+	-A AZURE-NPM-INGRESS -j AZURE-NPM-BASELINE-INGRESS
+	-A AZURE-NPM-INGRESS-123456 -j AZURE-NPM-INGRESS-ALLOW-MARK ...
+	COMMIT
+
+The caller must hold the policyMap lock.
+*/
+func (pMgr *PolicyManager) verifyChains(repair bool) ([]string, error) {
+	// Stop reconciling so we don't contend for iptables
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	driftedChainSet := make(map[string]struct{})
+	for _, family := range pMgr.families() {
+		parser := &parse.IPTablesParser{IOShim: pMgr.ioShim, IptablesSave: family.iptablesSave()}
+		table, err := parser.Iptables(util.IptablesFilterTable)
+		if err != nil {
+			return nil, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to run %s when verifying chains", family.iptablesSave()), err)
+		}
+
+		expectedChains := pMgr.expectedChains(family)
+		driftedChains := driftedChains(table, expectedChains)
+		if len(driftedChains) == 0 {
+			continue
+		}
+		klog.Infof("found %d chains in %s iptables which drifted: %+v", len(driftedChains), family, driftedChains)
+		for _, chain := range driftedChains {
+			driftedChainSet[chain] = struct{}{}
+		}
+
+		if !repair {
+			continue
+		}
+		creator := pMgr.creatorForDrift(driftedChains, expectedChains)
+		restoreErr := restore(family, creator)
+		metrics.IncDriftRepairs(metrics.ChainDrift, restoreErr != nil)
+		if restoreErr != nil {
+			metrics.SetKernelDrift(metrics.ChainDrift, len(driftedChainSet))
+			return sortedKeys(driftedChainSet), npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to repair drifted chains in %s iptables", family), restoreErr)
+		}
+	}
+
+	metrics.SetKernelDrift(metrics.ChainDrift, len(driftedChainSet))
+	return sortedKeys(driftedChainSet), nil
+}
+
+// expectedChains returns the base chains and the policy chains with their rules.
+// The caller must hold the policyMap lock.
+func (pMgr *PolicyManager) expectedChains(family ipFamily) map[string]*expectedChain {
+	chains := make(map[string]*expectedChain)
+	addLines := func(lines [][]string) {
+		for _, line := range lines {
+			chain := line[1]
+			chains[chain].lines = append(chains[chain].lines, line)
+		}
+	}
+
+	for _, chain := range iptablesAzureChains {
+		chains[chain] = &expectedChain{}
+	}
+	if len(pMgr.policyMap.cache) > 0 {
+		addLines(activationLines())
+	}
+	addLines(baseChainLines())
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		addLines(tierJumpLines(family, pMgr.tierPolicies(tier, nil, "")))
+	}
+
+	for _, networkPolicy := range pMgr.policyMap.cache {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			chains[chain] = &expectedChain{}
+		}
+		addLines(pMgr.networkPolicyLines(family, networkPolicy))
+
+		if networkPolicy.Tier != NetworkPolicyTier {
+			continue
+		}
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			chain := chains[util.IptablesAzureIngressChain]
+			line := append([]string{util.IptablesAppendFlag, util.IptablesAzureIngressChain}, ingressJumpSpecs(family, networkPolicy)...)
+			chain.unorderedLines = append(chain.unorderedLines, line)
+		}
+		if hasEgress {
+			chain := chains[util.IptablesAzureEgressChain]
+			line := append([]string{util.IptablesAppendFlag, util.IptablesAzureEgressChain}, egressJumpSpecs(family, networkPolicy)...)
+			chain.unorderedLines = append(chain.unorderedLines, line)
+		}
+	}
+	return chains
+}
+
+// driftedChains returns the sorted names of the expected chains which are missing from the table or have different rules.
+func driftedChains(table *NPMIPtable.Table, expectedChains map[string]*expectedChain) []string {
+	drifted := make([]string, 0)
+	for name, expected := range expectedChains {
+		chain, ok := table.Chains[name]
+		if !ok || !chainHasRules(chain, expected) {
+			drifted = append(drifted, name)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+func chainHasRules(chain *NPMIPtable.Chain, expected *expectedChain) bool {
+	if len(chain.Rules) != len(expected.unorderedLines)+len(expected.lines) {
+		return false
+	}
+
+	unorderedRules := make(map[string]int, len(expected.unorderedLines))
+	for _, line := range expected.unorderedLines {
+		unorderedRules[lineRuleString(line)]++
+	}
+	for _, rule := range chain.Rules[:len(expected.unorderedLines)] {
+		ruleString := parse.RuleString(rule)
+		if unorderedRules[ruleString] == 0 {
+			return false
+		}
+		unorderedRules[ruleString]--
+	}
+
+	for i, line := range expected.lines {
+		if parse.RuleString(chain.Rules[len(expected.unorderedLines)+i]) != lineRuleString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// lineRuleString returns the rule string of an iptables-restore line like "-A CHAIN specs...", written like iptables-save would.
+func lineRuleString(line []string) string {
+	return parse.RuleString(parse.RuleFromSpec(strings.Join(line[2:], " ")))
+}
+
+// creatorForDrift declares the drifted chains so that they're flushed, then appends their expected rules.
+// This is a separate function to help with UTs.
+func (pMgr *PolicyManager) creatorForDrift(driftedChains []string, expectedChains map[string]*expectedChain) *ioutil.FileCreator {
+	creator := pMgr.newCreatorWithChains(driftedChains)
+	for _, chain := range driftedChains {
+		expected := expectedChains[chain]
+		for _, line := range expected.unorderedLines {
+			creator.AddLine("", nil, line...)
+		}
+		for _, line := range expected.lines {
+			creator.AddLine("", nil, line...)
+		}
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // this function has a direct comparison in NPM v1 iptables manager (iptm.go)
-func (pMgr *PolicyManager) runIPTablesCommand(operationFlag string, args ...string) (int, error) {
-	return pMgr.ignoreErrorsAndRunIPTablesCommand(nil, operationFlag, args...)
+func (pMgr *PolicyManager) runIPTablesCommand(family ipFamily, operationFlag string, args ...string) (int, error) {
+	return pMgr.ignoreErrorsAndRunIPTablesCommand(family, nil, operationFlag, args...)
 }
 
-func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
+func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(family ipFamily, ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
 	allArgs := []string{util.IptablesWaitFlag, util.IptablesDefaultWaitTime, operationFlag}
 	allArgs = append(allArgs, args...)
 
-	klog.Infof("Executing iptables command with args %v", allArgs)
+	iptables := family.iptables()
+	klog.Infof("Executing %s command with args %v", iptables, allArgs)
 
-	command := pMgr.ioShim.Exec.Command(util.Iptables, allArgs...)
+	command := pMgr.ioShim.Exec.Command(iptables, allArgs...)
 	output, err := command.CombinedOutput()
 
 	var exitError utilexec.ExitError
@@ -375,21 +598,22 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 		outputString := strings.TrimSuffix(string(output), "\n")
 		for _, info := range ignored {
 			if errCode == info.exitCode && strings.Contains(outputString, info.stdErr) {
-				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, util.Iptables, allArgsString, errCode, outputString)
+				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, iptables, allArgsString, errCode, outputString)
 				return errCode, nil
 			}
 		}
 		if errCode > 0 {
-			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", util.Iptables, allArgsString, exitError, outputString)
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", iptables, allArgsString, exitError, outputString)
 		}
-		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", util.Iptables, allArgsString, outputString, exitError)
+		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", iptables, allArgsString, outputString, exitError)
 	}
 	return 0, nil
 }
 
 // Writes the restore file for bootup, and marks the following as stale: deprecated chains and old v2 policy chains.
+// The ip6tables file is written after the iptables one, so it adds to the stale chains instead of resetting them.
 // This is a separate function to help with UTs.
-func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *ioutil.FileCreator {
+func (pMgr *PolicyManager) creatorForBootup(family ipFamily, currentChains map[string]struct{}) *ioutil.FileCreator {
 	chainsToCreate := make([]string, 0, len(iptablesAzureChains))
 	for _, chain := range iptablesAzureChains {
 		_, exists := currentChains[chain]
@@ -401,49 +625,65 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	// Step 2.1 in bootup() comment: cleanup old NPM chains, and configure base chains and their rules
 	// To leave NPM deactivated, don't specify any rules for AZURE-NPM chain.
 	creator := pMgr.newCreatorWithChains(chainsToCreate)
-	pMgr.staleChains.empty()
+	if family == ipv4 {
+		pMgr.staleChains.empty()
+	}
 	for chain := range currentChains {
 		creator.AddLine("", nil, fmt.Sprintf("-F %s", chain))
 		// Step 2.2 in bootup() comment: delete deprecated chains and old v2 policy chains in the background
 		pMgr.staleChains.add(chain) // won't add base chains
 	}
 
+	for _, line := range baseChainLines() {
+		creator.AddLine("", nil, line...)
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
+
+// baseChainLines returns the rules of the base chains, except for AZURE-NPM and the jumps to policy chains.
+func baseChainLines() [][]string {
+	lines := make([][]string, 0, numBaseChainLines)
+
 	// add AZURE-NPM-INGRESS chain rules
 	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
 	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
-	creator.AddLine("", nil, ingressDropSpecs...)
+	lines = append(lines, ingressDropSpecs)
+	// BaselineAdminNetworkPolicies are only evaluated if no NetworkPolicy has a verdict
+	lines = append(lines, []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineIngressChain})
 
 	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
 	markIngressAllowSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain}
 	markIngressAllowSpecs = append(markIngressAllowSpecs, setMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
 	markIngressAllowSpecs = append(markIngressAllowSpecs, commentSpecs(fmt.Sprintf("SET-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
-	creator.AddLine("", nil, markIngressAllowSpecs...)
-	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
+	lines = append(lines, markIngressAllowSpecs)
+	lines = append(lines, []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureAdminEgressChain})
+	lines = append(lines, []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain})
 
 	// add AZURE-NPM-EGRESS chain rules
 	egressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	egressDropSpecs = append(egressDropSpecs, onMarkSpecs(util.IptablesAzureEgressDropMarkHex)...)
 	egressDropSpecs = append(egressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
-	creator.AddLine("", nil, egressDropSpecs...)
+	lines = append(lines, egressDropSpecs)
+	lines = append(lines, []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureBaselineEgressChain})
 
 	jumpOnIngressMatchSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, onMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, commentSpecs(fmt.Sprintf("ACCEPT-ON-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
-	creator.AddLine("", nil, jumpOnIngressMatchSpecs...)
+	lines = append(lines, jumpOnIngressMatchSpecs)
 
 	// add AZURE-NPM-ACCEPT chain rules
-	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept)
-	creator.AddLine("", nil, util.IptablesRestoreCommit)
-	return creator
+	lines = append(lines, []string{util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept})
+	return lines
 }
 
 // add/reposition the jump from FORWARD chain to AZURE-NPM chain to be in the correct position based on config:
 // option 1) jump to AZURE-NPM chain should be the first rule
 // option 2) jump to AZURE-NPM chain should be after the jump to KUBE-SERVICES chain
-func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
+func (pMgr *PolicyManager) positionAzureChainJumpRule(family ipFamily) error {
 	// get the line number for the azure jump
-	azureChainLineNum, err := pMgr.chainLineNumber(util.IptablesAzureChain)
+	azureChainLineNum, err := pMgr.chainLineNumber(family, util.IptablesAzureChain)
 	if err != nil {
 		baseErrString := "failed to get index of jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
@@ -458,7 +698,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	// place the azure jump in the first position, unless we want option 2 above and the kube jump exists
 	targetIndex := 1
 	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainAfterKubeServices {
-		kubeChainLineNum, err := pMgr.chainLineNumber(util.IptablesKubeServicesChain)
+		kubeChainLineNum, err := pMgr.chainLineNumber(family, util.IptablesKubeServicesChain)
 		if err != nil {
 			baseErrString := "failed to get index of jump from FORWARD chain to KUBE-SERVICES chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
@@ -480,7 +720,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	// delete the azure jump if it exists and update the target index
 	if azureChainLineNum != 0 {
 		metrics.SendErrorLogAndMetric(util.IptmID, "Info: Reconciler deleting and re-adding jump from FORWARD chain to AZURE-NPM chain table.")
-		if deleteErrCode, deleteErr := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...); deleteErr != nil {
+		if deleteErrCode, deleteErr := pMgr.runIPTablesCommand(family, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...); deleteErr != nil {
 			baseErrString := "failed to delete jump from FORWARD chain to AZURE-NPM chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, deleteErrCode, deleteErr.Error())
 			return npmerrors.SimpleErrorWrapper(baseErrString, deleteErr)
@@ -504,7 +744,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 		args = []string{util.IptablesForwardChain, strconv.Itoa(targetIndex)}
 		args = append(args, jumpToAzureChainArgs...)
 	}
-	if insertErrCode, err := pMgr.runIPTablesCommand(util.IptablesInsertionFlag, args...); err != nil {
+	if insertErrCode, err := pMgr.runIPTablesCommand(family, util.IptablesInsertionFlag, args...); err != nil {
 		baseErrString := "failed to insert jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, insertErrCode, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err)
@@ -514,8 +754,8 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 
 // returns 0 if the chain does not exist
 // this function has a direct comparison in NPM v1 iptables manager (iptm.go)
-func (pMgr *PolicyManager) chainLineNumber(chain string) (int, error) {
-	listForwardEntriesCommand := pMgr.ioShim.Exec.Command(util.Iptables, listForwardEntriesArgs...)
+func (pMgr *PolicyManager) chainLineNumber(family ipFamily, chain string) (int, error) {
+	listForwardEntriesCommand := pMgr.ioShim.Exec.Command(family.iptables(), listForwardEntriesArgs...)
 	grepCommand := pMgr.ioShim.Exec.Command(ioutil.Grep, chain)
 	searchResults, gotMatches, err := ioutil.PipeCommandToGrep(listForwardEntriesCommand, grepCommand)
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
 	"github.com/Azure/azure-container-networking/npm/util"
 	testutils "github.com/Azure/azure-container-networking/test/utils"
@@ -187,6 +188,40 @@ func TestCleanupChainsFailure(t *testing.T) {
 	assertStaleChainsContain(t, pMgr.staleChains, testChain1, testChain3)
 }
 
+func TestCleanupChainsDualStack(t *testing.T) {
+	calls := []testutils.TestCmd{
+		getFakeDestroyCommand(testChain1),
+		{Cmd: []string{"ip6tables", "-w", "60", "-X", testChain1}, ExitCode: 1}, // exit code 1 means the chain does not exist
+		getFakeDestroyCommand(testChain2),
+		{Cmd: []string{"ip6tables", "-w", "60", "-X", testChain2}, ExitCode: 2},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.Error(t, pMgr.cleanupChains([]string{testChain1, testChain2}))
+	assertStaleChainsContain(t, pMgr.staleChains, testChain2)
+}
+
+func TestBootupDualStack(t *testing.T) {
+	calls := GetBootupTestCalls(false)
+	calls = append(calls,
+		testutils.TestCmd{Cmd: []string{"ip6tables", "-w", "60", "-t", "filter", "-n", "-L"}, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "Chain AZURE-NPM"}, Stdout: grepOutputAzureChainsWithoutPolicies + "Chain AZURE-NPM-INGRESS-123456 (1 references)\n"},
+		fakeIP6TablesRestoreCommand,
+		testutils.TestCmd{Cmd: []string{"ip6tables", "-w", "60", "-t", "filter", "-n", "-L", "FORWARD", "--line-numbers"}, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "AZURE-NPM"}, ExitCode: 1},
+		testutils.TestCmd{Cmd: []string{"ip6tables", "-w", "60", "-I", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}},
+	)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.NoError(t, pMgr.Bootup(nil))
+	// the old policy chain in ip6tables is cleaned up with the ones in iptables
+	assertStaleChainsContain(t, pMgr.staleChains, "AZURE-NPM-INGRESS-123456")
+}
+
 func TestCreatorForBootup(t *testing.T) {
 	v1Chains := []string{
 		"AZURE-NPM-INGRESS-DROPS",
@@ -213,10 +248,17 @@ func TestCreatorForBootup(t *testing.T) {
 				":AZURE-NPM-INGRESS-ALLOW-MARK - -",
 				":AZURE-NPM-EGRESS - -",
 				":AZURE-NPM-ACCEPT - -",
+				":AZURE-NPM-ANP-INGRESS - -",
+				":AZURE-NPM-ANP-EGRESS - -",
+				":AZURE-NPM-BANP-INGRESS - -",
+				":AZURE-NPM-BANP-EGRESS - -",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-ANP-EGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -238,6 +280,10 @@ func TestCreatorForBootup(t *testing.T) {
 			// same expected lines as "no NPM prior", except for the old v2 policy chains in the header
 			expectedLines: []string{
 				"*filter",
+				":AZURE-NPM-ANP-INGRESS - -",
+				":AZURE-NPM-ANP-EGRESS - -",
+				":AZURE-NPM-BANP-INGRESS - -",
+				":AZURE-NPM-BANP-EGRESS - -",
 				"-F AZURE-NPM",
 				"-F AZURE-NPM-INGRESS",
 				"-F AZURE-NPM-INGRESS-ALLOW-MARK",
@@ -246,9 +292,12 @@ func TestCreatorForBootup(t *testing.T) {
 				"-F AZURE-NPM-INGRESS-123456",
 				"-F AZURE-NPM-EGRESS-123456",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-ANP-EGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -271,13 +320,20 @@ func TestCreatorForBootup(t *testing.T) {
 				"*filter",
 				":AZURE-NPM - -",
 				":AZURE-NPM-EGRESS - -",
+				":AZURE-NPM-ANP-INGRESS - -",
+				":AZURE-NPM-ANP-EGRESS - -",
+				":AZURE-NPM-BANP-INGRESS - -",
+				":AZURE-NPM-BANP-EGRESS - -",
 				"-F AZURE-NPM-ACCEPT",
 				"-F AZURE-NPM-INGRESS",
 				"-F AZURE-NPM-INGRESS-ALLOW-MARK",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-ANP-EGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -296,6 +352,10 @@ func TestCreatorForBootup(t *testing.T) {
 				":AZURE-NPM-INGRESS-ALLOW-MARK - -",
 				":AZURE-NPM-EGRESS - -",
 				":AZURE-NPM-ACCEPT - -",
+				":AZURE-NPM-ANP-INGRESS - -",
+				":AZURE-NPM-ANP-EGRESS - -",
+				":AZURE-NPM-BANP-INGRESS - -",
+				":AZURE-NPM-BANP-EGRESS - -",
 				"-F AZURE-NPM-INGRESS-DROPS",
 				"-F AZURE-NPM-INGRESS-TO",
 				"-F AZURE-NPM-INGRESS-PORTS",
@@ -303,9 +363,12 @@ func TestCreatorForBootup(t *testing.T) {
 				"-F AZURE-NPM-EGRESS-FROM",
 				"-F AZURE-NPM-EGRESS-PORTS",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-ANP-EGRESS",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -320,7 +383,7 @@ func TestCreatorForBootup(t *testing.T) {
 			ioshim := common.NewMockIOShim(nil)
 			defer ioshim.VerifyCalls(t, nil)
 			pMgr := NewPolicyManager(ioshim, ipsetConfig)
-			creator := pMgr.creatorForBootup(stringsToMap(tt.currentChains))
+			creator := pMgr.creatorForBootup(ipv4, stringsToMap(tt.currentChains))
 			actualLines := strings.Split(creator.ToString(), "\n")
 			sortedActualLines := sortFlushes(actualLines)
 			sortedExpectedLines := sortFlushes(tt.expectedLines)
@@ -772,7 +835,7 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 				PlaceAzureChainFirst: tt.placeAzureChainFirst,
 			}
 			pMgr := NewPolicyManager(ioshim, cfg)
-			err := pMgr.positionAzureChainJumpRule()
+			err := pMgr.positionAzureChainJumpRule(ipv4)
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -863,7 +926,7 @@ func TestChainLineNumber(t *testing.T) {
 			ioshim := common.NewMockIOShim(tt.calls)
 			defer ioshim.VerifyCalls(t, tt.calls)
 			pMgr := NewPolicyManager(ioshim, ipsetConfig)
-			lineNum, err := pMgr.chainLineNumber(testChainName)
+			lineNum, err := pMgr.chainLineNumber(ipv4, testChainName)
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -874,6 +937,277 @@ func TestChainLineNumber(t *testing.T) {
 	}
 }
 
+// iptablesSaveForVerify is iptables-save output with the base chains and bothDirectionsNetPol.
+// The chains and rules can be replaced to inject drift.
+type iptablesSaveForVerify struct {
+	chains []string
+	rules  []string
+}
+
+func newIPTablesSaveForVerify() *iptablesSaveForVerify {
+	return &iptablesSaveForVerify{
+		chains: []string{
+			"AZURE-NPM",
+			"AZURE-NPM-ACCEPT",
+			"AZURE-NPM-ANP-EGRESS",
+			"AZURE-NPM-ANP-INGRESS",
+			"AZURE-NPM-BANP-EGRESS",
+			"AZURE-NPM-BANP-INGRESS",
+			"AZURE-NPM-EGRESS",
+			"AZURE-NPM-INGRESS",
+			"AZURE-NPM-INGRESS-ALLOW-MARK",
+			bothDirectionsNetPolEgressChain,
+			bothDirectionsNetPolIngressChain,
+		},
+		rules: []string{
+			"-A AZURE-NPM -j AZURE-NPM-ANP-INGRESS",
+			"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+			"-A AZURE-NPM -j AZURE-NPM-ANP-EGRESS",
+			"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+			"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+			"-A AZURE-NPM-ACCEPT -j ACCEPT",
+			fmt.Sprintf("-A AZURE-NPM-EGRESS -m set --match-set %s src -m comment --comment %s -j %s",
+				ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolEgressJumpComment, bothDirectionsNetPolEgressChain),
+			"-A AZURE-NPM-EGRESS -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800 -j DROP",
+			"-A AZURE-NPM-EGRESS -j AZURE-NPM-BANP-EGRESS",
+			"-A AZURE-NPM-EGRESS -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200 -j AZURE-NPM-ACCEPT",
+			fmt.Sprintf("-A AZURE-NPM-INGRESS -m set --match-set %s dst -m comment --comment %s -j %s",
+				ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolIngressJumpComment, bothDirectionsNetPolIngressChain),
+			"-A AZURE-NPM-INGRESS -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400 -j DROP",
+			"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
+			"-A AZURE-NPM-INGRESS-ALLOW-MARK -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200 -j MARK --set-xmark 0x200/0x200",
+			"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-ANP-EGRESS",
+			"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+			fmt.Sprintf("-A %s -p tcp -m tcp --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s -j MARK --set-xmark 0x400/0x400",
+				bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment),
+			fmt.Sprintf("-A %s -m set --match-set %s src -m comment --comment %s -j AZURE-NPM-INGRESS-ALLOW-MARK",
+				bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ingressAllowComment),
+			fmt.Sprintf("-A %s -p udp -m udp --dport 144 -m set --match-set %s dst -m comment --comment %s -j MARK --set-xmark 0x800/0x800",
+				bothDirectionsNetPolEgressChain, ipsets.TestCIDRSet.HashedName, egressDropComment),
+			fmt.Sprintf("-A %s -m set --match-set %s dst -m comment --comment %s -j AZURE-NPM-ACCEPT",
+				bothDirectionsNetPolEgressChain, ipsets.TestNamedportSet.HashedName, egressAllowComment),
+		},
+	}
+}
+
+func (s *iptablesSaveForVerify) String() string {
+	lines := []string{"# Generated by iptables-save v1.8.7", "*filter", ":INPUT ACCEPT [0:0]", ":FORWARD ACCEPT [0:0]", ":OUTPUT ACCEPT [0:0]"}
+	for _, chain := range s.chains {
+		lines = append(lines, fmt.Sprintf(":%s - [0:0]", chain))
+	}
+	lines = append(lines, "-A FORWARD -m conntrack --ctstate NEW -j AZURE-NPM")
+	lines = append(lines, s.rules...)
+	lines = append(lines, "COMMIT", "# Completed")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (s *iptablesSaveForVerify) withoutRules(chain string) *iptablesSaveForVerify {
+	rules := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		if !strings.HasPrefix(rule, fmt.Sprintf("-A %s ", chain)) {
+			rules = append(rules, rule)
+		}
+	}
+	s.rules = rules
+	return s
+}
+
+// withoutChain removes the chain, its rules, and the jumps to it, like iptables -F and iptables -X would.
+func (s *iptablesSaveForVerify) withoutChain(chain string) *iptablesSaveForVerify {
+	chains := make([]string, 0, len(s.chains))
+	for _, c := range s.chains {
+		if c != chain {
+			chains = append(chains, c)
+		}
+	}
+	s.chains = chains
+
+	rules := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		if !strings.HasSuffix(rule, " -j "+chain) {
+			rules = append(rules, rule)
+		}
+	}
+	s.rules = rules
+	return s.withoutRules(chain)
+}
+
+func TestVerifyChains(t *testing.T) {
+	flushed := newIPTablesSaveForVerify()
+	flushed.rules = nil
+
+	extraRule := newIPTablesSaveForVerify().withoutChain(bothDirectionsNetPolEgressChain)
+	extraRule.rules = append(extraRule.rules, "-A AZURE-NPM-ACCEPT -j DROP")
+
+	reorderedRules := newIPTablesSaveForVerify().withoutRules(bothDirectionsNetPolIngressChain)
+	reorderedRules.rules = append(reorderedRules.rules,
+		fmt.Sprintf("-A %s -m set --match-set %s src -m comment --comment %s -j AZURE-NPM-INGRESS-ALLOW-MARK",
+			bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ingressAllowComment),
+		fmt.Sprintf("-A %s -p tcp -m tcp --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s -j MARK --set-xmark 0x400/0x400",
+			bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment),
+	)
+
+	tests := []struct {
+		name           string
+		iptablesSave   string
+		repair         bool
+		restoreCalls   []testutils.TestCmd
+		expectedChains []string
+		wantErr        bool
+	}{
+		{
+			name:           "no drift",
+			iptablesSave:   newIPTablesSaveForVerify().String(),
+			repair:         true,
+			expectedChains: []string{},
+		},
+		{
+			name:         "flushed chains without repair",
+			iptablesSave: flushed.String(),
+			expectedChains: []string{
+				"AZURE-NPM",
+				"AZURE-NPM-ACCEPT",
+				"AZURE-NPM-EGRESS",
+				bothDirectionsNetPolEgressChain,
+				"AZURE-NPM-INGRESS",
+				bothDirectionsNetPolIngressChain,
+				"AZURE-NPM-INGRESS-ALLOW-MARK",
+			},
+		},
+		{
+			name:           "missing policy chain and extra rule",
+			iptablesSave:   extraRule.String(),
+			repair:         true,
+			restoreCalls:   []testutils.TestCmd{fakeIPTablesRestoreCommand},
+			expectedChains: []string{"AZURE-NPM-ACCEPT", "AZURE-NPM-EGRESS", bothDirectionsNetPolEgressChain},
+		},
+		{
+			name:           "reordered policy rules",
+			iptablesSave:   reorderedRules.String(),
+			repair:         true,
+			restoreCalls:   []testutils.TestCmd{fakeIPTablesRestoreCommand},
+			expectedChains: []string{bothDirectionsNetPolIngressChain},
+		},
+		{
+			name:           "failed repair",
+			iptablesSave:   newIPTablesSaveForVerify().withoutRules("AZURE-NPM-ACCEPT").String(),
+			repair:         true,
+			restoreCalls:   []testutils.TestCmd{fakeIPTablesRestoreFailureCommand, fakeIPTablesRestoreFailureCommand},
+			expectedChains: []string{"AZURE-NPM-ACCEPT"},
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.ReinitializeAll()
+			calls := []testutils.TestCmd{{Cmd: []string{"iptables-save", "-t", "filter"}, Stdout: tt.iptablesSave}}
+			calls = append(calls, tt.restoreCalls...)
+			ioshim := common.NewMockIOShim(calls)
+			defer ioshim.VerifyCalls(t, calls)
+			pMgr := NewPolicyManager(ioshim, ipsetConfig)
+			pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+
+			chains, err := pMgr.VerifyChains(tt.repair)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedChains, chains)
+
+			numDrifted, err := metrics.GetKernelDrift(metrics.ChainDrift)
+			require.NoError(t, err)
+			require.Equal(t, len(tt.expectedChains), numDrifted)
+
+			numRepairs, err := metrics.TotalDriftRepairs(metrics.ChainDrift, false)
+			require.NoError(t, err)
+			numFailedRepairs, err := metrics.TotalDriftRepairs(metrics.ChainDrift, true)
+			require.NoError(t, err)
+			switch {
+			case len(tt.restoreCalls) == 0:
+				require.Equal(t, 0, numRepairs)
+				require.Equal(t, 0, numFailedRepairs)
+			case tt.wantErr:
+				require.Equal(t, 0, numRepairs)
+				require.Equal(t, 1, numFailedRepairs)
+			default:
+				require.Equal(t, 1, numRepairs)
+				require.Equal(t, 0, numFailedRepairs)
+			}
+		})
+	}
+}
+
+func TestVerifyChainsUnorderedJumps(t *testing.T) {
+	// jumps to NetworkPolicy chains are inserted at the top, so their order depends on the order the policies were added
+	iptablesSave := newIPTablesSaveForVerify()
+	iptablesSave.chains = append(iptablesSave.chains, ingressNetPolChain)
+	iptablesSave.rules = append([]string{
+		fmt.Sprintf("-A AZURE-NPM-INGRESS -m set --match-set %s dst -m set --match-set %s dst -m comment --comment %s -j %s",
+			ipsets.TestKeyPodSet.HashedName, ipsets.TestNSSet.HashedName, ingressNetPolJumpComment, ingressNetPolChain),
+		fmt.Sprintf("-A %s -p tcp -m tcp --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s -j MARK --set-xmark 0x400/0x400",
+			ingressNetPolChain, ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment),
+	}, iptablesSave.rules...)
+
+	calls := []testutils.TestCmd{{Cmd: []string{"iptables-save", "-t", "filter"}, Stdout: iptablesSave.String()}}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+	pMgr.policyMap.cache[ingressNetPol.PolicyKey] = ingressNetPol
+
+	chains, err := pMgr.VerifyChains(false)
+	require.NoError(t, err)
+	require.Empty(t, chains)
+}
+
+func TestVerifyChainsDualStack(t *testing.T) {
+	metrics.ReinitializeAll()
+	// NPM is deactivated since there are no policies, and ip6tables is missing a chain
+	iptablesSave := newIPTablesSaveForVerify().withoutRules("AZURE-NPM").withoutChain(bothDirectionsNetPolIngressChain).withoutChain(bothDirectionsNetPolEgressChain)
+	ip6tablesSave := newIPTablesSaveForVerify().withoutRules("AZURE-NPM").withoutChain(bothDirectionsNetPolIngressChain).withoutChain(bothDirectionsNetPolEgressChain)
+	ip6tablesSave.withoutChain("AZURE-NPM-INGRESS-ALLOW-MARK")
+
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"iptables-save", "-t", "filter"}, Stdout: iptablesSave.String()},
+		{Cmd: []string{"ip6tables-save", "-t", "filter"}, Stdout: ip6tablesSave.String()},
+		fakeIP6TablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	chains, err := pMgr.VerifyChains(true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"AZURE-NPM-INGRESS-ALLOW-MARK"}, chains)
+}
+
+func TestCreatorForDrift(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+
+	driftedChains := []string{"AZURE-NPM-INGRESS", bothDirectionsNetPolIngressChain}
+	creator := pMgr.creatorForDrift(driftedChains, pMgr.expectedChains(ipv4))
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		":AZURE-NPM-INGRESS - -",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		"-A AZURE-NPM-INGRESS " + ingressEgressNetPolIngressJump,
+		"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+		"-A AZURE-NPM-INGRESS -j AZURE-NPM-BANP-INGRESS",
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
 func getFakeDestroyCommand(chain string) testutils.TestCmd {
 	return testutils.TestCmd{Cmd: []string{"iptables", "-w", "60", "-X", chain}}
 }
@@ -15,7 +15,7 @@ type NPMNetworkPolicy struct {
 	Namespace string
 	// PolicyKey is a unique combination of "namespace/name" of network policy
 	PolicyKey string
-	// ACLPolicyID is only used in Windows. See aclPolicyID() in policy_windows.go for more info
+	// ACLPolicyID identifies the policy's ACLs in Windows and tags its audit logs in Linux. See aclPolicyID() for more info
 	ACLPolicyID string
 	// TODO get rid of PodSelectorIPSets in favor of PodSelectorList (exact same except need to add members field to SetInfo)
 	// PodSelectorIPSets holds the IPSets for the Pod Selector
@@ -29,9 +29,25 @@ type NPMNetworkPolicy struct {
 	// and not from pod selector IPSets, including children of a NestedLabelOfPod ipset
 	RuleIPSets []*ipsets.TranslatedIPSet
 	ACLs       []*ACLPolicy
+	// Tier is empty for NetworkPolicies.
+	// The AdminNetworkPolicy and BaselineAdminNetworkPolicy tiers are only supported in Linux.
+	Tier PolicyTier
+	// Priority orders the policies in the AdminNetworkPolicy tier. Lower priorities are evaluated first.
+	Priority int32
 	// podIP is key and endpoint ID as value
 	// Will be populated by dataplane and policy manager
 	PodEndpoints map[string]string
+	// audited is set by the policy manager if the policy's verdicts are logged in Linux
+	audited bool
+}
+
+const policyIDPrefix = "azure-acl"
+
+// aclPolicyID returns azure-acl-<network policy namespace>-<network policy name> format
+// to differentiate ACLs among different network policies,
+// but aclPolicy in the same network policy has the same aclPolicyID.
+func aclPolicyID(policyNS, policyName string) string {
+	return fmt.Sprintf("%s-%s-%s", policyIDPrefix, policyNS, policyName)
 }
 
 func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
@@ -42,6 +58,17 @@ func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	}
 }
 
+// NewAdminNPMNetworkPolicy creates the NPMNetworkPolicy for a cluster-scoped AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+// Its PolicyKey is "<tier>/<name>", which can't collide with a NetworkPolicy's since namespaces can't have uppercase letters.
+func NewAdminNPMNetworkPolicy(tier PolicyTier, name string, priority int32) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey:   fmt.Sprintf("%s/%s", tier, name),
+		ACLPolicyID: aclPolicyID(string(tier), name),
+		Tier:        tier,
+		Priority:    priority,
+	}
+}
+
 func (netPol *NPMNetworkPolicy) AllPodSelectorIPSets() []*ipsets.TranslatedIPSet {
 	return append(netPol.PodSelectorIPSets, netPol.ChildPodSelectorIPSets...)
 }
@@ -59,6 +86,14 @@ func (netPol *NPMNetworkPolicy) numACLRulesProducedInKernel() int {
 			hasEgress = true
 			numRules++
 		}
+		if aclPolicy.Target == Passed {
+			// in Linux, each pass rule is followed by a rule to return on the pass mark
+			numRules++
+		}
+		if netPol.audited {
+			// in Linux, each rule of an audited policy is preceded by an NFLOG rule
+			numRules++
+		}
 	}
 
 	// both Windows and Linux have an extra ACL rule for ingress and an extra rule for egress
@@ -132,10 +167,20 @@ func NormalizePolicy(networkPolicy *NPMNetworkPolicy) {
 }
 
 func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
+	if !networkPolicy.Tier.isKnown() {
+		return npmerrors.SimpleError(fmt.Sprintf("NetPol %s has unknown tier [%s]", networkPolicy.PolicyKey, networkPolicy.Tier))
+	}
+	if util.IsWindowsDP() && networkPolicy.Tier != NetworkPolicyTier {
+		return npmerrors.SimpleError(fmt.Sprintf("NetPol %s has unsupported tier [%s] on Windows", networkPolicy.PolicyKey, networkPolicy.Tier))
+	}
+
 	for _, aclPolicy := range networkPolicy.ACLs {
 		if !aclPolicy.hasKnownTarget() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown target [%s]", networkPolicy.PolicyKey, aclPolicy.Target))
 		}
+		if aclPolicy.Target == Passed && networkPolicy.Tier != AdminTier {
+			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has target [%s] outside of the %s tier", networkPolicy.PolicyKey, Passed, AdminTier))
+		}
 		if !aclPolicy.hasKnownDirection() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown direction [%s]", networkPolicy.PolicyKey, aclPolicy.Direction))
 		}
@@ -224,7 +269,7 @@ func (aclPolicy *ACLPolicy) hasKnownProtocol() bool {
 }
 
 func (aclPolicy *ACLPolicy) hasKnownTarget() bool {
-	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped
+	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped || aclPolicy.Target == Passed
 }
 
 func (aclPolicy *ACLPolicy) satisifiesPortAndProtocolConstraints() bool {
@@ -330,8 +375,26 @@ const (
 	Allowed Verdict = "ALLOW"
 	// Dropped is denying a flow
 	Dropped Verdict = "DROP"
+	// Passed skips the rest of the AdminNetworkPolicies, leaving the verdict to the lower tiers
+	Passed Verdict = "PASS"
+)
+
+// PolicyTier decides when a policy is evaluated relative to policies of the other tiers.
+type PolicyTier string
+
+const (
+	// NetworkPolicyTier is the zero value so that NetworkPolicies don't need to set it
+	NetworkPolicyTier PolicyTier = ""
+	// AdminTier policies are evaluated before NetworkPolicies, in order of Priority.
+	AdminTier PolicyTier = "AdminNetworkPolicy"
+	// BaselineTier policies are evaluated for Pods that no NetworkPolicy selects.
+	BaselineTier PolicyTier = "BaselineAdminNetworkPolicy"
 )
 
+func (tier PolicyTier) isKnown() bool {
+	return tier == NetworkPolicyTier || tier == AdminTier || tier == BaselineTier
+}
+
 // Protocol can be TCP, UDP, SCTP, or unspecified since they are currently supported in networkpolicy.
 // Protocol value is case-sensitive (Capital now).
 // TODO: Need to remove this dependency on case-sensitivity.
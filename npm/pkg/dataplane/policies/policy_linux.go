@@ -20,11 +20,6 @@ const (
 	maxLengthForMatchSetSpecs = 6
 )
 
-// the NPMNetworkPolicy ACLPolicyID field is unnused in Linux
-func aclPolicyID(_, _ string) string {
-	return ""
-}
-
 // returns two booleans indicating whether the network policy has ingress and egress respectively
 func (networkPolicy *NPMNetworkPolicy) hasIngressAndEgress() (hasIngress, hasEgress bool) {
 	hasIngress = false
@@ -37,11 +32,57 @@ func (networkPolicy *NPMNetworkPolicy) hasIngressAndEgress() (hasIngress, hasEgr
 }
 
 func (networkPolicy *NPMNetworkPolicy) egressChainName() string {
-	return networkPolicy.chainName(util.IptablesAzureEgressPolicyChainPrefix)
+	return networkPolicy.chainName(networkPolicy.Tier.egressPolicyChainPrefix())
 }
 
 func (networkPolicy *NPMNetworkPolicy) ingressChainName() string {
-	return networkPolicy.chainName(util.IptablesAzureIngressPolicyChainPrefix)
+	return networkPolicy.chainName(networkPolicy.Tier.ingressPolicyChainPrefix())
+}
+
+// ingressChain returns the base chain which jumps to the ingress chains of the tier's policies
+func (tier PolicyTier) ingressChain() string {
+	switch tier {
+	case AdminTier:
+		return util.IptablesAzureAdminIngressChain
+	case BaselineTier:
+		return util.IptablesAzureBaselineIngressChain
+	default:
+		return util.IptablesAzureIngressChain
+	}
+}
+
+// egressChain returns the base chain which jumps to the egress chains of the tier's policies
+func (tier PolicyTier) egressChain() string {
+	switch tier {
+	case AdminTier:
+		return util.IptablesAzureAdminEgressChain
+	case BaselineTier:
+		return util.IptablesAzureBaselineEgressChain
+	default:
+		return util.IptablesAzureEgressChain
+	}
+}
+
+func (tier PolicyTier) ingressPolicyChainPrefix() string {
+	switch tier {
+	case AdminTier:
+		return util.IptablesAzureAdminIngressPolicyChainPrefix
+	case BaselineTier:
+		return util.IptablesAzureBaselineIngressPolicyChainPrefix
+	default:
+		return util.IptablesAzureIngressPolicyChainPrefix
+	}
+}
+
+func (tier PolicyTier) egressPolicyChainPrefix() string {
+	switch tier {
+	case AdminTier:
+		return util.IptablesAzureAdminEgressPolicyChainPrefix
+	case BaselineTier:
+		return util.IptablesAzureBaselineEgressPolicyChainPrefix
+	default:
+		return util.IptablesAzureEgressPolicyChainPrefix
+	}
 }
 
 func (networkPolicy *NPMNetworkPolicy) chainName(prefix string) string {
@@ -72,6 +113,10 @@ func (networkPolicy *NPMNetworkPolicy) commentForJump(direction UniqueDirection)
 	if len(networkPolicy.PodSelectorList) > 0 {
 		podSelectorComment = commentForInfos(networkPolicy.PodSelectorList)
 	}
+	if networkPolicy.Tier != NetworkPolicyTier {
+		// admin policies are cluster-scoped
+		return fmt.Sprintf("%s-POLICY-%s-%s-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment)
+	}
 	return fmt.Sprintf("%s-POLICY-%s-%s-%s-IN-ns-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment, networkPolicy.Namespace)
 }
 
@@ -91,13 +136,17 @@ func (info SetInfo) comment() string {
 	return "!" + name
 }
 
-func (info SetInfo) matchSetSpecs(matchString string) []string {
+func (info SetInfo) matchSetSpecs(family ipFamily, matchString string) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs)
 	specs = append(specs, util.IptablesModuleFlag, util.IptablesSetModuleFlag)
 	if !info.Included {
 		specs = append(specs, util.IptablesNotFlag)
 	}
 	hashedSetName := info.IPSet.GetHashedName()
+	if family == ipv6 && info.IPSet.GetSetKind() == ipsets.HashSet {
+		// lists hold the inet6 twins of their members, so only hash sets are swapped for their twins
+		hashedSetName = ipsets.GetIPv6HashedName(hashedSetName)
+	}
 	specs = append(specs, util.IptablesMatchSetFlag, hashedSetName, matchString)
 	return specs
 }
@@ -128,9 +177,12 @@ func (aclPolicy *ACLPolicy) comment() string {
 	}
 
 	builder := strings.Builder{}
-	if aclPolicy.Target == Allowed {
+	switch aclPolicy.Target {
+	case Allowed:
 		builder.WriteString("ALLOW")
-	} else {
+	case Passed:
+		builder.WriteString("PASS")
+	default:
 		builder.WriteString("DROP")
 	}
 
@@ -214,6 +266,7 @@ func (portRange *Ports) toIPTablesString() string {
 					- ingress: "ALLOW-FROM"
 					- egress: "ALLOW-TO"
 			- denied: replace "ALLOW" with "DROP"
+			- passed: replace "ALLOW" with "PASS"
 		- similar idea (think there are at most two non-namedPort ipsets e.g. ns selector and pod selector):
 			prefix
 			[-ipset1Name]
@@ -234,7 +287,7 @@ func (portRange *Ports) toIPTablesString() string {
 			-policyKey
 			-TO         (or "-FROM" if egress)
 			[-podSelectorComment]   (or "all" if there are no pod selectors)
-			-IN-ns
+			-IN-ns      (omitted for cluster-scoped AdminNetworkPolicies and BaselineAdminNetworkPolicies)
 			-namespaceName
 
 	strings for protocol, ports, selectors:
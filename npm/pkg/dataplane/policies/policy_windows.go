@@ -11,7 +11,6 @@ import (
 const (
 	blockRulePriotity = 3000
 	allowRulePriotity = 222
-	policyIDPrefix    = "azure-acl"
 )
 
 var (
@@ -28,13 +27,6 @@ var (
 	ErrProtocolNotSupported       = errors.New("Protocol mentioned is not supported")
 )
 
-// aclPolicyID returns azure-acl-<network policy namespace>-<network policy name> format
-// to differentiate ACLs among different network policies,
-// but aclPolicy in the same network policy has the same aclPolicyID.
-func aclPolicyID(policyNS, policyName string) string {
-	return fmt.Sprintf("%s-%s-%s", policyIDPrefix, policyNS, policyName)
-}
-
 // NPMACLPolSettings is an adaption over the existing hcn.ACLPolicySettings
 // default ACL settings does not contain ID field but HNS is happy with taking an ID
 // this ID will help us woth correctly identifying the ACL policy when reading from HNS
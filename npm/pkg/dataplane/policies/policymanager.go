@@ -24,8 +24,8 @@ const (
 
 	// this number is based on the implementation in chain-management_linux.go
 	// it represents the number of rules unrelated to policies
-	// it's technically 3 off when there are no policies since we flush the AZURE-NPM chain then
-	numLinuxBaseACLRules = 11
+	// it's technically 5 off when there are no policies since we flush the AZURE-NPM chain then
+	numLinuxBaseACLRules = 16
 )
 
 type PolicyManagerCfg struct {
@@ -39,6 +39,10 @@ type PolicyManagerCfg struct {
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
 	MaxBatchedACLsPerPod int
+	// EnableIPv6 only affects Linux, where it also writes every chain and rule to ip6tables
+	EnableIPv6 bool
+	// Audit only affects Linux. It is nil if no policy is audited.
+	Audit *AuditCfg
 }
 
 type PolicyMap struct {
@@ -62,6 +66,7 @@ type PolicyManager struct {
 	ioShim           *common.IOShim
 	staleChains      *staleChains
 	reconcileManager *reconcileManager
+	auditedPolicies  *auditedPolicies
 	*PolicyManagerCfg
 }
 
@@ -75,6 +80,7 @@ func NewPolicyManager(ioShim *common.IOShim, cfg *PolicyManagerCfg) *PolicyManag
 		reconcileManager: &reconcileManager{
 			releaseLockSignal: make(chan struct{}, 1),
 		},
+		auditedPolicies:  newAuditedPolicies(),
 		PolicyManagerCfg: cfg,
 	}
 }
@@ -110,6 +116,15 @@ func (pMgr *PolicyManager) Reconcile() {
 	pMgr.reconcile()
 }
 
+// VerifyChains compares iptables with the base chains and the chains of the cached policies.
+// It returns the chains which are missing or have different rules, e.g. after another process flushed them.
+// If repair is true, the chains are rewritten.
+func (pMgr *PolicyManager) VerifyChains(repair bool) ([]string, error) {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+	return pMgr.verifyChains(repair)
+}
+
 func (pMgr *PolicyManager) PolicyExists(policyKey string) bool {
 	pMgr.policyMap.RLock()
 	defer pMgr.policyMap.RUnlock()
@@ -142,6 +157,7 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
 			return npmerrors.Errorf(npmerrors.AddPolicy, false, msg)
 		}
+		policy.audited = pMgr.Audit.audits(policy)
 	}
 
 	if len(nonEmptyPolicies) == 0 {
@@ -173,6 +189,9 @@ func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointLis
 
 		// add policy to cache
 		pMgr.policyMap.cache[policy.PolicyKey] = policy
+		if policy.audited {
+			pMgr.auditedPolicies.add(policy)
+		}
 	}
 	return nil
 }
@@ -220,6 +239,9 @@ func (pMgr *PolicyManager) RemovePolicy(policyKey string) error {
 
 	// remove policy from cache
 	delete(pMgr.policyMap.cache, policyKey)
+	if policy.audited {
+		pMgr.auditedPolicies.remove(policy)
+	}
 	return nil
 }
 
@@ -4,6 +4,8 @@ package policies
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -20,8 +22,45 @@ const (
 	knownLineErrorPattern = "Error occurred at line: (\\d+)"
 
 	chainSectionPrefix = "chain"
+
+	ipv4 ipFamily = "IPv4"
+	ipv6 ipFamily = "IPv6"
 )
 
+// ipFamily is the IP family of the iptables to write rules to.
+// In a dual-stack dataplane, every chain and rule is in both iptables and ip6tables,
+// and the rules in ip6tables match the inet6 twins of the hash sets.
+type ipFamily string
+
+func (family ipFamily) iptables() string {
+	if family == ipv6 {
+		return util.Ip6tables
+	}
+	return util.Iptables
+}
+
+func (family ipFamily) iptablesRestore() string {
+	if family == ipv6 {
+		return util.Ip6tablesRestore
+	}
+	return util.IptablesRestore
+}
+
+func (family ipFamily) iptablesSave() string {
+	if family == ipv6 {
+		return util.Ip6tablesSave
+	}
+	return util.IptablesSave
+}
+
+// families returns the IP families that the policies are enforced for.
+func (pMgr *PolicyManager) families() []ipFamily {
+	if pMgr.EnableIPv6 {
+		return []ipFamily{ipv4, ipv6}
+	}
+	return []ipFamily{ipv4}
+}
+
 /*
 Error handling for iptables-restore:
 Currently we retry on any error and will make two tries max.
@@ -44,18 +83,20 @@ Known errors that we should retry on:
 func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ map[string]string) error {
 	// 1. Add rules for the network policies and activate NPM (if necessary).
 	chainsToCreate := chainNames(networkPolicies)
-	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, networkPolicies)
 
 	// Stop reconciling so we don't contend for iptables, and so reconcile doesn't delete chainsToCreate.
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
-	timer := metrics.StartNewTimer()
-	err := restore(creator)
-	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
-	if err != nil {
-		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
-		return fmt.Errorf("failed to restore iptables with updated policies. err: %w", err)
+	for _, family := range pMgr.families() {
+		creator := pMgr.creatorForNewNetworkPolicies(family, chainsToCreate, networkPolicies)
+		timer := metrics.StartNewTimer()
+		err := restore(family, creator)
+		metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+		if err != nil {
+			metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+			return fmt.Errorf("failed to restore %s iptables with updated policies. err: %w", family, err)
+		}
 	}
 
 	// 2. Make sure the new chains don't get deleted in the background
@@ -67,26 +108,31 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 
 func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
 	chainsToDelete := chainNames([]*NPMNetworkPolicy{networkPolicy})
-	creator := pMgr.creatorForRemovingPolicies(chainsToDelete)
 
 	// Stop reconciling so we don't contend for iptables, and so we don't update the staleChains at the same time as reconcile()
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
-	// 1. Delete jump rules from ingress/egress chains to ingress/egress policy chains.
-	// We ought to delete these jump rules here in the foreground since if we add an NP back after deleting, iptables-restore --noflush can add duplicate jump rules.
-	deleteErr := pMgr.deleteOldJumpRulesOnRemove(networkPolicy)
-	if deleteErr != nil {
-		return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
-	}
+	for _, family := range pMgr.families() {
+		// 1. Delete jump rules from ingress/egress chains to ingress/egress policy chains.
+		// We ought to delete these jump rules here in the foreground since if we add an NP back after deleting, iptables-restore --noflush can add duplicate jump rules.
+		// The jumps to admin policy chains are instead rewritten in the restore file.
+		if networkPolicy.Tier == NetworkPolicyTier {
+			deleteErr := pMgr.deleteOldJumpRulesOnRemove(family, networkPolicy)
+			if deleteErr != nil {
+				return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
+			}
+		}
 
-	// 2. Flush the policy chains and deactivate NPM (if necessary).
-	timer := metrics.StartNewTimer()
-	restoreErr := restore(creator)
-	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
-	if restoreErr != nil {
-		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
-		return fmt.Errorf("failed to flush policies. err: %w", restoreErr)
+		// 2. Flush the policy chains and deactivate NPM (if necessary).
+		creator := pMgr.creatorForRemovingPolicies(family, networkPolicy, chainsToDelete)
+		timer := metrics.StartNewTimer()
+		restoreErr := restore(family, creator)
+		metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
+		if restoreErr != nil {
+			metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
+			return fmt.Errorf("failed to flush %s policies. err: %w", family, restoreErr)
+		}
 	}
 
 	// 3. Delete policy chains in the background.
@@ -96,8 +142,8 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 	return nil
 }
 
-func restore(creator *ioutil.FileCreator) error {
-	err := creator.RunCommandWithFile(util.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
+func restore(family ipFamily, creator *ioutil.FileCreator) error {
+	err := creator.RunCommandWithFile(family.iptablesRestore(), util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
 	if err != nil {
 		return fmt.Errorf("failed to restore iptables file. err: %w", err)
 	}
@@ -105,8 +151,12 @@ func restore(creator *ioutil.FileCreator) error {
 }
 
 // NOTE: if removing multiple policies, would need to add a isLastPolicy argument instead
-func (pMgr *PolicyManager) creatorForRemovingPolicies(allChainNames []string) *ioutil.FileCreator {
-	creator := pMgr.newCreatorWithChains(nil)
+func (pMgr *PolicyManager) creatorForRemovingPolicies(family ipFamily, networkPolicy *NPMNetworkPolicy, allChainNames []string) *ioutil.FileCreator {
+	var tierChains []string
+	if networkPolicy.Tier != NetworkPolicyTier {
+		tierChains = []string{networkPolicy.Tier.ingressChain(), networkPolicy.Tier.egressChain()}
+	}
+	creator := pMgr.newCreatorWithChains(tierChains)
 	// 1. Deactivate NPM (if necessary).
 	if pMgr.isLastPolicy() {
 		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain)
@@ -116,10 +166,70 @@ func (pMgr *PolicyManager) creatorForRemovingPolicies(allChainNames []string) *i
 	for _, chainName := range allChainNames {
 		creator.AddLine("", nil, util.IptablesFlushFlag, chainName)
 	}
+
+	// 3. Rewrite the jumps to the policy chains of an admin tier without the policy.
+	if networkPolicy.Tier != NetworkPolicyTier {
+		writeTierJumps(family, creator, pMgr.tierPolicies(networkPolicy.Tier, nil, networkPolicy.PolicyKey))
+	}
 	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
 
+// tierPolicies returns the policies of an admin tier in the order they are evaluated,
+// including the policies to add and excluding the policy to remove.
+// The caller must hold the policyMap lock.
+func (pMgr *PolicyManager) tierPolicies(tier PolicyTier, policiesToAdd []*NPMNetworkPolicy, policyKeyToRemove string) []*NPMNetworkPolicy {
+	policiesByKey := make(map[string]*NPMNetworkPolicy)
+	for key, policy := range pMgr.policyMap.cache {
+		if policy.Tier == tier && key != policyKeyToRemove {
+			policiesByKey[key] = policy
+		}
+	}
+	for _, policy := range policiesToAdd {
+		if policy.Tier == tier {
+			policiesByKey[policy.PolicyKey] = policy
+		}
+	}
+
+	tierPolicies := make([]*NPMNetworkPolicy, 0, len(policiesByKey))
+	for _, policy := range policiesByKey {
+		tierPolicies = append(tierPolicies, policy)
+	}
+	// the order of policies with the same priority is undefined, but sort by key so that it's deterministic
+	sort.Slice(tierPolicies, func(i, j int) bool {
+		if tierPolicies[i].Priority != tierPolicies[j].Priority {
+			return tierPolicies[i].Priority < tierPolicies[j].Priority
+		}
+		return tierPolicies[i].PolicyKey < tierPolicies[j].PolicyKey
+	})
+	return tierPolicies
+}
+
+// writeTierJumps appends the jumps to the policy chains of an admin tier in the order they are evaluated.
+// The tier's base chains must be declared in the file so that their old jumps are flushed.
+func writeTierJumps(family ipFamily, creator *ioutil.FileCreator, tierPolicies []*NPMNetworkPolicy) {
+	for _, line := range tierJumpLines(family, tierPolicies) {
+		creator.AddLine("", nil, line...)
+	}
+}
+
+// tierJumpLines returns the lines of writeTierJumps.
+func tierJumpLines(family ipFamily, tierPolicies []*NPMNetworkPolicy) [][]string {
+	lines := make([][]string, 0, 2*len(tierPolicies))
+	for _, networkPolicy := range tierPolicies {
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			specs := []string{util.IptablesAppendFlag, networkPolicy.Tier.ingressChain()}
+			lines = append(lines, append(specs, ingressJumpSpecs(family, networkPolicy)...))
+		}
+		if hasEgress {
+			specs := []string{util.IptablesAppendFlag, networkPolicy.Tier.egressChain()}
+			lines = append(lines, append(specs, egressJumpSpecs(family, networkPolicy)...))
+		}
+	}
+	return lines
+}
+
 // returns ingress and egress chain names for the policies
 func chainNames(networkPolicies []*NPMNetworkPolicy) []string {
 	chainNames := make([]string, 0)
@@ -151,38 +261,38 @@ func (pMgr *PolicyManager) newCreatorWithChains(chainNames []string) *ioutil.Fil
 }
 
 // will make a similar func for on update eventually
-func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(policy *NPMNetworkPolicy) error {
+func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(family ipFamily, policy *NPMNetworkPolicy) error {
 	shouldDeleteIngress, shouldDeleteEgress := policy.hasIngressAndEgress()
 	if shouldDeleteIngress {
-		if err := pMgr.deleteJumpRule(policy, true); err != nil {
+		if err := pMgr.deleteJumpRule(family, policy, true); err != nil {
 			return err
 		}
 	}
 	if shouldDeleteEgress {
-		if err := pMgr.deleteJumpRule(policy, false); err != nil {
+		if err := pMgr.deleteJumpRule(family, policy, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction UniqueDirection) error {
+func (pMgr *PolicyManager) deleteJumpRule(family ipFamily, policy *NPMNetworkPolicy, direction UniqueDirection) error {
 	var specs []string
 	var baseChainName string
 	var chainName string
 	if direction == forIngress {
-		specs = ingressJumpSpecs(policy)
+		specs = ingressJumpSpecs(family, policy)
 		baseChainName = util.IptablesAzureIngressChain
 		chainName = policy.ingressChainName()
 	} else {
-		specs = egressJumpSpecs(policy)
+		specs = egressJumpSpecs(family, policy)
 		baseChainName = util.IptablesAzureEgressChain
 		chainName = policy.egressChainName()
 	}
 
 	specs = append([]string{baseChainName}, specs...)
 	timer := metrics.StartNewTimer()
-	errCode, err := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, specs...)
+	errCode, err := pMgr.runIPTablesCommand(family, util.IptablesDeletionFlag, specs...)
 	metrics.RecordIPTablesDeleteLatency(timer)
 	// if this actually happens (don't think it should), could use ignoreErrorsAndRunIPTablesCommand instead with: "Bad rule (does a matching rule exist in that chain?)"
 	if err != nil && errCode != doesNotExistErrorCode && errCode != couldntLoadTargetErrorCode {
@@ -193,31 +303,53 @@ func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction Un
 	return nil
 }
 
-func ingressJumpSpecs(networkPolicy *NPMNetworkPolicy) []string {
+func ingressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
 	chainName := networkPolicy.ingressChainName()
 	specs := []string{util.IptablesJumpFlag, chainName}
-	specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, DstMatch)...)
+	specs = append(specs, matchSetSpecsForNetworkPolicy(family, networkPolicy, DstMatch)...)
+	if networkPolicy.Tier == AdminTier {
+		// a passed packet skips the rest of the AdminNetworkPolicies
+		specs = append(specs, notOnMarkSpecs(util.IptablesAzureIngressPassMarkHex)...)
+	}
 	specs = append(specs, commentSpecs(networkPolicy.commentForJumpToIngress())...)
 	return specs
 }
 
-func egressJumpSpecs(networkPolicy *NPMNetworkPolicy) []string {
+func egressJumpSpecs(family ipFamily, networkPolicy *NPMNetworkPolicy) []string {
 	chainName := networkPolicy.egressChainName()
 	specs := []string{util.IptablesJumpFlag, chainName}
-	specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, SrcMatch)...)
+	specs = append(specs, matchSetSpecsForNetworkPolicy(family, networkPolicy, SrcMatch)...)
+	if networkPolicy.Tier == AdminTier {
+		specs = append(specs, notOnMarkSpecs(util.IptablesAzureEgressPassMarkHex)...)
+	}
 	specs = append(specs, commentSpecs(networkPolicy.commentForJumpToEgress())...)
 	return specs
 }
 
-func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, networkPolicies []*NPMNetworkPolicy) *ioutil.FileCreator {
-	creator := pMgr.newCreatorWithChains(policyChains)
+func (pMgr *PolicyManager) creatorForNewNetworkPolicies(family ipFamily, policyChains []string, networkPolicies []*NPMNetworkPolicy) *ioutil.FileCreator {
+	// the base chains of the admin tiers with new policies are declared so that their jumps can be rewritten in order
+	tiersToRewrite := make([]PolicyTier, 0, 2)
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		for _, networkPolicy := range networkPolicies {
+			if networkPolicy.Tier == tier {
+				tiersToRewrite = append(tiersToRewrite, tier)
+				break
+			}
+		}
+	}
+	allChains := make([]string, 0, len(policyChains)+2*len(tiersToRewrite))
+	allChains = append(allChains, policyChains...)
+	for _, tier := range tiersToRewrite {
+		allChains = append(allChains, tier.ingressChain(), tier.egressChain())
+	}
+	creator := pMgr.newCreatorWithChains(allChains)
 
 	// 1. Activate NPM if necessary
 	if pMgr.isFirstPolicy() {
 		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain) // flush just in case there are old rules
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain)
+		for _, line := range activationLines() {
+			creator.AddLine("", nil, line...)
+		}
 	}
 
 	// 2. Add all rules for the network policies
@@ -225,60 +357,131 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 	egressJumpLineNumber := 1
 	for _, networkPolicy := range networkPolicies {
 		// 2.1 add all rules for the policy chain(s)
-		writeNetworkPolicyRules(creator, networkPolicy)
+		pMgr.writeNetworkPolicyRules(family, creator, networkPolicy)
+
+		if networkPolicy.Tier != NetworkPolicyTier {
+			// jumps to admin policy chains are rewritten below
+			continue
+		}
 
 		// 2.2 add jump rule(s) to the policy chain(s)
 		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
 		if hasIngress {
-			ingressJumpSpecs := insertSpecs(util.IptablesAzureIngressChain, ingressJumpLineNumber, ingressJumpSpecs(networkPolicy))
+			ingressJumpSpecs := insertSpecs(util.IptablesAzureIngressChain, ingressJumpLineNumber, ingressJumpSpecs(family, networkPolicy))
 			creator.AddLine("", nil, ingressJumpSpecs...) // TODO error handler
 			ingressJumpLineNumber++
 		}
 		if hasEgress {
-			egressJumpSpecs := insertSpecs(util.IptablesAzureEgressChain, egressJumpLineNumber, egressJumpSpecs(networkPolicy))
+			egressJumpSpecs := insertSpecs(util.IptablesAzureEgressChain, egressJumpLineNumber, egressJumpSpecs(family, networkPolicy))
 			creator.AddLine("", nil, egressJumpSpecs...) // TODO error handler
 			egressJumpLineNumber++
 		}
 	}
+
+	// 3. Rewrite the jumps to the policy chains of the admin tiers
+	for _, tier := range tiersToRewrite {
+		writeTierJumps(family, creator, pMgr.tierPolicies(tier, networkPolicies, ""))
+	}
 	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
 
+// activationLines returns the rules of the AZURE-NPM chain while there are policies.
+func activationLines() [][]string {
+	return [][]string{
+		{util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAdminIngressChain},
+		{util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain},
+		{util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAdminEgressChain},
+		{util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain},
+		{util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain},
+	}
+}
+
 // write rules for the policy chain(s)
-func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+func (pMgr *PolicyManager) writeNetworkPolicyRules(family ipFamily, creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	for _, line := range pMgr.networkPolicyLines(family, networkPolicy) {
+		creator.AddLine("", nil, line...) // TODO add error handler
+	}
+}
+
+// networkPolicyLines returns the rules of the policy chain(s) in order.
+func (pMgr *PolicyManager) networkPolicyLines(family ipFamily, networkPolicy *NPMNetworkPolicy) [][]string {
+	lines := make([][]string, 0, len(networkPolicy.ACLs))
 	for _, aclPolicy := range networkPolicy.ACLs {
 		var chainName string
 		var actionSpecs []string
+		var passMark string
+		var verdict AuditVerdict
 		if aclPolicy.hasIngress() {
 			chainName = networkPolicy.ingressChainName()
-			if aclPolicy.Target == Allowed {
+			passMark = util.IptablesAzureIngressPassMarkHex
+			switch {
+			case aclPolicy.Target == Allowed:
 				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureIngressAllowMarkChain}
-			} else {
+				verdict = AuditAllow
+			case aclPolicy.Target == Passed:
+				actionSpecs = setMarkSpecs(passMark)
+				verdict = AuditPass
+			case networkPolicy.Tier == NetworkPolicyTier:
 				actionSpecs = setMarkSpecs(util.IptablesAzureIngressDropMarkHex)
+				verdict = AuditDefaultDeny
+			default:
+				// admin policies deny regardless of lower tiers
+				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+				verdict = AuditDrop
 			}
 		} else {
 			chainName = networkPolicy.egressChainName()
-			if aclPolicy.Target == Allowed {
+			passMark = util.IptablesAzureEgressPassMarkHex
+			switch {
+			case aclPolicy.Target == Allowed:
 				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
-			} else {
+				verdict = AuditAllow
+			case aclPolicy.Target == Passed:
+				actionSpecs = setMarkSpecs(passMark)
+				verdict = AuditPass
+			case networkPolicy.Tier == NetworkPolicyTier:
 				actionSpecs = setMarkSpecs(util.IptablesAzureEgressDropMarkHex)
+				verdict = AuditDefaultDeny
+			default:
+				actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+				verdict = AuditDrop
 			}
 		}
+
+		if networkPolicy.audited {
+			// NFLOG doesn't terminate, so the packet continues to the rule with the same matches
+			auditLine := []string{"-A", chainName}
+			auditLine = append(auditLine, pMgr.nflogSpecs(auditPrefix(verdict, networkPolicy.auditTag()))...)
+			auditLine = append(auditLine, iptablesRuleSpecs(family, aclPolicy)...)
+			// the rate limit must be the last match so that only matching packets consume it
+			auditLine = append(auditLine, pMgr.limitSpecs()...)
+			lines = append(lines, auditLine)
+		}
+
 		line := []string{"-A", chainName}
 		line = append(line, actionSpecs...)
-		line = append(line, iptablesRuleSpecs(aclPolicy)...)
-		creator.AddLine("", nil, line...) // TODO add error handler
+		line = append(line, iptablesRuleSpecs(family, aclPolicy)...)
+		lines = append(lines, line)
+
+		if aclPolicy.Target == Passed {
+			// skip the rest of the policy's rules
+			returnSpecs := []string{"-A", chainName, util.IptablesJumpFlag, util.IptablesReturn}
+			returnSpecs = append(returnSpecs, onMarkSpecs(passMark)...)
+			lines = append(lines, returnSpecs)
+		}
 	}
+	return lines
 }
 
-func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
+func iptablesRuleSpecs(family ipFamily, aclPolicy *ACLPolicy) []string {
 	specs := make([]string, 0)
 	if aclPolicy.Protocol != UnspecifiedProtocol {
 		specs = append(specs, util.IptablesProtFlag, string(aclPolicy.Protocol))
 	}
 	specs = append(specs, dstPortSpecs(aclPolicy.DstPorts)...)
-	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.SrcList)...)
-	specs = append(specs, matchSetSpecsFromSetInfo(aclPolicy.DstList)...)
+	specs = append(specs, matchSetSpecsFromSetInfo(family, aclPolicy.SrcList)...)
+	specs = append(specs, matchSetSpecsFromSetInfo(family, aclPolicy.DstList)...)
 	specs = append(specs, commentSpecs(aclPolicy.comment())...)
 	return specs
 }
@@ -290,20 +493,20 @@ func dstPortSpecs(portRange Ports) []string {
 	return []string{util.IptablesDstPortFlag, portRange.toIPTablesString()}
 }
 
-func matchSetSpecsForNetworkPolicy(networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
+func matchSetSpecsForNetworkPolicy(family ipFamily, networkPolicy *NPMNetworkPolicy, matchType MatchType) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs*len(networkPolicy.PodSelectorList))
 	matchString := matchType.toIPTablesString()
 	for _, setInfo := range networkPolicy.PodSelectorList {
-		specs = append(specs, setInfo.matchSetSpecs(matchString)...)
+		specs = append(specs, setInfo.matchSetSpecs(family, matchString)...)
 	}
 	return specs
 }
 
-func matchSetSpecsFromSetInfo(setInfoList []SetInfo) []string {
+func matchSetSpecsFromSetInfo(family ipFamily, setInfoList []SetInfo) []string {
 	specs := make([]string, 0, maxLengthForMatchSetSpecs*len(setInfoList))
 	for _, setInfo := range setInfoList {
 		matchString := setInfo.MatchType.toIPTablesString()
-		specs = append(specs, setInfo.matchSetSpecs(matchString)...)
+		specs = append(specs, setInfo.matchSetSpecs(family, matchString)...)
 	}
 	return specs
 }
@@ -317,6 +520,36 @@ func setMarkSpecs(mark string) []string {
 	}
 }
 
+func (pMgr *PolicyManager) nflogSpecs(prefix string) []string {
+	return []string{
+		util.IptablesJumpFlag,
+		util.IptablesNFLOG,
+		util.IptablesNFLOGGroupFlag,
+		strconv.Itoa(pMgr.Audit.NFLOGGroup),
+		util.IptablesNFLOGPrefixFlag,
+		prefix,
+	}
+}
+
+func (pMgr *PolicyManager) limitSpecs() []string {
+	return []string{
+		util.IptablesModuleFlag,
+		util.IptablesLimitModuleFlag,
+		util.IptablesLimitFlag,
+		fmt.Sprintf("%d/second", pMgr.Audit.RateLimitPerSecond),
+	}
+}
+
+func notOnMarkSpecs(mark string) []string {
+	return []string{
+		util.IptablesModuleFlag,
+		util.IptablesMarkVerb,
+		util.IptablesNotFlag,
+		util.IptablesMarkFlag,
+		mark,
+	}
+}
+
 func commentSpecs(comment string) []string {
 	return []string{
 		util.IptablesModuleFlag,
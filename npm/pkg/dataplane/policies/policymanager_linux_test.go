@@ -227,7 +227,7 @@ func TestCreatorForAddPolicies(t *testing.T) {
 
 	// 1. test with activation
 	policies := []*NPMNetworkPolicy{allTestNetworkPolicies[0]}
-	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
 	actualLines := strings.Split(creator.ToString(), "\n")
 	expectedLines := []string{
 		"*filter",
@@ -236,7 +236,9 @@ func TestCreatorForAddPolicies(t *testing.T) {
 		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
 		"-F AZURE-NPM",
 		// activation rules for AZURE-NPM chain
+		"-A AZURE-NPM -j AZURE-NPM-ANP-INGRESS",
 		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ANP-EGRESS",
 		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
 		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
 		// policy 1
@@ -254,7 +256,7 @@ func TestCreatorForAddPolicies(t *testing.T) {
 	// 2. test without activation
 	// add a policy to the cache so that we don't activate (the cache doesn't impact creatorForNewNetworkPolicies)
 	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{allTestNetworkPolicies[0]}, nil))
-	creator = pMgr.creatorForNewNetworkPolicies(chainNames(allTestNetworkPolicies), allTestNetworkPolicies)
+	creator = pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(allTestNetworkPolicies), allTestNetworkPolicies)
 	actualLines = strings.Split(creator.ToString(), "\n")
 	expectedLines = []string{
 		"*filter",
@@ -282,6 +284,246 @@ func TestCreatorForAddPolicies(t *testing.T) {
 	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
 }
 
+func TestCreatorForAddPoliciesIPv6(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	listNetPol := &NPMNetworkPolicy{
+		Namespace:   "y",
+		PolicyKey:   "y/test4",
+		ACLPolicyID: "azure-acl-y-test4",
+		ACLs: []*ACLPolicy{
+			{
+				SrcList:   []SetInfo{{ipsets.TestKeyNSList.Metadata, true, SrcMatch}},
+				Target:    Allowed,
+				Direction: Ingress,
+				Protocol:  UnspecifiedProtocol,
+			},
+		},
+	}
+	policies := []*NPMNetworkPolicy{ingressNetPol, listNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(ipv6, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+
+	// hash sets are swapped for their inet6 twins, and lists aren't
+	cidrSetIPv6 := ipsets.GetIPv6HashedName(ipsets.TestCIDRSet.HashedName)
+	keyPodSetIPv6 := ipsets.GetIPv6HashedName(ipsets.TestKeyPodSet.HashedName)
+	nsSetIPv6 := ipsets.GetIPv6HashedName(ipsets.TestNSSet.HashedName)
+	listNetPolChain := listNetPol.ingressChainName()
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", ingressNetPolChain),
+		fmt.Sprintf(":%s - -", listNetPolChain),
+		"-F AZURE-NPM",
+		"-A AZURE-NPM -j AZURE-NPM-ANP-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ANP-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf(
+			"-A %s -j MARK --set-mark %s -p TCP --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s",
+			ingressNetPolChain, util.IptablesAzureIngressDropMarkHex, cidrSetIPv6, keyPodSetIPv6, ingressDropComment,
+		),
+		fmt.Sprintf(
+			"-I AZURE-NPM-INGRESS 1 -j %s -m set --match-set %s dst -m set --match-set %s dst -m comment --comment %s",
+			ingressNetPolChain, keyPodSetIPv6, nsSetIPv6, ingressNetPolJumpComment,
+		),
+		fmt.Sprintf(
+			"-A %s -j AZURE-NPM-INGRESS-ALLOW-MARK -m set --match-set %s src -m comment --comment ALLOW-FROM-nslabel-test-keyNS-list",
+			listNetPolChain, ipsets.TestKeyNSList.HashedName,
+		),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 2 -j %s -m comment --comment INGRESS-POLICY-y/test4-TO-all-IN-ns-y", listNetPolChain),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestCreatorForAdminPolicies(t *testing.T) {
+	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	adminACL := func(target Verdict, direction Direction) *ACLPolicy {
+		acl := &ACLPolicy{Target: target, Direction: direction, Protocol: UnspecifiedProtocol}
+		if direction == Ingress {
+			acl.SrcList = []SetInfo{{ipsets.TestCIDRSet.Metadata, true, SrcMatch}}
+		} else {
+			acl.DstList = []SetInfo{{ipsets.TestCIDRSet.Metadata, true, DstMatch}}
+		}
+		return acl
+	}
+
+	lowPriority := NewAdminNPMNetworkPolicy(AdminTier, "low", 20)
+	lowPriority.ACLs = []*ACLPolicy{adminACL(Dropped, Ingress)}
+	highPriority := NewAdminNPMNetworkPolicy(AdminTier, "high", 10)
+	highPriority.ACLs = []*ACLPolicy{adminACL(Passed, Ingress), adminACL(Allowed, Egress)}
+	baseline := NewAdminNPMNetworkPolicy(BaselineTier, "default", 0)
+	baseline.ACLs = []*ACLPolicy{adminACL(Dropped, Egress)}
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{lowPriority}, nil))
+
+	lowIngressJump := fmt.Sprintf("-j %s -m mark ! --mark 0x100/0x100 -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/low-TO-all",
+		lowPriority.ingressChainName())
+
+	// 1. a policy with a lower priority value is jumped to first, and jumps to baseline policies don't check the pass mark
+	policies := []*NPMNetworkPolicy{highPriority, baseline}
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", highPriority.ingressChainName()),
+		fmt.Sprintf(":%s - -", highPriority.egressChainName()),
+		fmt.Sprintf(":%s - -", baseline.egressChainName()),
+		":AZURE-NPM-ANP-INGRESS - -",
+		":AZURE-NPM-ANP-EGRESS - -",
+		":AZURE-NPM-BANP-INGRESS - -",
+		":AZURE-NPM-BANP-EGRESS - -",
+		fmt.Sprintf("-A %s -j MARK --set-mark 0x100/0x100 -m set --match-set %s src -m comment --comment PASS-FROM-cidr-test-cidr-set",
+			highPriority.ingressChainName(), ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A %s -j RETURN -m mark --mark 0x100/0x100", highPriority.ingressChainName()),
+		fmt.Sprintf("-A %s -j AZURE-NPM-ACCEPT -m set --match-set %s dst -m comment --comment ALLOW-TO-cidr-test-cidr-set",
+			highPriority.egressChainName(), ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A %s -j DROP -m set --match-set %s dst -m comment --comment DROP-TO-cidr-test-cidr-set",
+			baseline.egressChainName(), ipsets.TestCIDRSet.HashedName),
+		fmt.Sprintf("-A AZURE-NPM-ANP-INGRESS -j %s -m mark ! --mark 0x100/0x100 -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/high-TO-all",
+			highPriority.ingressChainName()),
+		fmt.Sprintf("-A AZURE-NPM-ANP-EGRESS -j %s -m mark ! --mark 0x1000/0x1000 -m comment --comment EGRESS-POLICY-AdminNetworkPolicy/high-FROM-all",
+			highPriority.egressChainName()),
+		"-A AZURE-NPM-ANP-INGRESS " + lowIngressJump,
+		fmt.Sprintf("-A AZURE-NPM-BANP-EGRESS -j %s -m comment --comment EGRESS-POLICY-BaselineAdminNetworkPolicy/default-FROM-all",
+			baseline.egressChainName()),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. the jumps of the tier are rewritten without the removed policy
+	pMgr.policyMap.cache[highPriority.PolicyKey] = highPriority
+	creator = pMgr.creatorForRemovingPolicies(ipv4, highPriority, chainNames([]*NPMNetworkPolicy{highPriority}))
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"*filter",
+		":AZURE-NPM-ANP-INGRESS - -",
+		":AZURE-NPM-ANP-EGRESS - -",
+		fmt.Sprintf("-F %s", highPriority.ingressChainName()),
+		fmt.Sprintf("-F %s", highPriority.egressChainName()),
+		"-A AZURE-NPM-ANP-INGRESS " + lowIngressJump,
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestCreatorForAuditedPolicies(t *testing.T) {
+	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	cfg := &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		Audit: &AuditCfg{
+			Namespaces:         []string{"x"},
+			PolicyKeys:         []string{"AdminNetworkPolicy/deny"},
+			NFLOGGroup:         100,
+			RateLimitPerSecond: 10,
+		},
+	}
+	pMgr := NewPolicyManager(ioshim, cfg)
+
+	// copies since AddPolicies marks the policies as audited
+	auditedNetPol := *bothDirectionsNetPol
+	unauditedNetPol := *egressNetPol
+	adminNetPol := NewAdminNPMNetworkPolicy(AdminTier, "deny", 10)
+	adminNetPol.ACLs = []*ACLPolicy{
+		{
+			SrcList:   []SetInfo{{ipsets.TestCIDRSet.Metadata, true, SrcMatch}},
+			Target:    Dropped,
+			Direction: Ingress,
+			Protocol:  UnspecifiedProtocol,
+		},
+	}
+	policies := []*NPMNetworkPolicy{&auditedNetPol, &unauditedNetPol, adminNetPol}
+	require.NoError(t, pMgr.AddPolicies(policies, nil))
+
+	auditRule := func(chain, prefix, matchSpecs string) string {
+		return fmt.Sprintf("-A %s -j NFLOG --nflog-group 100 --nflog-prefix %s %s -m limit --limit 10/second", chain, prefix, matchSpecs)
+	}
+	ingressDropMatches := fmt.Sprintf("-p TCP --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s",
+		ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment)
+	ingressAllowMatches := fmt.Sprintf("-m set --match-set %s src -m comment --comment %s", ipsets.TestCIDRSet.HashedName, ingressAllowComment)
+	egressDropMatches := fmt.Sprintf("-p UDP --dport 144 -m set --match-set %s dst -m comment --comment %s", ipsets.TestCIDRSet.HashedName, egressDropComment)
+	egressAllowMatches := fmt.Sprintf("-m set --match-set %s dst -m comment --comment %s", ipsets.TestNamedportSet.HashedName, egressAllowComment)
+	adminDropMatches := fmt.Sprintf("-m set --match-set %s src -m comment --comment DROP-FROM-cidr-test-cidr-set", ipsets.TestCIDRSet.HashedName)
+
+	// the NFLOG rules precede the rules of audited policies, and the policies were added so NPM isn't activated again
+	creator := pMgr.creatorForNewNetworkPolicies(ipv4, chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf(":%s - -", egressNetPolChain),
+		fmt.Sprintf(":%s - -", adminNetPol.ingressChainName()),
+		":AZURE-NPM-ANP-INGRESS - -",
+		":AZURE-NPM-ANP-EGRESS - -",
+		auditRule(bothDirectionsNetPolIngressChain, "DEFAULT-DENY:azure-acl-x-test1", ingressDropMatches),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		auditRule(bothDirectionsNetPolIngressChain, "ALLOW:azure-acl-x-test1", ingressAllowMatches),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		auditRule(bothDirectionsNetPolEgressChain, "DEFAULT-DENY:azure-acl-x-test1", egressDropMatches),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		auditRule(bothDirectionsNetPolEgressChain, "ALLOW:azure-acl-x-test1", egressAllowMatches),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		fmt.Sprintf("-I AZURE-NPM-INGRESS 1 %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 1 %s", ingressEgressNetPolEgressJump),
+		fmt.Sprintf("-A %s %s", egressNetPolChain, egressAllowRule),
+		fmt.Sprintf("-I AZURE-NPM-EGRESS 2 %s", egressNetPolJump),
+		auditRule(adminNetPol.ingressChainName(), "DROP:azure-acl-AdminNetworkPolicy-deny", adminDropMatches),
+		fmt.Sprintf("-A %s -j DROP %s", adminNetPol.ingressChainName(), adminDropMatches),
+		fmt.Sprintf("-A AZURE-NPM-ANP-INGRESS -j %s -m mark ! --mark 0x100/0x100 -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/deny-TO-all",
+			adminNetPol.ingressChainName()),
+		"COMMIT",
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// the listener can resolve the tags of the audited policies
+	audited, ok := pMgr.AuditedPolicy("azure-acl-x-test1")
+	require.True(t, ok)
+	require.Equal(t, AuditedPolicy{PolicyKey: "x/test1", Namespace: "x"}, audited)
+	audited, ok = pMgr.AuditedPolicy("azure-acl-AdminNetworkPolicy-deny")
+	require.True(t, ok)
+	require.Equal(t, AuditedPolicy{PolicyKey: "AdminNetworkPolicy/deny"}, audited)
+	_, ok = pMgr.AuditedPolicy("azure-acl-z-test3")
+	require.False(t, ok)
+}
+
+func TestAddAndRemovePolicyDualStack(t *testing.T) {
+	deleteJump := func(iptables string, family ipFamily) testutils.TestCmd {
+		args := []string{iptables, "-w", "60", "-D", util.IptablesAzureIngressChain}
+		return testutils.TestCmd{Cmd: append(args, ingressJumpSpecs(family, ingressNetPol)...)}
+	}
+	calls := []testutils.TestCmd{
+		fakeIPTablesRestoreCommand,
+		fakeIP6TablesRestoreCommand,
+		deleteJump("iptables", ipv4),
+		fakeIPTablesRestoreCommand,
+		deleteJump("ip6tables", ipv6),
+		fakeIP6TablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, dualStackConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{ingressNetPol}, nil))
+	require.NoError(t, pMgr.RemovePolicy(ingressNetPol.PolicyKey))
+	_, ok := pMgr.GetPolicy(ingressNetPol.PolicyKey)
+	require.False(t, ok)
+}
+
 func TestCreatorForRemovePolicies(t *testing.T) {
 	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
 	ioshim := common.NewMockIOShim(calls)
@@ -290,7 +532,7 @@ func TestCreatorForRemovePolicies(t *testing.T) {
 
 	// 1. test without deactivation (i.e. flushing azure chain when removing the last policy)
 	// hack: the cache is empty (and len(cache) != len(allTestNetworkPolicies)), so shouldDeactivate will be false
-	creator := pMgr.creatorForRemovingPolicies(chainNames(allTestNetworkPolicies))
+	creator := pMgr.creatorForRemovingPolicies(ipv4, bothDirectionsNetPol, chainNames(allTestNetworkPolicies))
 	actualLines := strings.Split(creator.ToString(), "\n")
 	expectedLines := []string{
 		"*filter",
@@ -307,7 +549,7 @@ func TestCreatorForRemovePolicies(t *testing.T) {
 	// add to the cache so that we deactivate
 	policy := TestNetworkPolicies[0]
 	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{policy}, nil))
-	creator = pMgr.creatorForRemovingPolicies(chainNames([]*NPMNetworkPolicy{policy}))
+	creator = pMgr.creatorForRemovingPolicies(ipv4, policy, chainNames([]*NPMNetworkPolicy{policy}))
 	actualLines = strings.Split(creator.ToString(), "\n")
 	expectedLines = []string{
 		"*filter",
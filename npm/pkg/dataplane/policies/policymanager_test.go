@@ -19,6 +19,12 @@ var (
 		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
 	}
 
+	dualStackConfig = &PolicyManagerCfg{
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		EnableIPv6:           true,
+	}
+
 	// below epList is no-op for linux
 	epList = map[string]string{
 		"10.0.0.1": "test123",
@@ -111,7 +117,7 @@ func TestBootup(t *testing.T) {
 
 	require.NoError(t, pMgr.Bootup(epIDs))
 
-	expectedNumACLs := 11
+	expectedNumACLs := 16
 	if util.IsWindowsDP() {
 		expectedNumACLs = 0
 	}
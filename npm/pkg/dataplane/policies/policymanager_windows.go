@@ -133,6 +133,11 @@ func (pMgr *PolicyManager) reconcile() {
 // AddAllPolicies is used in Windows to add all NetworkPolicies to an endpoint.
 // Will make a series of sequential HNS ADD calls based on MaxBatchedACLsPerPod.
 // A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
+// verifyChains is a no-op in Windows, where there are no chains.
+func (pMgr *PolicyManager) verifyChains(_ bool) ([]string, error) {
+	return nil, nil
+}
+
 func (pMgr *PolicyManager) AddAllPolicies(policyKeys map[string]struct{}, epToModifyID, epToModifyIP string) (map[string]struct{}, error) {
 	pMgr.policyMap.Lock()
 	defer pMgr.policyMap.Unlock()
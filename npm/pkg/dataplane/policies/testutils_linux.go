@@ -10,6 +10,7 @@ import (
 var (
 	fakeIPTablesRestoreCommand        = testutils.TestCmd{Cmd: []string{"iptables-restore", "-w", "60", "-T", "filter", "--noflush"}}
 	fakeIPTablesRestoreFailureCommand = testutils.TestCmd{Cmd: []string{"iptables-restore", "-w", "60", "-T", "filter", "--noflush"}, ExitCode: 1}
+	fakeIP6TablesRestoreCommand       = testutils.TestCmd{Cmd: []string{"ip6tables-restore", "-w", "60", "-T", "filter", "--noflush"}}
 
 	listLineNumbersCommandStrings = []string{"iptables", "-w", "60", "-t", "filter", "-n", "-L", "FORWARD", "--line-numbers"}
 	listAllCommandStrings         = []string{"iptables", "-w", "60", "-t", "filter", "-n", "-L"}
@@ -25,15 +26,19 @@ func GetAddPolicyFailureTestCalls(_ *NPMNetworkPolicy) []testutils.TestCmd {
 
 func GetRemovePolicyTestCalls(policy *NPMNetworkPolicy) []testutils.TestCmd {
 	calls := []testutils.TestCmd{}
+	if policy.Tier != NetworkPolicyTier {
+		// jumps to admin policy chains are rewritten in the restore file
+		return append(calls, fakeIPTablesRestoreCommand)
+	}
 	hasIngress, hasEgress := policy.hasIngressAndEgress()
 	if hasIngress {
 		deleteIngressJumpSpecs := []string{"iptables", "-w", "60", "-D", util.IptablesAzureIngressChain}
-		deleteIngressJumpSpecs = append(deleteIngressJumpSpecs, ingressJumpSpecs(policy)...)
+		deleteIngressJumpSpecs = append(deleteIngressJumpSpecs, ingressJumpSpecs(ipv4, policy)...)
 		calls = append(calls, testutils.TestCmd{Cmd: deleteIngressJumpSpecs})
 	}
 	if hasEgress {
 		deleteEgressJumpSpecs := []string{"iptables", "-w", "60", "-D", util.IptablesAzureEgressChain}
-		deleteEgressJumpSpecs = append(deleteEgressJumpSpecs, egressJumpSpecs(policy)...)
+		deleteEgressJumpSpecs = append(deleteEgressJumpSpecs, egressJumpSpecs(ipv4, policy)...)
 		calls = append(calls, testutils.TestCmd{Cmd: deleteEgressJumpSpecs})
 	}
 
@@ -7,6 +7,7 @@ import (
 	controllersv2 "github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/v2"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
@@ -34,6 +35,9 @@ type K8SControllersV2 struct {
 	NamespaceControllerV2 *controllersv2.NamespaceController     //nolint:structcheck // false lint error
 	NpmNamespaceCacheV2   *controllersv2.NpmNamespaceCache       //nolint:structcheck // false lint error
 	NetPolControllerV2    *controllersv2.NetworkPolicyController //nolint:structcheck // false lint error
+	// AdminNetPolControllerV2 and BaselineAdminNetPolControllerV2 are nil unless AdminNetworkPolicies are enabled
+	AdminNetPolControllerV2         *controllersv2.AdminNetworkPolicyController //nolint:structcheck // false lint error
+	BaselineAdminNetPolControllerV2 *controllersv2.AdminNetworkPolicyController //nolint:structcheck // false lint error
 }
 
 // Informers are the informers for the k8s controllers
@@ -42,6 +46,10 @@ type Informers struct {
 	PodInformer     coreinformers.PodInformer                 //nolint:structcheck // false lint error
 	NsInformer      coreinformers.NamespaceInformer           //nolint:structcheck // false lint error
 	NpInformer      networkinginformers.NetworkPolicyInformer //nolint:structcheck // false lint error
+	// DynamicInformerFactory, AnpInformer, and BanpInformer are nil unless AdminNetworkPolicies are enabled
+	DynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory //nolint:structcheck // false lint error
+	AnpInformer            informers.GenericInformer                    //nolint:structcheck // false lint error
+	BanpInformer           informers.GenericInformer                    //nolint:structcheck // false lint error
 }
 
 // AzureConfig captures the Azure specific configurations and fields
@@ -28,10 +28,12 @@ const (
 )
 
 var (
-	Iptables        = IptablesLegacy
-	Ip6tables       = Ip6tablesLegacy //nolint (avoid warning to capitalize this p)
-	IptablesSave    = IptablesSaveLegacy
-	IptablesRestore = IptablesRestoreLegacy
+	Iptables         = IptablesLegacy
+	Ip6tables        = Ip6tablesLegacy //nolint (avoid warning to capitalize this p)
+	IptablesSave     = IptablesSaveLegacy
+	Ip6tablesSave    = Ip6tablesSaveLegacy //nolint (avoid warning to capitalize this p)
+	IptablesRestore  = IptablesRestoreLegacy
+	Ip6tablesRestore = Ip6tablesRestoreLegacy //nolint (avoid warning to capitalize this p)
 )
 
 // iptables related constants.
@@ -40,7 +42,12 @@ const (
 	PlaceAzureChainFirst             = true
 
 	IptablesNft                string = "iptables-nft"
-	Ip6tablesLegacy            string = "ip6tables" //nolint (avoid warning to capitalize this p)
+	Ip6tablesLegacy            string = "ip6tables"             //nolint (avoid warning to capitalize this p)
+	Ip6tablesNft               string = "ip6tables-nft"         //nolint (avoid warning to capitalize this p)
+	Ip6tablesRestoreLegacy     string = "ip6tables-restore"     //nolint (avoid warning to capitalize this p)
+	Ip6tablesRestoreNft        string = "ip6tables-nft-restore" //nolint (avoid warning to capitalize this p)
+	Ip6tablesSaveLegacy        string = "ip6tables-save"        //nolint (avoid warning to capitalize this p)
+	Ip6tablesSaveNft           string = "ip6tables-nft-save"    //nolint (avoid warning to capitalize this p)
 	IptablesSaveNft            string = "iptables-nft-save"
 	IptablesRestoreNft         string = "iptables-nft-restore"
 	IptablesLegacy             string = "iptables"
@@ -93,6 +100,11 @@ const (
 	IptablesFilterTable        string = "filter"
 	IptablesCommentModuleFlag  string = "comment"
 	IptablesCommentFlag        string = "--comment"
+	IptablesNFLOG              string = "NFLOG"
+	IptablesNFLOGGroupFlag     string = "--nflog-group"
+	IptablesNFLOGPrefixFlag    string = "--nflog-prefix"
+	IptablesLimitModuleFlag    string = "limit"
+	IptablesLimitFlag          string = "--limit"
 	IptablesAddCommentFlag
 
 	IptablesTableFlag       string = "-t"
@@ -122,6 +134,18 @@ const (
 	IptablesAzureIngressPolicyChainPrefix string = "AZURE-NPM-INGRESS"
 	IptablesAzureEgressPolicyChainPrefix  string = "AZURE-NPM-EGRESS"
 
+	// NPM v2 Chains for AdminNetworkPolicies (evaluated before NetworkPolicies)
+	// and BaselineAdminNetworkPolicies (evaluated after NetworkPolicies).
+	// The policy chain prefixes are shorter than the base chains since chain names can have at most 28 characters.
+	IptablesAzureAdminIngressChain                string = "AZURE-NPM-ANP-INGRESS"
+	IptablesAzureAdminEgressChain                 string = "AZURE-NPM-ANP-EGRESS"
+	IptablesAzureBaselineIngressChain             string = "AZURE-NPM-BANP-INGRESS"
+	IptablesAzureBaselineEgressChain              string = "AZURE-NPM-BANP-EGRESS"
+	IptablesAzureAdminIngressPolicyChainPrefix    string = "AZURE-NPM-ANP-IN"
+	IptablesAzureAdminEgressPolicyChainPrefix     string = "AZURE-NPM-ANP-EG"
+	IptablesAzureBaselineIngressPolicyChainPrefix string = "AZURE-NPM-BANP-IN"
+	IptablesAzureBaselineEgressPolicyChainPrefix  string = "AZURE-NPM-BANP-EG"
+
 	// Below chain exists only in NPM before v1.2.6
 	IptablesAzureTargetSetsChain string = "AZURE-NPM-TARGET-SETS"
 	// Below chain existing only in NPM before v1.2.7
@@ -146,6 +170,10 @@ const (
 	IptablesAzureIngressAllowMarkHex string = "0x200/0x200"
 	IptablesAzureIngressDropMarkHex  string = "0x400/0x400"
 	IptablesAzureEgressDropMarkHex   string = "0x800/0x800"
+	// the pass marks skip the rest of the AdminNetworkPolicies for a direction.
+	// NPM uses the 8th and 12th bit for them
+	IptablesAzureIngressPassMarkHex string = "0x100/0x100"
+	IptablesAzureEgressPassMarkHex  string = "0x1000/0x1000"
 
 	// marks in NPM v1
 	IptablesAzureIngressMarkHex string = "0x2000"
@@ -285,6 +313,9 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 		Iptables = IptablesNft
 		IptablesSave = IptablesSaveNft
 		IptablesRestore = IptablesRestoreNft
+		Ip6tables = Ip6tablesNft
+		Ip6tablesRestore = Ip6tablesRestoreNft
+		Ip6tablesSave = Ip6tablesSaveNft
 	} else {
 		lCmd := ioShim.Exec.Command(IptablesSaveLegacy, "-t", "mangle")
 
@@ -298,6 +329,9 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 			Iptables = IptablesLegacy
 			IptablesSave = IptablesSaveLegacy
 			IptablesRestore = IptablesRestoreLegacy
+			Ip6tables = Ip6tablesLegacy
+			Ip6tablesRestore = Ip6tablesRestoreLegacy
+			Ip6tablesSave = Ip6tablesSaveLegacy
 		} else {
 			lsavecmd := ioShim.Exec.Command(IptablesSaveNft)
 			lsaveoutput, err := lsavecmd.CombinedOutput()
@@ -321,10 +355,16 @@ func DetectIptablesVersion(ioShim *common.IOShim) {
 				Iptables = IptablesLegacy
 				IptablesSave = IptablesSaveLegacy
 				IptablesRestore = IptablesRestoreLegacy
+				Ip6tables = Ip6tablesLegacy
+				Ip6tablesRestore = Ip6tablesRestoreLegacy
+				Ip6tablesSave = Ip6tablesSaveLegacy
 			} else {
 				Iptables = IptablesNft
 				IptablesSave = IptablesSaveNft
 				IptablesRestore = IptablesRestoreNft
+				Ip6tables = Ip6tablesNft
+				Ip6tablesRestore = Ip6tablesRestoreNft
+				Ip6tablesSave = Ip6tablesSaveNft
 			}
 		}
 	}
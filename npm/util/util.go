@@ -342,6 +342,26 @@ func IsIPV4(ip string) bool {
 	return address.Is4()
 }
 
+// IsIPV6 is IsIPV4 for IPv6 addresses and CIDRs.
+func IsIPV6(ip string) bool {
+	isIPBlock := strings.Contains(ip, "/")
+	ipOnly := strings.Split(ip, "/")
+	address, err := netip.ParseAddr(ipOnly[0])
+	if err != nil || !address.Is6() || address.Is4In6() {
+		return false
+	}
+	if !isIPBlock {
+		return true
+	}
+
+	prefix, err := netip.ParsePrefix(ip)
+	if err != nil {
+		return false
+	}
+	// like 0.0.0.0/0, only ::/0 may have a prefix length of 0
+	return prefix.Bits() > 0 || address.IsUnspecified()
+}
+
 // Get preferred outbound ip of this machine
 // source: https://stackoverflow.com/questions/23558425/how-do-i-get-the-local-ip-address-in-go
 func NodeIP() (string, error) {
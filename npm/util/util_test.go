@@ -451,3 +451,24 @@ func TestNodeIP(t *testing.T) {
 	_, err := NodeIP()
 	require.Nil(t, err, "NodeIP() returned error")
 }
+
+func TestIsIPV6(t *testing.T) {
+	tests := map[string]bool{
+		"2001:db8::1":            true,
+		"2001:db8::/32":          true,
+		"::/0":                   true,
+		"2001:db8::/0":           false,
+		"2001:db8::/129":         false,
+		"::ffff:10.0.0.1":        false,
+		"10.0.0.1":               false,
+		"10.0.0.0/8":             false,
+		"2001:db8::1,tcp:80":     false,
+		"":                       false,
+		"2001:db8::1 nomatch":    false,
+		"fe80::1%eth0":           true,
+		"2345:0425:2CA1::5673:1": true,
+	}
+	for ip, want := range tests {
+		require.Equal(t, want, IsIPV6(ip), ip)
+	}
+}
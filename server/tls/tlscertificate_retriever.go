@@ -8,6 +8,7 @@ import "time"
 type TlsSettings struct {
 	TLSSubjectName                     string
 	TLSCertificatePath                 string
+	TLSClientCAPath                    string
 	TLSPort                            string
 	KeyVaultURL                        string
 	KeyVaultCertificateName            string
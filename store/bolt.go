@@ -0,0 +1,334 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// BoltExtension - Extension of the bolt database replacing a json store file.
+	BoltExtension = ".db"
+
+	// MigratedExtension - Extension added to a json store file once it has been imported into a bolt database.
+	MigratedExtension = ".migrated"
+
+	// bucket holding the key value pairs.
+	boltBucket = "state"
+
+	// time to wait for another process to close the database.
+	boltOpenTimeout = DefaultLockTimeout
+)
+
+// Store backends.
+const (
+	BackendJSON = "json"
+	BackendBolt = "bolt"
+)
+
+var errUnsupportedBackend = errors.New("unsupported store backend")
+
+// boltStore is an implementation of KeyValueStore using an embedded bolt database.
+// Every write is committed to disk in its own transaction, instead of rewriting the whole store.
+type boltStore struct {
+	fileName     string
+	jsonFileName string
+	db           *bolt.DB
+	processLock  processlock.Interface
+	sync.Mutex
+	logger *zap.Logger
+}
+
+// NewStore creates the KeyValueStore of the given backend for a json store file.
+// The bolt backend keeps its database next to the json file, with the BoltExtension instead of .json.
+func NewStore(backend, jsonFileName string, lockclient processlock.Interface, logger *zap.Logger) (KeyValueStore, error) {
+	switch backend {
+	case BackendJSON, "":
+		return NewJsonFileStore(jsonFileName, lockclient, logger)
+	case BackendBolt:
+		return NewBoltStore(strings.TrimSuffix(jsonFileName, ".json")+BoltExtension, jsonFileName, lockclient, logger)
+	default:
+		return nil, errors.Wrapf(errUnsupportedBackend, "%s", backend)
+	}
+}
+
+// NewBoltStore creates a new boltStore object, accessed as a KeyValueStore.
+// If jsonFileName is set and the file exists, its key value pairs are imported into the database
+// the first time it is opened, and the file is renamed with the MigratedExtension.
+func NewBoltStore(fileName, jsonFileName string, lockclient processlock.Interface, logger *zap.Logger) (KeyValueStore, error) {
+	if fileName == "" {
+		return &boltStore{}, errors.New("need to pass in a bolt file path")
+	}
+
+	return &boltStore{
+		fileName:     fileName,
+		jsonFileName: jsonFileName,
+		processLock:  lockclient,
+		logger:       logger,
+	}, nil
+}
+
+func (kvs *boltStore) Exists() bool {
+	if _, err := os.Stat(kvs.fileName); err == nil {
+		return true
+	}
+	return kvs.pendingMigration()
+}
+
+// pendingMigration returns whether there is a json store file left to import.
+func (kvs *boltStore) pendingMigration() bool {
+	if kvs.jsonFileName == "" {
+		return false
+	}
+	_, err := os.Stat(kvs.jsonFileName)
+	return err == nil
+}
+
+// open opens the database if it is not open yet, creating it if needed.
+func (kvs *boltStore) open() error {
+	if kvs.db != nil {
+		return nil
+	}
+
+	db, err := bolt.Open(kvs.fileName, 0o644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return errors.Wrapf(err, "failed to open bolt store %s", kvs.fileName)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(boltBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return errors.Wrap(err, "failed to create bolt bucket")
+	}
+
+	kvs.db = db
+
+	if kvs.pendingMigration() {
+		if err := kvs.migrate(); err != nil {
+			kvs.close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close closes the database so that other processes can open it.
+func (kvs *boltStore) close() {
+	if kvs.db == nil {
+		return
+	}
+
+	if err := kvs.db.Close(); err != nil {
+		kvs.printf("Failed to close bolt store", zap.String("fileName", kvs.fileName), zap.Error(err))
+	}
+	kvs.db = nil
+}
+
+// migrate imports the key value pairs of the json store file in a single transaction.
+// Keys already in the database are not overwritten.
+func (kvs *boltStore) migrate() error {
+	b, err := os.ReadFile(kvs.jsonFileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read json store %s", kvs.jsonFileName)
+	}
+
+	data := make(map[string]json.RawMessage)
+	if len(b) != 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return errors.Wrapf(err, "failed to decode json store %s", kvs.jsonFileName)
+		}
+	}
+
+	err = kvs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(boltBucket))
+		for key, value := range data {
+			if bucket.Get([]byte(key)) != nil {
+				continue
+			}
+			if err := bucket.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to import json store %s", kvs.jsonFileName)
+	}
+
+	if err := os.Rename(kvs.jsonFileName, kvs.jsonFileName+MigratedExtension); err != nil {
+		return errors.Wrapf(err, "failed to rename migrated json store %s", kvs.jsonFileName)
+	}
+
+	kvs.printf("Migrated json store to bolt store", zap.String("jsonFileName", kvs.jsonFileName),
+		zap.String("fileName", kvs.fileName), zap.Int("keys", len(data)))
+
+	return nil
+}
+
+// Read restores the value for the given key from persistent store.
+func (kvs *boltStore) Read(key string, value interface{}) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if kvs.db == nil && !kvs.Exists() {
+		return ErrKeyNotFound
+	}
+
+	if err := kvs.open(); err != nil {
+		return err
+	}
+
+	var raw []byte
+	err := kvs.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(boltBucket)).Get([]byte(key)); v != nil {
+			// The value is only valid for the life of the transaction.
+			raw = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read bolt store")
+	}
+
+	if raw == nil {
+		return ErrKeyNotFound
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+// Write saves the given key value pair to persistent store.
+func (kvs *boltStore) Write(key string, value interface{}) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if err = kvs.open(); err != nil {
+		return err
+	}
+
+	err = kvs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(boltBucket)).Put([]byte(key), raw)
+	})
+
+	return errors.Wrap(err, "failed to write bolt store")
+}
+
+// Flush commits in-memory state to persistent store.
+// Writes are committed as they happen, so there is nothing left to flush.
+func (kvs *boltStore) Flush() error {
+	return nil
+}
+
+func (kvs *boltStore) lockUtil(status chan error) {
+	err := kvs.processLock.Lock()
+	status <- err
+}
+
+// Lock locks the store for exclusive access.
+func (kvs *boltStore) Lock(timeout time.Duration) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	afterTime := time.After(timeout)
+	status := make(chan error)
+
+	kvs.printf("Acquiring process lock")
+
+	go kvs.lockUtil(status)
+
+	var err error
+	select {
+	case <-afterTime:
+		return ErrTimeoutLockingStore
+	case err = <-status:
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "processLock acquire error")
+	}
+
+	kvs.printf("Acquired process lock with timeout value of", zap.Any("timeout", timeout))
+
+	return nil
+}
+
+// Unlock unlocks the store. The database is closed first, so that the next process can open it.
+func (kvs *boltStore) Unlock() error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	kvs.close()
+
+	err := kvs.processLock.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "unlock error")
+	}
+
+	kvs.printf("Released process lock")
+
+	return nil
+}
+
+// GetModificationTime returns the modification time of the persistent store.
+func (kvs *boltStore) GetModificationTime() (time.Time, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	fileName := kvs.fileName
+	if _, err := os.Stat(fileName); err != nil && kvs.pendingMigration() {
+		fileName = kvs.jsonFileName
+	}
+
+	info, err := os.Stat(fileName)
+	if err != nil {
+		kvs.printf("os.stat() for file", zap.String("fileName", fileName), zap.Error(err))
+		return time.Time{}.UTC(), err
+	}
+
+	return info.ModTime().UTC(), nil
+}
+
+func (kvs *boltStore) Remove() {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	kvs.close()
+	if err := os.Remove(kvs.fileName); err != nil {
+		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
+	}
+}
+
+// printf logs to the store logger if there is one, or to the default logger.
+func (kvs *boltStore) printf(msg string, fields ...zap.Field) {
+	if kvs.logger != nil {
+		kvs.logger.Info(msg, fields...)
+		return
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	log.Printf("%s %v", msg, enc.Fields)
+}
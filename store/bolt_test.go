@@ -0,0 +1,108 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/processlock"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that values written to the bolt store are read back after the database is reopened.
+func TestBoltStoreWriteAndRead(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.db")
+
+	kvs, err := NewBoltStore(fileName, "", processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.False(t, kvs.Exists())
+
+	// Reading a store that does not exist does not create it.
+	var value testType1
+	require.ErrorIs(t, kvs.Read(testKey1, &value), ErrKeyNotFound)
+	require.False(t, kvs.Exists())
+
+	require.NoError(t, kvs.Lock(DefaultLockTimeout))
+	require.NoError(t, kvs.Write(testKey1, testType1{"test", 42}))
+	require.NoError(t, kvs.Write(testKey2, testType1{"other", 7}))
+	require.NoError(t, kvs.Write(testKey1, testType1{"updated", 43}))
+	require.NoError(t, kvs.Flush())
+	require.NoError(t, kvs.Unlock())
+	require.True(t, kvs.Exists())
+
+	modTime, err := kvs.GetModificationTime()
+	require.NoError(t, err)
+	require.False(t, modTime.IsZero())
+
+	// A new store on the same file, as in the next CNI invocation.
+	kvs, err = NewBoltStore(fileName, "", processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, kvs.Read(testKey1, &value))
+	require.Equal(t, testType1{"updated", 43}, value)
+	require.NoError(t, kvs.Read(testKey2, &value))
+	require.Equal(t, testType1{"other", 7}, value)
+	require.ErrorIs(t, kvs.Read("missing", &value), ErrKeyNotFound)
+
+	kvs.Remove()
+	require.False(t, kvs.Exists())
+}
+
+// Tests that an existing json store file is imported once into the bolt store.
+func TestBoltStoreMigratesJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonFileName := filepath.Join(dir, "azure-vnet.json")
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"test","Field2":42},"key2":{"Field1":"other","Field2":7}}`), 0o600))
+
+	kvs, err := NewStore(BackendBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.True(t, kvs.Exists())
+
+	var value testType1
+	require.NoError(t, kvs.Read(testKey1, &value))
+	require.Equal(t, testType1{"test", 42}, value)
+
+	_, err = os.Stat(filepath.Join(dir, "azure-vnet.db"))
+	require.NoError(t, err)
+	_, err = os.Stat(jsonFileName)
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(jsonFileName + MigratedExtension)
+	require.NoError(t, err)
+
+	require.NoError(t, kvs.Write(testKey1, testType1{"updated", 43}))
+	require.NoError(t, kvs.Unlock())
+
+	// A json file showing up again does not overwrite keys already in the database.
+	require.NoError(t, os.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"stale","Field2":1},"key3":{"Field1":"new","Field2":3}}`), 0o600))
+
+	kvs, err = NewStore(BackendBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Read(testKey1, &value))
+	require.Equal(t, testType1{"updated", 43}, value)
+	require.NoError(t, kvs.Read("key3", &value))
+	require.Equal(t, testType1{"new", 3}, value)
+	require.NoError(t, kvs.Unlock())
+}
+
+func TestNewStoreBackends(t *testing.T) {
+	jsonFileName := filepath.Join(t.TempDir(), "test.json")
+
+	kvs, err := NewStore("", jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.IsType(t, &jsonFileStore{}, kvs)
+
+	kvs, err = NewStore(BackendJSON, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.IsType(t, &jsonFileStore{}, kvs)
+
+	kvs, err = NewStore(BackendBolt, jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.IsType(t, &boltStore{}, kvs)
+	require.Equal(t, filepath.Join(filepath.Dir(jsonFileName), "test.db"), kvs.(*boltStore).fileName)
+
+	_, err = NewStore("sqlite", jsonFileName, processlock.NewMockFileLock(false), nil)
+	require.ErrorIs(t, err, errUnsupportedBackend)
+}
@@ -6,7 +6,6 @@ package store
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -27,8 +26,20 @@ const (
 	DefaultLockTimeout        = 10000 * time.Millisecond
 	DefaultLockTimeoutLinux   = 30000 * time.Millisecond
 	DefaultLockTimeoutWindows = 60000 * time.Millisecond
+
+	// BackupExtension - Extension added to the file name for backup generations, followed by the generation.
+	BackupExtension = ".bak"
+
+	// DefaultBackupGenerations - number of previous versions of the file kept by the json store.
+	DefaultBackupGenerations = 3
 )
 
+// RecoveryHandler is called when a json store file could not be decoded and its state was recovered
+// from a backup generation. Processes set it to report the recovery through their telemetry.
+var RecoveryHandler func(fileName, backupFileName string, err error)
+
+var errTornWrite = errors.New("simulated torn write")
+
 // jsonFileStore is an implementation of KeyValueStore using a local JSON file.
 type jsonFileStore struct {
 	fileName    string
@@ -37,6 +48,12 @@ type jsonFileStore struct {
 	processLock processlock.Interface
 	sync.Mutex
 	logger *zap.Logger
+	// number of previous versions of the file kept as backups.
+	backups int
+	// set when the file could not be decoded, so that it is not kept as a backup.
+	corrupted bool
+	// when positive, the next flush writes only that many bytes in place and fails, as if the node lost power.
+	tornWriteAt int
 }
 
 // NewJsonFileStore creates a new jsonFileStore object, accessed as a KeyValueStore.
@@ -51,6 +68,7 @@ func NewJsonFileStore(fileName string, lockclient processlock.Interface, logger
 		processLock: lockclient,
 		data:        make(map[string]*json.RawMessage),
 		logger:      logger,
+		backups:     DefaultBackupGenerations,
 	}
 
 	return kvs, nil
@@ -70,34 +88,21 @@ func (kvs *jsonFileStore) Read(key string, value interface{}) error {
 
 	// Read contents from file if memory is not in sync.
 	if !kvs.inSync {
-		// Open and parse the file if it exists.
-		file, err := os.Open(kvs.fileName)
+		b, err := os.ReadFile(kvs.fileName)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return ErrKeyNotFound
 			}
 			return err
 		}
-		defer file.Close()
 
-		b, err := io.ReadAll(file)
+		data, err := kvs.decode(b)
 		if err != nil {
-			return err
-		}
-
-		if len(b) == 0 {
-			if kvs.logger != nil {
-				kvs.logger.Info("Unable to read empty file", zap.String("fileName", kvs.fileName))
-			} else {
-				log.Printf("Unable to read file %s, was empty", kvs.fileName)
+			if recoverErr := kvs.recoverFromBackup(err); recoverErr != nil {
+				return recoverErr
 			}
-
-			return ErrStoreEmpty
-		}
-
-		// Decode to raw JSON messages.
-		if err := json.Unmarshal(b, &kvs.data); err != nil {
-			return err
+		} else {
+			kvs.data = data
 		}
 
 		kvs.inSync = true
@@ -135,13 +140,120 @@ func (kvs *jsonFileStore) Flush() error {
 	return kvs.flush()
 }
 
+// decode parses the contents of a store file.
+func (kvs *jsonFileStore) decode(b []byte) (map[string]*json.RawMessage, error) {
+	if len(b) == 0 {
+		return nil, ErrStoreEmpty
+	}
+
+	data := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// backupFileName returns the file name of a backup generation, 1 being the newest.
+func (kvs *jsonFileStore) backupFileName(generation int) string {
+	return fmt.Sprintf("%s%s.%d", kvs.fileName, BackupExtension, generation)
+}
+
+// recoverFromBackup loads the newest backup generation that can be decoded after the store file could not be.
+// If there is none, the decode error of the store file is returned.
+func (kvs *jsonFileStore) recoverFromBackup(readErr error) error {
+	if errors.Is(readErr, ErrStoreEmpty) {
+		if kvs.logger != nil {
+			kvs.logger.Info("Unable to read empty file", zap.String("fileName", kvs.fileName))
+		} else {
+			log.Printf("Unable to read file %s, was empty", kvs.fileName)
+		}
+	}
+
+	for generation := 1; generation <= kvs.backups; generation++ {
+		backup := kvs.backupFileName(generation)
+		b, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+
+		data, err := kvs.decode(b)
+		if err != nil {
+			continue
+		}
+
+		kvs.data = data
+		kvs.corrupted = true
+
+		if kvs.logger != nil {
+			kvs.logger.Error("Recovered store from backup", zap.String("fileName", kvs.fileName),
+				zap.String("backup", backup), zap.Error(readErr))
+		} else {
+			log.Errorf("Recovered store %s from backup %s after error: %v", kvs.fileName, backup, readErr)
+		}
+
+		if RecoveryHandler != nil {
+			RecoveryHandler(kvs.fileName, backup, readErr)
+		}
+
+		return nil
+	}
+
+	return readErr
+}
+
+// rotateBackups shifts the backup generations and keeps the current store file as the newest one.
+func (kvs *jsonFileStore) rotateBackups() error {
+	if kvs.backups <= 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(kvs.fileName); err != nil {
+		return nil //nolint:nilerr // nothing to back up yet
+	}
+
+	if kvs.corrupted {
+		// The store file could not be read, it must not replace a good generation.
+		return nil
+	}
+
+	for generation := kvs.backups; generation > 1; generation-- {
+		if err := os.Rename(kvs.backupFileName(generation-1), kvs.backupFileName(generation)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate backup failed with: %w", err)
+		}
+	}
+
+	// The store file is about to be replaced by a new file, so the backup can share the current one.
+	if err := os.Link(kvs.fileName, kvs.backupFileName(1)); err != nil {
+		b, err := os.ReadFile(kvs.fileName)
+		if err != nil {
+			return fmt.Errorf("read store file for backup failed with: %w", err)
+		}
+		if err = os.WriteFile(kvs.backupFileName(1), b, 0o600); err != nil {
+			return fmt.Errorf("write backup failed with: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // Lock-free flush for internal callers.
+// The new contents are written to a temp file which is synced to disk and renamed over the store file,
+// so the store file is never left partially written.
 func (kvs *jsonFileStore) flush() error {
 	buf, err := json.MarshalIndent(&kvs.data, "", "\t")
 	if err != nil {
 		return err
 	}
 
+	if err = kvs.rotateBackups(); err != nil {
+		return err
+	}
+
+	if kvs.tornWriteAt > 0 {
+		return kvs.tornWrite(buf)
+	}
+
 	dir, file := filepath.Split(kvs.fileName)
 	if dir == "" {
 		dir = "."
@@ -167,6 +279,10 @@ func (kvs *jsonFileStore) flush() error {
 		return fmt.Errorf("Temp file write failed with: %v", err)
 	}
 
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("temp file sync failed with: %v", err)
+	}
+
 	if err = f.Close(); err != nil {
 		return fmt.Errorf("temp file close failed with: %v", err)
 	}
@@ -176,9 +292,43 @@ func (kvs *jsonFileStore) flush() error {
 		return fmt.Errorf("rename temp file to state file failed:%v", err)
 	}
 
+	syncDir(dir)
+	kvs.corrupted = false
+
 	return nil
 }
 
+// tornWrite leaves only part of buf in the store file and fails, as a crash before the data of
+// a non-atomic write reached the disk would. The old file is unlinked first, since the newest backup may share it.
+func (kvs *jsonFileStore) tornWrite(buf []byte) error {
+	n := kvs.tornWriteAt
+	if n > len(buf) {
+		n = len(buf)
+	}
+	kvs.tornWriteAt = 0
+
+	if err := os.Remove(kvs.fileName); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(kvs.fileName, buf[:n], 0o600); err != nil {
+		return err
+	}
+
+	return errTornWrite
+}
+
+// syncDir makes a rename in the directory durable. Directories cannot be synced on windows,
+// where ReplaceFile writes through instead.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
+
 func (kvs *jsonFileStore) lockUtil(status chan error) {
 	err := kvs.processLock.Lock()
 	status <- err
@@ -263,5 +413,8 @@ func (kvs *jsonFileStore) Remove() {
 	if err := os.Remove(kvs.fileName); err != nil {
 		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
 	}
+	for generation := 1; generation <= kvs.backups; generation++ {
+		_ = os.Remove(kvs.backupFileName(generation))
+	}
 	kvs.Mutex.Unlock()
 }
@@ -4,7 +4,10 @@
 package store
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -99,7 +102,7 @@ func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
 	}
 
 	file.Close()
-	os.Remove(testFileName)
+	kvs.Remove()
 
 	// Remove indentation to normalize the JSON encoding.
 	actualPair = string(data[:n])
@@ -154,7 +157,7 @@ func TestKeyValuePairsAreWrittenAndReadCorrectly(t *testing.T) {
 	}
 
 	// Cleanup.
-	os.Remove(testFileName)
+	kvs.Remove()
 }
 
 // test case for testing newjsonfilestore idempotent
@@ -237,3 +240,93 @@ func TestFileName(t *testing.T) {
 		t.Fatalf("This should not fail for a non-empty file %v", err)
 	}
 }
+
+// Tests that the store keeps the configured number of backup generations.
+func TestBackupGenerations(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	for i := 1; i <= DefaultBackupGenerations+2; i++ {
+		require.NoError(t, kvs.Write(testKey1, testType1{"test", i}))
+	}
+
+	// The newest backup holds the version before the current one.
+	for generation := 1; generation <= DefaultBackupGenerations; generation++ {
+		b, err := os.ReadFile(fmt.Sprintf("%s%s.%d", fileName, BackupExtension, generation))
+		require.NoError(t, err)
+
+		var data map[string]testType1
+		require.NoError(t, json.Unmarshal(b, &data))
+		require.Equal(t, DefaultBackupGenerations+2-generation, data[testKey1].Field2)
+	}
+
+	_, err = os.Stat(fmt.Sprintf("%s%s.%d", fileName, BackupExtension, DefaultBackupGenerations+1))
+	require.True(t, os.IsNotExist(err))
+
+	kvs.Remove()
+	matches, err := filepath.Glob(fileName + "*")
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
+// Tests that a store file torn by a crash mid-write is recovered from the newest valid backup.
+func TestRecoveryFromTornWrite(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	var recovered []string
+	RecoveryHandler = func(fileName, backupFileName string, err error) {
+		recovered = append(recovered, backupFileName)
+	}
+	defer func() { RecoveryHandler = nil }()
+
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(testKey1, testType1{"first", 1}))
+	require.NoError(t, kvs.Write(testKey1, testType1{"second", 2}))
+
+	// The backup of the first version is corrupted as well, so the second one has to be used.
+	require.NoError(t, os.WriteFile(fmt.Sprintf("%s%s.%d", fileName, BackupExtension, 2), []byte(`{"key1":`), 0o600))
+
+	kvs.(*jsonFileStore).tornWriteAt = 10
+	require.ErrorIs(t, kvs.Write(testKey1, testType1{"third", 3}), errTornWrite)
+
+	// A new process reading the store.
+	kvs, err = NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	var value testType1
+	require.NoError(t, kvs.Read(testKey1, &value))
+	require.Equal(t, testType1{"second", 2}, value)
+	require.Equal(t, []string{fileName + BackupExtension + ".1"}, recovered)
+
+	// The torn file does not become a backup, and the next write repairs the store file.
+	require.NoError(t, kvs.Write(testKey2, testType1{"fourth", 4}))
+	b, err := os.ReadFile(fileName + BackupExtension + ".1")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"key1":{"Field1":"second","Field2":2}}`, string(b))
+
+	kvs, err = NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Read(testKey2, &value))
+	require.Equal(t, testType1{"fourth", 4}, value)
+	require.Len(t, recovered, 1)
+}
+
+// Tests that an unreadable store file without a valid backup still fails to read.
+func TestRecoveryWithoutBackup(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	require.NoError(t, os.WriteFile(fileName, nil, 0o600))
+	kvs, err := NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+
+	var value testType1
+	require.ErrorIs(t, kvs.Read(testKey1, &value), ErrStoreEmpty)
+
+	require.NoError(t, os.WriteFile(fileName, []byte(`{"key1":{"Fie`), 0o600))
+	kvs, err = NewJsonFileStore(fileName, processlock.NewMockFileLock(false), nil)
+	require.NoError(t, err)
+	require.Error(t, kvs.Read(testKey1, &value))
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// LegacySchemaVersion is the version of values persisted without an envelope, before schemas were versioned.
+const LegacySchemaVersion = 0
+
+var (
+	// ErrSchemaVersionUnsupported is returned for values persisted with a schema version that has no migration to the requested one.
+	ErrSchemaVersionUnsupported = errors.New("unsupported schema version")
+	errInvalidMigration         = errors.New("invalid schema migration")
+)
+
+// Envelope is the persisted form of a value written with WriteVersioned.
+type Envelope struct {
+	SchemaVersion int
+	Data          json.RawMessage
+}
+
+// MigrationFunc converts a persisted value between two adjacent schema versions.
+type MigrationFunc func(data json.RawMessage) (json.RawMessage, error)
+
+// Migration converts a value from schema version Version-1 to Version with Forward, and back with Backward.
+type Migration struct {
+	Version  int
+	Forward  MigrationFunc
+	Backward MigrationFunc
+}
+
+// NoopMigration is the migration function of a version that did not change the encoding of the value.
+func NoopMigration(data json.RawMessage) (json.RawMessage, error) {
+	return data, nil
+}
+
+// EnvelopeMigration is the first migration of a value persisted before schemas were versioned. Version 1 moved
+// the value into an Envelope without changing its encoding.
+var EnvelopeMigration = Migration{Version: 1, Forward: NoopMigration, Backward: NoopMigration}
+
+var (
+	schemasMutex sync.RWMutex
+	// migrations of each registered key, indexed by version-1.
+	schemas = make(map[string][]Migration)
+)
+
+// RegisterSchema registers the migrations of the value persisted under key, starting from LegacySchemaVersion.
+// Migrations must be given in order without gaps, and the last one sets the current schema version of the key.
+// It is meant to be called from package init functions and panics if the schema is invalid or already registered.
+func RegisterSchema(key string, migrations ...Migration) {
+	schemasMutex.Lock()
+	defer schemasMutex.Unlock()
+
+	if _, ok := schemas[key]; ok {
+		panic(fmt.Sprintf("schema of key %s registered twice", key))
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 || m.Forward == nil || m.Backward == nil {
+			panic(errors.Wrapf(errInvalidMigration, "key %s version %d", key, m.Version))
+		}
+	}
+
+	schemas[key] = migrations
+}
+
+// SchemaVersion returns the current schema version of the value persisted under key.
+func SchemaVersion(key string) int {
+	schemasMutex.RLock()
+	defer schemasMutex.RUnlock()
+
+	return len(schemas[key])
+}
+
+// SchemaKeys returns the registered keys in lexical order.
+func SchemaKeys() []string {
+	schemasMutex.RLock()
+	defer schemasMutex.RUnlock()
+
+	keys := make([]string, 0, len(schemas))
+	for key := range schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Unwrap returns the schema version and data of a persisted value.
+// Values without an envelope are returned as they are, with the LegacySchemaVersion.
+func Unwrap(raw json.RawMessage) (int, json.RawMessage) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || len(fields) != 2 || fields["Data"] == nil {
+		return LegacySchemaVersion, raw
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil || fields["SchemaVersion"] == nil {
+		return LegacySchemaVersion, raw
+	}
+
+	return env.SchemaVersion, env.Data
+}
+
+// Wrap returns the persisted form of data encoded with the given schema version.
+// Data of the LegacySchemaVersion is persisted without an envelope, so that older versions can read it.
+func Wrap(version int, data json.RawMessage) (json.RawMessage, error) {
+	if version == LegacySchemaVersion {
+		return data, nil
+	}
+
+	return json.Marshal(Envelope{SchemaVersion: version, Data: data})
+}
+
+// Migrate converts data of the value persisted under key from one schema version to another,
+// running the forward or backward migrations in between.
+func Migrate(key string, data json.RawMessage, from, to int) (json.RawMessage, error) {
+	schemasMutex.RLock()
+	migrations := schemas[key]
+	schemasMutex.RUnlock()
+
+	if from < LegacySchemaVersion || from > len(migrations) {
+		return nil, errors.Wrapf(ErrSchemaVersionUnsupported, "key %s stored with version %d, current version is %d", key, from, len(migrations))
+	}
+	if to < LegacySchemaVersion || to > len(migrations) {
+		return nil, errors.Wrapf(ErrSchemaVersionUnsupported, "key %s cannot be converted to version %d, current version is %d", key, to, len(migrations))
+	}
+
+	var err error
+	for version := from; version < to; version++ {
+		if data, err = migrations[version].Forward(data); err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate key %s to version %d", key, version+1)
+		}
+	}
+	for version := from; version > to; version-- {
+		if data, err = migrations[version-1].Backward(data); err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate key %s to version %d", key, version-1)
+		}
+	}
+
+	return data, nil
+}
+
+// ReadVersioned restores the value for the given key, migrating it from the schema version
+// it was persisted with to the current one.
+func ReadVersioned(kvs KeyValueStore, key string, value interface{}) error {
+	var raw json.RawMessage
+	if err := kvs.Read(key, &raw); err != nil {
+		return err
+	}
+
+	if len(raw) == 0 {
+		// The store returned no value, leave the given one as it is.
+		return nil
+	}
+
+	version, data := Unwrap(raw)
+	data, err := Migrate(key, data, version, SchemaVersion(key))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+// WriteVersioned saves the value for the given key in an envelope with the current schema version.
+func WriteVersioned(kvs KeyValueStore, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	raw, err := Wrap(SchemaVersion(key), data)
+	if err != nil {
+		return err
+	}
+
+	return kvs.Write(key, raw)
+}
+
+// DumpVersioned returns the persisted form of all registered keys found in the store,
+// converted to the target schema version. A negative target keeps the current version of each key.
+func DumpVersioned(kvs KeyValueStore, targetVersion int) (map[string]json.RawMessage, error) {
+	state := make(map[string]json.RawMessage)
+
+	for _, key := range SchemaKeys() {
+		var raw json.RawMessage
+		if err := kvs.Read(key, &raw); err != nil {
+			if errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrStoreEmpty) {
+				continue
+			}
+			return nil, err
+		}
+
+		to := targetVersion
+		if to < 0 {
+			to = SchemaVersion(key)
+		}
+
+		version, data := Unwrap(raw)
+		data, err := Migrate(key, data, version, to)
+		if err != nil {
+			return nil, err
+		}
+
+		if state[key], err = Wrap(to, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
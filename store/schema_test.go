@@ -0,0 +1,116 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchemaKey = "TestSchema"
+
+// testStateV2 is the encoding of version 2 of the test schema, which renamed Name to PodName.
+// Versions 0 and 1 encode the name as Name.
+type testStateV2 struct {
+	PodName string
+	Count   int
+}
+
+func init() {
+	RegisterSchema(testSchemaKey,
+		Migration{Version: 1, Forward: NoopMigration, Backward: NoopMigration},
+		Migration{
+			Version:  2,
+			Forward:  func(data json.RawMessage) (json.RawMessage, error) { return renameField(data, "Name", "PodName") },
+			Backward: func(data json.RawMessage) (json.RawMessage, error) { return renameField(data, "PodName", "Name") },
+		})
+}
+
+func renameField(data json.RawMessage, from, to string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields[to] = fields[from]
+	delete(fields, from)
+	return json.Marshal(fields)
+}
+
+// Tests that values persisted with older schema versions are migrated when read.
+func TestReadVersionedMigratesForward(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "legacy value without envelope", raw: `{"Name":"pod","Count":3}`},
+		{name: "version 1", raw: `{"SchemaVersion":1,"Data":{"Name":"pod","Count":3}}`},
+		{name: "current version", raw: `{"SchemaVersion":2,"Data":{"PodName":"pod","Count":3}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kvs := NewMockStore("")
+			require.NoError(t, kvs.Write(testSchemaKey, json.RawMessage(tt.raw)))
+
+			var value testStateV2
+			require.NoError(t, ReadVersioned(kvs, testSchemaKey, &value))
+			require.Equal(t, testStateV2{PodName: "pod", Count: 3}, value)
+		})
+	}
+}
+
+// Tests that values persisted by a newer release are not loaded with a schema this release does not know.
+func TestReadVersionedRejectsNewerVersion(t *testing.T) {
+	kvs := NewMockStore("")
+	require.NoError(t, kvs.Write(testSchemaKey, json.RawMessage(`{"SchemaVersion":3,"Data":{"Pod":"pod"}}`)))
+
+	var value testStateV2
+	require.ErrorIs(t, ReadVersioned(kvs, testSchemaKey, &value), ErrSchemaVersionUnsupported)
+}
+
+// Tests that values are written in an envelope with the current schema version.
+func TestWriteVersioned(t *testing.T) {
+	kvs := NewMockStore("")
+	require.NoError(t, WriteVersioned(kvs, testSchemaKey, testStateV2{PodName: "pod", Count: 3}))
+
+	var raw json.RawMessage
+	require.NoError(t, kvs.Read(testSchemaKey, &raw))
+	require.JSONEq(t, `{"SchemaVersion":2,"Data":{"PodName":"pod","Count":3}}`, string(raw))
+
+	var value testStateV2
+	require.NoError(t, ReadVersioned(kvs, testSchemaKey, &value))
+	require.Equal(t, testStateV2{PodName: "pod", Count: 3}, value)
+}
+
+// Tests that the state can be dumped in the layout of an older release.
+func TestDumpVersioned(t *testing.T) {
+	kvs := NewMockStore("")
+	require.NoError(t, WriteVersioned(kvs, testSchemaKey, testStateV2{PodName: "pod", Count: 3}))
+
+	state, err := DumpVersioned(kvs, -1)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"SchemaVersion":2,"Data":{"PodName":"pod","Count":3}}`, string(state[testSchemaKey]))
+
+	state, err = DumpVersioned(kvs, 1)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"SchemaVersion":1,"Data":{"Name":"pod","Count":3}}`, string(state[testSchemaKey]))
+
+	// The legacy version is written without an envelope.
+	state, err = DumpVersioned(kvs, LegacySchemaVersion)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Name":"pod","Count":3}`, string(state[testSchemaKey]))
+
+	_, err = DumpVersioned(kvs, 3)
+	require.ErrorIs(t, err, ErrSchemaVersionUnsupported)
+}
+
+func TestRegisterSchemaValidatesMigrations(t *testing.T) {
+	require.Panics(t, func() { RegisterSchema(testSchemaKey) })
+	require.Panics(t, func() {
+		RegisterSchema("TestSchemaGap", Migration{Version: 2, Forward: NoopMigration, Backward: NoopMigration})
+	})
+	require.Panics(t, func() { RegisterSchema("TestSchemaNoBackward", Migration{Version: 1, Forward: NoopMigration}) })
+}
@@ -22,6 +22,19 @@ const (
 )
 
 func (tb *TelemetryBuffer) CreateAITelemetryHandle(aiConfig aitelemetry.AIConfig, disableAll, disableMetric, disableTrace bool) error {
+	return tb.createTelemetryHandle(func() (aitelemetry.TelemetryHandle, error) {
+		return aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
+	}, disableAll, disableMetric, disableTrace)
+}
+
+// CreateOTLPTelemetryHandle sends the telemetry to the OTLP endpoint of an OpenTelemetry collector instead of AppInsights.
+func (tb *TelemetryBuffer) CreateOTLPTelemetryHandle(aiConfig aitelemetry.AIConfig, endpoint string, disableAll, disableMetric, disableTrace bool) error {
+	return tb.createTelemetryHandle(func() (aitelemetry.TelemetryHandle, error) {
+		return aitelemetry.NewOTLPTelemetry(endpoint, aiConfig)
+	}, disableAll, disableMetric, disableTrace)
+}
+
+func (tb *TelemetryBuffer) createTelemetryHandle(newHandle func() (aitelemetry.TelemetryHandle, error), disableAll, disableMetric, disableTrace bool) error {
 	var err error
 
 	if disableAll {
@@ -33,7 +46,7 @@ func (tb *TelemetryBuffer) CreateAITelemetryHandle(aiConfig aitelemetry.AIConfig
 		return ErrTelemetryDisabled
 	}
 
-	th, err = aitelemetry.NewAITelemetry("", aiMetadata, aiConfig)
+	th, err = newHandle()
 	if err != nil {
 		return err
 	}
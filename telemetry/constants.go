@@ -10,6 +10,8 @@ const (
 	CNIUpdateTimeMetricStr = "CNIUpdateTimeMs"
 	CNILockTimeoutStr      = "CNILockTimeoutError"
 
+	CNIDataplaneCallTimeMetricStr = "CNIDataplaneCallTimeMs"
+
 	// Dimension Names
 	ContextStr        = "Context"
 	SubContextStr     = "SubContext"
@@ -20,6 +22,9 @@ const (
 	CNIModeStr        = "CNIMode"
 	CNINetworkModeStr = "CNINetworkMode"
 	OSTypeStr         = "OSType"
+	IPAMModeStr       = "IPAMMode"
+	ErrorClassStr     = "ErrorClass"
+	CallStr           = "Call"
 
 	// Values
 	SucceededStr     = "Succeeded"
@@ -0,0 +1,134 @@
+// Copyright Microsoft. All rights reserved.
+package telemetry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	operationLabel  = "operation"
+	ipamModeLabel   = "ipam_mode"
+	statusLabel     = "status"
+	errorClassLabel = "error_class"
+	callLabel       = "call"
+
+	metricsPath              = "/metrics"
+	metricsReadHeaderTimeout = 10 * time.Second
+)
+
+// operations of the CNI metrics that carry the duration of an invocation.
+var operationMetrics = map[string]string{
+	CNIAddTimeMetricStr:    "ADD",
+	CNIDelTimeMetricStr:    "DEL",
+	CNIUpdateTimeMetricStr: "UPDATE",
+}
+
+// The telemetry service aggregates the reports and metrics sent by each CNI invocation, so that they
+// can be scraped locally in addition to being sent to AppInsights.
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	operationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cni_operation_duration_seconds",
+			Help:    "Duration of CNI plugin invocations.",
+			Buckets: prometheus.ExponentialBuckets(0.025, 2, 12),
+		},
+		[]string{operationLabel, ipamModeLabel, statusLabel, errorClassLabel},
+	)
+	dataplaneCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cni_dataplane_call_duration_seconds",
+			Help:    "Duration of the netlink or HNS calls made by the CNI plugin to program networks and endpoints.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+		},
+		[]string{callLabel, statusLabel},
+	)
+	pluginErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cni_plugin_errors_total",
+			Help: "Errors reported by CNI plugin invocations.",
+		},
+		[]string{operationLabel, errorClassLabel},
+	)
+	lockTimeouts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cni_store_lock_timeouts_total",
+			Help: "CNI plugin invocations that timed out acquiring the state store lock.",
+		},
+	)
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		operationDuration,
+		dataplaneCallDuration,
+		pluginErrors,
+		lockTimeouts,
+	)
+}
+
+// recordMetric adds a metric sent by a CNI invocation to the prometheus metrics.
+func recordMetric(aiMetric AIMetric) {
+	m := aiMetric.Metric
+	dims := m.CustomDimensions
+
+	if operation, ok := operationMetrics[m.Name]; ok {
+		operationDuration.WithLabelValues(operation, dims[IPAMModeStr], dims[StatusStr], dims[ErrorClassStr]).
+			Observe(m.Value / float64(time.Second/time.Millisecond))
+		return
+	}
+
+	switch m.Name {
+	case CNIDataplaneCallTimeMetricStr:
+		dataplaneCallDuration.WithLabelValues(dims[CallStr], dims[StatusStr]).
+			Observe(m.Value / float64(time.Second/time.Millisecond))
+	case CNILockTimeoutStr:
+		lockTimeouts.Add(m.Value)
+	}
+}
+
+// recordReport adds a report sent by a CNI invocation to the prometheus metrics.
+func recordReport(report CNIReport) {
+	if report.ErrorMessage == "" {
+		return
+	}
+
+	pluginErrors.WithLabelValues(report.OperationType, report.ErrorClass).Inc()
+}
+
+// StartMetricsServer serves the aggregated CNI metrics for prometheus on the given address.
+func StartMetricsServer(addr string, logger *zap.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	}))
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadHeaderTimeout,
+	}
+
+	go func() {
+		if logger != nil {
+			logger.Info("Starting metrics server", zap.String("address", addr))
+		} else {
+			log.Logf("[Telemetry] Starting metrics server on %s", addr)
+		}
+
+		if err := srv.ListenAndServe(); err != nil {
+			if logger != nil {
+				logger.Error("Metrics server failed", zap.Error(err))
+			} else {
+				log.Logf("[Telemetry] Metrics server failed: %v", err)
+			}
+		}
+	}()
+}
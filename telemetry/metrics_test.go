@@ -0,0 +1,96 @@
+// Copyright Microsoft. All rights reserved.
+package telemetry
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/aitelemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordMetric(t *testing.T) {
+	operationDuration.Reset()
+	dataplaneCallDuration.Reset()
+	lockTimeoutsBefore := testutil.ToFloat64(lockTimeouts)
+
+	push(AIMetric{Metric: aitelemetry.Metric{
+		Name:  CNIAddTimeMetricStr,
+		Value: 1500,
+		CustomDimensions: map[string]string{
+			IPAMModeStr:   "azure-cns",
+			StatusStr:     FailedStr,
+			ErrorClassStr: "IPAM",
+		},
+	}})
+	push(AIMetric{Metric: aitelemetry.Metric{
+		Name:             CNIDataplaneCallTimeMetricStr,
+		Value:            20,
+		CustomDimensions: map[string]string{CallStr: "CreateEndpoint", StatusStr: SucceededStr},
+	}})
+	push(AIMetric{Metric: aitelemetry.Metric{Name: CNILockTimeoutStr, Value: 1}})
+	push(AIMetric{Metric: aitelemetry.Metric{Name: "unknown", Value: 1}})
+
+	require.Equal(t, 1, testutil.CollectAndCount(operationDuration))
+	require.Equal(t, 1, testutil.CollectAndCount(dataplaneCallDuration))
+	require.Equal(t, lockTimeoutsBefore+1, testutil.ToFloat64(lockTimeouts))
+
+	expected := `
+# HELP cni_operation_duration_seconds Duration of CNI plugin invocations.
+# TYPE cni_operation_duration_seconds histogram
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.025"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.05"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.1"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.2"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.4"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="0.8"} 0
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="1.6"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="3.2"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="6.4"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="12.8"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="25.6"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="51.2"} 1
+cni_operation_duration_seconds_bucket{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed",le="+Inf"} 1
+cni_operation_duration_seconds_sum{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed"} 1.5
+cni_operation_duration_seconds_count{error_class="IPAM",ipam_mode="azure-cns",operation="ADD",status="Failed"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(operationDuration, strings.NewReader(expected)))
+}
+
+func TestRecordReport(t *testing.T) {
+	pluginErrors.Reset()
+
+	push(CNIReport{OperationType: "ADD", EventMessage: "event"})
+	push(CNIReport{OperationType: "ADD", ErrorMessage: "failed", ErrorClass: "IPAM"})
+	push(CNIReport{OperationType: "ADD", ErrorMessage: "failed again", ErrorClass: "IPAM"})
+
+	require.Equal(t, float64(2), testutil.ToFloat64(pluginErrors.WithLabelValues("ADD", "IPAM")))
+	require.Equal(t, 1, testutil.CollectAndCount(pluginErrors))
+}
+
+func TestStartMetricsServer(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	StartMetricsServer(addr, nil)
+	push(AIMetric{Metric: aitelemetry.Metric{Name: CNILockTimeoutStr, Value: 1}})
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr + metricsPath) //nolint:noctx // test request
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "cni_store_lock_timeouts_total")
+}
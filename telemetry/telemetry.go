@@ -67,6 +67,7 @@ type CNIReport struct {
 	Name              string
 	Version           string
 	ErrorMessage      string
+	ErrorClass        string
 	EventMessage      string
 	OperationType     string
 	OperationDuration int
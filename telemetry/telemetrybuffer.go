@@ -34,6 +34,12 @@ type TelemetryConfig struct {
 	BatchSizeInBytes              int
 	GetEnvRetryCount              int
 	GetEnvRetryWaitTimeInSecs     int
+	// MetricsAddress is the address to serve prometheus metrics on, they are not served if empty.
+	MetricsAddress string
+	// TelemetryBackend selects where telemetry is sent, appinsights (default) or otlp.
+	TelemetryBackend string
+	// OTLPEndpoint is the OTLP/HTTP endpoint of the OpenTelemetry collector for the otlp backend.
+	OTLPEndpoint string
 }
 
 // FdName - file descriptor name
@@ -284,9 +290,11 @@ func (tb *TelemetryBuffer) Close() {
 func push(x interface{}) {
 	switch y := x.(type) {
 	case CNIReport:
+		recordReport(y)
 		SendAITelemetry(y)
 
 	case AIMetric:
+		recordMetric(y)
 		SendAIMetric(y)
 	default:
 		log.Printf("Push fn: Default case:%+v", y)
@@ -130,7 +130,12 @@ type AzureVnetEndpointInfo struct {
 
 func cnsManagedStateFileIps(result []byte) (map[string]string, error) {
 	var cnsResult CnsManagedState
-	err := json.Unmarshal(result, &cnsResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &cnsResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal cns endpoint list")
 	}
@@ -148,7 +153,12 @@ func cnsManagedStateFileIps(result []byte) (map[string]string, error) {
 
 func cnsManagedStateFileDualStackIps(result []byte) (map[string]string, error) {
 	var cnsResult CnsManagedState
-	err := json.Unmarshal(result, &cnsResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &cnsResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal cns endpoint list")
 	}
@@ -204,7 +214,12 @@ func ciliumStateFileDualStackIps(result []byte) (map[string]string, error) {
 
 func azureVnetStateIps(result []byte) (map[string]string, error) {
 	var azureVnetResult AzureCniState
-	err := json.Unmarshal(result, &azureVnetResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &azureVnetResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal azure vnet")
 	}
@@ -225,7 +240,12 @@ func azureVnetStateIps(result []byte) (map[string]string, error) {
 
 func azureVnetIpamStateIps(result []byte) (map[string]string, error) {
 	var azureVnetIpamResult AzureVnetIpam
-	err := json.Unmarshal(result, &azureVnetIpamResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &azureVnetIpamResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal azure vnet ipam")
 	}
@@ -2,8 +2,10 @@ package validate
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 
+	"github.com/Azure/azure-container-networking/store"
 	acnk8s "github.com/Azure/azure-container-networking/test/internal/kubernetes"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +28,21 @@ func compareIPs(expected map[string]string, actual []string) error {
 	return nil
 }
 
+// unwrapStateFile removes the schema version envelopes from the values of a json store file.
+func unwrapStateFile(result []byte) ([]byte, error) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(result, &state); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal state file")
+	}
+
+	for key, raw := range state {
+		_, state[key] = store.Unwrap(raw)
+	}
+
+	b, err := json.Marshal(state)
+	return b, errors.Wrap(err, "failed to marshal state file")
+}
+
 // func to get the pods ip without the node ip (ie. host network as false)
 func getPodIPsWithoutNodeIP(ctx context.Context, clientset *kubernetes.Clientset, node corev1.Node) []string {
 	podsIpsWithoutNodeIP := []string{}
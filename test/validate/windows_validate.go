@@ -157,7 +157,12 @@ func hnsNetworkState(result []byte) ([]HNSNetwork, error) {
 
 func azureVnetIps(result []byte) (map[string]string, error) {
 	var azureVnetResult AzureVnet
-	err := json.Unmarshal(result, &azureVnetResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &azureVnetResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal azure vnet")
 	}
@@ -178,7 +183,12 @@ func azureVnetIps(result []byte) (map[string]string, error) {
 
 func azureVnetIpamIps(result []byte) (map[string]string, error) {
 	var azureVnetIpamResult AzureVnetIpam
-	err := json.Unmarshal(result, &azureVnetIpamResult)
+	result, err := unwrapStateFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(result, &azureVnetIpamResult)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal azure vnet ipam")
 	}